@@ -0,0 +1,97 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/chat/globals"
+	"github.com/adamwalz/keybase-client/go/chat/utils"
+	"github.com/adamwalz/keybase-client/go/protocol/chat1"
+	"github.com/adamwalz/keybase-client/go/protocol/gregor1"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+)
+
+type Remind struct {
+	*baseCommand
+}
+
+func NewRemind(g *globals.Context) *Remind {
+	return &Remind{
+		baseCommand: newBaseCommand(g, "remind", "<when> <message>",
+			"Send a message later, at a time like 9:30 or 5pm, or after a duration like 2h", false),
+	}
+}
+
+func (h *Remind) Execute(ctx context.Context, uid gregor1.UID, convID chat1.ConversationID,
+	tlfName, text string, replyTo *chat1.MessageID) (err error) {
+	defer h.Trace(ctx, &err, "Remind")()
+	if !h.Match(ctx, text) {
+		return ErrInvalidCommand
+	}
+	defer func() {
+		if err != nil {
+			err := h.getChatUI().ChatCommandStatus(ctx, convID, fmt.Sprintf("Failed to schedule reminder: %v", err),
+				chat1.UICommandStatusDisplayTyp_ERROR, nil)
+			if err != nil {
+				h.Debug(ctx, "Execute: error with command status: %+v", err)
+			}
+		}
+	}()
+	toks, err := h.tokenize(text, 3)
+	if err != nil {
+		return err
+	}
+	sendAt, body, err := parseRemindArgs(h.G().Clock().Now(), toks[1:])
+	if err != nil {
+		return err
+	}
+	conv, err := getConvByID(ctx, h.G(), uid, convID)
+	if err != nil {
+		return err
+	}
+	msg := chat1.MessagePlaintext{
+		ClientHeader: chat1.MessageClientHeader{
+			Conv:        conv.Conv.Metadata.IdTriple,
+			TlfName:     tlfName,
+			TlfPublic:   conv.Conv.Metadata.Visibility == keybase1.TLFVisibility_PUBLIC,
+			MessageType: chat1.MessageType_TEXT,
+		},
+		MessageBody: chat1.NewMessageBodyWithText(chat1.MessageText{
+			Body: body,
+		}),
+	}
+	identifyBehavior, _, _ := globals.CtxIdentifyMode(ctx)
+	if _, err = h.G().MessageDeliverer.Schedule(ctx, convID, msg, gregor1.ToTime(sendAt),
+		identifyBehavior); err != nil {
+		return err
+	}
+	if err := h.getChatUI().ChatCommandStatus(ctx, convID,
+		fmt.Sprintf("Reminder scheduled for %s", sendAt.Format("Mon Jan 2 15:04")),
+		chat1.UICommandStatusDisplayTyp_STATUS, nil); err != nil {
+		h.Debug(ctx, "Execute: error with command status: %+v", err)
+	}
+	return nil
+}
+
+// parseRemindArgs splits the arguments of /remind into when to send the
+// reminder and its message. A time of the "2006-01-02 15:04" form is the only
+// one that takes two words, so it's recognized by its date.
+func parseRemindArgs(now time.Time, args []string) (sendAt time.Time, body string, err error) {
+	whenWords := 1
+	if _, err := time.Parse("2006-01-02", args[0]); err == nil {
+		whenWords = 2
+	}
+	if len(args) <= whenWords {
+		return sendAt, body, ErrInvalidArguments
+	}
+	sendAt, err = utils.ParseScheduleTime(now, strings.Join(args[:whenWords], " "))
+	if err != nil {
+		return sendAt, body, err
+	}
+	return sendAt, strings.Join(args[whenWords:], " "), nil
+}
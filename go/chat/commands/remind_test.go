@@ -0,0 +1,44 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRemindArgs(t *testing.T) {
+	loc := time.FixedZone("test", -5*60*60)
+	now := time.Date(2020, time.March, 10, 12, 0, 0, 0, loc)
+
+	sendAt, body, err := parseRemindArgs(now, strings.Split("2020-03-11 09:30 stand up", " "))
+	require.NoError(t, err)
+	require.True(t, sendAt.Equal(time.Date(2020, time.March, 11, 9, 30, 0, 0, loc)), "%v", sendAt)
+	require.Equal(t, "stand up", body)
+
+	sendAt, body, err = parseRemindArgs(now, strings.Split("2h take a break", " "))
+	require.NoError(t, err)
+	require.True(t, sendAt.Equal(now.Add(2*time.Hour)), "%v", sendAt)
+	require.Equal(t, "take a break", body)
+
+	sendAt, body, err = parseRemindArgs(now, strings.Split("9:30 stand up", " "))
+	require.NoError(t, err)
+	require.True(t, sendAt.Equal(time.Date(2020, time.March, 11, 9, 30, 0, 0, loc)), "%v", sendAt)
+	require.Equal(t, "stand up", body)
+
+	// a date with a time but no message
+	_, _, err = parseRemindArgs(now, strings.Split("2020-03-11 09:30", " "))
+	require.Equal(t, ErrInvalidArguments, err)
+
+	// a date needs a time
+	_, _, err = parseRemindArgs(now, strings.Split("2020-03-11 stand up", " "))
+	require.Error(t, err)
+
+	_, _, err = parseRemindArgs(now, strings.Split("2020-03-09 09:30 stand up", " "))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "in the past")
+}
@@ -53,6 +53,7 @@ const (
 	cmdMe
 	cmdMsg
 	cmdMute
+	cmdRemind
 	cmdShrug
 	cmdUnhide
 )
@@ -72,6 +73,7 @@ func (s *Source) allCommands() (res map[int]types.ConversationCommand) {
 	res[cmdMe] = NewMe(s.G())
 	res[cmdMsg] = NewMsg(s.G())
 	res[cmdMute] = NewMute(s.G())
+	res[cmdRemind] = NewRemind(s.G())
 	res[cmdShrug] = NewShrug(s.G())
 	res[cmdUnhide] = NewUnhide(s.G())
 	return res
@@ -90,6 +92,7 @@ func (s *Source) makeBuiltins() {
 		cmds[cmdMe],
 		cmds[cmdMsg],
 		cmds[cmdMute],
+		cmds[cmdRemind],
 		cmds[cmdShrug],
 		cmds[cmdUnhide],
 		cmds[cmdAddEmoji],
@@ -24,6 +24,9 @@ import (
 const deliverMaxAttempts = 180           // fifteen minutes in default mode
 const deliverDisconnectLimitMinutes = 10 // need to be offline for at least 10 minutes before auto failing a send
 
+// longest we sleep before checking on scheduled messages again
+const deliverScheduleMaxWait = time.Minute
+
 type DelivererInfoError interface {
 	IsImmediateFail() (chat1.OutboxErrorType, bool)
 }
@@ -72,6 +75,7 @@ type Deliverer struct {
 	shutdownCh       chan struct{}
 	msgSentCh        chan struct{}
 	reconnectCh      chan struct{}
+	scheduleCh       chan struct{}
 	kbfsDeliverQueue chan chat1.OutboxRecord
 	delivering       bool
 	connected        bool
@@ -94,6 +98,7 @@ func NewDeliverer(g *globals.Context, sender types.Sender, serverConn types.Serv
 		DebugLabeler:     utils.NewDebugLabeler(g.ExternalG(), "Deliverer", false),
 		msgSentCh:        make(chan struct{}, 100),
 		reconnectCh:      make(chan struct{}, 100),
+		scheduleCh:       make(chan struct{}, 100),
 		kbfsDeliverQueue: make(chan chat1.OutboxRecord, 100),
 		sender:           sender,
 		identNotifier:    NewCachingIdentifyNotifier(g),
@@ -161,6 +166,7 @@ func (s *Deliverer) Start(ctx context.Context, uid gregor1.UID) {
 	s.shutdownCh = make(chan struct{})
 	s.eg.Go(func() error { return s.deliverLoop(s.shutdownCh) })
 	s.eg.Go(func() error { return s.kbfsDeliverLoop(s.shutdownCh) })
+	s.eg.Go(func() error { return s.scheduleLoop(s.shutdownCh) })
 }
 
 func (s *Deliverer) Stop(ctx context.Context) chan struct{} {
@@ -260,18 +266,59 @@ func (s *Deliverer) Queue(ctx context.Context, convID chat1.ConversationID, msg
 
 	// Alert the deliver loop it should wake up
 	s.msgSentCh <- struct{}{}
+	s.updateLocalMtime(ctx, obr)
+	return obr, nil
+}
+
+func (s *Deliverer) updateLocalMtime(ctx context.Context, obr chat1.OutboxRecord) {
 	// Only update mtime badgable messages
-	if obr.Msg.IsBadgableType() {
-		go func(ctx context.Context) {
-			update := []chat1.LocalMtimeUpdate{{ConvID: convID, Mtime: obr.Ctime}}
-			if err := s.G().InboxSource.UpdateLocalMtime(ctx, s.outbox.GetUID(), update); err != nil {
-				s.Debug(ctx, "Queue: unable to update local mtime %v", obr.Ctime.Time())
-			}
-			time.Sleep(250 * time.Millisecond)
-			s.G().InboxSource.NotifyUpdate(ctx, s.outbox.GetUID(), convID)
-		}(globals.BackgroundChatCtx(ctx, s.G()))
+	if !obr.Msg.IsBadgableType() {
+		return
 	}
-	return obr, nil
+	go func(ctx context.Context) {
+		update := []chat1.LocalMtimeUpdate{{ConvID: obr.ConvID, Mtime: obr.Ctime}}
+		if err := s.G().InboxSource.UpdateLocalMtime(ctx, s.outbox.GetUID(), update); err != nil {
+			s.Debug(ctx, "Queue: unable to update local mtime %v", obr.Ctime.Time())
+		}
+		time.Sleep(250 * time.Millisecond)
+		s.G().InboxSource.NotifyUpdate(ctx, s.outbox.GetUID(), obr.ConvID)
+	}(globals.BackgroundChatCtx(ctx, s.G()))
+}
+
+// Schedule stores msg in the outbox to be queued for delivery at sendAt.
+func (s *Deliverer) Schedule(ctx context.Context, convID chat1.ConversationID, msg chat1.MessagePlaintext,
+	sendAt gregor1.Time, identifyBehavior keybase1.TLFIdentifyBehavior) (sm chat1.ScheduledMessage, err error) {
+	defer s.Trace(ctx, &err, "Schedule")()
+	if msg.ClientHeader.Conv.TopicType == chat1.TopicType_KBFSFILEEDIT {
+		return sm, errors.New("unable to schedule KBFS file edit messages")
+	}
+	if sm, err = s.outbox.ScheduleMessage(ctx, convID, msg, sendAt, identifyBehavior); err != nil {
+		return sm, err
+	}
+	s.Debug(ctx, "Schedule: scheduled new message: convID: %s outboxID: %s sendAt: %v", convID,
+		sm.OutboxID, sendAt.Time())
+	// Let the schedule loop pick the new time up
+	s.scheduleCh <- struct{}{}
+	return sm, nil
+}
+
+func (s *Deliverer) ScheduledMessages(ctx context.Context) (res []chat1.ScheduledMessage, err error) {
+	defer s.Trace(ctx, &err, "ScheduledMessages")()
+	if !s.IsDelivering() {
+		return nil, nil
+	}
+	return s.outbox.ScheduledMessages(ctx)
+}
+
+func (s *Deliverer) CancelScheduled(ctx context.Context, outboxID chat1.OutboxID) (err error) {
+	defer s.Trace(ctx, &err, "CancelScheduled(%s)", outboxID)()
+	if _, err := s.outbox.CancelScheduledMessage(ctx, outboxID); err != nil {
+		if _, ok := err.(storage.MissError); ok {
+			return fmt.Errorf("no scheduled message with ID %s", outboxID)
+		}
+		return err
+	}
+	return nil
 }
 
 func (s *Deliverer) ActiveDeliveries(ctx context.Context) (res []chat1.OutboxRecord, err error) {
@@ -662,6 +709,79 @@ func (s *Deliverer) kbfsDeliverLoop(shutdownCh chan struct{}) error {
 	}
 }
 
+// scheduleLoop queues scheduled messages for delivery once they are due. It
+// runs right away on start, so anything that came due while the service was
+// down goes out then.
+func (s *Deliverer) scheduleLoop(shutdownCh chan struct{}) error {
+	bgctx := libkb.WithLogTag(context.Background(), "SDELV")
+	s.Debug(bgctx, "scheduleLoop: starting scheduled message loop: uid: %s", s.outbox.GetUID())
+	for {
+		wait := s.releaseScheduled(bgctx)
+		select {
+		case <-shutdownCh:
+			s.Debug(bgctx, "scheduleLoop: shutting down scheduled message loop: uid: %s", s.outbox.GetUID())
+			return nil
+		case <-s.scheduleCh:
+		case <-s.clock.After(wait):
+		}
+	}
+}
+
+// releaseScheduled moves any due scheduled messages into the outbox, and
+// returns how long to wait before the next one is due.
+func (s *Deliverer) releaseScheduled(ctx context.Context) time.Duration {
+	sms, err := s.outbox.ScheduledMessages(ctx)
+	if err != nil {
+		s.Debug(ctx, "releaseScheduled: unable to read scheduled messages: %v", err)
+		return deliverScheduleMaxWait
+	}
+	now := s.clock.Now()
+	wait := deliverScheduleMaxWait
+	var due []chat1.ScheduledMessage
+	for _, sm := range sms {
+		if untilDue := sm.SendAt.Time().Sub(now); untilDue > 0 {
+			if untilDue < wait {
+				wait = untilDue
+			}
+			continue
+		}
+		// Same as NonblockingSender.Send, now that the message is being sent.
+		var prev chat1.MessageID
+		conv, err := utils.GetUnverifiedConv(ctx, s.G(), s.outbox.GetUID(), sm.ConvID,
+			types.InboxSourceDataSourceLocalOnly)
+		if err != nil {
+			s.Debug(ctx, "releaseScheduled: failed to get local inbox info: %s", err)
+		} else {
+			prev = conv.Conv.GetMaxMessageID()
+		}
+		if prev == 0 {
+			prev = 1
+		}
+		sm.Msg.ClientHeader.OutboxInfo = &chat1.OutboxInfo{
+			Prev:        prev,
+			ComposeTime: gregor1.ToTime(now),
+		}
+		due = append(due, sm)
+	}
+	if len(due) == 0 {
+		return wait
+	}
+	obrs, err := s.outbox.ReleaseScheduledMessages(ctx, due)
+	if err != nil {
+		s.Debug(ctx, "releaseScheduled: unable to release scheduled messages: %v", err)
+		return wait
+	}
+	for _, obr := range obrs {
+		s.Debug(ctx, "releaseScheduled: queued scheduled message: convID: %s outboxID: %s",
+			obr.ConvID, obr.OutboxID)
+		s.updateLocalMtime(ctx, obr)
+	}
+	if len(obrs) > 0 {
+		s.msgSentCh <- struct{}{}
+	}
+	return wait
+}
+
 func (s *Deliverer) deliverLoop(shutdownCh chan struct{}) error {
 	bgctx := libkb.WithLogTag(context.Background(), "DELV")
 	s.Debug(bgctx, "deliverLoop: starting non blocking sender deliver loop: uid: %s duration: %v",
@@ -1,6 +1,9 @@
 package s3
 
 import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
 	"log"
 	"sort"
 	"strings"
@@ -60,7 +63,11 @@ func (l keySortableTupleList) Swap(i, j int) {
 	l[i], l[j] = l[j], l[i]
 }
 
-func (s *S3) sign(method, canonicalPath string, params, headers map[string][]string) error {
+// StringToSign returns the payload that an S3 request with the given
+// method, canonical path, query parameters and headers is signed
+// over.  A server verifying a request can sign it again with the
+// secret key and compare signatures.
+func StringToSign(method, canonicalPath string, params, headers map[string][]string) string {
 	var md5, ctype, date, xamz string
 	var xamzDate bool
 	var sarray keySortableTupleList
@@ -91,12 +98,9 @@ func (s *S3) sign(method, canonicalPath string, params, headers map[string][]str
 		xamz = strings.Join(sarray.StringSlice(), "\n") + "\n"
 	}
 
-	expires := false
 	if v, ok := params["Expires"]; ok {
 		// Query string request authentication alternative.
-		expires = true
 		date = v[0]
-		params["AWSAccessKeyId"] = []string{s.AccessKey}
 	}
 
 	sarray = sarray[0:0]
@@ -117,7 +121,32 @@ func (s *S3) sign(method, canonicalPath string, params, headers map[string][]str
 		canonicalPath = canonicalPath + "?" + strings.Join(sarray.StringSlice(), "&")
 	}
 
-	payload := method + "\n" + md5 + "\n" + ctype + "\n" + date + "\n" + xamz + canonicalPath
+	return method + "\n" + md5 + "\n" + ctype + "\n" + date + "\n" + xamz + canonicalPath
+}
+
+// HMACSigner signs payloads locally with a secret key, the same way
+// S3 itself does.
+type HMACSigner struct {
+	SecretKey []byte
+}
+
+var _ Signer = HMACSigner{}
+
+// Sign implements the Signer interface for HMACSigner.
+func (s HMACSigner) Sign(payload []byte) ([]byte, error) {
+	mac := hmac.New(sha1.New, s.SecretKey)
+	_, _ = mac.Write(payload)
+	sig := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return []byte(sig), nil
+}
+
+func (s *S3) sign(method, canonicalPath string, params, headers map[string][]string) error {
+	payload := StringToSign(method, canonicalPath, params, headers)
+
+	_, expires := params["Expires"]
+	if expires {
+		params["AWSAccessKeyId"] = []string{s.AccessKey}
+	}
 
 	signature, err := s.Signer.Sign([]byte(payload))
 	if err != nil {
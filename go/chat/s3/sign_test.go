@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The examples from the S3 REST authentication documentation.
+func TestStringToSign(t *testing.T) {
+	signer := HMACSigner{
+		SecretKey: []byte("wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"),
+	}
+
+	payload := StringToSign("GET", "/johnsmith/photos/puppy.jpg", nil,
+		map[string][]string{
+			"Host": {"johnsmith.s3.amazonaws.com"},
+			"Date": {"Tue, 27 Mar 2007 19:36:42 +0000"},
+		})
+	require.Equal(t,
+		"GET\n\n\nTue, 27 Mar 2007 19:36:42 +0000\n/johnsmith/photos/puppy.jpg",
+		payload)
+	sig, err := signer.Sign([]byte(payload))
+	require.NoError(t, err)
+	require.Equal(t, "bWq2s1WEIj+Ydj0vQ697zp+IXMU=", string(sig))
+
+	payload = StringToSign("PUT", "/johnsmith/photos/puppy.jpg", nil,
+		map[string][]string{
+			"Content-Type":   {"image/jpeg"},
+			"Content-Length": {"94328"},
+			"Host":           {"johnsmith.s3.amazonaws.com"},
+			"Date":           {"Tue, 27 Mar 2007 21:15:45 +0000"},
+		})
+	sig, err = signer.Sign([]byte(payload))
+	require.NoError(t, err)
+	require.Equal(t, "MyyxeRY7whkBe+bq8fHCL/2kKUg=", string(sig))
+
+	t.Log("Only S3 sub-resources are included in the signed path")
+	payload = StringToSign("GET", "/johnsmith/", map[string][]string{
+		"uploads": {""},
+		"prefix":  {"photos"},
+	}, map[string][]string{"X-Amz-Date": {"Tue, 27 Mar 2007 19:42:41 +0000"}})
+	require.Equal(t,
+		"GET\n\n\n\nx-amz-date:Tue, 27 Mar 2007 19:42:41 +0000\n/johnsmith/?uploads",
+		payload)
+}
@@ -0,0 +1,307 @@
+package search
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set"
+	"github.com/adamwalz/keybase-client/go/chat/globals"
+	"github.com/adamwalz/keybase-client/go/chat/types"
+	"github.com/adamwalz/keybase-client/go/chat/utils"
+	"github.com/adamwalz/keybase-client/go/protocol/chat1"
+	"github.com/adamwalz/keybase-client/go/protocol/gregor1"
+	"mvdan.cc/xurls/v2"
+)
+
+// orOperator joins two query terms so that either of them can match.
+const orOperator = "OR"
+
+var linkRegexp = xurls.Strict()
+
+// queryTerm is a single word or quoted phrase of a search query.
+type queryTerm struct {
+	text   string
+	phrase bool
+	// tokens are the index tokens that a message has to contain to match the
+	// term.
+	tokens tokenMap
+	// phraseRe matches the words of a phrase in order, separated by anything
+	// the tokenizer splits on.
+	phraseRe *regexp.Regexp
+}
+
+func newQueryTerm(text string, phrase bool) (term queryTerm, ok bool) {
+	term = queryTerm{
+		text:   text,
+		phrase: phrase,
+		tokens: tokenize(text),
+	}
+	if len(term.tokens) == 0 {
+		return term, false
+	}
+	if phrase {
+		words := strings.Fields(text)
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		term.phraseRe = regexp.MustCompile("(?i)" + strings.Join(words, `[\s\.,\?!]+`))
+	}
+	return term, true
+}
+
+// highlightExpr returns the regexp used to highlight the term in a message.
+func (t queryTerm) highlightExpr() string {
+	if t.phrase {
+		return strings.TrimPrefix(t.phraseRe.String(), "(?i)")
+	}
+	return regexp.QuoteMeta(t.text)
+}
+
+// matches checks the term against the tokens of a message, and for phrases,
+// that the words appear next to each other in the message text.
+func (t queryTerm) matches(msgText string, msgTokens tokenMap) bool {
+	for token := range t.tokens {
+		if !msgTokens.contains(token) {
+			return false
+		}
+	}
+	return !t.phrase || t.phraseRe.MatchString(msgText)
+}
+
+// contains returns whether token is one of the tokens or aliases in the map,
+// which is what the index matches on.
+func (m tokenMap) contains(token string) bool {
+	if _, ok := m[token]; ok {
+		return true
+	}
+	for _, aliases := range m {
+		if _, ok := aliases[token]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// searchQuery is a parsed inbox search query. A message matches the query if
+// it matches at least one term of every clause.
+type searchQuery struct {
+	clauses [][]queryTerm
+	// hasOperators is set if the query used phrases or OR, in which case
+	// the query text can't be highlighted as a whole.
+	hasOperators bool
+}
+
+// splitQuery splits a query on whitespace, keeping quoted phrases (including
+// the quotes) together.
+func splitQuery(query string) (parts []string) {
+	var cur strings.Builder
+	inQuote := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			cur.WriteRune(r)
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n'):
+			if cur.Len() > 0 {
+				parts = append(parts, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		parts = append(parts, cur.String())
+	}
+	return parts
+}
+
+func isPhrase(part string) bool {
+	return len(part) >= 2 && strings.HasPrefix(part, `"`) && strings.HasSuffix(part, `"`)
+}
+
+// parseQuery parses the text of a search query, after all filters have been
+// removed by UpgradeSearchOptsFromQuery, into clauses. Words too short to be
+// indexed are dropped, as the tokenizer does.
+func parseQuery(query string) (q searchQuery) {
+	var clause []queryTerm
+	joinNext := false
+	for _, part := range splitQuery(query) {
+		if part == orOperator {
+			q.hasOperators = true
+			joinNext = len(clause) > 0
+			continue
+		}
+		phrase := isPhrase(part)
+		text := part
+		if phrase {
+			q.hasOperators = true
+			text = strings.TrimSpace(part[1 : len(part)-1])
+		}
+		term, ok := newQueryTerm(text, phrase)
+		if !ok {
+			continue
+		}
+		if !joinNext && len(clause) > 0 {
+			q.clauses = append(q.clauses, clause)
+			clause = nil
+		}
+		clause = append(clause, term)
+		joinNext = false
+	}
+	if len(clause) > 0 {
+		q.clauses = append(q.clauses, clause)
+	}
+	return q
+}
+
+func (q searchQuery) empty() bool {
+	return len(q.clauses) == 0
+}
+
+// matches checks a message against every clause of the query. The index can
+// only tell us which messages contain all the tokens of a term, so this is
+// also what checks that phrases appear in order.
+func (q searchQuery) matches(msg chat1.MessageUnboxed) bool {
+	msgText := msg.SearchableText()
+	msgTokens := tokenize(msgText)
+	for _, clause := range q.clauses {
+		matched := false
+		for _, term := range clause {
+			if term.matches(msgText, msgTokens) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// GetQueryRe returns a regexp to highlight the matches of a search query in
+// message text. Queries with phrases or OR highlight every term on its own,
+// others are highlighted as a whole.
+func GetQueryRe(query string) (*regexp.Regexp, error) {
+	q := parseQuery(query)
+	if !q.hasOperators || q.empty() {
+		return utils.GetQueryRe(query)
+	}
+	var exprs []string
+	for _, clause := range q.clauses {
+		for _, term := range clause {
+			exprs = append(exprs, term.highlightExpr())
+		}
+	}
+	return regexp.Compile("(?i)(" + strings.Join(exprs, "|") + ")")
+}
+
+// getTermHits returns the IDs of all messages in the conversation that
+// contain every token of the term.
+func getTermHits(ctx context.Context, s *store, convID chat1.ConversationID,
+	term queryTerm) (res mapset.Set, err error) {
+	for token := range term.tokens {
+		matchedIDs := mapset.NewThreadUnsafeSet()
+		idMap, err := s.GetHits(ctx, convID, token)
+		if err != nil {
+			return nil, err
+		}
+		for msgID := range idMap {
+			matchedIDs.Add(msgID)
+		}
+		if res == nil {
+			res = matchedIDs
+		} else {
+			res = res.Intersect(matchedIDs)
+		}
+		if res.Cardinality() == 0 {
+			break
+		}
+	}
+	return res, nil
+}
+
+// msgMatchesFilters checks a message against the filters of opts that can't
+// be evaluated in the protocol package.
+func msgMatchesFilters(msg chat1.MessageUnboxed, opts chat1.SearchOpts) bool {
+	if !opts.Matches(msg) || !msg.IsValidFull() {
+		return false
+	}
+	body := msg.Valid().MessageBody
+	if opts.HasAttachment && !body.IsType(chat1.MessageType_ATTACHMENT) {
+		return false
+	}
+	if opts.HasLink && !body.IsType(chat1.MessageType_UNFURL) &&
+		!linkRegexp.MatchString(msg.SearchableText()) {
+		return false
+	}
+	if len(opts.ExcludeTerms) > 0 {
+		msgText := msg.SearchableText()
+		msgTokens := tokenize(msgText)
+		for _, exclude := range opts.ExcludeTerms {
+			term, ok := newQueryTerm(exclude, false)
+			if ok && term.matches(msgText, msgTokens) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ConvMatchesName checks a conversation against the `in:` filter of a search.
+// The name can be a team, a team channel (`team#channel`), a channel in any
+// team (`#channel`), or a comma separated list of users that are all in the
+// conversation.
+func ConvMatchesName(conv types.RemoteConversation, name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return true
+	}
+	teamName, topicName := name, ""
+	if i := strings.Index(name, "#"); i >= 0 {
+		teamName, topicName = name[:i], name[i+1:]
+	}
+	if topicName != "" && (conv.GetTeamType() != chat1.TeamType_COMPLEX ||
+		strings.ToLower(conv.GetTopicName()) != topicName) {
+		return false
+	}
+	if teamName == "" {
+		return true
+	}
+	tlfName := strings.ToLower(utils.GetRemoteConvTLFName(conv))
+	if conv.GetMembersType() == chat1.ConversationMembersType_TEAM {
+		return tlfName == teamName
+	}
+	members := make(map[string]bool)
+	for _, member := range strings.FieldsFunc(tlfName, func(r rune) bool {
+		return r == ',' || r == '#'
+	}) {
+		members[member] = true
+	}
+	for _, username := range strings.Split(teamName, ",") {
+		if !members[strings.TrimPrefix(strings.TrimSpace(username), "@")] {
+			return false
+		}
+	}
+	return true
+}
+
+// pinnedMsgID returns the ID of the message pinned in the conversation, if
+// there is one.
+func pinnedMsgID(ctx context.Context, g *globals.Context, uid gregor1.UID,
+	conv types.RemoteConversation) (chat1.MessageID, bool) {
+	pin, err := conv.GetMaxMessage(chat1.MessageType_PIN)
+	if err != nil {
+		return 0, false
+	}
+	reason := chat1.GetThreadReason_INDEXED_SEARCH
+	msgs, err := g.ChatHelper.GetMessages(ctx, uid, conv.GetConvID(),
+		[]chat1.MessageID{pin.GetMessageID()}, true /* resolveSupersedes */, &reason)
+	if err != nil || len(msgs) == 0 || !msgs[0].IsValidFull() ||
+		!msgs[0].Valid().MessageBody.IsType(chat1.MessageType_PIN) {
+		return 0, false
+	}
+	return msgs[0].Valid().MessageBody.Pin().MsgID, true
+}
@@ -67,6 +67,18 @@ func (s *RegexpSearcher) Search(ctx context.Context, uid gregor1.UID, convID cha
 		maxMessages = MaxAllowedSearchMessages
 	}
 
+	var pinnedID chat1.MessageID
+	if opts.IsPinned {
+		conv, err := utils.GetUnverifiedConv(ctx, s.G(), uid, convID, types.InboxSourceDataSourceAll)
+		if err != nil {
+			return nil, nil, err
+		}
+		var ok bool
+		if pinnedID, ok = pinnedMsgID(ctx, s.G(), uid, conv); !ok {
+			return nil, nil, nil
+		}
+	}
+
 	// If we have to gather search result context around a pagination boundary,
 	// we may have to fetch the next page of the thread
 	var prevPage, curPage, nextPage *chat1.ThreadView
@@ -164,7 +176,10 @@ func (s *RegexpSearcher) Search(ctx context.Context, uid gregor1.UID, convID cha
 
 		for i, msg := range curPage.Messages {
 			numMessages++
-			if !opts.Matches(msg) {
+			if !msgMatchesFilters(msg, opts) {
+				continue
+			}
+			if opts.IsPinned && msg.GetMessageID() != pinnedID {
 				continue
 			}
 			matches := searchMatches(msg, queryRe)
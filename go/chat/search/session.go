@@ -24,7 +24,7 @@ type searchSession struct {
 	indexer          *Indexer
 	opts             chat1.SearchOpts
 
-	tokens           tokenMap
+	parsedQuery      searchQuery
 	queryRe          *regexp.Regexp
 	numConvsSearched int
 	inboxIndexStatus *inboxIndexStatus
@@ -77,30 +77,49 @@ func (s *searchSession) incrementNumConvsSearched() {
 	s.numConvsSearched++
 }
 
-// searchConv finds all messages that match the given set of tokens and opts,
-// results are ordered desc by msg id.
+// searchConv finds all messages that match the parsed query and opts, results
+// are ordered desc by msg id.
 func (s *searchSession) searchConv(ctx context.Context, convID chat1.ConversationID) (msgIDs []chat1.MessageID, err error) {
 	defer s.indexer.Trace(ctx, &err, fmt.Sprintf("searchConv convID: %s", convID))()
 	var allMsgIDs mapset.Set
-	for token := range s.tokens {
-		matchedIDs := mapset.NewThreadUnsafeSet()
-		idMap, err := s.indexer.store.GetHits(ctx, convID, token)
-		if err != nil {
-			return nil, err
-		}
-		for msgID := range idMap {
-			matchedIDs.Add(msgID)
+	for _, clause := range s.parsedQuery.clauses {
+		clauseIDs := mapset.NewThreadUnsafeSet()
+		for _, term := range clause {
+			termIDs, err := getTermHits(ctx, s.indexer.store, convID, term)
+			if err != nil {
+				return nil, err
+			}
+			clauseIDs = clauseIDs.Union(termIDs)
 		}
 		if allMsgIDs == nil {
-			allMsgIDs = matchedIDs
+			allMsgIDs = clauseIDs
 		} else {
-			allMsgIDs = allMsgIDs.Intersect(matchedIDs)
-			if allMsgIDs.Cardinality() == 0 {
-				// no matches in this conversation..
-				return nil, nil
-			}
+			allMsgIDs = allMsgIDs.Intersect(clauseIDs)
+		}
+		if allMsgIDs.Cardinality() == 0 {
+			// no matches in this conversation..
+			return nil, nil
 		}
 	}
+	for _, exclude := range s.opts.ExcludeTerms {
+		term, ok := newQueryTerm(exclude, false)
+		if !ok {
+			continue
+		}
+		excludedIDs, err := getTermHits(ctx, s.indexer.store, convID, term)
+		if err != nil {
+			return nil, err
+		}
+		allMsgIDs = allMsgIDs.Difference(excludedIDs)
+	}
+	if s.opts.IsPinned {
+		pinnedID, ok := pinnedMsgID(ctx, s.indexer.G(), s.uid, s.getConv(convID))
+		if !ok || !allMsgIDs.Contains(pinnedID) {
+			return nil, nil
+		}
+		allMsgIDs = mapset.NewThreadUnsafeSet()
+		allMsgIDs.Add(pinnedID)
+	}
 	msgIDSlice := msgIDsFromSet(allMsgIDs)
 
 	// Sort so we can truncate if necessary, returning the newest results first.
@@ -206,7 +225,8 @@ func (s *searchSession) searchHitBatch(ctx context.Context, convID chat1.Convers
 		return nil, err
 	}
 	for i, msg := range msgs {
-		if idSet.Contains(msg.GetMessageID()) && msg.IsValidFull() && s.opts.Matches(msg) {
+		if idSet.Contains(msg.GetMessageID()) && msgMatchesFilters(msg, s.opts) &&
+			s.parsedQuery.matches(msg) {
 			var afterMessages, beforeMessages []chat1.UIMessage
 			if s.opts.AfterContext > 0 {
 				afterLimit := i - s.opts.AfterContext
@@ -445,11 +465,11 @@ func (s *searchSession) postSearch(ctx context.Context) (err error) {
 func (s *searchSession) initRun(ctx context.Context) (shouldRun bool, err error) {
 	s.Lock()
 	defer s.Unlock()
-	s.tokens = tokenize(s.query)
-	if s.tokens == nil {
+	s.parsedQuery = parseQuery(s.query)
+	if s.parsedQuery.empty() {
 		return false, nil
 	}
-	s.queryRe, err = utils.GetQueryRe(s.query)
+	s.queryRe, err = GetQueryRe(s.query)
 	if err != nil {
 		return false, err
 	}
@@ -458,6 +478,13 @@ func (s *searchSession) initRun(ctx context.Context) (shouldRun bool, err error)
 	if err != nil {
 		return false, err
 	}
+	if s.opts.SentIn != "" {
+		for convID, conv := range s.convMap {
+			if !ConvMatchesName(conv, s.opts.SentIn) {
+				delete(s.convMap, convID)
+			}
+		}
+	}
 	s.convList = s.indexer.convsPrioritySorted(ctx, s.convMap)
 	if len(s.convList) == 0 {
 		return false, nil
@@ -158,11 +158,19 @@ const beforeFilter = "before:"
 const afterFilter = "after:"
 const fromFilter = "from:"
 const toFilter = "to:"
+const inFilter = "in:"
+const hasAttachmentFilter = "has:attachment"
+const hasLinkFilter = "has:link"
+const isPinnedFilter = "is:pinned"
 
 var senderRegex = regexp.MustCompile(fmt.Sprintf(
 	"(%s|%s)(@?[a-z0-9][a-z0-9_]+)", fromFilter, toFilter))
 var dateRangeRegex = regexp.MustCompile(fmt.Sprintf(
 	`(%s|%s)(\d{1,4}[-/\.]+\d{1,2}[-/\.]+\d{1,4})`, beforeFilter, afterFilter))
+var inRegex = regexp.MustCompile(fmt.Sprintf(
+	`(?:^|\s)%s([a-zA-Z0-9#@][a-zA-Z0-9_\.,#@-]*)`, inFilter))
+var flagRegex = regexp.MustCompile(fmt.Sprintf(
+	`(?:^|\s)(%ss?|%ss?|%s)\b`, hasAttachmentFilter, hasLinkFilter, isPinnedFilter))
 
 func UpgradeSearchOptsFromQuery(query string, opts chat1.SearchOpts, username string) (string, chat1.SearchOpts) {
 	query = strings.Trim(query, " ")
@@ -214,17 +222,64 @@ func UpgradeSearchOptsFromQuery(query string, opts chat1.SearchOpts, username st
 		}
 	}
 
+	// In
+	if match := inRegex.FindStringSubmatch(query); len(match) == 2 {
+		hasQueryOpts = true
+		query = strings.TrimSpace(strings.Replace(query, match[0], "", 1))
+		opts.SentIn = match[1]
+	}
+
+	// Has/Is
+	matches = flagRegex.FindAllStringSubmatch(query, -1)
+	for _, match := range matches {
+		// [fullMatch, flag]
+		if len(match) != 2 {
+			continue
+		}
+		hasQueryOpts = true
+		query = strings.TrimSpace(strings.Replace(query, match[0], "", 1))
+		switch strings.TrimSuffix(match[1], "s") {
+		case hasAttachmentFilter:
+			opts.HasAttachment = true
+		case hasLinkFilter:
+			opts.HasLink = true
+		case isPinnedFilter:
+			opts.IsPinned = true
+		}
+	}
+
+	// Excluded terms, unless we have a regex.
+	if !isRegexQuery(query) {
+		parts := splitQuery(query)
+		kept := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if len(part) > 1 && part[0] == '-' {
+				opts.ExcludeTerms = append(opts.ExcludeTerms, strings.Trim(part[1:], `"`))
+				continue
+			}
+			kept = append(kept, part)
+		}
+		if len(kept) < len(parts) {
+			hasQueryOpts = true
+			query = strings.Join(kept, " ")
+		}
+	}
+
 	if hasQueryOpts && len(query) == 0 {
 		query = "/.*/"
 	}
 	// IsRegex
-	if len(query) > 2 && query[0] == '/' && query[len(query)-1] == '/' {
+	if isRegexQuery(query) {
 		query = query[1 : len(query)-1]
 		opts.IsRegex = true
 	}
 	return query, opts
 }
 
+func isRegexQuery(query string) bool {
+	return len(query) > 2 && query[0] == '/' && query[len(query)-1] == '/'
+}
+
 func MinMaxIDs(conv chat1.Conversation) (min, max chat1.MessageID) {
 	// lowest msgID we care about
 	min = conv.GetMaxDeletedUpTo()
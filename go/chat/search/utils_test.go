@@ -259,4 +259,77 @@ func TestUpgradeSearchOptsFromQuery(t *testing.T) {
 	require.Equal(t, expectedTime, opts.SentBefore)
 	require.True(t, opts.IsRegex)
 
+	query, opts = UpgradeSearchOptsFromQuery(`in:acme#general has:link "release notes" -draft is:pinned`,
+		chat1.SearchOpts{}, username)
+	require.Equal(t, `"release notes"`, query)
+	require.Equal(t, "acme#general", opts.SentIn)
+	require.True(t, opts.HasLink)
+	require.True(t, opts.IsPinned)
+	require.False(t, opts.HasAttachment)
+	require.Equal(t, []string{"draft"}, opts.ExcludeTerms)
+	require.False(t, opts.IsRegex)
+
+	// filters without any terms search everything
+	query, opts = UpgradeSearchOptsFromQuery("has:attachments -screenshot", chat1.SearchOpts{}, username)
+	require.Equal(t, ".*", query)
+	require.True(t, opts.HasAttachment)
+	require.Equal(t, []string{"screenshot"}, opts.ExcludeTerms)
+	require.True(t, opts.IsRegex)
+
+	// filters need to be whole words
+	query, opts = UpgradeSearchOptsFromQuery("login:foo has:linkage co-op", chat1.SearchOpts{}, username)
+	require.Equal(t, "login:foo has:linkage co-op", query)
+	require.Empty(t, opts.SentIn)
+	require.False(t, opts.HasLink)
+	require.Nil(t, opts.ExcludeTerms)
+}
+
+func TestParseQuery(t *testing.T) {
+	q := parseQuery(`hello "big world" cats OR dogs OR "pet fish" a`)
+	require.True(t, q.hasOperators)
+	require.Len(t, q.clauses, 3)
+	require.Len(t, q.clauses[0], 1)
+	require.Equal(t, "hello", q.clauses[0][0].text)
+	require.Len(t, q.clauses[1], 1)
+	require.Equal(t, "big world", q.clauses[1][0].text)
+	require.True(t, q.clauses[1][0].phrase)
+	require.Len(t, q.clauses[2], 3)
+	require.Equal(t, "cats", q.clauses[2][0].text)
+	require.Equal(t, "dogs", q.clauses[2][1].text)
+	require.Equal(t, "pet fish", q.clauses[2][2].text)
+
+	q = parseQuery("hello, bye")
+	require.False(t, q.hasOperators)
+	require.Len(t, q.clauses, 2)
+	require.True(t, parseQuery("OR").empty())
+	require.True(t, parseQuery("hi").empty())
+
+	msg := func(body string) chat1.MessageUnboxed {
+		return chat1.NewMessageUnboxedWithValid(chat1.MessageUnboxedValid{
+			ClientHeader: chat1.MessageClientHeaderVerified{
+				MessageType: chat1.MessageType_TEXT,
+			},
+			MessageBody: chat1.NewMessageBodyWithText(chat1.MessageText{Body: body}),
+		})
+	}
+	q = parseQuery(`"big world" cats OR dogs`)
+	require.True(t, q.matches(msg("Hello big world, I have dogs")))
+	require.True(t, q.matches(msg("Big, world! cats")))
+	require.False(t, q.matches(msg("world big, cats and dogs")))
+	require.False(t, q.matches(msg("hello big world")))
+
+	re, err := GetQueryRe(`"big world" cats OR dogs`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"big world", "Dogs"},
+		re.FindAllString("big world Dogs", -1))
+	re, err = GetQueryRe("hello, bye")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Hello, Bye"}, re.FindAllString("Hello, Bye", -1))
+
+	opts := chat1.SearchOpts{ExcludeTerms: []string{"draft"}}
+	require.False(t, msgMatchesFilters(msg("drafting the notes"), opts))
+	require.True(t, msgMatchesFilters(msg("final notes"), opts))
+	opts = chat1.SearchOpts{HasLink: true}
+	require.True(t, msgMatchesFilters(msg("see https://keybase.io"), opts))
+	require.False(t, msgMatchesFilters(msg("see keybase"), opts))
 }
@@ -954,6 +954,66 @@ func TestChatSearchInbox(t *testing.T) {
 		verifySearchDone(1, false)
 		opts.SentTo = ""
 
+		// Test query operators and filters
+		msgIDNotes := sendMessage(chat1.NewMessageBodyWithText(chat1.MessageText{
+			Body: "the release notes are out: https://keybase.io/notes",
+		}), u1)
+		msgIDDraft := sendMessage(chat1.NewMessageBodyWithText(chat1.MessageText{
+			Body: "notes on the release draft",
+		}), u2)
+		verifyIndex()
+		verifyHitIDs := func(res *chat1.ChatSearchInboxResults, msgIDs ...chat1.MessageID) {
+			if len(msgIDs) == 0 {
+				require.Zero(t, len(res.Hits))
+				verifySearchDone(0, false)
+				return
+			}
+			require.Equal(t, 1, len(res.Hits))
+			convHit = res.Hits[0]
+			require.Equal(t, convID, convHit.ConvID)
+			require.Equal(t, len(msgIDs), len(convHit.Hits))
+			for i, msgID := range msgIDs {
+				require.EqualValues(t, msgID, convHit.Hits[i].HitMessage.Valid().MessageID)
+			}
+			verifySearchDone(len(msgIDs), false)
+		}
+
+		res = runSearch("release notes", opts, false /* expectedReindex*/)
+		verifyHitIDs(res, msgIDDraft, msgIDNotes)
+		res = runSearch(`"release notes"`, opts, false /* expectedReindex*/)
+		verifyHitIDs(res, msgIDNotes)
+		require.Equal(t, []chat1.ChatSearchMatch{{
+			StartIndex: 4,
+			EndIndex:   17,
+			Match:      "release notes",
+		}}, res.Hits[0].Hits[0].Matches)
+		res = runSearch("release -draft", opts, false /* expectedReindex*/)
+		verifyHitIDs(res, msgIDNotes)
+		res = runSearch(`"release draft" OR "notes are" notes`, opts, false /* expectedReindex*/)
+		verifyHitIDs(res, msgIDDraft, msgIDNotes)
+		res = runSearch(`"notes release"`, opts, false /* expectedReindex*/)
+		verifyHitIDs(res)
+		res = runSearch("notes has:link", opts, false /* expectedReindex*/)
+		verifyHitIDs(res, msgIDNotes)
+		res = runSearch("notes has:attachment", opts, false /* expectedReindex*/)
+		verifyHitIDs(res)
+		res = runSearch("notes in:"+u2.Username, opts, false /* expectedReindex*/)
+		verifyHitIDs(res, msgIDDraft, msgIDNotes)
+		res = runSearch("notes in:#general", opts, false /* expectedReindex*/)
+		verifyHitIDs(res)
+
+		res = runSearch("notes is:pinned", opts, false /* expectedReindex*/)
+		verifyHitIDs(res)
+		_, err = tc1.chatLocalHandler().PinMessage(tc1.startCtx, chat1.PinMessageArg{
+			ConvID: convID,
+			MsgID:  msgIDDraft,
+		})
+		require.NoError(t, err)
+		consumeNewMsgRemote(t, listener1, chat1.MessageType_PIN)
+		consumeNewMsgRemote(t, listener2, chat1.MessageType_PIN)
+		res = runSearch("notes is:pinned", opts, false /* expectedReindex*/)
+		verifyHitIDs(res, msgIDDraft)
+
 		// Test canceling sync loop
 		syncLoopCh := make(chan struct{})
 		indexer1.SetSyncLoopCh(syncLoopCh)
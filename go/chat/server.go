@@ -1481,6 +1481,39 @@ func (h *Server) RetryPost(ctx context.Context, arg chat1.RetryPostArg) (err err
 	return nil
 }
 
+func (h *Server) ScheduleLocalNonblock(ctx context.Context, arg chat1.ScheduleLocalNonblockArg) (res chat1.ScheduledMessage, err error) {
+	ctx = globals.ChatCtx(ctx, h.G(), arg.IdentifyBehavior, nil, h.identNotifier)
+	defer h.Trace(ctx, &err, "ScheduleLocalNonblock")()
+	if _, err = utils.AssertLoggedInUID(ctx, h.G()); err != nil {
+		return res, err
+	}
+	if !arg.SendAt.Time().After(h.G().Clock().Now()) {
+		return res, errors.New("scheduled messages must be sent in the future")
+	}
+	if arg.Msg.ClientHeader.Conv.TopicType == chat1.TopicType_NONE {
+		arg.Msg.ClientHeader.Conv.TopicType = chat1.TopicType_CHAT
+	}
+	return h.G().MessageDeliverer.Schedule(ctx, arg.ConversationID, arg.Msg, arg.SendAt, arg.IdentifyBehavior)
+}
+
+func (h *Server) GetScheduledMessagesLocal(ctx context.Context) (res []chat1.ScheduledMessage, err error) {
+	ctx = globals.ChatCtx(ctx, h.G(), keybase1.TLFIdentifyBehavior_CHAT_SKIP, nil, h.identNotifier)
+	defer h.Trace(ctx, &err, "GetScheduledMessagesLocal")()
+	if _, err = utils.AssertLoggedInUID(ctx, h.G()); err != nil {
+		return res, err
+	}
+	return h.G().MessageDeliverer.ScheduledMessages(ctx)
+}
+
+func (h *Server) CancelScheduledMessageLocal(ctx context.Context, outboxID chat1.OutboxID) (err error) {
+	ctx = globals.ChatCtx(ctx, h.G(), keybase1.TLFIdentifyBehavior_CHAT_SKIP, nil, h.identNotifier)
+	defer h.Trace(ctx, &err, "CancelScheduledMessageLocal(%s)", outboxID)()
+	if _, err = utils.AssertLoggedInUID(ctx, h.G()); err != nil {
+		return err
+	}
+	return h.G().MessageDeliverer.CancelScheduled(ctx, outboxID)
+}
+
 // remoteClient returns a client connection to gregord.
 func (h *Server) remoteClient() chat1.RemoteInterface {
 	if h.rc != nil {
@@ -2213,7 +2246,7 @@ func (h *Server) getSearchRegexp(query string, opts chat1.SearchOpts) (re *regex
 		re, err = regexp.Compile(query)
 	} else {
 		// String queries are set case insensitive
-		re, err = utils.GetQueryRe(query)
+		re, err = search.GetQueryRe(query)
 	}
 	if err != nil {
 		return nil, err
@@ -2302,6 +2335,15 @@ func (h *Server) delegateInboxSearch(ctx context.Context, uid gregor1.UID, query
 	if err != nil {
 		return res, err
 	}
+	if opts.SentIn != "" {
+		filtered := convs[:0]
+		for _, conv := range convs {
+			if search.ConvMatchesName(conv, opts.SentIn) {
+				filtered = append(filtered, conv)
+			}
+		}
+		convs = filtered
+	}
 	re, err := h.getSearchRegexp(query, opts)
 	if err != nil {
 		return res, err
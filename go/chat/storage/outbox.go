@@ -49,6 +49,9 @@ const outboxOrdinalStart = 100
 type diskOutbox struct {
 	Version int                  `codec:"V"`
 	Records []chat1.OutboxRecord `codec:"O"`
+	// Scheduled messages wait here until they are due, and only then become
+	// Records.
+	Scheduled []chat1.ScheduledMessage `codec:"S,omitempty"`
 }
 
 func (d diskOutbox) DeepCopy() diskOutbox {
@@ -56,9 +59,14 @@ func (d diskOutbox) DeepCopy() diskOutbox {
 	for _, obr := range d.Records {
 		obrs = append(obrs, obr.DeepCopy())
 	}
+	var scheduled []chat1.ScheduledMessage
+	for _, sm := range d.Scheduled {
+		scheduled = append(scheduled, sm.DeepCopy())
+	}
 	return diskOutbox{
-		Version: d.Version,
-		Records: obrs,
+		Version:   d.Version,
+		Records:   obrs,
+		Scheduled: scheduled,
 	}
 }
 
@@ -167,6 +175,22 @@ func (o *Outbox) PushMessage(ctx context.Context, convID chat1.ConversationID,
 		outboxID = *suppliedOutboxID
 	}
 
+	rec = o.appendRecord(ctx, &obox, convID, msg, outboxID, sendOpts, prepareOpts, identifyBehavior)
+
+	// Write out diskbox
+	obox.Version = outboxVersion
+	if err = o.writeStorage(ctx, obox); err != nil {
+		return rec, err
+	}
+
+	return rec, nil
+}
+
+// appendRecord adds a new record for msg to obox, which the caller then
+// writes out.
+func (o *Outbox) appendRecord(ctx context.Context, obox *diskOutbox, convID chat1.ConversationID,
+	msg chat1.MessagePlaintext, outboxID chat1.OutboxID, sendOpts *chat1.SenderSendOptions,
+	prepareOpts *chat1.SenderPrepareOptions, identifyBehavior keybase1.TLFIdentifyBehavior) chat1.OutboxRecord {
 	// Compute prev ordinal by predicting that all outbox messages will be appended to the thread
 	prevOrdinal := outboxOrdinalStart
 	for _, obr := range obox.Records {
@@ -177,7 +201,7 @@ func (o *Outbox) PushMessage(ctx context.Context, convID chat1.ConversationID,
 
 	// Append record
 	msg.ClientHeader.OutboxID = &outboxID
-	rec = chat1.OutboxRecord{
+	rec := chat1.OutboxRecord{
 		State:            chat1.NewOutboxStateWithSending(0),
 		Msg:              msg,
 		Ctime:            gregor1.ToTime(o.clock.Now()),
@@ -201,14 +225,128 @@ func (o *Outbox) PushMessage(ctx context.Context, convID chat1.ConversationID,
 	if o.newMessageNotifier != nil {
 		o.newMessageNotifier(ctx, rec)
 	}
+	return rec
+}
 
-	// Write out diskbox
+// ScheduleMessage stores msg to be sent at sendAt. It stays out of the way of
+// everything else in the outbox until ReleaseScheduledMessages is called for
+// it.
+func (o *Outbox) ScheduleMessage(ctx context.Context, convID chat1.ConversationID,
+	msg chat1.MessagePlaintext, sendAt gregor1.Time,
+	identifyBehavior keybase1.TLFIdentifyBehavior) (res chat1.ScheduledMessage, err Error) {
+	locks.Outbox.Lock()
+	defer locks.Outbox.Unlock()
+
+	obox, err := o.readStorage(ctx)
+	if err != nil {
+		if _, ok := err.(MissError); !ok {
+			return res, err
+		}
+		obox = diskOutbox{
+			Version: outboxVersion,
+			Records: []chat1.OutboxRecord{},
+		}
+	}
+	outboxID, ierr := NewOutboxID()
+	if ierr != nil {
+		return res, NewInternalError(ctx, o.DebugLabeler, "error getting outboxID: err: %s", ierr)
+	}
+	msg.ClientHeader.OutboxID = &outboxID
+	res = chat1.ScheduledMessage{
+		OutboxID:         outboxID,
+		ConvID:           convID,
+		SendAt:           sendAt,
+		Ctime:            gregor1.ToTime(o.clock.Now()),
+		Msg:              msg,
+		IdentifyBehavior: identifyBehavior,
+	}
+	obox.Scheduled = append(obox.Scheduled, res)
 	obox.Version = outboxVersion
 	if err = o.writeStorage(ctx, obox); err != nil {
-		return rec, err
+		return res, err
 	}
+	return res, nil
+}
 
-	return rec, nil
+// ScheduledMessages returns the messages waiting to be sent, soonest first.
+func (o *Outbox) ScheduledMessages(ctx context.Context) ([]chat1.ScheduledMessage, error) {
+	locks.Outbox.Lock()
+	defer locks.Outbox.Unlock()
+
+	obox, err := o.readStorage(ctx)
+	if err != nil {
+		if _, ok := err.(MissError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	res := append([]chat1.ScheduledMessage{}, obox.Scheduled...)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].SendAt < res[j].SendAt
+	})
+	return res, nil
+}
+
+// CancelScheduledMessage removes a scheduled message before it is sent.
+func (o *Outbox) CancelScheduledMessage(ctx context.Context, obid chat1.OutboxID) (res chat1.ScheduledMessage, err error) {
+	locks.Outbox.Lock()
+	defer locks.Outbox.Unlock()
+
+	obox, err := o.readStorage(ctx)
+	if err != nil {
+		return res, err
+	}
+	found := false
+	var scheduled []chat1.ScheduledMessage
+	for _, sm := range obox.Scheduled {
+		if sm.OutboxID.Eq(&obid) {
+			res = sm
+			found = true
+			continue
+		}
+		scheduled = append(scheduled, sm)
+	}
+	if !found {
+		return res, MissError{}
+	}
+	obox.Scheduled = scheduled
+	return res, o.writeStorage(ctx, obox)
+}
+
+// ReleaseScheduledMessages turns the given scheduled messages into regular
+// outbox records, to be picked up by the deliverer. The messages are passed
+// in rather than looked up so that the caller can fill in their OutboxInfo
+// first; any that have been cancelled in the meantime are skipped.
+func (o *Outbox) ReleaseScheduledMessages(ctx context.Context, due []chat1.ScheduledMessage) (res []chat1.OutboxRecord, err error) {
+	locks.Outbox.Lock()
+	defer locks.Outbox.Unlock()
+
+	obox, err := o.readStorage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	release := make(map[string]chat1.ScheduledMessage, len(due))
+	for _, sm := range due {
+		release[sm.OutboxID.String()] = sm
+	}
+	var scheduled []chat1.ScheduledMessage
+	for _, stored := range obox.Scheduled {
+		sm, ok := release[stored.OutboxID.String()]
+		if !ok {
+			scheduled = append(scheduled, stored)
+			continue
+		}
+		res = append(res, o.appendRecord(ctx, &obox, stored.ConvID, sm.Msg, stored.OutboxID, nil, nil,
+			stored.IdentifyBehavior))
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	obox.Scheduled = scheduled
+	if err := o.writeStorage(ctx, obox); err != nil {
+		return nil, err
+	}
+	return res, nil
 }
 
 // PullAllConversations grabs all outbox entries for the current outbox, and optionally deletes them
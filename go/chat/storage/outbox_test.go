@@ -331,3 +331,53 @@ func TestChatOutboxCancelMessagesWithPredicate(t *testing.T) {
 	require.NoError(t, err)
 	require.Zero(t, len(res))
 }
+
+func TestChatOutboxScheduled(t *testing.T) {
+	tc, ob, uid, cl := setupOutboxTest(t, "outbox")
+	defer tc.Cleanup()
+	ctx := context.TODO()
+
+	conv := makeConvo(gregor1.Time(5), 1, 1)
+	var scheduled []chat1.ScheduledMessage
+	for i := 3; i > 0; i-- {
+		sm, err := ob.ScheduleMessage(ctx, conv.GetConvID(), makeMsgPlaintext(fmt.Sprintf("later%d", i), uid),
+			gregor1.ToTime(cl.Now().Add(time.Duration(i)*time.Hour)), keybase1.TLFIdentifyBehavior_CHAT_CLI)
+		require.NoError(t, err)
+		require.True(t, sm.OutboxID.Eq(sm.Msg.ClientHeader.OutboxID))
+		scheduled = append(scheduled, sm)
+	}
+
+	// Scheduled messages aren't part of the regular outbox until released.
+	res, err := ob.PullAllConversations(ctx, true, false)
+	require.NoError(t, err)
+	require.Zero(t, len(res))
+	list, err := ob.ScheduledMessages(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	for i, sm := range list {
+		require.Equal(t, fmt.Sprintf("later%d", i+1), sm.Msg.MessageBody.Text().Body)
+	}
+
+	// later2 is cancelled, and can't be cancelled twice.
+	_, err = ob.CancelScheduledMessage(ctx, scheduled[1].OutboxID)
+	require.NoError(t, err)
+	_, err = ob.CancelScheduledMessage(ctx, scheduled[1].OutboxID)
+	require.IsType(t, MissError{}, err)
+
+	// Releasing later1 and the cancelled later2 only queues later1.
+	cl.Advance(2 * time.Hour)
+	released, err := ob.ReleaseScheduledMessages(ctx, []chat1.ScheduledMessage{scheduled[2], scheduled[1]})
+	require.NoError(t, err)
+	require.Len(t, released, 1)
+	require.True(t, released[0].OutboxID.Eq(&scheduled[2].OutboxID))
+	require.Equal(t, gregor1.ToTime(cl.Now()), released[0].Ctime)
+	res, err = ob.PullAllConversations(ctx, false, false)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, "later1", res[0].Msg.MessageBody.Text().Body)
+
+	list, err = ob.ScheduledMessages(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "later3", list[0].Msg.MessageBody.Text().Body)
+}
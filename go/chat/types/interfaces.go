@@ -120,6 +120,10 @@ type MessageDeliverer interface {
 	Queue(ctx context.Context, convID chat1.ConversationID, msg chat1.MessagePlaintext,
 		outboxID *chat1.OutboxID, sendOpts *chat1.SenderSendOptions,
 		prepareOpts *chat1.SenderPrepareOptions, identifyBehavior keybase1.TLFIdentifyBehavior) (chat1.OutboxRecord, error)
+	Schedule(ctx context.Context, convID chat1.ConversationID, msg chat1.MessagePlaintext,
+		sendAt gregor1.Time, identifyBehavior keybase1.TLFIdentifyBehavior) (chat1.ScheduledMessage, error)
+	ScheduledMessages(ctx context.Context) ([]chat1.ScheduledMessage, error)
+	CancelScheduled(ctx context.Context, outboxID chat1.OutboxID) error
 	ForceDeliverLoop(ctx context.Context)
 	ActiveDeliveries(ctx context.Context) ([]chat1.OutboxRecord, error)
 	NextFailure() (chan []chat1.OutboxRecord, func())
@@ -90,6 +90,47 @@ func ParseTimeFromRFC3339OrDurationFromPast(g *globals.Context, s string) (t tim
 	return time.Time{}, fmt.Errorf("given string is neither a valid time (%s) nor a valid duration (%v)", errt, errd)
 }
 
+// ParseScheduleTime parses when a scheduled message should be sent: an
+// RFC3339 time, a local "2006-01-02 15:04" time, a duration from now (see
+// ParseDurationExtended), or a time of day such as "9:30", "17:00" or "5pm",
+// which is the next time the clock in now's location reads that.
+func ParseScheduleTime(now time.Time, s string) (t time.Time, err error) {
+	s = strings.TrimSpace(s)
+	if t, err = time.Parse(time.RFC3339, s); err != nil {
+		t, err = time.ParseInLocation("2006-01-02 15:04", s, now.Location())
+	}
+	if err != nil {
+		if d, derr := ParseDurationExtended(s); derr == nil {
+			t, err = now.Add(d), nil
+		}
+	}
+	if err != nil {
+		t, err = parseTimeOfDay(now, s)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a time, a time of day or a duration", s)
+	}
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("%s is in the past", t.Format(time.RFC1123))
+	}
+	return t, nil
+}
+
+func parseTimeOfDay(now time.Time, s string) (t time.Time, err error) {
+	for _, layout := range []string{"15:04", "3:04pm", "3pm"} {
+		var tod time.Time
+		if tod, err = time.Parse(layout, strings.ToLower(s)); err != nil {
+			continue
+		}
+		t = time.Date(now.Year(), now.Month(), now.Day(), tod.Hour(), tod.Minute(), 0, 0, now.Location())
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return t, err
+}
+
 // upper bounds takes higher priority
 func Collar(lower int, ideal int, upper int) int {
 	if ideal > upper {
@@ -33,6 +33,29 @@ func TestParseDurationExtended(t *testing.T) {
 	test("123d12h2ns", 123*24*time.Hour+12*time.Hour+2*time.Nanosecond)
 }
 
+func TestParseScheduleTime(t *testing.T) {
+	loc := time.FixedZone("test", -5*3600)
+	now := time.Date(2020, 3, 4, 10, 30, 0, 0, loc)
+	for input, expected := range map[string]time.Time{
+		"2020-03-05T08:00:00Z": time.Date(2020, 3, 5, 8, 0, 0, 0, time.UTC),
+		"2020-03-04 17:00":     time.Date(2020, 3, 4, 17, 0, 0, 0, loc),
+		"90m":                  now.Add(90 * time.Minute),
+		"1d2h":                 now.Add(26 * time.Hour),
+		"11:15":                time.Date(2020, 3, 4, 11, 15, 0, 0, loc),
+		"9:00":                 time.Date(2020, 3, 5, 9, 0, 0, 0, loc),
+		"5pm":                  time.Date(2020, 3, 4, 17, 0, 0, 0, loc),
+		"10:30AM":              time.Date(2020, 3, 5, 10, 30, 0, 0, loc),
+	} {
+		res, err := ParseScheduleTime(now, input)
+		require.NoError(t, err, input)
+		require.True(t, expected.Equal(res), "%s: expected %v, got %v", input, expected, res)
+	}
+	for _, input := range []string{"", "soon", "-1h", "2020-03-04T10:00:00-05:00", "25:00"} {
+		_, err := ParseScheduleTime(now, input)
+		require.Error(t, err, input)
+	}
+}
+
 func TestParseAtMentionsNames(t *testing.T) {
 	text := "@Chat_1e2263952c hello! @Mike From @chat_5511c5e0ce. @ksjdskj 889@ds8 @_dskdjs @k1 @0011_"
 	matches := parseRegexpNames(context.TODO(), text, atMentionRegExp)
@@ -31,6 +31,15 @@ Send a message:
 Send a reply:
    {"method": "send", "params": {"options": {"channel": {"name": "you,them"}, "message": {"body": "is it cold today?"}, "reply_to": 314}}}
 
+Schedule a message to be sent later (at a time like "9:30", "5pm" or "2006-01-02T15:04:05Z07:00", or after a duration like "2h"):
+    {"method": "schedule", "params": {"options": {"channel": {"name": "treehouse", "members_type": "team"}, "message": {"body": "standup time!"}, "at": "9:30"}}}
+
+List messages scheduled to be sent later:
+    {"method": "listscheduled"}
+
+Cancel a scheduled message:
+    {"method": "cancelscheduled", "params": {"options": {"outbox_id": "<outbox_id from schedule or listscheduled>"}}}
+
 Delete a message:
     {"method": "delete", "params": {"options": {"channel": {"name": "you,them"}, "message_id": 314}}}
 
@@ -67,6 +76,12 @@ Mute a conversation:
 Search the inbox:
     {"method": "searchinbox", "params": {"options": {"query": "hi", "sent_by": "them", "sent_to": "you", "max_hits": 1000, "sent_after":"09/10/2017"}}}
 
+Search the inbox for a phrase, leaving out messages with a word:
+    {"method": "searchinbox", "params": {"options": {"query": "\"release notes\" OR changelog -draft in:treehouse#general has:link"}}}
+
+Search the inbox for pinned messages with attachments:
+    {"method": "searchinbox", "params": {"options": {"query": "diagram", "has_attachment": true, "is_pinned": true}}}
+
 Search conversation with a regex:
     {"method": "searchregexp", "params": {"options": {"channel": {"name": "you,them"}, "query": "a.*", "is_regex": true, "sent_by": "them", "sent_to": "you", "sent_before":"09/10/2017"}}}
 
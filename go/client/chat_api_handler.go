@@ -56,6 +56,9 @@ const (
 	methodEmojiList           = "emojilist"
 	methodEmojiRemove         = "emojiremove"
 	methodEmojiAddAlias       = "emojiaddalias"
+	methodSchedule            = "schedule"
+	methodListScheduled       = "listscheduled"
+	methodCancelScheduled     = "cancelscheduled"
 )
 
 // ChatAPIHandler can handle all of the chat json api methods.
@@ -95,6 +98,9 @@ type ChatAPIHandler interface {
 	EmojiAddAliasV1(context.Context, Call, io.Writer) error
 	EmojiListV1(context.Context, Call, io.Writer) error
 	EmojiRemoveV1(context.Context, Call, io.Writer) error
+	ScheduleV1(context.Context, Call, io.Writer) error
+	ListScheduledV1(context.Context, Call, io.Writer) error
+	CancelScheduledV1(context.Context, Call, io.Writer) error
 }
 
 // ChatAPI implements ChatAPIHandler and contains a ChatServiceHandler
@@ -397,8 +403,12 @@ type searchOptionsV1 struct {
 
 type searchInboxOptionsV1 struct {
 	searchOptionsV1
-	Query        string `json:"query"`
-	ForceReindex bool   `json:"force_reindex"`
+	Query         string `json:"query"`
+	ForceReindex  bool   `json:"force_reindex"`
+	SentIn        string `json:"sent_in"`
+	HasAttachment bool   `json:"has_attachment"`
+	HasLink       bool   `json:"has_link"`
+	IsPinned      bool   `json:"is_pinned"`
 }
 
 func (o searchInboxOptionsV1) Check() error {
@@ -1238,6 +1248,72 @@ func (a *ChatAPI) EmojiListV1(ctx context.Context, c Call, w io.Writer) error {
 	return a.encodeReply(c, a.svcHandler.EmojiListV1(ctx), w)
 }
 
+type scheduleOptionsV1 struct {
+	Channel        ChatChannel
+	ConversationID chat1.ConvIDStr `json:"conversation_id"`
+	Message        ChatMessage
+	At             string `json:"at"`
+}
+
+func (s scheduleOptionsV1) Check() error {
+	if err := checkChannelConv(methodSchedule, s.Channel, s.ConversationID); err != nil {
+		return err
+	}
+	if !s.Message.Valid() {
+		return ErrInvalidOptions{version: 1, method: methodSchedule, err: errors.New("invalid message, body cannot be empty")}
+	}
+	if len(s.At) == 0 {
+		return ErrInvalidOptions{version: 1, method: methodSchedule, err: errors.New("must specify a time to send at")}
+	}
+	if _, err := utils.ParseScheduleTime(time.Now(), s.At); err != nil {
+		return ErrInvalidOptions{version: 1, method: methodSchedule, err: err}
+	}
+	return nil
+}
+
+func (a *ChatAPI) ScheduleV1(ctx context.Context, c Call, w io.Writer) error {
+	if len(c.Params.Options) == 0 {
+		return ErrInvalidOptions{version: 1, method: methodSchedule, err: errors.New("empty options")}
+	}
+	var opts scheduleOptionsV1
+	if err := json.Unmarshal(c.Params.Options, &opts); err != nil {
+		return err
+	}
+	if err := opts.Check(); err != nil {
+		return err
+	}
+	return a.encodeReply(c, a.svcHandler.ScheduleV1(ctx, opts), w)
+}
+
+func (a *ChatAPI) ListScheduledV1(ctx context.Context, c Call, w io.Writer) error {
+	return a.encodeReply(c, a.svcHandler.ListScheduledV1(ctx), w)
+}
+
+type cancelScheduledOptionsV1 struct {
+	OutboxID chat1.OutboxID `json:"outbox_id"`
+}
+
+func (o cancelScheduledOptionsV1) Check() error {
+	if len(o.OutboxID) == 0 {
+		return ErrInvalidOptions{version: 1, method: methodCancelScheduled, err: errors.New("must specify an outbox_id")}
+	}
+	return nil
+}
+
+func (a *ChatAPI) CancelScheduledV1(ctx context.Context, c Call, w io.Writer) error {
+	if len(c.Params.Options) == 0 {
+		return ErrInvalidOptions{version: 1, method: methodCancelScheduled, err: errors.New("empty options")}
+	}
+	var opts cancelScheduledOptionsV1
+	if err := json.Unmarshal(c.Params.Options, &opts); err != nil {
+		return err
+	}
+	if err := opts.Check(); err != nil {
+		return err
+	}
+	return a.encodeReply(c, a.svcHandler.CancelScheduledV1(ctx, opts), w)
+}
+
 func (a *ChatAPI) encodeReply(call Call, reply Reply, w io.Writer) error {
 	return encodeReply(call, reply, w, a.indent)
 }
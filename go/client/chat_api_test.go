@@ -50,6 +50,9 @@ type handlerTracker struct {
 	emojiAddAliasV1     int
 	emojiListV1         int
 	emojiRemoveV1       int
+	scheduleV1          int
+	listScheduledV1     int
+	cancelScheduledV1   int
 }
 
 func (h *handlerTracker) ListV1(context.Context, Call, io.Writer) error {
@@ -225,6 +228,21 @@ func (h *handlerTracker) EmojiRemoveV1(context.Context, Call, io.Writer) error {
 	return nil
 }
 
+func (h *handlerTracker) ScheduleV1(context.Context, Call, io.Writer) error {
+	h.scheduleV1++
+	return nil
+}
+
+func (h *handlerTracker) ListScheduledV1(context.Context, Call, io.Writer) error {
+	h.listScheduledV1++
+	return nil
+}
+
+func (h *handlerTracker) CancelScheduledV1(context.Context, Call, io.Writer) error {
+	h.cancelScheduledV1++
+	return nil
+}
+
 type echoResult struct {
 	Status string `json:"status"`
 }
@@ -375,6 +393,18 @@ func (c *chatEcho) EmojiRemoveV1(context.Context, emojiRemoveOptionsV1) Reply {
 	return Reply{Result: echoOK}
 }
 
+func (c *chatEcho) ScheduleV1(context.Context, scheduleOptionsV1) Reply {
+	return Reply{Result: echoOK}
+}
+
+func (c *chatEcho) ListScheduledV1(context.Context) Reply {
+	return Reply{Result: echoOK}
+}
+
+func (c *chatEcho) CancelScheduledV1(context.Context, cancelScheduledOptionsV1) Reply {
+	return Reply{Result: echoOK}
+}
+
 type topTest struct {
 	input               string
 	output              string
@@ -798,6 +828,34 @@ var optTests = []optTest{
 		input:  `{"method": "unpin", "params":{"version": 1, "options": {"channel": {"name": "alice,bob"}}}}`,
 		output: `{"result":{"status":"ok"}}`,
 	},
+	{
+		input:  `{"method": "schedule", "params":{"version": 1}}`,
+		output: `{"error":{"code":0,"message":"invalid schedule v1 options: empty options"}}`,
+	},
+	{
+		input:  `{"method": "schedule", "params":{"version": 1, "options": {"channel": {"name": "alice,bob"}, "message": {"body": "hi"}}}}`,
+		output: `{"error":{"code":0,"message":"invalid schedule v1 options: must specify a time to send at"}}`,
+	},
+	{
+		input:  `{"method": "schedule", "params":{"version": 1, "options": {"channel": {"name": "alice,bob"}, "message": {"body": "hi"}, "at": "2001-01-01T00:00:00Z"}}}`,
+		output: `{"error":{"code":0,"message":"invalid schedule v1 options: Mon, 01 Jan 2001 00:00:00 UTC is in the past"}}`,
+	},
+	{
+		input:  `{"method": "schedule", "params":{"version": 1, "options": {"channel": {"name": "alice,bob"}, "message": {"body": "hi"}, "at": "2h"}}}`,
+		output: `{"result":{"status":"ok"}}`,
+	},
+	{
+		input:  `{"method": "listscheduled", "params":{"version": 1}}`,
+		output: `{"result":{"status":"ok"}}`,
+	},
+	{
+		input:  `{"method": "cancelscheduled", "params":{"version": 1, "options": {}}}`,
+		output: `{"error":{"code":0,"message":"invalid cancelscheduled v1 options: must specify an outbox_id"}}`,
+	},
+	{
+		input:  `{"method": "cancelscheduled", "params":{"version": 1, "options": {"outbox_id": "AQID"}}}`,
+		output: `{"result":{"status":"ok"}}`,
+	},
 }
 
 // TestChatAPIVersionHandlerOptions tests the option decoding.
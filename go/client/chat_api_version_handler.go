@@ -100,6 +100,12 @@ func (d *ChatAPIVersionHandler) handleV1(ctx context.Context, c Call, w io.Write
 		return d.handler.EmojiListV1(ctx, c, w)
 	case methodEmojiRemove:
 		return d.handler.EmojiRemoveV1(ctx, c, w)
+	case methodSchedule:
+		return d.handler.ScheduleV1(ctx, c, w)
+	case methodListScheduled:
+		return d.handler.ListScheduledV1(ctx, c, w)
+	case methodCancelScheduled:
+		return d.handler.CancelScheduledV1(ctx, c, w)
 	default:
 		return ErrInvalidMethod{name: c.Method, version: 1}
 	}
@@ -25,6 +25,8 @@ type ChatSendArg struct {
 	clearHeadline     bool
 	deleteHistory     *chat1.MessageDeleteHistory
 	ephemeralLifetime time.Duration
+	// If set, the message is scheduled to be sent at this time.
+	sendAt time.Time
 
 	hasTTY       bool
 	nonBlock     bool
@@ -118,7 +120,18 @@ func chatSend(ctx context.Context, g *libkb.GlobalContext, c ChatSendArg) error
 
 	arg.Msg = msg
 
-	if c.nonBlock {
+	switch {
+	case !c.sendAt.IsZero():
+		if _, err = resolver.ChatClient.ScheduleLocalNonblock(ctx, chat1.ScheduleLocalNonblockArg{
+			ConversationID:   arg.ConversationID,
+			Msg:              arg.Msg,
+			SendAt:           gregor1.ToTime(c.sendAt),
+			IdentifyBehavior: arg.IdentifyBehavior,
+		}); err != nil {
+			return err
+		}
+		g.UI.GetTerminalUI().Printf("Message scheduled for %s.\n", c.sendAt.Format(time.RFC1123))
+	case c.nonBlock:
 		var nbarg chat1.PostLocalNonblockArg
 		nbarg.ConversationID = arg.ConversationID
 		nbarg.Msg = arg.Msg
@@ -126,7 +139,7 @@ func chatSend(ctx context.Context, g *libkb.GlobalContext, c ChatSendArg) error
 		if _, err = resolver.ChatClient.PostLocalNonblock(ctx, nbarg); err != nil {
 			return err
 		}
-	} else {
+	default:
 		if _, err = resolver.ChatClient.PostLocal(ctx, arg); err != nil {
 			return err
 		}
@@ -55,6 +55,9 @@ type ChatServiceHandler interface {
 	EmojiAddAliasV1(context.Context, emojiAddAliasOptionsV1) Reply
 	EmojiRemoveV1(context.Context, emojiRemoveOptionsV1) Reply
 	EmojiListV1(context.Context) Reply
+	ScheduleV1(context.Context, scheduleOptionsV1) Reply
+	ListScheduledV1(context.Context) Reply
+	CancelScheduledV1(context.Context, cancelScheduledOptionsV1) Reply
 }
 
 // chatServiceHandler implements ChatServiceHandler.
@@ -741,6 +744,65 @@ func (c *chatServiceHandler) SendV1(ctx context.Context, opts sendOptionsV1, cha
 	return c.sendV1(ctx, arg, chatUI)
 }
 
+// ScheduleV1 implements ChatServiceHandler.ScheduleV1.
+func (c *chatServiceHandler) ScheduleV1(ctx context.Context, opts scheduleOptionsV1) Reply {
+	convID, err := chat1.MakeConvID(opts.ConversationID.String())
+	if err != nil {
+		return c.errReply(fmt.Errorf("invalid conv ID: %s", opts.ConversationID))
+	}
+	sendAt, err := utils.ParseScheduleTime(time.Now(), opts.At)
+	if err != nil {
+		return c.errReply(err)
+	}
+	arg := sendArgV1{
+		conversationID: convID,
+		channel:        opts.Channel,
+		body:           chat1.NewMessageBodyWithText(chat1.MessageText{Body: opts.Message.Body}),
+		mtype:          chat1.MessageType_TEXT,
+		response:       "message scheduled",
+		sendAt:         sendAt,
+	}
+	return c.sendV1(ctx, arg, utils.DummyChatUI{})
+}
+
+// ListScheduledV1 implements ChatServiceHandler.ListScheduledV1.
+func (c *chatServiceHandler) ListScheduledV1(ctx context.Context) Reply {
+	client, err := GetChatLocalClient(c.G())
+	if err != nil {
+		return c.errReply(err)
+	}
+	scheduled, err := client.GetScheduledMessagesLocal(ctx)
+	if err != nil {
+		return c.errReply(err)
+	}
+	res := chat1.ListScheduledRes{Messages: []chat1.ScheduledMsgSummary{}}
+	for _, sm := range scheduled {
+		summary := chat1.ScheduledMsgSummary{
+			OutboxID: sm.OutboxID,
+			ConvID:   sm.ConvID.ConvIDStr(),
+			SendAt:   sm.SendAt.UnixSeconds(),
+			SendAtMs: sm.SendAt.UnixMilliseconds(),
+		}
+		if sm.Msg.MessageBody.IsType(chat1.MessageType_TEXT) {
+			summary.Body = sm.Msg.MessageBody.Text().Body
+		}
+		res.Messages = append(res.Messages, summary)
+	}
+	return Reply{Result: res}
+}
+
+// CancelScheduledV1 implements ChatServiceHandler.CancelScheduledV1.
+func (c *chatServiceHandler) CancelScheduledV1(ctx context.Context, opts cancelScheduledOptionsV1) Reply {
+	client, err := GetChatLocalClient(c.G())
+	if err != nil {
+		return c.errReply(err)
+	}
+	if err := client.CancelScheduledMessageLocal(ctx, opts.OutboxID); err != nil {
+		return c.errReply(err)
+	}
+	return Reply{Result: chat1.EmptyRes{}}
+}
+
 // DeleteV1 implements ChatServiceHandler.DeleteV1.
 func (c *chatServiceHandler) DeleteV1(ctx context.Context, opts deleteOptionsV1) Reply {
 	convID, _, err := c.resolveAPIConvID(ctx, opts.ConversationID, opts.Channel)
@@ -1101,6 +1163,10 @@ func (c *chatServiceHandler) SearchInboxV1(ctx context.Context, opts searchInbox
 	searchOpts := chat1.SearchOpts{
 		ReindexMode:   reindexMode,
 		SentBy:        opts.SentBy,
+		SentIn:        opts.SentIn,
+		HasAttachment: opts.HasAttachment,
+		HasLink:       opts.HasLink,
+		IsPinned:      opts.IsPinned,
 		MaxHits:       opts.MaxHits,
 		BeforeContext: opts.BeforeContext,
 		AfterContext:  opts.AfterContext,
@@ -1262,6 +1328,8 @@ type sendArgV1 struct {
 	nonblock          bool
 	ephemeralLifetime ephemeralLifetime
 	replyTo           *chat1.MessageID
+	// If set, the message is left in the outbox until this time.
+	sendAt time.Time
 }
 
 func (c *chatServiceHandler) sendV1(ctx context.Context, arg sendArgV1, chatUI chat1.ChatUiInterface) Reply {
@@ -1304,7 +1372,19 @@ func (c *chatServiceHandler) sendV1(ctx context.Context, arg sendArgV1, chatUI c
 	var idFails []keybase1.TLFIdentifyFailure
 	var msgID *chat1.MessageID
 	var obid *chat1.OutboxID
-	if arg.nonblock {
+	switch {
+	case !arg.sendAt.IsZero():
+		sm, err := client.ScheduleLocalNonblock(ctx, chat1.ScheduleLocalNonblockArg{
+			ConversationID:   postArg.ConversationID,
+			Msg:              postArg.Msg,
+			SendAt:           gregor1.ToTime(arg.sendAt),
+			IdentifyBehavior: postArg.IdentifyBehavior,
+		})
+		if err != nil {
+			return c.errReply(err)
+		}
+		obid = &sm.OutboxID
+	case arg.nonblock:
 		var nbarg chat1.PostLocalNonblockArg
 		nbarg.ConversationID = postArg.ConversationID
 		nbarg.Msg = postArg.Msg
@@ -1316,7 +1396,7 @@ func (c *chatServiceHandler) sendV1(ctx context.Context, arg sendArgV1, chatUI c
 		obid = &plres.OutboxID
 		rl = append(rl, plres.RateLimits...)
 		idFails = plres.IdentifyFailures
-	} else {
+	default:
 		plres, err := client.PostLocal(ctx, postArg)
 		if err != nil {
 			return c.errReply(err)
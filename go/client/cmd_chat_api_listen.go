@@ -5,6 +5,7 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,6 +26,9 @@ type CmdChatAPIListen struct {
 	subscribeDev    bool
 	subscribeWallet bool
 	channelFilters  []ChatChannel
+
+	subscribeKVStore bool
+	kvStoreFilters   []KVStoreFilter
 }
 
 func newCmdChatAPIListen(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
@@ -67,6 +71,14 @@ func newCmdChatAPIListen(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli
 				Name:  "filter-channels",
 				Usage: "Only show notifications for specified list of channels.",
 			},
+			cli.BoolFlag{
+				Name:  "kvstore",
+				Usage: "Subscribe to notifications of kvstore entry changes",
+			},
+			cli.StringFlag{
+				Name:  "filter-kvstore",
+				Usage: "Only show kvstore notifications for specified list of teams and namespaces.",
+			},
 		},
 		Description: `"keybase chat api-listen" is a command that will print incoming chat messages, conversation, or
    wallet notifications until it's exited. Messages are printed to standard output in
@@ -85,6 +97,13 @@ func newCmdChatAPIListen(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli
    Only show messages from "alice,bob" user conversation:
 
       keybase chat api-listen --filter-channel '{"name":"alice,bob"}'
+
+   Also show puts and deletes of kvstore entries in the "pw-manager" namespace of
+   team "phoenix", and in the "config" namespace of any team:
+
+      keybase chat api-listen --kvstore --filter-kvstore '[{"team":"phoenix", "namespace":"pw-manager"}, {"namespace":"config"}]'
+
+   kvstore notifications don't include the entry's value. Use "keybase kvstore api" to fetch it.
 `,
 	}
 }
@@ -101,10 +120,35 @@ func (c *CmdChatAPIListen) ParseArgv(ctx *cli.Context) error {
 	}
 	c.subscribeDev = ctx.Bool("dev")
 	c.subscribeWallet = ctx.Bool("wallet")
+	c.subscribeKVStore = ctx.Bool("kvstore")
+
+	if err := c.parseFilterKVStoreArgs(ctx); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+func (c *CmdChatAPIListen) parseFilterKVStoreArgs(ctx *cli.Context) error {
+	fs := ctx.String("filter-kvstore")
+	if fs == "" {
+		return nil
+	}
+	if !c.subscribeKVStore {
+		return errors.New("--filter-kvstore requires --kvstore")
+	}
+	if err := json.Unmarshal([]byte(fs), &c.kvStoreFilters); err != nil {
+		return err
+	}
+	for _, v := range c.kvStoreFilters {
+		if !v.Valid() {
+			str, _ := json.Marshal(v)
+			return fmt.Errorf("kvstore filter not valid: %s", str)
+		}
+	}
+	return nil
+}
+
 func (c *CmdChatAPIListen) parseFilterChannelArgs(ctx *cli.Context) error {
 	if chs := ctx.String("filter-channels"); chs != "" {
 		if err := json.Unmarshal([]byte(chs), &c.channelFilters); err != nil {
@@ -171,6 +215,10 @@ func (c *CmdChatAPIListen) Run() error {
 		stellarDisplay := newWalletNotificationDisplay(c.G())
 		protocols = append(protocols, stellar1.NotifyProtocol(stellarDisplay))
 	}
+	if c.subscribeKVStore {
+		kvStoreDisplay := newKVStoreNotificationDisplay(c.G(), c.kvStoreFilters)
+		protocols = append(protocols, keybase1.NotifyKVStoreProtocol(kvStoreDisplay))
+	}
 
 	if err := RegisterProtocolsWithContext(protocols, c.G()); err != nil {
 		return err
@@ -183,6 +231,7 @@ func (c *CmdChatAPIListen) Run() error {
 		Chat:    true,
 		Chatdev: c.subscribeDev,
 		Wallet:  c.subscribeWallet,
+		Kvstore: c.subscribeKVStore,
 	}
 	if err := cli.SetNotifications(context.TODO(), channels); err != nil {
 		return err
@@ -199,6 +248,12 @@ func (c *CmdChatAPIListen) Run() error {
 			return err
 		}
 	}
+	if c.subscribeKVStore {
+		_, err := errWriter.Write([]byte(fmt.Sprintf("Listening for kvstore notifications. Filters: %+v\n", c.kvStoreFilters)))
+		if err != nil {
+			return err
+		}
+	}
 
 	for {
 		if err := sendPing(sessionClient); err != nil {
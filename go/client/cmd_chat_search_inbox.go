@@ -68,7 +68,20 @@ func newCmdChatSearchInbox(cl *libcmdline.CommandLine, g *libkb.GlobalContext) c
 				Name:  "names-only",
 				Usage: "Search only the names of conversations",
 			},
+			cli.BoolFlag{
+				Name:  "has-attachment",
+				Usage: "Only return attachments. Same as has:attachment in the query.",
+			},
+			cli.BoolFlag{
+				Name:  "has-link",
+				Usage: "Only return messages with links. Same as has:link in the query.",
+			},
+			cli.BoolFlag{
+				Name:  "pinned",
+				Usage: "Only return pinned messages. Same as is:pinned in the query.",
+			},
 		),
+		Description: chatSearchInboxDoc,
 	}
 }
 
@@ -163,6 +176,9 @@ func (c *CmdChatSearchInbox) ParseArgv(ctx *cli.Context) (err error) {
 	}
 	c.opts.MaxConvsSearched = ctx.Int("max-convs-searched")
 	c.opts.MaxConvsHit = ctx.Int("max-convs-hit")
+	c.opts.HasAttachment = ctx.Bool("has-attachment")
+	c.opts.HasLink = ctx.Bool("has-link")
+	c.opts.IsPinned = ctx.Bool("pinned")
 
 	c.opts.AfterContext = ctx.Int("after-context")
 	c.opts.BeforeContext = ctx.Int("before-context")
@@ -187,3 +203,34 @@ func (c *CmdChatSearchInbox) GetUsage() libkb.Usage {
 		API:    true,
 	}
 }
+
+const chatSearchInboxDoc = `"keybase chat search" searches the messages of all conversations in your inbox.
+
+Words in the query must all appear in a message for it to match. The query
+can also contain:
+
+    "some phrase"     words that must appear together, in this order
+    this OR that      either of two words or phrases
+    -word             leave out messages containing the word
+    from:alice        messages sent by a user
+    to:alice          messages mentioning a user
+    before:2020-01-31 messages sent before a date
+    after:2020-01-31  messages sent after a date
+    in:#general       messages in a channel of any team
+    in:acme#general   messages in a channel of a team
+    in:alice,bob      messages in conversations with the given users
+    has:attachment    attachments only
+    has:link          messages with links only
+    is:pinned         pinned messages only
+    /regexp/          a regular expression to search with, which is slower
+
+EXAMPLES:
+
+Search for a phrase in the #general channel of any team:
+
+    keybase chat search '"release notes" in:#general'
+
+Search for links sent by alice, except those mentioning drafts:
+
+    keybase chat search 'from:alice has:link -draft'
+`
@@ -14,6 +14,7 @@ import (
 	"github.com/keybase/cli"
 	"github.com/adamwalz/keybase-client/go/chat/globals"
 	"github.com/adamwalz/keybase-client/go/chat/msgchecker"
+	"github.com/adamwalz/keybase-client/go/chat/utils"
 	"github.com/adamwalz/keybase-client/go/libcmdline"
 	"github.com/adamwalz/keybase-client/go/libkb"
 	"github.com/adamwalz/keybase-client/go/protocol/chat1"
@@ -29,6 +30,7 @@ type CmdChatSend struct {
 	message           string
 	setHeadline       string
 	ephemeralLifetime time.Duration
+	sendAt            time.Time
 	clearHeadline     bool
 	hasTTY            bool
 	nonBlock          bool
@@ -60,6 +62,11 @@ func newCmdChatSend(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Comm
 	flags := append(getConversationResolverFlags(),
 		mustGetChatFlags("set-headline", "clear-headline", "nonblock", "exploding-lifetime")...,
 	)
+	flags = append(flags, cli.StringFlag{
+		Name: "at",
+		Usage: `Schedule the message to be sent later instead of now. Takes a time like
+	"17:30", "9am", "2006-01-02 15:04" or an RFC 3339 timestamp, or a duration like "2h".`,
+	})
 	return cli.Command{
 		Name:         "send",
 		Usage:        "Send a message to a conversation",
@@ -127,6 +134,7 @@ func (c *CmdChatSend) Run() (err error) {
 		team:              c.team,
 		setTopicName:      "",
 		ephemeralLifetime: c.ephemeralLifetime,
+		sendAt:            c.sendAt,
 	})
 }
 
@@ -167,6 +175,18 @@ func (c *CmdChatSend) ParseArgv(ctx *cli.Context) (err error) {
 		}
 	}
 
+	if at := ctx.String("at"); at != "" {
+		if c.sendAt, err = utils.ParseScheduleTime(time.Now(), at); err != nil {
+			return err
+		}
+		if c.nonBlock {
+			return fmt.Errorf("--at cannot be used with --nonblock")
+		}
+		if nActions > 0 {
+			return fmt.Errorf("--at can only be used to send a message")
+		}
+	}
+
 	if c.ephemeralLifetime != 0 {
 		if c.ephemeralLifetime > libkb.MaxEphemeralContentLifetime {
 			return fmt.Errorf("ephemeral lifetime cannot exceed %v", libkb.MaxEphemeralContentLifetime)
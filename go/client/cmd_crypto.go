@@ -0,0 +1,22 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+)
+
+func NewCmdCrypto(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	subcommands := []cli.Command{
+		newCmdCryptoAPI(cl, g),
+	}
+	return cli.Command{
+		Name:         "crypto",
+		Usage:        "Encrypt, decrypt, sign and verify from programs",
+		ArgumentHelp: "[arguments...]",
+		Subcommands:  subcommands,
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+)
+
+type CmdCryptoAPI struct {
+	libkb.Contextified
+	cmdAPI
+}
+
+func newCmdCryptoAPI(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return newCmdAPI(cl, NewCmdCryptoAPIRunner(g), "JSON API", cryptoAPIDoc)
+}
+
+func NewCmdCryptoAPIRunner(g *libkb.GlobalContext) *CmdCryptoAPI {
+	return &CmdCryptoAPI{
+		Contextified: libkb.NewContextified(g),
+	}
+}
+
+func (c *CmdCryptoAPI) Run() error {
+	h := newCryptoAPIHandler(c.G(), c.indent)
+	return c.runHandler(h)
+}
@@ -6,6 +6,7 @@ package client
 import (
 	"golang.org/x/net/context"
 
+	"errors"
 	"fmt"
 
 	humanize "github.com/dustin/go-humanize"
@@ -22,6 +23,7 @@ type CmdDecrypt struct {
 	spui       *SaltpackUI
 	opts       keybase1.SaltpackDecryptOptions
 	senderfile *FileSink
+	dirSink    *DirExtractSink
 }
 
 func NewCmdDecrypt(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
@@ -54,6 +56,10 @@ func NewCmdDecrypt(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Comma
 			Name:  "encryptor-outfile",
 			Usage: "Write the Keybase name of the encryptor to this file",
 		},
+		cli.StringFlag{
+			Name:  "r, recursive",
+			Usage: "Decrypt a directory encrypted with \"keybase encrypt -r\" into this new directory.",
+		},
 	}
 
 	return cli.Command{
@@ -116,6 +122,8 @@ func (c *CmdDecrypt) Run() error {
 
 	snk, src, err := c.filter.ClientFilterOpen(c.G())
 	if err != nil {
+		// Cleans up a half-opened -r directory.
+		_ = c.filter.Close(err)
 		return err
 	}
 
@@ -138,7 +146,13 @@ func (c *CmdDecrypt) Run() error {
 	}
 
 	cerr := c.filter.Close(err)
-	return libkb.PickFirstError(err, cerr)
+	if err = libkb.PickFirstError(err, cerr); err != nil {
+		return err
+	}
+	if c.dirSink != nil {
+		c.G().UI.GetDumbOutputUI().PrintfStderr("Decrypted %d files into %s\n", c.dirSink.Files(), c.dirSink.dest)
+	}
+	return nil
 }
 
 func (c *CmdDecrypt) GetUsage() libkb.Usage {
@@ -166,6 +180,13 @@ func (c *CmdDecrypt) ParseArgv(ctx *cli.Context) error {
 	outfile := ctx.String("outfile")
 	infile := ctx.String("infile")
 	senderfile := ctx.String("encryptor-outfile")
+	if dir := ctx.String("recursive"); len(dir) > 0 {
+		if len(outfile) > 0 {
+			return errors.New("can't decrypt into a directory and an outfile at the same time")
+		}
+		c.dirSink = NewDirExtractSink(dir)
+		c.filter.SetSink(c.dirSink)
+	}
 	if err := c.filter.FilterInit(c.G(), msg, infile, outfile); err != nil {
 		return err
 	}
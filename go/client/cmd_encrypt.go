@@ -6,6 +6,7 @@ package client
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 
 	"golang.org/x/net/context"
@@ -46,6 +47,10 @@ func NewCmdEncrypt(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Comma
 			Name:  "o, outfile",
 			Usage: "Specify an outfile (stdout by default).",
 		},
+		cli.StringFlag{
+			Name:  "r, recursive",
+			Usage: "Encrypt this directory, with the modes and modification times of its files.",
+		},
 		cli.BoolFlag{
 			Name:  "no-entity-keys",
 			Usage: "Do not use per user/per team keys for encryption.",
@@ -95,6 +100,11 @@ func NewCmdEncrypt(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Comma
 			Name:  "saltpack-version",
 			Usage: "Force a specific saltpack version",
 		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "saltpack",
+			Usage: "Output format: saltpack|age.",
+		},
 	}
 	if develUsage {
 		flags = append(flags, cli.BoolFlag{
@@ -144,6 +154,18 @@ but cannot convince a third party of this fact) or made completely anonymous.
 Encrypting for teams (and users not yet on keybase or with missing keys) with
 repudiable authentication is not possible. You can still use the signed or
 anonymous modes in such cases.
+
+With "-r <dir>", the whole directory is encrypted as a single message, which
+"keybase decrypt -r" turns back into a directory:
+
+    keybase encrypt -r photos -o photos.saltpack alice
+    keybase decrypt -r photos -i photos.saltpack
+
+With "--format age", the message is written as an age file
+(https://age-encryption.org) for the device and paper keys of the recipients,
+which can be decrypted by "keybase decrypt" or by age itself with the X25519
+key of one of those devices. age files are always anonymous, and can't be
+encrypted for teams, per-user keys or users who aren't on keybase yet.
 `,
 		Action: func(c *cli.Context) {
 			cl.ChooseCommand(&CmdEncrypt{
@@ -253,6 +275,25 @@ func (c *CmdEncrypt) ParseArgv(ctx *cli.Context) error {
 	if c.opts.AuthenticityType, ok = keybase1.AuthenticityTypeMap[strings.ToUpper(ctx.String("auth-type"))]; !ok {
 		return errors.New("invalid auth-type option provided")
 	}
+	if c.opts.Format, ok = keybase1.EncryptionFormatMap[strings.ToUpper(ctx.String("format"))]; !ok {
+		return errors.New("invalid format option provided")
+	}
+	if c.opts.Format == keybase1.EncryptionFormat_AGE {
+		// age only knows about X25519 recipients, which are our device and
+		// paper encryption keys, and has no sender authentication at all.
+		switch {
+		case forTeamRecipients:
+			return errors.New("--format=age can't be used to encrypt for teams")
+		case ctx.IsSet("auth-type") && c.opts.AuthenticityType != keybase1.AuthenticityType_ANONYMOUS:
+			return errors.New("--format=age only supports --auth-type=anonymous")
+		case ctx.IsSet("saltpack-version"):
+			return errors.New("--saltpack-version can't be used with --format=age")
+		case !(c.opts.UseDeviceKeys || c.opts.UsePaperKeys):
+			return errors.New("--format=age needs device or paper keys; please remove --no-device-keys or --no-paper-keys")
+		}
+		c.opts.UseEntityKeys = false
+		c.opts.AuthenticityType = keybase1.AuthenticityType_ANONYMOUS
+	}
 
 	// Repudiable authenticity corresponds to the saltpack encryption format
 	// (which uses pairwise MACs instead of signatures). Because of the spec and
@@ -270,5 +311,20 @@ func (c *CmdEncrypt) ParseArgv(ctx *cli.Context) error {
 	msg := ctx.String("message")
 	outfile := ctx.String("outfile")
 	infile := ctx.String("infile")
+	if dir := ctx.String("recursive"); len(dir) > 0 {
+		if len(msg) > 0 || len(infile) > 0 {
+			return errors.New("can't encrypt a directory and a message or infile at the same time")
+		}
+		if fi, err := os.Stat(dir); err != nil {
+			return err
+		} else if !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		src, err := NewDirArchiveSource(dir, outfile)
+		if err != nil {
+			return err
+		}
+		c.filter.SetSource(src)
+	}
 	return c.filter.FilterInit(c.G(), msg, infile, outfile)
 }
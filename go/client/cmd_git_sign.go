@@ -0,0 +1,372 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/keybase/go-framed-msgpack-rpc/rpc"
+)
+
+// Git only keeps the signatures of tags if they start with a marker it knows,
+// so the saltpack signature goes inside a PGP-style block.
+const (
+	gitSignBegin   = "-----BEGIN PGP SIGNATURE-----"
+	gitSignEnd     = "-----END PGP SIGNATURE-----"
+	gitSignComment = "Comment: Keybase saltpack signature, verify with keybase git-sign"
+)
+
+func NewCmdGitSign(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:  "git-sign",
+		Usage: "Sign and verify git commits and tags (use as gpg.program)",
+		Action: func(c *cli.Context) {
+			cl.ChooseCommand(&CmdGitSign{Contextified: libkb.NewContextified(g)}, "git-sign", c)
+		},
+		// git runs gpg.program with gpg's arguments, which don't follow our
+		// flag conventions.
+		SkipFlagParsing: true,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "b, detach-sign",
+				Usage: "Make a detached signature of stdin.",
+			},
+			cli.BoolFlag{
+				Name:  "s, sign",
+				Usage: "Ignored; signatures are always made.",
+			},
+			cli.BoolFlag{
+				Name:  "a, armor",
+				Usage: "Ignored; signatures are always armored.",
+			},
+			cli.StringFlag{
+				Name:  "u, local-user",
+				Usage: "Ignored; signatures are made with the current device's key.",
+			},
+			cli.StringFlag{
+				Name:  "bsau",
+				Usage: "What git passes to sign (same as -b -s -a -u).",
+			},
+			cli.BoolFlag{
+				Name:  "verify",
+				Usage: "Verify the signature file given as the first argument against stdin.",
+			},
+			cli.IntFlag{
+				Name:  "status-fd",
+				Usage: "Write GnuPG status lines to this file descriptor.",
+			},
+			cli.StringFlag{
+				Name:  "keyid-format",
+				Usage: "Ignored.",
+			},
+		},
+		Description: gitSignDoc,
+	}
+}
+
+type CmdGitSign struct {
+	libkb.Contextified
+	verify    bool
+	sigFile   string
+	statusFD  int
+	statusOut io.Writer
+}
+
+func (c *CmdGitSign) ParseArgv(ctx *cli.Context) error {
+	c.verify = ctx.Bool("verify")
+	c.statusFD = ctx.Int("status-fd")
+	sign := ctx.Bool("detach-sign") || ctx.IsSet("bsau")
+	switch {
+	case c.verify && sign:
+		return errors.New("can't both sign and verify")
+	case c.verify:
+		// git passes the signature file and "-" for the payload on stdin
+		if len(ctx.Args()) == 0 || len(ctx.Args()) > 2 || (len(ctx.Args()) == 2 && ctx.Args()[1] != "-") {
+			return errors.New("usage: git-sign --verify <signature file> [-]")
+		}
+		c.sigFile = ctx.Args()[0]
+	case sign:
+		if len(ctx.Args()) > 0 {
+			return UnexpectedArgsError("git-sign")
+		}
+	default:
+		return errors.New("either --detach-sign or --verify is required")
+	}
+	return nil
+}
+
+func (c *CmdGitSign) Run() (err error) {
+	c.statusOut = c.openStatusFD()
+	if c.verify {
+		return c.runVerify()
+	}
+	return c.runSign()
+}
+
+func (c *CmdGitSign) openStatusFD() io.Writer {
+	switch c.statusFD {
+	case 0:
+		return io.Discard
+	case 1:
+		return os.Stdout
+	case 2:
+		return os.Stderr
+	default:
+		return os.NewFile(uintptr(c.statusFD), "status-fd")
+	}
+}
+
+func (c *CmdGitSign) status(format string, args ...interface{}) {
+	fmt.Fprintf(c.statusOut, "[GNUPG:] "+format+"\n", args...)
+}
+
+func (c *CmdGitSign) runSign() error {
+	cli, err := GetSaltpackClient(c.G())
+	if err != nil {
+		return err
+	}
+	protocols := []rpc.Protocol{
+		NewStreamUIProtocol(c.G()),
+		NewSecretUIProtocol(c.G()),
+	}
+	if err = RegisterProtocolsWithContext(protocols, c.G()); err != nil {
+		return err
+	}
+
+	source := &StdinSource{}
+	if err := source.Open(); err != nil {
+		return err
+	}
+	defer source.Close()
+	sink := libkb.NewBufferCloser()
+	c.status("BEGIN_SIGNING")
+	err = cli.SaltpackSign(context.TODO(), keybase1.SaltpackSignArg{
+		Source: c.G().XStreams.ExportReader(source),
+		Sink:   c.G().XStreams.ExportWriter(sink),
+		Opts: keybase1.SaltpackSignOptions{
+			Detached: true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// git looks for SIG_CREATED to know the signature was made. The fields
+	// after the type don't apply to saltpack, apart from the time.
+	c.status("SIG_CREATED D 0 0 00 %d %s", time.Now().Unix(), c.G().Env.GetUsername())
+	_, err = os.Stdout.WriteString(wrapGitSignature(sink.String()))
+	return err
+}
+
+func (c *CmdGitSign) runVerify() error {
+	armored, err := os.ReadFile(c.sigFile)
+	if err != nil {
+		return err
+	}
+	sig, err := unwrapGitSignature(string(armored))
+	if err != nil {
+		return err
+	}
+
+	cli, err := GetSaltpackClient(c.G())
+	if err != nil {
+		return err
+	}
+	spui := &gitSignSaltpackUI{}
+	protocols := []rpc.Protocol{
+		NewStreamUIProtocol(c.G()),
+		NewSecretUIProtocol(c.G()),
+		// Identify output would end up in git's status parsing, so the
+		// outcome is reported through the status lines instead.
+		NewNullIdentifyUIProtocol(),
+		keybase1.SaltpackUiProtocol(spui),
+	}
+	if err = RegisterProtocolsWithContext(protocols, c.G()); err != nil {
+		return err
+	}
+
+	source := &StdinSource{}
+	if err := source.Open(); err != nil {
+		return err
+	}
+	defer source.Close()
+	c.status("NEWSIG")
+	err = cli.SaltpackVerify(context.TODO(), keybase1.SaltpackVerifyArg{
+		Source: c.G().XStreams.ExportReader(source),
+		Sink:   c.G().XStreams.ExportWriter(libkb.NewBufferCloser()),
+		Opts: keybase1.SaltpackVerifyOptions{
+			Signature: []byte(sig),
+		},
+	})
+
+	w := c.G().UI.GetTerminalUI().ErrorWriter()
+	signer := spui.signer(lookupDeviceName(c.G(), spui.sender.Uid, spui.kid))
+	keyID := spui.kid.String()
+	if keyID == "" {
+		keyID = "0"
+	}
+	if err != nil {
+		c.status("BADSIG %s %s", keyID, signer)
+		fmt.Fprintf(w, "keybase: BAD signature from %s: %v\n", signer, err)
+		return err
+	}
+	switch spui.sender.SenderType {
+	case keybase1.SaltpackSenderType_REVOKED:
+		c.status("REVKEYSIG %s %s", keyID, signer)
+		fmt.Fprintf(w, "keybase: Signature from %s, made with a revoked key %s\n", signer, keyID)
+		return libkb.IdentifyFailedError{Assertion: spui.sender.Username, Reason: "sender key revoked"}
+	case keybase1.SaltpackSenderType_EXPIRED:
+		c.status("EXPKEYSIG %s %s", keyID, signer)
+		fmt.Fprintf(w, "keybase: Signature from %s, made with an expired key %s\n", signer, keyID)
+		return libkb.IdentifyFailedError{Assertion: spui.sender.Username, Reason: "sender key expired"}
+	}
+	c.status("GOODSIG %s %s", keyID, signer)
+	c.status("%s 0 keybase", gitSignTrust(spui.sender.SenderType))
+	fmt.Fprintf(w, "keybase: Good signature from %s\n", signer)
+	fmt.Fprintf(w, "keybase: using key %s\n", keyID)
+	if spui.sender.SenderType == keybase1.SaltpackSenderType_TRACKING_BROKE {
+		fmt.Fprintf(w, "keybase: WARNING: your view of %s's identity is broken\n", spui.sender.Username)
+	}
+	return nil
+}
+
+// lookupDeviceName finds which of the user's devices the key belongs to, so
+// that it can be shown alongside the username. It's best effort.
+func lookupDeviceName(g *libkb.GlobalContext, uid keybase1.UID, kid keybase1.KID) string {
+	if uid.IsNil() || kid.IsNil() {
+		return ""
+	}
+	cli, err := GetUserClient(g)
+	if err != nil {
+		return ""
+	}
+	upk, err := cli.LoadUserPlusKeys(context.TODO(), keybase1.LoadUserPlusKeysArg{Uid: uid})
+	if err != nil {
+		g.Log.Debug("lookupDeviceName: failed to load %s: %v", uid, err)
+		return ""
+	}
+	for _, key := range upk.DeviceKeys {
+		if key.KID.Equal(kid) {
+			return key.DeviceDescription
+		}
+	}
+	for _, key := range upk.RevokedDeviceKeys {
+		if key.Key.KID.Equal(kid) {
+			return key.Key.DeviceDescription
+		}
+	}
+	return ""
+}
+
+func (c *CmdGitSign) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		API:       true,
+		KbKeyring: true,
+	}
+}
+
+// gitSignSaltpackUI records who signed, rather than printing it, and lets
+// verification finish for bad senders so that they can be reported to git.
+type gitSignSaltpackUI struct {
+	kid    keybase1.KID
+	sender keybase1.SaltpackSender
+}
+
+var _ keybase1.SaltpackUiInterface = (*gitSignSaltpackUI)(nil)
+
+func (s *gitSignSaltpackUI) SaltpackPromptForDecrypt(context.Context, keybase1.SaltpackPromptForDecryptArg) error {
+	return nil
+}
+
+func (s *gitSignSaltpackUI) SaltpackVerifySuccess(_ context.Context, arg keybase1.SaltpackVerifySuccessArg) error {
+	s.kid = arg.SigningKID
+	s.sender = arg.Sender
+	return nil
+}
+
+func (s *gitSignSaltpackUI) SaltpackVerifyBadSender(_ context.Context, arg keybase1.SaltpackVerifyBadSenderArg) error {
+	s.kid = arg.SigningKID
+	s.sender = arg.Sender
+	return nil
+}
+
+// signer is the user ID git shows for the signature, e.g. `alice (laptop)`.
+func (s *gitSignSaltpackUI) signer(device string) string {
+	name := s.sender.Username
+	if name == "" {
+		name = "unknown"
+	}
+	if device != "" {
+		name += fmt.Sprintf(" (%s)", device)
+	}
+	return name
+}
+
+func gitSignTrust(senderType keybase1.SaltpackSenderType) string {
+	switch senderType {
+	case keybase1.SaltpackSenderType_SELF:
+		return "TRUST_ULTIMATE"
+	case keybase1.SaltpackSenderType_TRACKING_OK:
+		return "TRUST_FULLY"
+	case keybase1.SaltpackSenderType_TRACKING_BROKE:
+		return "TRUST_NEVER"
+	default:
+		return "TRUST_UNDEFINED"
+	}
+}
+
+func wrapGitSignature(armored string) string {
+	return fmt.Sprintf("%s\n%s\n\n%s\n%s\n", gitSignBegin, gitSignComment, strings.TrimSpace(armored), gitSignEnd)
+}
+
+func unwrapGitSignature(s string) (string, error) {
+	begin := strings.Index(s, gitSignBegin)
+	end := strings.LastIndex(s, gitSignEnd)
+	if begin < 0 || end < begin {
+		return "", errors.New("not a keybase git signature")
+	}
+	body := s[begin+len(gitSignBegin) : end]
+	// skip the armor headers, which end at the first blank line
+	if i := strings.Index(body, "\n\n"); i >= 0 {
+		body = body[i+2:]
+	}
+	body = strings.TrimSpace(body)
+	if !strings.HasPrefix(body, "BEGIN KEYBASE SALTPACK DETACHED SIGNATURE.") {
+		return "", errors.New("signature isn't a saltpack signature; it may have been made with gpg")
+	}
+	return body, nil
+}
+
+const gitSignDoc = `"keybase git-sign" signs git commits and tags with the key of the current
+Keybase device, and verifies them, by speaking the same command-line
+interface git uses to run gpg. Signatures are saltpack detached signatures,
+wrapped in a block git recognizes as a signature.
+
+git runs gpg.program directly rather than through a shell, so point it at a
+small wrapper script:
+
+    cat > ~/bin/keybase-git-sign <<'EOS'
+    #!/bin/sh
+    exec keybase git-sign "$@"
+    EOS
+    chmod +x ~/bin/keybase-git-sign
+    git config --global gpg.program ~/bin/keybase-git-sign
+    git config --global commit.gpgsign true
+
+"git log --show-signature", "git verify-commit" and "git verify-tag" then
+show the Keybase user and device that made each signature. Only keybase
+git-sign can check these signatures; gpg can't.
+`
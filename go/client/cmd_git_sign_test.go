@@ -0,0 +1,32 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitSignatureWrapping(t *testing.T) {
+	sig := "BEGIN KEYBASE SALTPACK DETACHED SIGNATURE. kXR7VktZdyH7rvq v5wcIkHbs7mPCSd NhKLR9E0K47y29T. END KEYBASE SALTPACK DETACHED SIGNATURE.\n"
+	wrapped := wrapGitSignature(sig)
+	require.True(t, strings.HasPrefix(wrapped, gitSignBegin+"\n"))
+	require.True(t, strings.HasSuffix(wrapped, gitSignEnd+"\n"))
+
+	unwrapped, err := unwrapGitSignature(wrapped)
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimSpace(sig), unwrapped)
+
+	// git hands over tag signatures with the tag message before them.
+	unwrapped, err = unwrapGitSignature("object abc\ntag v1.0\n\nrelease\n" + wrapped)
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimSpace(sig), unwrapped)
+
+	_, err = unwrapGitSignature(gitSignBegin + "\n\niQEzBAABCAAdFiEE\n" + gitSignEnd + "\n")
+	require.Error(t, err)
+	_, err = unwrapGitSignature(sig)
+	require.Error(t, err)
+}
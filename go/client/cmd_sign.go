@@ -4,6 +4,9 @@
 package client
 
 import (
+	"errors"
+	"path/filepath"
+
 	"golang.org/x/net/context"
 
 	"github.com/keybase/cli"
@@ -45,6 +48,10 @@ func NewCmdSign(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command
 				Name:  "saltpack-version",
 				Usage: "Force a specific saltpack version",
 			},
+			cli.StringFlag{
+				Name:  "manifest",
+				Usage: "Sign a manifest of the files in this directory, for \"keybase verify --manifest\".",
+			},
 		},
 	}
 }
@@ -55,6 +62,8 @@ type CmdSign struct {
 	detached        bool
 	binary          bool
 	saltpackVersion int
+	manifestDir     string
+	outfile         string
 }
 
 func (s *CmdSign) ParseArgv(ctx *cli.Context) error {
@@ -70,9 +79,41 @@ func (s *CmdSign) ParseArgv(ctx *cli.Context) error {
 	outfile := ctx.String("outfile")
 	infile := ctx.String("infile")
 
+	s.manifestDir = ctx.String("manifest")
+	if len(s.manifestDir) > 0 {
+		if len(msg) > 0 || len(infile) > 0 || s.detached {
+			return errors.New("--manifest can't be used with --message, --infile or --detached")
+		}
+		s.outfile = outfile
+	}
+
 	return s.FilterInit(s.G(), msg, infile, outfile)
 }
 
+// setManifestSource makes the manifest of s.manifestDir the message to sign,
+// leaving out the signed manifest itself if it's being written into the
+// directory.
+func (s *CmdSign) setManifestSource() error {
+	var skip string
+	if len(s.outfile) > 0 && s.outfile != "-" {
+		var err error
+		if skip, err = filepath.Abs(s.outfile); err != nil {
+			return err
+		}
+	}
+	m, err := newDirManifest(s.manifestDir, skip)
+	if err != nil {
+		return err
+	}
+	data, err := m.encode()
+	if err != nil {
+		return err
+	}
+	s.SetSource(NewBufferSource(string(data)))
+	s.G().UI.GetDumbOutputUI().PrintfStderr("Signing a manifest of %d files in %s\n", len(m.Files), s.manifestDir)
+	return nil
+}
+
 func (s *CmdSign) Run() (err error) {
 	protocols := []rpc.Protocol{
 		NewStreamUIProtocol(s.G()),
@@ -86,6 +127,11 @@ func (s *CmdSign) Run() (err error) {
 	if err = RegisterProtocolsWithContext(protocols, s.G()); err != nil {
 		return err
 	}
+	if len(s.manifestDir) > 0 {
+		if err := s.setManifestSource(); err != nil {
+			return err
+		}
+	}
 	snk, src, err := s.ClientFilterOpen(s.G())
 	if err == nil {
 		arg := keybase1.SaltpackSignArg{
@@ -39,6 +39,7 @@ func NewCmdSimpleFS(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Comm
 			NewCmdSimpleFSRemove(cl, g),
 			NewCmdSimpleFSMkdir(cl, g),
 			NewCmdSimpleFSStat(cl, g),
+			NewCmdSimpleFSXattr(cl, g),
 			NewCmdSimpleFSGetStatus(cl, g),
 			NewCmdSimpleFSKill(cl, g),
 			NewCmdSimpleFSPs(cl, g),
@@ -57,6 +58,8 @@ func NewCmdSimpleFS(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Comm
 			NewCmdSimpleFSSearch(cl, g),
 			NewCmdSimpleFSResetIndex(cl, g),
 			NewCmdSimpleFSIndexProgress(cl, g),
+			NewCmdSimpleFSWebDAV(cl, g),
+			NewCmdSimpleFSS3(cl, g),
 		}, getBuildSpecificFSCommands(cl, g)...),
 	}
 }
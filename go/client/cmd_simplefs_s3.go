@@ -0,0 +1,130 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+// CmdSimpleFSS3 is the 'fs s3' command.
+type CmdSimpleFSS3 struct {
+	libkb.Contextified
+	port    int
+	stop    bool
+	buckets []keybase1.SimpleFSS3Bucket
+}
+
+// NewCmdSimpleFSS3 creates a new cli.Command.
+func NewCmdSimpleFSS3(
+	cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "s3",
+		ArgumentHelp: "[<bucket>=<path> ...]",
+		Usage:        "serve KBFS folders over an S3-compatible API on localhost",
+		Action: func(c *cli.Context) {
+			cl.ChooseCommand(
+				&CmdSimpleFSS3{Contextified: libkb.NewContextified(g)},
+				"s3", c)
+			cl.SetNoStandalone()
+		},
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "p, port",
+				Usage: "listen on this port, instead of an automatically-picked one",
+			},
+			cli.BoolFlag{
+				Name:  "stop",
+				Usage: "stop the running S3 gateway",
+			},
+		},
+		Description: `Start an S3-compatible gateway on the loopback interface,
+   serving each given KBFS folder as a bucket.  For example:
+
+     keybase fs s3 photos=/keybase/private/alice/photos
+
+   With no arguments, the details of the running gateway are printed.
+
+   Clients must sign requests with the printed access key and secret
+   key, using AWS signature version 2 and path-style bucket addressing.`,
+	}
+}
+
+// Run runs the command in client/server mode.
+func (c *CmdSimpleFSS3) Run() error {
+	cli, err := GetSimpleFSClient(c.G())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.TODO()
+	if c.stop {
+		return cli.SimpleFSStopS3Gateway(ctx)
+	}
+
+	info, err := cli.SimpleFSStartS3Gateway(ctx, keybase1.SimpleFSStartS3GatewayArg{
+		Port:    c.port,
+		Buckets: c.buckets,
+	})
+	if err != nil {
+		return err
+	}
+	ui := c.G().UI.GetTerminalUI()
+	ui.Printf("URL:\t%s\n", info.Url)
+	ui.Printf("Access key:\t%s\n", info.AccessKey)
+	ui.Printf("Secret key:\t%s\n", info.SecretKey)
+	ui.Printf("Buckets:\t%s\n", strings.Join(info.Buckets, ", "))
+	return nil
+}
+
+// ParseArgv gets the optional flags and the buckets.
+func (c *CmdSimpleFSS3) ParseArgv(ctx *cli.Context) error {
+	c.port = ctx.Int("port")
+	c.stop = ctx.Bool("stop")
+	if c.port < 0 || c.port > 65535 {
+		return errors.New("invalid port")
+	}
+	if c.stop && (c.port != 0 || len(ctx.Args()) != 0) {
+		return errors.New("--stop can't be used with a port or buckets")
+	}
+
+	for _, arg := range ctx.Args() {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("buckets must be given as <bucket>=<path>: %s", arg)
+		}
+		p, err := makeSimpleFSPath(parts[1])
+		if err != nil {
+			return err
+		}
+		pathType, err := p.PathType()
+		if err != nil {
+			return err
+		}
+		if pathType != keybase1.PathType_KBFS {
+			return fmt.Errorf("%s is not a KBFS path", parts[1])
+		}
+		c.buckets = append(c.buckets, keybase1.SimpleFSS3Bucket{
+			Name: parts[0],
+			Path: p.Kbfs(),
+		})
+	}
+	return nil
+}
+
+// GetUsage says what this command needs to operate.
+func (c *CmdSimpleFSS3) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		KbKeyring: true,
+		API:       true,
+	}
+}
@@ -6,16 +6,17 @@ package client
 import (
 	"context"
 	"fmt"
+	"strings"
+	"unicode"
 
 	"github.com/keybase/cli"
 	"github.com/adamwalz/keybase-client/go/libcmdline"
 	"github.com/adamwalz/keybase-client/go/libkb"
 	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/adamwalz/keybase-client/go/terminalescaper"
 )
 
-const (
-	defaultNumFSSearchResults = 10
-)
+const defaultNumFSSearchResults = 10
 
 // CmdSimpleFSSearch is the 'fs search' command.
 type CmdSimpleFSSearch struct {
@@ -24,6 +25,7 @@ type CmdSimpleFSSearch struct {
 	query        string
 	numResults   int
 	startingFrom int
+	filters      keybase1.SimpleFSSearchFilters
 }
 
 // NewCmdSimpleFSSearch creates a new cli.Command.
@@ -48,6 +50,28 @@ func NewCmdSimpleFSSearch(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cl
 				Usage: "what number result to start from (for paging)",
 				Value: 0,
 			},
+			cli.StringFlag{
+				Name:  "folder",
+				Usage: "only search in this folder (e.g., /keybase/team/acme)",
+			},
+			cli.StringFlag{
+				Name:  "prefix",
+				Usage: "only return results under this path",
+			},
+			cli.StringFlag{
+				Name:  "type",
+				Usage: "only return files with this extension (e.g., md)",
+			},
+			cli.StringFlag{
+				Name: "modified-after",
+				Usage: "only return results modified at or after this " +
+					"date (YYYY-MM-DD) or time (RFC 3339)",
+			},
+			cli.StringFlag{
+				Name: "modified-before",
+				Usage: "only return results modified before this " +
+					"date (YYYY-MM-DD) or time (RFC 3339)",
+			},
 		},
 	}
 }
@@ -63,6 +87,7 @@ func (c *CmdSimpleFSSearch) Run() error {
 		Query:        c.query,
 		NumResults:   c.numResults,
 		StartingFrom: c.startingFrom,
+		Filters:      c.filters,
 	}
 	res, err := cli.SimpleFSSearch(context.TODO(), arg)
 	if err != nil {
@@ -76,7 +101,10 @@ func (c *CmdSimpleFSSearch) Run() error {
 	}
 
 	for _, hit := range res.Hits {
-		ui.Printf("%s\n", hit.Path)
+		ui.Printf("%s (score: %.3f)\n", hit.Path, hit.Score)
+		for _, f := range hit.Fragments {
+			_, _ = ui.PrintfUnescaped("    %s\n", c.highlightFragment(f))
+		}
 	}
 	if res.NextResult != -1 {
 		ui.Printf(
@@ -86,6 +114,74 @@ func (c *CmdSimpleFSSearch) Run() error {
 	return nil
 }
 
+// highlightFragment escapes the text of a fragment for the terminal,
+// and colors the parts of it that matched the query.
+func (c *CmdSimpleFSSearch) highlightFragment(
+	f keybase1.SimpleFSSearchFragment) string {
+	return highlightFragment(f, func(s string) string {
+		return ColorString(c.G(), "red", s)
+	})
+}
+
+// highlightFragment collapses each run of whitespace in the fragment into a
+// single space, as if the whole fragment had been normalized before being
+// split up around its matches, and passes the matches through highlight.
+func highlightFragment(
+	f keybase1.SimpleFSSearchFragment, highlight func(string) string) string {
+	var res strings.Builder
+	// whether there was whitespace since the last word written
+	space := false
+	write := func(s string, matched bool) {
+		fields := strings.Fields(s)
+		if len(fields) == 0 {
+			space = space || len(s) > 0
+			return
+		}
+		if strings.TrimLeftFunc(s, unicode.IsSpace) != s {
+			space = true
+		}
+		if space && res.Len() > 0 {
+			res.WriteString(" ")
+		}
+		text := terminalescaper.Clean(strings.Join(fields, " "))
+		if matched {
+			text = highlight(text)
+		}
+		res.WriteString(text)
+		space = strings.TrimRightFunc(s, unicode.IsSpace) != s
+	}
+	curr := 0
+	for _, m := range f.Matches {
+		if m.Start < curr || m.End < m.Start || m.End > len(f.Text) {
+			// sanity check string indices
+			continue
+		}
+		write(f.Text[curr:m.Start], false)
+		write(f.Text[m.Start:m.End], true)
+		curr = m.End
+	}
+	write(f.Text[curr:], false)
+	return res.String()
+}
+
+func makeFSSearchPath(p string) (string, error) {
+	if p == "" {
+		return "", nil
+	}
+	path, err := makeSimpleFSPath(p)
+	if err != nil {
+		return "", err
+	}
+	pathType, err := path.PathType()
+	if err != nil {
+		return "", err
+	}
+	if pathType != keybase1.PathType_KBFS {
+		return "", fmt.Errorf("%s is not a KBFS path", p)
+	}
+	return path.Kbfs().Path, nil
+}
+
 // ParseArgv gets the optional flags and the query.
 func (c *CmdSimpleFSSearch) ParseArgv(ctx *cli.Context) error {
 	if len(ctx.Args()) != 1 {
@@ -99,6 +195,27 @@ func (c *CmdSimpleFSSearch) ParseArgv(ctx *cli.Context) error {
 	}
 	c.startingFrom = ctx.Int("start-from")
 
+	var err error
+	c.filters.Tlf, err = makeFSSearchPath(ctx.String("folder"))
+	if err != nil {
+		return err
+	}
+	c.filters.PathPrefix, err = makeFSSearchPath(ctx.String("prefix"))
+	if err != nil {
+		return err
+	}
+	c.filters.FileType = strings.TrimPrefix(ctx.String("type"), ".")
+	c.filters.ModifiedAfter, err = parseDateOrTime(
+		ctx.String("modified-after"))
+	if err != nil {
+		return err
+	}
+	c.filters.ModifiedBefore, err = parseDateOrTime(
+		ctx.String("modified-before"))
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
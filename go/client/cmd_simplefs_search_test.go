@@ -0,0 +1,30 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"testing"
+
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighlightFragment(t *testing.T) {
+	brackets := func(s string) string { return "[" + s + "]" }
+	for _, tc := range []struct {
+		text    string
+		matches []keybase1.SimpleFSSearchMatch
+		want    string
+	}{
+		{"the quick brown fox", []keybase1.SimpleFSSearchMatch{{Start: 4, End: 9}}, "the [quick] brown fox"},
+		{"  the\n\tquick  brown\n", []keybase1.SimpleFSSearchMatch{{Start: 7, End: 12}}, "the [quick] brown"},
+		{"quick brown", []keybase1.SimpleFSSearchMatch{{Start: 0, End: 5}, {Start: 6, End: 11}}, "[quick] [brown]"},
+		{"quickbrown", []keybase1.SimpleFSSearchMatch{{Start: 0, End: 5}}, "[quick]brown"},
+		{"a  b", []keybase1.SimpleFSSearchMatch{{Start: 1, End: 3}}, "a b"},
+		{"a b", []keybase1.SimpleFSSearchMatch{{Start: 2, End: 9}}, "a b"},
+	} {
+		f := keybase1.SimpleFSSearchFragment{Text: tc.text, Matches: tc.matches}
+		require.Equal(t, tc.want, highlightFragment(f, brackets), tc.text)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"errors"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"golang.org/x/net/context"
+)
+
+// CmdSimpleFSWebDAV is the 'fs webdav' command.
+type CmdSimpleFSWebDAV struct {
+	libkb.Contextified
+	port int
+	stop bool
+}
+
+// NewCmdSimpleFSWebDAV creates a new cli.Command.
+func NewCmdSimpleFSWebDAV(
+	cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:  "webdav",
+		Usage: "serve KBFS read-write over WebDAV on localhost",
+		Action: func(c *cli.Context) {
+			cl.ChooseCommand(
+				&CmdSimpleFSWebDAV{Contextified: libkb.NewContextified(g)},
+				"webdav", c)
+			cl.SetNoStandalone()
+		},
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "p, port",
+				Usage: "listen on this port, instead of an automatically-picked one",
+			},
+			cli.BoolFlag{
+				Name:  "stop",
+				Usage: "stop the running WebDAV server",
+			},
+		},
+		Description: `Start a WebDAV server on the loopback interface, for
+   mounting KBFS in environments without FUSE.  If a server is already
+   running, its details are printed instead.
+
+   Clients must authenticate with HTTP basic auth, using any user name
+   and the printed token as the password.  The token expires after 10
+   minutes without any requests; run this command again for a new one.`,
+	}
+}
+
+// Run runs the command in client/server mode.
+func (c *CmdSimpleFSWebDAV) Run() error {
+	cli, err := GetSimpleFSClient(c.G())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.TODO()
+	if c.stop {
+		return cli.SimpleFSStopWebDAV(ctx)
+	}
+
+	info, err := cli.SimpleFSStartWebDAV(ctx, c.port)
+	if err != nil {
+		return err
+	}
+	ui := c.G().UI.GetTerminalUI()
+	ui.Printf("URL:\t%s\n", info.Url)
+	ui.Printf("Token:\t%s\n", info.Token)
+	return nil
+}
+
+// ParseArgv gets the optional flags.
+func (c *CmdSimpleFSWebDAV) ParseArgv(ctx *cli.Context) error {
+	if len(ctx.Args()) != 0 {
+		return errors.New("webdav takes no arguments")
+	}
+	c.port = ctx.Int("port")
+	c.stop = ctx.Bool("stop")
+	if c.port < 0 || c.port > 65535 {
+		return errors.New("invalid port")
+	}
+	if c.stop && c.port != 0 {
+		return errors.New("--stop and --port can't be used together")
+	}
+	return nil
+}
+
+// GetUsage says what this command needs to operate.
+func (c *CmdSimpleFSWebDAV) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		KbKeyring: true,
+		API:       true,
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+)
+
+// NewCmdSimpleFSXattr creates the xattr command, which is just a
+// holder for subcommands.
+func NewCmdSimpleFSXattr(
+	cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:  "xattr",
+		Usage: "Manages the user extended attributes of KBFS files",
+		Description: `Reading user extended attributes always works, but setting or
+   removing them is off by default, because KBFS clients that predate
+   them can't resolve conflicts that involve them.  Once every device
+   that writes to your folders is up to date, start KBFS with
+   --enable-user-xattrs to turn them on.`,
+		Subcommands: []cli.Command{
+			NewCmdSimpleFSXattrList(cl, g),
+			NewCmdSimpleFSXattrGet(cl, g),
+			NewCmdSimpleFSXattrSet(cl, g),
+			NewCmdSimpleFSXattrRemove(cl, g),
+		},
+	}
+}
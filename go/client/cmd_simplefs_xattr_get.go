@@ -0,0 +1,82 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+// CmdSimpleFSXattrGet is the 'fs xattr get' command.
+type CmdSimpleFSXattrGet struct {
+	libkb.Contextified
+	path keybase1.Path
+	name string
+}
+
+// NewCmdSimpleFSXattrGet creates a new cli.Command.
+func NewCmdSimpleFSXattrGet(
+	cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "get",
+		ArgumentHelp: "<path> <name>",
+		Usage:        "print the value of a user extended attribute",
+		Action: func(c *cli.Context) {
+			cl.ChooseCommand(&CmdSimpleFSXattrGet{
+				Contextified: libkb.NewContextified(g)}, "get", c)
+			cl.SetNoStandalone()
+		},
+	}
+}
+
+// Run runs the command in client/server mode.
+func (c *CmdSimpleFSXattrGet) Run() error {
+	cli, err := GetSimpleFSClient(c.G())
+	if err != nil {
+		return err
+	}
+
+	xattrs, err := cli.SimpleFSListXattrs(context.TODO(), c.path)
+	if err != nil {
+		return err
+	}
+
+	for _, x := range xattrs {
+		if x.Name == c.name {
+			c.G().UI.GetTerminalUI().Printf("%s\n", x.Value)
+			return nil
+		}
+	}
+	return fmt.Errorf("No extended attribute %q on %s", c.name, c.path)
+}
+
+// ParseArgv gets the required path and attribute name.
+func (c *CmdSimpleFSXattrGet) ParseArgv(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		return errors.New("xattr get requires a KBFS path and a name")
+	}
+
+	p, err := makeSimpleFSPath(ctx.Args()[0])
+	if err != nil {
+		return err
+	}
+	c.path = p
+	c.name = ctx.Args()[1]
+	return nil
+}
+
+// GetUsage says what this command needs to operate.
+func (c *CmdSimpleFSXattrGet) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		KbKeyring: true,
+		API:       true,
+	}
+}
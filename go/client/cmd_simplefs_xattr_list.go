@@ -0,0 +1,77 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"errors"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+// CmdSimpleFSXattrList is the 'fs xattr list' command.
+type CmdSimpleFSXattrList struct {
+	libkb.Contextified
+	path keybase1.Path
+}
+
+// NewCmdSimpleFSXattrList creates a new cli.Command.
+func NewCmdSimpleFSXattrList(
+	cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "list",
+		ArgumentHelp: "<path>",
+		Usage:        "list the user extended attributes of a file or directory",
+		Action: func(c *cli.Context) {
+			cl.ChooseCommand(&CmdSimpleFSXattrList{
+				Contextified: libkb.NewContextified(g)}, "list", c)
+			cl.SetNoStandalone()
+		},
+	}
+}
+
+// Run runs the command in client/server mode.
+func (c *CmdSimpleFSXattrList) Run() error {
+	cli, err := GetSimpleFSClient(c.G())
+	if err != nil {
+		return err
+	}
+
+	xattrs, err := cli.SimpleFSListXattrs(context.TODO(), c.path)
+	if err != nil {
+		return err
+	}
+
+	ui := c.G().UI.GetTerminalUI()
+	for _, x := range xattrs {
+		ui.Printf("%s\n", x.Name)
+	}
+	return nil
+}
+
+// ParseArgv gets the required path.
+func (c *CmdSimpleFSXattrList) ParseArgv(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		return errors.New("xattr list requires a KBFS path argument")
+	}
+
+	p, err := makeSimpleFSPath(ctx.Args()[0])
+	if err != nil {
+		return err
+	}
+	c.path = p
+	return nil
+}
+
+// GetUsage says what this command needs to operate.
+func (c *CmdSimpleFSXattrList) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		KbKeyring: true,
+		API:       true,
+	}
+}
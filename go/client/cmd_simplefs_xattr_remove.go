@@ -0,0 +1,75 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"errors"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+// CmdSimpleFSXattrRemove is the 'fs xattr rm' command.
+type CmdSimpleFSXattrRemove struct {
+	libkb.Contextified
+	path keybase1.Path
+	name string
+}
+
+// NewCmdSimpleFSXattrRemove creates a new cli.Command.
+func NewCmdSimpleFSXattrRemove(
+	cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "rm",
+		ArgumentHelp: "<path> <name>",
+		Usage: "remove a user extended attribute " +
+			"(needs KBFS to run with --enable-user-xattrs)",
+		Action: func(c *cli.Context) {
+			cl.ChooseCommand(&CmdSimpleFSXattrRemove{
+				Contextified: libkb.NewContextified(g)}, "rm", c)
+			cl.SetNoStandalone()
+		},
+	}
+}
+
+// Run runs the command in client/server mode.
+func (c *CmdSimpleFSXattrRemove) Run() error {
+	cli, err := GetSimpleFSClient(c.G())
+	if err != nil {
+		return err
+	}
+
+	return cli.SimpleFSRemoveXattr(
+		context.TODO(), keybase1.SimpleFSRemoveXattrArg{
+			Path: c.path,
+			Name: c.name,
+		})
+}
+
+// ParseArgv gets the required path and attribute name.
+func (c *CmdSimpleFSXattrRemove) ParseArgv(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		return errors.New("xattr rm requires a KBFS path and a name")
+	}
+
+	p, err := makeSimpleFSPath(ctx.Args()[0])
+	if err != nil {
+		return err
+	}
+	c.path = p
+	c.name = ctx.Args()[1]
+	return nil
+}
+
+// GetUsage says what this command needs to operate.
+func (c *CmdSimpleFSXattrRemove) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		KbKeyring: true,
+		API:       true,
+	}
+}
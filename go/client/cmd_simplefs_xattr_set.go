@@ -0,0 +1,79 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"errors"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+// CmdSimpleFSXattrSet is the 'fs xattr set' command.
+type CmdSimpleFSXattrSet struct {
+	libkb.Contextified
+	path  keybase1.Path
+	name  string
+	value []byte
+}
+
+// NewCmdSimpleFSXattrSet creates a new cli.Command.
+func NewCmdSimpleFSXattrSet(
+	cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "set",
+		ArgumentHelp: "<path> <name> <value>",
+		Usage: "set a user extended attribute " +
+			"(the name must start with \"user.\"; needs KBFS to run " +
+			"with --enable-user-xattrs)",
+		Action: func(c *cli.Context) {
+			cl.ChooseCommand(&CmdSimpleFSXattrSet{
+				Contextified: libkb.NewContextified(g)}, "set", c)
+			cl.SetNoStandalone()
+		},
+	}
+}
+
+// Run runs the command in client/server mode.
+func (c *CmdSimpleFSXattrSet) Run() error {
+	cli, err := GetSimpleFSClient(c.G())
+	if err != nil {
+		return err
+	}
+
+	return cli.SimpleFSSetXattr(context.TODO(), keybase1.SimpleFSSetXattrArg{
+		Path:  c.path,
+		Name:  c.name,
+		Value: c.value,
+	})
+}
+
+// ParseArgv gets the required path, attribute name and value.
+func (c *CmdSimpleFSXattrSet) ParseArgv(ctx *cli.Context) error {
+	if len(ctx.Args()) != 3 {
+		return errors.New(
+			"xattr set requires a KBFS path, a name and a value")
+	}
+
+	p, err := makeSimpleFSPath(ctx.Args()[0])
+	if err != nil {
+		return err
+	}
+	c.path = p
+	c.name = ctx.Args()[1]
+	c.value = []byte(ctx.Args()[2])
+	return nil
+}
+
+// GetUsage says what this command needs to operate.
+func (c *CmdSimpleFSXattrSet) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		KbKeyring: true,
+		API:       true,
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/net/context"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/keybase/go-framed-msgpack-rpc/rpc"
+)
+
+func NewCmdSSHAgent(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:  "ssh-agent",
+		Usage: "Serve the ssh-agent protocol with this device's key",
+		Action: func(c *cli.Context) {
+			cl.ChooseCommand(&CmdSSHAgent{Contextified: libkb.NewContextified(g)}, "ssh-agent", c)
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "a, socket",
+				Usage: "Listen on this socket (default is ssh-agent.sock in the Keybase runtime directory).",
+			},
+			cli.BoolFlag{
+				Name:  "p, print-key",
+				Usage: "Print the authorized_keys line for this device's key and exit.",
+			},
+		},
+		Description: sshAgentDoc,
+	}
+}
+
+type CmdSSHAgent struct {
+	libkb.Contextified
+	socket   string
+	printKey bool
+}
+
+func (c *CmdSSHAgent) ParseArgv(ctx *cli.Context) error {
+	if len(ctx.Args()) > 0 {
+		return UnexpectedArgsError("ssh-agent")
+	}
+	c.socket = ctx.String("socket")
+	if c.socket == "" {
+		c.socket = filepath.Join(c.G().Env.GetRuntimeDir(), "ssh-agent.sock")
+	}
+	c.printKey = ctx.Bool("print-key")
+	return nil
+}
+
+func (c *CmdSSHAgent) Run() error {
+	key, err := c.loadDeviceKey()
+	if err != nil {
+		return err
+	}
+	if c.printKey {
+		_, err := c.G().UI.GetDumbOutputUI().Printf("%s\n", key.authorizedKey())
+		return err
+	}
+
+	protocols := []rpc.Protocol{
+		NewSecretUIProtocol(c.G()),
+	}
+	if err := RegisterProtocolsWithContext(protocols, c.G()); err != nil {
+		return err
+	}
+	cli, err := GetCryptoClient(c.G())
+	if err != nil {
+		return err
+	}
+
+	// A socket left behind by an agent that didn't shut down cleanly would
+	// stop us from listening.
+	if err := os.Remove(c.socket); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l, err := net.Listen("unix", c.socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(c.socket)
+	defer l.Close()
+	if err := os.Chmod(c.socket, 0600); err != nil {
+		return err
+	}
+
+	dui := c.G().UI.GetDumbOutputUI()
+	dui.Printf("SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", c.socket)
+	dui.PrintfStderr("Serving %s on %s\n", key.comment, c.socket)
+
+	a := &keybaseSSHAgent{
+		Contextified: libkb.NewContextified(c.G()),
+		cli:          cli,
+		key:          key,
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := agent.ServeAgent(a, conn); err != nil && err != io.EOF {
+				c.G().Log.Debug("ssh-agent: connection ended: %v", err)
+			}
+		}()
+	}
+}
+
+func (c *CmdSSHAgent) loadDeviceKey() (*sshDeviceKey, error) {
+	cli, err := GetSessionClient(c.G())
+	if err != nil {
+		return nil, err
+	}
+	session, err := cli.CurrentSession(context.TODO(), 0)
+	if err != nil {
+		return nil, err
+	}
+	device := lookupDeviceName(c.G(), session.Uid, session.DeviceSibkeyKid)
+	return newSSHDeviceKey(session.DeviceSibkeyKid, session.Username, device)
+}
+
+func (c *CmdSSHAgent) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		API:       true,
+		KbKeyring: true,
+	}
+}
+
+// keybaseSSHAgent holds no keys itself: signatures are made by the service
+// with the device's signing key, and only for SSH logins and SSH signatures
+// (ssh-keygen -Y sign), so that nothing with access to the socket can get
+// the key to sign anything else.
+type keybaseSSHAgent struct {
+	libkb.Contextified
+	cli keybase1.CryptoClient
+	key *sshDeviceKey
+}
+
+var _ agent.Agent = (*keybaseSSHAgent)(nil)
+
+var errSSHAgentReadOnly = errors.New("the Keybase ssh-agent only holds the device key")
+
+func (a *keybaseSSHAgent) List() ([]*agent.Key, error) {
+	return []*agent.Key{{
+		Format:  a.key.pub.Type(),
+		Blob:    a.key.pub.Marshal(),
+		Comment: a.key.comment,
+	}}, nil
+}
+
+func (a *keybaseSSHAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	if !bytes.Equal(key.Marshal(), a.key.pub.Marshal()) {
+		return nil, errors.New("unknown key")
+	}
+	reason, err := checkSSHSignData(data, a.key.pub)
+	if err != nil {
+		a.G().Log.Warning("ssh-agent: refusing to sign: %v", err)
+		return nil, err
+	}
+	res, err := a.cli.SignED25519(context.TODO(), keybase1.SignED25519Arg{
+		Msg:    data,
+		Reason: reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.Signature{
+		Format: ssh.KeyAlgoED25519,
+		Blob:   res.Sig[:],
+	}, nil
+}
+
+func (a *keybaseSSHAgent) Add(agent.AddedKey) error       { return errSSHAgentReadOnly }
+func (a *keybaseSSHAgent) Remove(ssh.PublicKey) error     { return errSSHAgentReadOnly }
+func (a *keybaseSSHAgent) RemoveAll() error               { return errSSHAgentReadOnly }
+func (a *keybaseSSHAgent) Lock([]byte) error              { return errSSHAgentReadOnly }
+func (a *keybaseSSHAgent) Unlock([]byte) error            { return errSSHAgentReadOnly }
+func (a *keybaseSSHAgent) Signers() ([]ssh.Signer, error) { return nil, nil }
+
+const (
+	sshAuthMethodPublicKey = "publickey"
+	// OpenSSH 8.9 and later bind public key logins to the server's host key,
+	// which follows the user's public key in the signed data.
+	sshAuthMethodHostBound = "publickey-hostbound-v00@openssh.com"
+)
+
+// checkSSHSignData makes sure data is what an SSH client signs to log in with
+// key (RFC 4252 section 7, or OpenSSH's host-bound variant), or an SSHSIG
+// signed data blob, and returns the reason to give for the signature.
+func checkSSHSignData(data []byte, key ssh.PublicKey) (string, error) {
+	if bytes.HasPrefix(data, []byte("SSHSIG")) {
+		var sig struct {
+			Namespace string
+			Reserved  string
+			HashAlg   string
+			Hash      string
+		}
+		if err := ssh.Unmarshal(data[len("SSHSIG"):], &sig); err != nil {
+			return "", fmt.Errorf("malformed SSHSIG data: %v", err)
+		}
+		if sig.Namespace == "" {
+			return "", errors.New("SSHSIG data without a namespace")
+		}
+		return fmt.Sprintf("ssh-keygen signature (namespace %q)", sig.Namespace), nil
+	}
+
+	var req struct {
+		SessionID []byte
+		Type      byte
+		User      string
+		Service   string
+		Method    string
+		HasSig    bool
+		Algo      string
+		PubKey    []byte
+		Rest      []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &req); err != nil {
+		return "", fmt.Errorf("not an SSH login request: %v", err)
+	}
+	const msgUserAuthRequest = 50
+	if req.Type != msgUserAuthRequest || !req.HasSig ||
+		(req.Method != sshAuthMethodPublicKey && req.Method != sshAuthMethodHostBound) {
+		return "", errors.New("not an SSH public key login request")
+	}
+	if !bytes.Equal(req.PubKey, key.Marshal()) {
+		return "", errors.New("SSH login request is for a different key")
+	}
+	if req.Method == sshAuthMethodPublicKey {
+		if len(req.Rest) != 0 {
+			return "", errors.New("SSH login request has trailing data")
+		}
+		return fmt.Sprintf("ssh login as %q", req.User), nil
+	}
+
+	var hostBound struct {
+		HostKey []byte
+	}
+	if err := ssh.Unmarshal(req.Rest, &hostBound); err != nil {
+		return "", fmt.Errorf("malformed host-bound SSH login request: %v", err)
+	}
+	hostKey, err := ssh.ParsePublicKey(hostBound.HostKey)
+	if err != nil {
+		return "", fmt.Errorf("bad host key in SSH login request: %v", err)
+	}
+	return fmt.Sprintf("ssh login as %q to the host with key %s", req.User, ssh.FingerprintSHA256(hostKey)), nil
+}
+
+const sshAgentDoc = `"keybase ssh-agent" serves the ssh-agent protocol on a local socket, with the
+signing key of the current Keybase device as its only key. The key never
+leaves the Keybase service, which signs SSH logins and ssh-keygen -Y
+signatures with it and refuses to sign anything else.
+
+It prints the shell commands that point ssh at the socket, like ssh-agent,
+and runs until it is stopped:
+
+    keybase ssh-agent -a ~/.ssh/keybase-agent.sock &
+    export SSH_AUTH_SOCK=~/.ssh/keybase-agent.sock
+
+Print the authorized_keys line for this device, to give it access to a
+server:
+
+    keybase ssh-agent --print-key >> ~/.ssh/authorized_keys
+
+Forwarding the agent (ssh -A) lets the remote host log in as you while you
+are connected, as with any agent. See also "keybase ssh-keys".
+`
@@ -0,0 +1,127 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/adamwalz/keybase-client/go/kbcrypto"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+)
+
+func TestSSHDeviceKey(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	var naclPub kbcrypto.NaclSigningKeyPublic
+	copy(naclPub[:], pub)
+
+	key, err := newSSHDeviceKey(naclPub.GetKID(), "alice", "laptop")
+	require.NoError(t, err)
+	line := key.authorizedKey()
+	require.True(t, strings.HasPrefix(line, "ssh-ed25519 "))
+	require.True(t, strings.HasSuffix(line, " alice@keybase (laptop)"))
+
+	parsed, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	require.NoError(t, err)
+	require.Equal(t, "alice@keybase (laptop)", comment)
+	require.Equal(t, key.pub.Marshal(), parsed.Marshal())
+
+	// What the service signs with the device key verifies as an SSH signature.
+	msg := []byte("hello")
+	sig := &ssh.Signature{Format: ssh.KeyAlgoED25519, Blob: ed25519.Sign(priv, msg)}
+	require.NoError(t, parsed.Verify(msg, sig))
+
+	_, err = newSSHDeviceKey(keybase1.KID("0121"+strings.Repeat("ab", 32)+"0a"), "alice", "")
+	require.Error(t, err)
+}
+
+func TestCheckSSHSignData(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	other, err := ssh.NewPublicKey(otherPub)
+	require.NoError(t, err)
+
+	type userAuth struct {
+		SessionID []byte
+		Type      byte
+		User      string
+		Service   string
+		Method    string
+		HasSig    bool
+		Algo      string
+		PubKey    []byte
+	}
+	login := userAuth{
+		SessionID: []byte("session"),
+		Type:      50,
+		User:      "git",
+		Service:   "ssh-connection",
+		Method:    "publickey",
+		HasSig:    true,
+		Algo:      ssh.KeyAlgoED25519,
+		PubKey:    key.Marshal(),
+	}
+	reason, err := checkSSHSignData(ssh.Marshal(login), key)
+	require.NoError(t, err)
+	require.Contains(t, reason, `"git"`)
+
+	_, err = checkSSHSignData(ssh.Marshal(login), other)
+	require.Error(t, err)
+	bad := login
+	bad.Method = "password"
+	_, err = checkSSHSignData(ssh.Marshal(bad), key)
+	require.Error(t, err)
+
+	// A "publickey" request can't carry anything after the key.
+	_, err = checkSSHSignData(append(ssh.Marshal(login), ssh.Marshal(struct{ HostKey []byte }{other.Marshal()})...), key)
+	require.Error(t, err)
+
+	// OpenSSH 8.9 and later bind the login to the server's host key.
+	hostPub := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize)).Public().(ed25519.PublicKey)
+	hostKey, err := ssh.NewPublicKey(hostPub)
+	require.NoError(t, err)
+	hostBound := login
+	hostBound.Method = "publickey-hostbound-v00@openssh.com"
+	withHostKey := func(req userAuth, hostKey []byte) []byte {
+		return append(ssh.Marshal(req), ssh.Marshal(struct{ HostKey []byte }{hostKey})...)
+	}
+	hostBoundData := withHostKey(hostBound, hostKey.Marshal())
+	reason, err = checkSSHSignData(hostBoundData, key)
+	require.NoError(t, err)
+	require.Contains(t, reason, `"git"`)
+	require.Contains(t, reason, ssh.FingerprintSHA256(hostKey))
+	_, err = checkSSHSignData(hostBoundData, other)
+	require.Error(t, err)
+	// The host key has to be there, be a real key, and be the last thing.
+	_, err = checkSSHSignData(ssh.Marshal(hostBound), key)
+	require.Error(t, err)
+	_, err = checkSSHSignData(withHostKey(hostBound, []byte("not a key")), key)
+	require.Error(t, err)
+	_, err = checkSSHSignData(append(hostBoundData, 0), key)
+	require.Error(t, err)
+
+	sshsig := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Hash      string
+	}{"git", "", "sha512", "digest"})...)
+	reason, err = checkSSHSignData(sshsig, key)
+	require.NoError(t, err)
+	require.Contains(t, reason, `"git"`)
+
+	// Anything else, like a sigchain payload, is refused.
+	_, err = checkSSHSignData([]byte(`{"body":{"type":"sibkey"}}`), key)
+	require.Error(t, err)
+}
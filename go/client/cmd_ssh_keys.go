@@ -0,0 +1,218 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/context"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/kbcrypto"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/keybase/go-framed-msgpack-rpc/rpc"
+)
+
+// sshDeviceKey is the SSH form of a device's Ed25519 signing key.
+type sshDeviceKey struct {
+	pub     ssh.PublicKey
+	comment string
+}
+
+func newSSHDeviceKey(kid keybase1.KID, username, device string) (*sshDeviceKey, error) {
+	if kbcrypto.AlgoType(kid.GetKeyType()) != kbcrypto.KIDNaclEddsa {
+		return nil, fmt.Errorf("key %s is not an Ed25519 signing key", kid)
+	}
+	raw := kbcrypto.KIDToNaclSigningKeyPublic(kid.ToBytes())
+	if raw == nil {
+		return nil, fmt.Errorf("bad Ed25519 key %s", kid)
+	}
+	pub, err := ssh.NewPublicKey(ed25519.PublicKey(raw[:]))
+	if err != nil {
+		return nil, err
+	}
+	comment := username + "@keybase"
+	if device != "" {
+		comment += fmt.Sprintf(" (%s)", device)
+	}
+	return &sshDeviceKey{pub: pub, comment: comment}, nil
+}
+
+func (k *sshDeviceKey) authorizedKey() string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(k.pub))) + " " + k.comment
+}
+
+func NewCmdSSHKeys(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "ssh-keys",
+		ArgumentHelp: "<user or assertion>",
+		Usage:        "Print authorized_keys lines for a user's or team's devices",
+		Action: func(c *cli.Context) {
+			cl.ChooseCommand(&CmdSSHKeys{Contextified: libkb.NewContextified(g)}, "ssh-keys", c)
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "t, team",
+				Usage: "Print the keys of the members of this team instead.",
+			},
+			cli.StringFlag{
+				Name:  "r, min-role",
+				Usage: "With --team, only members with at least this role (reader, writer, admin, owner; default reader).",
+			},
+		},
+		Description: sshKeysDoc,
+	}
+}
+
+type CmdSSHKeys struct {
+	libkb.Contextified
+	user    string
+	team    string
+	minRole keybase1.TeamRole
+}
+
+func (c *CmdSSHKeys) ParseArgv(ctx *cli.Context) error {
+	c.team = ctx.String("team")
+	switch {
+	case c.team != "" && len(ctx.Args()) > 0:
+		return errors.New("give either a user or --team, not both")
+	case c.team == "" && len(ctx.Args()) != 1:
+		return errors.New("one user argument required")
+	case c.team == "":
+		c.user = ctx.Args()[0]
+	}
+	c.minRole = keybase1.TeamRole_READER
+	if s := ctx.String("min-role"); s != "" {
+		if c.team == "" {
+			return errors.New("--min-role only makes sense with --team")
+		}
+		role, ok := keybase1.TeamRoleMap[strings.ToUpper(s)]
+		if !ok || role.IsBotLike() || role == keybase1.TeamRole_NONE {
+			return errors.New("invalid --min-role, please use reader, writer, admin or owner")
+		}
+		c.minRole = role
+	}
+	return nil
+}
+
+func (c *CmdSSHKeys) Run() error {
+	protocols := []rpc.Protocol{
+		// Nothing but the keys goes to stdout, so that it can be appended
+		// to authorized_keys; identify failures come back as errors.
+		NewNullIdentifyUIProtocol(),
+	}
+	if err := RegisterProtocolsWithContext(protocols, c.G()); err != nil {
+		return err
+	}
+
+	users := []string{c.user}
+	if c.team != "" {
+		var err error
+		if users, err = c.teamMembers(); err != nil {
+			return err
+		}
+	}
+
+	cli, err := GetIdentifyClient(c.G())
+	if err != nil {
+		return err
+	}
+	dui := c.G().UI.GetDumbOutputUI()
+	var errs []error
+	for _, user := range users {
+		lines, err := c.userKeys(cli, user)
+		if err != nil {
+			// Keep going so that one member's broken identity doesn't lock
+			// the whole team out, but fail at the end so scripts notice.
+			dui.PrintfStderr("Skipping %s: %v\n", user, err)
+			errs = append(errs, fmt.Errorf("%s: %v", user, err))
+			continue
+		}
+		for _, line := range lines {
+			dui.Printf("%s\n", line)
+		}
+	}
+	return libkb.CombineErrors(errs...)
+}
+
+func (c *CmdSSHKeys) teamMembers() (users []string, err error) {
+	cli, err := GetTeamsClient(c.G())
+	if err != nil {
+		return nil, err
+	}
+	details, err := cli.TeamGet(context.TODO(), keybase1.TeamGetArg{Name: c.team})
+	if err != nil {
+		return nil, err
+	}
+	members := details.Members
+	for _, list := range [][]keybase1.TeamMemberDetails{members.Owners, members.Admins, members.Writers, members.Readers} {
+		for _, m := range list {
+			if m.Status == keybase1.TeamMemberStatus_ACTIVE && m.Role.IsOrAbove(c.minRole) {
+				users = append(users, m.Username)
+			}
+		}
+	}
+	return users, nil
+}
+
+// userKeys identifies the user and returns the authorized_keys lines for the
+// signing keys of their active devices, leaving out paper keys.
+func (c *CmdSSHKeys) userKeys(cli keybase1.IdentifyClient, assertion string) (lines []string, err error) {
+	res, err := cli.Identify2(context.TODO(), keybase1.Identify2Arg{
+		UserAssertion:    assertion,
+		Reason:           keybase1.IdentifyReason{Reason: "CLI ssh-keys command"},
+		AlwaysBlock:      true,
+		NoSkipSelf:       true,
+		IdentifyBehavior: keybase1.TLFIdentifyBehavior_CLI,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range res.Upk.DeviceKeys {
+		if !key.IsSibkey || key.DeviceType == keybase1.DeviceTypeV2_PAPER ||
+			kbcrypto.AlgoType(key.KID.GetKeyType()) != kbcrypto.KIDNaclEddsa {
+			continue
+		}
+		k, err := newSSHDeviceKey(key.KID, res.Upk.Username, key.DeviceDescription)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, k.authorizedKey())
+	}
+	return lines, nil
+}
+
+func (c *CmdSSHKeys) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		API:       true,
+		KbKeyring: true,
+	}
+}
+
+const sshKeysDoc = `"keybase ssh-keys" identifies a user, like "keybase id", and prints an
+authorized_keys line for the signing key of each of their active devices.
+These are the keys "keybase ssh-agent" uses. Paper keys are left out.
+
+With --team, it does the same for every member of the team. Members whose
+identity can't be checked are skipped with a warning, and the command fails
+once it has printed the rest.
+
+EXAMPLES:
+
+Give alice's devices access to this account:
+
+    keybase ssh-keys alice >> ~/.ssh/authorized_keys
+
+Give every writer of acme.ops access, refreshing the file from cron:
+
+    keybase ssh-keys --team acme.ops --min-role writer > ~/.ssh/authorized_keys.new &&
+        mv ~/.ssh/authorized_keys.new ~/.ssh/authorized_keys
+`
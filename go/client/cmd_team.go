@@ -16,6 +16,11 @@ func NewCmdTeam(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command
 		newCmdTeamCreate(cl, g),
 		newCmdTeamAddMember(cl, g),
 		newCmdTeamAddMembersBulk(cl, g),
+		newCmdTeamApply(cl, g),
+		newCmdTeamHistory(cl, g),
+		newCmdTeamExportChain(cl, g),
+		newCmdTeamVerifyChain(cl, g),
+		newCmdTeamListExpiring(cl, g),
 		newCmdTeamRemoveMember(cl, g),
 		newCmdTeamEditMember(cl, g),
 		newCmdTeamListMemberships(cl, g),
@@ -6,6 +6,10 @@ package client
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/keybase/cli"
 	"github.com/adamwalz/keybase-client/go/libcmdline"
 	"github.com/adamwalz/keybase-client/go/libkb"
@@ -23,6 +27,8 @@ type CmdTeamAddMember struct {
 	BotSettings          *keybase1.TeamBotSettings
 	SkipChatNotification bool
 	EmailInviteMessage   *string
+	Expires              time.Duration
+	DowngradeTo          keybase1.TeamRole
 }
 
 func newCmdTeamAddMember(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
@@ -58,6 +64,14 @@ func newCmdTeamAddMember(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli
 				Name:  "m, email-invite-message",
 				Usage: "send a welcome message along with your email invitation",
 			},
+			cli.StringFlag{
+				Name:  "expires",
+				Usage: "end the membership after this long (e.g. 72h, 30d, 2w)",
+			},
+			cli.StringFlag{
+				Name:  "downgrade-to",
+				Usage: "with --expires, change the member's role to this instead of removing them",
+			},
 		},
 		Description: teamAddMemberDoc,
 	}
@@ -87,8 +101,27 @@ func (c *CmdTeamAddMember) ParseArgv(ctx *cli.Context) error {
 		c.EmailInviteMessage = &emailInviteMsg
 	}
 
+	if expires := ctx.String("expires"); expires != "" {
+		c.Expires, err = parseMembershipDuration(expires)
+		if err != nil {
+			return err
+		}
+		if downgradeTo := ctx.String("downgrade-to"); downgradeTo != "" {
+			var ok bool
+			c.DowngradeTo, ok = keybase1.TeamRoleMap[strings.ToUpper(downgradeTo)]
+			if !ok {
+				return errors.New("invalid --downgrade-to role, please use owner, admin, writer or reader")
+			}
+		}
+	} else if ctx.String("downgrade-to") != "" {
+		return errors.New("--downgrade-to only makes sense with --expires")
+	}
+
 	c.Email = ctx.String("email")
 	if len(c.Email) > 0 {
+		if c.Expires != 0 {
+			return errors.New("--expires only works with --user")
+		}
 		if !libkb.CheckEmail.F(c.Email) {
 			return errors.New("invalid email address")
 		}
@@ -97,6 +130,9 @@ func (c *CmdTeamAddMember) ParseArgv(ctx *cli.Context) error {
 
 	c.Phone = ctx.String("phone")
 	if len(c.Phone) > 0 {
+		if c.Expires != 0 {
+			return errors.New("--expires only works with --user")
+		}
 		return nil
 	}
 
@@ -154,6 +190,11 @@ func (c *CmdTeamAddMember) Run() error {
 		} else if c.Phone != "" {
 			dui.Printf("%s matched the Keybase username %s.\n", c.Phone, res.User.Username)
 		}
+		if c.Expires != 0 {
+			if err := c.setExpiry(cli, res.User.Username); err != nil {
+				return err
+			}
+		}
 		if res.ChatSending {
 			// The chat message may still be in flight or fail.
 			dui.Printf("Success! A keybase chat message has been sent to %s. To skip this, use `-s` or `--skip-chat-message`\n", res.User.Username)
@@ -177,6 +218,10 @@ func (c *CmdTeamAddMember) Run() error {
 		return nil
 	}
 
+	if c.Expires != 0 {
+		dui.Printf("The membership can't expire until the invitation is accepted; run add-member with --expires again then.\n")
+	}
+
 	if res.User != nil {
 		// user without keys or without puk
 		dui.Printf("Pending! Keybase stored a team invitation for %s. When they open the Keybase app, their account will be upgraded and you will be notified.\n", res.User.Username)
@@ -188,6 +233,21 @@ func (c *CmdTeamAddMember) Run() error {
 	return nil
 }
 
+func (c *CmdTeamAddMember) setExpiry(cli keybase1.TeamsClient, username string) error {
+	expiresAt := time.Now().Add(c.Expires)
+	err := cli.TeamSetMemberExpiry(context.Background(), keybase1.TeamSetMemberExpiryArg{
+		TeamName:    c.Team,
+		Username:    username,
+		ExpiresAt:   keybase1.ToTime(expiresAt),
+		DowngradeTo: c.DowngradeTo,
+	})
+	if err != nil {
+		return fmt.Errorf("%s was added to %s, but setting the membership to expire failed: %v", username, c.Team, err)
+	}
+	c.G().UI.GetDumbOutputUI().Printf("The membership expires %s.\n", expiresAt.Format(time.RFC1123))
+	return nil
+}
+
 func (c *CmdTeamAddMember) GetUsage() libkb.Usage {
 	return libkb.Usage{
 		Config:    true,
@@ -215,4 +275,15 @@ Add a user via email:
 Add a user via phone:
 
     keybase team add-member acme --phone=18581234567 --role=reader
+
+Add a contractor for 30 days, after which an admin's service removes them:
+
+    keybase team add-member acme --user=alice --role=writer --expires=30d
+
+Or keeps them on as a reader:
+
+    keybase team add-member acme --user=alice --role=writer --expires=30d --downgrade-to=reader
+
+Admins are warned in chat a few days before a membership expires. See
+"keybase team list-expiring".
 `
@@ -0,0 +1,227 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+type CmdTeamApply struct {
+	libkb.Contextified
+	infile string
+	dryRun bool
+}
+
+func newCmdTeamApply(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "apply",
+		ArgumentHelp: "<file | ->",
+		Usage:        "Bring teams and their members to the state described in a file.",
+		Action: func(c *cli.Context) {
+			cmd := NewCmdTeamApplyRunner(g)
+			cl.ChooseCommand(cmd, "apply", c)
+		},
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "n, dry-run",
+				Usage: "only show the changes, don't make them",
+			},
+		},
+		Description: teamApplyDoc,
+	}
+}
+
+func NewCmdTeamApplyRunner(g *libkb.GlobalContext) *CmdTeamApply {
+	return &CmdTeamApply{Contextified: libkb.NewContextified(g)}
+}
+
+func (c *CmdTeamApply) ParseArgv(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		return errors.New("apply requires a file name, or - to read from stdin")
+	}
+	c.infile = ctx.Args()[0]
+	c.dryRun = ctx.Bool("dry-run")
+	return nil
+}
+
+func (c *CmdTeamApply) readSpec() (opts applyOptions, err error) {
+	var b []byte
+	if c.infile == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(c.infile)
+	}
+	if err != nil {
+		return opts, err
+	}
+	if err := json.Unmarshal(b, &opts); err != nil {
+		return opts, fmt.Errorf("could not parse %s: %v", c.infile, err)
+	}
+	return opts, opts.Check()
+}
+
+func (c *CmdTeamApply) Run() error {
+	opts, err := c.readSpec()
+	if err != nil {
+		return err
+	}
+	teams, err := opts.teams()
+	if err != nil {
+		return err
+	}
+
+	cli, err := GetTeamsClient(c.G())
+	if err != nil {
+		return err
+	}
+	res, err := cli.TeamApply(context.Background(), keybase1.TeamApplyArg{
+		Teams:  teams,
+		DryRun: c.dryRun || opts.DryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	dui := c.G().UI.GetTerminalUI()
+	for _, change := range res.Changes {
+		dui.Printf("%s\n", c.formatChange(change))
+	}
+	switch {
+	case len(res.Changes) == 0:
+		dui.Printf("Nothing to change.\n")
+	case res.Applied:
+		dui.Printf("Applied %d change(s).\n", len(res.Changes))
+	case len(res.Teams) == 0:
+		dui.Printf("Dry run: %d change(s) not applied.\n", len(res.Changes))
+	default:
+		return c.reportFailure(res)
+	}
+	return nil
+}
+
+// reportFailure says which teams were changed before one of them failed.
+func (c *CmdTeamApply) reportFailure(res keybase1.TeamApplyResult) error {
+	dui := c.G().UI.GetTerminalUI()
+	var failed keybase1.TeamApplyTeamResult
+	for _, team := range res.Teams {
+		if team.Applied {
+			dui.Printf("Applied the changes to %s.\n", team.TeamName)
+			continue
+		}
+		failed = team
+	}
+	var msg string
+	if failed.Error != nil {
+		msg = *failed.Error
+	}
+	return fmt.Errorf("failed to apply the changes to %s (some of them may have been made), "+
+		"and stopped before any later teams: %s", failed.TeamName, msg)
+}
+
+func formatBotSettings(s *keybase1.TeamBotSettings) string {
+	if s == nil {
+		return "none"
+	}
+	var parts []string
+	if s.Cmds {
+		parts = append(parts, "commands")
+	}
+	if s.Mentions {
+		parts = append(parts, "mentions")
+	}
+	if len(s.Triggers) > 0 {
+		parts = append(parts, fmt.Sprintf("triggers %s", strings.Join(s.Triggers, ",")))
+	}
+	if len(s.Convs) > 0 {
+		parts = append(parts, fmt.Sprintf("%d conversation(s)", len(s.Convs)))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (c *CmdTeamApply) formatChange(change keybase1.TeamApplyChange) string {
+	var sign, color, desc string
+	switch change.Type {
+	case keybase1.TeamApplyChangeType_CREATE_SUBTEAM:
+		sign, color, desc = "+", "green", "create subteam"
+	case keybase1.TeamApplyChangeType_ADD_MEMBER:
+		sign, color = "+", "green"
+		desc = fmt.Sprintf("add %s as %s", change.Username, change.NewRole.HumanString())
+		if change.BotSettings != nil {
+			desc += fmt.Sprintf(" (bot settings: %s)", formatBotSettings(change.BotSettings))
+		}
+	case keybase1.TeamApplyChangeType_CHANGE_ROLE:
+		sign, color = "~", "yellow"
+		desc = fmt.Sprintf("change %s from %s to %s", change.Username,
+			change.OldRole.HumanString(), change.NewRole.HumanString())
+	case keybase1.TeamApplyChangeType_SET_BOT_SETTINGS:
+		sign, color = "~", "yellow"
+		desc = fmt.Sprintf("set bot settings of %s to %s", change.Username, formatBotSettings(change.BotSettings))
+	case keybase1.TeamApplyChangeType_REMOVE_MEMBER:
+		sign, color = "-", "red"
+		desc = fmt.Sprintf("remove %s (%s)", change.Username, change.OldRole.HumanString())
+	case keybase1.TeamApplyChangeType_CHANGE_SETTINGS:
+		sign, color, desc = "~", "yellow", "make team closed"
+		if change.Settings != nil && change.Settings.Open {
+			desc = fmt.Sprintf("make team open, joining as %s", change.Settings.JoinAs.HumanString())
+		}
+	default:
+		sign, color, desc = "?", "yellow", change.Type.String()
+	}
+	return ColorString(c.G(), color, "%s %s: %s", sign, change.TeamName, desc)
+}
+
+func (c *CmdTeamApply) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		API:       true,
+		KbKeyring: true,
+	}
+}
+
+const teamApplyDoc = `"keybase team apply" brings a set of teams to the state described in a JSON
+file: members not in the file are removed, missing ones are added, and roles,
+bot settings and open-team settings are changed to match. Subteams that don't
+exist yet are created. Teams are changed in order, parents first.
+
+The file has the same format as the options of the "apply" method of
+"keybase team api":
+
+    {"teams": [
+      {"team": "acme", "members": [
+        {"username": "alice", "role": "owner"},
+        {"username": "bob", "role": "writer"}
+      ], "settings": {"open": true, "join-as": "reader"}},
+      {"team": "acme.bots", "members": [
+        {"username": "bob", "role": "admin"},
+        {"username": "helperbot", "role": "restrictedbot", "bot-settings": {"cmds": true}}
+      ]}
+    ]}
+
+"settings" can be left out to leave a team's settings alone. You can't remove
+or demote yourself with "apply".
+
+EXAMPLES:
+
+Show what would change, without changing anything:
+
+    keybase team apply --dry-run teams.json
+
+Apply the changes:
+
+    keybase team apply teams.json
+`
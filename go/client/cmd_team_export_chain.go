@@ -0,0 +1,95 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"os"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+type CmdTeamExportChain struct {
+	libkb.Contextified
+	teamName string
+	outfile  string
+}
+
+func newCmdTeamExportChain(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "export-chain",
+		ArgumentHelp: "<team name>",
+		Usage:        "Export a team's sigchain so that it can be verified offline.",
+		Action: func(c *cli.Context) {
+			cmd := NewCmdTeamExportChainRunner(g)
+			cl.ChooseCommand(cmd, "export-chain", c)
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "o, outfile",
+				Usage: "write the export to this file instead of stdout",
+			},
+		},
+		Description: teamExportChainDoc,
+	}
+}
+
+func NewCmdTeamExportChainRunner(g *libkb.GlobalContext) *CmdTeamExportChain {
+	return &CmdTeamExportChain{Contextified: libkb.NewContextified(g)}
+}
+
+func (c *CmdTeamExportChain) ParseArgv(ctx *cli.Context) (err error) {
+	c.teamName, err = ParseOneTeamName(ctx)
+	if err != nil {
+		return err
+	}
+	c.outfile = ctx.String("outfile")
+	return nil
+}
+
+func (c *CmdTeamExportChain) Run() error {
+	cli, err := GetTeamsClient(c.G())
+	if err != nil {
+		return err
+	}
+	bundle, err := cli.TeamExportChain(context.Background(), keybase1.TeamExportChainArg{
+		TeamName: c.teamName,
+	})
+	if err != nil {
+		return err
+	}
+	if c.outfile != "" {
+		return os.WriteFile(c.outfile, []byte(bundle+"\n"), 0644)
+	}
+	_, err = c.G().UI.GetDumbOutputUI().Printf("%s\n", bundle)
+	return err
+}
+
+func (c *CmdTeamExportChain) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		API:       true,
+		KbKeyring: true,
+	}
+}
+
+const teamExportChainDoc = `"keybase team export-chain" writes out everything needed to check the
+sigchain of a team without a connection to the Keybase servers: the links of
+its sigchain and hidden chain, the chains of its parent teams, the sigchains
+of the users who signed them, and the paths in the Merkle tree that anchor
+all of these.
+
+Links that you aren't allowed to see, like invites for non-admins, are
+exported stubbed. The export is verified before it is written out; use
+"keybase team verify-chain" to verify it again later, or elsewhere.
+
+EXAMPLES:
+
+Export the sigchain of a team to a file:
+
+    keybase team export-chain acme -o acme-chain.json
+`
@@ -0,0 +1,224 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+type CmdTeamHistory struct {
+	libkb.Contextified
+	arg  keybase1.TeamHistoryArg
+	json bool
+}
+
+func newCmdTeamHistory(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "history",
+		ArgumentHelp: "<team name>",
+		Usage:        "Show who changed a team's members, invites, settings and keys, and when.",
+		Action: func(c *cli.Context) {
+			cmd := NewCmdTeamHistoryRunner(g)
+			cl.ChooseCommand(cmd, "history", c)
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "m, member",
+				Usage: "only show events about this user",
+			},
+			cli.StringFlag{
+				Name:  "after",
+				Usage: "only show events at or after this date (YYYY-MM-DD or RFC 3339)",
+			},
+			cli.StringFlag{
+				Name:  "before",
+				Usage: "only show events before this date (YYYY-MM-DD or RFC 3339)",
+			},
+			cli.BoolFlag{
+				Name:  "j, json",
+				Usage: "output events as JSON",
+			},
+		},
+		Description: teamHistoryDoc,
+	}
+}
+
+func NewCmdTeamHistoryRunner(g *libkb.GlobalContext) *CmdTeamHistory {
+	return &CmdTeamHistory{Contextified: libkb.NewContextified(g)}
+}
+
+func (c *CmdTeamHistory) ParseArgv(ctx *cli.Context) (err error) {
+	c.arg.TeamName, err = ParseOneTeamName(ctx)
+	if err != nil {
+		return err
+	}
+	c.arg.Member = ctx.String("member")
+	if c.arg.After, err = parseDateOrTime(ctx.String("after")); err != nil {
+		return err
+	}
+	if c.arg.Before, err = parseDateOrTime(ctx.String("before")); err != nil {
+		return err
+	}
+	c.json = ctx.Bool("json")
+	return nil
+}
+
+func (c *CmdTeamHistory) Run() error {
+	cli, err := GetTeamsClient(c.G())
+	if err != nil {
+		return err
+	}
+	events, err := cli.TeamHistory(context.Background(), c.arg)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		b, err := json.MarshalIndent(events, "", "    ")
+		if err != nil {
+			return err
+		}
+		dui := c.G().UI.GetDumbOutputUI()
+		_, err = dui.Printf(string(b) + "\n")
+		return err
+	}
+
+	dui := c.G().UI.GetTerminalUI()
+	if len(events) == 0 {
+		dui.Printf("No events found.\n")
+		return nil
+	}
+	tabw := new(tabwriter.Writer)
+	tabw.Init(dui.OutputWriter(), 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tabw, "TIME\tLINK\tMERKLE\tSIGNED BY\tEVENT\n")
+	for _, e := range events {
+		fmt.Fprintf(tabw, "%s\t%s\t%d\t%s\t%s\n", formatHistoryTime(e.Time), formatHistoryLocation(e.Location),
+			e.MerkleSeqno, formatHistorySigner(e), formatHistoryEvent(e))
+	}
+	return tabw.Flush()
+}
+
+func formatHistoryTime(t keybase1.Time) string {
+	if t == 0 {
+		return "unknown"
+	}
+	return t.Time().Local().Format("2006-01-02 15:04:05")
+}
+
+func formatHistoryLocation(loc keybase1.SigChainLocation) string {
+	if loc.SeqType == keybase1.SeqType_TEAM_PRIVATE_HIDDEN {
+		return fmt.Sprintf("hidden:%d", loc.Seqno)
+	}
+	return fmt.Sprintf("%d", loc.Seqno)
+}
+
+func formatHistorySigner(e keybase1.TeamHistoryEvent) string {
+	signer := e.SignerUsername
+	if signer == "" {
+		signer = e.Signer.Uid.String()
+	}
+	if e.SigningDevice != "" {
+		signer += fmt.Sprintf(" (%s)", e.SigningDevice)
+	}
+	if e.SignerIsImplicitAdmin {
+		signer += " [implicit admin]"
+	}
+	return signer
+}
+
+func formatHistoryMember(e keybase1.TeamHistoryEvent) string {
+	if e.MemberUsername != "" {
+		return e.MemberUsername
+	}
+	if e.Member != nil {
+		return e.Member.String()
+	}
+	return "?"
+}
+
+func formatHistoryEvent(e keybase1.TeamHistoryEvent) string {
+	switch e.Type {
+	case keybase1.TeamHistoryEventType_CREATED:
+		return "created team"
+	case keybase1.TeamHistoryEventType_MEMBER_ADDED:
+		return fmt.Sprintf("added %s as %s", formatHistoryMember(e), e.NewRole.HumanString())
+	case keybase1.TeamHistoryEventType_ROLE_CHANGED:
+		return fmt.Sprintf("changed %s from %s to %s", formatHistoryMember(e),
+			e.OldRole.HumanString(), e.NewRole.HumanString())
+	case keybase1.TeamHistoryEventType_MEMBER_REMOVED:
+		if e.Member != nil && e.Member.Eq(e.Signer) {
+			return fmt.Sprintf("%s left (was %s)", formatHistoryMember(e), e.OldRole.HumanString())
+		}
+		return fmt.Sprintf("removed %s (was %s)", formatHistoryMember(e), e.OldRole.HumanString())
+	case keybase1.TeamHistoryEventType_INVITE_ADDED:
+		return fmt.Sprintf("invited %s as %s", formatHistoryInvite(e.Invite), e.NewRole.HumanString())
+	case keybase1.TeamHistoryEventType_INVITE_CANCELED:
+		return fmt.Sprintf("canceled invite for %s", formatHistoryInvite(e.Invite))
+	case keybase1.TeamHistoryEventType_INVITE_COMPLETED:
+		if e.Member != nil {
+			return fmt.Sprintf("%s joined with invite for %s", formatHistoryMember(e), formatHistoryInvite(e.Invite))
+		}
+		return fmt.Sprintf("completed invite for %s", formatHistoryInvite(e.Invite))
+	case keybase1.TeamHistoryEventType_SETTINGS_CHANGED:
+		if e.Settings != nil && e.Settings.Open {
+			return fmt.Sprintf("made team open, joining as %s", e.Settings.JoinAs.HumanString())
+		}
+		return "made team closed"
+	case keybase1.TeamHistoryEventType_KEY_ROTATED:
+		return fmt.Sprintf("rotated team key to generation %d", e.Generation)
+	default:
+		return e.Type.String()
+	}
+}
+
+func formatHistoryInvite(invite *keybase1.TeamInvite) string {
+	if invite == nil {
+		return "?"
+	}
+	typ, err := invite.Type.String()
+	if err != nil || typ == "keybase" {
+		return string(invite.Name)
+	}
+	return fmt.Sprintf("%s (%s)", invite.Name, typ)
+}
+
+func (c *CmdTeamHistory) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		API:       true,
+		KbKeyring: true,
+	}
+}
+
+const teamHistoryDoc = `"keybase team history" replays the sigchain of a team to list, oldest first,
+when members were added or removed or had their role changed, when invites
+were made, used or canceled, when the team was opened or closed, and when its
+key was rotated. Each event shows who signed it (and with which device), and
+where it is in the team's sigchain and the Merkle tree.
+
+Admins see everything; other members don't see the invites and settings
+changes that are hidden from them.
+
+EXAMPLES:
+
+Show the whole history of a team:
+
+    keybase team history acme
+
+Show when alice joined or left, or had their role changed, this year:
+
+    keybase team history acme --member alice --after 2020-01-01
+
+Output the history as JSON:
+
+    keybase team history acme --json
+`
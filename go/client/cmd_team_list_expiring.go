@@ -0,0 +1,131 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+type CmdTeamListExpiring struct {
+	libkb.Contextified
+	arg  keybase1.TeamListExpiringMembersArg
+	json bool
+}
+
+func newCmdTeamListExpiring(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "list-expiring",
+		ArgumentHelp: "[team name]",
+		Usage:        "List team memberships that are set to expire.",
+		Action: func(c *cli.Context) {
+			cmd := NewCmdTeamListExpiringRunner(g)
+			cl.ChooseCommand(cmd, "list-expiring", c)
+		},
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "j, json",
+				Usage: "output memberships as JSON",
+			},
+		},
+		Description: teamListExpiringDoc,
+	}
+}
+
+func NewCmdTeamListExpiringRunner(g *libkb.GlobalContext) *CmdTeamListExpiring {
+	return &CmdTeamListExpiring{Contextified: libkb.NewContextified(g)}
+}
+
+func (c *CmdTeamListExpiring) ParseArgv(ctx *cli.Context) error {
+	switch len(ctx.Args()) {
+	case 0:
+	case 1:
+		c.arg.TeamName = ctx.Args()[0]
+	default:
+		return errors.New("at most one team name argument allowed")
+	}
+	c.json = ctx.Bool("json")
+	return nil
+}
+
+func (c *CmdTeamListExpiring) Run() error {
+	cli, err := GetTeamsClient(c.G())
+	if err != nil {
+		return err
+	}
+	expiries, err := cli.TeamListExpiringMembers(context.Background(), c.arg)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		b, err := json.MarshalIndent(expiries, "", "    ")
+		if err != nil {
+			return err
+		}
+		dui := c.G().UI.GetDumbOutputUI()
+		_, err = dui.Printf(string(b) + "\n")
+		return err
+	}
+
+	dui := c.G().UI.GetTerminalUI()
+	if len(expiries) == 0 {
+		dui.Printf("No memberships are set to expire.\n")
+		return nil
+	}
+	tabw := new(tabwriter.Writer)
+	tabw.Init(dui.OutputWriter(), 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tabw, "EXPIRES\tTEAM\tMEMBER\tROLE\tTHEN\tSET BY\tWARNED\n")
+	for _, e := range expiries {
+		then := "remove"
+		if e.DowngradeTo != keybase1.TeamRole_NONE {
+			then = "downgrade to " + e.DowngradeTo.HumanString()
+		}
+		role := e.Role.HumanString()
+		if e.Role == keybase1.TeamRole_NONE {
+			role = "not a member"
+		}
+		warned := "no"
+		if e.WarnedAt != 0 {
+			warned = formatHistoryTime(e.WarnedAt)
+		}
+		fmt.Fprintf(tabw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", formatHistoryTime(e.ExpiresAt), e.TeamName,
+			e.Username, role, then, e.SetBy, warned)
+	}
+	return tabw.Flush()
+}
+
+func (c *CmdTeamListExpiring) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config:    true,
+		API:       true,
+		KbKeyring: true,
+	}
+}
+
+const teamListExpiringDoc = `"keybase team list-expiring" lists the memberships that were set to expire
+with "keybase team add-member --expires", soonest first. Without a team name,
+it lists them for every team you are an admin of.
+
+The services of the team's admins warn the admins in chat a few days before a
+membership expires, and then remove the member or change their role.
+
+EXAMPLES:
+
+List the memberships of acme that are set to expire:
+
+    keybase team list-expiring acme
+
+List them for all of your teams, as JSON:
+
+    keybase team list-expiring --json
+`
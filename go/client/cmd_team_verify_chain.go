@@ -0,0 +1,127 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/keybase/cli"
+	"github.com/adamwalz/keybase-client/go/libcmdline"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+type CmdTeamVerifyChain struct {
+	libkb.Contextified
+	infile string
+	json   bool
+}
+
+func newCmdTeamVerifyChain(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Command {
+	return cli.Command{
+		Name:         "verify-chain",
+		ArgumentHelp: "<file | ->",
+		Usage:        "Verify a sigchain exported with export-chain, offline.",
+		Action: func(c *cli.Context) {
+			cmd := NewCmdTeamVerifyChainRunner(g)
+			cl.ChooseCommand(cmd, "verify-chain", c)
+		},
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "j, json",
+				Usage: "output the result as JSON",
+			},
+		},
+		Description: teamVerifyChainDoc,
+	}
+}
+
+func NewCmdTeamVerifyChainRunner(g *libkb.GlobalContext) *CmdTeamVerifyChain {
+	return &CmdTeamVerifyChain{Contextified: libkb.NewContextified(g)}
+}
+
+func (c *CmdTeamVerifyChain) ParseArgv(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		return errors.New("verify-chain requires a file name, or - to read from stdin")
+	}
+	c.infile = ctx.Args()[0]
+	c.json = ctx.Bool("json")
+	return nil
+}
+
+func (c *CmdTeamVerifyChain) Run() error {
+	var b []byte
+	var err error
+	if c.infile == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(c.infile)
+	}
+	if err != nil {
+		return err
+	}
+
+	cli, err := GetTeamsClient(c.G())
+	if err != nil {
+		return err
+	}
+	res, err := cli.TeamVerifyChain(context.Background(), keybase1.TeamVerifyChainArg{
+		Bundle: string(b),
+	})
+	if err != nil {
+		return fmt.Errorf("%s failed to verify: %v", c.infile, err)
+	}
+
+	if c.json {
+		b, err := json.MarshalIndent(res, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = c.G().UI.GetDumbOutputUI().Printf(string(b) + "\n")
+		return err
+	}
+
+	dui := c.G().UI.GetTerminalUI()
+	dui.Printf("%s %s (%s)\n", ColorString(c.G(), "green", "Verified"), res.TeamName, res.TeamID)
+	dui.Printf("  sigchain:     %d links, ending in %s\n", res.LastSeqno, res.LastLinkID)
+	if res.StubbedLinks > 0 {
+		dui.Printf("                %d of them stubbed, checked by their place in the chain only\n", res.StubbedLinks)
+	}
+	dui.Printf("  hidden chain: %d links\n", res.LastHiddenSeqno)
+	dui.Printf("  parent teams: %d\n", res.Ancestors)
+	dui.Printf("  signed by:    %d users, with %d key and adminship ordering proofs\n", res.Signers, res.HappensBeforeProofs)
+	dui.Printf("  merkle root:  %d (%s)\n", res.MerkleSeqno, formatHistoryTime(res.MerkleTime))
+	dui.Printf("  exported at:  %s\n", formatHistoryTime(res.ExportedAt))
+	return nil
+}
+
+func (c *CmdTeamVerifyChain) GetUsage() libkb.Usage {
+	return libkb.Usage{
+		Config: true,
+	}
+}
+
+const teamVerifyChainDoc = `"keybase team verify-chain" checks a file written by "keybase team
+export-chain", without talking to the Keybase servers. The sigchain is
+replayed with the same checks the client makes when it loads a team:
+
+  - every link is signed by a key of its signer, which was not revoked
+    before the link was signed. The signers' keys come from their own
+    sigchains, which are verified and anchored the same way;
+  - the signer had the role the link needs, in the team or (for implicit
+    admins) in a parent team, when the link was signed;
+  - the chain, and the hidden chain, end at the tails in a Merkle tree root
+    signed by Keybase.
+
+Verification does not need you to be logged in, nor to be in the team.
+
+EXAMPLES:
+
+    keybase team verify-chain acme-chain.json
+`
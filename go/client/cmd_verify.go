@@ -5,7 +5,9 @@ package client
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"golang.org/x/net/context"
 
@@ -52,6 +54,10 @@ func NewCmdVerify(cl *libcmdline.CommandLine, g *libkb.GlobalContext) cli.Comman
 				Name:  "f, force",
 				Usage: "Output the verified message even if the sender's identity can't be verified",
 			},
+			cli.StringFlag{
+				Name:  "manifest",
+				Usage: "Check this directory against a manifest signed with \"keybase sign --manifest\".",
+			},
 		},
 	}
 }
@@ -63,6 +69,9 @@ type CmdVerify struct {
 	signedBy     string
 	spui         *SaltpackUI
 	force        bool
+	manifestDir  string
+	manifest     *ManifestSink
+	infile       string
 }
 
 func (c *CmdVerify) ParseArgv(ctx *cli.Context) error {
@@ -79,11 +88,21 @@ func (c *CmdVerify) ParseArgv(ctx *cli.Context) error {
 		}
 		outfile = "/dev/null"
 	}
+	c.signedBy = ctx.String("signed-by")
+	detachedFilename := ctx.String("detached")
+
+	c.manifestDir = ctx.String("manifest")
+	if len(c.manifestDir) > 0 {
+		if len(outfile) > 0 || len(detachedFilename) > 0 {
+			return errors.New("--manifest can't be used with --outfile, --no-output or --detached")
+		}
+		c.infile = infile
+		c.manifest = &ManifestSink{}
+		c.SetSink(c.manifest)
+	}
 	if err := c.FilterInit(c.G(), msg, infile, outfile); err != nil {
 		return err
 	}
-	c.signedBy = ctx.String("signed-by")
-	detachedFilename := ctx.String("detached")
 
 	if len(detachedFilename) > 0 {
 		data, err := os.ReadFile(detachedFilename)
@@ -133,7 +152,49 @@ func (c *CmdVerify) Run() (err error) {
 		err = cli.SaltpackVerify(context.TODO(), arg)
 	}
 	cerr := c.Close(err)
-	return libkb.PickFirstError(err, cerr)
+	if err = libkb.PickFirstError(err, cerr); err != nil {
+		return err
+	}
+	if c.manifest != nil {
+		return c.checkManifest()
+	}
+	return nil
+}
+
+// checkManifest compares the directory to the manifest that was just
+// verified, and reports every file that doesn't match.
+func (c *CmdVerify) checkManifest() error {
+	signed, err := decodeDirManifest(c.manifest.Bytes())
+	if err != nil {
+		return err
+	}
+	var skip string
+	if len(c.infile) > 0 && c.infile != "-" {
+		if skip, err = filepath.Abs(c.infile); err != nil {
+			return err
+		}
+	}
+	cur, err := newDirManifest(c.manifestDir, skip)
+	if err != nil {
+		return err
+	}
+	diff := signed.diff(cur)
+	dui := c.G().UI.GetDumbOutputUI()
+	for _, p := range diff.Modified {
+		dui.Printf("modified: %s\n", p)
+	}
+	for _, p := range diff.Added {
+		dui.Printf("added: %s\n", p)
+	}
+	for _, p := range diff.Missing {
+		dui.Printf("missing: %s\n", p)
+	}
+	if !diff.Empty() {
+		return fmt.Errorf("%s doesn't match the signed manifest: %d modified, %d added, %d missing",
+			c.manifestDir, len(diff.Modified), len(diff.Added), len(diff.Missing))
+	}
+	dui.PrintfStderr("All %d files in %s match the signed manifest.\n", len(signed.Files), c.manifestDir)
+	return nil
 }
 
 func (c *CmdVerify) GetUsage() libkb.Usage {
@@ -27,6 +27,7 @@ func GetCommands(cl *libcmdline.CommandLine, g *libkb.GlobalContext) []cli.Comma
 		NewCmdConfig(cl, g),
 		NewCmdCtl(cl, g),
 		NewCmdCurrency(cl, g),
+		NewCmdCrypto(cl, g),
 		NewCmdDb(cl, g),
 		NewCmdDecrypt(cl, g),
 		NewCmdDeprovision(cl, g),
@@ -38,6 +39,7 @@ func GetCommands(cl *libcmdline.CommandLine, g *libkb.GlobalContext) []cli.Comma
 		NewCmdEncrypt(cl, g),
 		NewCmdFNMR(cl, g),
 		NewCmdGit(cl, g),
+		NewCmdGitSign(cl, g),
 		NewCmdHome(cl, g),
 		NewCmdID(cl, g),
 		NewCmdInterestingPeople(cl, g),
@@ -60,6 +62,8 @@ func GetCommands(cl *libcmdline.CommandLine, g *libkb.GlobalContext) []cli.Comma
 		NewCmdSelfProvision(cl, g),
 		NewCmdSign(cl, g),
 		NewCmdSigs(cl, g),
+		NewCmdSSHAgent(cl, g),
+		NewCmdSSHKeys(cl, g),
 		NewCmdSignup(cl, g),
 		NewCmdSimpleFS(cl, g),
 		NewCmdStatus(cl, g),
@@ -0,0 +1,49 @@
+package client
+
+const cryptoAPIDoc = `"keybase crypto api" provides a JSON API to the saltpack and PGP operations of "keybase encrypt", "keybase decrypt", "keybase sign", "keybase verify" and "keybase pgp".
+
+Every method reads its input from exactly one of "message" (text), "messageBase64" (binary data) or "inputFile". The output comes back in "output" when it is text (armored messages and signatures, UTF-8 plaintexts) and in "outputBase64" otherwise, unless "outputFile" is set, in which case it is written there.
+
+Decrypting and verifying returns "signed" and a "signer" with the "uid", "username", "kid" and, for saltpack, "senderType" (self, tracking_ok, not_tracked, unknown, tracking_broke, revoked or expired) of whoever made the message, and "verified": false when their identity or key is broken. Such messages are refused unless "force" is set. Anonymous saltpack messages have no "signer".
+
+EXAMPLES:
+
+Encrypt a message for users (all of their devices and paper keys, and yours):
+	{"method": "encrypt", "params": {"options": {"recipients": ["alice", "bob@github"], "message": "hello"}}}
+
+Encrypt a message for a team (with the team's key only; use "includeDeviceKeys", "includePaperKeys" and "includeSelfEncrypt" to add more):
+	{"method": "encrypt", "params": {"options": {"teamRecipients": ["phoenix"], "message": "hello"}}}
+
+Encrypt a file into binary saltpack, anonymously:
+	{"method": "encrypt", "params": {"options": {"recipients": ["alice"], "inputFile": "/path/to/report.pdf", "outputFile": "/path/to/report.pdf.saltpack", "binary": true, "authType": "anonymous"}}}
+
+Decrypt a message:
+	{"method": "decrypt", "params": {"options": {"message": "BEGIN KEYBASE SALTPACK ENCRYPTED MESSAGE. ..."}}}
+
+Decrypt binary data, even if the sender's identity is broken:
+	{"method": "decrypt", "params": {"options": {"messageBase64": "xA...", "force": true}}}
+
+Sign a message (use "binary": true for binary saltpack, returned in "outputBase64"):
+	{"method": "sign", "params": {"options": {"message": "I approve release 1.2"}}}
+
+Make a detached signature of a file:
+	{"method": "sign", "params": {"options": {"inputFile": "/path/to/release.tar.gz", "detached": true}}}
+
+Verify a signed message, and that alice signed it:
+	{"method": "verify", "params": {"options": {"message": "BEGIN KEYBASE SALTPACK SIGNED MESSAGE. ...", "signedBy": "alice"}}}
+
+Verify a detached signature (armored in "signature", or binary in "signatureBase64"):
+	{"method": "verify", "params": {"options": {"inputFile": "/path/to/release.tar.gz", "signature": "BEGIN KEYBASE SALTPACK DETACHED SIGNATURE. ..."}}}
+
+PGP encrypt a message, signed with your PGP key unless "noSign" is set ("key" picks among several):
+	{"method": "pgp-encrypt", "params": {"options": {"recipients": ["alice"], "message": "hello"}}}
+
+PGP decrypt a message, requiring that alice signed it:
+	{"method": "pgp-decrypt", "params": {"options": {"message": "-----BEGIN PGP MESSAGE----- ...", "signedBy": "alice"}}}
+
+PGP sign a message ("mode" is attached, detached or clear; "text" for text input):
+	{"method": "pgp-sign", "params": {"options": {"message": "I approve release 1.2", "mode": "clear"}}}
+
+PGP verify a detached signature (signatures by keys Keybase doesn't know come back with only a "keyID"):
+	{"method": "pgp-verify", "params": {"options": {"inputFile": "/path/to/release.tar.gz", "signature": "-----BEGIN PGP SIGNATURE----- ..."}}}
+`
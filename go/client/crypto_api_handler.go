@@ -0,0 +1,809 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/keybase/go-framed-msgpack-rpc/rpc"
+	"golang.org/x/net/context"
+)
+
+type cryptoAPIHandler struct {
+	libkb.Contextified
+	saltpack keybase1.SaltpackClient
+	pgp      keybase1.PGPClient
+	ui       *cryptoAPIUI
+	indent   bool
+}
+
+func newCryptoAPIHandler(g *libkb.GlobalContext, indentOutput bool) *cryptoAPIHandler {
+	return &cryptoAPIHandler{
+		Contextified: libkb.NewContextified(g),
+		ui:           &cryptoAPIUI{Contextified: libkb.NewContextified(g)},
+		indent:       indentOutput,
+	}
+}
+
+func (t *cryptoAPIHandler) handle(ctx context.Context, c Call, w io.Writer) error {
+	switch c.Params.Version {
+	case 0, 1:
+		return t.handleV1(ctx, c, w)
+	default:
+		return ErrInvalidVersion{version: c.Params.Version}
+	}
+}
+
+const (
+	encryptMethod    = "encrypt"
+	decryptMethod    = "decrypt"
+	signMethod       = "sign"
+	verifyMethod     = "verify"
+	pgpEncryptMethod = "pgp-encrypt"
+	pgpDecryptMethod = "pgp-decrypt"
+	pgpSignMethod    = "pgp-sign"
+	pgpVerifyMethod  = "pgp-verify"
+)
+
+var validCryptoMethodsV1 = map[string]bool{
+	encryptMethod:    true,
+	decryptMethod:    true,
+	signMethod:       true,
+	verifyMethod:     true,
+	pgpEncryptMethod: true,
+	pgpDecryptMethod: true,
+	pgpSignMethod:    true,
+	pgpVerifyMethod:  true,
+}
+
+func (t *cryptoAPIHandler) handleV1(ctx context.Context, c Call, w io.Writer) error {
+	if !validCryptoMethodsV1[c.Method] {
+		return ErrInvalidMethod{name: c.Method, version: 1}
+	}
+
+	if err := t.setup(); err != nil {
+		return err
+	}
+	// The UI is registered once per connection, so forget what the previous
+	// call recorded.
+	t.ui.reset()
+
+	switch c.Method {
+	case encryptMethod:
+		return t.encrypt(ctx, c, w)
+	case decryptMethod:
+		return t.decrypt(ctx, c, w)
+	case signMethod:
+		return t.sign(ctx, c, w)
+	case verifyMethod:
+		return t.verify(ctx, c, w)
+	case pgpEncryptMethod:
+		return t.pgpEncrypt(ctx, c, w)
+	case pgpDecryptMethod:
+		return t.pgpDecrypt(ctx, c, w)
+	case pgpSignMethod:
+		return t.pgpSign(ctx, c, w)
+	case pgpVerifyMethod:
+		return t.pgpVerify(ctx, c, w)
+	default:
+		return ErrInvalidMethod{name: c.Method, version: 1}
+	}
+}
+
+func (t *cryptoAPIHandler) setup() (err error) {
+	if t.saltpack.Cli != nil {
+		return nil
+	}
+	// Identify results and signer warnings end up in the JSON result instead
+	// of on the terminal; only passphrase prompts are left to the user.
+	protocols := []rpc.Protocol{
+		NewStreamUIProtocol(t.G()),
+		NewSecretUIProtocol(t.G()),
+		NewNullIdentifyUIProtocol(),
+		keybase1.SaltpackUiProtocol(t.ui),
+		keybase1.PGPUiProtocol(t.ui),
+	}
+	if err := RegisterProtocolsWithContext(protocols, t.G()); err != nil {
+		return err
+	}
+	if t.pgp, err = GetPGPClient(t.G()); err != nil {
+		return err
+	}
+	if t.saltpack, err = GetSaltpackClient(t.G()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// cryptoAPIInput is where every method reads its message from, and where it
+// writes the result to if it isn't returned in the JSON reply.
+type cryptoAPIInput struct {
+	Message       string `json:"message,omitempty"`
+	MessageBase64 string `json:"messageBase64,omitempty"`
+	InputFile     string `json:"inputFile,omitempty"`
+	OutputFile    string `json:"outputFile,omitempty"`
+}
+
+func (a *cryptoAPIInput) check() error {
+	n := 0
+	for _, s := range []string{a.Message, a.MessageBase64, a.InputFile} {
+		if len(s) > 0 {
+			n++
+		}
+	}
+	if n != 1 {
+		return errors.New("exactly one of `message`, `messageBase64` and `inputFile` is required")
+	}
+	if len(a.MessageBase64) > 0 {
+		if _, err := base64.StdEncoding.DecodeString(a.MessageBase64); err != nil {
+			return fmt.Errorf("`messageBase64` isn't valid base64: %s", err)
+		}
+	}
+	return nil
+}
+
+// cryptoAPIStreams hooks the input and output of a call up to the service.
+type cryptoAPIStreams struct {
+	source Source
+	sink   Sink
+	buf    *libkb.BufferCloser
+	// outputFile is set when the output goes to a file instead of the reply.
+	outputFile string
+}
+
+func (t *cryptoAPIHandler) openStreams(in cryptoAPIInput) (s *cryptoAPIStreams, src, snk keybase1.Stream, err error) {
+	s = &cryptoAPIStreams{}
+	switch {
+	case len(in.InputFile) > 0:
+		s.source = NewFileSource(in.InputFile)
+	case len(in.MessageBase64) > 0:
+		data, err := base64.StdEncoding.DecodeString(in.MessageBase64)
+		if err != nil {
+			return nil, src, snk, err
+		}
+		s.source = NewBufferSource(string(data))
+	default:
+		s.source = NewBufferSource(in.Message)
+	}
+	if err := s.source.Open(); err != nil {
+		return nil, src, snk, err
+	}
+	if len(in.OutputFile) > 0 {
+		s.outputFile = in.OutputFile
+		s.sink = NewFileSink(t.G(), in.OutputFile)
+		if err := s.sink.Open(); err != nil {
+			s.source.Close()
+			return nil, src, snk, err
+		}
+		snk = t.G().XStreams.ExportWriter(s.sink)
+	} else {
+		s.buf = libkb.NewBufferCloser()
+		snk = t.G().XStreams.ExportWriter(s.buf)
+	}
+	src = t.G().XStreams.ExportReader(s.source)
+	return s, src, snk, nil
+}
+
+// close finishes the call's streams, and fills in where its output went. Text
+// output is returned as is; anything else (binary ciphertext or signatures,
+// or a plaintext that isn't UTF-8) is returned as base64.
+func (s *cryptoAPIStreams) close(inerr error, res *cryptoAPIResult, binary bool) error {
+	err := s.source.CloseWithError(inerr)
+	if s.sink != nil {
+		err = libkb.PickFirstError(err, s.sink.Close(), s.sink.HitError(inerr))
+		if inerr == nil {
+			res.OutputFile = s.outputFile
+		}
+		return err
+	}
+	if inerr != nil {
+		return err
+	}
+	data := s.buf.Bytes()
+	if binary || !utf8.Valid(data) {
+		res.OutputBase64 = base64.StdEncoding.EncodeToString(data)
+	} else {
+		res.Output = string(data)
+	}
+	return err
+}
+
+// cryptoAPISigner is who encrypted or signed a message, as far as Keybase can
+// tell.
+type cryptoAPISigner struct {
+	UID         keybase1.UID `json:"uid,omitempty"`
+	Username    string       `json:"username,omitempty"`
+	Fullname    string       `json:"fullname,omitempty"`
+	KID         keybase1.KID `json:"kid,omitempty"`
+	KeyID       string       `json:"keyID,omitempty"`
+	Fingerprint string       `json:"fingerprint,omitempty"`
+	SenderType  string       `json:"senderType,omitempty"`
+	Verified    bool         `json:"verified"`
+	Warnings    []string     `json:"warnings,omitempty"`
+}
+
+func newSaltpackSigner(kid keybase1.KID, sender keybase1.SaltpackSender, verified bool) *cryptoAPISigner {
+	return &cryptoAPISigner{
+		UID:        sender.Uid,
+		Username:   sender.Username,
+		Fullname:   sender.Fullname,
+		KID:        kid,
+		SenderType: strings.ToLower(sender.SenderType.String()),
+		Verified:   verified,
+	}
+}
+
+type cryptoAPIResult struct {
+	Output       string `json:"output,omitempty"`
+	OutputBase64 string `json:"outputBase64,omitempty"`
+	OutputFile   string `json:"outputFile,omitempty"`
+	// Set by decrypt and verify methods.
+	Signed *bool            `json:"signed,omitempty"`
+	Signer *cryptoAPISigner `json:"signer,omitempty"`
+	// Set by encrypt if a recipient was a social assertion nobody has
+	// proven yet, and the message was encrypted for an implicit team.
+	UnresolvedSBSAssertion string `json:"unresolvedSBSAssertion,omitempty"`
+}
+
+type encryptOptions struct {
+	cryptoAPIInput
+	Recipients         []string `json:"recipients,omitempty"`
+	TeamRecipients     []string `json:"teamRecipients,omitempty"`
+	AuthType           string   `json:"authType,omitempty"`
+	NoEntityKeys       bool     `json:"noEntityKeys,omitempty"`
+	IncludeDeviceKeys  bool     `json:"includeDeviceKeys,omitempty"`
+	IncludePaperKeys   bool     `json:"includePaperKeys,omitempty"`
+	IncludeSelfEncrypt bool     `json:"includeSelfEncrypt,omitempty"`
+	NoDeviceKeys       bool     `json:"noDeviceKeys,omitempty"`
+	NoPaperKeys        bool     `json:"noPaperKeys,omitempty"`
+	NoSelfEncrypt      bool     `json:"noSelfEncrypt,omitempty"`
+	Binary             bool     `json:"binary,omitempty"`
+	SaltpackVersion    int      `json:"saltpackVersion,omitempty"`
+}
+
+// Check applies the same rules as "keybase encrypt": messages for users use
+// all of their keys unless told otherwise, messages for teams only use the
+// team's key unless told otherwise.
+func (a *encryptOptions) Check() error {
+	if err := a.cryptoAPIInput.check(); err != nil {
+		return err
+	}
+	forRecipients := len(a.Recipients) > 0
+	forTeamRecipients := len(a.TeamRecipients) > 0
+	if !forRecipients && !forTeamRecipients {
+		return errors.New("`recipients` or `teamRecipients` required")
+	}
+	if forRecipients && forTeamRecipients {
+		return errors.New("can only encrypt for either only `recipients` or only `teamRecipients`")
+	}
+	if forRecipients && (a.IncludeDeviceKeys || a.IncludePaperKeys || a.IncludeSelfEncrypt) {
+		return errors.New("`includeDeviceKeys`, `includePaperKeys` and `includeSelfEncrypt` only apply to `teamRecipients`")
+	}
+	if forTeamRecipients && (a.NoDeviceKeys || a.NoPaperKeys || a.NoSelfEncrypt) {
+		return errors.New("`noDeviceKeys`, `noPaperKeys` and `noSelfEncrypt` only apply to `recipients`")
+	}
+	opts, err := a.saltpackOptions()
+	if err != nil {
+		return err
+	}
+	if !(opts.UseEntityKeys || opts.UseDeviceKeys || opts.UsePaperKeys) {
+		return errors.New("no keys left to encrypt for")
+	}
+	if forTeamRecipients && opts.UseEntityKeys && opts.AuthenticityType == keybase1.AuthenticityType_REPUDIABLE {
+		return errors.New("`authType` repudiable requires `noEntityKeys` when encrypting for a team")
+	}
+	return nil
+}
+
+func (a *encryptOptions) saltpackOptions() (opts keybase1.SaltpackEncryptOptions, err error) {
+	opts = keybase1.SaltpackEncryptOptions{
+		Recipients:      a.Recipients,
+		TeamRecipients:  a.TeamRecipients,
+		UseEntityKeys:   !a.NoEntityKeys,
+		Binary:          a.Binary,
+		SaltpackVersion: a.SaltpackVersion,
+	}
+	if len(a.Recipients) > 0 {
+		opts.UseDeviceKeys = !a.NoDeviceKeys
+		opts.UsePaperKeys = !a.NoPaperKeys
+		opts.NoSelfEncrypt = a.NoSelfEncrypt
+	} else {
+		opts.UseDeviceKeys = a.IncludeDeviceKeys
+		opts.UsePaperKeys = a.IncludePaperKeys
+		opts.NoSelfEncrypt = !a.IncludeSelfEncrypt
+	}
+	authType := a.AuthType
+	if len(authType) == 0 {
+		authType = "signed"
+	}
+	var ok bool
+	if opts.AuthenticityType, ok = keybase1.AuthenticityTypeMap[strings.ToUpper(authType)]; !ok {
+		return opts, fmt.Errorf("invalid `authType` %q, please use signed, repudiable or anonymous", a.AuthType)
+	}
+	return opts, nil
+}
+
+func (t *cryptoAPIHandler) encrypt(ctx context.Context, c Call, w io.Writer) error {
+	var opts encryptOptions
+	if err := unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	spOpts, err := opts.saltpackOptions()
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	streams, src, snk, err := t.openStreams(opts.cryptoAPIInput)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	var res cryptoAPIResult
+	enc, err := t.saltpack.SaltpackEncrypt(ctx, keybase1.SaltpackEncryptArg{
+		Source: src,
+		Sink:   snk,
+		Opts:   spOpts,
+	})
+	if err = libkb.PickFirstError(err, streams.close(err, &res, opts.Binary)); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	if enc.UsedUnresolvedSBS {
+		res.UnresolvedSBSAssertion = enc.UnresolvedSBSAssertion
+	}
+	return t.encodeResult(c, res, w)
+}
+
+type decryptOptions struct {
+	cryptoAPIInput
+	Force       bool `json:"force,omitempty"`
+	UsePaperKey bool `json:"usePaperKey,omitempty"`
+}
+
+func (a *decryptOptions) Check() error {
+	return a.cryptoAPIInput.check()
+}
+
+func (t *cryptoAPIHandler) decrypt(ctx context.Context, c Call, w io.Writer) error {
+	var opts decryptOptions
+	if err := unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	t.ui.force = opts.Force
+	streams, src, snk, err := t.openStreams(opts.cryptoAPIInput)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	var res cryptoAPIResult
+	info, err := t.saltpack.SaltpackDecrypt(ctx, keybase1.SaltpackDecryptArg{
+		Source: src,
+		Sink:   snk,
+		Opts: keybase1.SaltpackDecryptOptions{
+			ForceRemoteCheck: true,
+			UsePaperKey:      opts.UsePaperKey,
+		},
+	})
+	if err = libkb.PickFirstError(err, streams.close(err, &res, false)); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	signed := t.ui.signed
+	res.Signed = &signed
+	res.Signer = t.ui.signer
+	if res.Signer == nil && info.Sender.SenderType != keybase1.SaltpackSenderType_ANONYMOUS {
+		res.Signer = newSaltpackSigner("", info.Sender, true)
+	}
+	return t.encodeResult(c, res, w)
+}
+
+type signOptions struct {
+	cryptoAPIInput
+	Detached        bool `json:"detached,omitempty"`
+	Binary          bool `json:"binary,omitempty"`
+	SaltpackVersion int  `json:"saltpackVersion,omitempty"`
+}
+
+func (a *signOptions) Check() error {
+	return a.cryptoAPIInput.check()
+}
+
+func (t *cryptoAPIHandler) sign(ctx context.Context, c Call, w io.Writer) error {
+	var opts signOptions
+	if err := unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	streams, src, snk, err := t.openStreams(opts.cryptoAPIInput)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	var res cryptoAPIResult
+	err = t.saltpack.SaltpackSign(ctx, keybase1.SaltpackSignArg{
+		Source: src,
+		Sink:   snk,
+		Opts: keybase1.SaltpackSignOptions{
+			Detached:        opts.Detached,
+			Binary:          opts.Binary,
+			SaltpackVersion: opts.SaltpackVersion,
+		},
+	})
+	if err = libkb.PickFirstError(err, streams.close(err, &res, opts.Binary)); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	return t.encodeResult(c, res, w)
+}
+
+// detachedSignature is a detached signature handed to verify or pgp-verify,
+// armored or base64-encoded binary.
+type detachedSignature struct {
+	Signature       string `json:"signature,omitempty"`
+	SignatureBase64 string `json:"signatureBase64,omitempty"`
+}
+
+func (a *detachedSignature) check() error {
+	if len(a.Signature) > 0 && len(a.SignatureBase64) > 0 {
+		return errors.New("only one of `signature` and `signatureBase64` can be set")
+	}
+	if len(a.SignatureBase64) > 0 {
+		if _, err := base64.StdEncoding.DecodeString(a.SignatureBase64); err != nil {
+			return fmt.Errorf("`signatureBase64` isn't valid base64: %s", err)
+		}
+	}
+	return nil
+}
+
+func (a *detachedSignature) bytes() []byte {
+	if len(a.SignatureBase64) > 0 {
+		data, _ := base64.StdEncoding.DecodeString(a.SignatureBase64)
+		return data
+	}
+	if len(a.Signature) > 0 {
+		return []byte(a.Signature)
+	}
+	return nil
+}
+
+type verifyOptions struct {
+	cryptoAPIInput
+	detachedSignature
+	SignedBy string `json:"signedBy,omitempty"`
+	Force    bool   `json:"force,omitempty"`
+}
+
+func (a *verifyOptions) Check() error {
+	if err := a.cryptoAPIInput.check(); err != nil {
+		return err
+	}
+	return a.detachedSignature.check()
+}
+
+func (t *cryptoAPIHandler) verify(ctx context.Context, c Call, w io.Writer) error {
+	var opts verifyOptions
+	if err := unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	t.ui.force = opts.Force
+	streams, src, snk, err := t.openStreams(opts.cryptoAPIInput)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	var res cryptoAPIResult
+	err = t.saltpack.SaltpackVerify(ctx, keybase1.SaltpackVerifyArg{
+		Source: src,
+		Sink:   snk,
+		Opts: keybase1.SaltpackVerifyOptions{
+			SignedBy:  opts.SignedBy,
+			Signature: opts.bytes(),
+		},
+	})
+	if err = libkb.PickFirstError(err, streams.close(err, &res, false)); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	// A detached signature has nothing to output but who made it.
+	if len(opts.bytes()) > 0 {
+		res.Output, res.OutputBase64 = "", ""
+	}
+	signed := true
+	res.Signed = &signed
+	res.Signer = t.ui.signer
+	return t.encodeResult(c, res, w)
+}
+
+type pgpEncryptOptions struct {
+	cryptoAPIInput
+	Recipients []string `json:"recipients,omitempty"`
+	NoSign     bool     `json:"noSign,omitempty"`
+	NoSelf     bool     `json:"noSelf,omitempty"`
+	Binary     bool     `json:"binary,omitempty"`
+	Key        string   `json:"key,omitempty"`
+}
+
+func (a *pgpEncryptOptions) Check() error {
+	if err := a.cryptoAPIInput.check(); err != nil {
+		return err
+	}
+	if len(a.Recipients) == 0 && a.NoSelf {
+		return errors.New("`recipients` required with `noSelf`")
+	}
+	return nil
+}
+
+func (t *cryptoAPIHandler) pgpEncrypt(ctx context.Context, c Call, w io.Writer) error {
+	var opts pgpEncryptOptions
+	if err := unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	streams, src, snk, err := t.openStreams(opts.cryptoAPIInput)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	var res cryptoAPIResult
+	err = t.pgp.PGPEncrypt(ctx, keybase1.PGPEncryptArg{
+		Source: src,
+		Sink:   snk,
+		Opts: keybase1.PGPEncryptOptions{
+			Recipients: opts.Recipients,
+			NoSign:     opts.NoSign,
+			NoSelf:     opts.NoSelf,
+			BinaryOut:  opts.Binary,
+			KeyQuery:   opts.Key,
+		},
+	})
+	if err = libkb.PickFirstError(err, streams.close(err, &res, opts.Binary)); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	return t.encodeResult(c, res, w)
+}
+
+type pgpDecryptOptions struct {
+	cryptoAPIInput
+	SignedBy     string `json:"signedBy,omitempty"`
+	AssertSigned bool   `json:"assertSigned,omitempty"`
+}
+
+func (a *pgpDecryptOptions) Check() error {
+	return a.cryptoAPIInput.check()
+}
+
+func (t *cryptoAPIHandler) pgpDecrypt(ctx context.Context, c Call, w io.Writer) error {
+	var opts pgpDecryptOptions
+	if err := unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	streams, src, snk, err := t.openStreams(opts.cryptoAPIInput)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	var res cryptoAPIResult
+	sv, err := t.pgp.PGPDecrypt(ctx, keybase1.PGPDecryptArg{
+		Source: src,
+		Sink:   snk,
+		Opts: keybase1.PGPDecryptOptions{
+			AssertSigned: opts.AssertSigned || len(opts.SignedBy) > 0,
+			SignedBy:     opts.SignedBy,
+		},
+	})
+	if err = libkb.PickFirstError(err, streams.close(err, &res, false)); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	t.setPGPSigner(&res, sv)
+	return t.encodeResult(c, res, w)
+}
+
+type pgpSignOptions struct {
+	cryptoAPIInput
+	Mode   string `json:"mode,omitempty"`
+	Binary bool   `json:"binary,omitempty"`
+	Text   bool   `json:"text,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+func (a *pgpSignOptions) Check() error {
+	if err := a.cryptoAPIInput.check(); err != nil {
+		return err
+	}
+	if _, err := a.signMode(); err != nil {
+		return err
+	}
+	if a.Binary && a.Text {
+		return errors.New("only one of `binary` and `text` can be set")
+	}
+	return nil
+}
+
+func (a *pgpSignOptions) signMode() (keybase1.SignMode, error) {
+	switch a.Mode {
+	case "", "attached":
+		return keybase1.SignMode_ATTACHED, nil
+	case "detached":
+		return keybase1.SignMode_DETACHED, nil
+	case "clear":
+		return keybase1.SignMode_CLEAR, nil
+	default:
+		return 0, fmt.Errorf("invalid `mode` %q, please use attached, detached or clear", a.Mode)
+	}
+}
+
+func (t *cryptoAPIHandler) pgpSign(ctx context.Context, c Call, w io.Writer) error {
+	var opts pgpSignOptions
+	if err := unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	mode, _ := opts.signMode()
+	streams, src, snk, err := t.openStreams(opts.cryptoAPIInput)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	var res cryptoAPIResult
+	err = t.pgp.PGPSign(ctx, keybase1.PGPSignArg{
+		Source: src,
+		Sink:   snk,
+		Opts: keybase1.PGPSignOptions{
+			KeyQuery:  opts.Key,
+			Mode:      mode,
+			BinaryIn:  !opts.Text,
+			BinaryOut: opts.Binary,
+		},
+	})
+	if err = libkb.PickFirstError(err, streams.close(err, &res, opts.Binary)); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	return t.encodeResult(c, res, w)
+}
+
+type pgpVerifyOptions struct {
+	cryptoAPIInput
+	detachedSignature
+	SignedBy string `json:"signedBy,omitempty"`
+}
+
+func (a *pgpVerifyOptions) Check() error {
+	if err := a.cryptoAPIInput.check(); err != nil {
+		return err
+	}
+	if len(a.OutputFile) > 0 {
+		return errors.New("`outputFile` isn't supported by pgp-verify")
+	}
+	return a.detachedSignature.check()
+}
+
+func (t *cryptoAPIHandler) pgpVerify(ctx context.Context, c Call, w io.Writer) error {
+	var opts pgpVerifyOptions
+	if err := unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	streams, src, _, err := t.openStreams(opts.cryptoAPIInput)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	var res cryptoAPIResult
+	sv, err := t.pgp.PGPVerify(ctx, keybase1.PGPVerifyArg{
+		Source: src,
+		Opts: keybase1.PGPVerifyOptions{
+			SignedBy:  opts.SignedBy,
+			Signature: opts.bytes(),
+		},
+	})
+	if err = libkb.PickFirstError(err, streams.close(err, &res, false)); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	t.setPGPSigner(&res, sv)
+	return t.encodeResult(c, res, w)
+}
+
+func (t *cryptoAPIHandler) setPGPSigner(res *cryptoAPIResult, sv keybase1.PGPSigVerification) {
+	res.Signed = &sv.IsSigned
+	if !sv.IsSigned {
+		return
+	}
+	res.Signer = &cryptoAPISigner{
+		UID:         sv.Signer.Uid,
+		Username:    sv.Signer.Username,
+		KID:         sv.SignKey.KID,
+		Fingerprint: sv.SignKey.PGPFingerprint,
+		Verified:    sv.Verified,
+		Warnings:    sv.Warnings,
+	}
+	// Signatures by keys that aren't on Keybase only come back through the UI.
+	if len(sv.Signer.Username) == 0 && t.ui.signer != nil {
+		res.Signer.KeyID = t.ui.signer.KeyID
+	}
+}
+
+func (t *cryptoAPIHandler) encodeResult(call Call, result interface{}, w io.Writer) error {
+	return encodeResult(call, result, w, t.indent)
+}
+
+func (t *cryptoAPIHandler) encodeErr(call Call, err error, w io.Writer) error {
+	return encodeErr(call, err, w, t.indent)
+}
+
+// cryptoAPIUI records what the service tells the user about the sender or
+// signer of a message, instead of printing it, so that it can go in the
+// JSON result.
+type cryptoAPIUI struct {
+	libkb.Contextified
+	force  bool
+	signed bool
+	signer *cryptoAPISigner
+}
+
+var _ keybase1.SaltpackUiInterface = (*cryptoAPIUI)(nil)
+var _ keybase1.PGPUiInterface = (*cryptoAPIUI)(nil)
+
+func (u *cryptoAPIUI) reset() {
+	u.force = false
+	u.signed = false
+	u.signer = nil
+}
+
+// badSenderReason says what's wrong with a sender whose identity or key is
+// broken, and is empty otherwise.
+func badSenderReason(sender keybase1.SaltpackSender) string {
+	switch sender.SenderType {
+	case keybase1.SaltpackSenderType_TRACKING_BROKE:
+		return "sender identity failed"
+	case keybase1.SaltpackSenderType_REVOKED:
+		return "sender key revoked"
+	case keybase1.SaltpackSenderType_EXPIRED:
+		return "sender key expired"
+	default:
+		return ""
+	}
+}
+
+// checkSender fails the call for a bad sender unless forced, like "keybase
+// decrypt" and "keybase verify" do without --force.
+func (u *cryptoAPIUI) checkSender(sender keybase1.SaltpackSender) error {
+	reason := badSenderReason(sender)
+	if len(reason) == 0 || u.force {
+		return nil
+	}
+	return libkb.IdentifyFailedError{Assertion: sender.Username, Reason: reason}
+}
+
+func (u *cryptoAPIUI) SaltpackPromptForDecrypt(_ context.Context, arg keybase1.SaltpackPromptForDecryptArg) error {
+	u.signed = arg.Signed
+	if arg.Sender.SenderType != keybase1.SaltpackSenderType_ANONYMOUS {
+		u.signer = newSaltpackSigner(arg.SigningKID, arg.Sender, len(badSenderReason(arg.Sender)) == 0)
+	}
+	return u.checkSender(arg.Sender)
+}
+
+func (u *cryptoAPIUI) SaltpackVerifySuccess(_ context.Context, arg keybase1.SaltpackVerifySuccessArg) error {
+	u.signer = newSaltpackSigner(arg.SigningKID, arg.Sender, true)
+	return nil
+}
+
+func (u *cryptoAPIUI) SaltpackVerifyBadSender(_ context.Context, arg keybase1.SaltpackVerifyBadSenderArg) error {
+	u.signer = newSaltpackSigner(arg.SigningKID, arg.Sender, false)
+	return u.checkSender(arg.Sender)
+}
+
+func (u *cryptoAPIUI) OutputPGPWarning(context.Context, keybase1.OutputPGPWarningArg) error {
+	return nil
+}
+
+func (u *cryptoAPIUI) OutputSignatureSuccess(context.Context, keybase1.OutputSignatureSuccessArg) error {
+	return nil
+}
+
+func (u *cryptoAPIUI) OutputSignatureNonKeybase(_ context.Context, arg keybase1.OutputSignatureNonKeybaseArg) error {
+	u.signer = &cryptoAPISigner{KeyID: arg.KeyID, Warnings: arg.Warnings}
+	return nil
+}
+
+func (u *cryptoAPIUI) KeyGenerated(context.Context, keybase1.KeyGeneratedArg) error {
+	return nil
+}
+
+func (u *cryptoAPIUI) ShouldPushPrivate(context.Context, keybase1.ShouldPushPrivateArg) (bool, error) {
+	return false, nil
+}
+
+func (u *cryptoAPIUI) Finished(context.Context, int) error {
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+)
+
+func TestCryptoAPIEncryptOptions(t *testing.T) {
+	check := func(opts string) (*encryptOptions, error) {
+		var a encryptOptions
+		err := unmarshalOptions(Call{Params: Params{Options: json.RawMessage(opts)}}, &a)
+		return &a, err
+	}
+
+	a, err := check(`{"recipients": ["alice"], "message": "hi"}`)
+	require.NoError(t, err)
+	opts, err := a.saltpackOptions()
+	require.NoError(t, err)
+	require.True(t, opts.UseEntityKeys && opts.UseDeviceKeys && opts.UsePaperKeys)
+	require.False(t, opts.NoSelfEncrypt)
+	require.Equal(t, keybase1.AuthenticityType_SIGNED, opts.AuthenticityType)
+
+	a, err = check(`{"teamRecipients": ["phoenix"], "inputFile": "/tmp/x", "authType": "anonymous"}`)
+	require.NoError(t, err)
+	opts, err = a.saltpackOptions()
+	require.NoError(t, err)
+	require.True(t, opts.UseEntityKeys)
+	require.False(t, opts.UseDeviceKeys || opts.UsePaperKeys)
+	require.True(t, opts.NoSelfEncrypt)
+	require.Equal(t, keybase1.AuthenticityType_ANONYMOUS, opts.AuthenticityType)
+
+	for _, bad := range []string{
+		`{"recipients": ["alice"]}`,
+		`{"recipients": ["alice"], "message": "hi", "inputFile": "/tmp/x"}`,
+		`{"message": "hi"}`,
+		`{"recipients": ["alice"], "teamRecipients": ["phoenix"], "message": "hi"}`,
+		`{"recipients": ["alice"], "message": "hi", "includePaperKeys": true}`,
+		`{"teamRecipients": ["phoenix"], "message": "hi", "authType": "repudiable"}`,
+		`{"teamRecipients": ["phoenix"], "message": "hi", "noEntityKeys": true}`,
+		`{"recipients": ["alice"], "message": "hi", "authType": "sneaky"}`,
+		`{"recipients": ["alice"], "messageBase64": "not base64!"}`,
+	} {
+		_, err := check(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestCryptoAPIStreamsOutput(t *testing.T) {
+	finish := func(data []byte, binary bool) cryptoAPIResult {
+		s := &cryptoAPIStreams{source: NewBufferSource(""), buf: libkb.NewBufferCloser()}
+		_, err := s.buf.Write(data)
+		require.NoError(t, err)
+		var res cryptoAPIResult
+		require.NoError(t, s.close(nil, &res, binary))
+		return res
+	}
+
+	res := finish([]byte("BEGIN KEYBASE SALTPACK SIGNED MESSAGE."), false)
+	require.Equal(t, "BEGIN KEYBASE SALTPACK SIGNED MESSAGE.", res.Output)
+	require.Empty(t, res.OutputBase64)
+
+	res = finish([]byte("text"), true)
+	require.Empty(t, res.Output)
+	require.Equal(t, "dGV4dA==", res.OutputBase64)
+
+	// Plaintexts that aren't UTF-8 can't go in a JSON string as is.
+	res = finish([]byte{0xff, 0xfe}, false)
+	require.Empty(t, res.Output)
+	require.Equal(t, "//4=", res.OutputBase64)
+}
+
+func TestCryptoAPIUIBadSender(t *testing.T) {
+	ui := &cryptoAPIUI{}
+	arg := keybase1.SaltpackPromptForDecryptArg{
+		SigningKID: keybase1.KID("0120abcd"),
+		Sender: keybase1.SaltpackSender{
+			Username:   "alice",
+			SenderType: keybase1.SaltpackSenderType_REVOKED,
+		},
+		Signed: true,
+	}
+	require.Error(t, ui.SaltpackPromptForDecrypt(context.TODO(), arg))
+
+	ui.reset()
+	ui.force = true
+	require.NoError(t, ui.SaltpackPromptForDecrypt(context.TODO(), arg))
+	require.True(t, ui.signed)
+	require.Equal(t, "alice", ui.signer.Username)
+	require.Equal(t, "revoked", ui.signer.SenderType)
+	require.False(t, ui.signer.Verified)
+
+	ui.reset()
+	arg.Sender.SenderType = keybase1.SaltpackSenderType_ANONYMOUS
+	require.NoError(t, ui.SaltpackPromptForDecrypt(context.TODO(), arg))
+	require.Nil(t, ui.signer)
+}
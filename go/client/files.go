@@ -357,11 +357,26 @@ func (u *UnixFilter) FilterInit(g *libkb.GlobalContext, msg, infile, outfile str
 	return nil
 }
 
+// SetSource makes the filter read from src instead of a message, an infile
+// or stdin.
+func (u *UnixFilter) SetSource(src Source) {
+	u.source = src
+}
+
+// SetSink makes the filter write to snk instead of an outfile or stdout.
+func (u *UnixFilter) SetSink(snk Sink) {
+	u.sink = snk
+}
+
 func (u *UnixFilter) FilterOpen(g *libkb.GlobalContext) (err error) {
-	if u.source, err = initSource(u.msg, u.infile); err != nil {
-		return err
+	if u.source == nil {
+		if u.source, err = initSource(u.msg, u.infile); err != nil {
+			return err
+		}
+	}
+	if u.sink == nil {
+		u.sink = initSink(g, u.outfile)
 	}
-	u.sink = initSink(g, u.outfile)
 
 	if err = u.sink.Open(); err != nil {
 		return err
@@ -15,12 +15,24 @@ Get an entry in your implicit self-team:
 Get an entry for a named team (always returns the latest revision, non-existent entries have a revision of 0):
 	{"method": "get", "params": {"options": {"team": "phoenix", "namespace": "pw-manager", "entryKey": "geocities"}}}
 
+Get an entry, falling back to the last revision this device saw if the Keybase servers can't be reached (the result then has "stale": true):
+	{"method": "get", "params": {"options": {"team": "phoenix", "namespace": "pw-manager", "entryKey": "geocities", "allowStale": true}}}
+
 Put an encrypted entry for anyone in team phoenix:
 	{"method": "put", "params": {"options": {"team": "phoenix", "namespace": "pw-manager", "entryKey": "geocities", "entryValue": "all my secrets"}}}
 
 Put an entry (specifying a non-zero revision enables custom concurrency behavior, e.g. 1 will throw an error if the entry already exists):
 	{"method": "put", "params": {"options": {"team": "phoenix", "namespace": "pw-manager", "entryKey": "geocities", "revision": 1, "entryValue": "all my secrets"}}}
 
+Values larger than 64KB are split into several entries behind the scenes, and reassembled and checked on "get"; "del" deletes every part (values can be up to 3MB).
+
+Put the contents of a file (use "base64": true for binary files; the entry then holds base64 text):
+	{"method": "put", "params": {"options": {"team": "phoenix", "namespace": "certs", "entryKey": "bundle", "entryValueFile": "/path/to/ca-bundle.pem"}}}
+	{"method": "put", "params": {"options": {"team": "phoenix", "namespace": "blobs", "entryKey": "logo", "entryValueFile": "/path/to/logo.png", "base64": true}}}
+
+Get an entry into a file (with "base64": true, the entry is decoded before it's written):
+	{"method": "get", "params": {"options": {"team": "phoenix", "namespace": "blobs", "entryKey": "logo", "outputFile": "/path/to/logo.png", "base64": true}}}
+
 List all namespaces with a non-deleted entryKey (pagination not yet implemented for >10k items):
 	{"method": "list", "params": {"options": {"team": "phoenix"}}}
 
@@ -32,4 +44,7 @@ Delete an entry:
 
 Delete an entry (also supports specifying the revision):
 	{"method": "del", "params": {"options": {"team": "phoenix", "namespace": "pw-manager", "revision": 4, "entryKey": "geocities"}}}
+
+Put and delete several entries atomically (either every entry is written at its revision, or none are; "revision" is optional per entry):
+	{"method": "batch", "params": {"options": {"team": "phoenix", "entries": [{"namespace": "pw-manager", "entryKey": "index", "revision": 3, "entryValue": "geocities,myspace"}, {"namespace": "pw-manager", "entryKey": "myspace", "entryValue": "more secrets"}, {"namespace": "pw-manager", "entryKey": "friendster", "delete": true}]}}}
 `
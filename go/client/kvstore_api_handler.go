@@ -1,9 +1,12 @@
 package client
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"unicode/utf8"
 
 	"github.com/adamwalz/keybase-client/go/libkb"
 	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
@@ -31,17 +34,19 @@ func (t *kvStoreAPIHandler) handle(ctx context.Context, c Call, w io.Writer) err
 }
 
 const (
-	getEntryMethod = "get"
-	putEntryMethod = "put"
-	listMethod     = "list"
-	delEntryMethod = "del"
+	getEntryMethod     = "get"
+	putEntryMethod     = "put"
+	listMethod         = "list"
+	delEntryMethod     = "del"
+	batchEntriesMethod = "batch"
 )
 
 var validKvstoreMethodsV1 = map[string]bool{
-	getEntryMethod: true,
-	putEntryMethod: true,
-	listMethod:     true,
-	delEntryMethod: true,
+	getEntryMethod:     true,
+	putEntryMethod:     true,
+	listMethod:         true,
+	delEntryMethod:     true,
+	batchEntriesMethod: true,
 }
 
 func (t *kvStoreAPIHandler) handleV1(ctx context.Context, c Call, w io.Writer) error {
@@ -78,15 +83,20 @@ func (t *kvStoreAPIHandler) handleV1(ctx context.Context, c Call, w io.Writer) e
 		return t.list(ctx, c, w)
 	case delEntryMethod:
 		return t.deleteEntry(ctx, c, w)
+	case batchEntriesMethod:
+		return t.batchEntries(ctx, c, w)
 	default:
 		return ErrInvalidMethod{name: c.Method, version: 1}
 	}
 }
 
 type getEntryOptions struct {
-	Team      *string `json:"team,omitempty"`
-	Namespace string  `json:"namespace"`
-	EntryKey  string  `json:"entryKey"`
+	Team       *string `json:"team,omitempty"`
+	Namespace  string  `json:"namespace"`
+	EntryKey   string  `json:"entryKey"`
+	AllowStale bool    `json:"allowStale,omitempty"`
+	OutputFile string  `json:"outputFile,omitempty"`
+	Base64     bool    `json:"base64,omitempty"`
 }
 
 func (a *getEntryOptions) Check() error {
@@ -96,6 +106,30 @@ func (a *getEntryOptions) Check() error {
 	if len(a.EntryKey) == 0 {
 		return errors.New("`entryKey` field required")
 	}
+	if a.Base64 && len(a.OutputFile) == 0 {
+		return errors.New("`base64` requires `outputFile`")
+	}
+	return nil
+}
+
+// writeEntryValue writes a fetched value to opts.OutputFile, decoding it first
+// if it was stored as base64, and leaves it out of the JSON result.
+func (t *kvStoreAPIHandler) writeEntryValue(opts getEntryOptions, res *keybase1.KVGetResult) error {
+	if res.EntryValue == nil {
+		return fmt.Errorf("entry %s/%s has no value to write to %s", opts.Namespace, opts.EntryKey, opts.OutputFile)
+	}
+	data := []byte(*res.EntryValue)
+	if opts.Base64 {
+		decoded, err := base64.StdEncoding.DecodeString(*res.EntryValue)
+		if err != nil {
+			return fmt.Errorf("entry %s/%s isn't valid base64: %s", opts.Namespace, opts.EntryKey, err)
+		}
+		data = decoded
+	}
+	if err := os.WriteFile(opts.OutputFile, data, 0600); err != nil {
+		return err
+	}
+	res.EntryValue = nil
 	return nil
 }
 
@@ -113,19 +147,29 @@ func (t *kvStoreAPIHandler) getEntry(ctx context.Context, c Call, w io.Writer) e
 		Namespace: opts.Namespace,
 		EntryKey:  opts.EntryKey,
 	}
+	if opts.AllowStale {
+		arg.Oa = keybase1.OfflineAvailability_BEST_EFFORT
+	}
 	res, err := t.kvstore.GetKVEntry(ctx, arg)
 	if err != nil {
 		return t.encodeErr(c, err, w)
 	}
+	if len(opts.OutputFile) > 0 {
+		if err := t.writeEntryValue(opts, &res); err != nil {
+			return t.encodeErr(c, err, w)
+		}
+	}
 	return t.encodeResult(c, res, w)
 }
 
 type putEntryOptions struct {
-	Team       *string `json:"team,omitempty"`
-	Namespace  string  `json:"namespace"`
-	EntryKey   string  `json:"entryKey"`
-	Revision   *int    `json:"revision"`
-	EntryValue string  `json:"entryValue"`
+	Team           *string `json:"team,omitempty"`
+	Namespace      string  `json:"namespace"`
+	EntryKey       string  `json:"entryKey"`
+	Revision       *int    `json:"revision"`
+	EntryValue     string  `json:"entryValue"`
+	EntryValueFile string  `json:"entryValueFile,omitempty"`
+	Base64         bool    `json:"base64,omitempty"`
 }
 
 func (a *putEntryOptions) Check() error {
@@ -135,8 +179,16 @@ func (a *putEntryOptions) Check() error {
 	if len(a.EntryKey) == 0 {
 		return errors.New("`entryKey` field required")
 	}
-	if len(a.EntryValue) == 0 {
-		return errors.New("`entryValue` field required")
+	if len(a.EntryValue) == 0 && len(a.EntryValueFile) == 0 {
+		return errors.New("`entryValue` or `entryValueFile` field required")
+	}
+	if len(a.EntryValue) > 0 && len(a.EntryValueFile) > 0 {
+		return errors.New("only one of `entryValue` and `entryValueFile` can be set")
+	}
+	if a.Base64 && len(a.EntryValue) > 0 {
+		if _, err := base64.StdEncoding.DecodeString(a.EntryValue); err != nil {
+			return fmt.Errorf("`entryValue` isn't valid base64: %s", err)
+		}
 	}
 	if a.Revision != nil && *a.Revision <= 0 {
 		return errors.New("if setting optional `revision` field, it needs to be a positive integer")
@@ -156,13 +208,31 @@ func (t *kvStoreAPIHandler) putEntry(ctx context.Context, c Call, w io.Writer) e
 	if opts.Revision != nil {
 		revision = *opts.Revision
 	}
+	entryValue := opts.EntryValue
+	if len(opts.EntryValueFile) > 0 {
+		data, err := os.ReadFile(opts.EntryValueFile)
+		if err != nil {
+			return t.encodeErr(c, err, w)
+		}
+		switch {
+		case opts.Base64:
+			entryValue = base64.StdEncoding.EncodeToString(data)
+		case utf8.Valid(data):
+			entryValue = string(data)
+		default:
+			return t.encodeErr(c, fmt.Errorf("%s isn't UTF-8 text; set `base64` to store it as binary", opts.EntryValueFile), w)
+		}
+		if len(entryValue) == 0 {
+			return t.encodeErr(c, fmt.Errorf("%s is empty", opts.EntryValueFile), w)
+		}
+	}
 	arg := keybase1.PutKVEntryArg{
 		SessionID:  0,
 		TeamName:   *opts.Team,
 		Namespace:  opts.Namespace,
 		EntryKey:   opts.EntryKey,
 		Revision:   revision,
-		EntryValue: opts.EntryValue,
+		EntryValue: entryValue,
 	}
 	res, err := t.kvstore.PutKVEntry(ctx, arg)
 	if err != nil {
@@ -259,6 +329,76 @@ func (t *kvStoreAPIHandler) list(ctx context.Context, c Call, w io.Writer) error
 	return t.encodeResult(c, res, w)
 }
 
+type batchEntriesEntryOptions struct {
+	Namespace  string  `json:"namespace"`
+	EntryKey   string  `json:"entryKey"`
+	Revision   *int    `json:"revision"`
+	EntryValue *string `json:"entryValue"`
+	Delete     bool    `json:"delete"`
+}
+
+type batchEntriesOptions struct {
+	Team    *string                    `json:"team,omitempty"`
+	Entries []batchEntriesEntryOptions `json:"entries"`
+}
+
+func (a *batchEntriesOptions) Check() error {
+	if len(a.Entries) == 0 {
+		return errors.New("`entries` field required")
+	}
+	for i, e := range a.Entries {
+		if len(e.Namespace) == 0 {
+			return fmt.Errorf("entry %d: `namespace` field required", i)
+		}
+		if len(e.EntryKey) == 0 {
+			return fmt.Errorf("entry %d: `entryKey` field required", i)
+		}
+		if e.Delete && e.EntryValue != nil {
+			return fmt.Errorf("entry %d: cannot set both `entryValue` and `delete`", i)
+		}
+		if !e.Delete && (e.EntryValue == nil || len(*e.EntryValue) == 0) {
+			return fmt.Errorf("entry %d: `entryValue` field required unless `delete` is set", i)
+		}
+		if e.Revision != nil && *e.Revision <= 0 {
+			return fmt.Errorf("entry %d: if setting optional `revision` field, it needs to be a positive integer", i)
+		}
+	}
+	return nil
+}
+
+func (t *kvStoreAPIHandler) batchEntries(ctx context.Context, c Call, w io.Writer) error {
+	var opts batchEntriesOptions
+	if err := unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	if opts.Team == nil {
+		opts.Team = &t.selfTeam
+	}
+	entries := make([]keybase1.KVBatchEntry, len(opts.Entries))
+	for i, e := range opts.Entries {
+		var revision int
+		if e.Revision != nil {
+			revision = *e.Revision
+		}
+		entries[i] = keybase1.KVBatchEntry{
+			Namespace:  e.Namespace,
+			EntryKey:   e.EntryKey,
+			Revision:   revision,
+			EntryValue: e.EntryValue,
+		}
+	}
+	arg := keybase1.BatchKVEntriesArg{
+		SessionID: 0,
+		TeamName:  *opts.Team,
+		Entries:   entries,
+	}
+	res, err := t.kvstore.BatchKVEntries(ctx, arg)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	return t.encodeResult(c, res, w)
+}
+
 func (t *kvStoreAPIHandler) encodeResult(call Call, result interface{}, w io.Writer) error {
 	return encodeResult(call, result, w, t.indent)
 }
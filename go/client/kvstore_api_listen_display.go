@@ -0,0 +1,86 @@
+// Copyright 2019 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+const notifTypeKVStore = "kvstore"
+
+type kvStoreNotification struct {
+	// kvstore
+	Type string `json:"type"`
+	// put or delete
+	Source       string                 `json:"source"`
+	Notification keybase1.KVEntryChange `json:"notification"`
+}
+
+// KVStoreFilter matches kvstore notifications by team, namespace, or both.
+type KVStoreFilter struct {
+	Team      string `json:"team,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Valid is true if the filter restricts by at least one of its fields.
+func (f KVStoreFilter) Valid() bool {
+	return len(f.Team) > 0 || len(f.Namespace) > 0
+}
+
+func (f KVStoreFilter) matches(change keybase1.KVEntryChange) bool {
+	if len(f.Team) > 0 && f.Team != change.TeamName {
+		return false
+	}
+	if len(f.Namespace) > 0 && f.Namespace != change.Namespace {
+		return false
+	}
+	return true
+}
+
+type kvStoreNotificationDisplay struct {
+	*baseNotificationDisplay
+	filters []KVStoreFilter
+}
+
+var _ keybase1.NotifyKVStoreInterface = (*kvStoreNotificationDisplay)(nil)
+
+func newKVStoreNotificationDisplay(g *libkb.GlobalContext, filters []KVStoreFilter) *kvStoreNotificationDisplay {
+	return &kvStoreNotificationDisplay{
+		baseNotificationDisplay: newBaseNotificationDisplay(g),
+		filters:                 filters,
+	}
+}
+
+func (d *kvStoreNotificationDisplay) shouldDisplay(change keybase1.KVEntryChange) bool {
+	if len(d.filters) == 0 {
+		return true
+	}
+	for _, f := range d.filters {
+		if f.matches(change) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *kvStoreNotificationDisplay) KvEntryChanged(ctx context.Context, change keybase1.KVEntryChange) error {
+	if !d.shouldDisplay(change) {
+		return nil
+	}
+	source, ok := keybase1.KVEntryChangeTypeRevMap[change.ChangeType]
+	if !ok {
+		source = fmt.Sprintf("%v", int(change.ChangeType))
+	}
+	d.printJSON(kvStoreNotification{
+		Type:         notifTypeKVStore,
+		Source:       strings.ToLower(source),
+		Notification: change,
+	})
+	return nil
+}
@@ -377,6 +377,14 @@ func GetSessionClient(g *libkb.GlobalContext) (keybase1.SessionClient, error) {
 	return keybase1.SessionClient{Cli: rcli}, nil
 }
 
+func GetCryptoClient(g *libkb.GlobalContext) (keybase1.CryptoClient, error) {
+	rcli, _, err := GetRPCClientWithContext(g)
+	if err != nil {
+		return keybase1.CryptoClient{}, err
+	}
+	return keybase1.CryptoClient{Cli: rcli}, nil
+}
+
 func GetSimpleFSClient(g *libkb.GlobalContext) (cli keybase1.SimpleFSClient, err error) {
 	rcli, _, err := GetRPCClientWithContext(g)
 	if err != nil {
@@ -0,0 +1,448 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// walkDir calls fn for everything under dir in lexical order, with its path
+// relative to dir in slash form, starting with dir itself as ".". skip, if
+// set, is an absolute path left out of the walk, like the output file of the
+// command doing the walking.
+func walkDir(dir, skip string, fn func(rel, full string, fi fs.FileInfo) error) error {
+	return filepath.WalkDir(dir, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if len(skip) > 0 {
+			if abs, err := filepath.Abs(full); err == nil && abs == skip {
+				return nil
+			}
+		}
+		rel, err := filepath.Rel(dir, full)
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), full, fi)
+	})
+}
+
+// writeDirArchive writes dir to w as a tar archive that only depends on the
+// names, contents, permission bits and modification times of what's in it,
+// so that the same directory always makes the same archive. Owners, access
+// times and the like are left out. skip is passed on to walkDir.
+func writeDirArchive(w io.Writer, dir, skip string) error {
+	tw := tar.NewWriter(w)
+	err := walkDir(dir, skip, func(rel, full string, fi fs.FileInfo) error {
+		hdr := &tar.Header{
+			Name:    rel,
+			Mode:    int64(fi.Mode().Perm()),
+			ModTime: fi.ModTime(),
+			Format:  tar.FormatPAX,
+		}
+		switch {
+		case fi.IsDir():
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		case fi.Mode().IsRegular():
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = fi.Size()
+		case fi.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(full)
+			if err != nil {
+				return err
+			}
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = target
+		default:
+			return fmt.Errorf("%s: can't archive a %s", full, fi.Mode().Type())
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+		f, err := os.Open(full)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		n, err := io.Copy(tw, f)
+		if err != nil {
+			return err
+		}
+		if n != hdr.Size {
+			return fmt.Errorf("%s changed size while it was being archived", full)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// archiveEntryPath checks that an archive entry stays inside the directory
+// it's extracted to, and returns its cleaned, slash-separated path.
+func archiveEntryPath(name string, symlinks map[string]bool) (string, error) {
+	p := path.Clean(name)
+	if path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("archive entry %q is outside of the directory", name)
+	}
+	// A symlink extracted earlier could point anywhere, so nothing can be
+	// extracted through one.
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if symlinks[dir] {
+			return "", fmt.Errorf("archive entry %q is inside symlink %q", name, dir)
+		}
+	}
+	return p, nil
+}
+
+// extractDirArchive extracts an archive made by writeDirArchive into dir,
+// which must already exist, and returns the number of files it holds.
+// Directory modes and times are set last, once their contents are written.
+func extractDirArchive(r io.Reader, dir string) (files int, err error) {
+	tr := tar.NewReader(r)
+	symlinks := make(map[string]bool)
+	var dirs []*tar.Header
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, err
+		}
+		rel, err := archiveEntryPath(hdr.Name, symlinks)
+		if err != nil {
+			return files, err
+		}
+		full := filepath.Join(dir, filepath.FromSlash(rel))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if rel != "." {
+				if err := os.Mkdir(full, 0700); err != nil {
+					return files, err
+				}
+			}
+			hdr.Name = full
+			dirs = append(dirs, hdr)
+		case tar.TypeReg:
+			if err := extractFile(tr, full, hdr); err != nil {
+				return files, fmt.Errorf("%s: %v", rel, err)
+			}
+			files++
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, full); err != nil {
+				return files, err
+			}
+			symlinks[rel] = true
+			files++
+		default:
+			return files, fmt.Errorf("archive entry %q has unsupported type %q", hdr.Name, hdr.Typeflag)
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		hdr := dirs[i]
+		if err := os.Chmod(hdr.Name, fs.FileMode(hdr.Mode).Perm()); err != nil {
+			return files, err
+		}
+		if err := os.Chtimes(hdr.Name, hdr.ModTime, hdr.ModTime); err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+func extractFile(r io.Reader, full string, hdr *tar.Header) error {
+	f, err := os.OpenFile(full, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if n != hdr.Size {
+		return io.ErrUnexpectedEOF
+	}
+	if err := os.Chmod(full, fs.FileMode(hdr.Mode).Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(full, hdr.ModTime, hdr.ModTime)
+}
+
+// DirArchiveSource is a Source that reads a directory as an archive, for
+// "keybase encrypt -r".
+type DirArchiveSource struct {
+	dir  string
+	skip string
+	pr   *io.PipeReader
+}
+
+// NewDirArchiveSource archives dir, leaving out outfile if it's inside dir,
+// since it's being written while the archive is read. outfile may be empty,
+// or "-" for stdout.
+func NewDirArchiveSource(dir, outfile string) (*DirArchiveSource, error) {
+	s := &DirArchiveSource{dir: dir}
+	if len(outfile) > 0 && outfile != "-" {
+		var err error
+		if s.skip, err = filepath.Abs(outfile); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *DirArchiveSource) Open() error {
+	pr, pw := io.Pipe()
+	s.pr = pr
+	go func() {
+		pw.CloseWithError(writeDirArchive(pw, s.dir, s.skip))
+	}()
+	return nil
+}
+
+func (s *DirArchiveSource) Read(p []byte) (int, error) {
+	return s.pr.Read(p)
+}
+
+func (s *DirArchiveSource) Close() error {
+	if s.pr == nil {
+		return nil
+	}
+	return s.pr.Close()
+}
+
+func (s *DirArchiveSource) CloseWithError(error) error {
+	return s.Close()
+}
+
+func (s *DirArchiveSource) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("DirArchiveSource does not support Seek")
+}
+
+// DirExtractSink is a Sink that extracts the archive written to it into a
+// new directory, for "keybase decrypt -r". The archive is extracted next to
+// the destination, and only moved there if it was all decrypted and
+// verified, so that nothing shows up from a message that turns out to be
+// bad halfway through.
+type DirExtractSink struct {
+	dest  string
+	tmp   string
+	pw    *io.PipeWriter
+	done  chan error
+	err   error
+	files int
+}
+
+func NewDirExtractSink(dest string) *DirExtractSink {
+	return &DirExtractSink{dest: dest}
+}
+
+func (s *DirExtractSink) Open() (err error) {
+	if _, err := os.Lstat(s.dest); err == nil {
+		return fmt.Errorf("%s already exists", s.dest)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	s.tmp, err = os.MkdirTemp(filepath.Dir(s.dest), "."+filepath.Base(s.dest)+".partial-")
+	if err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	s.pw = pw
+	s.done = make(chan error, 1)
+	go func() {
+		files, err := extractDirArchive(pr, s.tmp)
+		if err == nil {
+			// Anything after the end of the archive is as suspicious as
+			// anything else that's wrong with it.
+			var n int64
+			if n, err = io.Copy(io.Discard, pr); err == nil && n > 0 {
+				err = errors.New("unexpected data after the end of the archive")
+			}
+		}
+		s.files = files
+		pr.CloseWithError(err)
+		s.done <- err
+	}()
+	return nil
+}
+
+func (s *DirExtractSink) Write(p []byte) (int, error) {
+	return s.pw.Write(p)
+}
+
+func (s *DirExtractSink) Close() error {
+	if s.pw == nil || s.done == nil {
+		return s.err
+	}
+	s.pw.Close()
+	s.err = <-s.done
+	s.done = nil
+	return s.err
+}
+
+// HitError moves the extracted directory into place, unless decryption or
+// extraction failed, in which case it's thrown away.
+func (s *DirExtractSink) HitError(e error) error {
+	if len(s.tmp) == 0 {
+		return nil
+	}
+	tmp := s.tmp
+	s.tmp = ""
+	if e != nil || s.err != nil {
+		return os.RemoveAll(tmp)
+	}
+	return os.Rename(tmp, s.dest)
+}
+
+// Files is the number of files and symlinks extracted.
+func (s *DirExtractSink) Files() int {
+	return s.files
+}
+
+// dirManifest lists the files and symlinks in a directory, with the hashes
+// of the files, for "keybase sign --manifest". Empty directories aren't
+// listed.
+type dirManifest struct {
+	Version int                `json:"version"`
+	Files   []dirManifestEntry `json:"files"`
+}
+
+type dirManifestEntry struct {
+	Path    string      `json:"path"`
+	Mode    fs.FileMode `json:"mode"`
+	Size    int64       `json:"size,omitempty"`
+	SHA256  string      `json:"sha256,omitempty"`
+	Symlink string      `json:"symlink,omitempty"`
+}
+
+const dirManifestVersion = 1
+
+func newDirManifest(dir, skip string) (*dirManifest, error) {
+	m := &dirManifest{Version: dirManifestVersion, Files: []dirManifestEntry{}}
+	err := walkDir(dir, skip, func(rel, full string, fi fs.FileInfo) error {
+		e := dirManifestEntry{Path: rel, Mode: fi.Mode().Perm()}
+		switch {
+		case fi.IsDir():
+			return nil
+		case fi.Mode().IsRegular():
+			f, err := os.Open(full)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			h := sha256.New()
+			if e.Size, err = io.Copy(h, f); err != nil {
+				return err
+			}
+			e.SHA256 = hex.EncodeToString(h.Sum(nil))
+		case fi.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(full)
+			if err != nil {
+				return err
+			}
+			e.Mode = 0
+			e.Symlink = target
+		default:
+			return fmt.Errorf("%s: can't add a %s to a manifest", full, fi.Mode().Type())
+		}
+		m.Files = append(m.Files, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *dirManifest) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeDirManifest(data []byte) (*dirManifest, error) {
+	var m dirManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("not a directory manifest: %v", err)
+	}
+	if m.Version != dirManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+	return &m, nil
+}
+
+// dirManifestDiff is how a directory differs from a signed manifest of it.
+type dirManifestDiff struct {
+	Modified []string
+	Added    []string
+	Missing  []string
+}
+
+func (d dirManifestDiff) Empty() bool {
+	return len(d.Modified)+len(d.Added)+len(d.Missing) == 0
+}
+
+// diff compares the directory as it is now, in cur, to the signed manifest m.
+// A file whose contents, permissions or type changed counts as modified.
+func (m *dirManifest) diff(cur *dirManifest) (d dirManifestDiff) {
+	signed := make(map[string]dirManifestEntry, len(m.Files))
+	for _, e := range m.Files {
+		signed[e.Path] = e
+	}
+	for _, e := range cur.Files {
+		s, ok := signed[e.Path]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, e.Path)
+		case s != e:
+			d.Modified = append(d.Modified, e.Path)
+		}
+		delete(signed, e.Path)
+	}
+	for _, e := range m.Files {
+		if _, ok := signed[e.Path]; ok {
+			d.Missing = append(d.Missing, e.Path)
+		}
+	}
+	return d
+}
+
+// ManifestSink is a Sink that keeps a verified manifest in memory.
+type ManifestSink struct {
+	bytes.Buffer
+}
+
+func (s *ManifestSink) Open() error            { return nil }
+func (s *ManifestSink) Close() error           { return nil }
+func (s *ManifestSink) HitError(_ error) error { return nil }
@@ -0,0 +1,169 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func makeTestDir(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "src")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "empty"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "run.sh"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(dir, "link")))
+	mtime := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "sub", "run.sh"), mtime, mtime))
+	return dir
+}
+
+func TestDirArchiveRoundTrip(t *testing.T) {
+	dir := makeTestDir(t)
+
+	var a1, a2 bytes.Buffer
+	require.NoError(t, writeDirArchive(&a1, dir, ""))
+	require.NoError(t, writeDirArchive(&a2, dir, ""))
+	require.Equal(t, a1.Bytes(), a2.Bytes())
+
+	dest := filepath.Join(t.TempDir(), "dest")
+	snk := NewDirExtractSink(dest)
+	require.NoError(t, snk.Open())
+	_, err := snk.Write(a1.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, snk.Close())
+	require.NoError(t, snk.HitError(nil))
+	require.Equal(t, 3, snk.Files())
+
+	data, err := os.ReadFile(filepath.Join(dest, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+	fi, err := os.Stat(filepath.Join(dest, "sub", "run.sh"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+	require.True(t, fi.ModTime().Equal(time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)))
+	target, err := os.Readlink(filepath.Join(dest, "link"))
+	require.NoError(t, err)
+	require.Equal(t, "a.txt", target)
+	_, err = os.Stat(filepath.Join(dest, "sub", "empty"))
+	require.NoError(t, err)
+
+	// The extracted copy makes the same archive as the original.
+	var a3 bytes.Buffer
+	require.NoError(t, writeDirArchive(&a3, dest, ""))
+	require.Equal(t, a1.Bytes(), a3.Bytes())
+}
+
+func TestDirArchiveSkipsOutfile(t *testing.T) {
+	dir := makeTestDir(t)
+	var want bytes.Buffer
+	require.NoError(t, writeDirArchive(&want, dir, ""))
+
+	// Encrypting into the directory being encrypted leaves the output out.
+	// Writing it changes the directory's own mtime, so put that back.
+	fi, err := os.Stat(dir)
+	require.NoError(t, err)
+	outfile := filepath.Join(dir, "out.saltpack")
+	require.NoError(t, os.WriteFile(outfile, []byte("partial output"), 0644))
+	require.NoError(t, os.Chtimes(dir, fi.ModTime(), fi.ModTime()))
+	src, err := NewDirArchiveSource(dir, outfile)
+	require.NoError(t, err)
+	require.NoError(t, src.Open())
+	got, err := io.ReadAll(src)
+	require.NoError(t, err)
+	require.NoError(t, src.Close())
+	require.Equal(t, want.Bytes(), got)
+
+	// A relative outfile path is the same file.
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	rel, err := filepath.Rel(wd, outfile)
+	require.NoError(t, err)
+	src, err = NewDirArchiveSource(dir, rel)
+	require.NoError(t, err)
+	require.NoError(t, src.Open())
+	got, err = io.ReadAll(src)
+	require.NoError(t, err)
+	require.NoError(t, src.Close())
+	require.Equal(t, want.Bytes(), got)
+}
+
+func TestDirExtractSinkFailure(t *testing.T) {
+	dir := makeTestDir(t)
+	var a bytes.Buffer
+	require.NoError(t, writeDirArchive(&a, dir, ""))
+
+	// A decryption error throws away whatever was extracted.
+	parent := t.TempDir()
+	dest := filepath.Join(parent, "dest")
+	snk := NewDirExtractSink(dest)
+	require.NoError(t, snk.Open())
+	_, err := snk.Write(a.Bytes()[:a.Len()/2])
+	require.NoError(t, err)
+	snk.Close()
+	require.NoError(t, snk.HitError(errors.New("bad chunk")))
+	entries, err := os.ReadDir(parent)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// It won't extract over an existing directory.
+	require.Error(t, NewDirExtractSink(dir).Open())
+}
+
+func TestDirArchiveRejectsEscapes(t *testing.T) {
+	for _, hdrs := range [][]*tar.Header{
+		{{Name: "../evil", Typeflag: tar.TypeReg}},
+		{{Name: "/etc/evil", Typeflag: tar.TypeReg}},
+		{{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}, {Name: "link/evil", Typeflag: tar.TypeReg}},
+	} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range hdrs {
+			require.NoError(t, tw.WriteHeader(hdr))
+		}
+		require.NoError(t, tw.Close())
+		_, err := extractDirArchive(&buf, t.TempDir())
+		require.Error(t, err, hdrs[len(hdrs)-1].Name)
+	}
+}
+
+func TestDirManifestDiff(t *testing.T) {
+	dir := makeTestDir(t)
+	signedPath := filepath.Join(dir, "MANIFEST.saltpack")
+	require.NoError(t, os.WriteFile(signedPath, []byte("signed"), 0644))
+
+	m, err := newDirManifest(dir, signedPath)
+	require.NoError(t, err)
+	data, err := m.encode()
+	require.NoError(t, err)
+	signed, err := decodeDirManifest(data)
+	require.NoError(t, err)
+	require.Len(t, signed.Files, 3)
+
+	cur, err := newDirManifest(dir, signedPath)
+	require.NoError(t, err)
+	require.True(t, signed.diff(cur).Empty())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("jello"), 0644))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "sub", "run.sh"), 0700))
+	require.NoError(t, os.Remove(filepath.Join(dir, "link")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), nil, 0644))
+	cur, err = newDirManifest(dir, signedPath)
+	require.NoError(t, err)
+	diff := signed.diff(cur)
+	require.Equal(t, []string{"a.txt", "sub/run.sh"}, diff.Modified)
+	require.Equal(t, []string{"new.txt"}, diff.Added)
+	require.Equal(t, []string{"link"}, diff.Missing)
+
+	_, err = decodeDirManifest([]byte(`{"version": 2, "files": []}`))
+	require.Error(t, err)
+}
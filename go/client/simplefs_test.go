@@ -90,6 +90,27 @@ func (s SimpleFSMock) SimpleFSSetStat(ctx context.Context, arg keybase1.SimpleFS
 	return nil
 }
 
+// SimpleFSListXattrs - List the user extended attributes of a file or
+// directory.
+func (s SimpleFSMock) SimpleFSListXattrs(
+	ctx context.Context, path keybase1.Path) ([]keybase1.SimpleFSXattr, error) {
+	return nil, nil
+}
+
+// SimpleFSSetXattr - Set a user extended attribute on a file or
+// directory.
+func (s SimpleFSMock) SimpleFSSetXattr(
+	ctx context.Context, arg keybase1.SimpleFSSetXattrArg) error {
+	return nil
+}
+
+// SimpleFSRemoveXattr - Remove a user extended attribute from a file or
+// directory.
+func (s SimpleFSMock) SimpleFSRemoveXattr(
+	ctx context.Context, arg keybase1.SimpleFSRemoveXattrArg) error {
+	return nil
+}
+
 // SimpleFSRead - Read (possibly partial) contents of open file,
 // up to the amount specified by size.
 // Repeat until zero bytes are returned or error.
@@ -385,6 +406,25 @@ func (s SimpleFSMock) SimpleFSGetGUIFileContext(ctx context.Context,
 	return keybase1.GUIFileContext{}, nil
 }
 
+func (s SimpleFSMock) SimpleFSStartWebDAV(
+	_ context.Context, _ int) (keybase1.SimpleFSWebDAVServer, error) {
+	return keybase1.SimpleFSWebDAVServer{}, nil
+}
+
+func (s SimpleFSMock) SimpleFSStopWebDAV(_ context.Context) error {
+	return nil
+}
+
+func (s SimpleFSMock) SimpleFSStartS3Gateway(
+	_ context.Context, _ keybase1.SimpleFSStartS3GatewayArg) (
+	keybase1.SimpleFSS3Gateway, error) {
+	return keybase1.SimpleFSS3Gateway{}, nil
+}
+
+func (s SimpleFSMock) SimpleFSStopS3Gateway(_ context.Context) error {
+	return nil
+}
+
 func (s SimpleFSMock) SimpleFSGetFilesTabBadge(_ context.Context) (
 	keybase1.FilesTabBadge, error) {
 	return keybase1.FilesTabBadge_NONE, nil
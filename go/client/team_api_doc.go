@@ -22,6 +22,9 @@ Add members to a team:
 Change a member's role:
     {"method": "edit-member", "params": {"options": {"team": "phoenix", "username": "frank", "role": "writer"}}}
 
+Show the history of a team's membership, invites, settings and keys ("member", "after" and "before" are optional):
+    {"method": "history", "params": {"options": {"team": "phoenix", "member": "frank", "after": "2020-01-01", "before": "2020-07-01T00:00:00Z"}}}
+
 Remove a member:
     {"method": "remove-member", "params": {"options": {"team": "phoenix", "username": "frank"}}}
 
@@ -34,6 +37,9 @@ Rename a subteam:
 Leave a team:
     {"method": "leave-team", "params": {"options": {"team": "phoenix.humans", "permanent": true}}}
 
+Bring a team and its subteams to a desired state (set "dry-run" to only list the changes):
+    {"method": "apply", "params": {"options": {"dry-run": true, "teams": [{"team": "phoenix", "members": [{"username": "cleo", "role": "owner"}, {"username": "frank", "role": "writer"}], "settings": {"open": true, "join-as": "reader"}}, {"team": "phoenix.bots", "members": [{"username": "frank", "role": "admin"}, {"username": "helperbot", "role": "restrictedbot", "bot-settings": {"cmds": true}}]}]}}}
+
 List requests to join a team:
     {"method": "list-requests", "params": {"options": {"team": "phoenix"}}}
 `
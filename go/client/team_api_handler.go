@@ -31,8 +31,10 @@ func (t *teamAPIHandler) handle(ctx context.Context, c Call, w io.Writer) error
 
 const (
 	addMembersMethod    = "add-members"
+	applyMethod         = "apply"
 	createTeamMethod    = "create-team"
 	editMemberMethod    = "edit-member"
+	teamHistoryMethod   = "history"
 	leaveTeamMethod     = "leave-team"
 	listSelfMethod      = "list-self-memberships"
 	listTeamMethod      = "list-team-memberships"
@@ -44,8 +46,10 @@ const (
 
 var validMethodsV1 = map[string]bool{
 	addMembersMethod:    true,
+	applyMethod:         true,
 	createTeamMethod:    true,
 	editMemberMethod:    true,
+	teamHistoryMethod:   true,
 	leaveTeamMethod:     true,
 	listSelfMethod:      true,
 	listTeamMethod:      true,
@@ -73,10 +77,14 @@ func (t *teamAPIHandler) handleV1(ctx context.Context, c Call, w io.Writer) erro
 	switch c.Method {
 	case addMembersMethod:
 		return t.addMembers(ctx, c, w)
+	case applyMethod:
+		return t.apply(ctx, c, w)
 	case createTeamMethod:
 		return t.createTeam(ctx, c, w)
 	case editMemberMethod:
 		return t.editMember(ctx, c, w)
+	case teamHistoryMethod:
+		return t.history(ctx, c, w)
 	case leaveTeamMethod:
 		return t.leaveTeam(ctx, c, w)
 	case listSelfMethod:
@@ -189,6 +197,100 @@ func (t *teamAPIHandler) addMembers(ctx context.Context, c Call, w io.Writer) er
 	return t.encodeResult(c, all, w)
 }
 
+type applyMemberOptions struct {
+	Username    string                    `json:"username"`
+	Role        string                    `json:"role"`
+	BotSettings *keybase1.TeamBotSettings `json:"bot-settings,omitempty"`
+}
+
+type applySettingsOptions struct {
+	Open   bool   `json:"open"`
+	JoinAs string `json:"join-as,omitempty"`
+}
+
+type applyTeamOptions struct {
+	Team     string                `json:"team"`
+	Members  []applyMemberOptions  `json:"members"`
+	Settings *applySettingsOptions `json:"settings,omitempty"`
+}
+
+// applyOptions is the desired state of a set of teams, shared by the `apply`
+// API method and `keybase team apply`.
+type applyOptions struct {
+	Teams  []applyTeamOptions `json:"teams"`
+	DryRun bool               `json:"dry-run"`
+}
+
+func (a *applyOptions) Check() error {
+	if len(a.Teams) == 0 {
+		return errors.New("no teams specified")
+	}
+	_, err := a.teams()
+	return err
+}
+
+func (a *applyOptions) teams() (res []keybase1.TeamApplyTeam, err error) {
+	for _, team := range a.Teams {
+		name, err := keybase1.TeamNameFromString(team.Team)
+		if err != nil {
+			return nil, err
+		}
+		desired := keybase1.TeamApplyTeam{Name: name.String()}
+		for _, m := range team.Members {
+			if len(m.Username) == 0 {
+				return nil, errors.New("empty username")
+			}
+			if len(m.Role) == 0 {
+				return nil, errors.New("empty role")
+			}
+			role, err := mapRole(m.Role)
+			if err != nil {
+				return nil, err
+			}
+			desired.Members = append(desired.Members, keybase1.TeamApplyMember{
+				Username:    m.Username,
+				Role:        role,
+				BotSettings: m.BotSettings,
+			})
+		}
+		if team.Settings != nil {
+			settings := keybase1.TeamSettings{Open: team.Settings.Open}
+			if team.Settings.Open {
+				joinAs := team.Settings.JoinAs
+				if len(joinAs) == 0 {
+					joinAs = "reader"
+				}
+				if settings.JoinAs, err = mapRole(joinAs); err != nil {
+					return nil, err
+				}
+			}
+			desired.Settings = &settings
+		}
+		res = append(res, desired)
+	}
+	return res, nil
+}
+
+func (t *teamAPIHandler) apply(ctx context.Context, c Call, w io.Writer) error {
+	var opts applyOptions
+	if err := t.unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+
+	teams, err := opts.teams()
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	res, err := t.cli.TeamApply(ctx, keybase1.TeamApplyArg{
+		Teams:  teams,
+		DryRun: opts.DryRun,
+	})
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	return t.encodeResult(c, res, w)
+}
+
 type createTeamOptions struct {
 	Team string `json:"team"`
 }
@@ -265,6 +367,48 @@ func (t *teamAPIHandler) editMember(ctx context.Context, c Call, w io.Writer) er
 	return t.encodeResult(c, nil, w)
 }
 
+type historyOptions struct {
+	Team   string `json:"team"`
+	Member string `json:"member"`
+	After  string `json:"after"`
+	Before string `json:"before"`
+}
+
+func (h *historyOptions) Check() error {
+	if _, err := keybase1.TeamNameFromString(h.Team); err != nil {
+		return err
+	}
+	if _, err := parseDateOrTime(h.After); err != nil {
+		return err
+	}
+	_, err := parseDateOrTime(h.Before)
+	return err
+}
+
+func (t *teamAPIHandler) history(ctx context.Context, c Call, w io.Writer) error {
+	var opts historyOptions
+	if err := t.unmarshalOptions(c, &opts); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+
+	arg := keybase1.TeamHistoryArg{
+		TeamName: opts.Team,
+		Member:   opts.Member,
+	}
+	var err error
+	if arg.After, err = parseDateOrTime(opts.After); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	if arg.Before, err = parseDateOrTime(opts.Before); err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	events, err := t.cli.TeamHistory(ctx, arg)
+	if err != nil {
+		return t.encodeErr(c, err, w)
+	}
+	return t.encodeResult(c, events, w)
+}
+
 type leaveTeamOptions struct {
 	Team      string `json:"team"`
 	Permanent bool   `json:"permanent"`
@@ -3,7 +3,9 @@ package client
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/keybase/cli"
 	"github.com/adamwalz/keybase-client/go/chat/utils"
@@ -155,3 +157,29 @@ func lookupConvIDsByTopicName(g *libkb.GlobalContext, tlfName string,
 	}
 	return convIDs, nil
 }
+
+// parseMembershipDuration parses durations like time.ParseDuration, and also
+// whole days and weeks such as "30d" or "2w".
+func parseMembershipDuration(s string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %v", s, err)
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("duration has to be positive, got %q", s)
+		}
+		return d, nil
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package client
+
+import (
+	"fmt"
+	"time"
+
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+)
+
+// dateFlagLayout is the day-only form accepted by parseDateOrTime.
+const dateFlagLayout = "2006-01-02"
+
+// parseDateOrTime parses the value of a date flag (like --after or --before),
+// which is either a local date or an RFC 3339 time. An empty value is the zero
+// time.
+func parseDateOrTime(s string) (keybase1.Time, error) {
+	if s == "" {
+		return 0, nil
+	}
+	t, err := time.ParseInLocation(dateFlagLayout, s, time.Local)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return 0, fmt.Errorf("bad time %q: must be YYYY-MM-DD or RFC 3339", s)
+		}
+	}
+	return keybase1.ToTime(t), nil
+}
@@ -137,6 +137,8 @@ func (e *SaltpackDecrypt) Run(m libkb.MetaContext) (err error) {
 	var me *libkb.User
 
 	keyring := saltpackBasic.NewKeyring()
+	// age files are only ever encrypted for device and paper keys.
+	var ageKeys []libkb.NaclDHKeyPair
 
 	if e.arg.Opts.UsePaperKey {
 		// Prompt the user for a paper key. This doesn't require you to be
@@ -147,6 +149,7 @@ func (e *SaltpackDecrypt) Run(m libkb.MetaContext) (err error) {
 		}
 		encryptionNaclKeyPair := keypair.EncryptionKey().(libkb.NaclDHKeyPair)
 		addToKeyring(keyring, &encryptionNaclKeyPair)
+		ageKeys = append(ageKeys, encryptionNaclKeyPair)
 
 		// If a paper key is used, we do not have PUK or an active session, so we cannot talk to the server to resolve pseudonym.
 		m.Debug("substituting the default PseudonymResolver as a paper key is being used for decryption")
@@ -172,6 +175,7 @@ func (e *SaltpackDecrypt) Run(m libkb.MetaContext) (err error) {
 		}
 		m.Debug("adding device key for decryption: %v", key.GetKID())
 		addToKeyring(keyring, key)
+		ageKeys = append(ageKeys, *key)
 
 		perUserKeyring, err := m.G().GetPerUserKeyring(m.Ctx())
 		if err != nil {
@@ -207,9 +211,20 @@ func (e *SaltpackDecrypt) Run(m libkb.MetaContext) (err error) {
 		return e.promptForDecrypt(m, kidToIdentify, isAnon, signed)
 	}
 
+	// age files are recognized by their first line and decrypted with the
+	// same keys; they're never signed, so the sender is always anonymous.
+	sc, source, classifyErr := libkb.ClassifyStream(e.arg.Source)
+	if classifyErr == nil && sc.Format == libkb.CryptoMessageFormatAge {
+		m.Debug("| AgeDecrypt")
+		hookAge := func() error {
+			return e.promptForDecrypt(m, "", true /* isAnon */, false /* not signed */)
+		}
+		return libkb.AgeDecrypt(source, e.arg.Sink, ageKeys, hookAge)
+	}
+
 	m.Debug("| SaltpackDecrypt")
 	var mki *saltpack.MessageKeyInfo
-	mki, err = libkb.SaltpackDecrypt(m, e.arg.Source, e.arg.Sink, keyring, hookMki, hookSenderSigningKey, e.pnymResolver)
+	mki, err = libkb.SaltpackDecrypt(m, source, e.arg.Sink, keyring, hookMki, hookSenderSigningKey, e.pnymResolver)
 
 	if decErr, ok := err.(libkb.DecryptionError); ok && decErr.Cause.Err == saltpack.ErrNoDecryptionKey {
 		m.Debug("switching cause of libkb.DecryptionError from saltpack.ErrNoDecryptionKey to more specific libkb.NoDecryptionKeyError")
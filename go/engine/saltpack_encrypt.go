@@ -90,6 +90,10 @@ func (e *SaltpackEncrypt) Run(m libkb.MetaContext) (err error) {
 		return err
 	}
 
+	if e.arg.Opts.Format == keybase1.EncryptionFormat_AGE {
+		return e.runAge(m)
+	}
+
 	if !(e.arg.Opts.UseEntityKeys || e.arg.Opts.UseDeviceKeys || e.arg.Opts.UsePaperKeys || e.arg.Opts.UseKBFSKeysOnlyForTesting) {
 		return fmt.Errorf("no key type for encryption was specified")
 	}
@@ -202,3 +206,44 @@ func (e *SaltpackEncrypt) Run(m libkb.MetaContext) (err error) {
 	}
 	return libkb.SaltpackEncrypt(m, &encarg)
 }
+
+// runAge writes an age file instead of a saltpack message. Age can only
+// encrypt for X25519 keys the recipient holds, which are the device and
+// paper encryption keys; there are no per-user or team keys, no pseudonyms
+// for users who haven't joined yet, and no sender authentication.
+func (e *SaltpackEncrypt) runAge(m libkb.MetaContext) error {
+	switch {
+	case len(e.arg.Opts.TeamRecipients) > 0:
+		return fmt.Errorf("age files can only be encrypted for users, not teams")
+	case e.arg.Opts.AuthenticityType != keybase1.AuthenticityType_ANONYMOUS:
+		return fmt.Errorf("age files can't be signed; use --auth-type=anonymous")
+	case !(e.arg.Opts.UseDeviceKeys || e.arg.Opts.UsePaperKeys):
+		return fmt.Errorf("age files can only be encrypted for device or paper keys")
+	}
+
+	kf := NewSaltpackUserKeyfinder(libkb.SaltpackRecipientKeyfinderArg{
+		Recipients:    e.arg.Opts.Recipients,
+		NoSelfEncrypt: e.arg.Opts.NoSelfEncrypt,
+		UseDeviceKeys: e.arg.Opts.UseDeviceKeys,
+		UsePaperKeys:  e.arg.Opts.UsePaperKeys,
+		NoForcePoll:   e.arg.Opts.NoForcePoll,
+	})
+	if err := RunEngine2(m, kf); err != nil {
+		return err
+	}
+
+	var receivers []libkb.NaclDHKeyPublic
+	for _, kid := range kf.GetPublicKIDs() {
+		gk, err := libkb.ImportKeypairFromKID(kid)
+		if err != nil {
+			return err
+		}
+		kp, ok := gk.(libkb.NaclDHKeyPair)
+		if !ok {
+			return libkb.KeyCannotEncryptError{}
+		}
+		receivers = append(receivers, kp.Public)
+	}
+	m.Debug("SaltpackEncrypt: writing an age file for %d keys", len(receivers))
+	return libkb.AgeEncrypt(e.arg.Source, e.arg.Sink, receivers, e.arg.Opts.Binary)
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+)
+
+var TeamMemberExpiryBackgroundSettings = BackgroundTaskSettings{
+	Start:        3 * time.Minute,
+	StartStagger: 2 * time.Minute,
+	WakeUp:       1 * time.Minute,
+	Interval:     1 * time.Hour,
+	Limit:        10 * time.Minute,
+}
+
+// TeamMemberExpiryBackground periodically warns admins about and ends the
+// team memberships that are set to expire.
+type TeamMemberExpiryBackground struct {
+	libkb.Contextified
+	sync.Mutex
+
+	task *BackgroundTask
+}
+
+func NewTeamMemberExpiryBackground(g *libkb.GlobalContext) *TeamMemberExpiryBackground {
+	task := NewBackgroundTask(g, &BackgroundTaskArgs{
+		Name:     "TeamMemberExpiryBackground",
+		F:        TeamMemberExpiryBackgroundRound,
+		Settings: TeamMemberExpiryBackgroundSettings,
+	})
+	return &TeamMemberExpiryBackground{
+		Contextified: libkb.NewContextified(g),
+		// Install the task early so that Shutdown can be called before RunEngine.
+		task: task,
+	}
+}
+
+func (e *TeamMemberExpiryBackground) Name() string {
+	return "TeamMemberExpiryBackground"
+}
+
+func (e *TeamMemberExpiryBackground) Prereqs() Prereqs {
+	return Prereqs{}
+}
+
+func (e *TeamMemberExpiryBackground) RequiredUIs() []libkb.UIKind {
+	return []libkb.UIKind{}
+}
+
+func (e *TeamMemberExpiryBackground) SubConsumers() []libkb.UIConsumer {
+	return []libkb.UIConsumer{}
+}
+
+// Run starts the engine.
+// Returns immediately, kicks off a background goroutine.
+func (e *TeamMemberExpiryBackground) Run(m libkb.MetaContext) (err error) {
+	return RunEngine2(m, e.task)
+}
+
+func (e *TeamMemberExpiryBackground) Shutdown() {
+	e.task.Shutdown()
+}
+
+func TeamMemberExpiryBackgroundRound(mctx libkb.MetaContext) error {
+	g := mctx.G()
+	if !g.ActiveDevice.Valid() {
+		mctx.Debug("TeamMemberExpiryBackgroundRound; not logged in")
+		return nil
+	}
+
+	return g.GetTeamMemberExpirer().ExpireMembers(mctx)
+}
@@ -0,0 +1,201 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"math/bits"
+
+	"github.com/adamwalz/keybase-client/go/kbfs/kbfscodec"
+)
+
+// fingerprintWindow is the number of trailing bytes that determine
+// the value of the rolling hash: each new byte shifts the hash left
+// by one bit, so a byte's contribution is gone after 64 more bytes.
+const fingerprintWindow = 64
+
+// fingerprintGear maps each byte value to a random 64-bit value for
+// the rolling hash.  It's generated from a fixed seed (using
+// splitmix64), because all writers must agree on it for identical
+// data to be split identically.
+var fingerprintGear = func() (gear [256]uint64) {
+	x := uint64(0x6b6266732d636463)
+	for i := range gear {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+	return gear
+}()
+
+// BlockSplitterFingerprint implements the BlockSplitter interface by
+// picking file block boundaries based on the contents of the file,
+// using a rolling hash over the last few bytes of data.  A block
+// ends wherever the hash matches a fixed bit pattern (though never
+// before a minimum size, and never after the max size).  Since a
+// boundary only depends on the bytes right before it, inserting or
+// removing data only changes the blocks around the edit; the blocks
+// after it split exactly as before, so they have the same contents
+// and can be deduplicated against the existing blocks instead of
+// being uploaded again.
+//
+// Everything besides file block boundaries is handled the same way
+// as in the embedded BlockSplitterSimple.
+type BlockSplitterFingerprint struct {
+	*BlockSplitterSimple
+	minSize int64
+	mask    uint64
+}
+
+var _ BlockSplitter = (*BlockSplitterFingerprint)(nil)
+
+func newBlockSplitterFingerprint(
+	simple *BlockSplitterSimple) *BlockSplitterFingerprint {
+	// Aim for an average block size of about half the max size: no
+	// boundaries for the first quarter, and then one expected every
+	// quarter after that.  That leaves only about a 5% chance that a
+	// block runs all the way to the max size, where it has to be cut
+	// without regard to its contents.
+	minSize := simple.maxSize / 4
+	var mask uint64
+	if minSize > 1 {
+		// Use the top bits of the hash, which depend on the whole
+		// window; the bottom bits only depend on the last few bytes.
+		maskBits := bits.Len64(uint64(minSize)) - 1
+		mask = ^uint64(0) << (64 - maskBits)
+	}
+	return &BlockSplitterFingerprint{
+		BlockSplitterSimple: simple,
+		minSize:             minSize,
+		mask:                mask,
+	}
+}
+
+// NewBlockSplitterFingerprint creates a new BlockSplitterFingerprint,
+// with a max block size that matches the desired size for file
+// blocks in the same way as NewBlockSplitterSimple.
+func NewBlockSplitterFingerprint(desiredBlockSize int64,
+	blockChangeEmbedMaxSize uint64, codec kbfscodec.Codec) (
+	*BlockSplitterFingerprint, error) {
+	simple, err := NewBlockSplitterSimple(
+		desiredBlockSize, blockChangeEmbedMaxSize, codec)
+	if err != nil {
+		return nil, err
+	}
+	return newBlockSplitterFingerprint(simple), nil
+}
+
+// NewBlockSplitterFingerprintExact returns a BlockSplitterFingerprint
+// with the max block size set to an exact value.
+func NewBlockSplitterFingerprintExact(
+	maxSize int64, maxPtrsPerBlock int, blockChangeEmbedMaxSize uint64) (
+	*BlockSplitterFingerprint, error) {
+	simple, err := NewBlockSplitterSimpleExact(
+		maxSize, maxPtrsPerBlock, blockChangeEmbedMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	return newBlockSplitterFingerprint(simple), nil
+}
+
+// hash returns the rolling hash value after the given bytes.  Only
+// the last `fingerprintWindow` bytes matter, so callers can pass in
+// just those.
+func (b *BlockSplitterFingerprint) hash(data []byte) (h uint64) {
+	for _, c := range data {
+		h = h<<1 + fingerprintGear[c]
+	}
+	return h
+}
+
+// nextSplit continues the rolling hash `h` over `data`, which starts
+// at offset `off` within its block, and returns the number of bytes
+// of `data` that belong in the block before the first boundary, or
+// -1 if there is no boundary in `data`.
+func (b *BlockSplitterFingerprint) nextSplit(
+	h uint64, off int64, data []byte) int64 {
+	for i, c := range data {
+		h = h<<1 + fingerprintGear[c]
+		if off+int64(i)+1 >= b.minSize && h&b.mask == 0 {
+			return int64(i) + 1
+		}
+	}
+	return -1
+}
+
+// CopyUntilSplit implements the BlockSplitter interface for
+// BlockSplitterFingerprint.
+func (b *BlockSplitterFingerprint) CopyUntilSplit(
+	block *FileBlock, lastBlock bool, data []byte, off int64) int64 {
+	currLen := int64(len(block.Contents))
+	if !lastBlock || off < currLen {
+		// This isn't an append, so just fill up the block; `Split`
+		// will move the boundaries to the right place before the
+		// block is readied.
+		return b.BlockSplitterSimple.CopyUntilSplit(
+			block, lastBlock, data, off)
+	}
+
+	maxSize := b.MaxSize()
+	if off >= maxSize {
+		// There's no room left in this block, but fill in the hole
+		// up to the end of it.
+		if currLen < maxSize {
+			block.Contents = append(
+				block.Contents, make([]byte, maxSize-currLen)...)
+		}
+		return 0
+	}
+	if off > currLen {
+		block.Contents = append(block.Contents, make([]byte, off-currLen)...)
+	}
+
+	window := block.Contents
+	if len(window) > fingerprintWindow {
+		window = window[len(window)-fingerprintWindow:]
+	}
+	h := b.hash(window)
+	if off == currLen && off >= b.minSize && h&b.mask == 0 {
+		// The block already ends at a boundary.
+		return 0
+	}
+
+	toCopy := int64(len(data))
+	if off+toCopy > maxSize {
+		toCopy = maxSize - off
+	}
+	if n := b.nextSplit(h, off, data[:toCopy]); n >= 0 {
+		toCopy = n
+	}
+	block.Contents = append(block.Contents, data[:toCopy]...)
+	return toCopy
+}
+
+// CheckSplit implements the BlockSplitter interface for
+// BlockSplitterFingerprint.
+func (b *BlockSplitterFingerprint) CheckSplit(block *FileBlock) int64 {
+	n := int64(len(block.Contents))
+	maxSize := b.MaxSize()
+	contents := block.Contents
+	if n > maxSize {
+		contents = contents[:maxSize]
+	}
+	splitAt := b.nextSplit(0, 0, contents)
+	switch {
+	case splitAt < 0 && n < maxSize:
+		// The block ends before its boundary, so it needs more
+		// bytes (unless it's the last block in the file).
+		return -1
+	case splitAt < 0:
+		// No boundary before the max size, so the block has to be
+		// cut there.
+		splitAt = maxSize
+	}
+	if splitAt == n {
+		return 0
+	}
+	return splitAt
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testBsplitterFingerprintData(seed int64, n int) []byte {
+	data := make([]byte, n)
+	_, _ = rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// testBsplitterFingerprintChunks splits `data` the way a sequence of
+// appends to the end of a file would.
+func testBsplitterFingerprintChunks(
+	t *testing.T, bsplit *BlockSplitterFingerprint, data []byte,
+	writeSize int) (chunks [][]byte) {
+	fblock := NewFileBlock().(*FileBlock)
+	for len(data) > 0 {
+		toWrite := data
+		if len(toWrite) > writeSize {
+			toWrite = toWrite[:writeSize]
+		}
+		n := bsplit.CopyUntilSplit(
+			fblock, true, toWrite, int64(len(fblock.Contents)))
+		data = data[n:]
+		if n < int64(len(toWrite)) {
+			chunks = append(chunks, fblock.Contents)
+			fblock = NewFileBlock().(*FileBlock)
+		}
+	}
+	if len(fblock.Contents) > 0 {
+		chunks = append(chunks, fblock.Contents)
+	}
+	return chunks
+}
+
+func TestBsplitterFingerprintAppend(t *testing.T) {
+	bsplit, err := NewBlockSplitterFingerprintExact(4096, 10, 10)
+	require.NoError(t, err)
+	data := testBsplitterFingerprintData(1, 256*1024)
+
+	chunks := testBsplitterFingerprintChunks(t, bsplit, data, 1000)
+	require.True(t, len(chunks) > 256*1024/4096)
+	// The boundaries don't depend on how the data was written.
+	require.Equal(t, chunks, testBsplitterFingerprintChunks(
+		t, bsplit, data, 333))
+	require.Equal(t, data, bytes.Join(chunks, nil))
+
+	for i, chunk := range chunks {
+		if i == len(chunks)-1 {
+			break
+		}
+		require.True(t, int64(len(chunk)) >= bsplit.minSize)
+		require.True(t, int64(len(chunk)) <= bsplit.MaxSize())
+		require.Equal(t, int64(0), bsplit.CheckSplit(
+			&FileBlock{Contents: chunk}), "chunk %d", i)
+	}
+}
+
+func TestBsplitterFingerprintCheckSplit(t *testing.T) {
+	bsplit, err := NewBlockSplitterFingerprintExact(4096, 10, 10)
+	require.NoError(t, err)
+	data := testBsplitterFingerprintData(2, 64*1024)
+	chunks := testBsplitterFingerprintChunks(t, bsplit, data, len(data))
+	require.True(t, len(chunks) > 2)
+
+	// Too long: split at the end of the first chunk.
+	fblock := &FileBlock{Contents: append(append([]byte{}, chunks[0]...),
+		chunks[1]...)}
+	require.Equal(t, int64(len(chunks[0])), bsplit.CheckSplit(fblock))
+
+	// Too short: needs more bytes.
+	fblock = &FileBlock{Contents: chunks[0][:len(chunks[0])-1]}
+	require.Equal(t, int64(-1), bsplit.CheckSplit(fblock))
+
+	// Way too long, with no boundaries: cut at the max size.
+	fblock = &FileBlock{Contents: bytes.Repeat(
+		chunks[0][len(chunks[0])-1:], 2*int(bsplit.MaxSize()))}
+	if bsplit.nextSplit(0, 0, fblock.Contents) < 0 {
+		require.Equal(t, bsplit.MaxSize(), bsplit.CheckSplit(fblock))
+	}
+}
+
+// Inserting a few bytes near the start of the data should only
+// change the first few blocks.
+func TestBsplitterFingerprintInsert(t *testing.T) {
+	bsplit, err := NewBlockSplitterFingerprintExact(4096, 10, 10)
+	require.NoError(t, err)
+	data := testBsplitterFingerprintData(3, 256*1024)
+	chunks := testBsplitterFingerprintChunks(t, bsplit, data, 4096)
+
+	edited := append(append(append([]byte{}, data[:100]...),
+		[]byte("inserted")...), data[100:]...)
+	editedChunks := testBsplitterFingerprintChunks(t, bsplit, edited, 4096)
+
+	known := make(map[string]bool, len(chunks))
+	for _, chunk := range chunks {
+		known[string(chunk)] = true
+	}
+	changed := 0
+	for _, chunk := range editedChunks {
+		if !known[string(chunk)] {
+			changed++
+		}
+	}
+	require.True(t, changed*10 < len(editedChunks), "%d of %d chunks changed",
+		changed, len(editedChunks))
+}
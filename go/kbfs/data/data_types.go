@@ -94,6 +94,14 @@ const (
 	// IndirectDirsVer is the data version for a directory block
 	// that contains indirect pointers.
 	IndirectDirsVer Ver = 4
+	// CompressedBlocksVer is the data version for a block whose
+	// plaintext was compressed before it was encrypted.  Clients
+	// that don't know how to decompress it will refuse to read it,
+	// rather than failing to decode it.
+	CompressedBlocksVer Ver = 5
+	// NewestReadableVer is the newest data version this client can
+	// read, even if it isn't configured to write blocks with it.
+	NewestReadableVer = CompressedBlocksVer
 )
 
 // BlockReqType indicates whether an operation makes block
@@ -252,6 +260,10 @@ type ReadyBlockData struct {
 	// These fields should not be used outside of putBlockToServer.
 	Buf        []byte
 	ServerHalf kbfscrypto.BlockCryptKeyServerHalf
+
+	// Compression is the method that was used to compress the
+	// block's plaintext before encryption.
+	Compression kbfscrypto.BlockCompressionVer
 }
 
 // GetEncodedSize returns the size of the encoded (and encrypted)
@@ -279,10 +291,12 @@ type EntryInfo struct {
 	TeamWriter keybase1.UID `codec:"tw,omitempty"`
 	// Tracks a skiplist of the previous revisions for this entry.
 	PrevRevisions PrevRevisions `codec:"pr,omitempty"`
+	// User extended attributes set on this entry.
+	Xattrs UserXattrs `codec:"x,omitempty"`
 }
 
 func init() {
-	if reflect.ValueOf(EntryInfo{}).NumField() != 7 {
+	if reflect.ValueOf(EntryInfo{}).NumField() != 8 {
 		panic(errors.New(
 			"Unexpected number of fields in EntryInfo; " +
 				"please update EntryInfo.Eq() for your " +
@@ -309,7 +323,7 @@ func EntryInfoFromFileInfo(fi os.FileInfo) EntryInfo {
 		Size:  uint64(fi.Size()), // TODO: deal with negatives?
 		Mtime: mtime,
 		Ctime: mtime,
-		// Leave TeamWriter and PrevRevisions empty
+		// Leave TeamWriter, PrevRevisions and Xattrs empty
 	}
 }
 
@@ -321,6 +335,7 @@ func (ei EntryInfo) Eq(other EntryInfo) bool {
 		ei.Mtime == other.Mtime &&
 		ei.Ctime == other.Ctime &&
 		ei.TeamWriter == other.TeamWriter &&
+		ei.Xattrs.Eq(other.Xattrs) &&
 		len(ei.PrevRevisions) == len(other.PrevRevisions)
 	if !eq {
 		return false
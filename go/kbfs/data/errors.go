@@ -79,3 +79,28 @@ type ShutdownHappenedError struct {
 func (e ShutdownHappenedError) Error() string {
 	return "Shutdown happened"
 }
+
+// InvalidUserXattrNameError indicates that the user tried to set an
+// extended attribute whose name isn't allowed.
+type InvalidUserXattrNameError struct {
+	Name string
+}
+
+// Error implements the error interface for InvalidUserXattrNameError
+func (e InvalidUserXattrNameError) Error() string {
+	return fmt.Sprintf("Invalid extended attribute name %q; names must "+
+		"start with %q and be at most %d bytes",
+		e.Name, UserXattrPrefix, MaxUserXattrNameBytes)
+}
+
+// UserXattrsTooBigError indicates that setting an extended attribute
+// would make an entry's extended attributes too big.
+type UserXattrsTooBigError struct {
+	Size int
+}
+
+// Error implements the error interface for UserXattrsTooBigError
+func (e UserXattrsTooBigError) Error() string {
+	return fmt.Sprintf("Extended attributes would take up %d bytes, "+
+		"more than the limit of %d", e.Size, MaxUserXattrsBytes)
+}
@@ -972,23 +972,41 @@ func (fd *FileData) Split(ctx context.Context, id tlf.ID,
 						break
 					}
 				}
+
+				// Mark all parents as dirty.
+				_, newUnrefs, err := fd.tree.markParentsDirty(
+					ctx, rParentBlocks)
+				unrefs = append(unrefs, newUnrefs...)
+				if err != nil {
+					return unrefs, err
+				}
 			} else {
+				// Mark all parents as dirty while the parent still
+				// has an entry for the right block, since
+				// `markParentsDirty` looks it up by index.
+				dirtyPtrs, newUnrefs, err := fd.tree.markParentsDirty(
+					ctx, rParentBlocks)
+				unrefs = append(unrefs, newUnrefs...)
+				if err != nil {
+					return unrefs, err
+				}
+
 				// TODO: If we're down to just one leaf block at this
 				// level, remove the layer of indirection (KBFS-1824).
 				iptrs := pblock.IPtrs
 				pblock.IPtrs = make([]IndirectFilePtr, len(iptrs)-1)
 				copy(pblock.IPtrs, iptrs[:pb.childIndex])
 				copy(pblock.IPtrs[pb.childIndex:], iptrs[pb.childIndex+1:])
+				err = fd.tree.cacher(ctx, dirtyPtrs[len(dirtyPtrs)-1], pblock)
+				if err != nil {
+					return unrefs, err
+				}
 			}
 
-			// Mark all parents as dirty.
-			_, newUnrefs, err := fd.tree.markParentsDirty(ctx, rParentBlocks)
-			unrefs = append(unrefs, newUnrefs...)
-			if err != nil {
-				return unrefs, err
-			}
-
-			off = endOfBlock
+			// Check this block again, since the bytes it just got
+			// may contain a better place to end it (or it may still
+			// need more).
+			off = startOff
 		}
 	}
 	return unrefs, nil
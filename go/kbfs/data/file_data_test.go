@@ -24,6 +24,12 @@ import (
 
 func setupFileDataTest(t *testing.T, maxBlockSize int64,
 	maxPtrsPerBlock int) (*FileData, BlockCache, DirtyBlockCache, *DirtyFile) {
+	return setupFileDataTestWithSplitter(
+		t, &BlockSplitterSimple{maxBlockSize, maxPtrsPerBlock, 10, 0})
+}
+
+func setupFileDataTestWithSplitter(t *testing.T, bsplit BlockSplitter) (
+	*FileData, BlockCache, DirtyBlockCache, *DirtyFile) {
 	// Make a fake file.
 	ptr := BlockPointer{
 		ID:         kbfsblock.FakeID(42),
@@ -36,7 +42,6 @@ func setupFileDataTest(t *testing.T, maxBlockSize int64,
 		nil,
 	}
 	chargedTo := keybase1.MakeTestUID(1).AsUserOrTeam()
-	bsplit := &BlockSplitterSimple{maxBlockSize, maxPtrsPerBlock, 10, 0}
 	kmd := libkeytest.NewEmptyKeyMetadata(id, 1)
 
 	cleanCache := NewBlockCacheStandard(1<<10, 1<<20)
@@ -879,3 +884,169 @@ func TestFileDataWriteExtendExistingFileWithGap(t *testing.T) {
 		})
 	}
 }
+
+func testFileDataTopBlock(t *testing.T, fd *FileData,
+	dirtyBcache DirtyBlockCache) *FileBlock {
+	block, err := dirtyBcache.Get(context.Background(), fd.tree.file.Tlf,
+		fd.rootBlockPointer(), MasterBranch)
+	require.NoError(t, err)
+	return block.(*FileBlock)
+}
+
+func testFileDataLeafContents(t *testing.T, fd *FileData,
+	dirtyBcache DirtyBlockCache) (leaves [][]byte) {
+	topBlock := testFileDataTopBlock(t, fd, dirtyBcache)
+	for off := Int64Offset(0); off >= 0; {
+		_, _, block, nextBlockOff, _, _, err := fd.GetFileBlockAtOffset(
+			context.Background(), topBlock, off, BlockRead)
+		require.NoError(t, err)
+		// Copy the contents, since later writes modify dirty blocks
+		// in place.
+		leaves = append(leaves, append([]byte{}, block.Contents...))
+		off = nextBlockOff
+	}
+	return leaves
+}
+
+// Test that with a fingerprinting block splitter, rewriting a file
+// with a few bytes inserted near the start leaves most of the leaf
+// blocks the same, once the boundaries are fixed up by `Split`.
+func TestFileDataSplitFingerprint(t *testing.T) {
+	bsplit, err := NewBlockSplitterFingerprintExact(1024, 4, 10)
+	require.NoError(t, err)
+	fd, cleanBcache, dirtyBcache, df := setupFileDataTestWithSplitter(
+		t, bsplit)
+	err = cleanBcache.Put(
+		fd.rootBlockPointer(), fd.tree.file.Tlf, NewFileBlock(),
+		TransientEntry, SkipCacheHash)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	write := func(data []byte, off Int64Offset, de DirEntry) DirEntry {
+		topBlock, _, err := fd.getter(
+			ctx, fd.tree.kmd, fd.rootBlockPointer(), fd.tree.file, BlockWrite)
+		require.NoError(t, err)
+		de, _, _, _, _, err = fd.Write(ctx, data, off, topBlock, de, df)
+		require.NoError(t, err)
+		return de
+	}
+
+	checkFile := func(de DirEntry, data []byte) [][]byte {
+		_, err := fd.Split(ctx, fd.tree.file.Tlf, dirtyBcache,
+			testFileDataTopBlock(t, fd, dirtyBcache), df)
+		require.NoError(t, err)
+
+		require.Equal(t, uint64(len(data)), de.Size)
+		gotData := make([]byte, len(data))
+		nRead, err := fd.Read(ctx, gotData, 0)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), nRead)
+		require.True(t, bytes.Equal(data, gotData))
+
+		leaves := testFileDataLeafContents(t, fd, dirtyBcache)
+		for i, leaf := range leaves[:len(leaves)-1] {
+			require.Equal(t, int64(0), bsplit.CheckSplit(
+				&FileBlock{Contents: leaf}), "leaf %d", i)
+		}
+		return leaves
+	}
+
+	// Write the file in a series of appends.
+	data := testBsplitterFingerprintData(1, 32*1024)
+	var de DirEntry
+	for off := 0; off < len(data); off += 1000 {
+		end := off + 1000
+		if end > len(data) {
+			end = len(data)
+		}
+		de = write(data[off:end], Int64Offset(off), de)
+	}
+	leaves := checkFile(de, data)
+	require.True(t, len(leaves) > len(data)/1024)
+
+	// Rewrite the whole file, with a few bytes inserted near the start.
+	edited := append(append(append([]byte{}, data[:300]...),
+		[]byte("inserted")...), data[300:]...)
+	de = write(edited, 0, de)
+	editedLeaves := checkFile(de, edited)
+
+	known := make(map[string]bool, len(leaves))
+	for _, leaf := range leaves {
+		known[string(leaf)] = true
+	}
+	changed := 0
+	for _, leaf := range editedLeaves {
+		if !known[string(leaf)] {
+			changed++
+		}
+	}
+	require.True(t, changed*10 < len(editedLeaves), "%d of %d leaves changed",
+		changed, len(editedLeaves))
+}
+
+// testBlockSplitterFull is a simple splitter that wants every leaf
+// block but the last one to be full.
+type testBlockSplitterFull struct {
+	*BlockSplitterSimple
+}
+
+func (b testBlockSplitterFull) CheckSplit(block *FileBlock) int64 {
+	if int64(len(block.Contents)) < b.maxSize {
+		return -1
+	}
+	return 0
+}
+
+// Test that `Split` leaves the blocks written by a simple splitter
+// alone, and that a block that needs more bytes keeps pulling them in
+// from the blocks to its right until it's full, even when that empties
+// those blocks.
+func TestFileDataSplitSimple(t *testing.T) {
+	fd, cleanBcache, dirtyBcache, df := setupFileDataTest(t, 4, 16)
+	err := cleanBcache.Put(
+		fd.rootBlockPointer(), fd.tree.file.Tlf, NewFileBlock(),
+		TransientEntry, SkipCacheHash)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	data := make([]byte, 30)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	topBlock, _, err := fd.getter(
+		ctx, fd.tree.kmd, fd.rootBlockPointer(), fd.tree.file, BlockWrite)
+	require.NoError(t, err)
+	de, _, _, _, _, err := fd.Write(ctx, data, 0, topBlock, DirEntry{}, df)
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(data)), de.Size)
+
+	leafLens := func() (lens []int) {
+		for _, leaf := range testFileDataLeafContents(t, fd, dirtyBcache) {
+			lens = append(lens, len(leaf))
+		}
+		return lens
+	}
+	checkData := func() {
+		gotData := make([]byte, len(data))
+		nRead, err := fd.Read(ctx, gotData, 0)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), nRead)
+		require.Equal(t, data, gotData)
+	}
+	require.Equal(t, []int{4, 4, 4, 4, 4, 4, 4, 2}, leafLens())
+
+	unrefs, err := fd.Split(ctx, fd.tree.file.Tlf, dirtyBcache,
+		testFileDataTopBlock(t, fd, dirtyBcache), df)
+	require.NoError(t, err)
+	require.Len(t, unrefs, 0)
+	require.Equal(t, []int{4, 4, 4, 4, 4, 4, 4, 2}, leafLens())
+	checkData()
+
+	// Each block now takes in two and a half of the old blocks.
+	fd.tree.bsplit = testBlockSplitterFull{&BlockSplitterSimple{10, 16, 10, 0}}
+	_, err = fd.Split(ctx, fd.tree.file.Tlf, dirtyBcache,
+		testFileDataTopBlock(t, fd, dirtyBcache), df)
+	require.NoError(t, err)
+	require.Equal(t, []int{10, 10, 10}, leafLens())
+	checkData()
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 
 	"github.com/adamwalz/keybase-client/go/kbfs/kbfsblock"
+	"github.com/adamwalz/keybase-client/go/kbfs/kbfscrypto"
 	"github.com/adamwalz/keybase-client/go/kbfs/libkey"
 	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
 )
@@ -48,10 +49,15 @@ func ReadyBlock(
 		// In case we're deduping an old pointer with an unknown block type.
 		ptr.DirectType = directType
 	} else {
+		dataVer := block.DataVersion()
+		if readyBlockData.Compression != kbfscrypto.BlockCompressionNone &&
+			dataVer < CompressedBlocksVer {
+			dataVer = CompressedBlocksVer
+		}
 		ptr = BlockPointer{
 			ID:         bid,
 			KeyGen:     kmd.LatestKeyGeneration(),
-			DataVer:    block.DataVersion(),
+			DataVer:    dataVer,
 			DirectType: directType,
 			Context:    kbfsblock.MakeFirstContext(chargedTo, bType),
 		}
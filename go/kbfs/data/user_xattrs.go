@@ -0,0 +1,103 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"bytes"
+	"strings"
+)
+
+const (
+	// UserXattrPrefix is the namespace prefix that every user
+	// extended attribute name must have.
+	UserXattrPrefix = "user."
+	// MaxUserXattrNameBytes is the longest allowed name for a user
+	// extended attribute, including its prefix.
+	MaxUserXattrNameBytes = 255
+	// MaxUserXattrsBytes is the most space that all the names and
+	// values of an entry's user extended attributes can take up
+	// together.  They're stored in the parent directory's block, so
+	// this needs to stay small relative to the block size.
+	MaxUserXattrsBytes = 4096
+)
+
+// UserXattrs holds the user extended attributes (e.g.,
+// "user.backup.tag") of a directory entry, keyed by name.  A
+// UserXattrs value is never modified in place, since copies of an
+// EntryInfo share it; use `With` and `Without` to make a changed
+// copy instead.
+type UserXattrs map[string][]byte
+
+// CheckUserXattrName returns an error if `name` isn't a valid name
+// for a user extended attribute.
+func CheckUserXattrName(name string) error {
+	if !strings.HasPrefix(name, UserXattrPrefix) ||
+		len(name) == len(UserXattrPrefix) ||
+		len(name) > MaxUserXattrNameBytes ||
+		strings.IndexByte(name, 0) >= 0 {
+		return InvalidUserXattrNameError{name}
+	}
+	return nil
+}
+
+// Size returns the number of bytes taken up by all the names and
+// values in `ux`.
+func (ux UserXattrs) Size() (size int) {
+	for name, value := range ux {
+		size += len(name) + len(value)
+	}
+	return size
+}
+
+// With returns a copy of `ux` with the attribute `name` set to
+// `value`, or an error if the name is invalid or the attributes
+// would get too big.
+func (ux UserXattrs) With(name string, value []byte) (UserXattrs, error) {
+	if err := CheckUserXattrName(name); err != nil {
+		return nil, err
+	}
+	ret := make(UserXattrs, len(ux)+1)
+	for n, v := range ux {
+		ret[n] = v
+	}
+	ret[name] = append([]byte{}, value...)
+	if size := ret.Size(); size > MaxUserXattrsBytes {
+		return nil, UserXattrsTooBigError{size}
+	}
+	return ret, nil
+}
+
+// Without returns a copy of `ux` without the attribute `name`, and
+// whether `name` was set in the first place.  It returns nil if no
+// attributes are left.
+func (ux UserXattrs) Without(name string) (UserXattrs, bool) {
+	if _, ok := ux[name]; !ok {
+		return ux, false
+	}
+	if len(ux) == 1 {
+		return nil, true
+	}
+	ret := make(UserXattrs, len(ux)-1)
+	for n, v := range ux {
+		if n != name {
+			ret[n] = v
+		}
+	}
+	return ret, true
+}
+
+// Eq returns true if `other` has the same attributes as `ux`.
+func (ux UserXattrs) Eq(other UserXattrs) bool {
+	if len(ux) != len(other) {
+		return false
+	}
+	for name, value := range ux {
+		otherValue, ok := other[name]
+		if !ok || !bytes.Equal(value, otherValue) {
+			return false
+		}
+	}
+	return true
+}
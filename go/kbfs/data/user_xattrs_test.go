@@ -0,0 +1,94 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adamwalz/keybase-client/go/kbfs/kbfscodec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserXattrsWithWithout(t *testing.T) {
+	var ux UserXattrs
+	ux1, err := ux.With("user.a", []byte("1"))
+	require.NoError(t, err)
+	ux2, err := ux1.With("user.b", []byte("2"))
+	require.NoError(t, err)
+	require.Len(t, ux1, 1)
+	require.Equal(t, UserXattrs{
+		"user.a": []byte("1"),
+		"user.b": []byte("2"),
+	}, ux2)
+
+	ux3, removed := ux2.Without("user.a")
+	require.True(t, removed)
+	require.Equal(t, UserXattrs{"user.b": []byte("2")}, ux3)
+	require.Len(t, ux2, 2)
+
+	_, removed = ux3.Without("user.a")
+	require.False(t, removed)
+	ux4, removed := ux3.Without("user.b")
+	require.True(t, removed)
+	require.Nil(t, ux4)
+
+	require.True(t, ux2.Eq(UserXattrs{
+		"user.b": []byte("2"),
+		"user.a": []byte("1"),
+	}))
+	require.False(t, ux2.Eq(ux3))
+	require.True(t, ux4.Eq(nil))
+}
+
+func TestUserXattrsLimits(t *testing.T) {
+	var ux UserXattrs
+	for _, name := range []string{
+		"a", "user.", "trusted.a", "user.a\x00b",
+		"user." + strings.Repeat("a", MaxUserXattrNameBytes),
+	} {
+		_, err := ux.With(name, nil)
+		require.Equal(t, InvalidUserXattrNameError{name}, err, name)
+	}
+
+	ux, err := ux.With("user.a", make([]byte, MaxUserXattrsBytes-6))
+	require.NoError(t, err)
+	_, err = ux.With("user.b", nil)
+	require.Equal(t, UserXattrsTooBigError{MaxUserXattrsBytes + 6}, err)
+	// Replacing a value only counts the new value.
+	_, err = ux.With("user.a", nil)
+	require.NoError(t, err)
+}
+
+// Entries without extended attributes must encode the same way they
+// did before the field existed.
+func TestEntryInfoXattrsEncoding(t *testing.T) {
+	codec := kbfscodec.NewMsgpack()
+	type entryInfoWithoutXattrs struct {
+		Type          EntryType
+		Size          uint64
+		SymPath       string `codec:",omitempty"`
+		Mtime         int64
+		Ctime         int64
+		TeamWriter    string        `codec:"tw,omitempty"`
+		PrevRevisions PrevRevisions `codec:"pr,omitempty"`
+	}
+	ei := EntryInfo{Type: File, Size: 10, Mtime: 1, Ctime: 2}
+	buf, err := codec.Encode(ei)
+	require.NoError(t, err)
+	oldBuf, err := codec.Encode(entryInfoWithoutXattrs{
+		Type: File, Size: 10, Mtime: 1, Ctime: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, oldBuf, buf)
+
+	ei.Xattrs = UserXattrs{"user.a": []byte("1")}
+	buf, err = codec.Encode(ei)
+	require.NoError(t, err)
+	var decoded EntryInfo
+	err = codec.Decode(buf, &decoded)
+	require.NoError(t, err)
+	require.True(t, ei.Eq(decoded))
+}
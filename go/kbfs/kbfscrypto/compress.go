@@ -0,0 +1,90 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package kbfscrypto
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+)
+
+// BlockCompressionVer denotes a version for the method used to
+// compress an encoded block before it is padded and encrypted.
+type BlockCompressionVer int
+
+const (
+	// BlockCompressionNone is the compression version for blocks
+	// that are stored uncompressed.
+	BlockCompressionNone BlockCompressionVer = 0
+	// BlockCompressionSnappy is the compression version for blocks
+	// compressed with the snappy block format.
+	BlockCompressionSnappy BlockCompressionVer = 1
+)
+
+// maxDecompressedBlockSize bounds how big a compressed block may
+// claim to be once decompressed.  KBFS blocks are much smaller than
+// this; anything bigger is corrupt (or malicious).
+const maxDecompressedBlockSize = 16 * 1024 * 1024
+
+func (v BlockCompressionVer) String() string {
+	switch v {
+	case BlockCompressionNone:
+		return "BlockCompressionNone"
+	case BlockCompressionSnappy:
+		return "BlockCompressionSnappy"
+	default:
+		return fmt.Sprintf("BlockCompressionVer(%d)", v)
+	}
+}
+
+// CompressBlock compresses an encoded block with the given
+// compression version.  If compressing wouldn't make the block any
+// smaller, it returns the block unchanged along with
+// BlockCompressionNone, and that's the version that must be recorded
+// with the block.
+func CompressBlock(encodedBlock []byte, ver BlockCompressionVer) (
+	[]byte, BlockCompressionVer, error) {
+	var compressed []byte
+	switch ver {
+	case BlockCompressionNone:
+		return encodedBlock, BlockCompressionNone, nil
+	case BlockCompressionSnappy:
+		compressed = snappy.Encode(nil, encodedBlock)
+	default:
+		return nil, BlockCompressionNone, errors.WithStack(
+			UnknownBlockCompressionVer{ver})
+	}
+
+	if len(compressed) >= len(encodedBlock) {
+		return encodedBlock, BlockCompressionNone, nil
+	}
+	return compressed, ver, nil
+}
+
+// DecompressBlock reverses CompressBlock, returning the encoded
+// block.
+func DecompressBlock(compressedBlock []byte, ver BlockCompressionVer) (
+	[]byte, error) {
+	switch ver {
+	case BlockCompressionNone:
+		return compressedBlock, nil
+	case BlockCompressionSnappy:
+		n, err := snappy.DecodedLen(compressedBlock)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if n > maxDecompressedBlockSize {
+			return nil, errors.WithStack(DecompressedBlockTooBigError{n})
+		}
+		encodedBlock, err := snappy.Decode(nil, compressedBlock)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return encodedBlock, nil
+	default:
+		return nil, errors.WithStack(UnknownBlockCompressionVer{ver})
+	}
+}
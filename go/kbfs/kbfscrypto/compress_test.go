@@ -0,0 +1,61 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package kbfscrypto
+
+import (
+	"bytes"
+	"testing"
+	"testing/quick"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests compressing -> decompressing results in the same block data,
+// whichever version ends up being used.
+func TestBlockCompressionRoundTrip(t *testing.T) {
+	f := func(b []byte) bool {
+		compressed, ver, err := CompressBlock(b, BlockCompressionSnappy)
+		if err != nil {
+			t.Logf("CompressBlock err: %s", err)
+			return false
+		}
+		if ver != BlockCompressionNone && len(compressed) >= len(b) {
+			t.Logf("Compressed block len %d >= input block len %d",
+				len(compressed), len(b))
+			return false
+		}
+		decompressed, err := DecompressBlock(compressed, ver)
+		if err != nil {
+			t.Logf("DecompressBlock err: %s", err)
+			return false
+		}
+		return bytes.Equal(b, decompressed)
+	}
+
+	err := quick.Check(f, nil)
+	require.NoError(t, err)
+}
+
+func TestBlockCompressionShrinks(t *testing.T) {
+	b := bytes.Repeat([]byte("compressible "), 1000)
+	compressed, ver, err := CompressBlock(b, BlockCompressionSnappy)
+	require.NoError(t, err)
+	require.Equal(t, BlockCompressionSnappy, ver)
+	require.True(t, len(compressed) < len(b)/10)
+
+	compressed, ver, err = CompressBlock(b, BlockCompressionNone)
+	require.NoError(t, err)
+	require.Equal(t, BlockCompressionNone, ver)
+	require.Equal(t, b, compressed)
+}
+
+func TestBlockCompressionUnknownVer(t *testing.T) {
+	_, _, err := CompressBlock([]byte{1}, BlockCompressionVer(100))
+	require.Equal(t, UnknownBlockCompressionVer{100}, errors.Cause(err))
+
+	_, err = DecompressBlock([]byte{1}, BlockCompressionVer(100))
+	require.Equal(t, UnknownBlockCompressionVer{100}, errors.Cause(err))
+}
@@ -222,6 +222,11 @@ func DecryptPrivateMetadata(
 // EncryptedBlock is an encrypted Block object.
 type EncryptedBlock struct {
 	encryptedData
+	// Compression is the method that was used to compress the
+	// encoded block before it was padded and encrypted.  It's
+	// omitted for uncompressed blocks, so that they encode the same
+	// way they did before compression existed.
+	Compression BlockCompressionVer `codec:"c,omitempty"`
 }
 
 // EncryptPaddedEncodedBlock encrypts a padded, encoded block.
@@ -249,7 +254,7 @@ func EncryptPaddedEncodedBlock(
 		return EncryptedBlock{}, errors.WithStack(UnknownEncryptionVer{ver})
 	}
 
-	return EncryptedBlock{ed}, nil
+	return EncryptedBlock{encryptedData: ed}, nil
 }
 
 // DecryptBlock decrypts a block, but does not unpad or decode it.
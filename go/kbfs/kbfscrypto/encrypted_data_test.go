@@ -62,7 +62,7 @@ func TestDecryptDataFailure(t *testing.T) {
 	half := [32]byte{0x50, 0x51}
 	blockServerHalf := BlockCryptKeyServerHalf{publicByte32Container{half}}
 	_, err = DecryptBlock(
-		EncryptedBlock{encryptedDataWrongNonce},
+		EncryptedBlock{encryptedData: encryptedDataWrongNonce},
 		tlfCryptKey, blockServerHalf)
 	assert.Equal(t,
 		InvalidNonceError{encryptedDataWrongNonce.Nonce},
@@ -83,3 +83,23 @@ func (e PaddedBlockReadError) Error() string {
 	return fmt.Sprintf("Reading block data out of padded block resulted in %d bytes, expected %d",
 		e.ActualLen, e.ExpectedLen)
 }
+
+// UnknownBlockCompressionVer indicates that we can't decompress a
+// block because it has an unknown compression version.
+type UnknownBlockCompressionVer struct {
+	Ver BlockCompressionVer
+}
+
+func (e UnknownBlockCompressionVer) Error() string {
+	return fmt.Sprintf("Unknown block compression version %d", int(e.Ver))
+}
+
+// DecompressedBlockTooBigError indicates that a compressed block
+// claims to be bigger than any valid block once decompressed.
+type DecompressedBlockTooBigError struct {
+	Size int
+}
+
+func (e DecompressedBlockTooBigError) Error() string {
+	return fmt.Sprintf("Decompressed block size %d is too big", e.Size)
+}
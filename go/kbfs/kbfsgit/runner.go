@@ -139,6 +139,16 @@ type runner struct {
 	progress  bool
 	cloning   bool
 
+	// Options for shallow and partial fetches; see shallow.go.
+	depth           int
+	deepenSince     time.Time
+	deepenNot       []string
+	deepenRelative  bool
+	filter          string
+	filterBlobLimit int64
+	fromPromisor    bool
+	noDependents    bool
+
 	logSync     sync.Once
 	logSyncDone sync.Once
 
@@ -1877,7 +1887,9 @@ func (r *runner) handleOption(ctx context.Context, args []string) (err error) {
 		}
 	}()
 
-	if len(args) != 2 {
+	// The deepen-since value is the date exactly as the user typed
+	// it, which might contain spaces.
+	if len(args) < 2 || (len(args) > 2 && args[0] != gitOptionDeepenSince) {
 		return errors.Errorf("Bad option request: %v", args)
 	}
 
@@ -1927,6 +1939,14 @@ func (r *runner) handleOption(ctx context.Context, args []string) (err error) {
 				result = "ok"
 			}
 		}
+	case gitOptionDepth, gitOptionDeepenSince, gitOptionDeepenNot,
+		gitOptionDeepenRelative, gitOptionFilter, gitOptionFromPromisor,
+		gitOptionNoDependents:
+		result, err = r.handleShallowOption(
+			ctx, option, strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
 	default:
 		result = "unsupported"
 	}
@@ -2133,7 +2153,13 @@ func (r *runner) processCommand(
 			if len(cmdParts) == 0 {
 				switch {
 				case len(fetchBatch) > 0:
-					if r.cloning {
+					if r.isShallowOrPartialFetch() {
+						r.log.CDebugf(ctx, "Processing shallow fetch batch")
+						err = r.handleShallowFetchBatch(ctx, fetchBatch)
+						if err != nil {
+							return err
+						}
+					} else if r.cloning {
 						r.log.CDebugf(ctx, "Processing clone")
 						err = r.handleClone(ctx)
 						if err != nil {
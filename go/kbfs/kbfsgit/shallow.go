@@ -0,0 +1,633 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package kbfsgit
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/packfile"
+	gogitobj "gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+)
+
+const (
+	gitOptionDepth          = "depth"
+	gitOptionDeepenSince    = "deepen-since"
+	gitOptionDeepenNot      = "deepen-not"
+	gitOptionDeepenRelative = "deepen-relative"
+	gitOptionFilter         = "filter"
+	gitOptionFromPromisor   = "from-promisor"
+	gitOptionNoDependents   = "no-dependents"
+
+	gitFilterBlobNone  = "blob:none"
+	gitFilterBlobLimit = "blob:limit="
+)
+
+var deepenSinceAgoRegexp = regexp.MustCompile(
+	`^(\d+)\s+(second|minute|hour|day|week|month|year)s?\s+ago$`)
+
+// parseDeepenSince parses the value git passes along for
+// `--shallow-since`, which is whatever the user typed.  We accept
+// unix timestamps, RFC 3339 and plain dates, and relative times like
+// "2 weeks ago", which covers the common uses of git's much more
+// lenient date parser.
+func parseDeepenSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseInt(strings.TrimPrefix(s, "@"), 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	for _, layout := range []string{
+		time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05",
+		"2006-01-02",
+	} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if m := deepenSinceAgoRegexp.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch m[2] {
+		case "second":
+			return now.Add(-time.Duration(n) * time.Second), nil
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		case "year":
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+	return time.Time{}, errors.Errorf("Unrecognized date: %s", s)
+}
+
+// parseFilter parses a partial clone filter spec.  Only blob filters
+// are supported; `ok` is false for anything else.  The returned limit
+// is the size at which blobs start being omitted, so 0 omits all of
+// them.
+func parseFilter(spec string) (limit int64, ok bool, err error) {
+	if spec == gitFilterBlobNone {
+		return 0, true, nil
+	}
+	if !strings.HasPrefix(spec, gitFilterBlobLimit) {
+		return 0, false, nil
+	}
+	s := strings.TrimPrefix(spec, gitFilterBlobLimit)
+	multiplier := int64(1)
+	if len(s) > 0 {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			multiplier = 1 << 10
+		case 'm', 'M':
+			multiplier = 1 << 20
+		case 'g', 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false, errors.Errorf("Bad filter: %s", spec)
+	}
+	return n * multiplier, true, nil
+}
+
+func isHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// handleShallowOption sets one of the options that turn a fetch
+// into a shallow or partial one.  It returns the result line for the
+// option command.
+func (r *runner) handleShallowOption(
+	ctx context.Context, option, value string) (string, error) {
+	switch option {
+	case gitOptionDepth:
+		d, err := strconv.Atoi(value)
+		if err != nil {
+			return "", err
+		}
+		if d < 0 {
+			return "", errors.Errorf("Bad depth: %d", d)
+		}
+		r.depth = d
+		r.log.CDebugf(ctx, "Setting depth to %d", d)
+	case gitOptionDeepenSince:
+		since, err := parseDeepenSince(value, r.config.Clock().Now())
+		if err != nil {
+			return "", err
+		}
+		r.deepenSince = since
+		r.log.CDebugf(ctx, "Setting deepen-since to %s", since)
+	case gitOptionDeepenNot:
+		r.deepenNot = append(r.deepenNot, value)
+		r.log.CDebugf(ctx, "Adding deepen-not %s", value)
+	case gitOptionDeepenRelative:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", err
+		}
+		r.deepenRelative = b
+		r.log.CDebugf(ctx, "Setting deepen-relative to %t", b)
+	case gitOptionFilter:
+		limit, ok, err := parseFilter(value)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			r.log.CDebugf(ctx, "Filter %s is unsupported", value)
+			return "unsupported", nil
+		}
+		r.filter = value
+		r.filterBlobLimit = limit
+		r.log.CDebugf(ctx, "Setting filter to %s", value)
+	case gitOptionFromPromisor:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", err
+		}
+		r.fromPromisor = b
+		r.log.CDebugf(ctx, "Setting from-promisor to %t", b)
+	case gitOptionNoDependents:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", err
+		}
+		r.noDependents = b
+		r.log.CDebugf(ctx, "Setting no-dependents to %t", b)
+	default:
+		return "unsupported", nil
+	}
+	return "ok", nil
+}
+
+// isShallowOrPartialFetch returns true if any of the options set
+// require computing exactly which objects to send, rather than
+// copying or pushing everything reachable from the fetched refs.
+func (r *runner) isShallowOrPartialFetch() bool {
+	return r.depth > 0 || !r.deepenSince.IsZero() || len(r.deepenNot) > 0 ||
+		r.filter != "" || r.fromPromisor || r.noDependents
+}
+
+// resolveDeepenNot finds the commit a `--shallow-exclude` ref points
+// to, using the same lookup rules as `git rev-parse`.
+func resolveDeepenNot(repo *gogit.Repository, name string) (
+	plumbing.Hash, error) {
+	for _, refName := range []string{
+		name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name,
+		"refs/remotes/" + name,
+	} {
+		ref, err := repo.Reference(plumbing.ReferenceName(refName), true)
+		if err == plumbing.ErrReferenceNotFound {
+			continue
+		} else if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return peelToCommit(repo, ref.Hash())
+	}
+	if isHash(name) {
+		return peelToCommit(repo, plumbing.NewHash(name))
+	}
+	return plumbing.ZeroHash, errors.Errorf(
+		"Couldn't find deepen-not ref %s", name)
+}
+
+func peelToCommit(repo *gogit.Repository, h plumbing.Hash) (
+	plumbing.Hash, error) {
+	for {
+		tag, err := repo.TagObject(h)
+		if err == plumbing.ErrObjectNotFound {
+			return h, nil
+		} else if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		h = tag.Target
+	}
+}
+
+// shallowFetch computes the set of objects needed for a shallow or
+// partial fetch, and the resulting shallow boundary of the local
+// repo.
+type shallowFetch struct {
+	r     *runner
+	repo  *gogit.Repository
+	local *filesystem.Storage
+
+	oldShallow map[plumbing.Hash]bool
+	newShallow map[plumbing.Hash]bool
+	excluded   map[plumbing.Hash]bool
+	walkLocal  bool
+
+	objects []plumbing.Hash
+	seen    map[plumbing.Hash]bool
+}
+
+type shallowCommit struct {
+	hash  plumbing.Hash
+	depth int
+	// bounded is false for commits that don't count toward the depth
+	// limit, which happens for commits newer than the existing
+	// shallow boundary when deepening relative to it.
+	bounded bool
+}
+
+func (sf *shallowFetch) hasLocally(h plumbing.Hash) bool {
+	return sf.local.HasEncodedObject(h) == nil
+}
+
+// stopAt returns true if the walk doesn't need to go past commit
+// `h`, because the local repo already has its entire history.  That
+// isn't known for commits that lead to a shallow commit, so when
+// re-limiting the history of a shallow repo, the walk goes through
+// all the local commits it reaches.
+func (sf *shallowFetch) stopAt(h plumbing.Hash) bool {
+	if sf.oldShallow[h] || !sf.hasLocally(h) {
+		return false
+	}
+	return !sf.walkLocal
+}
+
+func (sf *shallowFetch) add(h plumbing.Hash) bool {
+	if sf.seen[h] {
+		return false
+	}
+	sf.seen[h] = true
+	if sf.hasLocally(h) {
+		return false
+	}
+	sf.objects = append(sf.objects, h)
+	return true
+}
+
+// addExcludedCommits marks every commit reachable from the
+// deepen-not refs.  This walks their entire history, but excluding
+// refs is rare enough that it's not worth being clever about it.
+func (sf *shallowFetch) addExcludedCommits(ctx context.Context) error {
+	var queue []plumbing.Hash
+	for _, name := range sf.r.deepenNot {
+		h, err := resolveDeepenNot(sf.repo, name)
+		if err != nil {
+			return err
+		}
+		queue = append(queue, h)
+	}
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		if sf.excluded[h] {
+			continue
+		}
+		sf.excluded[h] = true
+		c, err := sf.repo.CommitObject(h)
+		if err != nil {
+			return err
+		}
+		queue = append(queue, c.ParentHashes...)
+	}
+	sf.r.log.CDebugf(ctx, "Excluding %d commits", len(sf.excluded))
+	return nil
+}
+
+func (sf *shallowFetch) isExcluded(c *gogitobj.Commit) bool {
+	if sf.excluded[c.Hash] {
+		return true
+	}
+	return !sf.r.deepenSince.IsZero() &&
+		c.Committer.When.Before(sf.r.deepenSince)
+}
+
+func (sf *shallowFetch) addTree(h plumbing.Hash) error {
+	if !sf.add(h) {
+		return nil
+	}
+	tree, err := sf.repo.TreeObject(h)
+	if err != nil {
+		return err
+	}
+	for _, entry := range tree.Entries {
+		switch entry.Mode {
+		case filemode.Submodule:
+			continue
+		case filemode.Dir:
+			err = sf.addTree(entry.Hash)
+			if err != nil {
+				return err
+			}
+		default:
+			err = sf.addBlob(entry.Hash)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sf *shallowFetch) addBlob(h plumbing.Hash) error {
+	if sf.r.filter != "" {
+		if sf.r.filterBlobLimit == 0 {
+			return nil
+		}
+		size, err := sf.repo.Storer.EncodedObjectSize(h)
+		if err != nil {
+			return err
+		}
+		if size >= sf.r.filterBlobLimit {
+			return nil
+		}
+	}
+	sf.add(h)
+	return nil
+}
+
+// addWant adds an object the helper was explicitly asked to fetch.
+// Blobs asked for directly always get sent, regardless of the
+// filter, since that's how git lazily fetches missing blobs from a
+// partial clone.
+func (sf *shallowFetch) addWant(h plumbing.Hash, queue []shallowCommit) (
+	[]shallowCommit, error) {
+	for {
+		obj, err := sf.repo.Storer.EncodedObject(plumbing.AnyObject, h)
+		if err != nil {
+			return nil, err
+		}
+		if sf.r.noDependents {
+			sf.add(h)
+			return queue, nil
+		}
+		switch obj.Type() {
+		case plumbing.TagObject:
+			sf.add(h)
+			tag, err := sf.repo.TagObject(h)
+			if err != nil {
+				return nil, err
+			}
+			h = tag.Target
+			continue
+		case plumbing.CommitObject:
+			return append(queue, shallowCommit{
+				hash:    h,
+				depth:   1,
+				bounded: !sf.r.deepenRelative,
+			}), nil
+		case plumbing.TreeObject:
+			return queue, sf.addTree(h)
+		case plumbing.BlobObject:
+			sf.add(h)
+			return queue, nil
+		default:
+			return nil, errors.Errorf("Unexpected object type %s for %s",
+				obj.Type(), h)
+		}
+	}
+}
+
+// walk does a breadth-first walk of the history of the wanted
+// commits, so each commit is first reached at its smallest depth,
+// and stops at commits the local repo already has in full.  Commits
+// at the depth limit, or whose parents are excluded, become the new
+// shallow boundary.
+func (sf *shallowFetch) walk(ctx context.Context, queue []shallowCommit) error {
+	visited := make(map[plumbing.Hash]bool)
+	if sf.r.deepenRelative {
+		for h := range sf.oldShallow {
+			queue = append(queue, shallowCommit{hash: h, bounded: true})
+		}
+	}
+	for len(queue) > 0 {
+		sc := queue[0]
+		queue = queue[1:]
+		if visited[sc.hash] {
+			continue
+		}
+		visited[sc.hash] = true
+
+		if sf.oldShallow[sc.hash] {
+			if sf.r.deepenRelative {
+				sc.depth = 0
+				sc.bounded = true
+			}
+		} else if sf.stopAt(sc.hash) {
+			continue
+		}
+
+		c, err := sf.repo.CommitObject(sc.hash)
+		if err != nil {
+			return err
+		}
+		if sf.add(c.Hash) {
+			err = sf.addTree(c.TreeHash)
+			if err != nil {
+				return err
+			}
+		}
+
+		if c.NumParents() == 0 {
+			continue
+		}
+		if sf.r.depth > 0 && sc.bounded && sc.depth >= sf.r.depth {
+			sf.newShallow[c.Hash] = true
+			continue
+		}
+		var parents []shallowCommit
+		for _, p := range c.ParentHashes {
+			if sf.stopAt(p) {
+				continue
+			}
+			pc, err := sf.repo.CommitObject(p)
+			if err != nil {
+				return err
+			}
+			if sf.isExcluded(pc) {
+				parents = nil
+				sf.newShallow[c.Hash] = true
+				break
+			}
+			parents = append(parents, shallowCommit{
+				hash:    p,
+				depth:   sc.depth + 1,
+				bounded: sc.bounded,
+			})
+		}
+		if !sf.newShallow[c.Hash] {
+			// Deepened past an old shallow commit.
+			delete(sf.oldShallow, c.Hash)
+		}
+		queue = append(queue, parents...)
+	}
+	sf.r.log.CDebugf(ctx, "Visited %d commits, found %d objects to send",
+		len(visited), len(sf.objects))
+	return nil
+}
+
+// writePack writes the objects into a new pack in the local repo,
+// and returns the pack's checksum.
+func (sf *shallowFetch) writePack(ctx context.Context) (
+	checksum plumbing.Hash, err error) {
+	var statusChan plumbing.StatusChan
+	if sf.r.verbosity >= 1 {
+		s := make(chan plumbing.StatusUpdate)
+		defer close(s)
+		statusChan = plumbing.StatusChan(s)
+		go sf.r.processGogitStatus(ctx, s, nil)
+	}
+
+	w, err := sf.local.PackfileWriter(statusChan)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer func() {
+		closeErr := w.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	e := packfile.NewEncoder(w, sf.repo.Storer, false)
+	return e.Encode(sf.objects, 0, statusChan)
+}
+
+func (sf *shallowFetch) updateShallow(ctx context.Context) error {
+	for h := range sf.oldShallow {
+		sf.newShallow[h] = true
+	}
+	oldShallow, err := sf.local.Shallow()
+	if err != nil {
+		return err
+	}
+	if len(oldShallow) == len(sf.newShallow) {
+		changed := false
+		for _, h := range oldShallow {
+			if !sf.newShallow[h] {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			return nil
+		}
+	}
+	shallow := make([]plumbing.Hash, 0, len(sf.newShallow))
+	for h := range sf.newShallow {
+		shallow = append(shallow, h)
+	}
+	sf.r.log.CDebugf(ctx, "Updating shallow list with %d commits", len(shallow))
+	return sf.local.SetShallow(shallow)
+}
+
+// handleShallowFetchBatch fetches only part of the history or objects
+// of the requested refs, as limited by the depth, deepen-* and
+// filter options.  Rather than copying the whole KBFS repo, it walks
+// the history itself and writes exactly the needed objects into a
+// new pack in the local repo, and records the commits whose parents
+// were left out in the local `shallow` file.  As with a regular
+// fetch, the invoking `git` process updates the refs afterward.
+func (r *runner) handleShallowFetchBatch(ctx context.Context, args [][]string) (
+	err error) {
+	repo, _, err := r.initRepoIfNeeded(ctx, gitCmdFetch)
+	if err != nil {
+		return err
+	}
+
+	r.log.CDebugf(ctx, "Shallow fetching %d refs into %s (depth=%d, "+
+		"relative=%t, since=%s, not=%v, filter=%q)", len(args), r.gitDir,
+		r.depth, r.deepenRelative, r.deepenSince, r.deepenNot, r.filter)
+
+	local, err := filesystem.NewStorage(osfs.New(r.gitDir))
+	if err != nil {
+		return err
+	}
+	oldShallow, err := local.Shallow()
+	if err != nil {
+		return err
+	}
+	sf := &shallowFetch{
+		r:          r,
+		repo:       repo,
+		local:      local,
+		oldShallow: make(map[plumbing.Hash]bool, len(oldShallow)),
+		newShallow: make(map[plumbing.Hash]bool),
+		excluded:   make(map[plumbing.Hash]bool),
+		seen:       make(map[plumbing.Hash]bool),
+	}
+	for _, h := range oldShallow {
+		sf.oldShallow[h] = true
+	}
+	// Relative deepening starts from the old shallow commits
+	// directly, so only an absolute limit needs to walk through the
+	// local history.
+	sf.walkLocal = len(oldShallow) > 0 && !r.deepenRelative &&
+		(r.depth > 0 || !r.deepenSince.IsZero() || len(r.deepenNot) > 0)
+	err = sf.addExcludedCommits(ctx)
+	if err != nil {
+		return err
+	}
+
+	var queue []shallowCommit
+	for _, fetch := range args {
+		if len(fetch) != 2 || !isHash(fetch[0]) {
+			return errors.Errorf("Bad fetch request: %v", fetch)
+		}
+		queue, err = sf.addWant(plumbing.NewHash(fetch[0]), queue)
+		if err != nil {
+			return err
+		}
+	}
+	err = sf.walk(ctx, queue)
+	if err != nil {
+		return err
+	}
+
+	if len(sf.objects) > 0 {
+		checksum, err := sf.writePack(ctx)
+		if err != nil {
+			return err
+		}
+		if r.filter != "" || r.fromPromisor {
+			// Mark the pack as coming from a promisor remote, so git
+			// knows it can lazily fetch whatever the pack leaves out.
+			promisorPath := filepath.Join(r.gitDir, "objects", "pack",
+				fmt.Sprintf("pack-%s.promisor", checksum))
+			err = os.WriteFile(promisorPath, nil, 0664)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = sf.updateShallow(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.output.Write([]byte("\n"))
+	return err
+}
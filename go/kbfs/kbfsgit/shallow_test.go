@@ -0,0 +1,283 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package kbfsgit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/kbfs/libgit"
+	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
+	"github.com/adamwalz/keybase-client/go/kbfs/tlfhandle"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+)
+
+func TestParseDeepenSince(t *testing.T) {
+	now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+	for s, expected := range map[string]time.Time{
+		"1577836800":           time.Unix(1577836800, 0),
+		"@1577836800":          time.Unix(1577836800, 0),
+		"2020-01-01T00:00:00Z": time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"2020-01-01": time.Date(
+			2020, 1, 1, 0, 0, 0, 0, time.Local),
+		"2020-01-01 10:30:00": time.Date(
+			2020, 1, 1, 10, 30, 0, 0, time.Local),
+		"3 hours ago": now.Add(-3 * time.Hour),
+		"1 day ago":   now.AddDate(0, 0, -1),
+		"2 weeks ago": now.AddDate(0, 0, -14),
+		"1 year ago":  now.AddDate(-1, 0, 0),
+	} {
+		since, err := parseDeepenSince(s, now)
+		require.NoError(t, err, s)
+		require.True(t, expected.Equal(since), s)
+	}
+
+	_, err := parseDeepenSince("last tuesday", now)
+	require.Error(t, err)
+}
+
+func TestParseFilter(t *testing.T) {
+	for spec, expected := range map[string]int64{
+		"blob:none":      0,
+		"blob:limit=0":   0,
+		"blob:limit=100": 100,
+		"blob:limit=2k":  2 << 10,
+		"blob:limit=1m":  1 << 20,
+		"blob:limit=1g":  1 << 30,
+	} {
+		limit, ok, err := parseFilter(spec)
+		require.NoError(t, err, spec)
+		require.True(t, ok, spec)
+		require.Equal(t, expected, limit, spec)
+	}
+
+	_, ok, err := parseFilter("tree:0")
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, _, err = parseFilter("blob:limit=lots")
+	require.Error(t, err)
+}
+
+func gitRevParse(t *testing.T, gitDir, rev string) plumbing.Hash {
+	output, err := exec.Command(
+		"git", "--git-dir", gitDir, "rev-parse", rev).Output()
+	require.NoError(t, err)
+	return plumbing.NewHash(strings.TrimSpace(string(output)))
+}
+
+func testRunnerFetchWithOptions(
+	ctx context.Context, t *testing.T, config libkbfs.Config,
+	dotgit, head string, options ...string) string {
+	inputReader, inputWriter := io.Pipe()
+	defer inputWriter.Close()
+	go func() {
+		for _, option := range options {
+			_, _ = inputWriter.Write([]byte(
+				fmt.Sprintf("option %s\n", option)))
+		}
+		_, _ = inputWriter.Write([]byte(fmt.Sprintf(
+			"fetch %s %s\n\n\n", head, head)))
+	}()
+
+	var output bytes.Buffer
+	r, err := newRunner(ctx, config, "origin", "keybase://private/user1/test",
+		dotgit, inputReader, &output, testErrput{t})
+	require.NoError(t, err)
+	err = r.processCommands(ctx)
+	require.NoError(t, err)
+	return output.String()
+}
+
+func TestRunnerShallowFetch(t *testing.T) {
+	ctx, config, tempdir := initConfigForRunner(t)
+	defer os.RemoveAll(tempdir)
+	defer libkbfs.CheckConfigAndShutdown(ctx, t, config)
+
+	git1, err := os.MkdirTemp(os.TempDir(), "kbfsgittest")
+	require.NoError(t, err)
+	defer os.RemoveAll(git1)
+	dotgit1 := filepath.Join(git1, ".git")
+
+	makeLocalRepoWithOneFile(t, git1, "foo", "hello", "")
+	addOneFileToRepo(t, git1, "foo2", "hello2")
+	addOneFileToRepo(t, git1, "foo3", "hello3")
+	commits := []plumbing.Hash{
+		gitRevParse(t, dotgit1, "HEAD"),
+		gitRevParse(t, dotgit1, "HEAD~1"),
+		gitRevParse(t, dotgit1, "HEAD~2"),
+	}
+
+	h, err := tlfhandle.ParseHandle(
+		ctx, config.KBPKI(), config.MDOps(), config, "user1", tlf.Private)
+	require.NoError(t, err)
+	_, err = libgit.CreateRepoAndID(ctx, config, h, "test")
+	require.NoError(t, err)
+	testPush(ctx, t, config, git1, "refs/heads/master:refs/heads/master")
+
+	git2, err := os.MkdirTemp(os.TempDir(), "kbfsgittest")
+	require.NoError(t, err)
+	defer os.RemoveAll(git2)
+	dotgit2 := filepath.Join(git2, ".git")
+	gitExec(t, dotgit2, git2, "init")
+	checkLocal := func(numCommits int, shallow ...plumbing.Hash) {
+		t.Helper()
+		// Open the repo fresh each time, since the storage caches the
+		// list of packs.
+		local, err := filesystem.NewStorage(osfs.New(dotgit2))
+		require.NoError(t, err)
+		for i, c := range commits {
+			err := local.HasEncodedObject(c)
+			if i < numCommits {
+				require.NoError(t, err)
+			} else {
+				require.Equal(t, plumbing.ErrObjectNotFound, err)
+			}
+		}
+		localShallow, err := local.Shallow()
+		require.NoError(t, err)
+		require.ElementsMatch(t, shallow, localShallow)
+	}
+
+	head := commits[0].String()
+	output := testRunnerFetchWithOptions(
+		ctx, t, config, dotgit2, head, "cloning true", "depth 1")
+	require.Equal(t, "ok\nok\n\n", output)
+	checkLocal(1, commits[0])
+	gitExec(t, dotgit2, git2, "checkout", head)
+	data, err := os.ReadFile(filepath.Join(git2, "foo3"))
+	require.NoError(t, err)
+	require.Equal(t, "hello3", string(data))
+	t.Log("A depth-1 clone only gets the head commit")
+
+	output = testRunnerFetchWithOptions(
+		ctx, t, config, dotgit2, head, "depth 1", "deepen-relative true")
+	require.Equal(t, "ok\nok\n\n", output)
+	checkLocal(2, commits[1])
+	t.Log("Deepening fetches one more commit")
+
+	output = testRunnerFetchWithOptions(
+		ctx, t, config, dotgit2, head, "depth 2147483647")
+	require.Equal(t, "ok\n\n", output)
+	checkLocal(3)
+	gitExec(t, dotgit2, git2, "fsck", "--connectivity-only")
+	t.Log("Unshallowing fetches the rest of the history")
+}
+
+func TestRunnerShallowFetchExclude(t *testing.T) {
+	ctx, config, tempdir := initConfigForRunner(t)
+	defer os.RemoveAll(tempdir)
+	defer libkbfs.CheckConfigAndShutdown(ctx, t, config)
+
+	git1, err := os.MkdirTemp(os.TempDir(), "kbfsgittest")
+	require.NoError(t, err)
+	defer os.RemoveAll(git1)
+	dotgit1 := filepath.Join(git1, ".git")
+
+	makeLocalRepoWithOneFile(t, git1, "foo", "hello", "")
+	gitExec(t, dotgit1, git1, "tag", "v1")
+	addOneFileToRepo(t, git1, "foo2", "hello2")
+	addOneFileToRepo(t, git1, "foo3", "hello3")
+	head := gitRevParse(t, dotgit1, "HEAD")
+	middle := gitRevParse(t, dotgit1, "HEAD~1")
+
+	h, err := tlfhandle.ParseHandle(
+		ctx, config.KBPKI(), config.MDOps(), config, "user1", tlf.Private)
+	require.NoError(t, err)
+	_, err = libgit.CreateRepoAndID(ctx, config, h, "test")
+	require.NoError(t, err)
+	testPushWithTemplate(ctx, t, config, git1,
+		[]string{
+			"refs/heads/master:refs/heads/master",
+			"refs/tags/v1:refs/tags/v1",
+		}, "ok %s\nok %s\n\n", "user1")
+
+	git2, err := os.MkdirTemp(os.TempDir(), "kbfsgittest")
+	require.NoError(t, err)
+	defer os.RemoveAll(git2)
+	dotgit2 := filepath.Join(git2, ".git")
+	gitExec(t, dotgit2, git2, "init")
+
+	output := testRunnerFetchWithOptions(
+		ctx, t, config, dotgit2, head.String(), "deepen-not v1")
+	require.Equal(t, "ok\n\n", output)
+	local, err := filesystem.NewStorage(osfs.New(dotgit2))
+	require.NoError(t, err)
+	require.NoError(t, local.HasEncodedObject(head))
+	require.NoError(t, local.HasEncodedObject(middle))
+	require.Equal(t, plumbing.ErrObjectNotFound,
+		local.HasEncodedObject(gitRevParse(t, dotgit1, "v1")))
+	shallow, err := local.Shallow()
+	require.NoError(t, err)
+	require.Equal(t, []plumbing.Hash{middle}, shallow)
+}
+
+func TestRunnerPartialFetch(t *testing.T) {
+	ctx, config, tempdir := initConfigForRunner(t)
+	defer os.RemoveAll(tempdir)
+	defer libkbfs.CheckConfigAndShutdown(ctx, t, config)
+
+	git1, err := os.MkdirTemp(os.TempDir(), "kbfsgittest")
+	require.NoError(t, err)
+	defer os.RemoveAll(git1)
+	dotgit1 := filepath.Join(git1, ".git")
+
+	makeLocalRepoWithOneFile(t, git1, "small", "hello", "")
+	addOneFileToRepo(t, git1, "big", strings.Repeat("big", 1000))
+	head := gitRevParse(t, dotgit1, "HEAD")
+	small := gitRevParse(t, dotgit1, "HEAD:small")
+	big := gitRevParse(t, dotgit1, "HEAD:big")
+
+	h, err := tlfhandle.ParseHandle(
+		ctx, config.KBPKI(), config.MDOps(), config, "user1", tlf.Private)
+	require.NoError(t, err)
+	_, err = libgit.CreateRepoAndID(ctx, config, h, "test")
+	require.NoError(t, err)
+	testPush(ctx, t, config, git1, "refs/heads/master:refs/heads/master")
+
+	git2, err := os.MkdirTemp(os.TempDir(), "kbfsgittest")
+	require.NoError(t, err)
+	defer os.RemoveAll(git2)
+	dotgit2 := filepath.Join(git2, ".git")
+	gitExec(t, dotgit2, git2, "init")
+
+	output := testRunnerFetchWithOptions(ctx, t, config, dotgit2,
+		head.String(), "cloning true", "filter blob:limit=1k")
+	require.Equal(t, "ok\nok\n\n", output)
+	local, err := filesystem.NewStorage(osfs.New(dotgit2))
+	require.NoError(t, err)
+	require.NoError(t, local.HasEncodedObject(head))
+	require.NoError(t, local.HasEncodedObject(gitRevParse(t, dotgit1, "HEAD~1")))
+	require.NoError(t, local.HasEncodedObject(small))
+	require.Equal(t, plumbing.ErrObjectNotFound, local.HasEncodedObject(big))
+	promisors, err := filepath.Glob(
+		filepath.Join(dotgit2, "objects", "pack", "pack-*.promisor"))
+	require.NoError(t, err)
+	require.Len(t, promisors, 1)
+	t.Log("Blobs over the limit are left out of a partial clone")
+
+	output = testRunnerFetchWithOptions(ctx, t, config, dotgit2,
+		big.String(), "from-promisor true", "filter blob:limit=1k")
+	require.Equal(t, "ok\nok\n\n", output)
+	local, err = filesystem.NewStorage(osfs.New(dotgit2))
+	require.NoError(t, err)
+	require.NoError(t, local.HasEncodedObject(big))
+	promisors, err = filepath.Glob(
+		filepath.Join(dotgit2, "objects", "pack", "pack-*.promisor"))
+	require.NoError(t, err)
+	require.Len(t, promisors, 2)
+	t.Log("Missing blobs can be fetched lazily")
+}
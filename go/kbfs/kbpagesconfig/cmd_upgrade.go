@@ -65,7 +65,7 @@ func upgradeToSHA256WithPrompter(kbpConfigDir string, prompter prompter) (err er
 		return fmt.Errorf(
 			"reading config file %s error: %v", kbpConfigPath, err)
 	}
-	if cfg.Version() != config.Version1 {
+	if v := cfg.Version(); v != config.Version1 && v != config.Version2 {
 		return fmt.Errorf(
 			"unsupported config version %s", cfg.Version())
 	}
@@ -95,6 +95,8 @@ func upgradeToSHA256WithPrompter(kbpConfigDir string, prompter prompter) (err er
 	}
 
 	newConfig := config.DefaultV1()
+	// Keep the version so per-path rules in a v2 config stay valid.
+	newConfig.Common = oldConfig.Common
 	for p, acl := range oldConfig.ACLs {
 		if newConfig.ACLs == nil {
 			newConfig.ACLs = make(map[string]config.PerPathConfigV1)
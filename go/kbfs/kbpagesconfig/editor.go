@@ -31,7 +31,7 @@ func newKBPConfigEditorWithPrompter(kbpConfigDir string, p prompter) (
 				"reading config file %s error: %v", kbpConfigPath, err)
 		}
 		switch cfg.Version() {
-		case config.Version1:
+		case config.Version1, config.Version2:
 			editor.kbpConfig = cfg.(*config.V1)
 			needsUpgrade, err := editor.kbpConfig.HasBcryptPasswords()
 			if err != nil {
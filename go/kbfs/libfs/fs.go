@@ -992,6 +992,67 @@ func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) (
 	return fs.config.KBFSOps().SetMtime(fs.ctx, n, &mtime)
 }
 
+// Xattrs returns the user extended attributes of the file or
+// directory at `name`.
+func (fs *FS) Xattrs(name string) (xattrs data.UserXattrs, err error) {
+	fs.log.CDebugf(fs.ctx, "Xattrs %s", fs.PathForLogging(name))
+	defer func() {
+		fs.deferLog.CDebugf(fs.ctx, "Xattrs done: %+v", err)
+		err = translateErr(err)
+	}()
+
+	fi, err := fs.Lstat(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi, ok := fi.(*FileInfo); ok {
+		return fi.ei.Xattrs, nil
+	}
+	return nil, nil
+}
+
+// SetXattr sets the user extended attribute `attr` on the file or
+// directory at `name`.
+func (fs *FS) SetXattr(name, attr string, value []byte) (err error) {
+	fs.log.CDebugf(fs.ctx, "SetXattr %s %s", fs.PathForLogging(name), attr)
+	defer func() {
+		fs.deferLog.CDebugf(fs.ctx, "SetXattr done: %+v", err)
+		err = translateErr(err)
+	}()
+
+	if err := fs.chooseErrorIfEmpty(onFsEmptyErrNotSupported); err != nil {
+		return err
+	}
+
+	n, _, err := fs.lookupOrCreateEntry(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	return fs.config.KBFSOps().SetXattr(fs.ctx, n, attr, value)
+}
+
+// RemoveXattr removes the user extended attribute `attr` from the
+// file or directory at `name`.
+func (fs *FS) RemoveXattr(name, attr string) (err error) {
+	fs.log.CDebugf(fs.ctx, "RemoveXattr %s %s", fs.PathForLogging(name), attr)
+	defer func() {
+		fs.deferLog.CDebugf(fs.ctx, "RemoveXattr done: %+v", err)
+		err = translateErr(err)
+	}()
+
+	if err := fs.chooseErrorIfEmpty(onFsEmptyErrNotSupported); err != nil {
+		return err
+	}
+
+	n, _, err := fs.lookupOrCreateEntry(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	return fs.config.KBFSOps().RemoveXattr(fs.ctx, n, attr)
+}
+
 // ChrootAsLibFS returns a *FS whose root is p.
 func (fs *FS) ChrootAsLibFS(p string) (newFS *FS, err error) {
 	fs.log.CDebugf(fs.ctx, "Chroot %s", fs.PathForLogging(p))
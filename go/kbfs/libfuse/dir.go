@@ -462,6 +462,7 @@ type DirInterface interface {
 	fs.NodeSetattrer
 	fs.NodeFsyncer
 	fs.NodeGetxattrer
+	fs.NodeListxattrer
 	fs.NodeSetxattrer
 }
 
@@ -480,11 +481,11 @@ func newDirWithInode(folder *Folder, node libkbfs.Node, inode uint64) *Dir {
 		node:   node,
 		inode:  inode,
 	}
+	var fallback XattrHandler = NoXattrHandler{}
 	if folder.quarantine {
-		d.XattrHandler = NewQuarantineXattrHandler(node, folder)
-	} else {
-		d.XattrHandler = NoXattrHandler{}
+		fallback = NewQuarantineXattrHandler(node, folder)
 	}
+	d.XattrHandler = NewUserXattrHandler(node, folder, fallback)
 	return d
 }
 
@@ -547,11 +548,11 @@ func (d *Dir) makeFile(node libkbfs.Node) (file *File) {
 		node:   node,
 		inode:  d.folder.fs.assignInode(),
 	}
+	var fallback XattrHandler = NoXattrHandler{}
 	if d.folder.quarantine {
-		file.XattrHandler = NewQuarantineXattrHandler(node, d.folder)
-	} else {
-		file.XattrHandler = NoXattrHandler{}
+		fallback = NewQuarantineXattrHandler(node, d.folder)
 	}
+	file.XattrHandler = NewUserXattrHandler(node, d.folder, fallback)
 	return file
 }
 
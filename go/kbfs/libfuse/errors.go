@@ -51,6 +51,14 @@ func filterError(err error) error {
 		return errorWithErrno{err, syscall.ENOENT}
 	case libkbfs.DirNotEmptyError:
 		return errorWithErrno{err, syscall.ENOTEMPTY}
+	case libkbfs.NoSuchXattrError:
+		return errorWithErrno{err, syscall.Errno(fuse.ErrNoXattr)}
+	case libkbfs.UserXattrsDisabledError:
+		return errorWithErrno{err, syscall.ENOTSUP}
+	case data.InvalidUserXattrNameError:
+		return errorWithErrno{err, syscall.ERANGE}
+	case data.UserXattrsTooBigError:
+		return errorWithErrno{err, syscall.E2BIG}
 	case tlfhandle.ReadAccessError:
 		return errorWithErrno{err, syscall.EACCES}
 	case tlfhandle.WriteAccessError:
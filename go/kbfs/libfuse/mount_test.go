@@ -2133,6 +2133,57 @@ func TestSetattrDirMtimeNow(t *testing.T) {
 	}
 }
 
+func TestUserXattrs(t *testing.T) {
+	ctx := libcontext.BackgroundContextWithCancellationDelayer()
+	defer testCleanupDelayer(ctx, t)
+	config := libkbfs.MakeTestConfigOrBust(t, "jdoe")
+	config.SetUserXattrsEnabled(true)
+	defer libkbfs.CheckConfigAndShutdown(ctx, t, config)
+	mnt, _, cancelFn := makeFS(ctx, t, config)
+	defer mnt.Close()
+	defer cancelFn()
+
+	p := path.Join(mnt.Dir, PrivateName, "jdoe", "myfile")
+	if err := ioutil.WriteFile(p, []byte("hello, world\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	syncFilename(t, p)
+
+	err := unix.Setxattr(p, "user.tag", []byte("blue"), 0)
+	require.NoError(t, err)
+	err = unix.Setxattr(p, "user.tag", []byte("red"), unix.XATTR_CREATE)
+	require.Equal(t, syscall.EEXIST, err)
+	err = unix.Setxattr(p, "user.other", []byte("x"), unix.XATTR_REPLACE)
+	require.Equal(t, syscall.Errno(fuse.ErrNoXattr), err)
+
+	buf := make([]byte, 100)
+	n, err := unix.Getxattr(p, "user.tag", buf)
+	require.NoError(t, err)
+	require.Equal(t, "blue", string(buf[:n]))
+	n, err = unix.Listxattr(p, buf)
+	require.NoError(t, err)
+	require.Equal(t, "user.tag\x00", string(buf[:n]))
+
+	// The attribute is part of the synced directory entry.
+	syncFilename(t, p)
+	rootNode, err := libkbfs.GetRootNodeForTest(
+		ctx, config, "jdoe", tlf.Private)
+	require.NoError(t, err)
+	fileNode, _, err := config.KBFSOps().Lookup(
+		ctx, rootNode, rootNode.ChildName("myfile"))
+	require.NoError(t, err)
+	ei, err := config.KBFSOps().Stat(ctx, fileNode)
+	require.NoError(t, err)
+	require.Equal(t, []byte("blue"), ei.Xattrs["user.tag"])
+
+	err = unix.Removexattr(p, "user.tag")
+	require.NoError(t, err)
+	_, err = unix.Getxattr(p, "user.tag", buf)
+	require.Equal(t, syscall.Errno(fuse.ErrNoXattr), err)
+	err = unix.Removexattr(p, "user.tag")
+	require.Equal(t, syscall.Errno(fuse.ErrNoXattr), err)
+}
+
 func TestFsync(t *testing.T) {
 	ctx := libcontext.BackgroundContextWithCancellationDelayer()
 	defer testCleanupDelayer(ctx, t)
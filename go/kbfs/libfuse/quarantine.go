@@ -13,10 +13,11 @@ import (
 	"golang.org/x/net/context"
 )
 
-// XattrHandler is an interface that includes fuse Get/Set/Remove calls for
-// xattr.
+// XattrHandler is an interface that includes fuse Get/List/Set/Remove
+// calls for xattr.
 type XattrHandler interface {
 	fs.NodeGetxattrer
+	fs.NodeListxattrer
 	fs.NodeSetxattrer
 	fs.NodeRemovexattrer
 }
@@ -32,6 +33,12 @@ func (h NoXattrHandler) Getxattr(context.Context,
 	return fuse.ENOTSUP
 }
 
+// Listxattr implements the fs.NodeListxattrer interface.
+func (h NoXattrHandler) Listxattr(context.Context,
+	*fuse.ListxattrRequest, *fuse.ListxattrResponse) error {
+	return fuse.ENOTSUP
+}
+
 // Setxattr implements the fs.NodeSetxattrer interface.
 func (h NoXattrHandler) Setxattr(context.Context, *fuse.SetxattrRequest) error {
 	return fuse.ENOTSUP
@@ -114,6 +114,13 @@ func (h *QuarantineXattrHandler) Getxattr(ctx context.Context,
 	}
 }
 
+// Listxattr implements the fs.NodeListxattrer interface.
+func (h *QuarantineXattrHandler) Listxattr(context.Context,
+	*fuse.ListxattrRequest, *fuse.ListxattrResponse) error {
+	// Let the OS fall back to the ._ file based method.
+	return fuse.ENOTSUP
+}
+
 // Setxattr implements the fs.NodeSetxattrer interface.
 func (h *QuarantineXattrHandler) Setxattr(ctx context.Context,
 	req *fuse.SetxattrRequest) (err error) {
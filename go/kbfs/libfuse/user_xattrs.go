@@ -0,0 +1,159 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+//
+//go:build !windows
+// +build !windows
+
+package libfuse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"bazil.org/fuse"
+	"github.com/adamwalz/keybase-client/go/kbfs/data"
+	"github.com/adamwalz/keybase-client/go/kbfs/libcontext"
+	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+// UserXattrHandler handles the user extended attributes (those
+// starting with "user.") of a node by storing them in KBFS, where
+// they are synced along with the rest of the node's metadata.  All
+// other xattrs are passed on to a fallback handler.
+type UserXattrHandler struct {
+	node     libkbfs.Node
+	folder   *Folder
+	fallback XattrHandler
+}
+
+// NewUserXattrHandler returns a handler that stores user xattrs for
+// `node` in KBFS, and passes all other xattr calls to `fallback`.
+func NewUserXattrHandler(
+	node libkbfs.Node, folder *Folder, fallback XattrHandler) XattrHandler {
+	return &UserXattrHandler{
+		node:     node,
+		folder:   folder,
+		fallback: fallback,
+	}
+}
+
+var _ XattrHandler = (*UserXattrHandler)(nil)
+
+func (h *UserXattrHandler) stat(ctx context.Context) (data.EntryInfo, error) {
+	// This fits in situation 1 as described in
+	// libkbfs/delayed_cancellation.go
+	err := libcontext.EnableDelayedCancellationWithGracePeriod(
+		ctx, h.folder.fs.config.DelayedCancellationGracePeriod())
+	if err != nil {
+		return data.EntryInfo{}, err
+	}
+	return h.folder.fs.config.KBFSOps().Stat(ctx, h.node)
+}
+
+// Getxattr implements the fs.NodeGetxattrer interface.
+func (h *UserXattrHandler) Getxattr(ctx context.Context,
+	req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) (err error) {
+	if !strings.HasPrefix(req.Name, data.UserXattrPrefix) {
+		return h.fallback.Getxattr(ctx, req, resp)
+	}
+
+	ctx = h.folder.fs.config.MaybeStartTrace(ctx, "UserXattrHandler.Getxattr",
+		fmt.Sprintf("%s %s", h.node.GetBasename(), req.Name))
+	defer func() { h.folder.fs.config.MaybeFinishTrace(ctx, err) }()
+
+	h.folder.fs.vlog.CLogf(ctx, libkb.VLog1,
+		"UserXattrHandler Getxattr %s %s", h.node.GetBasename(), req.Name)
+	defer func() { err = h.folder.processError(ctx, libkbfs.ReadMode, err) }()
+
+	ei, err := h.stat(ctx)
+	if err != nil {
+		return err
+	}
+	value, ok := ei.Xattrs[req.Name]
+	if !ok {
+		return fuse.ErrNoXattr
+	}
+	resp.Xattr = value
+	return nil
+}
+
+// Listxattr implements the fs.NodeListxattrer interface.  Only the
+// user xattrs are listed.
+func (h *UserXattrHandler) Listxattr(ctx context.Context,
+	req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) (err error) {
+	ctx = h.folder.fs.config.MaybeStartTrace(ctx, "UserXattrHandler.Listxattr",
+		h.node.GetBasename().String())
+	defer func() { h.folder.fs.config.MaybeFinishTrace(ctx, err) }()
+
+	h.folder.fs.vlog.CLogf(ctx, libkb.VLog1,
+		"UserXattrHandler Listxattr %s", h.node.GetBasename())
+	defer func() { err = h.folder.processError(ctx, libkbfs.ReadMode, err) }()
+
+	ei, err := h.stat(ctx)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(ei.Xattrs))
+	for name := range ei.Xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resp.Append(names...)
+	return nil
+}
+
+// Setxattr implements the fs.NodeSetxattrer interface.
+func (h *UserXattrHandler) Setxattr(ctx context.Context,
+	req *fuse.SetxattrRequest) (err error) {
+	if !strings.HasPrefix(req.Name, data.UserXattrPrefix) {
+		return h.fallback.Setxattr(ctx, req)
+	}
+
+	ctx = h.folder.fs.config.MaybeStartTrace(ctx, "UserXattrHandler.Setxattr",
+		fmt.Sprintf("%s %s", h.node.GetBasename(), req.Name))
+	defer func() { h.folder.fs.config.MaybeFinishTrace(ctx, err) }()
+
+	h.folder.fs.log.CDebugf(ctx,
+		"UserXattrHandler Setxattr %s %s", h.node.GetBasename(), req.Name)
+	defer func() { err = h.folder.processError(ctx, libkbfs.WriteMode, err) }()
+
+	// bazil.org/fuse leaves XATTR_CREATE and XATTR_REPLACE up to us.
+	if req.Flags&(unix.XATTR_CREATE|unix.XATTR_REPLACE) != 0 {
+		ei, err := h.stat(ctx)
+		if err != nil {
+			return err
+		}
+		_, exists := ei.Xattrs[req.Name]
+		if exists && req.Flags&unix.XATTR_CREATE != 0 {
+			return fuse.EEXIST
+		} else if !exists && req.Flags&unix.XATTR_REPLACE != 0 {
+			return fuse.ErrNoXattr
+		}
+	}
+
+	return h.folder.fs.config.KBFSOps().SetXattr(
+		ctx, h.node, req.Name, req.Xattr)
+}
+
+// Removexattr implements the fs.NodeRemovexattrer interface.
+func (h *UserXattrHandler) Removexattr(
+	ctx context.Context, req *fuse.RemovexattrRequest) (err error) {
+	if !strings.HasPrefix(req.Name, data.UserXattrPrefix) {
+		return h.fallback.Removexattr(ctx, req)
+	}
+
+	ctx = h.folder.fs.config.MaybeStartTrace(ctx, "UserXattrHandler.Removexattr",
+		fmt.Sprintf("%s %s", h.node.GetBasename(), req.Name))
+	defer func() { h.folder.fs.config.MaybeFinishTrace(ctx, err) }()
+
+	h.folder.fs.log.CDebugf(ctx,
+		"UserXattrHandler Removexattr %s %s", h.node.GetBasename(), req.Name)
+	defer func() { err = h.folder.processError(ctx, libkbfs.WriteMode, err) }()
+
+	return h.folder.fs.config.KBFSOps().RemoveXattr(ctx, h.node, req.Name)
+}
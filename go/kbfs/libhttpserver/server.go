@@ -51,6 +51,16 @@ type Server struct {
 const tokenByteSize = 32
 const tokenValidTime = 10 * time.Minute
 
+// NewToken returns a new random token that HTTP clients can present
+// to authenticate with a local KBFS server.
+func NewToken() (string, error) {
+	buf := make([]byte, tokenByteSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(buf), nil
+}
+
 // CurrentToken returns the currently valid token that a HTTP client can use to
 // load content from the server.
 func (s *Server) CurrentToken() (token string, err error) {
@@ -62,11 +72,10 @@ func (s *Server) CurrentToken() (token string, err error) {
 
 	s.tokenLock.RUnlock()
 
-	buf := make([]byte, tokenByteSize)
-	if _, err = rand.Read(buf); err != nil {
+	token, err = NewToken()
+	if err != nil {
 		return "", err
 	}
-	token = base64.URLEncoding.EncodeToString(buf)
 
 	s.tokenLock.Lock()
 	defer s.tokenLock.Unlock()
@@ -10,6 +10,7 @@ import (
 	"github.com/adamwalz/keybase-client/go/kbfs/data"
 	"github.com/adamwalz/keybase-client/go/kbfs/env"
 	"github.com/adamwalz/keybase-client/go/kbfs/kbfsblock"
+	"github.com/adamwalz/keybase-client/go/kbfs/kbfscrypto"
 	"github.com/adamwalz/keybase-client/go/kbfs/libkey"
 	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
 	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
@@ -192,12 +193,16 @@ func (b *BlockOpsStandard) Ready(ctx context.Context, kmd libkey.KeyMetadata,
 	}
 
 	readyBlockData = data.ReadyBlockData{
-		Buf:        buf,
-		ServerHalf: serverHalf,
+		Buf:         buf,
+		ServerHalf:  serverHalf,
+		Compression: encryptedBlock.Compression,
 	}
 
+	// A compressed block can legitimately be smaller than its
+	// plaintext, so only uncompressed blocks get this sanity check.
 	encodedSize := readyBlockData.GetEncodedSize()
-	if encodedSize < plainSize {
+	if encryptedBlock.Compression == kbfscrypto.BlockCompressionNone &&
+		encodedSize < plainSize {
 		err = TooLowByteCountError{
 			ExpectedMinByteCount: plainSize,
 			ByteCount:            encodedSize,
@@ -123,6 +123,10 @@ func (config testBlockOpsConfig) BlockCryptVersion() kbfscrypto.EncryptionVer {
 	return kbfscrypto.EncryptionSecretboxWithKeyNonce
 }
 
+func (config testBlockOpsConfig) BlockCompressionVersion() kbfscrypto.BlockCompressionVer {
+	return kbfscrypto.BlockCompressionNone
+}
+
 func (config testBlockOpsConfig) Clock() Clock {
 	return config.clock
 }
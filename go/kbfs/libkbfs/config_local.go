@@ -103,6 +103,7 @@ type ConfigLocal struct {
 	keyserv            libkey.KeyServer
 	service            KeybaseService
 	bsplit             data.BlockSplitter
+	tlfBsplits         map[tlf.ID]data.BlockSplitter
 	notifier           Notifier
 	clock              Clock
 	kbpki              KBPKI
@@ -111,6 +112,7 @@ type ConfigLocal struct {
 	registry           metrics.Registry
 	loggerFn           func(prefix string) logger.Logger
 	noBGFlush          bool // logic opposite so the default value is the common setting
+	userXattrs         bool
 	rwpWaitTime        time.Duration
 	diskLimiter        DiskLimiter
 	syncedTlfs         map[tlf.ID]FolderSyncConfig // if nil, couldn't load DB
@@ -156,6 +158,10 @@ type ConfigLocal struct {
 	// blockCryptVersion is the version to use when encrypting blocks.
 	blockCryptVersion kbfscrypto.EncryptionVer
 
+	// blockCompressionVersion is the version to use when compressing
+	// blocks.
+	blockCompressionVersion kbfscrypto.BlockCompressionVer
+
 	// conflictResolutionDB stores information about failed CRs
 	conflictResolutionDB *ldbutils.LevelDb
 
@@ -668,6 +674,27 @@ func (c *ConfigLocal) SetBlockSplitter(b data.BlockSplitter) {
 	c.bsplit = b
 }
 
+// BlockSplitterForTlf implements the Config interface for ConfigLocal.
+func (c *ConfigLocal) BlockSplitterForTlf(tlfID tlf.ID) data.BlockSplitter {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if bsplit, ok := c.tlfBsplits[tlfID]; ok {
+		return bsplit
+	}
+	return c.bsplit
+}
+
+// SetBlockSplitterForTlf implements the Config interface for ConfigLocal.
+func (c *ConfigLocal) SetBlockSplitterForTlf(
+	tlfID tlf.ID, b data.BlockSplitter) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.tlfBsplits == nil {
+		c.tlfBsplits = make(map[tlf.ID]data.BlockSplitter)
+	}
+	c.tlfBsplits[tlfID] = b
+}
+
 // Notifier implements the Config interface for ConfigLocal.
 func (c *ConfigLocal) Notifier() Notifier {
 	c.lock.RLock()
@@ -739,7 +766,12 @@ func (c *ConfigLocal) SetMetadataVersion(mdVer kbfsmd.MetadataVer) {
 }
 
 // DataVersion implements the Config interface for ConfigLocal.
+// Compressed blocks get their data version from data.ReadyBlock, so
+// the newer version is only reported if this device writes them.
 func (c *ConfigLocal) DataVersion() data.Ver {
+	if c.BlockCompressionVersion() != kbfscrypto.BlockCompressionNone {
+		return data.CompressedBlocksVer
+	}
 	return data.IndirectDirsVer
 }
 
@@ -757,6 +789,23 @@ func (c *ConfigLocal) SetBlockCryptVersion(ver kbfscrypto.EncryptionVer) {
 	c.blockCryptVersion = ver
 }
 
+// BlockCompressionVersion implements the Config interface for
+// ConfigLocal.
+func (c *ConfigLocal) BlockCompressionVersion() kbfscrypto.BlockCompressionVer {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.blockCompressionVersion
+}
+
+// SetBlockCompressionVersion implements the Config interface for
+// ConfigLocal.
+func (c *ConfigLocal) SetBlockCompressionVersion(
+	ver kbfscrypto.BlockCompressionVer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.blockCompressionVersion = ver
+}
+
 // DefaultBlockType implements the Config interface for ConfigLocal.
 func (c *ConfigLocal) DefaultBlockType() keybase1.BlockType {
 	c.lock.RLock()
@@ -789,6 +838,20 @@ func (c *ConfigLocal) SetDoBackgroundFlushes(doBGFlush bool) {
 	c.noBGFlush = !doBGFlush
 }
 
+// UserXattrsEnabled implements the Config interface for ConfigLocal.
+func (c *ConfigLocal) UserXattrsEnabled() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.userXattrs
+}
+
+// SetUserXattrsEnabled implements the Config interface for ConfigLocal.
+func (c *ConfigLocal) SetUserXattrsEnabled(enabled bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.userXattrs = enabled
+}
+
 // RekeyWithPromptWaitTime implements the Config interface for
 // ConfigLocal.
 func (c *ConfigLocal) RekeyWithPromptWaitTime() time.Duration {
@@ -2177,8 +2177,9 @@ func collapseActions(unmergedChains *crChains, unmergedPaths []data.Path,
 
 		fileActions := actionMap[p.TailPointer()]
 
-		// If this is a directory with setAttr(mtime)-related actions,
-		// just those action should be collapsed into the parent.
+		// If this is a directory with setAttr(mtime or xattr)-related
+		// actions, just those action should be collapsed into the
+		// parent.
 		if !chain.isFile() {
 			var parentActions crActionList
 			var otherDirActions crActionList
@@ -2186,13 +2187,16 @@ func collapseActions(unmergedChains *crChains, unmergedPaths []data.Path,
 				moved := false
 				switch realAction := action.(type) {
 				case *copyUnmergedAttrAction:
-					if realAction.attr[0] == mtimeAttr && !realAction.moved {
+					attr := realAction.attr[0]
+					if (attr == mtimeAttr || attr == xattrAttr) &&
+						!realAction.moved {
 						realAction.moved = true
 						parentActions = append(parentActions, realAction)
 						moved = true
 					}
 				case *renameUnmergedAction:
-					if realAction.causedByAttr == mtimeAttr &&
+					attr := realAction.causedByAttr
+					if (attr == mtimeAttr || attr == xattrAttr) &&
 						!realAction.moved {
 						realAction.moved = true
 						parentActions = append(parentActions, realAction)
@@ -216,6 +216,8 @@ func (cuea *copyUnmergedEntryAction) do(
 				unmergedEntry.Type = cuea.unmergedEntry.Type
 			case mtimeAttr:
 				unmergedEntry.Mtime = cuea.unmergedEntry.Mtime
+			case xattrAttr:
+				unmergedEntry.Xattrs = cuea.unmergedEntry.Xattrs
 			}
 		}
 	}
@@ -450,6 +452,8 @@ func (cuaa *copyUnmergedAttrAction) do(
 			mergedEntry.Type = unmergedEntry.Type
 		case mtimeAttr:
 			mergedEntry.Mtime = unmergedEntry.Mtime
+		case xattrAttr:
+			mergedEntry.Xattrs = unmergedEntry.Xattrs
 		case sizeAttr:
 			mergedEntry.Size = unmergedEntry.Size
 			mergedEntry.EncodedSize = unmergedEntry.EncodedSize
@@ -257,12 +257,13 @@ func (cc *crChain) identifyType(ctx context.Context, fbo *folderBlockOps,
 			cc.file = true
 			return nil
 		case *setAttrOp:
-			if realOp.Attr != mtimeAttr {
+			if realOp.Attr != mtimeAttr && realOp.Attr != xattrAttr {
 				cc.file = true
 				return nil
 			}
-			// We can't tell the file type from an mtimeAttr, so we
-			// may have to actually fetch the block to figure it out.
+			// We can't tell the file type from an mtimeAttr or
+			// xattrAttr, so we may have to actually fetch the block
+			// to figure it out.
 			parentDir = realOp.Dir.Ref
 			lastSetAttr = realOp
 		default:
@@ -130,7 +130,13 @@ func (c CryptoCommon) EncryptBlock(
 		return -1, kbfscrypto.EncryptedBlock{}, err
 	}
 
-	paddedBlock, err := kbfscrypto.PadBlock(encodedBlock)
+	compressedBlock, compressionVer, err := kbfscrypto.CompressBlock(
+		encodedBlock, c.blockCryptVersioner.BlockCompressionVersion())
+	if err != nil {
+		return -1, kbfscrypto.EncryptedBlock{}, err
+	}
+
+	paddedBlock, err := kbfscrypto.PadBlock(compressedBlock)
 	if err != nil {
 		return -1, kbfscrypto.EncryptedBlock{}, err
 	}
@@ -142,6 +148,7 @@ func (c CryptoCommon) EncryptBlock(
 	if err != nil {
 		return -1, kbfscrypto.EncryptedBlock{}, err
 	}
+	encryptedBlock.Compression = compressionVer
 
 	plainSize = len(encodedBlock)
 	return plainSize, encryptedBlock, nil
@@ -159,7 +166,13 @@ func (c CryptoCommon) DecryptBlock(
 		return err
 	}
 
-	encodedBlock, err := kbfscrypto.DepadBlock(paddedBlock)
+	compressedBlock, err := kbfscrypto.DepadBlock(paddedBlock)
+	if err != nil {
+		return err
+	}
+
+	encodedBlock, err := kbfscrypto.DecompressBlock(
+		compressedBlock, encryptedBlock.Compression)
 	if err != nil {
 		return err
 	}
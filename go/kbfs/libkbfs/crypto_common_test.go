@@ -5,6 +5,7 @@
 package libkbfs
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -18,18 +19,25 @@ import (
 
 type simpleBlockCryptVersioner struct {
 	t kbfscrypto.EncryptionVer
+	c kbfscrypto.BlockCompressionVer
 }
 
 func (sbcv simpleBlockCryptVersioner) BlockCryptVersion() kbfscrypto.EncryptionVer {
 	return sbcv.t
 }
 
+func (sbcv simpleBlockCryptVersioner) BlockCompressionVersion() kbfscrypto.BlockCompressionVer {
+	return sbcv.c
+}
+
 func makeBlockCryptV1() simpleBlockCryptVersioner {
-	return simpleBlockCryptVersioner{kbfscrypto.EncryptionSecretbox}
+	return simpleBlockCryptVersioner{t: kbfscrypto.EncryptionSecretbox}
 }
 
 func makeBlockCryptV2() simpleBlockCryptVersioner {
-	return simpleBlockCryptVersioner{kbfscrypto.EncryptionSecretboxWithKeyNonce}
+	return simpleBlockCryptVersioner{
+		t: kbfscrypto.EncryptionSecretboxWithKeyNonce,
+	}
 }
 
 // Test (very superficially) that MakeRandomTLFEphemeralKeys() returns
@@ -283,6 +291,38 @@ func TestDecryptV2EncryptedBlock(t *testing.T) {
 	testDecryptEncryptedBlock(t, c)
 }
 
+// Test that crypto.DecryptBlock() decrypts a compressed, encrypted
+// Block object, and that blocks that don't shrink aren't compressed.
+func TestDecryptCompressedEncryptedBlock(t *testing.T) {
+	versioner := makeBlockCryptV2()
+	versioner.c = kbfscrypto.BlockCompressionSnappy
+	c := MakeCryptoCommon(kbfscodec.NewMsgpack(), versioner)
+	testDecryptEncryptedBlock(t, c)
+
+	tlfCryptKey, blockServerHalf, _ := makeFakeBlockCryptKey(t)
+	block := data.NewFileBlock().(*data.FileBlock)
+	block.Contents = bytes.Repeat([]byte("all work and no play "), 1000)
+
+	plainSize, encryptedBlock, err := c.EncryptBlock(
+		block, tlfCryptKey, blockServerHalf)
+	require.NoError(t, err)
+	require.Equal(
+		t, kbfscrypto.BlockCompressionSnappy, encryptedBlock.Compression)
+	require.True(t, len(encryptedBlock.EncryptedData) < plainSize)
+
+	decryptedBlock := data.NewFileBlock().(*data.FileBlock)
+	err = c.DecryptBlock(
+		encryptedBlock, tlfCryptKey, blockServerHalf, decryptedBlock)
+	require.NoError(t, err)
+	require.Equal(t, block.Contents, decryptedBlock.Contents)
+
+	_, encryptedBlock, err = c.EncryptBlock(
+		&TestBlock{50}, tlfCryptKey, blockServerHalf)
+	require.NoError(t, err)
+	require.Equal(
+		t, kbfscrypto.BlockCompressionNone, encryptedBlock.Compression)
+}
+
 // Test that secretbox encrypted data length is a deterministic
 // function of the input data length.
 func TestSecretboxEncryptedLen(t *testing.T) {
@@ -61,6 +61,27 @@ func (e InvalidParentPathError) Error() string {
 	return fmt.Sprintf("Path with invalid parent %s", e.p.DebugString())
 }
 
+// NoSuchXattrError indicates that the user tried to remove an
+// extended attribute that isn't set.
+type NoSuchXattrError struct {
+	Name string
+}
+
+// Error implements the error interface for NoSuchXattrError.
+func (e NoSuchXattrError) Error() string {
+	return fmt.Sprintf("No such extended attribute %q", e.Name)
+}
+
+// UserXattrsDisabledError indicates that the user tried to change
+// an extended attribute while they are turned off in the config.
+type UserXattrsDisabledError struct{}
+
+// Error implements the error interface for UserXattrsDisabledError.
+func (e UserXattrsDisabledError) Error() string {
+	return "Setting extended attributes is not enabled; " +
+		"start KBFS with --enable-user-xattrs to turn it on"
+}
+
 // DirNotEmptyError indicates that the user tried to unlink a
 // subdirectory that was not empty.
 type DirNotEmptyError struct {
@@ -1414,6 +1414,8 @@ func (fbo *folderBlockOps) setCachedAttrLocked(
 		de.Type = realEntry.Type
 	case mtimeAttr:
 		de.Mtime = realEntry.Mtime
+	case xattrAttr:
+		de.Xattrs = realEntry.Xattrs
 	}
 	de.Ctime = realEntry.Ctime
 
@@ -1922,7 +1924,8 @@ func (fbo *folderBlockOps) PrepRename(
 func (fbo *folderBlockOps) newFileData(lState *kbfssync.LockState,
 	file data.Path, chargedTo keybase1.UserOrTeamID, kmd libkey.KeyMetadata) *data.FileData {
 	fbo.blockLock.AssertAnyLocked(lState)
-	return data.NewFileData(file, chargedTo, fbo.config.BlockSplitter(), kmd,
+	return data.NewFileData(file, chargedTo,
+		fbo.config.BlockSplitterForTlf(fbo.id()), kmd,
 		func(ctx context.Context, kmd libkey.KeyMetadata, ptr data.BlockPointer,
 			file data.Path, rtype data.BlockReqType) (*data.FileBlock, bool, error) {
 			lState := lState
@@ -1942,7 +1945,8 @@ func (fbo *folderBlockOps) newFileDataWithCache(lState *kbfssync.LockState,
 	file data.Path, chargedTo keybase1.UserOrTeamID, kmd libkey.KeyMetadata,
 	dirtyBcache data.DirtyBlockCacheSimple) *data.FileData {
 	fbo.blockLock.AssertAnyLocked(lState)
-	return data.NewFileData(file, chargedTo, fbo.config.BlockSplitter(), kmd,
+	return data.NewFileData(file, chargedTo,
+		fbo.config.BlockSplitterForTlf(fbo.id()), kmd,
 		func(ctx context.Context, kmd libkey.KeyMetadata, ptr data.BlockPointer,
 			file data.Path, rtype data.BlockReqType) (*data.FileBlock, bool, error) {
 			block, err := dirtyBcache.Get(ctx, file.Tlf, ptr, file.Branch)
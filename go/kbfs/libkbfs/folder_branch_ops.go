@@ -5,6 +5,7 @@
 package libkbfs
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"math/rand"
@@ -4424,6 +4425,18 @@ const (
 	CtxAllowNameKey CtxAllowNameKeyType = iota
 )
 
+// CtxAllowXattrsKeyType is the type for a context key that allows
+// setting extended attributes.
+type CtxAllowXattrsKeyType int
+
+const (
+	// CtxAllowXattrsKey can be set to any non-nil value in a context
+	// to let specialized KBFS programs (like the S3 gateway) store
+	// their own metadata in extended attributes, even when
+	// `Config.UserXattrsEnabled` is false.
+	CtxAllowXattrsKey CtxAllowXattrsKeyType = iota
+)
+
 func checkDisallowedPrefixes(
 	ctx context.Context, name data.PathPartString) error {
 	for _, prefix := range disallowedPrefixes {
@@ -5777,6 +5790,131 @@ func (fbo *folderBranchOps) SetMtime(
 		})
 }
 
+func (fbo *folderBranchOps) setXattrLocked(
+	ctx context.Context, lState *kbfssync.LockState, file Node,
+	name string, value []byte, remove bool) error {
+	fbo.mdWriterLock.AssertLocked(lState)
+
+	if !fbo.config.UserXattrsEnabled() &&
+		ctx.Value(CtxAllowXattrsKey) == nil {
+		return UserXattrsDisabledError{}
+	}
+
+	filePath, err := fbo.pathFromNodeForMDWriteLocked(lState, file)
+	if err != nil {
+		return err
+	}
+
+	if !filePath.HasValidParent() {
+		return InvalidParentPathError{filePath}
+	}
+
+	// Verify we have permission to write (no need to make a successor yet).
+	md, err := fbo.getMDForWriteLockedForFilename(ctx, lState, "")
+	if err != nil {
+		return err
+	}
+
+	de, err := fbo.blocks.GetEntryEvenIfDeleted(
+		ctx, lState, md.ReadOnly(), filePath)
+	if err != nil {
+		return err
+	}
+
+	if remove {
+		var removed bool
+		de.Xattrs, removed = de.Xattrs.Without(name)
+		if !removed {
+			return NoSuchXattrError{name}
+		}
+	} else {
+		if oldValue, ok := de.Xattrs[name]; ok &&
+			bytes.Equal(oldValue, value) {
+			fbo.vlog.CLogf(ctx, libkb.VLog1, "Ignoring no-op setxattr")
+			return nil
+		}
+		de.Xattrs, err = de.Xattrs.With(name, value)
+		if err != nil {
+			return err
+		}
+	}
+	// Changing the xattrs counts as changing the file MD, so must
+	// set ctime too.
+	de.Ctime = fbo.nowUnixNano()
+
+	parentPtr := filePath.ParentPath().TailPointer()
+	sao, err := newSetAttrOp(
+		filePath.TailName().Plaintext(), parentPtr, xattrAttr,
+		filePath.TailPointer())
+	if err != nil {
+		return err
+	}
+	sao.AddSelfUpdate(parentPtr)
+
+	// If the node has been unlinked, we can safely ignore this
+	// setxattr.
+	if fbo.nodeCache.IsUnlinked(file) {
+		fbo.vlog.CLogf(
+			ctx, libkb.VLog1, "Skipping setxattr for a removed file %v",
+			filePath.TailPointer())
+		_ = fbo.blocks.UpdateCachedEntryAttributesOnRemovedFile(
+			ctx, lState, md.ReadOnly(), sao, filePath, de)
+		return nil
+	}
+
+	sao.setFinalPath(filePath)
+
+	dirCacheUndoFn, err := fbo.blocks.SetAttrInDirEntryInCache(
+		ctx, lState, md.ReadOnly(), filePath, de, sao.Attr)
+	if err != nil {
+		return err
+	}
+	return fbo.notifyAndSyncOrSignal(
+		ctx, lState, dirCacheUndoFn, []Node{file}, sao, md.ReadOnly())
+}
+
+func (fbo *folderBranchOps) SetXattr(
+	ctx context.Context, file Node, name string, value []byte) (err error) {
+	startTime, timer := fbo.startOp(
+		ctx, "SetXattr %s %s", getNodeIDStr(file), name)
+	defer func() {
+		fbo.endOp(
+			ctx, startTime, timer, "SetXattr %s %s done: %+v",
+			getNodeIDStr(file), name, err)
+	}()
+
+	err = fbo.checkNodeForWrite(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	return fbo.doMDWriteWithRetryUnlessCanceled(ctx,
+		func(lState *kbfssync.LockState) error {
+			return fbo.setXattrLocked(ctx, lState, file, name, value, false)
+		})
+}
+
+func (fbo *folderBranchOps) RemoveXattr(
+	ctx context.Context, file Node, name string) (err error) {
+	startTime, timer := fbo.startOp(
+		ctx, "RemoveXattr %s %s", getNodeIDStr(file), name)
+	defer func() {
+		fbo.endOp(
+			ctx, startTime, timer, "RemoveXattr %s %s done: %+v",
+			getNodeIDStr(file), name, err)
+	}()
+
+	err = fbo.checkNodeForWrite(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	return fbo.doMDWriteWithRetryUnlessCanceled(ctx,
+		func(lState *kbfssync.LockState) error {
+			return fbo.setXattrLocked(ctx, lState, file, name, nil, true)
+		})
+}
+
 type cleanupFn func(context.Context, *kbfssync.LockState, []data.BlockPointer, error)
 
 // startSyncLocked readies the blocks and other state needed to sync a
@@ -130,8 +130,8 @@ func (fup *folderUpdatePrepper) unembedBlockChanges(
 
 	df := data.NewDirtyFile(file, dirtyBcache)
 	fd := data.NewFileData(
-		file, chargedTo, fup.config.BlockSplitter(), md.ReadOnly(), getter,
-		cacher, fup.log, fup.vlog)
+		file, chargedTo, fup.config.BlockSplitterForTlf(md.TlfID()),
+		md.ReadOnly(), getter, cacher, fup.log, fup.vlog)
 
 	// Write all the data.
 	_, _, _, _, _, err = fd.Write(ctx, buf, 0, block, data.DirEntry{}, df)
@@ -19,9 +19,11 @@ import (
 
 	"github.com/adamwalz/keybase-client/go/kbconst"
 	"github.com/adamwalz/keybase-client/go/kbfs/data"
+	"github.com/adamwalz/keybase-client/go/kbfs/kbfscodec"
 	"github.com/adamwalz/keybase-client/go/kbfs/kbfscrypto"
 	"github.com/adamwalz/keybase-client/go/kbfs/kbfsmd"
 	"github.com/adamwalz/keybase-client/go/kbfs/libkey"
+	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
 	"github.com/adamwalz/keybase-client/go/libkb"
 	"github.com/adamwalz/keybase-client/go/logger"
 	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
@@ -52,6 +54,16 @@ const (
 	InitSingleOpWithQRString = "singleOpQR"
 )
 
+const (
+	// BlockSplitterSimpleString splits file data into fixed-size
+	// blocks.
+	BlockSplitterSimpleString = "simple"
+	// BlockSplitterFingerprintString splits file data into blocks
+	// at content-defined boundaries, so that small edits to a big
+	// file only change the blocks around the edit.
+	BlockSplitterFingerprintString = "fingerprint"
+)
+
 // CtxInitTagKey is the type used for unique context tags for KBFS init.
 type CtxInitTagKey int
 
@@ -73,6 +85,8 @@ const (
 	configBlockCacheMemMaxBytesStr = "kbfs.block_cache.mem_max_bytes"
 	configBlockCacheDiskMaxFracStr = "kbfs.block_cache.disk_max_fraction"
 	configBlockCacheSyncMaxFracStr = "kbfs.block_cache.sync_max_fraction"
+	configBlockSplitterStr         = "kbfs.block_splitter"
+	configFingerprintSplitTlfsStr  = "kbfs.block_splitter_fingerprint_tlfs"
 )
 
 // InitParams contains the initialization parameters for Init(). It is
@@ -117,6 +131,16 @@ type InitParams struct {
 	// encrypting new blocks.
 	BlockCryptVersion kbfscrypto.EncryptionVer
 
+	// BlockCompressionVersion is the compression version to use
+	// when encrypting new blocks.  Blocks written with compression
+	// can't be read by clients that predate it.
+	BlockCompressionVersion kbfscrypto.BlockCompressionVer
+
+	// EnableUserXattrs, if true, lets users set extended attributes
+	// on files and directories.  Clients that predate them can't
+	// resolve conflicts that involve them.
+	EnableUserXattrs bool
+
 	// LogToFile if true, logs to a default file location.
 	LogToFile bool
 
@@ -161,6 +185,18 @@ type InitParams struct {
 	// SyncBlockCacheFraction indicates what fraction of free space on the disk
 	// is allowed to be occupied by the KBFS sync block cache for offline use.
 	SyncBlockCacheFraction float64
+
+	// BlockSplitter is how file data gets split into blocks, either
+	// BlockSplitterSimpleString or BlockSplitterFingerprintString.
+	// If empty, the value from the config file is used, or simple
+	// splitting if that isn't set either.
+	BlockSplitter string
+
+	// FingerprintSplitTlfs is a comma-separated list of TLF IDs that
+	// split file data at content-defined boundaries, regardless of
+	// BlockSplitter.  If empty, the value from the config file is
+	// used.
+	FingerprintSplitTlfs string
 }
 
 // defaultBServer returns the default value for the -bserver flag.
@@ -317,6 +353,14 @@ func AddFlagsWithDefaults(
 	flags.IntVar((*int)(&params.BlockCryptVersion), "block-crypt-version",
 		int(defaultParams.BlockCryptVersion),
 		"Encryption version to use when encrypting new blocks")
+	flags.IntVar((*int)(&params.BlockCompressionVersion),
+		"block-compression-version",
+		int(defaultParams.BlockCompressionVersion),
+		"Compression version to use for new blocks (0 for none, 1 for snappy)")
+	flags.BoolVar(&params.EnableUserXattrs, "enable-user-xattrs",
+		defaultParams.EnableUserXattrs,
+		"Allow setting user extended attributes (unsafe while older "+
+			"clients still write to the same folders)")
 	flags.StringVar(&params.Mode, "mode", defaultParams.Mode,
 		fmt.Sprintf("Overall initialization mode for KBFS, indicating how "+
 			"heavy-weight it can be (%s, %s, %s, %s or %s)", InitDefaultString,
@@ -331,6 +375,15 @@ func AddFlagsWithDefaults(
 		"sync-block-cache-fraction", defaultParams.SyncBlockCacheFraction,
 		"The portion of the free disk space that KBFS will use for offline storage")
 
+	flags.StringVar(&params.BlockSplitter, "block-splitter",
+		defaultParams.BlockSplitter,
+		fmt.Sprintf("How to split file data into blocks (%s or %s)",
+			BlockSplitterSimpleString, BlockSplitterFingerprintString))
+	flags.StringVar(&params.FingerprintSplitTlfs, "fingerprint-split-tlfs",
+		defaultParams.FingerprintSplitTlfs,
+		"Comma-separated list of TLF IDs that split file data into blocks "+
+			"at content-defined boundaries")
+
 	return &params
 }
 
@@ -690,6 +743,95 @@ func getCleanBlockCacheCapacity(
 	return cap
 }
 
+// makeBlockSplitter makes a splitter of the given kind.  Whatever
+// the kind, directories are split and block changes are embedded in
+// the same way, since `Config.BlockSplitter()` is used for those
+// regardless of the TLF.
+func makeBlockSplitter(kind string, codec kbfscodec.Codec) (
+	data.BlockSplitter, error) {
+	var bsplitter data.BlockSplitter
+	var simple *data.BlockSplitterSimple
+	switch kind {
+	case BlockSplitterSimpleString:
+		s, err := data.NewBlockSplitterSimple(
+			data.MaxBlockSizeBytesDefault, 8*1024, codec)
+		if err != nil {
+			return nil, err
+		}
+		bsplitter, simple = s, s
+	case BlockSplitterFingerprintString:
+		f, err := data.NewBlockSplitterFingerprint(
+			data.MaxBlockSizeBytesDefault, 8*1024, codec)
+		if err != nil {
+			return nil, err
+		}
+		bsplitter, simple = f, f.BlockSplitterSimple
+	default:
+		return nil, fmt.Errorf("Unknown block splitter: %s", kind)
+	}
+	err := simple.SetMaxDirEntriesByBlockSize(codec)
+	if err != nil {
+		return nil, err
+	}
+	return bsplitter, nil
+}
+
+// setBlockSplitters sets the default block splitter for `config`,
+// and the block splitter for any TLFs that are configured to split
+// file data at content-defined boundaries.
+func setBlockSplitters(
+	ctx context.Context, kbCtx Context, params InitParams,
+	config Config, log logger.Logger) error {
+	kind := params.BlockSplitter
+	tlfsString := params.FingerprintSplitTlfs
+
+	// Use the splitters from the config file if none are provided
+	// on the command line.
+	kbConfig := kbCtx.GetEnv().GetConfig()
+	if kind == "" {
+		if configKind, ok := kbConfig.GetStringAtPath(
+			configBlockSplitterStr); ok {
+			log.CDebugf(
+				ctx, "Using block splitter from config file: %s", configKind)
+			kind = configKind
+		} else {
+			kind = BlockSplitterSimpleString
+		}
+	}
+	if tlfsString == "" {
+		if configTlfs, ok := kbConfig.GetStringAtPath(
+			configFingerprintSplitTlfsStr); ok {
+			log.CDebugf(
+				ctx, "Using fingerprint split TLFs from config file: %s",
+				configTlfs)
+			tlfsString = configTlfs
+		}
+	}
+
+	bsplitter, err := makeBlockSplitter(kind, config.Codec())
+	if err != nil {
+		return err
+	}
+	config.SetBlockSplitter(bsplitter)
+
+	if tlfsString == "" {
+		return nil
+	}
+	fsplitter, err := makeBlockSplitter(
+		BlockSplitterFingerprintString, config.Codec())
+	if err != nil {
+		return err
+	}
+	for _, idString := range strings.Split(tlfsString, ",") {
+		tlfID, err := tlf.ParseID(strings.TrimSpace(idString))
+		if err != nil {
+			return err
+		}
+		config.SetBlockSplitterForTlf(tlfID, fsplitter)
+	}
+	return nil
+}
+
 func getCacheFrac(
 	ctx context.Context, kbCtx Context, param, defaultVal float64,
 	configKey string, log logger.Logger) float64 {
@@ -755,16 +897,10 @@ func doInit(
 	config.SetBlockOps(NewBlockOpsStandard(
 		config, workers, prefetchWorkers, throttledPrefetchPeriod, kbCtx))
 
-	bsplitter, err := data.NewBlockSplitterSimple(
-		data.MaxBlockSizeBytesDefault, 8*1024, config.Codec())
+	err = setBlockSplitters(ctx, kbCtx, params, config, log)
 	if err != nil {
 		return nil, err
 	}
-	err = bsplitter.SetMaxDirEntriesByBlockSize(config.Codec())
-	if err != nil {
-		return nil, err
-	}
-	config.SetBlockSplitter(bsplitter)
 
 	if registry := config.MetricsRegistry(); registry != nil {
 		keyCache := config.KeyCache()
@@ -779,6 +915,8 @@ func doInit(
 
 	config.SetMetadataVersion(params.MetadataVersion)
 	config.SetBlockCryptVersion(params.BlockCryptVersion)
+	config.SetBlockCompressionVersion(params.BlockCompressionVersion)
+	config.SetUserXattrsEnabled(params.EnableUserXattrs)
 	config.SetTLFValidDuration(params.TLFValidDuration)
 	config.SetBGFlushPeriod(params.BGFlushPeriod)
 
@@ -515,6 +515,17 @@ type KBFSOps interface {
 	// the top-level folder.  If mtime is nil, it is a noop.  This is
 	// a remote-sync operation.
 	SetMtime(ctx context.Context, file Node, mtime *time.Time) error
+	// SetXattr sets the user extended attribute `name` on the file or
+	// directory represented by a given node, if the logged-in user
+	// has write permissions to the top-level folder.  `name` must
+	// start with "user.".  The attributes are returned as part of
+	// the node's EntryInfo.  This is a remote-sync operation.
+	SetXattr(ctx context.Context, file Node, name string, value []byte) error
+	// RemoveXattr removes the user extended attribute `name` from the
+	// file or directory represented by a given node, if the
+	// logged-in user has write permissions to the top-level folder.
+	// This is a remote-sync operation.
+	RemoveXattr(ctx context.Context, file Node, name string) error
 	// SyncAll flushes all outstanding writes and truncates for any
 	// dirty files to the KBFS servers within the given folder, if the
 	// logged-in user has write permissions to the top-level folder.
@@ -1311,7 +1322,7 @@ type cryptoPure interface {
 
 	// EncryptBlocks encrypts a block. plainSize is the size of the encoded
 	// block; EncryptBlock() must guarantee that plainSize <=
-	// len(encryptedBlock).
+	// len(encryptedBlock), unless the block was compressed.
 	EncryptBlock(
 		block data.Block, tlfCryptKey kbfscrypto.TLFCryptKey,
 		blockServerHalf kbfscrypto.BlockCryptKeyServerHalf) (
@@ -2124,6 +2135,9 @@ type blockCryptVersioner interface {
 	// BlockCryptVersion returns the block encryption version to be used for
 	// new blocks.
 	BlockCryptVersion() kbfscrypto.EncryptionVer
+	// BlockCompressionVersion returns the compression version to be
+	// used for new blocks.
+	BlockCompressionVersion() kbfscrypto.BlockCompressionVer
 }
 
 // SubscriptionID identifies a subscription.
@@ -2266,6 +2280,13 @@ type Config interface {
 	SetKeybaseService(KeybaseService)
 	BlockSplitter() data.BlockSplitter
 	SetBlockSplitter(data.BlockSplitter)
+	// BlockSplitterForTlf returns the splitter for the file data
+	// of the given TLF.  Splitters only differ in where they split
+	// file data: they're all built on a BlockSplitterSimple with the
+	// same parameters, so code that only splits directories or
+	// decides whether to embed block changes uses BlockSplitter().
+	BlockSplitterForTlf(tlf.ID) data.BlockSplitter
+	SetBlockSplitterForTlf(tlf.ID, data.BlockSplitter)
 	Notifier() Notifier
 	SetNotifier(Notifier)
 	SetClock(Clock)
@@ -2276,6 +2297,7 @@ type Config interface {
 	MetadataVersion() kbfsmd.MetadataVer
 	SetMetadataVersion(kbfsmd.MetadataVer)
 	SetBlockCryptVersion(kbfscrypto.EncryptionVer)
+	SetBlockCompressionVersion(kbfscrypto.BlockCompressionVer)
 	DefaultBlockType() keybase1.BlockType
 	SetDefaultBlockType(blockType keybase1.BlockType)
 	// GetConflictResolutionDB gets the levelDB in which conflict resolution
@@ -2294,6 +2316,13 @@ type Config interface {
 	// be true except for during some testing.
 	DoBackgroundFlushes() bool
 	SetDoBackgroundFlushes(bool)
+	// UserXattrsEnabled says whether users may set extended
+	// attributes.  Older clients treat the setAttr ops that record
+	// them as file attribute changes during conflict resolution, so
+	// this should stay off until every device writing to a folder
+	// understands them.
+	UserXattrsEnabled() bool
+	SetUserXattrsEnabled(bool)
 	// RekeyWithPromptWaitTime indicates how long to wait, after
 	// setting the rekey bit, before prompting for a paper key.
 	RekeyWithPromptWaitTime() time.Duration
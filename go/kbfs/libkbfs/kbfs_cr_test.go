@@ -19,6 +19,7 @@ import (
 	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
 	"github.com/adamwalz/keybase-client/go/kbfs/tlfhandle"
 	kbname "github.com/adamwalz/keybase-client/go/kbun"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
@@ -789,6 +790,107 @@ func TestBasicCRFileConflict(t *testing.T) {
 	require.Equal(t, children1, children2)
 }
 
+// Tests that user xattrs set on an unmerged branch survive conflict
+// resolution against merged writes, without causing conflict renames.
+func TestCRUserXattrs(t *testing.T) {
+	// simulate two users
+	var userName1, userName2 kbname.NormalizedUsername = "u1", "u2"
+	config1, _, ctx, cancel := kbfsOpsConcurInit(t, userName1, userName2)
+	defer kbfsConcurTestShutdown(ctx, t, config1, cancel)
+
+	config2 := ConfigAsUser(config1, userName2)
+	defer CheckConfigAndShutdown(ctx, t, config2)
+	config1.SetUserXattrsEnabled(true)
+	config2.SetUserXattrsEnabled(true)
+
+	name := userName1.String() + "," + userName2.String()
+
+	// user1 creates a file in a shared dir, and tags it
+	rootNode1 := GetRootNodeOrBust(ctx, t, config1, name, tlf.Private)
+
+	kbfsOps1 := config1.KBFSOps()
+	dirA1, _, err := kbfsOps1.CreateDir(ctx, rootNode1, testPPS("a"))
+	require.NoError(t, err)
+	fileB1, _, err := kbfsOps1.CreateFile(
+		ctx, dirA1, testPPS("b"), false, NoExcl)
+	require.NoError(t, err)
+	err = kbfsOps1.SetXattr(ctx, fileB1, "user.tag", []byte("blue"))
+	require.NoError(t, err)
+	err = kbfsOps1.SyncAll(ctx, rootNode1.GetFolderBranch())
+	require.NoError(t, err)
+
+	// look it up on user2
+	rootNode2 := GetRootNodeOrBust(ctx, t, config2, name, tlf.Private)
+
+	kbfsOps2 := config2.KBFSOps()
+	dirA2, _, err := kbfsOps2.Lookup(ctx, rootNode2, testPPS("a"))
+	require.NoError(t, err)
+	fileB2, ei, err := kbfsOps2.Lookup(ctx, dirA2, testPPS("b"))
+	require.NoError(t, err)
+	require.Equal(t, data.UserXattrs{"user.tag": []byte("blue")}, ei.Xattrs)
+
+	// disable updates on user 2
+	c, err := DisableUpdatesForTesting(config2, rootNode2.GetFolderBranch())
+	require.NoError(t, err)
+	err = DisableCRForTesting(config2, rootNode2.GetFolderBranch())
+	require.NoError(t, err)
+
+	// User 1 writes the file
+	data1 := []byte{1, 2, 3, 4, 5}
+	err = kbfsOps1.Write(ctx, fileB1, data1, 0)
+	require.NoError(t, err)
+	err = kbfsOps1.SyncAll(ctx, fileB1.GetFolderBranch())
+	require.NoError(t, err)
+
+	// User 2 changes the xattrs on the file and the directory
+	err = kbfsOps2.RemoveXattr(ctx, fileB2, "user.tag")
+	require.NoError(t, err)
+	err = kbfsOps2.RemoveXattr(ctx, fileB2, "user.tag")
+	require.Equal(t, NoSuchXattrError{"user.tag"}, errors.Cause(err))
+	err = kbfsOps2.SetXattr(ctx, fileB2, "user.color", []byte("red"))
+	require.NoError(t, err)
+	err = kbfsOps2.SetXattr(ctx, dirA2, "user.dir", []byte("yes"))
+	require.NoError(t, err)
+	err = kbfsOps2.SyncAll(ctx, fileB2.GetFolderBranch())
+	require.NoError(t, err)
+
+	// re-enable updates, and wait for CR to complete
+	c <- struct{}{}
+	err = RestartCRForTesting(
+		libcontext.BackgroundContextWithCancellationDelayer(), config2,
+		rootNode2.GetFolderBranch())
+	require.NoError(t, err)
+	err = kbfsOps2.SyncFromServer(ctx,
+		rootNode2.GetFolderBranch(), nil)
+	require.NoError(t, err)
+
+	err = kbfsOps1.SyncFromServer(ctx,
+		rootNode1.GetFolderBranch(), nil)
+	require.NoError(t, err)
+
+	// No conflict copies, and both users see the write and the xattrs.
+	for _, kbfsOps := range []KBFSOps{kbfsOps1, kbfsOps2} {
+		rootNode := rootNode1
+		if kbfsOps == kbfsOps2 {
+			rootNode = rootNode2
+		}
+		dirA, ei, err := kbfsOps.Lookup(ctx, rootNode, testPPS("a"))
+		require.NoError(t, err)
+		require.Equal(t, data.UserXattrs{"user.dir": []byte("yes")}, ei.Xattrs)
+		children, err := kbfsOps.GetDirChildren(ctx, dirA)
+		require.NoError(t, err)
+		require.Len(t, children, 1)
+		fileB, ei, err := kbfsOps.Lookup(ctx, dirA, testPPS("b"))
+		require.NoError(t, err)
+		require.Equal(t,
+			data.UserXattrs{"user.color": []byte("red")}, ei.Xattrs)
+		buf := make([]byte, len(data1))
+		n, err := kbfsOps.Read(ctx, fileB, buf, 0)
+		require.NoError(t, err)
+		require.Equal(t, data1, buf[:n])
+	}
+}
+
 // Tests that if CR fails enough times it will stop trying,
 // and that we can move the conflicts out of the way.
 func TestBasicCRFailureAndFixing(t *testing.T) {
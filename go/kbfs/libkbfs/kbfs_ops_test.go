@@ -3486,6 +3486,81 @@ func TestKBFSOpsFailToReadUnverifiableBlock(t *testing.T) {
 	require.IsType(t, kbfshash.HashMismatchError{}, errors.Cause(err))
 }
 
+// Test that files written with block compression turned on can be
+// read back by another device, and that the compressed blocks are
+// marked with a data version that older clients will refuse.
+func TestKBFSOpsCompressedBlocks(t *testing.T) {
+	config, _, ctx, cancel := kbfsOpsInitNoMocks(t, "test_user")
+	defer kbfsTestShutdownNoMocks(ctx, t, config, cancel)
+	require.Equal(t, data.IndirectDirsVer, config.DataVersion())
+	config.SetBlockCompressionVersion(kbfscrypto.BlockCompressionSnappy)
+	require.Equal(t, data.CompressedBlocksVer, config.DataVersion())
+
+	rootNode := GetRootNodeOrBust(ctx, t, config, "test_user", tlf.Private)
+	kbfsOps := config.KBFSOps()
+	fileNode, _, err := kbfsOps.CreateFile(
+		ctx, rootNode, testPPS("a"), false, NoExcl)
+	require.NoError(t, err)
+	contents := bytes.Repeat([]byte("compress me "), 1000)
+	err = kbfsOps.Write(ctx, fileNode, contents, 0)
+	require.NoError(t, err)
+	err = kbfsOps.SyncAll(ctx, fileNode.GetFolderBranch())
+	require.NoError(t, err)
+
+	md, err := kbfsOps.GetNodeMetadata(ctx, fileNode)
+	require.NoError(t, err)
+	require.Equal(t, data.CompressedBlocksVer, md.BlockInfo.DataVer)
+	require.True(t, int(md.BlockInfo.EncodedSize) < len(contents))
+
+	// Read using a different "device", with an empty block cache,
+	// that doesn't compress blocks itself.
+	config2 := ConfigAsUser(config, "test_user")
+	defer CheckConfigAndShutdown(ctx, t, config2)
+	require.Equal(t, data.IndirectDirsVer, config2.DataVersion())
+	rootNode2 := GetRootNodeOrBust(ctx, t, config2, "test_user", tlf.Private)
+	fileNode2, _, err := config2.KBFSOps().Lookup(ctx, rootNode2, testPPS("a"))
+	require.NoError(t, err)
+	buf := make([]byte, len(contents))
+	n, err := config2.KBFSOps().Read(ctx, fileNode2, buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(contents)), n)
+	require.Equal(t, contents, buf)
+}
+
+// Test that user xattrs can only be changed once they're enabled.
+func TestKBFSOpsUserXattrsDisabled(t *testing.T) {
+	config, _, ctx, cancel := kbfsOpsInitNoMocks(t, "test_user")
+	defer kbfsTestShutdownNoMocks(ctx, t, config, cancel)
+
+	rootNode := GetRootNodeOrBust(ctx, t, config, "test_user", tlf.Private)
+	kbfsOps := config.KBFSOps()
+	fileNode, _, err := kbfsOps.CreateFile(
+		ctx, rootNode, testPPS("a"), false, NoExcl)
+	require.NoError(t, err)
+
+	err = kbfsOps.SetXattr(ctx, fileNode, "user.tag", []byte("blue"))
+	require.IsType(t, UserXattrsDisabledError{}, errors.Cause(err))
+
+	config.SetUserXattrsEnabled(true)
+	err = kbfsOps.SetXattr(ctx, fileNode, "user.tag", []byte("blue"))
+	require.NoError(t, err)
+
+	config.SetUserXattrsEnabled(false)
+	err = kbfsOps.RemoveXattr(ctx, fileNode, "user.tag")
+	require.IsType(t, UserXattrsDisabledError{}, errors.Cause(err))
+	ei, err := kbfsOps.Stat(ctx, fileNode)
+	require.NoError(t, err)
+	require.Equal(t, []byte("blue"), ei.Xattrs["user.tag"])
+
+	// Internal callers can still set their own metadata.
+	allowCtx := context.WithValue(ctx, CtxAllowXattrsKey, true)
+	err = kbfsOps.SetXattr(allowCtx, fileNode, "user.tag", []byte("red"))
+	require.NoError(t, err)
+	ei, err = kbfsOps.Stat(ctx, fileNode)
+	require.NoError(t, err)
+	require.Equal(t, []byte("red"), ei.Xattrs["user.tag"])
+}
+
 // Test that the size of a single empty block doesn't change.  If this
 // test ever fails, consult max or strib before merging.
 func TestKBFSOpsEmptyTlfSize(t *testing.T) {
@@ -1523,6 +1523,20 @@ func (mr *MockKBFSOpsMockRecorder) RemoveEntry(arg0, arg1, arg2 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveEntry", reflect.TypeOf((*MockKBFSOps)(nil).RemoveEntry), arg0, arg1, arg2)
 }
 
+// RemoveXattr mocks base method.
+func (m *MockKBFSOps) RemoveXattr(arg0 context.Context, arg1 Node, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveXattr", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveXattr indicates an expected call of RemoveXattr.
+func (mr *MockKBFSOpsMockRecorder) RemoveXattr(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveXattr", reflect.TypeOf((*MockKBFSOps)(nil).RemoveXattr), arg0, arg1, arg2)
+}
+
 // Rename mocks base method.
 func (m *MockKBFSOps) Rename(arg0 context.Context, arg1 Node, arg2 data.PathPartString, arg3 Node, arg4 data.PathPartString) error {
 	m.ctrl.T.Helper()
@@ -1603,6 +1617,20 @@ func (mr *MockKBFSOpsMockRecorder) SetMtime(arg0, arg1, arg2 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMtime", reflect.TypeOf((*MockKBFSOps)(nil).SetMtime), arg0, arg1, arg2)
 }
 
+// SetXattr mocks base method.
+func (m *MockKBFSOps) SetXattr(arg0 context.Context, arg1 Node, arg2 string, arg3 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetXattr", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetXattr indicates an expected call of SetXattr.
+func (mr *MockKBFSOpsMockRecorder) SetXattr(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetXattr", reflect.TypeOf((*MockKBFSOps)(nil).SetXattr), arg0, arg1, arg2, arg3)
+}
+
 // SetSyncConfig mocks base method.
 func (m *MockKBFSOps) SetSyncConfig(arg0 context.Context, arg1 tlf.ID, arg2 keybase1.FolderSyncConfig) (<-chan error, error) {
 	m.ctrl.T.Helper()
@@ -1172,6 +1172,7 @@ const (
 	exAttr attrChange = iota
 	mtimeAttr
 	sizeAttr // only used during conflict resolution
+	xattrAttr
 )
 
 func (ac attrChange) String() string {
@@ -1182,6 +1183,8 @@ func (ac attrChange) String() string {
 		return "mtime"
 	case sizeAttr:
 		return "size"
+	case xattrAttr:
+		return "xattr"
 	}
 	return "<invalid attrChange>"
 }
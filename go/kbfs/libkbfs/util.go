@@ -117,13 +117,16 @@ func CtxWithRandomIDReplayable(ctx context.Context, tagKey interface{},
 }
 
 // checkDataVersion validates that the data version for a
-// block pointer is valid for the given version validator
+// block pointer is valid for the given version validator.  Blocks
+// written by other devices may use any version this client can read,
+// even one the versioner doesn't write itself.
 func checkDataVersion(
 	versioner data.Versioner, p data.Path, ptr data.BlockPointer) error {
 	if ptr.DataVer < data.FirstValidVer {
 		return errors.WithStack(InvalidDataVersionError{ptr.DataVer})
 	}
-	if versioner != nil && ptr.DataVer > versioner.DataVersion() {
+	if versioner != nil && ptr.DataVer > versioner.DataVersion() &&
+		ptr.DataVer > data.NewestReadableVer {
 		return errors.WithStack(NewDataVersionError{p, ptr.DataVer})
 	}
 	return nil
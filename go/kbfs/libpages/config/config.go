@@ -9,6 +9,7 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"net/http"
 )
 
 // DefaultConfigFilename is the default filename for Keybase Pages config file.
@@ -35,9 +36,9 @@ const (
 	Version1
 	// Version2 is version 2.
 	//
-	// Currently the only difference between V1 and V2 is that V2 uses
-	// sha-based password hash instead of bcrypt in V1. V2 still uses the ACL
-	// definition and checker from V1.
+	// V2 is the V1 format plus redirects, rewrites and custom headers in
+	// per-path configs, and is parsed into a V1. Servers that only support
+	// V1 reject V2 configs rather than ignoring rules they don't know about.
 	Version2
 )
 const (
@@ -89,6 +90,16 @@ type Config interface {
 	// GetAccessControlAllowOrigin returns a string that, if non-empty, should
 	// be set as Access-Control-Allow-Origin header.
 	GetAccessControlAllowOrigin(path string) (setting string, err error)
+	// GetRedirect returns where a request for path should be redirected to,
+	// and the status code to redirect with. If to is empty, there's no
+	// redirect for path.
+	GetRedirect(path string) (to string, status int, err error)
+	// GetRewrite returns the path of the file to serve for path if the file
+	// at path doesn't exist. If to is empty, there's no rewrite for path.
+	GetRewrite(path string) (to string, err error)
+	// GetCustomHeaders returns the custom headers that should be set when
+	// serving path. The caller must not modify the returned headers.
+	GetCustomHeaders(path string) (headers http.Header, err error)
 
 	Encode(w io.Writer, prettify bool) error
 }
@@ -109,7 +120,7 @@ func ParseConfig(reader io.Reader) (config Config, err error) {
 		return nil, err
 	}
 	switch v {
-	case Version1:
+	case Version1, Version2:
 		var v1 V1
 		err = json.NewDecoder(buf).Decode(&v1)
 		if err != nil {
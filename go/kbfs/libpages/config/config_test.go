@@ -41,3 +41,36 @@ func TestParseConfigV1(t *testing.T) {
 	require.Equal(t, config.Common, parsedV1.Common)
 	require.Equal(t, config.Users, parsedV1.Users)
 }
+
+func TestParseConfigV2(t *testing.T) {
+	config := &V1{
+		Common: Common{
+			Version: Version2Str,
+		},
+		PerPathConfigs: map[string]PerPathConfigV1{
+			"/": {
+				AnonymousPermissions: PermRead,
+				Redirects: []RedirectRuleV1{
+					{From: "/old.html", To: "/new.html"},
+				},
+			},
+		},
+	}
+	buf := &bytes.Buffer{}
+	err := json.NewEncoder(buf).Encode(config)
+	require.NoError(t, err)
+	parsed, err := ParseConfig(buf)
+	require.NoError(t, err)
+	require.Equal(t, Version2, parsed.Version())
+	to, status, err := parsed.GetRedirect("/old.html")
+	require.NoError(t, err)
+	require.Equal(t, "/new.html", to)
+	require.Equal(t, 301, status)
+
+	config.Common.Version = Version1Str
+	buf.Reset()
+	err = json.NewEncoder(buf).Encode(config)
+	require.NoError(t, err)
+	_, err = ParseConfig(buf)
+	require.IsType(t, ErrInvalidConfig{}, err)
+}
@@ -7,7 +7,9 @@ package config
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -79,6 +81,25 @@ func (c *V1) checkAndRenameACLsIfNeeded() error {
 	return nil
 }
 
+// checkRulesVersion makes sure redirects, rewrites and custom headers only
+// appear in configs that declare Version2, so that servers that only know
+// about Version1 reject them instead of silently ignoring the rules.
+func (c *V1) checkRulesVersion() error {
+	if c.Common.Version == Version2Str {
+		return nil
+	}
+	for p, perPathConfig := range c.PerPathConfigs {
+		if len(perPathConfig.Redirects) > 0 ||
+			len(perPathConfig.Rewrites) > 0 ||
+			len(perPathConfig.Headers) > 0 {
+			return ErrInvalidConfig{fmt.Sprintf(
+				"redirects, rewrites and headers (in %s) require "+
+					"config version %s", p, Version2Str)}
+		}
+	}
+	return nil
+}
+
 func (c *V1) init() {
 	c.bcryptLimiter = rate.NewLimiter(rate.Every(bcryptRateLimitInterval), 1)
 
@@ -86,6 +107,10 @@ func (c *V1) init() {
 	if c.perPathConfigsReaderInitErr != nil {
 		return
 	}
+	c.perPathConfigsReaderInitErr = c.checkRulesVersion()
+	if c.perPathConfigsReaderInitErr != nil {
+		return
+	}
 	c.perPathConfigsReader, c.perPathConfigsReaderInitErr =
 		makePerPathConfigsReaderV1(c.PerPathConfigs, c.Users)
 	if c.perPathConfigsReaderInitErr != nil {
@@ -114,6 +139,9 @@ func (c *V1) EnsureInit() error {
 
 // Version implements the Config interface.
 func (c *V1) Version() Version {
+	if c.Common.Version == Version2Str {
+		return Version2
+	}
 	return Version1
 }
 
@@ -152,6 +180,31 @@ func (c *V1) GetAccessControlAllowOrigin(path string) (setting string, err error
 	return c.perPathConfigsReader.getSetAccessControlAllowOrigin(path), nil
 }
 
+// GetRedirect implements the Config interface.
+func (c *V1) GetRedirect(path string) (to string, status int, err error) {
+	if err = c.EnsureInit(); err != nil {
+		return "", 0, err
+	}
+	to, status = c.perPathConfigsReader.getRedirect(path)
+	return to, status, nil
+}
+
+// GetRewrite implements the Config interface.
+func (c *V1) GetRewrite(path string) (to string, err error) {
+	if err = c.EnsureInit(); err != nil {
+		return "", err
+	}
+	return c.perPathConfigsReader.getRewrite(path), nil
+}
+
+// GetCustomHeaders implements the Config interface.
+func (c *V1) GetCustomHeaders(path string) (headers http.Header, err error) {
+	if err = c.EnsureInit(); err != nil {
+		return nil, err
+	}
+	return c.perPathConfigsReader.getCustomHeaders(path), nil
+}
+
 // Encode implements the Config interface.
 func (c *V1) Encode(w io.Writer, prettify bool) error {
 	encoder := json.NewEncoder(w)
@@ -176,6 +229,9 @@ func (c *V1) Validate() (err error) {
 	if err := c.checkAndRenameACLsIfNeeded(); err != nil {
 		return err
 	}
+	if err := c.checkRulesVersion(); err != nil {
+		return err
+	}
 	_, err = makePerPathConfigsReaderV1(c.PerPathConfigs, c.Users)
 	return err
 }
@@ -388,3 +388,131 @@ func TestV1DeprecatingACLsField(t *testing.T) {
 	}).EnsureInit()
 	require.IsType(t, ErrACLsPerPathConfigsBothPresent{}, err)
 }
+
+func TestConfigV1Rules(t *testing.T) {
+	config := &V1{
+		Common: Common{
+			Version: Version2Str,
+		},
+		PerPathConfigs: map[string]PerPathConfigV1{
+			"/": {
+				AnonymousPermissions: PermRead,
+				Redirects: []RedirectRuleV1{
+					{From: "/old.html", To: "/new.html"},
+					{From: "/blog/*", To: "https://blog.example.com/:splat",
+						Status: 302},
+				},
+				Headers: map[string]string{
+					"cache-control": "max-age=60",
+				},
+			},
+			"/docs": {
+				AnonymousPermissions: PermRead,
+				Redirects: []RedirectRuleV1{
+					{From: "/docs/v1/*", To: "/docs/v2/:splat", Status: 308},
+				},
+				Rewrites: []RewriteRuleV1{
+					{From: "/docs/app/*", To: "/docs/app/index.html"},
+				},
+				Headers: map[string]string{
+					"Content-Security-Policy": "default-src 'self'",
+				},
+			},
+		},
+	}
+	require.NoError(t, config.Validate())
+
+	for p, expected := range map[string]struct {
+		to     string
+		status int
+	}{
+		"/old.html":            {"/new.html", 301},
+		"/old.html/":           {"/new.html", 301},
+		"/new.html":            {"", 0},
+		"/blog":                {"https://blog.example.com/", 302},
+		"/blog/2020/post.html": {"https://blog.example.com/2020/post.html", 302},
+		"/blogs":               {"", 0},
+		"/docs/v1/a/b.html":    {"/docs/v2/a/b.html", 308},
+		"/docs/v1/../v1/a":     {"/docs/v2/a", 308},
+		"/docs/v2/a":           {"", 0},
+		// The /docs config doesn't inherit the root's rules.
+		"/docs/old.html": {"", 0},
+	} {
+		to, status, err := config.GetRedirect(p)
+		require.NoError(t, err)
+		require.Equal(t, expected.to, to, p)
+		require.Equal(t, expected.status, status, p)
+	}
+
+	to, err := config.GetRewrite("/docs/app/some/route")
+	require.NoError(t, err)
+	require.Equal(t, "/docs/app/index.html", to)
+	to, err = config.GetRewrite("/docs/other")
+	require.NoError(t, err)
+	require.Equal(t, "", to)
+
+	headers, err := config.GetCustomHeaders("/index.html")
+	require.NoError(t, err)
+	require.Equal(t, "max-age=60", headers.Get("Cache-Control"))
+	headers, err = config.GetCustomHeaders("/docs/a.html")
+	require.NoError(t, err)
+	require.Equal(t, "default-src 'self'",
+		headers.Get("Content-Security-Policy"))
+	require.Equal(t, "", headers.Get("Cache-Control"))
+}
+
+func TestConfigV1InvalidRules(t *testing.T) {
+	for _, perPathConfigs := range []map[string]PerPathConfigV1{
+		{"/": {Redirects: []RedirectRuleV1{{From: "old", To: "/new"}}}},
+		{"/": {Redirects: []RedirectRuleV1{{From: "/*/a", To: "/new"}}}},
+		{"/": {Redirects: []RedirectRuleV1{{From: "/a*", To: "/new"}}}},
+		{"/": {Redirects: []RedirectRuleV1{{From: "/a", To: "new"}}}},
+		{"/": {Redirects: []RedirectRuleV1{{From: "/a", To: "//evil.com"}}}},
+		{"/": {Redirects: []RedirectRuleV1{
+			{From: "/a", To: "javascript:alert(1)"}}}},
+		{"/": {Redirects: []RedirectRuleV1{
+			{From: "/a", To: "/b", Status: 200}}}},
+		{"/docs": {Redirects: []RedirectRuleV1{{From: "/a", To: "/b"}}}},
+		{"/docs": {Redirects: []RedirectRuleV1{{From: "/docsx", To: "/b"}}}},
+		{"/": {Rewrites: []RewriteRuleV1{
+			{From: "/app/*", To: "https://example.com"}}}},
+		{"/": {Headers: map[string]string{"Bad Header": "x"}}},
+		{"/": {Headers: map[string]string{"X-Foo": "a\r\nb"}}},
+		{"/": {Headers: map[string]string{"Set-Cookie": "a=b"}}},
+		{"/": {Headers: map[string]string{
+			"strict-transport-security": "max-age=0"}}},
+		{"/": {Headers: map[string]string{"x-foo": "a", "X-Foo": "b"}}},
+	} {
+		err := (&V1{
+			Common: Common{
+				Version: Version2Str,
+			},
+			PerPathConfigs: perPathConfigs,
+		}).Validate()
+		require.Error(t, err, "%+v", perPathConfigs)
+		require.IsType(t, ErrInvalidConfig{}, err)
+	}
+}
+
+func TestConfigV1RulesRequireVersion2(t *testing.T) {
+	config := &V1{
+		Common: Common{
+			Version: Version1Str,
+		},
+		PerPathConfigs: map[string]PerPathConfigV1{
+			"/": {
+				AnonymousPermissions: PermRead,
+				Headers: map[string]string{
+					"Cache-Control": "max-age=60",
+				},
+			},
+		},
+	}
+	err := config.Validate()
+	require.Error(t, err)
+	require.IsType(t, ErrInvalidConfig{}, err)
+
+	config.Common.Version = Version2Str
+	require.NoError(t, config.Validate())
+	require.Equal(t, Version2, config.Version())
+}
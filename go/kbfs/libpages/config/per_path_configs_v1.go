@@ -6,6 +6,7 @@ package config
 
 import (
 	"errors"
+	"net/http"
 	"path"
 	"strings"
 )
@@ -40,6 +41,21 @@ type PerPathConfigV1 struct {
 	// Custom404NotFound specifies a path (relative to site root) to a html
 	// file to be served when 404 errors happen.
 	Custom404NotFound string `json:"custom_404_not_found,omitempty"`
+
+	// Redirects is a list of redirect rules for requests under the
+	// corresponding path. They are checked in order, and the first one that
+	// matches the request path applies. Redirects, Rewrites and Headers are
+	// only allowed in configs with version Version2.
+	Redirects []RedirectRuleV1 `json:"redirects,omitempty"`
+	// Rewrites is a list of rewrite rules for requests under the
+	// corresponding path. They are checked in order, and the first one that
+	// matches the request path applies, but only if the requested file
+	// doesn't exist.
+	Rewrites []RewriteRuleV1 `json:"rewrites,omitempty"`
+	// Headers is a map of header name -> value of custom headers to set when
+	// serving requests under the corresponding path, e.g. Cache-Control or
+	// Content-Security-Policy.
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 // permissionsV1 is the parsed version of a permission string.
@@ -91,6 +107,10 @@ type perPathConfigV1 struct {
 	accessControlAllowOrigin string
 	custom403Forbidden       string
 	custom404NotFound        string
+
+	redirects []pathRuleV1
+	rewrites  []pathRuleV1
+	headers   http.Header
 }
 
 func checkCors(acao string) (cleaned string, err error) {
@@ -158,6 +178,23 @@ func makePerPathConfigV1Internal(
 		a.Custom404NotFound); err != nil {
 		return nil, err
 	}
+	for _, r := range a.Redirects {
+		rule, err := makeRedirectRuleV1(r, p)
+		if err != nil {
+			return nil, err
+		}
+		ac.redirects = append(ac.redirects, rule)
+	}
+	for _, r := range a.Rewrites {
+		rule, err := makeRewriteRuleV1(r, p)
+		if err != nil {
+			return nil, err
+		}
+		ac.rewrites = append(ac.rewrites, rule)
+	}
+	if ac.headers, err = checkHeaders(a.Headers); err != nil {
+		return nil, err
+	}
 
 	return ac, nil
 }
@@ -278,6 +315,35 @@ func (c *perPathConfigsReaderV1) getSetAccessControlAllowOrigin(p string) (setti
 	return ac.accessControlAllowOrigin
 }
 
+// matchRules returns the target of the first rule in rules that matches p.
+func matchRules(rules []pathRuleV1, p string) (rule pathRuleV1, to string) {
+	cleaned := path.Clean("/" + p)
+	for _, rule := range rules {
+		if to, ok := rule.match(cleaned); ok {
+			return rule, to
+		}
+	}
+	return pathRuleV1{}, ""
+}
+
+func (c *perPathConfigsReaderV1) getRedirect(p string) (
+	to string, status int) {
+	ac := c.getPerPathConfig(nil, p)
+	rule, to := matchRules(ac.redirects, p)
+	return to, rule.status
+}
+
+func (c *perPathConfigsReaderV1) getRewrite(p string) (to string) {
+	ac := c.getPerPathConfig(nil, p)
+	_, to = matchRules(ac.rewrites, p)
+	return to
+}
+
+func (c *perPathConfigsReaderV1) getCustomHeaders(p string) http.Header {
+	ac := c.getPerPathConfig(nil, p)
+	return ac.headers
+}
+
 // makePerPathConfigsReaderV1 makes an *perPathConfigsReaderV1 out of
 // user-defined per-path configs. It recursively constructs nested
 // *perPathConfigsReaderV1 so that each defined path has a corresponding
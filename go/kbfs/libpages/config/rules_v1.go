@@ -0,0 +1,201 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+const (
+	// RuleWildcard, as the last element of the From path of a redirect or
+	// rewrite rule, matches any number of path elements, including none.
+	RuleWildcard = "*"
+	// RuleSplat, in the To field of a redirect or rewrite rule, is replaced
+	// by whatever the wildcard in the From path matched.
+	RuleSplat = ":splat"
+)
+
+// RedirectRuleV1 defines a redirect for the V1 config.
+type RedirectRuleV1 struct {
+	// From is the path (relative to site root) that the rule applies to. It
+	// can end with a "*" element to match everything under the path.
+	From string `json:"from"`
+	// To is either a path (relative to site root) or an absolute http(s)
+	// URL to redirect to. If From ends with a wildcard, any ":splat" in To is
+	// replaced with the part of the request path that the wildcard matched.
+	To string `json:"to"`
+	// Status is the HTTP status code to redirect with. It can be 301, 302,
+	// 303, 307 or 308, and defaults to 301.
+	Status int `json:"status,omitempty"`
+}
+
+// RewriteRuleV1 defines a rewrite for the V1 config. A rewrite serves a
+// different file than the one requested, without the client knowing about
+// it, when the requested file doesn't exist. This is useful for single page
+// apps, where every path should be served by the same index.html.
+type RewriteRuleV1 struct {
+	// From is the path (relative to site root) that the rule applies to. It
+	// can end with a "*" element to match everything under the path.
+	From string `json:"from"`
+	// To is the path (relative to site root) of the file to serve instead.
+	// If From ends with a wildcard, any ":splat" in To is replaced with the
+	// part of the request path that the wildcard matched.
+	To string `json:"to"`
+}
+
+// reservedHeaders are the response headers that can't be set through the
+// Headers field of a per-path config, either because the server manages them
+// or because they are better set through a dedicated field.
+var reservedHeaders = map[string]bool{
+	"Access-Control-Allow-Origin": true,
+	"Connection":                  true,
+	"Content-Length":              true,
+	"Content-Range":               true,
+	"Location":                    true,
+	"Set-Cookie":                  true,
+	"Strict-Transport-Security":   true,
+	"Transfer-Encoding":           true,
+	"Www-Authenticate":            true,
+}
+
+// pathRuleV1 is the parsed version of a RedirectRuleV1 or a RewriteRuleV1.
+type pathRuleV1 struct {
+	// from is the cleaned From path, without the wildcard if there was one.
+	from     string
+	wildcard bool
+	to       string
+	status   int
+}
+
+// match returns the target of r for the cleaned path p, if r applies to it.
+func (r pathRuleV1) match(p string) (to string, ok bool) {
+	if !r.wildcard {
+		return r.to, p == r.from
+	}
+	var splat string
+	switch {
+	case p == r.from:
+	case r.from == "/":
+		splat = strings.TrimPrefix(p, "/")
+	case strings.HasPrefix(p, r.from+"/"):
+		splat = strings.TrimPrefix(p, r.from+"/")
+	default:
+		return "", false
+	}
+	return strings.Replace(r.to, RuleSplat, splat, -1), true
+}
+
+// parseRuleFrom checks that from is under perPathConfigPath, which is the
+// only place the rule can ever apply since the per-path config that
+// contains it is picked based on the request path.
+func parseRuleFrom(from string, perPathConfigPath string) (
+	cleaned string, wildcard bool, err error) {
+	if !strings.HasPrefix(from, "/") {
+		return "", false, ErrInvalidConfig{
+			msg: fmt.Sprintf("rule path %q doesn't start with /", from)}
+	}
+	if path.Base(from) == RuleWildcard {
+		wildcard = true
+		from = path.Dir(from)
+	}
+	if strings.Contains(from, RuleWildcard) {
+		return "", false, ErrInvalidConfig{msg: fmt.Sprintf(
+			"rule path %q can only have %q as its last element",
+			from, RuleWildcard)}
+	}
+	cleaned = path.Clean(from)
+	base := path.Clean("/" + perPathConfigPath)
+	if base != "/" && cleaned != base && !strings.HasPrefix(cleaned, base+"/") {
+		return "", false, ErrInvalidConfig{msg: fmt.Sprintf(
+			"rule path %q is outside of %q", from, base)}
+	}
+	return cleaned, wildcard, nil
+}
+
+func checkSitePath(p string) (cleaned string, err error) {
+	if !strings.HasPrefix(p, "/") {
+		return "", ErrInvalidConfig{
+			msg: fmt.Sprintf("rule target %q doesn't start with /", p)}
+	}
+	cleaned = path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned, nil
+}
+
+func makeRedirectRuleV1(r RedirectRuleV1, perPathConfigPath string) (
+	rule pathRuleV1, err error) {
+	rule.from, rule.wildcard, err = parseRuleFrom(r.From, perPathConfigPath)
+	if err != nil {
+		return pathRuleV1{}, err
+	}
+	switch r.Status {
+	case 0:
+		rule.status = http.StatusMovedPermanently
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		rule.status = r.Status
+	default:
+		return pathRuleV1{}, ErrInvalidConfig{
+			msg: fmt.Sprintf("invalid redirect status %d", r.Status)}
+	}
+	if strings.HasPrefix(r.To, "/") && !strings.HasPrefix(r.To, "//") {
+		rule.to, err = checkSitePath(r.To)
+		return rule, err
+	}
+	u, err := url.Parse(r.To)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" {
+		return pathRuleV1{}, ErrInvalidConfig{
+			msg: fmt.Sprintf("invalid redirect target %q", r.To)}
+	}
+	rule.to = r.To
+	return rule, nil
+}
+
+func makeRewriteRuleV1(r RewriteRuleV1, perPathConfigPath string) (
+	rule pathRuleV1, err error) {
+	rule.from, rule.wildcard, err = parseRuleFrom(r.From, perPathConfigPath)
+	if err != nil {
+		return pathRuleV1{}, err
+	}
+	rule.to, err = checkSitePath(r.To)
+	return rule, err
+}
+
+func checkHeaders(headers map[string]string) (http.Header, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	checked := make(http.Header, len(headers))
+	for name, value := range headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return nil, ErrInvalidConfig{
+				msg: fmt.Sprintf("invalid header name %q", name)}
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return nil, ErrInvalidConfig{
+				msg: fmt.Sprintf("invalid value for header %s", name)}
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if reservedHeaders[canonical] {
+			return nil, ErrInvalidConfig{
+				msg: fmt.Sprintf("header %s can't be set", canonical)}
+		}
+		if checked.Get(canonical) != "" {
+			return nil, ErrInvalidConfig{
+				msg: fmt.Sprintf("duplicate header %s", canonical)}
+		}
+		checked.Set(canonical, value)
+	}
+	return checked, nil
+}
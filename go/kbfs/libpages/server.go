@@ -179,7 +179,8 @@ func (s *Server) handleError(w http.ResponseWriter, err error) {
 		http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		return
 	case config.ErrDuplicatePerPathConfigPath, config.ErrInvalidPermissions,
-		config.ErrInvalidVersion, config.ErrUndefinedUsername:
+		config.ErrInvalidVersion, config.ErrUndefinedUsername,
+		config.ErrInvalidConfig:
 		http.Error(w, "invalid .kbp_config", http.StatusPreconditionFailed)
 		return
 	default:
@@ -251,6 +252,51 @@ func (s *Server) isDirWithNoIndexHTML(
 	}
 }
 
+func (s *Server) exists(realFS *libfs.FS, requestPath string) (bool, error) {
+	_, err := realFS.Stat(strings.Trim(path.Clean(requestPath), "/"))
+	switch {
+	case os.IsNotExist(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	default:
+		return true, nil
+	}
+}
+
+// checkPermissions checks whether the request can read requestPath, or list
+// it if it's a directory with no index.html, and writes an unauthorized
+// response if not.
+func (s *Server) checkPermissions(w http.ResponseWriter, r *http.Request,
+	cfg config.Config, realFS *libfs.FS, requestPath string,
+	username *string) (authorized bool, err error) {
+	canRead, canList, possibleRead, possibleList,
+		realm, err := cfg.GetPermissions(requestPath, username)
+	if err != nil {
+		return false, err
+	}
+
+	// Check if it's a directory containing no index.html before letting
+	// http.FileServer handle it.  This permission check should ideally
+	// happen inside the http package, but unfortunately there isn't a
+	// way today.
+	isListing, err := s.isDirWithNoIndexHTML(realFS, requestPath)
+	if err != nil {
+		return false, err
+	}
+
+	if isListing && !canList {
+		s.handleUnauthorized(w, r, realm, possibleList)
+		return false, nil
+	}
+
+	if !isListing && !canRead {
+		s.handleUnauthorized(w, r, realm, possibleRead)
+		return false, nil
+	}
+	return true, nil
+}
+
 // ServedRequestInfo holds information regarding to an incoming request
 // that might be useful for stats.
 type ServedRequestInfo struct {
@@ -417,40 +463,78 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		sri.Authenticated = true
 		username = &user
 	}
-	canRead, canList, possibleRead, possibleList,
-		realm, err := cfg.GetPermissions(r.URL.Path, username)
+	authorized, err := s.checkPermissions(
+		w, r, cfg, realFS, r.URL.Path, username)
 	if err != nil {
 		s.handleError(w, err)
 		return
 	}
+	if !authorized {
+		return
+	}
 
-	// Check if it's a directory containing no index.html before letting
-	// http.FileServer handle it.  This permission check should ideally
-	// happen inside the http package, but unfortunately there isn't a
-	// way today.
-	isListing, err := s.isDirWithNoIndexHTML(realFS, r.URL.Path)
+	redirect, status, err := cfg.GetRedirect(r.URL.Path)
 	if err != nil {
 		s.handleError(w, err)
 		return
 	}
+	if len(redirect) > 0 {
+		if len(r.URL.RawQuery) > 0 && !strings.Contains(redirect, "?") {
+			redirect += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, redirect, status)
+		return
+	}
 
-	if isListing && !canList {
-		s.handleUnauthorized(w, r, realm, possibleList)
+	accessControlAllowOrigin, err := cfg.GetAccessControlAllowOrigin(r.URL.Path)
+	if err != nil {
+		s.handleError(w, err)
 		return
 	}
+	if len(accessControlAllowOrigin) > 0 {
+		s.setAccessControlAllowOriginHeader(w, accessControlAllowOrigin)
+	}
 
-	if !isListing && !canRead {
-		s.handleUnauthorized(w, r, realm, possibleRead)
+	headers, err := cfg.GetCustomHeaders(r.URL.Path)
+	if err != nil {
+		s.handleError(w, err)
 		return
 	}
+	for name := range headers {
+		w.Header().Set(name, headers.Get(name))
+	}
 
-	accessControlAllowOrigin, err := cfg.GetAccessControlAllowOrigin(r.URL.Path)
+	rewrite, err := cfg.GetRewrite(r.URL.Path)
 	if err != nil {
 		s.handleError(w, err)
 		return
 	}
-	if len(accessControlAllowOrigin) > 0 {
-		s.setAccessControlAllowOriginHeader(w, accessControlAllowOrigin)
+	if len(rewrite) > 0 {
+		var exists bool
+		exists, err = s.exists(realFS, r.URL.Path)
+		if err != nil {
+			s.handleError(w, err)
+			return
+		}
+		if !exists {
+			// http.FileServer redirects requests for index.html to the
+			// directory containing it, which would undo the rewrite.
+			if path.Base(rewrite) == "index.html" {
+				rewrite = strings.TrimSuffix(rewrite, "index.html")
+			}
+			authorized, err = s.checkPermissions(
+				w, r, cfg, realFS, rewrite, username)
+			if err != nil {
+				s.handleError(w, err)
+				return
+			}
+			if !authorized {
+				return
+			}
+			r = r.Clone(r.Context())
+			r.URL.Path = rewrite
+			r.URL.RawPath = ""
+		}
 	}
 
 	http.FileServer(realFS.ToHTTPFileSystem(ctx)).ServeHTTP(w, r)
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
 	"testing"
 
 	lru "github.com/hashicorp/golang-lru"
@@ -16,6 +17,7 @@ import (
 	"github.com/adamwalz/keybase-client/go/kbfs/libcontext"
 	"github.com/adamwalz/keybase-client/go/kbfs/libfs"
 	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/libpages/config"
 	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
 	"github.com/adamwalz/keybase-client/go/kbfs/tlfhandle"
 	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
@@ -23,7 +25,8 @@ import (
 	"go.uber.org/zap"
 )
 
-func populateContent(t *testing.T, config libkbfs.Config) {
+func populateContent(
+	t *testing.T, config libkbfs.Config, files map[string]string) {
 	ctx := libcontext.BackgroundContextWithCancellationDelayer()
 	h, err := tlfhandle.ParseHandle(
 		ctx, config.KBPKI(), config.MDOps(), nil, "bot,user", tlf.Private)
@@ -41,11 +44,28 @@ func populateContent(t *testing.T, config libkbfs.Config) {
 	require.NoError(t, err)
 	err = f.Close()
 	require.NoError(t, err)
+	for p, content := range files {
+		err = fs.MkdirAll(path.Dir(p), 0600)
+		require.NoError(t, err)
+		f, err := fs.Create(p)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+		err = f.Close()
+		require.NoError(t, err)
+	}
 	err = fs.SyncAll()
 	require.NoError(t, err)
 }
 
 func makeTestKBFSConfig(t *testing.T) (
+	kbfsConfig libkbfs.Config, shutdown func()) {
+	return makeTestKBFSConfigWithFiles(t, nil)
+}
+
+// makeTestKBFSConfigWithFiles is like makeTestKBFSConfig, but also writes
+// files, a map of path -> content, into the site.
+func makeTestKBFSConfigWithFiles(t *testing.T, files map[string]string) (
 	kbfsConfig libkbfs.Config, shutdown func()) {
 	// This env is needed for the regression test for HOTPOT-2207.
 	oldEnv := os.Getenv(libkbfs.EnvKeybaseTestObfuscateLogsForTest)
@@ -55,7 +75,7 @@ func makeTestKBFSConfig(t *testing.T) (
 	cfg := libkbfs.MakeTestConfigOrBustLoggedInWithMode(
 		t, 0, libkbfs.InitSingleOp, "bot", "user")
 
-	populateContent(t, cfg)
+	populateContent(t, cfg, files)
 
 	tempdir, err := ioutil.TempDir(os.TempDir(), "journal_server")
 	require.NoError(t, err)
@@ -122,3 +142,63 @@ func TestServerDefault(t *testing.T) {
 	// TODO: if we ever add a test that involves bcrypt, remember to swap
 	// DefaultCost out and use MinCost.
 }
+
+func TestServerRules(t *testing.T) {
+	kbfsConfig, shutdown := makeTestKBFSConfigWithFiles(t, map[string]string{
+		"/app/index.html": "app",
+		"/app/style.css":  "css",
+		config.DefaultConfigFilepath: `{
+  "version": "v2",
+  "per_path_configs": {
+    "/": {
+      "anonymous_permissions": "read",
+      "redirects": [
+        {"from": "/old/*", "to": "/dir/:splat", "status": 302}
+      ]
+    },
+    "/app": {
+      "anonymous_permissions": "read",
+      "rewrites": [{"from": "/app/*", "to": "/app/index.html"}],
+      "headers": {"Cache-Control": "no-cache"}
+    }
+  }
+}`,
+	})
+	defer shutdown()
+
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+	server := Server{
+		kbfsConfig: kbfsConfig,
+		config: &ServerConfig{
+			Logger: logger,
+		},
+		rootLoader: TestRootLoader{
+			"example.com": "/keybase/private/user,bot",
+		},
+	}
+	server.siteCache, err = lru.NewWithEvict(fsCacheSize, server.siteCacheEvict)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/old/file?a=b", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "/dir/file?a=b", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/app/some/route", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "app", w.Body.String())
+	require.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/app/style.css", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "css", w.Body.String())
+	require.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/non-existent", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, "", w.Header().Get("Cache-Control"))
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libs3gateway
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/chat/s3"
+)
+
+// maxRequestSkew is how far the date of a signed request may be from
+// our clock, which limits how long a captured request can be
+// replayed.
+const maxRequestSkew = 15 * time.Minute
+
+// dateFormats are the formats clients use for the request date.
+// go/chat/s3 uses RFC 1123 with a "UTC" zone, which the HTTP date
+// parser doesn't accept.
+var dateFormats = []string{
+	http.TimeFormat,
+	time.RFC1123,
+	time.RFC1123Z,
+	"20060102T150405Z",
+}
+
+func parseDate(date string) (t time.Time, err error) {
+	for _, layout := range dateFormats {
+		t, err = time.Parse(layout, date)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// checkAuth verifies that the request was signed with our secret key,
+// either in the Authorization header or, for pre-signed URLs, in the
+// query parameters.
+func (s *Server) checkAuth(req *http.Request) error {
+	now := s.config.Clock().Now()
+	query := req.URL.Query()
+	var accessKey, signature string
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		if !strings.HasPrefix(auth, "AWS ") {
+			return errUnsupportedSignature
+		}
+		creds := strings.TrimPrefix(auth, "AWS ")
+		i := strings.LastIndex(creds, ":")
+		if i < 0 {
+			return errAccessDenied
+		}
+		accessKey, signature = creds[:i], creds[i+1:]
+
+		date := req.Header.Get("X-Amz-Date")
+		if date == "" {
+			date = req.Header.Get("Date")
+		}
+		t, err := parseDate(date)
+		if err != nil {
+			return errAccessDenied
+		}
+		if skew := now.Sub(t); skew > maxRequestSkew || skew < -maxRequestSkew {
+			return errRequestTimeTooSkewed
+		}
+	} else if sig := query.Get("Signature"); sig != "" {
+		accessKey, signature = query.Get("AWSAccessKeyId"), sig
+		expires, err := strconv.ParseInt(query.Get("Expires"), 10, 64)
+		if err != nil {
+			return errAccessDenied
+		}
+		if now.Unix() > expires {
+			return errRequestExpired
+		}
+	} else {
+		return errAccessDenied
+	}
+
+	if subtle.ConstantTimeCompare(
+		[]byte(accessKey), []byte(s.accessKey)) != 1 {
+		return errInvalidAccessKeyID
+	}
+
+	// Clients differ in how they escape the path they sign, so try
+	// both the path exactly as sent and the way go/chat/s3 escapes
+	// it.
+	signer := s3.HMACSigner{SecretKey: []byte(s.secretKey)}
+	paths := []string{
+		req.URL.EscapedPath(),
+		(&url.URL{Path: req.URL.Path}).String(),
+	}
+	for _, p := range paths {
+		payload := s3.StringToSign(req.Method, p, query, req.Header)
+		expected, err := signer.Sign([]byte(payload))
+		if err != nil {
+			return err
+		}
+		if hmac.Equal(expected, []byte(signature)) {
+			return nil
+		}
+	}
+	return errSignatureDoesNotMatch
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libs3gateway
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/chat/s3"
+	"github.com/adamwalz/keybase-client/go/kbfs/data"
+	"github.com/adamwalz/keybase-client/go/kbfs/libfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+)
+
+const fsCacheSize = 64
+
+// s3TimeFormat is the format S3 uses for timestamps in XML bodies.
+const s3TimeFormat = "2006-01-02T15:04:05.000Z"
+
+// Bucket maps an S3 bucket to a directory in KBFS.
+type Bucket struct {
+	Name    string
+	TlfType tlf.Type
+	TlfName string
+	// Subdir is the path of the bucket's directory within the TLF,
+	// or "" for the root of the TLF.  It must already exist.
+	Subdir string
+}
+
+func (b Bucket) owner() s3.Owner {
+	return s3.Owner{ID: b.TlfName, DisplayName: b.TlfName}
+}
+
+type obsoleteTrackingFS struct {
+	fs *libfs.FS
+	ch <-chan struct{}
+}
+
+func (e obsoleteTrackingFS) isObsolete() bool {
+	select {
+	case <-e.ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) getBucketFS(ctx context.Context, b Bucket) (
+	*libfs.FS, error) {
+	if fsCached, ok := s.fs.Get(b.Name); ok {
+		if fsCachedTyped, ok := fsCached.(obsoleteTrackingFS); ok {
+			if !fsCachedTyped.isObsolete() {
+				return fsCachedTyped.fs.WithContext(ctx), nil
+			}
+		}
+	}
+
+	tlfHandle, err := libkbfs.GetHandleFromFolderNameAndType(ctx,
+		s.config.KBPKI(), s.config.MDOps(), s.config, b.TlfName, b.TlfType)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketFS, err := libfs.NewFS(ctx,
+		s.config, tlfHandle, data.MasterBranch, b.Subdir, "",
+		keybase1.MDPriorityNormal)
+	if err != nil {
+		return nil, err
+	}
+
+	fsLifeCh, err := bucketFS.SubscribeToObsolete()
+	if err != nil {
+		return nil, err
+	}
+
+	s.fs.Add(b.Name, obsoleteTrackingFS{fs: bucketFS, ch: fsLifeCh})
+	return bucketFS, nil
+}
+
+// checkKey makes sure an object key maps cleanly to a path within the
+// bucket directory.  A trailing slash is allowed, and refers to a
+// directory.
+func checkKey(key string) error {
+	p := strings.TrimSuffix(key, "/")
+	if p == "" || strings.HasPrefix(p, "/") || path.Clean(p) != p ||
+		p == ".." || strings.HasPrefix(p, "../") {
+		return errInvalidKey
+	}
+	for _, part := range strings.Split(p, "/") {
+		if strings.HasPrefix(part, ".kbfs") {
+			return errInvalidKey
+		}
+	}
+	return nil
+}
+
+type bucketInfo struct {
+	Name         string
+	CreationDate string
+}
+
+type listAllMyBucketsResult struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult"`
+	Owner   s3.Owner
+	Buckets []bucketInfo `xml:"Buckets>Bucket"`
+}
+
+func (s *Server) listBuckets(w http.ResponseWriter) error {
+	var res listAllMyBucketsResult
+	for name := range s.buckets {
+		res.Buckets = append(res.Buckets, bucketInfo{
+			Name:         name,
+			CreationDate: formatTime(s.started),
+		})
+	}
+	sort.Slice(res.Buckets, func(i, j int) bool {
+		return res.Buckets[i].Name < res.Buckets[j].Name
+	})
+	writeXML(w, http.StatusOK, res)
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(s3TimeFormat)
+}
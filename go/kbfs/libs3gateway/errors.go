@@ -0,0 +1,146 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libs3gateway
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/pkg/errors"
+)
+
+// Error is an error that's reported to S3 clients with the given
+// HTTP status and S3 error code.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// Error implements the error interface for Error.
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	errAccessDenied = Error{
+		http.StatusForbidden, "AccessDenied", "Access Denied"}
+	errBadDigest = Error{
+		http.StatusBadRequest, "BadDigest",
+		"The Content-MD5 you specified did not match what we received."}
+	errInvalidAccessKeyID = Error{
+		http.StatusForbidden, "InvalidAccessKeyId",
+		"The access key ID you provided does not exist in our records."}
+	errInvalidKey = Error{
+		http.StatusBadRequest, "InvalidArgument",
+		"Object keys must be clean relative paths that don't use " +
+			"reserved KBFS names."}
+	errInvalidPart = Error{
+		http.StatusBadRequest, "InvalidPart",
+		"One or more of the specified parts could not be found."}
+	errInvalidPartOrder = Error{
+		http.StatusBadRequest, "InvalidPartOrder",
+		"The list of parts was not in ascending order."}
+	errMalformedXML = Error{
+		http.StatusBadRequest, "MalformedXML",
+		"The XML you provided was not well-formed."}
+	errMethodNotAllowed = Error{
+		http.StatusMethodNotAllowed, "MethodNotAllowed",
+		"The specified method is not allowed against this resource."}
+	errNoSuchBucket = Error{
+		http.StatusNotFound, "NoSuchBucket",
+		"The specified bucket does not exist."}
+	errNoSuchKey = Error{
+		http.StatusNotFound, "NoSuchKey",
+		"The specified key does not exist."}
+	errNoSuchUpload = Error{
+		http.StatusNotFound, "NoSuchUpload",
+		"The specified multipart upload does not exist."}
+	errNotImplemented = Error{
+		http.StatusNotImplemented, "NotImplemented",
+		"This operation is not supported by the KBFS S3 gateway."}
+	errRequestExpired = Error{
+		http.StatusForbidden, "AccessDenied", "Request has expired"}
+	errRequestTimeTooSkewed = Error{
+		http.StatusForbidden, "RequestTimeTooSkewed",
+		"The difference between the request time and the current " +
+			"time is too large."}
+	errSignatureDoesNotMatch = Error{
+		http.StatusForbidden, "SignatureDoesNotMatch",
+		"The request signature we calculated does not match the " +
+			"signature you provided."}
+	errUnsupportedSignature = Error{
+		http.StatusBadRequest, "InvalidRequest",
+		"Only AWS signature version 2 is supported."}
+)
+
+func invalidArgumentError(msg string) Error {
+	return Error{http.StatusBadRequest, "InvalidArgument", msg}
+}
+
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string
+	Message  string
+	Resource string
+}
+
+// toS3Error converts a KBFS error into the error that should be
+// reported to the client.
+func toS3Error(err error) (Error, bool) {
+	switch e := errors.Cause(err).(type) {
+	case Error:
+		return e, true
+	}
+	switch {
+	case os.IsNotExist(err):
+		return errNoSuchKey, true
+	case os.IsPermission(err):
+		return errAccessDenied, true
+	default:
+		return Error{
+			http.StatusInternalServerError, "InternalError",
+			"We encountered an internal error. Please try again."}, false
+	}
+}
+
+func (s *Server) writeError(ctx context.Context,
+	w http.ResponseWriter, req *http.Request, err error) {
+	s3Err, expected := toS3Error(err)
+	if !expected {
+		s.logger.CWarningf(ctx, "%s %s failed: %+v",
+			req.Method, req.URL.Path, err)
+	} else {
+		s.vlog.CLogf(ctx, libkb.VLog1, "%s %s failed: %+v",
+			req.Method, req.URL.Path, err)
+	}
+
+	// HEAD responses can't have a body.
+	if req.Method == http.MethodHead {
+		w.WriteHeader(s3Err.StatusCode)
+		return
+	}
+	writeXML(w, s3Err.StatusCode, errorResponse{
+		Code:     s3Err.Code,
+		Message:  s3Err.Message,
+		Resource: req.URL.Path,
+	})
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	buf, err := xml.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(buf)
+}
@@ -0,0 +1,281 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libs3gateway
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adamwalz/keybase-client/go/chat/s3"
+	"github.com/adamwalz/keybase-client/go/kbfs/libfs"
+	"github.com/pkg/errors"
+)
+
+// maxListKeys is the most keys S3 returns in one page of a listing.
+const maxListKeys = 1000
+
+type commonPrefix struct {
+	Prefix string
+}
+
+// listBucketResult is the response to both versions of the
+// ListObjects API.  The V2-only fields are left out of V1 responses,
+// and vice versa.
+type listBucketResult struct {
+	XMLName      xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name         string
+	Prefix       string
+	Delimiter    string `xml:",omitempty"`
+	EncodingType string `xml:",omitempty"`
+	MaxKeys      int
+	IsTruncated  bool
+
+	Marker     *string `xml:",omitempty"`
+	NextMarker string  `xml:",omitempty"`
+
+	KeyCount              *int   `xml:",omitempty"`
+	StartAfter            string `xml:",omitempty"`
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+
+	Contents       []s3.Key
+	CommonPrefixes []commonPrefix
+}
+
+var errListFull = errors.New("Listing is full")
+
+// lister walks the directory tree of a bucket in key order, and
+// collects one page of keys and common prefixes.
+//
+// Directories aren't objects in S3, so only regular files are listed,
+// except that an empty directory is listed as a key ending in a
+// slash, the way S3 consoles represent folders.
+type lister struct {
+	fs        *libfs.FS
+	owner     s3.Owner
+	prefix    string
+	delimiter string
+	marker    string
+	maxKeys   int
+
+	contents   []s3.Key
+	prefixes   []commonPrefix
+	lastPrefix string
+	// next is the last key or common prefix added to the page.
+	next      string
+	truncated bool
+}
+
+func (l *lister) commonPrefix(key string) string {
+	if l.delimiter == "" || !strings.HasPrefix(key, l.prefix) {
+		return ""
+	}
+	rest := key[len(l.prefix):]
+	i := strings.Index(rest, l.delimiter)
+	if i < 0 {
+		return ""
+	}
+	return l.prefix + rest[:i+len(l.delimiter)]
+}
+
+// add adds the key to the page, unless it comes before the marker or
+// it rolls up into a common prefix that's already been added.
+func (l *lister) add(key string, fi os.FileInfo) error {
+	cp := l.commonPrefix(key)
+	if key <= l.marker || (cp != "" && cp <= l.marker) ||
+		(cp != "" && cp == l.lastPrefix) {
+		return nil
+	}
+	if len(l.contents)+len(l.prefixes) >= l.maxKeys {
+		l.truncated = true
+		return errListFull
+	}
+
+	if cp != "" {
+		l.prefixes = append(l.prefixes, commonPrefix{cp})
+		l.lastPrefix = cp
+		l.next = cp
+		return nil
+	}
+
+	etag := emptyETag
+	var size int64
+	if !fi.IsDir() {
+		var err error
+		etag, err = getETag(l.fs, key, fi)
+		if err != nil {
+			return err
+		}
+		size = fi.Size()
+	}
+	l.contents = append(l.contents, s3.Key{
+		Key:          key,
+		LastModified: formatTime(fi.ModTime()),
+		Size:         size,
+		ETag:         etag,
+		StorageClass: "STANDARD",
+		Owner:        l.owner,
+	})
+	l.next = key
+	return nil
+}
+
+// sortName returns the name that puts directory entries in the same
+// order as the keys within them.  A directory sorts as if its name
+// ends with a slash, since all the keys within it do.
+func sortName(fi os.FileInfo) string {
+	if fi.IsDir() {
+		return fi.Name() + "/"
+	}
+	return fi.Name()
+}
+
+func (l *lister) walk(dir string) error {
+	fis, err := l.fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(fis) == 0 && dir != "" {
+		fi, err := l.fs.Stat(dir)
+		if err != nil {
+			return err
+		}
+		return l.add(dir+"/", fi)
+	}
+
+	sort.Slice(fis, func(i, j int) bool {
+		return sortName(fis[i]) < sortName(fis[j])
+	})
+	for _, fi := range fis {
+		if strings.HasPrefix(fi.Name(), ".kbfs") {
+			continue
+		}
+		key := path.Join(dir, fi.Name())
+		switch {
+		case fi.IsDir():
+			dirKey := key + "/"
+			if !strings.HasPrefix(dirKey, l.prefix) &&
+				!strings.HasPrefix(l.prefix, dirKey) {
+				continue
+			}
+			if l.marker > dirKey && !strings.HasPrefix(l.marker, dirKey) {
+				// Every key in this directory comes before the marker.
+				continue
+			}
+			if l.commonPrefix(dirKey) == dirKey {
+				// Everything in the directory rolls up into the
+				// same common prefix, so there's no need to look
+				// inside.
+				err = l.add(dirKey, fi)
+			} else {
+				err = l.walk(key)
+			}
+		case fi.Mode().IsRegular():
+			if !strings.HasPrefix(key, l.prefix) {
+				continue
+			}
+			err = l.add(key, fi)
+		default:
+			// Symlinks have no S3 equivalent.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) listObjects(
+	w http.ResponseWriter, req *http.Request, b Bucket,
+	fs *libfs.FS) error {
+	query := req.URL.Query()
+	maxKeys := maxListKeys
+	if v := query.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return invalidArgumentError("Invalid max-keys")
+		}
+		if n < maxKeys {
+			maxKeys = n
+		}
+	}
+
+	l := &lister{
+		fs:        fs,
+		owner:     b.owner(),
+		prefix:    query.Get("prefix"),
+		delimiter: query.Get("delimiter"),
+		maxKeys:   maxKeys,
+	}
+	isV2 := query.Get("list-type") == "2"
+	if isV2 {
+		l.marker = query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			marker, err := base64.RawURLEncoding.DecodeString(token)
+			if err != nil {
+				return invalidArgumentError(
+					"The continuation token provided is incorrect")
+			}
+			l.marker = string(marker)
+		}
+	} else {
+		l.marker = query.Get("marker")
+	}
+
+	err := l.walk("")
+	if err != nil && err != errListFull {
+		return err
+	}
+
+	// Clients ask for URL encoding so that keys can contain
+	// characters that XML can't.
+	encode := func(s string) string { return s }
+	if query.Get("encoding-type") == "url" {
+		encode = url.QueryEscape
+	}
+	for i := range l.contents {
+		l.contents[i].Key = encode(l.contents[i].Key)
+	}
+	for i := range l.prefixes {
+		l.prefixes[i].Prefix = encode(l.prefixes[i].Prefix)
+	}
+
+	res := listBucketResult{
+		Name:           b.Name,
+		Prefix:         encode(l.prefix),
+		Delimiter:      encode(l.delimiter),
+		EncodingType:   query.Get("encoding-type"),
+		MaxKeys:        maxKeys,
+		IsTruncated:    l.truncated,
+		Contents:       l.contents,
+		CommonPrefixes: l.prefixes,
+	}
+	if isV2 {
+		keyCount := len(l.contents) + len(l.prefixes)
+		res.KeyCount = &keyCount
+		res.StartAfter = encode(query.Get("start-after"))
+		res.ContinuationToken = query.Get("continuation-token")
+		if l.truncated {
+			res.NextContinuationToken =
+				base64.RawURLEncoding.EncodeToString([]byte(l.next))
+		}
+	} else {
+		marker := encode(l.marker)
+		res.Marker = &marker
+		if l.truncated {
+			res.NextMarker = encode(l.next)
+		}
+	}
+	writeXML(w, http.StatusOK, res)
+	return nil
+}
@@ -0,0 +1,349 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libs3gateway
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adamwalz/keybase-client/go/kbfs/libfs"
+	"github.com/pkg/errors"
+)
+
+const (
+	uploadIDByteSize = 16
+	maxPartNumber    = 10000
+	// uploadKeyFile, in each upload's directory, holds the key of
+	// the object being uploaded.
+	uploadKeyFile = "key"
+)
+
+// A multipart upload lives in its own directory under uploadsDir,
+// which holds the key being uploaded, and each part in a file named
+// by its part number.
+func uploadDir(uploadID string) string {
+	return path.Join(uploadsDir, uploadID)
+}
+
+func partFile(uploadID string, partNumber int) string {
+	return path.Join(uploadDir(uploadID), strconv.Itoa(partNumber))
+}
+
+// checkUpload makes sure the upload exists, and is for the given key.
+func checkUpload(fs *libfs.FS, key, uploadID string) error {
+	if uploadID == "" || strings.ContainsAny(uploadID, "/.") {
+		return errNoSuchUpload
+	}
+	f, err := fs.Open(path.Join(uploadDir(uploadID), uploadKeyFile))
+	if os.IsNotExist(err) {
+		return errNoSuchUpload
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	uploadKey, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if string(uploadKey) != key {
+		return errNoSuchUpload
+	}
+	return nil
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ InitiateMultipartUploadResult"`
+	Bucket   string
+	Key      string
+	UploadID string `xml:"UploadId"`
+}
+
+func (s *Server) initiateMultipartUpload(
+	w http.ResponseWriter, b Bucket, fs *libfs.FS, key string) error {
+	if strings.HasSuffix(key, "/") {
+		return errInvalidKey
+	}
+	buf := make([]byte, uploadIDByteSize)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	uploadID := hex.EncodeToString(buf)
+
+	f, err := fs.OpenFile(
+		path.Join(uploadDir(uploadID), uploadKeyFile),
+		os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write([]byte(key))
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	if err := fs.SyncAll(); err != nil {
+		return err
+	}
+
+	writeXML(w, http.StatusOK, initiateMultipartUploadResult{
+		Bucket:   b.Name,
+		Key:      key,
+		UploadID: uploadID,
+	})
+	return nil
+}
+
+func parsePartNumber(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > maxPartNumber {
+		return 0, invalidArgumentError(fmt.Sprintf(
+			"Part number must be an integer between 1 and %d, inclusive",
+			maxPartNumber))
+	}
+	return n, nil
+}
+
+func (s *Server) uploadPart(
+	w http.ResponseWriter, req *http.Request, fs *libfs.FS,
+	key, uploadID string) error {
+	partNumber, err := parsePartNumber(req.URL.Query().Get("partNumber"))
+	if err != nil {
+		return err
+	}
+	if err := checkUpload(fs, key, uploadID); err != nil {
+		return err
+	}
+
+	tmpName, sum, err := writeFile(
+		fs, uploadDir(uploadID), req.Body, req.Header.Get("Content-MD5"))
+	if err != nil {
+		return err
+	}
+	etag := quoteETag(sum)
+	if err := commitFile(
+		fs, tmpName, partFile(uploadID, partNumber), etag); err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+type completeMultipartUpload struct {
+	Parts []completedPart `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUploadResult"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
+// multipartETag returns the ETag S3 gives to an object uploaded in
+// parts: the MD5 of the concatenated MD5s of the parts, and the
+// number of parts.
+func multipartETag(partETags []string) (string, error) {
+	h := md5.New()
+	for _, etag := range partETags {
+		sum, err := hex.DecodeString(strings.Trim(etag, `"`))
+		if err != nil {
+			return "", err
+		}
+		_, _ = h.Write(sum)
+	}
+	return fmt.Sprintf(`"%s-%d"`,
+		hex.EncodeToString(h.Sum(nil)), len(partETags)), nil
+}
+
+func removeUpload(
+	ctx context.Context, fs *libfs.FS, uploadID string) error {
+	fi, err := fs.Stat(uploadDir(uploadID))
+	if err != nil {
+		return err
+	}
+	parentFS, err := fs.Chroot(uploadsDir)
+	if err != nil {
+		return err
+	}
+	return libfs.RecursiveDelete(ctx, parentFS, fi)
+}
+
+func (s *Server) completeMultipartUpload(
+	w http.ResponseWriter, req *http.Request, b Bucket, fs *libfs.FS,
+	key, uploadID string) error {
+	if err := checkUpload(fs, key, uploadID); err != nil {
+		return err
+	}
+	var cmu completeMultipartUpload
+	if err := xml.NewDecoder(req.Body).Decode(&cmu); err != nil {
+		return errors.WithStack(errMalformedXML)
+	}
+	if len(cmu.Parts) == 0 {
+		return errors.WithStack(errMalformedXML)
+	}
+
+	partETags := make([]string, 0, len(cmu.Parts))
+	for i, part := range cmu.Parts {
+		if i > 0 && part.PartNumber <= cmu.Parts[i-1].PartNumber {
+			return errInvalidPartOrder
+		}
+		p := partFile(uploadID, part.PartNumber)
+		fi, err := fs.Stat(p)
+		if os.IsNotExist(err) {
+			return errInvalidPart
+		} else if err != nil {
+			return err
+		}
+		etag, err := getETag(fs, p, fi)
+		if err != nil {
+			return err
+		}
+		if strings.Trim(part.ETag, `"`) != strings.Trim(etag, `"`) {
+			return errInvalidPart
+		}
+		partETags = append(partETags, etag)
+	}
+	etag, err := multipartETag(partETags)
+	if err != nil {
+		return err
+	}
+
+	f, err := fs.TempFile(uploadDir(uploadID), "object-")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	for _, part := range cmu.Parts {
+		err = func() error {
+			pf, err := fs.Open(partFile(uploadID, part.PartNumber))
+			if err != nil {
+				return err
+			}
+			defer pf.Close()
+			_, err = io.Copy(f, pf)
+			return err
+		}()
+		if err != nil {
+			break
+		}
+	}
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if err := commitFile(fs, tmpName, key, etag); err != nil {
+		return err
+	}
+	if err := removeUpload(req.Context(), fs, uploadID); err != nil {
+		return err
+	}
+	if err := fs.SyncAll(); err != nil {
+		return err
+	}
+
+	writeXML(w, http.StatusOK, completeMultipartUploadResult{
+		Location: "/" + path.Join(b.Name, key),
+		Bucket:   b.Name,
+		Key:      key,
+		ETag:     etag,
+	})
+	return nil
+}
+
+func (s *Server) abortMultipartUpload(
+	ctx context.Context, w http.ResponseWriter, fs *libfs.FS,
+	key, uploadID string) error {
+	if err := checkUpload(fs, key, uploadID); err != nil {
+		return err
+	}
+	if err := removeUpload(ctx, fs, uploadID); err != nil {
+		return err
+	}
+	if err := fs.SyncAll(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type partInfo struct {
+	PartNumber   int
+	LastModified string
+	ETag         string
+	Size         int64
+}
+
+type listPartsResult struct {
+	XMLName     xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListPartsResult"`
+	Bucket      string
+	Key         string
+	UploadID    string `xml:"UploadId"`
+	IsTruncated bool
+	Parts       []partInfo `xml:"Part"`
+}
+
+func (s *Server) listParts(
+	w http.ResponseWriter, b Bucket, fs *libfs.FS, key,
+	uploadID string) error {
+	if err := checkUpload(fs, key, uploadID); err != nil {
+		return err
+	}
+	fis, err := fs.ReadDir(uploadDir(uploadID))
+	if err != nil {
+		return err
+	}
+
+	res := listPartsResult{
+		Bucket:   b.Name,
+		Key:      key,
+		UploadID: uploadID,
+	}
+	for _, fi := range fis {
+		partNumber, err := strconv.Atoi(fi.Name())
+		if err != nil {
+			// The key file, or a part that's still being written.
+			continue
+		}
+		etag, err := getETag(fs, partFile(uploadID, partNumber), fi)
+		if err != nil {
+			return err
+		}
+		res.Parts = append(res.Parts, partInfo{
+			PartNumber:   partNumber,
+			LastModified: formatTime(fi.ModTime()),
+			ETag:         etag,
+			Size:         fi.Size(),
+		})
+	}
+	sort.Slice(res.Parts, func(i, j int) bool {
+		return res.Parts[i].PartNumber < res.Parts[j].PartNumber
+	})
+	writeXML(w, http.StatusOK, res)
+	return nil
+}
@@ -0,0 +1,320 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libs3gateway
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/adamwalz/keybase-client/go/kbfs/libfs"
+	"github.com/pkg/errors"
+)
+
+const (
+	// uploadsDir, at the root of each bucket, holds in-progress
+	// PUTs and multipart uploads, so that objects only appear under
+	// their key once they're complete.
+	uploadsDir = ".kbfs_s3_uploads"
+
+	// etagXattr caches the ETag of an object, along with the mtime
+	// of the file when the ETag was computed.
+	etagXattr = "user.s3.etag"
+)
+
+// emptyETag is the ETag of an empty object.
+var emptyETag = quoteETag(md5.New().Sum(nil))
+
+func quoteETag(sum []byte) string {
+	return `"` + hex.EncodeToString(sum) + `"`
+}
+
+func etagXattrValue(fi os.FileInfo, etag string) []byte {
+	return []byte(fmt.Sprintf("%d:%s", fi.ModTime().UnixNano(), etag))
+}
+
+// getETag returns the ETag of the file at `p`.  If the file wasn't
+// written through the gateway, or has been changed since, that
+// requires reading the whole file.
+func getETag(fs *libfs.FS, p string, fi os.FileInfo) (string, error) {
+	xattrs, err := fs.Xattrs(p)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := xattrs[etagXattr]; ok {
+		parts := strings.SplitN(string(v), ":", 2)
+		if len(parts) == 2 &&
+			parts[0] == strconv.FormatInt(fi.ModTime().UnixNano(), 10) {
+			return parts[1], nil
+		}
+	}
+
+	f, err := fs.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return quoteETag(h.Sum(nil)), nil
+}
+
+// writeFile writes the contents of `r` to a temporary file in
+// `tmpDir`, and returns its name and the MD5 sum of the contents.  If
+// `contentMD5` is set, it must match the contents.
+func writeFile(fs *libfs.FS, tmpDir string, r io.Reader, contentMD5 string) (
+	tmpName string, sum []byte, err error) {
+	f, err := fs.TempFile(tmpDir, "put-")
+	if err != nil {
+		return "", nil, err
+	}
+	tmpName = f.Name()
+	defer func() {
+		if err != nil {
+			_ = fs.Remove(tmpName)
+		}
+	}()
+
+	h := md5.New()
+	_, err = io.Copy(f, io.TeeReader(r, h))
+	closeErr := f.Close()
+	if err != nil {
+		return "", nil, err
+	}
+	if closeErr != nil {
+		return "", nil, closeErr
+	}
+
+	sum = h.Sum(nil)
+	if contentMD5 != "" && contentMD5 != base64.StdEncoding.EncodeToString(sum) {
+		return "", nil, errBadDigest
+	}
+	return tmpName, sum, nil
+}
+
+// commitFile records the ETag of a completely-written temporary file,
+// moves it into place, and flushes it to the journal.  The ETag is
+// set before the rename, so that a failed commit never leaves an
+// object without one under `p`; in that case the temporary file is
+// removed instead.
+func commitFile(fs *libfs.FS, tmpName, p, etag string) (err error) {
+	defer func() {
+		if err != nil {
+			_ = fs.Remove(tmpName)
+		}
+	}()
+
+	fi, err := fs.Stat(tmpName)
+	if err != nil {
+		return err
+	}
+	err = fs.SetXattr(tmpName, etagXattr, etagXattrValue(fi, etag))
+	if err != nil {
+		return err
+	}
+	if err := fs.Rename(tmpName, p); err != nil {
+		return err
+	}
+	return fs.SyncAll()
+}
+
+func (s *Server) getObject(
+	w http.ResponseWriter, req *http.Request, fs *libfs.FS,
+	key string) error {
+	p := strings.TrimSuffix(key, "/")
+	fi, err := fs.Stat(p)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(key, "/") {
+		if !fi.IsDir() {
+			return errNoSuchKey
+		}
+		w.Header().Set("ETag", emptyETag)
+		w.Header().Set("Last-Modified", fi.ModTime().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	if !fi.Mode().IsRegular() {
+		return errNoSuchKey
+	}
+
+	etag, err := getETag(fs, p, fi)
+	if err != nil {
+		return err
+	}
+	f, err := fs.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w.Header().Set("ETag", etag)
+	// ServeContent takes care of HEAD requests, ranges and
+	// conditional requests.
+	http.ServeContent(w, req, path.Base(p), fi.ModTime(), f)
+	return nil
+}
+
+func (s *Server) putObject(
+	w http.ResponseWriter, req *http.Request, fs *libfs.FS,
+	key string) error {
+	if req.Header.Get("X-Amz-Copy-Source") != "" {
+		return errNotImplemented
+	}
+
+	if strings.HasSuffix(key, "/") {
+		// A folder.
+		if req.ContentLength > 0 {
+			return invalidArgumentError(
+				"Keys ending in a slash can't have any content")
+		}
+		if err := fs.MkdirAll(strings.TrimSuffix(key, "/"), 0755); err != nil {
+			return err
+		}
+		if err := fs.SyncAll(); err != nil {
+			return err
+		}
+		w.Header().Set("ETag", emptyETag)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	tmpName, sum, err := writeFile(
+		fs, uploadsDir, req.Body, req.Header.Get("Content-MD5"))
+	if err != nil {
+		return err
+	}
+	etag := quoteETag(sum)
+	if err := commitFile(fs, tmpName, key, etag); err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// removeObject removes the file or empty directory for the key, and
+// then any directories that are left empty, since S3 has no notion
+// of directories.  It's not an error if the object doesn't exist.
+func removeObject(fs *libfs.FS, key string) error {
+	p := strings.TrimSuffix(key, "/")
+	fi, err := fs.Lstat(p)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	case fi.IsDir() != strings.HasSuffix(key, "/"):
+		return nil
+	}
+
+	if fi.IsDir() {
+		fis, err := fs.ReadDir(p)
+		if err != nil {
+			return err
+		}
+		if len(fis) > 0 {
+			// Other objects still live under this prefix.
+			return nil
+		}
+	}
+	if err := fs.Remove(p); err != nil {
+		return err
+	}
+
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		fis, err := fs.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(fis) > 0 {
+			break
+		}
+		if err := fs.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) deleteObject(
+	w http.ResponseWriter, fs *libfs.FS, key string) error {
+	if err := removeObject(fs, key); err != nil {
+		return err
+	}
+	if err := fs.SyncAll(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type deleteRequest struct {
+	Quiet   bool
+	Objects []struct {
+		Key string
+	} `xml:"Object"`
+}
+
+type deletedObject struct {
+	Key string
+}
+
+type deleteError struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+type deleteResult struct {
+	XMLName xml.Name        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ DeleteResult"`
+	Deleted []deletedObject `xml:"Deleted"`
+	Errors  []deleteError   `xml:"Error"`
+}
+
+func (s *Server) deleteObjects(
+	w http.ResponseWriter, req *http.Request, fs *libfs.FS) error {
+	var dr deleteRequest
+	if err := xml.NewDecoder(req.Body).Decode(&dr); err != nil {
+		return errors.WithStack(errMalformedXML)
+	}
+
+	var res deleteResult
+	for _, o := range dr.Objects {
+		err := checkKey(o.Key)
+		if err == nil {
+			err = removeObject(fs, o.Key)
+		}
+		if err != nil {
+			s3Err, _ := toS3Error(err)
+			res.Errors = append(res.Errors, deleteError{
+				Key:     o.Key,
+				Code:    s3Err.Code,
+				Message: s3Err.Message,
+			})
+			continue
+		}
+		if !dr.Quiet {
+			res.Deleted = append(res.Deleted, deletedObject{o.Key})
+		}
+	}
+	if err := fs.SyncAll(); err != nil {
+		return err
+	}
+	writeXML(w, http.StatusOK, res)
+	return nil
+}
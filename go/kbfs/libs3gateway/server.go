@@ -0,0 +1,268 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libs3gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/kbfs/libcontext"
+	"github.com/adamwalz/keybase-client/go/kbfs/libhttpserver"
+	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/kbhttp"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/logger"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Debug tag ID for an individual S3 request.
+	ctxS3OpID = "S3ID"
+)
+
+type ctxS3TagKey int
+
+const (
+	ctxS3IDKey ctxS3TagKey = iota
+)
+
+const accessKeyByteSize = 10
+
+// Bucket names follow the S3 rules for DNS-compatible names.
+var bucketNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// Server is a local HTTP server that speaks a subset of the S3 API,
+// so that existing S3 clients can read and write KBFS.  Each bucket
+// maps to a directory in a TLF, and object keys are paths relative
+// to that directory.
+//
+// Requests are authenticated with version 2 of the AWS signature
+// scheme (see go/chat/s3), using an access key and secret key that
+// are generated when the server starts.  Only path-style bucket
+// addressing is supported.
+type Server struct {
+	config    libkbfs.Config
+	logger    logger.Logger
+	vlog      *libkb.VDebugLog
+	accessKey string
+	secretKey string
+	buckets   map[string]Bucket
+	started   time.Time
+
+	fs     *lru.Cache
+	server *kbhttp.Srv
+}
+
+// New creates and starts a new S3 gateway serving the given buckets,
+// listening on the given port on the loopback interface, or on an
+// automatically-picked port if `port` is 0.
+func New(config libkbfs.Config, port int, buckets []Bucket) (
+	s *Server, err error) {
+	if len(buckets) == 0 {
+		return nil, errors.New("At least one bucket is required")
+	}
+	bucketMap := make(map[string]Bucket, len(buckets))
+	for _, b := range buckets {
+		if !bucketNameRegexp.MatchString(b.Name) {
+			return nil, errors.Errorf("Invalid bucket name %q", b.Name)
+		}
+		if _, ok := bucketMap[b.Name]; ok {
+			return nil, errors.Errorf("Duplicate bucket name %q", b.Name)
+		}
+		bucketMap[b.Name] = b
+	}
+
+	accessKey := make([]byte, accessKeyByteSize)
+	if _, err := rand.Read(accessKey); err != nil {
+		return nil, err
+	}
+	secretKey, err := libhttpserver.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	fsCache, err := lru.New(fsCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	log := config.MakeLogger("S3GW")
+	s = &Server{
+		config:    config,
+		logger:    log,
+		vlog:      config.MakeVLogger(log),
+		accessKey: strings.ToUpper(hex.EncodeToString(accessKey)),
+		secretKey: secretKey,
+		buckets:   bucketMap,
+		started:   config.Clock().Now(),
+		fs:        fsCache,
+	}
+
+	var listenerSource kbhttp.ListenerSource = kbhttp.NewAutoPortListenerSource()
+	if port != 0 {
+		listenerSource = kbhttp.NewFixedPortListenerSource(port)
+	}
+	s.server = kbhttp.NewSrv(log, listenerSource)
+	if err = s.server.Start(); err != nil {
+		return nil, err
+	}
+	s.server.Handle("/", http.HandlerFunc(s.serve))
+	return s, nil
+}
+
+// splitPath splits a path-style request path into the bucket name and
+// the object key.
+func splitPath(p string) (bucket, key string) {
+	parts := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)
+	if len(parts) > 1 {
+		key = parts[1]
+	}
+	return parts[0], key
+}
+
+func (s *Server) serve(w http.ResponseWriter, req *http.Request) {
+	s.vlog.Log(libkb.VLog1, "Incoming %s request from %q: %s",
+		req.Method, req.UserAgent(), req.URL.Path)
+	if err := s.checkAuth(req); err != nil {
+		s.vlog.Log(libkb.VLog1, "Failed to authenticate: %+v", err)
+		s.writeError(req.Context(), w, req, err)
+		return
+	}
+
+	ctx, err := libcontext.NewContextWithCancellationDelayer(
+		libkbfs.CtxWithRandomIDReplayable(
+			req.Context(), ctxS3IDKey, ctxS3OpID, s.logger))
+	if err != nil {
+		s.logger.Error("serve: failed to make context: %+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = libcontext.CleanupCancellationDelayer(ctx) }()
+	// Multipart uploads and in-progress PUTs are staged in a
+	// directory that KBFS would otherwise refuse to create.
+	ctx = context.WithValue(ctx, libkbfs.CtxAllowNameKey, uploadsDir)
+	// ETags are cached in xattrs, which must work even when users
+	// can't set their own.
+	ctx = context.WithValue(ctx, libkbfs.CtxAllowXattrsKey, true)
+
+	if err := s.route(ctx, w, req.WithContext(ctx)); err != nil {
+		s.writeError(ctx, w, req, err)
+	}
+}
+
+func (s *Server) route(
+	ctx context.Context, w http.ResponseWriter, req *http.Request) error {
+	bucketName, key := splitPath(req.URL.Path)
+	if bucketName == "" {
+		if req.Method != http.MethodGet {
+			return errMethodNotAllowed
+		}
+		return s.listBuckets(w)
+	}
+
+	b, ok := s.buckets[bucketName]
+	if !ok {
+		return errNoSuchBucket
+	}
+	fs, err := s.getBucketFS(ctx, b)
+	if err != nil {
+		s.logger.CDebugf(ctx, "Couldn't get FS for bucket %s: %+v",
+			b.Name, err)
+		return errNoSuchBucket
+	}
+	query := req.URL.Query()
+
+	if key == "" {
+		switch req.Method {
+		case http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+			return nil
+		case http.MethodGet:
+			if _, ok := query["uploads"]; ok {
+				return errNotImplemented
+			}
+			return s.listObjects(w, req, b, fs)
+		case http.MethodPost:
+			if _, ok := query["delete"]; ok {
+				return s.deleteObjects(w, req, fs)
+			}
+			return errNotImplemented
+		default:
+			return errMethodNotAllowed
+		}
+	}
+
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if _, ok := query["uploadId"]; ok {
+		uploadID := query.Get("uploadId")
+		switch req.Method {
+		case http.MethodPut:
+			return s.uploadPart(w, req, fs, key, uploadID)
+		case http.MethodPost:
+			return s.completeMultipartUpload(w, req, b, fs, key, uploadID)
+		case http.MethodDelete:
+			return s.abortMultipartUpload(ctx, w, fs, key, uploadID)
+		case http.MethodGet:
+			return s.listParts(w, b, fs, key, uploadID)
+		default:
+			return errMethodNotAllowed
+		}
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return s.getObject(w, req, fs, key)
+	case http.MethodPut:
+		return s.putObject(w, req, fs, key)
+	case http.MethodPost:
+		if _, ok := query["uploads"]; ok {
+			return s.initiateMultipartUpload(w, b, fs, key)
+		}
+		return errNotImplemented
+	case http.MethodDelete:
+		return s.deleteObject(w, fs, key)
+	default:
+		return errMethodNotAllowed
+	}
+}
+
+// Address returns the address that the server is listening on.
+func (s *Server) Address() (string, error) {
+	return s.server.Addr()
+}
+
+// AccessKey returns the access key ID that clients must sign with.
+func (s *Server) AccessKey() string {
+	return s.accessKey
+}
+
+// SecretKey returns the secret key that clients must sign with.
+func (s *Server) SecretKey() string {
+	return s.secretKey
+}
+
+// BucketNames returns the sorted names of the buckets that the
+// server serves.
+func (s *Server) BucketNames() []string {
+	names := make([]string, 0, len(s.buckets))
+	for name := range s.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Shutdown shuts down the server.
+func (s *Server) Shutdown() {
+	s.server.Stop()
+}
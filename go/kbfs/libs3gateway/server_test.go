@@ -0,0 +1,341 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libs3gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/chat/s3"
+	"github.com/adamwalz/keybase-client/go/kbfs/data"
+	"github.com/adamwalz/keybase-client/go/kbfs/libcontext"
+	"github.com/adamwalz/keybase-client/go/kbfs/libfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
+	"github.com/adamwalz/keybase-client/go/kbfs/tlfhandle"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func makeTestServer(t *testing.T) (
+	s *Server, tlfFS *libfs.FS, shutdown func()) {
+	ctx := libcontext.BackgroundContextWithCancellationDelayer()
+	config := libkbfs.MakeTestConfigOrBust(t, "alice", "bob")
+
+	h, err := tlfhandle.ParseHandle(
+		ctx, config.KBPKI(), config.MDOps(), config, "alice,bob",
+		tlf.Private)
+	require.NoError(t, err)
+	tlfFS, err = libfs.NewFS(
+		ctx, config, h, data.MasterBranch, "", "",
+		keybase1.MDPriorityNormal)
+	require.NoError(t, err)
+	require.NoError(t, tlfFS.MkdirAll("photos", 0755))
+	require.NoError(t, tlfFS.SyncAll())
+
+	s, err = New(config, 0, []Bucket{{
+		Name:    "photos",
+		TlfType: tlf.Private,
+		TlfName: "alice,bob",
+		Subdir:  "photos",
+	}})
+	require.NoError(t, err)
+	return s, tlfFS, func() {
+		s.Shutdown()
+		libkbfs.CheckConfigAndShutdown(ctx, t, config)
+	}
+}
+
+func makeTestBucket(t *testing.T, s *Server, secretKey string) *s3.Bucket {
+	addr, err := s.Address()
+	require.NoError(t, err)
+	conn := s3.New(nil, s3.HMACSigner{SecretKey: []byte(secretKey)},
+		s3.Region{S3Endpoint: "http://" + addr}, http.DefaultClient)
+	conn.SetAccessKey(s.AccessKey())
+	conn.AttemptStrategy = s3.AttemptStrategy{Min: 1}
+	return conn.Bucket("photos").(*s3.Bucket)
+}
+
+func md5ETag(content string) string {
+	sum := md5.Sum([]byte(content))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// doSigned sends a request signed the same way as go/chat/s3.
+func doSigned(t *testing.T, s *Server, method, p string,
+	query url.Values) (int, []byte) {
+	addr, err := s.Address()
+	require.NoError(t, err)
+	u := url.URL{
+		Scheme: "http", Host: addr, Path: p, RawQuery: query.Encode()}
+	req, err := http.NewRequest(method, u.String(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	payload := s3.StringToSign(
+		method, (&url.URL{Path: p}).String(), query, req.Header)
+	sig, err := s3.HMACSigner{SecretKey: []byte(s.SecretKey())}.Sign(
+		[]byte(payload))
+	require.NoError(t, err)
+	req.Header.Set("Authorization",
+		fmt.Sprintf("AWS %s:%s", s.AccessKey(), sig))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, body
+}
+
+func TestServerAuth(t *testing.T) {
+	s, _, shutdown := makeTestServer(t)
+	defer shutdown()
+
+	addr, err := s.Address()
+	require.NoError(t, err)
+	resp, err := http.Get(fmt.Sprintf("http://%s/photos/", addr))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp.Body.Close()
+
+	b := makeTestBucket(t, s, "wrong secret")
+	_, err = b.List("", "", "", 0)
+	require.Error(t, err)
+	require.Equal(t, "SignatureDoesNotMatch", err.(*s3.Error).Code)
+
+	b = makeTestBucket(t, s, s.SecretKey())
+	res, err := b.List("", "", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, "photos", res.Name)
+	require.Len(t, res.Contents, 0)
+
+	t.Log("Pre-signed URLs work until they expire")
+	require.NoError(t, b.Put(
+		context.Background(), "a", []byte("a"), "text/plain", s3.Private,
+		s3.Options{}))
+	resp, err = http.Get(b.SignedURL("a", time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	resp, err = http.Get(b.SignedURL("a", time.Now().Add(-time.Minute)))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp.Body.Close()
+
+	status, body := doSigned(t, s, http.MethodGet, "/", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Contains(t, string(body), "<Name>photos</Name>")
+	status, _ = doSigned(t, s, http.MethodGet, "/videos/", nil)
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestServerObjects(t *testing.T) {
+	s, tlfFS, shutdown := makeTestServer(t)
+	defer shutdown()
+	b := makeTestBucket(t, s, s.SecretKey())
+	ctx := context.Background()
+
+	t.Log("Put some objects, and read them back")
+	objects := map[string]string{
+		"a.txt":        "a",
+		"b/c.txt":      "bc",
+		"b/d/e.txt":    "bde",
+		"b-f.txt":      "bf",
+		"g with space": "g",
+	}
+	for key, content := range objects {
+		require.NoError(t, b.Put(
+			ctx, key, []byte(content), "text/plain", s3.Private,
+			s3.Options{}))
+	}
+	for key, content := range objects {
+		got, err := b.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, content, string(got))
+	}
+	rc, err := b.GetReaderWithRange(ctx, "b/d/e.txt", 1, 2)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	require.Equal(t, "d", string(got))
+
+	t.Log("Objects are visible as files in KBFS")
+	f, err := tlfFS.Open("photos/b/d/e.txt")
+	require.NoError(t, err)
+	got, err = io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Equal(t, "bde", string(got))
+
+	t.Log("List everything in key order")
+	res, err := b.List("", "", "", 0)
+	require.NoError(t, err)
+	var keys []string
+	for _, k := range res.Contents {
+		keys = append(keys, k.Key)
+		require.Equal(t, md5ETag(objects[k.Key]), k.ETag)
+		require.Equal(t, int64(len(objects[k.Key])), k.Size)
+	}
+	require.Equal(t, []string{
+		"a.txt", "b-f.txt", "b/c.txt", "b/d/e.txt", "g with space"}, keys)
+
+	t.Log("List with a delimiter")
+	res, err = b.List("b/", "/", "", 0)
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	require.Equal(t, "b/c.txt", res.Contents[0].Key)
+	require.Equal(t, []string{"b/d/"}, res.CommonPrefixes)
+
+	t.Log("Page through a V2 listing")
+	var pageKeys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "max-keys": {"2"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		status, body := doSigned(t, s, http.MethodGet, "/photos/", query)
+		require.Equal(t, http.StatusOK, status, string(body))
+		var page listBucketResult
+		require.NoError(t, xml.Unmarshal(body, &page))
+		require.Equal(t, len(page.Contents), *page.KeyCount)
+		for _, k := range page.Contents {
+			pageKeys = append(pageKeys, k.Key)
+		}
+		if !page.IsTruncated {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	require.Equal(t, keys, pageKeys)
+
+	t.Log("Overwrite and delete")
+	require.NoError(t, b.Put(
+		ctx, "b/c.txt", []byte("new"), "text/plain", s3.Private,
+		s3.Options{}))
+	got, err = b.Get(ctx, "b/c.txt")
+	require.NoError(t, err)
+	require.Equal(t, "new", string(got))
+	require.NoError(t, b.Del(ctx, "b/d/e.txt"))
+	_, err = b.Get(ctx, "b/d/e.txt")
+	require.Error(t, err)
+	require.Equal(t, "NoSuchKey", err.(*s3.Error).Code)
+	_, err = tlfFS.Stat("photos/b/d")
+	require.Error(t, err)
+
+	t.Log("A file changed outside the gateway gets a new ETag")
+	f, err = tlfFS.OpenFile("photos/a.txt", 0x1, 0600) // os.O_WRONLY
+	require.NoError(t, err)
+	_, err = f.Write([]byte("z"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.NoError(t, tlfFS.SyncAll())
+	res, err = b.List("a", "", "", 0)
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	require.Equal(t, md5ETag("z"), res.Contents[0].ETag)
+
+	t.Log("Keys can't escape the bucket")
+	status, _ := doSigned(
+		t, s, http.MethodGet, "/photos/.kbfs_s3_uploads/x", nil)
+	require.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestServerMultipart(t *testing.T) {
+	s, _, shutdown := makeTestServer(t)
+	defer shutdown()
+	b := makeTestBucket(t, s, s.SecretKey())
+	ctx := context.Background()
+
+	m, err := b.InitMulti(ctx, "big/file", "text/plain", s3.Private)
+	require.NoError(t, err)
+	part1, err := m.PutPart(ctx, 1, bytes.NewReader([]byte("hello ")))
+	require.NoError(t, err)
+	part2, err := m.PutPart(ctx, 2, bytes.NewReader([]byte("world")))
+	require.NoError(t, err)
+	require.Equal(t, md5ETag("world"), part2.ETag)
+
+	parts, err := m.ListParts(ctx)
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	require.Equal(t, part1.ETag, parts[0].ETag)
+	require.Equal(t, int64(5), parts[1].Size)
+
+	t.Log("Incomplete uploads aren't visible")
+	res, err := b.List("", "", "", 0)
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 0)
+
+	require.NoError(t, m.Complete(ctx, parts))
+	got, err := b.Get(ctx, "big/file")
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(got))
+	res, err = b.List("", "", "", 0)
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	require.True(t, strings.HasSuffix(res.Contents[0].ETag, `-2"`))
+
+	t.Log("Aborted uploads go away")
+	m, err = b.InitMulti(ctx, "other", "text/plain", s3.Private)
+	require.NoError(t, err)
+	_, err = m.PutPart(ctx, 1, bytes.NewReader([]byte("x")))
+	require.NoError(t, err)
+	require.NoError(t, m.Abort(ctx))
+	_, err = m.ListParts(ctx)
+	require.Error(t, err)
+	require.Equal(t, "NoSuchUpload", err.(*s3.Error).Code)
+}
+
+func TestCommitFile(t *testing.T) {
+	_, tlfFS, shutdown := makeTestServer(t)
+	defer shutdown()
+
+	writeTmp := func(name, content string) {
+		f, err := tlfFS.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	t.Log("Without the gateway's context, user xattrs are off, so the " +
+		"commit fails and leaves nothing behind")
+	writeTmp("photos/tmp1", "a")
+	err := commitFile(tlfFS, "photos/tmp1", "photos/a.txt", md5ETag("a"))
+	require.IsType(t, libkbfs.UserXattrsDisabledError{}, errors.Cause(err))
+	_, err = tlfFS.Stat("photos/a.txt")
+	require.True(t, os.IsNotExist(err))
+	_, err = tlfFS.Stat("photos/tmp1")
+	require.True(t, os.IsNotExist(err))
+
+	t.Log("A successful commit caches an ETag that's still valid " +
+		"after the rename")
+	ctx := context.WithValue(
+		libcontext.BackgroundContextWithCancellationDelayer(),
+		libkbfs.CtxAllowXattrsKey, true)
+	defer func() { _ = libcontext.CleanupCancellationDelayer(ctx) }()
+	fs := tlfFS.WithContext(ctx)
+	writeTmp("photos/tmp2", "a")
+	require.NoError(t, commitFile(
+		fs, "photos/tmp2", "photos/a.txt", md5ETag("a")))
+	fi, err := fs.Stat("photos/a.txt")
+	require.NoError(t, err)
+	xattrs, err := fs.Xattrs("photos/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, etagXattrValue(fi, md5ETag("a")), xattrs[etagXattr])
+}
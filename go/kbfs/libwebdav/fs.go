@@ -0,0 +1,429 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libwebdav
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/adamwalz/keybase-client/go/kbfs/data"
+	"github.com/adamwalz/keybase-client/go/kbfs/libfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
+	"github.com/adamwalz/keybase-client/go/logger"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+	billy "gopkg.in/src-d/go-billy.v4"
+)
+
+const fsCacheSize = 64
+
+// fileSystem implements webdav.FileSystem on top of KBFS.  Paths
+// look like /<type>/<tlf name>/<path within TLF>.  The top two
+// levels are read-only listings; everything inside a TLF is served
+// by a read-write libfs.FS.
+type fileSystem struct {
+	config libkbfs.Config
+	log    logger.Logger
+	fs     *lru.Cache
+}
+
+var _ webdav.FileSystem = (*fileSystem)(nil)
+
+func newFileSystem(config libkbfs.Config, log logger.Logger) (
+	*fileSystem, error) {
+	fsCache, err := lru.New(fsCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSystem{
+		config: config,
+		log:    log,
+		fs:     fsCache,
+	}, nil
+}
+
+type obsoleteTrackingFS struct {
+	fs *libfs.FS
+	ch <-chan struct{}
+}
+
+func (e obsoleteTrackingFS) isObsolete() bool {
+	select {
+	case <-e.ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// kbfsPath is a parsed WebDAV path.  `depth` is 0 for the root, 1
+// for a TLF type directory, and 2 or more for a path within a TLF.
+type kbfsPath struct {
+	depth   int
+	tlfType tlf.Type
+	tlfName string
+	// inTlf is the path relative to the TLF root ("" for the root).
+	inTlf string
+}
+
+func parsePath(name string) (p kbfsPath, err error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return kbfsPath{}, nil
+	}
+	fields := strings.SplitN(name, "/", 3)
+	p.depth = len(fields)
+	p.tlfType, err = tlf.ParseTlfTypeFromPath(fields[0])
+	if err != nil {
+		return kbfsPath{}, os.ErrNotExist
+	}
+	if len(fields) > 1 {
+		p.tlfName = fields[1]
+	}
+	if len(fields) > 2 {
+		p.inTlf = fields[2]
+	}
+	return p, nil
+}
+
+func (p kbfsPath) sameTlf(other kbfsPath) bool {
+	return p.tlfType == other.tlfType && p.tlfName == other.tlfName
+}
+
+func (fs *fileSystem) getTlfFS(ctx context.Context, p kbfsPath) (
+	*libfs.FS, error) {
+	cacheKey := path.Join(p.tlfType.PathString(), p.tlfName)
+	if fsCached, ok := fs.fs.Get(cacheKey); ok {
+		if fsCachedTyped, ok := fsCached.(obsoleteTrackingFS); ok {
+			if !fsCachedTyped.isObsolete() {
+				return fsCachedTyped.fs.WithContext(ctx), nil
+			}
+		}
+	}
+
+	tlfHandle, err := libkbfs.GetHandleFromFolderNameAndType(ctx,
+		fs.config.KBPKI(), fs.config.MDOps(), fs.config, p.tlfName,
+		p.tlfType)
+	if err != nil {
+		return nil, err
+	}
+
+	tlfFS, err := libfs.NewFS(ctx,
+		fs.config, tlfHandle, data.MasterBranch, "", "",
+		keybase1.MDPriorityNormal)
+	if err != nil {
+		return nil, err
+	}
+
+	fsLifeCh, err := tlfFS.SubscribeToObsolete()
+	if err != nil {
+		return nil, err
+	}
+
+	fs.fs.Add(cacheKey, obsoleteTrackingFS{fs: tlfFS, ch: fsLifeCh})
+	return tlfFS, nil
+}
+
+// dirInfo describes the synthetic directories above the TLFs.
+type dirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+var _ os.FileInfo = dirInfo{}
+
+func (di dirInfo) Name() string       { return di.name }
+func (di dirInfo) Size() int64        { return 0 }
+func (di dirInfo) Mode() os.FileMode  { return os.ModeDir | 0500 }
+func (di dirInfo) ModTime() time.Time { return di.modTime }
+func (di dirInfo) IsDir() bool        { return true }
+func (di dirInfo) Sys() interface{}   { return nil }
+
+// listTlfs returns the favorited TLFs of the given type.
+func (fs *fileSystem) listTlfs(ctx context.Context, t tlf.Type) (
+	fis []os.FileInfo, err error) {
+	favs, err := fs.config.KBFSOps().GetFavorites(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := fs.config.Clock().Now()
+	for _, fav := range favs {
+		if fav.Type == t {
+			fis = append(fis, dirInfo{fav.Name, now})
+		}
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	return fis, nil
+}
+
+// Mkdir implements the webdav.FileSystem interface for fileSystem.
+func (fs *fileSystem) Mkdir(
+	ctx context.Context, name string, perm os.FileMode) error {
+	p, err := parsePath(name)
+	if err != nil {
+		return err
+	}
+	if p.depth <= 2 {
+		return os.ErrPermission
+	}
+	tlfFS, err := fs.getTlfFS(ctx, p)
+	if err != nil {
+		return err
+	}
+	// libfs only offers MkdirAll, but MKCOL must fail if the
+	// directory or its parent is missing.
+	if _, err := tlfFS.Stat(p.inTlf); err == nil {
+		return os.ErrExist
+	}
+	if _, err := tlfFS.Stat(path.Dir(p.inTlf)); err != nil {
+		return err
+	}
+	return tlfFS.MkdirAll(p.inTlf, perm)
+}
+
+// OpenFile implements the webdav.FileSystem interface for fileSystem.
+func (fs *fileSystem) OpenFile(
+	ctx context.Context, name string, flag int, perm os.FileMode) (
+	webdav.File, error) {
+	p, err := parsePath(name)
+	if err != nil {
+		return nil, err
+	}
+	isWrite := flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) != 0
+
+	switch p.depth {
+	case 0:
+		if isWrite {
+			return nil, os.ErrPermission
+		}
+		return &dir{
+			info: dirInfo{"/", fs.config.Clock().Now()},
+			readDir: func() ([]os.FileInfo, error) {
+				return libfs.NewRootFS(fs.config).ReadDir("")
+			},
+		}, nil
+	case 1:
+		if isWrite {
+			return nil, os.ErrPermission
+		}
+		return &dir{
+			info: dirInfo{p.tlfType.PathString(), fs.config.Clock().Now()},
+			readDir: func() ([]os.FileInfo, error) {
+				return fs.listTlfs(ctx, p.tlfType)
+			},
+		}, nil
+	}
+
+	tlfFS, err := fs.getTlfFS(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := tlfFS.Stat(p.inTlf)
+	switch {
+	case err == nil && fi.IsDir():
+		if isWrite {
+			return nil, os.ErrPermission
+		}
+		return &dir{
+			info: fi,
+			readDir: func() ([]os.FileInfo, error) {
+				return tlfFS.ReadDir(p.inTlf)
+			},
+		}, nil
+	case err == nil:
+	case os.IsNotExist(err) && flag&os.O_CREATE != 0:
+		// libfs creates missing parent directories, but WebDAV
+		// requires them to already exist.
+		if _, err := tlfFS.Stat(path.Dir(p.inTlf)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	f, err := tlfFS.OpenFile(p.inTlf, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, fs: tlfFS}, nil
+}
+
+// RemoveAll implements the webdav.FileSystem interface for fileSystem.
+func (fs *fileSystem) RemoveAll(ctx context.Context, name string) error {
+	p, err := parsePath(name)
+	if err != nil {
+		return err
+	}
+	if p.depth <= 2 {
+		return os.ErrPermission
+	}
+	tlfFS, err := fs.getTlfFS(ctx, p)
+	if err != nil {
+		return err
+	}
+	fi, err := tlfFS.Lstat(p.inTlf)
+	if err != nil {
+		return err
+	}
+	parentFS, err := tlfFS.Chroot(path.Dir(p.inTlf))
+	if err != nil {
+		return err
+	}
+	return libfs.RecursiveDelete(ctx, parentFS, fi)
+}
+
+// Rename implements the webdav.FileSystem interface for fileSystem.
+func (fs *fileSystem) Rename(
+	ctx context.Context, oldName, newName string) error {
+	oldP, err := parsePath(oldName)
+	if err != nil {
+		return err
+	}
+	newP, err := parsePath(newName)
+	if err != nil {
+		return err
+	}
+	if oldP.depth <= 2 || newP.depth <= 2 {
+		return os.ErrPermission
+	}
+	if !oldP.sameTlf(newP) {
+		// KBFS can't rename across TLFs; clients have to copy
+		// instead.
+		return errors.Errorf(
+			"Can't move %s to a different folder %s", oldName, newName)
+	}
+	tlfFS, err := fs.getTlfFS(ctx, oldP)
+	if err != nil {
+		return err
+	}
+	return tlfFS.Rename(oldP.inTlf, newP.inTlf)
+}
+
+// Stat implements the webdav.FileSystem interface for fileSystem.
+func (fs *fileSystem) Stat(ctx context.Context, name string) (
+	os.FileInfo, error) {
+	p, err := parsePath(name)
+	if err != nil {
+		return nil, err
+	}
+	switch p.depth {
+	case 0:
+		return dirInfo{"/", fs.config.Clock().Now()}, nil
+	case 1:
+		return dirInfo{p.tlfType.PathString(), fs.config.Clock().Now()}, nil
+	}
+	tlfFS, err := fs.getTlfFS(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return tlfFS.Stat(p.inTlf)
+}
+
+// file is a webdav.File for a KBFS file.
+type file struct {
+	billy.File
+	fs      *libfs.FS
+	written bool
+}
+
+var _ webdav.File = (*file)(nil)
+
+// Write implements the io.Writer interface for file.
+func (f *file) Write(p []byte) (int, error) {
+	f.written = true
+	return f.File.Write(p)
+}
+
+// Close implements the io.Closer interface for file.  WebDAV clients
+// have no way to ask for an fsync, so a file that was written to is
+// synced to the journal when it's closed.
+func (f *file) Close() error {
+	err := f.File.Close()
+	if err != nil || !f.written {
+		return err
+	}
+	return f.fs.SyncAll()
+}
+
+// Readdir implements the http.File interface for file.
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, libkbfs.NotDirError{}
+}
+
+// Stat implements the http.File interface for file.
+func (f *file) Stat() (os.FileInfo, error) {
+	return f.fs.Stat(f.Name())
+}
+
+// dir is a webdav.File for a KBFS directory, or for one of the
+// synthetic directories above the TLFs.
+type dir struct {
+	info    os.FileInfo
+	readDir func() ([]os.FileInfo, error)
+
+	// children holds the entries not yet returned by Readdir, after
+	// the first call.
+	children []os.FileInfo
+	read     bool
+}
+
+var _ webdav.File = (*dir)(nil)
+
+// Close implements the http.File interface for dir.
+func (d *dir) Close() error {
+	return nil
+}
+
+// Read implements the http.File interface for dir.
+func (d *dir) Read(_ []byte) (int, error) {
+	return 0, libkbfs.NotFileError{}
+}
+
+// Seek implements the http.File interface for dir.
+func (d *dir) Seek(_ int64, _ int) (int64, error) {
+	return 0, libkbfs.NotFileError{}
+}
+
+// Write implements the io.Writer interface for dir.
+func (d *dir) Write(_ []byte) (int, error) {
+	return 0, libkbfs.NotFileError{}
+}
+
+// Readdir implements the http.File interface for dir, with the
+// semantics of os.File.Readdir.
+func (d *dir) Readdir(count int) (fis []os.FileInfo, err error) {
+	if !d.read {
+		d.children, err = d.readDir()
+		if err != nil {
+			return nil, err
+		}
+		d.read = true
+	}
+	if count <= 0 {
+		fis, d.children = d.children, nil
+		return fis, nil
+	}
+	if len(d.children) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.children) {
+		count = len(d.children)
+	}
+	fis, d.children = d.children[:count], d.children[count:]
+	return fis, nil
+}
+
+// Stat implements the http.File interface for dir.
+func (d *dir) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
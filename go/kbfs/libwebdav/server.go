@@ -0,0 +1,195 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libwebdav
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/kbfs/libcontext"
+	"github.com/adamwalz/keybase-client/go/kbfs/libhttpserver"
+	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/kbhttp"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/logger"
+	"golang.org/x/net/webdav"
+)
+
+const (
+	// Debug tag ID for an individual WebDAV request.
+	ctxWebDAVOpID = "WDID"
+
+	// tokenIdleTime is how long a token stays valid without being used.
+	tokenIdleTime = 10 * time.Minute
+)
+
+type ctxWebDAVTagKey int
+
+const (
+	ctxWebDAVIDKey ctxWebDAVTagKey = iota
+)
+
+// Server is a local WebDAV server that serves KBFS read-write, for
+// environments where FUSE isn't available.
+//
+// Clients authenticate with a token generated the same way as the
+// tokens for libhttpserver.Server, passed either as the password for
+// HTTP basic auth (with any user name), or as a `token` query
+// parameter.  Like libhttpserver tokens, it expires after 10 minutes,
+// but those are counted from the last request that used it rather
+// than from when it was made, since WebDAV clients tend to stay
+// mounted for a long time.
+type Server struct {
+	config libkbfs.Config
+	logger logger.Logger
+	vlog   *libkb.VDebugLog
+
+	tokenLock       sync.Mutex
+	token           string
+	tokenExpireTime time.Time
+
+	handler *webdav.Handler
+	server  *kbhttp.Srv
+}
+
+func (s *Server) handleUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="KBFS"`)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+// checkToken checks the token of a request, and if it's valid, keeps
+// it valid for another tokenIdleTime.
+func (s *Server) checkToken(req *http.Request) bool {
+	token := req.URL.Query().Get("token")
+	if _, password, ok := req.BasicAuth(); ok {
+		token = password
+	}
+	s.tokenLock.Lock()
+	defer s.tokenLock.Unlock()
+	now := s.config.Clock().Now()
+	if len(token) == 0 || !now.Before(s.tokenExpireTime) ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		return false
+	}
+	s.tokenExpireTime = now.Add(tokenIdleTime)
+	return true
+}
+
+// checkHost protects against DNS rebinding, by only accepting
+// requests addressed to the loopback address we listen on.
+func (s *Server) checkHost(req *http.Request) (bool, error) {
+	addr, err := s.server.Addr()
+	if err != nil {
+		return false, err
+	}
+	if req.Host == addr {
+		return true, nil
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false, err
+	}
+	return req.Host == net.JoinHostPort("localhost", port), nil
+}
+
+func (s *Server) serve(w http.ResponseWriter, req *http.Request) {
+	s.vlog.Log(libkb.VLog1, "Incoming %s request from %q: %s",
+		req.Method, req.UserAgent(), req.URL.Path)
+	ok, err := s.checkHost(req)
+	if err != nil {
+		s.logger.Error("serve: failed to get server address: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		s.logger.Warning("Host %s didn't match our address, failing "+
+			"request to protect against DNS rebinding", req.Host)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.checkToken(req) {
+		s.vlog.Log(libkb.VLog1, "Invalid or missing token")
+		s.handleUnauthorized(w)
+		return
+	}
+
+	ctx, err := libcontext.NewContextWithCancellationDelayer(
+		libkbfs.CtxWithRandomIDReplayable(
+			req.Context(), ctxWebDAVIDKey, ctxWebDAVOpID, s.logger))
+	if err != nil {
+		s.logger.Error("serve: failed to make context: %+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = libcontext.CleanupCancellationDelayer(ctx) }()
+	s.handler.ServeHTTP(w, req.WithContext(ctx))
+}
+
+// New creates and starts a new WebDAV server, listening on the given
+// port on the loopback interface, or on an automatically-picked port
+// if `port` is 0.
+func New(config libkbfs.Config, port int) (s *Server, err error) {
+	log := config.MakeLogger("WDAV")
+	fs, err := newFileSystem(config, log)
+	if err != nil {
+		return nil, err
+	}
+	s = &Server{
+		config: config,
+		logger: log,
+		vlog:   config.MakeVLogger(log),
+	}
+	s.handler = &webdav.Handler{
+		FileSystem: fs,
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(req *http.Request, err error) {
+			if err != nil {
+				s.logger.Debug("%s %s failed: %+v", req.Method, req.URL.Path, err)
+			}
+		},
+	}
+
+	var listenerSource kbhttp.ListenerSource = kbhttp.NewAutoPortListenerSource()
+	if port != 0 {
+		listenerSource = kbhttp.NewFixedPortListenerSource(port)
+	}
+	s.server = kbhttp.NewSrv(log, listenerSource)
+	if err = s.server.Start(); err != nil {
+		return nil, err
+	}
+	s.server.Handle("/", http.HandlerFunc(s.serve))
+	return s, nil
+}
+
+// Address returns the address that the server is listening on.
+func (s *Server) Address() (string, error) {
+	return s.server.Addr()
+}
+
+// CurrentToken returns the token that clients must present, making a
+// new one if the last one expired.  Either way, the token is valid for
+// at least another tokenIdleTime.
+func (s *Server) CurrentToken() (string, error) {
+	s.tokenLock.Lock()
+	defer s.tokenLock.Unlock()
+	now := s.config.Clock().Now()
+	if !now.Before(s.tokenExpireTime) {
+		token, err := libhttpserver.NewToken()
+		if err != nil {
+			return "", err
+		}
+		s.token = token
+	}
+	s.tokenExpireTime = now.Add(tokenIdleTime)
+	return s.token, nil
+}
+
+// Shutdown shuts down the server.
+func (s *Server) Shutdown() {
+	s.server.Stop()
+}
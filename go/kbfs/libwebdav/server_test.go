@@ -0,0 +1,218 @@
+// Copyright 2020 Keybase Inc. All rights reserved.
+// Use of this source code is governed by a BSD
+// license that can be found in the LICENSE file.
+
+package libwebdav
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/kbfs/data"
+	"github.com/adamwalz/keybase-client/go/kbfs/libcontext"
+	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/test/clocktest"
+	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
+	"github.com/adamwalz/keybase-client/go/kbfs/tlfhandle"
+	"github.com/stretchr/testify/require"
+)
+
+func makeTestKBFSConfig(t *testing.T) (
+	kbfsConfig libkbfs.Config, shutdown func()) {
+	ctx := libcontext.BackgroundContextWithCancellationDelayer()
+	cfg := libkbfs.MakeTestConfigOrBust(t, "alice", "bob")
+	shutdown = func() {
+		libkbfs.CheckConfigAndShutdown(ctx, t, cfg)
+	}
+
+	h, err := tlfhandle.ParseHandle(
+		ctx, cfg.KBPKI(), cfg.MDOps(), cfg, "alice,bob", tlf.Private)
+	require.NoError(t, err)
+	_, _, err = cfg.KBFSOps().GetOrCreateRootNode(ctx, h, data.MasterBranch)
+	require.NoError(t, err)
+
+	return cfg, shutdown
+}
+
+type testClient struct {
+	t     *testing.T
+	addr  string
+	token string
+}
+
+func (c testClient) do(
+	method, path, body string, headers map[string]string) (int, string) {
+	req, err := http.NewRequest(
+		method, fmt.Sprintf("http://%s%s", c.addr, path),
+		strings.NewReader(body))
+	require.NoError(c.t, err)
+	if c.token != "" {
+		req.SetBasicAuth("kbfs", c.token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+	return resp.StatusCode, string(buf)
+}
+
+func TestServerAuth(t *testing.T) {
+	kbfsConfig, shutdown := makeTestKBFSConfig(t)
+	defer shutdown()
+
+	s, err := New(kbfsConfig, 0)
+	require.NoError(t, err)
+	defer s.Shutdown()
+	addr, err := s.Address()
+	require.NoError(t, err)
+
+	c := testClient{t, addr, ""}
+	status, _ := c.do("PROPFIND", "/", "", map[string]string{"Depth": "0"})
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	c.token = "deadbeef"
+	status, _ = c.do("PROPFIND", "/", "", map[string]string{"Depth": "0"})
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	token, err := s.CurrentToken()
+	require.NoError(t, err)
+	c.token = ""
+	status, _ = c.do("PROPFIND", "/?token="+token, "",
+		map[string]string{"Depth": "0"})
+	require.Equal(t, http.StatusMultiStatus, status)
+}
+
+func TestServerTokenExpiry(t *testing.T) {
+	kbfsConfig, shutdown := makeTestKBFSConfig(t)
+	defer shutdown()
+	clock := clocktest.NewTestClockNow()
+	kbfsConfig.SetClock(clock)
+
+	s, err := New(kbfsConfig, 0)
+	require.NoError(t, err)
+	defer s.Shutdown()
+	addr, err := s.Address()
+	require.NoError(t, err)
+	token, err := s.CurrentToken()
+	require.NoError(t, err)
+	c := testClient{t, addr, token}
+
+	t.Log("Using the token keeps it valid")
+	for i := 0; i < 3; i++ {
+		clock.Add(tokenIdleTime - time.Second)
+		status, _ := c.do("PROPFIND", "/", "", map[string]string{"Depth": "0"})
+		require.Equal(t, http.StatusMultiStatus, status)
+	}
+
+	t.Log("An idle token expires, and gets replaced")
+	clock.Add(tokenIdleTime)
+	status, _ := c.do("PROPFIND", "/", "", map[string]string{"Depth": "0"})
+	require.Equal(t, http.StatusUnauthorized, status)
+	newToken, err := s.CurrentToken()
+	require.NoError(t, err)
+	require.NotEqual(t, token, newToken)
+	status, _ = c.do("PROPFIND", "/", "", map[string]string{"Depth": "0"})
+	require.Equal(t, http.StatusUnauthorized, status)
+	c.token = newToken
+	status, _ = c.do("PROPFIND", "/", "", map[string]string{"Depth": "0"})
+	require.Equal(t, http.StatusMultiStatus, status)
+
+	t.Log("Asking for the token again doesn't change it while it's valid")
+	sameToken, err := s.CurrentToken()
+	require.NoError(t, err)
+	require.Equal(t, newToken, sameToken)
+}
+
+func TestServerReadWrite(t *testing.T) {
+	kbfsConfig, shutdown := makeTestKBFSConfig(t)
+	defer shutdown()
+
+	s, err := New(kbfsConfig, 0)
+	require.NoError(t, err)
+	defer s.Shutdown()
+	addr, err := s.Address()
+	require.NoError(t, err)
+	token, err := s.CurrentToken()
+	require.NoError(t, err)
+	c := testClient{t, addr, token}
+	tlfPath := "/private/alice,bob"
+
+	t.Log("Top-level directories can't be changed")
+	status, _ := c.do("MKCOL", "/private/foo", "", nil)
+	require.Equal(t, http.StatusMethodNotAllowed, status)
+
+	status, body := c.do("PROPFIND", "/private/", "",
+		map[string]string{"Depth": "1"})
+	require.Equal(t, http.StatusMultiStatus, status)
+	require.Contains(t, body, tlfPath)
+
+	t.Log("Make a directory and write a file into it")
+	status, _ = c.do("MKCOL", tlfPath+"/a", "", nil)
+	require.Equal(t, http.StatusCreated, status)
+	status, _ = c.do("PUT", tlfPath+"/a/b.txt", "hello", nil)
+	require.Equal(t, http.StatusCreated, status)
+	status, body = c.do("GET", tlfPath+"/a/b.txt", "", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "hello", body)
+
+	status, body = c.do("PROPFIND", tlfPath+"/a/", "",
+		map[string]string{"Depth": "1"})
+	require.Equal(t, http.StatusMultiStatus, status)
+	require.Contains(t, body, tlfPath+"/a/b.txt")
+
+	t.Log("Lock the file, and make sure writes need the lock token")
+	status, body = c.do("LOCK", tlfPath+"/a/b.txt",
+		`<?xml version="1.0" encoding="utf-8"?>
+<D:lockinfo xmlns:D="DAV:">
+  <D:lockscope><D:exclusive/></D:lockscope>
+  <D:locktype><D:write/></D:locktype>
+</D:lockinfo>`, map[string]string{"Timeout": "Second-60"})
+	require.Equal(t, http.StatusOK, status)
+	start := strings.Index(body, "<D:locktoken><D:href>")
+	require.True(t, start >= 0, body)
+	start += len("<D:locktoken><D:href>")
+	lockToken := body[start : start+strings.Index(body[start:], "<")]
+	status, _ = c.do("PUT", tlfPath+"/a/b.txt", "locked out", nil)
+	require.Equal(t, http.StatusLocked, status)
+	status, _ = c.do("PUT", tlfPath+"/a/b.txt", "world",
+		map[string]string{"If": "(<" + lockToken + ">)"})
+	require.Equal(t, http.StatusCreated, status)
+	status, _ = c.do("UNLOCK", tlfPath+"/a/b.txt", "",
+		map[string]string{"Lock-Token": "<" + lockToken + ">"})
+	require.Equal(t, http.StatusNoContent, status)
+
+	t.Log("Copy and move")
+	status, _ = c.do("COPY", tlfPath+"/a", "", map[string]string{
+		"Destination": fmt.Sprintf("http://%s%s/c", addr, tlfPath),
+	})
+	require.Equal(t, http.StatusCreated, status)
+	status, _ = c.do("MOVE", tlfPath+"/c/b.txt", "", map[string]string{
+		"Destination": fmt.Sprintf("http://%s%s/d.txt", addr, tlfPath),
+	})
+	require.Equal(t, http.StatusCreated, status)
+	status, body = c.do("GET", tlfPath+"/d.txt", "", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "world", body)
+	status, _ = c.do("GET", tlfPath+"/c/b.txt", "", nil)
+	require.Equal(t, http.StatusNotFound, status)
+
+	t.Log("Moving to a different folder isn't supported")
+	status, _ = c.do("MOVE", tlfPath+"/d.txt", "", map[string]string{
+		"Destination": fmt.Sprintf("http://%s/private/alice/d.txt", addr),
+	})
+	require.NotEqual(t, http.StatusCreated, status)
+
+	t.Log("Delete a directory tree")
+	status, _ = c.do("DELETE", tlfPath+"/a", "", nil)
+	require.Equal(t, http.StatusNoContent, status)
+	status, _ = c.do("GET", tlfPath+"/a/b.txt", "", nil)
+	require.Equal(t, http.StatusNotFound, status)
+}
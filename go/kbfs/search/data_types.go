@@ -4,7 +4,50 @@
 
 package search
 
+import (
+	"time"
+
+	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
+)
+
+// Match indicates the part of a fragment, `Text[Start:End]`, that
+// matched a search query.
+type Match struct {
+	Start int
+	End   int
+}
+
+// Fragment is a snippet of the indexed contents of a document that
+// matched a search query.
+type Fragment struct {
+	Text    string
+	Matches []Match
+}
+
 // Result indicates a single document that matches a search query.
 type Result struct {
-	Path string
+	Path      string
+	Score     float64
+	Fragments []Fragment
+}
+
+// SearchOptions restricts which documents can match a search query.
+// The zero value doesn't restrict anything.
+type SearchOptions struct {
+	// TlfID, if set, limits results to a single TLF.
+	TlfID tlf.ID
+	// PathPrefix, if set, limits results to the given path (starting
+	// with the canonical path of the TLF, like
+	// `/keybase/private/alice`) and everything under it.  Callers
+	// should also set `TlfID` to the TLF of the path, so that the
+	// index doesn't have to consider documents from other TLFs.
+	PathPrefix string
+	// FileType, if set, limits results to files with the given
+	// extension (without the leading dot, and case-insensitive).
+	FileType string
+	// MtimeAfter and MtimeBefore, if set, limit results to documents
+	// last modified at or after `MtimeAfter`, and before
+	// `MtimeBefore`.
+	MtimeAfter  time.Time
+	MtimeBefore time.Time
 }
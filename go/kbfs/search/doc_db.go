@@ -7,6 +7,8 @@ package search
 import (
 	"context"
 	"io"
+	"path"
+	"strings"
 	"sync"
 
 	"github.com/adamwalz/keybase-client/go/kbfs/ldbutils"
@@ -174,6 +176,64 @@ func (db *DocDb) Put(
 	return db.db.Put([]byte(docID), encodedMetadata, nil)
 }
 
+// Subtree returns the IDs of the docs at the given full path, and of
+// every doc under them.  Like in search results, the full path of a
+// doc is the names of it and of all its ancestors, joined together.
+// This has to scan the whole db.
+func (db *DocDb) Subtree(ctx context.Context, fullPath string) (
+	docIDs []string, err error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	err = db.checkDbLocked(ctx, "DD(Subtree)")
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string][]string)
+	names := make(map[string]string)
+	iter := db.db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		var md docMD
+		err = db.config.Codec().Decode(iter.Value(), &md)
+		if err != nil {
+			return nil, err
+		}
+		docID := string(iter.Key())
+		children[md.ParentDocID] = append(children[md.ParentDocID], docID)
+		names[docID] = md.Name
+	}
+	err = iter.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk down from the root docs, only into the docs on the way to
+	// `fullPath`, and then into everything under it.
+	var walk func(docID, p string, under bool)
+	walk = func(docID, p string, under bool) {
+		if !under {
+			p = path.Join(p, names[docID])
+			switch {
+			case p == fullPath:
+				under = true
+			case !strings.HasPrefix(fullPath, p+"/"):
+				return
+			}
+		}
+		if under {
+			docIDs = append(docIDs, docID)
+		}
+		for _, child := range children[docID] {
+			walk(child, p, under)
+		}
+	}
+	for _, root := range children[""] {
+		walk(root, "", false)
+	}
+	return docIDs, nil
+}
+
 // Delete removes the metadata for the TLF from the DB.
 func (db *DocDb) Delete(
 	ctx context.Context, docID string) error {
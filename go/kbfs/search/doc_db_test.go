@@ -78,4 +78,15 @@ func TestDocDb(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, d1, gotP3)
 	require.Equal(t, n3, gotN3)
+
+	t.Log("Find the docs under a path")
+	subtree, err := db2.Subtree(ctx, n1)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{d1, d2, d3}, subtree)
+	subtree, err = db2.Subtree(ctx, n1+"/"+n2)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{d2, d3}, subtree)
+	subtree, err = db2.Subtree(ctx, n2)
+	require.NoError(t, err)
+	require.Empty(t, subtree)
 }
@@ -28,14 +28,43 @@ const (
 	sniffLen = uint64(512)
 )
 
+// indexedBase holds the fields common to all docs.  Docs must keep it
+// in an exported field, rather than embedding it, for it to be
+// visible to the indexer.
 type indexedBase struct {
 	TlfID    tlf.ID
 	Revision kbfsmd.Revision
 	Mtime    time.Time
+	// Ext is the lower-cased extension of a file name, without the
+	// leading dot, so that searches can be limited to a file type.
+	Ext string
+}
+
+func makeBase(
+	n libkbfs.Node, ei data.EntryInfo, revision kbfsmd.Revision,
+	mtime time.Time) indexedBase {
+	base := indexedBase{
+		TlfID:    n.GetFolderBranch().Tlf,
+		Revision: revision,
+		Mtime:    mtime,
+	}
+	if ei.Type == data.File || ei.Type == data.Exec {
+		base.Ext = fileExt(n.GetBasename().Plaintext())
+	}
+	return base
+}
+
+func fileExt(name string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+// indexedFile is the doc for a file whose contents can't be indexed.
+type indexedFile struct {
+	Base indexedBase
 }
 
 type indexedTextFile struct {
-	indexedBase
+	Base indexedBase
 	Text string
 }
 
@@ -46,7 +75,7 @@ func (itf indexedTextFile) Type() string {
 }
 
 type indexedHTMLFile struct {
-	indexedBase
+	Base indexedBase
 	HTML string
 }
 
@@ -102,7 +131,7 @@ func getTextToIndex(
 }
 
 type indexedName struct {
-	indexedBase
+	Base          indexedBase
 	Name          string
 	TokenizedName string
 }
@@ -127,32 +156,23 @@ func makeNameDocWithBase(
 	fullName := n.GetBasename().Plaintext()
 	tokenizedName := strings.Map(removePunct, fullName)
 	return indexedName{
-		indexedBase:   base,
+		Base:          base,
 		Name:          fullName,
 		TokenizedName: tokenizedName,
 	}
 }
 
 func makeNameDoc(
-	n libkbfs.Node, revision kbfsmd.Revision, mtime time.Time) (
-	nameDoc interface{}) {
-	base := indexedBase{
-		TlfID:    n.GetFolderBranch().Tlf,
-		Revision: revision,
-		Mtime:    mtime,
-	}
-	return makeNameDocWithBase(n, base)
+	n libkbfs.Node, ei data.EntryInfo, revision kbfsmd.Revision,
+	mtime time.Time) (nameDoc interface{}) {
+	return makeNameDocWithBase(n, makeBase(n, ei, revision, mtime))
 }
 
 func makeDoc(
 	ctx context.Context, config libkbfs.Config, n libkbfs.Node,
 	ei data.EntryInfo, revision kbfsmd.Revision, mtime time.Time) (
 	doc, nameDoc interface{}, err error) {
-	base := indexedBase{
-		TlfID:    n.GetFolderBranch().Tlf,
-		Revision: revision,
-		Mtime:    mtime,
-	}
+	base := makeBase(n, ei, revision, mtime)
 
 	// Name goes in a separate doc, so we can rename a file without
 	// having to re-index all of its contents.
@@ -184,6 +204,6 @@ func makeDoc(
 		return indexedTextFile{base, text}, name, nil
 	default:
 		// Unindexable content type.
-		return base, name, nil
+		return indexedFile{base}, name, nil
 	}
 }
@@ -17,6 +17,8 @@ import (
 	"github.com/blevesearch/bleve"
 	"github.com/blevesearch/bleve/index/store"
 	"github.com/blevesearch/bleve/registry"
+	bsearch "github.com/blevesearch/bleve/search"
+	"github.com/blevesearch/bleve/search/query"
 	"github.com/adamwalz/keybase-client/go/kbfs/data"
 	"github.com/adamwalz/keybase-client/go/kbfs/idutil"
 	"github.com/adamwalz/keybase-client/go/kbfs/ioutil"
@@ -193,6 +195,29 @@ func defaultInitConfig(
 	return newCtx, newConfig, newConfig.Shutdown, err
 }
 
+// removeOldIndexes deletes the on-disk data of any index made before
+// `currentIndexVersion`.  Those are missing fields that searches
+// filter on, so there's nothing worth migrating, and everything just
+// gets re-indexed.
+func (i *Indexer) removeOldIndexes(ctx context.Context) error {
+	for _, dir := range oldIndexDirs {
+		p := filepath.Join(i.config.StorageRoot(), indexStorageDir, dir)
+		_, err := os.Stat(p)
+		switch {
+		case os.IsNotExist(errors.Cause(err)):
+			continue
+		case err != nil:
+			return err
+		}
+		i.log.CDebugf(ctx, "Removing old index data at %s", p)
+		err = ioutil.RemoveAll(p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (i *Indexer) loadIndex(ctx context.Context) (err error) {
 	i.log.CDebugf(ctx, "Loading index")
 	defer func() { i.log.CDebugf(ctx, "Done loading index: %+v", err) }()
@@ -213,6 +238,11 @@ func (i *Indexer) loadIndex(ctx context.Context) (err error) {
 		return nil
 	}
 
+	err = i.removeOldIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Create a new Config object for the index data, with a storage
 	// root that's unique to this user.
 	ctx, indexConfig, configShutdown, err := i.configInitFn(
@@ -276,7 +306,9 @@ func (i *Indexer) loadIndex(ctx context.Context) (err error) {
 	// so we don't really need to worry about concurrent KBFS
 	// processes here.
 	var index bleve.Index
-	p := filepath.Join(i.config.StorageRoot(), indexStorageDir, bleveIndexDir)
+	p := filepath.Join(
+		i.config.StorageRoot(), indexStorageDir, currentIndexVersion,
+		bleveIndexDir)
 	_, err = os.Stat(p)
 	switch {
 	case os.IsNotExist(errors.Cause(err)):
@@ -782,6 +814,23 @@ func (i *Indexer) renameChild(
 		return err
 	}
 
+	// The extension of a file is indexed along with its contents, so
+	// if it changed, the contents need to be re-indexed as well.
+	_, oldName, err := i.docDb.Get(ctx, docID)
+	if err != nil {
+		return err
+	}
+	mtime := time.Unix(0, ei.Mtime)
+	var newDoc interface{}
+	newNameDoc := makeNameDoc(n, ei, revision, mtime)
+	if fileExt(oldName) != fileExt(childName.Plaintext()) {
+		newDoc, newNameDoc, err = makeDoc(
+			ctx, i.config, n, ei, revision, mtime)
+		if err != nil {
+			return err
+		}
+	}
+
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
@@ -790,7 +839,12 @@ func (i *Indexer) renameChild(
 		return err
 	}
 
-	newNameDoc := makeNameDoc(n, revision, time.Unix(0, ei.Mtime))
+	if newDoc != nil {
+		err = b.Index(docID, newDoc)
+		if err != nil {
+			return err
+		}
+	}
 	err = b.Index(nameDocID(docID), newNameDoc)
 	if err != nil {
 		return err
@@ -1445,17 +1499,88 @@ func (i *Indexer) waitForSyncs(ctx context.Context) error {
 	return i.indexWG.Wait(ctx)
 }
 
+// makeQuery restricts the given query string to the documents allowed
+// by `opts`.  Path names aren't indexed, so if `opts` has a path
+// prefix, the caller must look up the IDs of the docs under it and
+// pass them in as `subtreeDocIDs`.
+func (opts SearchOptions) makeQuery(
+	q string, subtreeDocIDs []string) query.Query {
+	sQuery := bleve.NewQueryStringQuery(q)
+	queries := []query.Query{sQuery}
+	if opts.TlfID != tlf.NullID {
+		tlfQuery := bleve.NewTermQuery(opts.TlfID.String())
+		tlfQuery.SetField(baseFieldName + "." + tlfIDFieldName)
+		queries = append(queries, tlfQuery)
+	}
+	if opts.PathPrefix != "" {
+		// Both the contents and the name of each doc can match.
+		ids := make([]string, 0, 2*len(subtreeDocIDs))
+		for _, docID := range subtreeDocIDs {
+			ids = append(ids, docID, nameDocID(docID))
+		}
+		queries = append(queries, bleve.NewDocIDQuery(ids))
+	}
+	if opts.FileType != "" {
+		extQuery := bleve.NewTermQuery(fileExt("." + opts.FileType))
+		extQuery.SetField(baseFieldName + "." + extFieldName)
+		queries = append(queries, extQuery)
+	}
+	if !opts.MtimeAfter.IsZero() || !opts.MtimeBefore.IsZero() {
+		mtimeQuery := bleve.NewDateRangeQuery(
+			opts.MtimeAfter, opts.MtimeBefore)
+		mtimeQuery.SetField(baseFieldName + "." + mtimeFieldName)
+		queries = append(queries, mtimeQuery)
+	}
+	if len(queries) == 1 {
+		return sQuery
+	}
+	return bleve.NewConjunctionQuery(queries...)
+}
+
+// makeFragment strips the match markers out of a highlighted
+// fragment, and records where they were.
+func makeFragment(highlighted string) (f Fragment) {
+	var text strings.Builder
+	start := -1
+	for _, r := range highlighted {
+		switch string(r) {
+		case matchStartMarker:
+			start = text.Len()
+		case matchEndMarker:
+			if start >= 0 {
+				f.Matches = append(f.Matches, Match{start, text.Len()})
+				start = -1
+			}
+		default:
+			text.WriteRune(r)
+		}
+	}
+	f.Text = text.String()
+	return f
+}
+
+func makeFragments(hit *bsearch.DocumentMatch) (fragments []Fragment) {
+	for _, field := range []string{textFieldName, htmlFieldName} {
+		for _, highlighted := range hit.Fragments[field] {
+			fragments = append(fragments, makeFragment(highlighted))
+		}
+	}
+	return fragments
+}
+
 // Search executes the given query and returns the results in the form
-// of full KBFS paths to each hit.  `numResults` limits the number of
-// returned results, and `startingResult` indicates the number of
-// results that have been previously fetched -- basically it indicates
-// the starting index number of the next page of desired results.  The
-// return parameter `nextResult` indicates what `startingResult` could
-// be set to next time, to get more results, where -1 indicates that
-// there are no more results.
+// of full KBFS paths to each hit, along with the score of the hit and
+// any highlighted fragments of the file contents that matched.
+// `numResults` limits the number of returned results, and
+// `startingResult` indicates the number of results that have been
+// previously fetched -- basically it indicates the starting index
+// number of the next page of desired results.  `opts` restricts which
+// documents can match the query.  The return parameter `nextResult`
+// indicates what `startingResult` could be set to next time, to get
+// more results, where -1 indicates that there are no more results.
 func (i *Indexer) Search(
-	ctx context.Context, query string, numResults, startingResult int) (
-	results []Result, nextResult int, err error) {
+	ctx context.Context, query string, numResults, startingResult int,
+	opts SearchOptions) (results []Result, nextResult int, err error) {
 	if numResults == 0 {
 		return nil, 0, nil
 	}
@@ -1467,14 +1592,28 @@ func (i *Indexer) Search(
 		return nil, 0, errors.New("Index not loaded")
 	}
 
-	sQuery := bleve.NewQueryStringQuery(query)
+	var subtreeDocIDs []string
+	if opts.PathPrefix != "" {
+		subtreeDocIDs, err = i.docDb.Subtree(ctx, path.Clean(opts.PathPrefix))
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(subtreeDocIDs) == 0 {
+			return nil, -1, nil
+		}
+	}
+
+	sQuery := opts.makeQuery(query, subtreeDocIDs)
 	nextResult = startingResult
 	results = make([]Result, 0, numResults)
-	usedPaths := make(map[string]bool)
+	usedPaths := make(map[string]int)
 resultLoop:
 	for len(results) < numResults {
 		req := bleve.NewSearchRequestOptions(
 			sQuery, numResults, nextResult, false)
+		req.Highlight = bleve.NewHighlightWithStyle(highlighterName)
+		req.Highlight.AddField(textFieldName)
+		req.Highlight.AddField(htmlFieldName)
 		indexResults, err := i.index.Search(req)
 		if err != nil {
 			return nil, 0, err
@@ -1500,11 +1639,20 @@ resultLoop:
 				p[k], p[opp] = p[opp], p[k]
 			}
 			fullPath := path.Join(p...)
-			if usedPaths[fullPath] {
+			fragments := makeFragments(hit)
+			if k, ok := usedPaths[fullPath]; ok {
+				// The name and the contents of a file are separate
+				// docs, so keep any contents that matched as well.
+				results[k].Fragments = append(
+					results[k].Fragments, fragments...)
 				continue
 			}
-			usedPaths[fullPath] = true
-			results = append(results, Result{fullPath})
+			usedPaths[fullPath] = len(results)
+			results = append(results, Result{
+				Path:      fullPath,
+				Score:     hit.Score,
+				Fragments: fragments,
+			})
 
 			if len(results) >= numResults {
 				nextResult += j + 1
@@ -11,6 +11,7 @@ import (
 
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -155,6 +156,13 @@ func TestIndexFile(t *testing.T) {
 	defer os.RemoveAll(tempdir)
 	config.SetStorageRoot(tempdir)
 
+	t.Log("Leave some data from older index versions around")
+	for _, dir := range oldIndexDirs {
+		err = os.MkdirAll(
+			filepath.Join(tempdir, indexStorageDir, dir, "old"), 0700)
+		require.NoError(t, err)
+	}
+
 	i, err := newIndexerWithConfigInit(
 		config, testInitConfig, testKVStoreName("TestIndexFile"))
 	require.NoError(t, err)
@@ -163,6 +171,13 @@ func TestIndexFile(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
+	err = i.waitForIndex(ctx)
+	require.NoError(t, err)
+	for _, dir := range oldIndexDirs {
+		_, err = os.Stat(filepath.Join(tempdir, indexStorageDir, dir))
+		require.True(t, os.IsNotExist(err), dir)
+	}
+
 	h, err := tlfhandle.ParseHandle(
 		ctx, config.KBPKI(), config.MDOps(), nil, "user1", tlf.Private)
 	require.NoError(t, err)
@@ -466,8 +481,18 @@ func TestFullIndexSearch(t *testing.T) {
 	rootNode, _, err := kbfsOps.GetOrCreateRootNode(ctx, h, data.MasterBranch)
 	require.NoError(t, err)
 
-	t.Log("Create two dirs with two files each")
+	t.Log("Create a text file, and two dirs with two files each")
+	txtName := "gamma.txt"
+	txtNode, _, err := kbfsOps.CreateFile(
+		ctx, rootNode, data.NewPathPartString(txtName, nil), false,
+		libkbfs.NoExcl)
+	require.NoError(t, err)
+	err = kbfsOps.Write(ctx, txtNode, []byte("Dolor, in a text file."), 0)
+	require.NoError(t, err)
 	names := makeDirTreesToIndex(ctx, t, kbfsOps, rootNode)
+	ei, err := kbfsOps.Stat(ctx, txtNode)
+	require.NoError(t, err)
+	mtime := time.Unix(0, ei.Mtime)
 
 	t.Log("Wait for index to load")
 	err = i.waitForIndex(ctx)
@@ -486,9 +511,10 @@ func TestFullIndexSearch(t *testing.T) {
 	require.NoError(t, err)
 
 	t.Log("Search!")
-	checkSearch := func(
-		query string, numResults, start int, expectedResults map[string]bool) {
-		results, _, err := i.Search(ctx, query, numResults, start)
+	checkSearchWithOpts := func(
+		query string, numResults, start int, opts SearchOptions,
+		expectedResults map[string]bool) {
+		results, _, err := i.Search(ctx, query, numResults, start, opts)
 		require.NoError(t, err)
 		for _, r := range results {
 			_, ok := expectedResults[r.Path]
@@ -497,6 +523,11 @@ func TestFullIndexSearch(t *testing.T) {
 		}
 		require.Len(t, expectedResults, 0)
 	}
+	checkSearch := func(
+		query string, numResults, start int, expectedResults map[string]bool) {
+		checkSearchWithOpts(
+			query, numResults, start, SearchOptions{}, expectedResults)
+	}
 
 	userPath := func(dir, child string) string {
 		return path.Clean("/keybase/private/user1/" + dir + "/" + child)
@@ -505,6 +536,7 @@ func TestFullIndexSearch(t *testing.T) {
 	checkSearch("dolor", 10, 0, map[string]bool{
 		userPath(names[0], names[0]+"_file1"): true,
 		userPath(names[1], names[1]+"_file1"): true,
+		userPath("", txtName):                 true,
 	})
 
 	checkSearch(names[0], 10, 0, map[string]bool{
@@ -513,12 +545,82 @@ func TestFullIndexSearch(t *testing.T) {
 		userPath(names[0], names[0]+"_file2"): true,
 	})
 
+	t.Log("Check the highlighted fragments")
+	results, _, err := i.Search(
+		ctx, "tortor", 10, 0, SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NotZero(t, results[0].Score)
+	require.Len(t, results[0].Fragments, 1)
+	f := results[0].Fragments[0]
+	require.Equal(t,
+		"Ut feugiat dolor in tortor viverra, ac egestas justo tincidunt.",
+		f.Text)
+	require.Equal(t, []Match{{20, 26}}, f.Matches)
+
+	t.Log("Filter by TLF")
+	tlfID := rootNode.GetFolderBranch().Tlf
+	checkSearchWithOpts("dolor", 10, 0, SearchOptions{TlfID: tlfID},
+		map[string]bool{
+			userPath(names[0], names[0]+"_file1"): true,
+			userPath(names[1], names[1]+"_file1"): true,
+			userPath("", txtName):                 true,
+		})
+	checkSearchWithOpts("dolor", 10, 0,
+		SearchOptions{TlfID: tlf.FakeID(1, tlf.Private)}, map[string]bool{})
+
+	t.Log("Filter by path prefix")
+	checkSearchWithOpts("dolor", 10, 0,
+		SearchOptions{TlfID: tlfID, PathPrefix: userPath(names[1], "")},
+		map[string]bool{
+			userPath(names[1], names[1]+"_file1"): true,
+		})
+
+	t.Log("Page through results under a path prefix")
+	results, nextResult, err := i.Search(ctx, "dolor", 1, 0,
+		SearchOptions{TlfID: tlfID, PathPrefix: userPath(names[0], "")})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, userPath(names[0], names[0]+"_file1"), results[0].Path)
+	require.Equal(t, 1, nextResult)
+	results, nextResult, err = i.Search(ctx, "dolor", 1, nextResult,
+		SearchOptions{TlfID: tlfID, PathPrefix: userPath(names[0], "")})
+	require.NoError(t, err)
+	require.Len(t, results, 0)
+	require.Equal(t, -1, nextResult)
+	results, nextResult, err = i.Search(ctx, "dolor", 10, 0,
+		SearchOptions{TlfID: tlfID, PathPrefix: userPath("missing", "")})
+	require.NoError(t, err)
+	require.Len(t, results, 0)
+	require.Equal(t, -1, nextResult)
+
+	t.Log("Filter by file type")
+	checkSearchWithOpts("dolor", 10, 0, SearchOptions{FileType: "TXT"},
+		map[string]bool{
+			userPath("", txtName): true,
+		})
+
+	t.Log("Filter by modification time")
+	checkSearchWithOpts("dolor", 10, 0,
+		SearchOptions{MtimeBefore: mtime.Add(-time.Second)},
+		map[string]bool{})
+	checkSearchWithOpts("gamma", 10, 0,
+		SearchOptions{
+			MtimeAfter:  mtime.Add(-time.Second),
+			MtimeBefore: mtime.Add(time.Second),
+		},
+		map[string]bool{
+			userPath("", txtName): true,
+		})
+
 	t.Log("Try partial results")
-	results, nextResult, err := i.Search(ctx, names[0], 2, 0)
+	results, nextResult, err = i.Search(
+		ctx, names[0], 2, 0, SearchOptions{})
 	require.NoError(t, err)
 	require.Len(t, results, 2)
 	require.Equal(t, 2, nextResult)
-	results2, nextResult2, err := i.Search(ctx, names[0], 2, nextResult)
+	results2, nextResult2, err := i.Search(
+		ctx, names[0], 2, nextResult, SearchOptions{})
 	require.NoError(t, err)
 	require.Len(t, results2, 1)
 	require.Equal(t, -1, nextResult2)
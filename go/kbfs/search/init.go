@@ -21,11 +21,16 @@ const (
 	bserverStorageDir  = "bserver"
 	mdserverStorageDir = "mdserver"
 
-	currentIndexVersion = "v1"
+	currentIndexVersion = "v2"
 
 	indexBlocksInCache = 100
 )
 
+// oldIndexDirs are the directories under `indexStorageDir` used by
+// index versions before `currentIndexVersion`.  The first version
+// kept its Bleve index directly under `indexStorageDir`.
+var oldIndexDirs = []string{bleveIndexDir, "v1"}
+
 // Params returns a set of default parameters for search-related
 // operations.
 func Params(kbCtx libkbfs.Context, storageRoot string, uid keybase1.UID) (
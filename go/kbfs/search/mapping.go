@@ -7,16 +7,36 @@ package search
 import (
 	"github.com/blevesearch/bleve"
 	"github.com/blevesearch/bleve/analysis"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
 	"github.com/blevesearch/bleve/analysis/char/html"
 	"github.com/blevesearch/bleve/analysis/token/lowercase"
 	"github.com/blevesearch/bleve/analysis/tokenizer/web"
 	"github.com/blevesearch/bleve/mapping"
 	"github.com/blevesearch/bleve/registry"
+	"github.com/blevesearch/bleve/search/highlight"
+	htmlFormatter "github.com/blevesearch/bleve/search/highlight/format/html"
+	simpleFragmenter "github.com/blevesearch/bleve/search/highlight/fragmenter/simple"
+	simpleHighlighter "github.com/blevesearch/bleve/search/highlight/highlighter/simple"
 )
 
 const (
 	htmlAnalyzerName = "kbfsHTML"
 	htmlFieldName    = "HTML"
+	textFieldName    = "Text"
+	baseFieldName    = "Base"
+	tlfIDFieldName   = "TlfID"
+	mtimeFieldName   = "Mtime"
+	extFieldName     = "Ext"
+
+	highlighterName       = "kbfsHighlighter"
+	fragmentFormatterName = "kbfsFragmentFormatter"
+
+	// The formatter wraps each match in a fragment with these
+	// control characters, which content sniffing doesn't allow in
+	// text documents, so that `Search` can turn the fragment back
+	// into plain text and match offsets.
+	matchStartMarker = "\x02"
+	matchEndMarker   = "\x03"
 )
 
 func htmlAnalyzerConstructor(
@@ -46,8 +66,54 @@ func htmlAnalyzerConstructor(
 	return &rv, nil
 }
 
+func fragmentFormatterConstructor(
+	config map[string]interface{}, cache *registry.Cache) (
+	highlight.FragmentFormatter, error) {
+	return htmlFormatter.NewFragmentFormatter(
+		matchStartMarker, matchEndMarker), nil
+}
+
+func highlighterConstructor(
+	config map[string]interface{}, cache *registry.Cache) (
+	highlight.Highlighter, error) {
+	fragmenter, err := cache.FragmenterNamed(simpleFragmenter.Name)
+	if err != nil {
+		return nil, err
+	}
+	formatter, err := cache.FragmentFormatterNamed(fragmentFormatterName)
+	if err != nil {
+		return nil, err
+	}
+	return simpleHighlighter.NewHighlighter(
+		fragmenter, formatter, simpleHighlighter.DefaultSeparator), nil
+}
+
 func init() {
 	registry.RegisterAnalyzer(htmlAnalyzerName, htmlAnalyzerConstructor)
+	registry.RegisterFragmentFormatter(
+		fragmentFormatterName, fragmentFormatterConstructor)
+	registry.RegisterHighlighter(highlighterName, highlighterConstructor)
+}
+
+// addBaseMapping maps the fields of the base doc that searches can be
+// filtered on as single keywords, rather than as text.  The TLF ID in
+// particular won't be indexed at all without an explicit mapping.
+func addBaseMapping(docMapping *mapping.DocumentMapping) {
+	baseMapping := mapping.NewDocumentMapping()
+
+	tlfIDFieldMapping := mapping.NewTextFieldMapping()
+	tlfIDFieldMapping.Analyzer = keyword.Name
+	tlfIDFieldMapping.Store = false
+	tlfIDFieldMapping.IncludeTermVectors = false
+	baseMapping.AddFieldMappingsAt(tlfIDFieldName, tlfIDFieldMapping)
+
+	extFieldMapping := mapping.NewTextFieldMapping()
+	extFieldMapping.Analyzer = keyword.Name
+	extFieldMapping.Store = false
+	extFieldMapping.IncludeTermVectors = false
+	baseMapping.AddFieldMappingsAt(extFieldName, extFieldMapping)
+
+	docMapping.AddSubDocumentMapping(baseFieldName, baseMapping)
 }
 
 func makeIndexMapping() (*mapping.IndexMappingImpl, error) {
@@ -55,13 +121,17 @@ func makeIndexMapping() (*mapping.IndexMappingImpl, error) {
 	// text files we can mark them as such.
 	indexMapping := bleve.NewIndexMapping()
 
+	addBaseMapping(indexMapping.DefaultMapping)
+
 	textMapping := mapping.NewDocumentMapping()
+	addBaseMapping(textMapping)
 	indexMapping.AddDocumentMapping(textFileType, textMapping)
 
 	htmlFieldMapping := mapping.NewTextFieldMapping()
 	htmlFieldMapping.Analyzer = htmlAnalyzerName
 	htmlDocMapping := mapping.NewDocumentMapping()
 	htmlDocMapping.AddFieldMappingsAt(htmlFieldName, htmlFieldMapping)
+	addBaseMapping(htmlDocMapping)
 	indexMapping.AddDocumentMapping(htmlFileType, htmlDocMapping)
 
 	return indexMapping, nil
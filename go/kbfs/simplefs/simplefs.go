@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,6 +17,7 @@ import (
 	stdpath "path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -29,6 +31,8 @@ import (
 	"github.com/adamwalz/keybase-client/go/kbfs/libfs"
 	"github.com/adamwalz/keybase-client/go/kbfs/libhttpserver"
 	"github.com/adamwalz/keybase-client/go/kbfs/libkbfs"
+	"github.com/adamwalz/keybase-client/go/kbfs/libs3gateway"
+	"github.com/adamwalz/keybase-client/go/kbfs/libwebdav"
 	"github.com/adamwalz/keybase-client/go/kbfs/search"
 	"github.com/adamwalz/keybase-client/go/kbfs/tlf"
 	"github.com/adamwalz/keybase-client/go/kbfs/tlfhandle"
@@ -159,6 +163,12 @@ type SimpleFS struct {
 
 	localHTTPServer *libhttpserver.Server
 
+	webdavLock   sync.Mutex
+	webdavServer *libwebdav.Server
+
+	s3GatewayLock sync.Mutex
+	s3Gateway     *libs3gateway.Server
+
 	subscriptionNotifier libkbfs.SubscriptionNotifier
 
 	downloadManager *downloadManager
@@ -1822,6 +1832,82 @@ func (k *SimpleFS) SimpleFSSetStat(
 	return changeFS.Chmod(finalElem, mode)
 }
 
+func (k *SimpleFS) getLibFS(
+	ctx context.Context, path keybase1.Path) (*libfs.FS, string, error) {
+	fs, finalElem, err := k.getFS(ctx, path)
+	if err != nil {
+		return nil, "", err
+	}
+	asLibFS, ok := fs.(*libfs.FS)
+	if !ok {
+		return nil, "", errors.Errorf("FS was not a KBFS file system: %T", fs)
+	}
+	return asLibFS, finalElem, nil
+}
+
+// SimpleFSListXattrs - List the user extended attributes of a file
+// or directory.
+func (k *SimpleFS) SimpleFSListXattrs(
+	ctx context.Context, path keybase1.Path) (
+	res []keybase1.SimpleFSXattr, err error) {
+	defer func() { err = translateErr(err) }()
+	ctx, err = k.startSyncOp(ctx, "ListXattrs", path, &path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { k.doneSyncOp(ctx, err) }()
+
+	fs, finalElem, err := k.getLibFS(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	xattrs, err := fs.Xattrs(finalElem)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range xattrs {
+		res = append(res, keybase1.SimpleFSXattr{Name: name, Value: value})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// SimpleFSSetXattr - Set a user extended attribute on a file or
+// directory.
+func (k *SimpleFS) SimpleFSSetXattr(
+	ctx context.Context, arg keybase1.SimpleFSSetXattrArg) (err error) {
+	defer func() { err = translateErr(err) }()
+	ctx, err = k.startSyncOp(ctx, "SetXattr", arg, &arg.Path, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { k.doneSyncOp(ctx, err) }()
+
+	fs, finalElem, err := k.getLibFS(ctx, arg.Path)
+	if err != nil {
+		return err
+	}
+	return fs.SetXattr(finalElem, arg.Name, arg.Value)
+}
+
+// SimpleFSRemoveXattr - Remove a user extended attribute from a file
+// or directory.
+func (k *SimpleFS) SimpleFSRemoveXattr(
+	ctx context.Context, arg keybase1.SimpleFSRemoveXattrArg) (err error) {
+	defer func() { err = translateErr(err) }()
+	ctx, err = k.startSyncOp(ctx, "RemoveXattr", arg, &arg.Path, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { k.doneSyncOp(ctx, err) }()
+
+	fs, finalElem, err := k.getLibFS(ctx, arg.Path)
+	if err != nil {
+		return err
+	}
+	return fs.RemoveXattr(finalElem, arg.Name)
+}
+
 func (k *SimpleFS) startReadWriteOp(
 	ctx context.Context, opid keybase1.OpID, opType keybase1.AsyncOps,
 	desc keybase1.OpDescription) (context.Context, error) {
@@ -3334,6 +3420,157 @@ func (k *SimpleFS) SimpleFSGetGUIFileContext(ctx context.Context,
 	}, nil
 }
 
+// SimpleFSStartWebDAV implements the SimpleFSInterface.
+func (k *SimpleFS) SimpleFSStartWebDAV(ctx context.Context, port int) (
+	info keybase1.SimpleFSWebDAVServer, err error) {
+	defer func() {
+		k.log.CDebugf(ctx, "SimpleFSStartWebDAV port=%d err=%+v", port, err)
+	}()
+	k.webdavLock.Lock()
+	defer k.webdavLock.Unlock()
+	if k.webdavServer == nil {
+		k.webdavServer, err = libwebdav.New(k.config, port)
+		if err != nil {
+			return keybase1.SimpleFSWebDAVServer{}, err
+		}
+	}
+
+	address, err := k.webdavServer.Address()
+	if err != nil {
+		return keybase1.SimpleFSWebDAVServer{}, err
+	}
+	if port != 0 {
+		_, runningPort, err := net.SplitHostPort(address)
+		if err != nil {
+			return keybase1.SimpleFSWebDAVServer{}, err
+		}
+		if runningPort != strconv.Itoa(port) {
+			return keybase1.SimpleFSWebDAVServer{}, fmt.Errorf(
+				"a WebDAV server is already running on port %s; "+
+					"stop it first to use port %d", runningPort, port)
+		}
+	}
+	token, err := k.webdavServer.CurrentToken()
+	if err != nil {
+		return keybase1.SimpleFSWebDAVServer{}, err
+	}
+	u := url.URL{
+		Scheme: "http",
+		Host:   address,
+		Path:   "/",
+	}
+	return keybase1.SimpleFSWebDAVServer{
+		Url:   u.String(),
+		Token: token,
+	}, nil
+}
+
+// SimpleFSStopWebDAV implements the SimpleFSInterface.
+func (k *SimpleFS) SimpleFSStopWebDAV(ctx context.Context) error {
+	k.webdavLock.Lock()
+	defer k.webdavLock.Unlock()
+	if k.webdavServer == nil {
+		return nil
+	}
+	k.log.CDebugf(ctx, "Stopping WebDAV server")
+	k.webdavServer.Shutdown()
+	k.webdavServer = nil
+	return nil
+}
+
+func (k *SimpleFS) s3GatewayInfo() (keybase1.SimpleFSS3Gateway, error) {
+	address, err := k.s3Gateway.Address()
+	if err != nil {
+		return keybase1.SimpleFSS3Gateway{}, err
+	}
+	u := url.URL{
+		Scheme: "http",
+		Host:   address,
+		Path:   "/",
+	}
+	return keybase1.SimpleFSS3Gateway{
+		Url:       u.String(),
+		AccessKey: k.s3Gateway.AccessKey(),
+		SecretKey: k.s3Gateway.SecretKey(),
+		Buckets:   k.s3Gateway.BucketNames(),
+	}, nil
+}
+
+// SimpleFSStartS3Gateway implements the SimpleFSInterface.
+func (k *SimpleFS) SimpleFSStartS3Gateway(
+	ctx context.Context, arg keybase1.SimpleFSStartS3GatewayArg) (
+	info keybase1.SimpleFSS3Gateway, err error) {
+	defer func() {
+		k.log.CDebugf(ctx, "SimpleFSStartS3Gateway port=%d buckets=%d err=%+v",
+			arg.Port, len(arg.Buckets), err)
+	}()
+	k.s3GatewayLock.Lock()
+	defer k.s3GatewayLock.Unlock()
+	if k.s3Gateway != nil {
+		if len(arg.Buckets) > 0 || arg.Port != 0 {
+			return keybase1.SimpleFSS3Gateway{}, errors.New(
+				"The S3 gateway is already running; stop it first")
+		}
+		return k.s3GatewayInfo()
+	}
+	if len(arg.Buckets) == 0 {
+		return keybase1.SimpleFSS3Gateway{}, errors.New(
+			"The S3 gateway isn't running, and no buckets were given")
+	}
+
+	buckets := make([]libs3gateway.Bucket, 0, len(arg.Buckets))
+	for _, b := range arg.Buckets {
+		t, tlfName, middlePath, finalElem, err := remoteTlfAndPath(
+			keybase1.NewPathWithKbfs(b.Path))
+		if err != nil {
+			return keybase1.SimpleFSS3Gateway{}, err
+		}
+		subdir := stdpath.Join(middlePath, finalElem)
+		if subdir != "" {
+			// Make sure the directory exists, so that bad paths are
+			// caught now rather than on every request.
+			parentFS, name, err := k.getFSIfExists(
+				ctx, keybase1.NewPathWithKbfs(b.Path))
+			if err != nil {
+				return keybase1.SimpleFSS3Gateway{}, err
+			}
+			fi, err := parentFS.Stat(name)
+			if err != nil {
+				return keybase1.SimpleFSS3Gateway{}, err
+			}
+			if !fi.IsDir() {
+				return keybase1.SimpleFSS3Gateway{}, errors.Errorf(
+					"%s is not a directory", b.Path.Path)
+			}
+		}
+		buckets = append(buckets, libs3gateway.Bucket{
+			Name:    b.Name,
+			TlfType: t,
+			TlfName: tlfName,
+			Subdir:  subdir,
+		})
+	}
+
+	k.s3Gateway, err = libs3gateway.New(k.config, arg.Port, buckets)
+	if err != nil {
+		return keybase1.SimpleFSS3Gateway{}, err
+	}
+	return k.s3GatewayInfo()
+}
+
+// SimpleFSStopS3Gateway implements the SimpleFSInterface.
+func (k *SimpleFS) SimpleFSStopS3Gateway(ctx context.Context) error {
+	k.s3GatewayLock.Lock()
+	defer k.s3GatewayLock.Unlock()
+	if k.s3Gateway == nil {
+		return nil
+	}
+	k.log.CDebugf(ctx, "Stopping S3 gateway")
+	k.s3Gateway.Shutdown()
+	k.s3Gateway = nil
+	return nil
+}
+
 const kbfsOpsWaitDuration = 200 * time.Millisecond
 const kbfsOpsWaitTimeout = 4 * time.Second
 
@@ -3397,6 +3634,59 @@ func (k *SimpleFS) SimpleFSSetSyncOnCellular(
 	return nil
 }
 
+// resolveSearchPath returns the TLF ID and the canonical path (as
+// used in search results) of a KBFS path given as a search filter.
+func (k *SimpleFS) resolveSearchPath(ctx context.Context, p string) (
+	tlfID tlf.ID, canonicalPath string, err error) {
+	t, tlfName, middlePath, finalElem, err := remoteTlfAndPath(
+		keybase1.NewPathWithKbfsPath(p))
+	if err != nil {
+		return tlf.NullID, "", err
+	}
+	kbpki, err := k.getKBPKI(ctx)
+	if err != nil {
+		return tlf.NullID, "", err
+	}
+	tlfHandle, err := libkbfs.GetHandleFromFolderNameAndType(
+		ctx, kbpki, k.config.MDOps(), k.config, tlfName, t)
+	if err != nil {
+		return tlf.NullID, "", err
+	}
+	return tlfHandle.TlfID(), stdpath.Join(
+		tlfHandle.GetCanonicalPath(), middlePath, finalElem), nil
+}
+
+func (k *SimpleFS) searchOptionsFromFilters(
+	ctx context.Context, filters keybase1.SimpleFSSearchFilters) (
+	opts search.SearchOptions, err error) {
+	if filters.Tlf != "" {
+		opts.TlfID, _, err = k.resolveSearchPath(ctx, filters.Tlf)
+		if err != nil {
+			return search.SearchOptions{}, err
+		}
+	}
+	if filters.PathPrefix != "" {
+		tlfID, prefix, err := k.resolveSearchPath(ctx, filters.PathPrefix)
+		if err != nil {
+			return search.SearchOptions{}, err
+		}
+		if opts.TlfID != tlf.NullID && opts.TlfID != tlfID {
+			return search.SearchOptions{}, simpleFSError{
+				reason: "Path prefix is not in the given folder"}
+		}
+		opts.TlfID = tlfID
+		opts.PathPrefix = prefix
+	}
+	opts.FileType = filters.FileType
+	if filters.ModifiedAfter != 0 {
+		opts.MtimeAfter = keybase1.FromTime(filters.ModifiedAfter)
+	}
+	if filters.ModifiedBefore != 0 {
+		opts.MtimeBefore = keybase1.FromTime(filters.ModifiedBefore)
+	}
+	return opts, nil
+}
+
 // SimpleFSSearch implements the SimpleFSInterface.
 func (k *SimpleFS) SimpleFSSearch(
 	ctx context.Context, arg keybase1.SimpleFSSearchArg) (
@@ -3406,8 +3696,13 @@ func (k *SimpleFS) SimpleFSSearch(
 			errors.New("Indexing not enabled")
 	}
 
+	opts, err := k.searchOptionsFromFilters(ctx, arg.Filters)
+	if err != nil {
+		return keybase1.SimpleFSSearchResults{}, err
+	}
+
 	results, nextResult, err := k.indexer.Search(
-		ctx, arg.Query, arg.NumResults, arg.StartingFrom)
+		ctx, arg.Query, arg.NumResults, arg.StartingFrom, opts)
 	if err != nil {
 		return keybase1.SimpleFSSearchResults{}, err
 	}
@@ -3415,6 +3710,18 @@ func (k *SimpleFS) SimpleFSSearch(
 	res.Hits = make([]keybase1.SimpleFSSearchHit, len(results))
 	for i, result := range results {
 		res.Hits[i].Path = result.Path
+		res.Hits[i].Score = result.Score
+		res.Hits[i].Fragments = make(
+			[]keybase1.SimpleFSSearchFragment, len(result.Fragments))
+		for j, f := range result.Fragments {
+			res.Hits[i].Fragments[j].Text = f.Text
+			res.Hits[i].Fragments[j].Matches = make(
+				[]keybase1.SimpleFSSearchMatch, len(f.Matches))
+			for l, m := range f.Matches {
+				res.Hits[i].Fragments[j].Matches[l] =
+					keybase1.SimpleFSSearchMatch{Start: m.Start, End: m.End}
+			}
+		}
 	}
 	res.NextResult = nextResult
 	return res, nil
@@ -3562,6 +3869,8 @@ func (k *SimpleFS) SimpleFSCancelJournalUploads(
 
 // Shutdown shuts down SimpleFS.
 func (k *SimpleFS) Shutdown(ctx context.Context) error {
+	_ = k.SimpleFSStopWebDAV(ctx)
+	_ = k.SimpleFSStopS3Gateway(ctx)
 	if k.indexer == nil {
 		return nil
 	}
@@ -8,11 +8,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -980,6 +981,67 @@ func TestRemove(t *testing.T) {
 	testList(ctx, t, sfs, pathKbfs)
 }
 
+func TestXattrs(t *testing.T) {
+	ctx := context.Background()
+	config := libkbfs.MakeTestConfigOrBust(t, "jdoe")
+	config.SetUserXattrsEnabled(true)
+	sfs := newSimpleFS(env.EmptyAppStateUpdater{}, config)
+	defer closeSimpleFS(ctx, t, sfs)
+
+	t.Log("Make a file to tag")
+	pathKbfs := keybase1.NewPathWithKbfsPath("/private/jdoe")
+	pathFile := pathAppend(pathKbfs, "test.txt")
+	writeRemoteFile(ctx, t, sfs, pathFile, []byte("foo"))
+	syncFS(ctx, t, sfs, "/private/jdoe")
+
+	xattrs, err := sfs.SimpleFSListXattrs(ctx, pathFile)
+	require.NoError(t, err)
+	require.Len(t, xattrs, 0)
+
+	t.Log("Set some xattrs")
+	for _, name := range []string{"user.b", "user.a"} {
+		err = sfs.SimpleFSSetXattr(ctx, keybase1.SimpleFSSetXattrArg{
+			Path:  pathFile,
+			Name:  name,
+			Value: []byte(name + " value"),
+		})
+		require.NoError(t, err)
+	}
+	err = sfs.SimpleFSSetXattr(ctx, keybase1.SimpleFSSetXattrArg{
+		Path:  pathFile,
+		Name:  "trusted.a",
+		Value: []byte("nope"),
+	})
+	require.Error(t, err)
+	syncFS(ctx, t, sfs, "/private/jdoe")
+
+	xattrs, err = sfs.SimpleFSListXattrs(ctx, pathFile)
+	require.NoError(t, err)
+	require.Equal(t, []keybase1.SimpleFSXattr{
+		{Name: "user.a", Value: []byte("user.a value")},
+		{Name: "user.b", Value: []byte("user.b value")},
+	}, xattrs)
+
+	t.Log("Remove one")
+	err = sfs.SimpleFSRemoveXattr(ctx, keybase1.SimpleFSRemoveXattrArg{
+		Path: pathFile,
+		Name: "user.a",
+	})
+	require.NoError(t, err)
+	err = sfs.SimpleFSRemoveXattr(ctx, keybase1.SimpleFSRemoveXattrArg{
+		Path: pathFile,
+		Name: "user.a",
+	})
+	require.Error(t, err)
+	syncFS(ctx, t, sfs, "/private/jdoe")
+
+	xattrs, err = sfs.SimpleFSListXattrs(ctx, pathFile)
+	require.NoError(t, err)
+	require.Equal(t, []keybase1.SimpleFSXattr{
+		{Name: "user.b", Value: []byte("user.b value")},
+	}, xattrs)
+}
+
 func TestRemoveRecursive(t *testing.T) {
 	ctx := context.Background()
 	sfs := newSimpleFS(
@@ -1799,3 +1861,32 @@ func TestProfiles(t *testing.T) {
 	buf = readRemoteFile(ctx, t, sfs, pathAppend(path, "goroutine"))
 	require.NotEmpty(t, buf)
 }
+
+func TestStartWebDAV(t *testing.T) {
+	ctx := context.Background()
+	sfs := newSimpleFS(
+		env.EmptyAppStateUpdater{}, libkbfs.MakeTestConfigOrBust(t, "jdoe"))
+	defer closeSimpleFS(ctx, t, sfs)
+
+	info, err := sfs.SimpleFSStartWebDAV(ctx, 0)
+	require.NoError(t, err)
+	u, err := url.Parse(info.Url)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	t.Log("Starting again returns the running server")
+	info2, err := sfs.SimpleFSStartWebDAV(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, info, info2)
+	info2, err = sfs.SimpleFSStartWebDAV(ctx, port)
+	require.NoError(t, err)
+	require.Equal(t, info, info2)
+
+	t.Log("Asking for a different port while running is an error")
+	_, err = sfs.SimpleFSStartWebDAV(ctx, port+1)
+	require.Error(t, err)
+
+	err = sfs.SimpleFSStopWebDAV(ctx)
+	require.NoError(t, err)
+}
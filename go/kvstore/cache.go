@@ -36,9 +36,9 @@ func NewKVRevisionCache(g *libkb.GlobalContext) *KVRevisionCache {
 	return kvr
 }
 
-// Hash is a sha256 on the input string. If the string is empty, then Hash will also be an
+// hashCiphertext is a sha256 on the input string. If the string is empty, then the hash will also be an
 // empty string for tracking deleted entries in perpetuity.
-func (k *KVRevisionCache) hash(ciphertext *string) string {
+func hashCiphertext(ciphertext *string) string {
 	if ciphertext == nil || len(*ciphertext) == 0 || *ciphertext == DeletedOrNonExistent {
 		return DeletedOrNonExistent
 	}
@@ -54,7 +54,12 @@ func (k *KVRevisionCache) checkLocked(mctx libkb.MetaContext, entryID keybase1.K
 		// this entry didn't exist in the cache, so there's nothing to check
 		return nil
 	}
-	entryHash := k.hash(ciphertext)
+	return checkEntry(entry, hashCiphertext(ciphertext), teamKeyGen, revision)
+}
+
+// checkEntry makes sure an entry from the server isn't older than, or
+// inconsistent with, the last version of it that we've seen.
+func checkEntry(entry kvCacheEntry, entryHash string, teamKeyGen keybase1.PerTeamKeyGeneration, revision int) error {
 	if revision < entry.Revision {
 		return KVCacheError{fmt.Sprintf("cache error: revision decreased from %d to %d", entry.Revision, revision)}
 	}
@@ -88,7 +93,7 @@ func (k *KVRevisionCache) Put(mctx libkb.MetaContext, entryID keybase1.KVEntryID
 		return err
 	}
 
-	entryHash := k.hash(ciphertext)
+	entryHash := hashCiphertext(ciphertext)
 	newEntry := kvCacheEntry{
 		EntryHash:  entryHash,
 		TeamKeyGen: teamKeyGen,
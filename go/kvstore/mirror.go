@@ -0,0 +1,195 @@
+package kvstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/adamwalz/keybase-client/go/encrypteddb"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"golang.org/x/net/context"
+)
+
+const kvMirrorDiskVersion = 1
+
+// KVMirrorEntry is the last version of an entry that this device has read or
+// written, stored decrypted (but encrypted at rest with a device key) so that
+// it can be served while the server is unreachable.
+type KVMirrorEntry struct {
+	Version    int
+	TeamID     keybase1.TeamID
+	Namespace  string
+	EntryKey   string
+	EntryValue *string // nil if the entry was deleted or never set
+	Revision   int
+	EntryHash  string
+	TeamKeyGen keybase1.PerTeamKeyGeneration
+	Ctime      keybase1.Time // when we last saw this revision from the server
+}
+
+type kvMirrorTeam struct {
+	Version int
+	TeamID  keybase1.TeamID
+}
+
+// KVMirror is a local, encrypted copy of the kvstore entries that the current
+// user has read or written. Besides serving stale reads while offline, it
+// persists the KVRevisionCache rollback checks across restarts.
+type KVMirror struct {
+	sync.Mutex
+	edb *encrypteddb.EncryptedDB
+}
+
+func NewKVMirror(g *libkb.GlobalContext) *KVMirror {
+	keyFn := func(ctx context.Context) ([32]byte, error) {
+		return encrypteddb.GetSecretBoxKey(ctx, g, libkb.EncryptionReasonKVStoreLocalStorage, "kvstore mirror")
+	}
+	dbFn := func(g *libkb.GlobalContext) *libkb.JSONLocalDb {
+		return g.LocalDb
+	}
+	return &KVMirror{
+		edb: encrypteddb.New(g, dbFn, keyFn),
+	}
+}
+
+// Keys are hashed so that team names, namespaces and entry keys aren't left
+// in the clear on disk.
+func (m *KVMirror) dbKey(mctx libkb.MetaContext, parts ...string) libkb.DbKey {
+	h := sha256.New()
+	for _, part := range parts {
+		_, _ = h.Write([]byte(part))
+		_, _ = h.Write([]byte{0})
+	}
+	return libkb.DbKey{
+		Typ: libkb.DBKVStoreMirror,
+		Key: fmt.Sprintf("%s:%s", mctx.G().GetMyUID(), hex.EncodeToString(h.Sum(nil)[:16])),
+	}
+}
+
+func (m *KVMirror) teamKey(mctx libkb.MetaContext, teamName string) libkb.DbKey {
+	return m.dbKey(mctx, "team", teamName)
+}
+
+func (m *KVMirror) entryKey(mctx libkb.MetaContext, entryID keybase1.KVEntryID) libkb.DbKey {
+	return m.dbKey(mctx, "entry", entryID.TeamID.String(), entryID.Namespace, entryID.EntryKey)
+}
+
+// PutTeamID remembers what a team name resolved to, so that entries can be
+// found by name without asking the server.
+func (m *KVMirror) PutTeamID(mctx libkb.MetaContext, teamName string, teamID keybase1.TeamID) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.edb.Put(mctx.Ctx(), m.teamKey(mctx, teamName), kvMirrorTeam{
+		Version: kvMirrorDiskVersion,
+		TeamID:  teamID,
+	})
+}
+
+func (m *KVMirror) LookupTeamID(mctx libkb.MetaContext, teamName string) (teamID keybase1.TeamID, found bool, err error) {
+	m.Lock()
+	defer m.Unlock()
+	var team kvMirrorTeam
+	found, err = m.edb.Get(mctx.Ctx(), m.teamKey(mctx, teamName), &team)
+	if err != nil || !found {
+		return teamID, false, err
+	}
+	if team.Version != kvMirrorDiskVersion {
+		mctx.Debug("KVMirror: found team with the wrong version (%d != %d) so returning 'not found'", team.Version, kvMirrorDiskVersion)
+		return teamID, false, nil
+	}
+	return team.TeamID, true, nil
+}
+
+func (m *KVMirror) getLocked(mctx libkb.MetaContext, entryID keybase1.KVEntryID) (entry KVMirrorEntry, found bool, err error) {
+	found, err = m.edb.Get(mctx.Ctx(), m.entryKey(mctx, entryID), &entry)
+	if err != nil || !found {
+		return KVMirrorEntry{}, false, err
+	}
+	if entry.Version != kvMirrorDiskVersion {
+		mctx.Debug("KVMirror: found entry with the wrong version (%d != %d) so returning 'not found'", entry.Version, kvMirrorDiskVersion)
+		return KVMirrorEntry{}, false, nil
+	}
+	return entry, true, nil
+}
+
+func (m *KVMirror) Get(mctx libkb.MetaContext, entryID keybase1.KVEntryID) (entry KVMirrorEntry, found bool, err error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.getLocked(mctx, entryID)
+}
+
+func (m *KVMirror) checkLocked(mctx libkb.MetaContext, entryID keybase1.KVEntryID, entryHash string, teamKeyGen keybase1.PerTeamKeyGeneration, revision int) error {
+	entry, found, err := m.getLocked(mctx, entryID)
+	if err != nil {
+		// the mirror is best-effort, so don't fail reads just because it
+		// can't be read
+		mctx.Debug("KVMirror: unable to read %+v, skipping check: %v", entryID, err)
+		return nil
+	}
+	if !found {
+		// this entry isn't mirrored, so there's nothing to check
+		return nil
+	}
+	return checkEntry(kvCacheEntry{
+		Revision:   entry.Revision,
+		EntryHash:  entry.EntryHash,
+		TeamKeyGen: entry.TeamKeyGen,
+	}, entryHash, teamKeyGen, revision)
+}
+
+// Check is the same rollback check that KVRevisionCache does, against the
+// last version of the entry that this device saw, even before a restart.
+func (m *KVMirror) Check(mctx libkb.MetaContext, entryID keybase1.KVEntryID, ciphertext *string, teamKeyGen keybase1.PerTeamKeyGeneration, revision int) (err error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.checkLocked(mctx, entryID, hashCiphertext(ciphertext), teamKeyGen, revision)
+}
+
+// Put stores a verified entry from the server along with its decrypted
+// value.
+func (m *KVMirror) Put(mctx libkb.MetaContext, entryID keybase1.KVEntryID, entryValue *string, ciphertext *string, teamKeyGen keybase1.PerTeamKeyGeneration, revision int) (err error) {
+	m.Lock()
+	defer m.Unlock()
+	entryHash := hashCiphertext(ciphertext)
+	if err := m.checkLocked(mctx, entryID, entryHash, teamKeyGen, revision); err != nil {
+		return err
+	}
+	if entryHash == DeletedOrNonExistent {
+		entryValue = nil
+	}
+	return m.edb.Put(mctx.Ctx(), m.entryKey(mctx, entryID), KVMirrorEntry{
+		Version:    kvMirrorDiskVersion,
+		TeamID:     entryID.TeamID,
+		Namespace:  entryID.Namespace,
+		EntryKey:   entryID.EntryKey,
+		EntryValue: entryValue,
+		Revision:   revision,
+		EntryHash:  entryHash,
+		TeamKeyGen: teamKeyGen,
+		Ctime:      keybase1.ToTime(mctx.G().Clock().Now()),
+	})
+}
+
+func (m *KVMirror) MarkDeleted(mctx libkb.MetaContext, entryID keybase1.KVEntryID, revision int) (err error) {
+	m.Lock()
+	defer m.Unlock()
+	existing, found, err := m.getLocked(mctx, entryID)
+	if err != nil {
+		return err
+	}
+	if found && revision <= existing.Revision {
+		return NewKVRevisionError("" /* use the default out-of-date message */)
+	}
+	return m.edb.Put(mctx.Ctx(), m.entryKey(mctx, entryID), KVMirrorEntry{
+		Version:    kvMirrorDiskVersion,
+		TeamID:     entryID.TeamID,
+		Namespace:  entryID.Namespace,
+		EntryKey:   entryID.EntryKey,
+		Revision:   revision,
+		EntryHash:  DeletedOrNonExistent,
+		TeamKeyGen: existing.TeamKeyGen, // nothing gets encrypted here, so this should just roll forward or default to 0
+		Ctime:      keybase1.ToTime(mctx.G().Clock().Now()),
+	})
+}
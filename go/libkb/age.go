@@ -0,0 +1,603 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package libkb
+
+// An implementation of the age file format (https://age-encryption.org/v1)
+// for Keybase's Curve25519 device and paper encryption keys, which are
+// X25519 keys as far as age is concerned. Only X25519 recipient stanzas are
+// written or understood.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	ageVersionLine   = "age-encryption.org/v1"
+	ageArmorHeader   = "-----BEGIN AGE ENCRYPTED FILE-----"
+	ageArmorFooter   = "-----END AGE ENCRYPTED FILE-----"
+	ageX25519Label   = "age-encryption.org/v1/X25519"
+	ageFileKeySize   = 16
+	ageNonceSize     = 16
+	ageChunkSize     = 64 * 1024
+	ageColumns       = 64
+	ageMaxHeaderSize = 1 << 20
+)
+
+var ageB64 = base64.RawStdEncoding.Strict()
+
+// ErrAgeMalformed is returned for age files that don't follow the spec.
+type ErrAgeMalformed struct {
+	Msg string
+}
+
+func (e ErrAgeMalformed) Error() string {
+	return fmt.Sprintf("malformed age file: %s", e.Msg)
+}
+
+func isAgeMessage(buf []byte) (armored, ok bool) {
+	switch {
+	case bytes.HasPrefix(buf, []byte(ageVersionLine+"\n")):
+		return false, true
+	case bytes.HasPrefix(buf, []byte(ageArmorHeader)):
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+func ageHKDF(secret, salt []byte, info string) []byte {
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// ageStanza is a recipient stanza: "-> type args...", then the body.
+type ageStanza struct {
+	args []string
+	body []byte
+}
+
+func (s ageStanza) marshal(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "-> %s\n", strings.Join(s.args, " ")); err != nil {
+		return err
+	}
+	b64 := ageB64.EncodeToString(s.body)
+	// The body is wrapped at 64 columns, and always ends with a line that's
+	// shorter than that, even if it has to be empty.
+	for {
+		n := len(b64)
+		if n > ageColumns {
+			n = ageColumns
+		}
+		if _, err := io.WriteString(w, b64[:n]+"\n"); err != nil {
+			return err
+		}
+		if n < ageColumns {
+			return nil
+		}
+		b64 = b64[n:]
+	}
+}
+
+func ageWrapX25519(rng io.Reader, fileKey []byte, recipient NaclDHKeyPublic) (ageStanza, error) {
+	ephemeral := make([]byte, curve25519.ScalarSize)
+	if _, err := io.ReadFull(rng, ephemeral); err != nil {
+		return ageStanza{}, err
+	}
+	share, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return ageStanza{}, err
+	}
+	shared, err := curve25519.X25519(ephemeral, recipient[:])
+	if err != nil {
+		return ageStanza{}, err
+	}
+	salt := append(append([]byte{}, share...), recipient[:]...)
+	aead, err := chacha20poly1305.New(ageHKDF(shared, salt, ageX25519Label))
+	if err != nil {
+		return ageStanza{}, err
+	}
+	return ageStanza{
+		args: []string{"X25519", ageB64.EncodeToString(share)},
+		body: aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil),
+	}, nil
+}
+
+// ageUnwrapX25519 returns the file key if the stanza is for key, and nil
+// otherwise.
+func ageUnwrapX25519(s ageStanza, key NaclDHKeyPair) ([]byte, error) {
+	if len(s.args) != 2 {
+		return nil, ErrAgeMalformed{Msg: "X25519 stanza with wrong number of arguments"}
+	}
+	share, err := ageB64.DecodeString(s.args[1])
+	if err != nil || len(share) != curve25519.PointSize {
+		return nil, ErrAgeMalformed{Msg: "bad X25519 share"}
+	}
+	if len(s.body) != ageFileKeySize+chacha20poly1305.Overhead {
+		return nil, ErrAgeMalformed{Msg: "bad X25519 stanza body"}
+	}
+	if key.Private == nil {
+		return nil, nil
+	}
+	shared, err := curve25519.X25519(key.Private[:], share)
+	if err != nil {
+		return nil, ErrAgeMalformed{Msg: "low order X25519 share"}
+	}
+	salt := append(append([]byte{}, share...), key.Public[:]...)
+	aead, err := chacha20poly1305.New(ageHKDF(shared, salt, ageX25519Label))
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), s.body, nil)
+	if err != nil {
+		return nil, nil
+	}
+	return fileKey, nil
+}
+
+func ageHeaderMAC(fileKey, header []byte) []byte {
+	h := hmac.New(sha256.New, ageHKDF(fileKey, nil, "header"))
+	h.Write(header)
+	return h.Sum(nil)
+}
+
+// ageStreamWriter encrypts the payload in 64KiB chunks with the STREAM
+// construction; the last chunk is flagged in its nonce.
+type ageStreamWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint64
+}
+
+func ageChunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	for i := 10; i >= 3; i-- {
+		nonce[i] = byte(counter)
+		counter >>= 8
+	}
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func (s *ageStreamWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	s.buf = append(s.buf, p...)
+	// A full chunk can only be written once there's more after it, since
+	// the last chunk has to be marked as such.
+	for len(s.buf) > ageChunkSize {
+		if err := s.flush(s.buf[:ageChunkSize], false); err != nil {
+			return 0, err
+		}
+		s.buf = s.buf[ageChunkSize:]
+	}
+	return n, nil
+}
+
+func (s *ageStreamWriter) flush(chunk []byte, last bool) error {
+	if _, err := s.w.Write(s.aead.Seal(nil, ageChunkNonce(s.counter, last), chunk, nil)); err != nil {
+		return err
+	}
+	s.counter++
+	return nil
+}
+
+func (s *ageStreamWriter) Close() error {
+	return s.flush(s.buf, true)
+}
+
+// ageArmorWriter writes the ASCII armored form: strict PEM with 64 columns
+// of padded base64.
+type ageArmorWriter struct {
+	w   io.Writer
+	col int
+	enc io.WriteCloser
+}
+
+func newAgeArmorWriter(w io.Writer) (*ageArmorWriter, error) {
+	if _, err := io.WriteString(w, ageArmorHeader+"\n"); err != nil {
+		return nil, err
+	}
+	a := &ageArmorWriter{w: w}
+	a.enc = base64.NewEncoder(base64.StdEncoding, ageLineWriter{a})
+	return a, nil
+}
+
+type ageLineWriter struct {
+	a *ageArmorWriter
+}
+
+func (l ageLineWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		k := ageColumns - l.a.col
+		if k > len(p) {
+			k = len(p)
+		}
+		if _, err := l.a.w.Write(p[:k]); err != nil {
+			return n, err
+		}
+		n += k
+		p = p[k:]
+		if l.a.col += k; l.a.col == ageColumns {
+			if _, err := io.WriteString(l.a.w, "\n"); err != nil {
+				return n, err
+			}
+			l.a.col = 0
+		}
+	}
+	return n, nil
+}
+
+func (a *ageArmorWriter) Write(p []byte) (int, error) {
+	return a.enc.Write(p)
+}
+
+func (a *ageArmorWriter) Close() error {
+	if err := a.enc.Close(); err != nil {
+		return err
+	}
+	if a.col > 0 {
+		if _, err := io.WriteString(a.w, "\n"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(a.w, ageArmorFooter+"\n")
+	return err
+}
+
+type ageEncryptWriter struct {
+	stream *ageStreamWriter
+	armor  *ageArmorWriter
+	sink   io.WriteCloser
+}
+
+func (w *ageEncryptWriter) Write(p []byte) (int, error) {
+	return w.stream.Write(p)
+}
+
+func (w *ageEncryptWriter) Close() error {
+	if err := w.stream.Close(); err != nil {
+		return err
+	}
+	if w.armor != nil {
+		if err := w.armor.Close(); err != nil {
+			return err
+		}
+	}
+	return w.sink.Close()
+}
+
+// newAgeEncryptWriter writes an age header for receivers to sink, and returns
+// a writer for the plaintext, which has to be closed to finish the file.
+// Randomness comes from rng, so that tests can be deterministic.
+func newAgeEncryptWriter(rng io.Reader, sink io.WriteCloser, receivers []NaclDHKeyPublic, armored bool) (io.WriteCloser, error) {
+	if len(receivers) == 0 {
+		return nil, errors.New("no recipients for the age file")
+	}
+	ret := &ageEncryptWriter{sink: sink}
+	w := io.Writer(sink)
+	if armored {
+		a, err := newAgeArmorWriter(sink)
+		if err != nil {
+			return nil, err
+		}
+		ret.armor = a
+		w = a
+	}
+
+	fileKey := make([]byte, ageFileKeySize)
+	if _, err := io.ReadFull(rng, fileKey); err != nil {
+		return nil, err
+	}
+	var header bytes.Buffer
+	header.WriteString(ageVersionLine + "\n")
+	for _, r := range receivers {
+		s, err := ageWrapX25519(rng, fileKey, r)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.marshal(&header); err != nil {
+			return nil, err
+		}
+	}
+	header.WriteString("---")
+	mac := ageHeaderMAC(fileKey, header.Bytes())
+	header.WriteString(" " + ageB64.EncodeToString(mac) + "\n")
+
+	nonce := make([]byte, ageNonceSize)
+	if _, err := io.ReadFull(rng, nonce); err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(ageHKDF(fileKey, nonce, "payload"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(nonce); err != nil {
+		return nil, err
+	}
+	ret.stream = &ageStreamWriter{w: w, aead: aead}
+	return ret, nil
+}
+
+// AgeEncrypt encrypts source into sink as an age file for receivers, ASCII
+// armored unless binary is set.
+func AgeEncrypt(source io.Reader, sink io.WriteCloser, receivers []NaclDHKeyPublic, binary bool) error {
+	w, err := newAgeEncryptWriter(rand.Reader, sink, receivers, !binary)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, source); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// ageArmorReader undoes ageArmorWriter.
+type ageArmorReader struct {
+	r    *bufio.Reader
+	line []byte
+	done bool
+}
+
+func (a *ageArmorReader) Read(p []byte) (int, error) {
+	for len(a.line) == 0 {
+		if a.done {
+			return 0, io.EOF
+		}
+		line, err := a.r.ReadString('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			if err == io.EOF {
+				return 0, ErrAgeMalformed{Msg: "armor without an end line"}
+			}
+			return 0, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == ageArmorFooter {
+			a.done = true
+			continue
+		}
+		if len(line) > ageColumns {
+			return 0, ErrAgeMalformed{Msg: "armor line too long"}
+		}
+		a.line = []byte(line)
+	}
+	n := copy(p, a.line)
+	a.line = a.line[n:]
+	return n, nil
+}
+
+func newAgeArmorReader(r *bufio.Reader) (io.Reader, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(line, "\r\n") != ageArmorHeader {
+		return nil, ErrAgeMalformed{Msg: "bad armor header"}
+	}
+	return base64.NewDecoder(base64.StdEncoding, &ageArmorReader{r: r}), nil
+}
+
+type ageHeader struct {
+	stanzas []ageStanza
+	mac     []byte
+	// raw is the header up to and including "---", which the MAC covers.
+	raw []byte
+}
+
+func readAgeHeader(r *bufio.Reader) (*ageHeader, error) {
+	var raw bytes.Buffer
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			return "", ErrAgeMalformed{Msg: "truncated header"}
+		}
+		if err != nil {
+			return "", err
+		}
+		if raw.Len()+len(line) > ageMaxHeaderSize {
+			return "", ErrAgeMalformed{Msg: "header too large"}
+		}
+		raw.WriteString(line)
+		return strings.TrimSuffix(line, "\n"), nil
+	}
+
+	line, err := readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line != ageVersionLine {
+		return nil, ErrAgeMalformed{Msg: fmt.Sprintf("unsupported version line %q", line)}
+	}
+	h := &ageHeader{}
+	for {
+		line, err := readLine()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(line, "--- ") {
+			mac, err := ageB64.DecodeString(line[len("--- "):])
+			if err != nil || len(mac) != sha256.Size {
+				return nil, ErrAgeMalformed{Msg: "bad header MAC"}
+			}
+			h.mac = mac
+			h.raw = raw.Bytes()[:raw.Len()-len(line)-1+len("---")]
+			return h, nil
+		}
+		if !strings.HasPrefix(line, "-> ") {
+			return nil, ErrAgeMalformed{Msg: fmt.Sprintf("unexpected header line %q", line)}
+		}
+		args := strings.Split(line[len("-> "):], " ")
+		for _, a := range args {
+			if len(a) == 0 {
+				return nil, ErrAgeMalformed{Msg: "empty stanza argument"}
+			}
+		}
+		s := ageStanza{args: args}
+		for {
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			if len(line) > ageColumns {
+				return nil, ErrAgeMalformed{Msg: "stanza body line too long"}
+			}
+			b, err := ageB64.DecodeString(line)
+			if err != nil {
+				return nil, ErrAgeMalformed{Msg: "bad stanza body"}
+			}
+			s.body = append(s.body, b...)
+			if len(line) < ageColumns {
+				break
+			}
+		}
+		h.stanzas = append(h.stanzas, s)
+	}
+}
+
+// ageStreamReader decrypts a STREAM payload, making sure it ends with a
+// chunk marked as the last one.
+type ageStreamReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	counter uint64
+	buf     []byte
+	done    bool
+}
+
+func (s *ageStreamReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *ageStreamReader) readChunk() error {
+	chunk := make([]byte, ageChunkSize+chacha20poly1305.Overhead)
+	n, err := io.ReadFull(s.r, chunk)
+	switch {
+	case err == io.EOF:
+		return ErrAgeMalformed{Msg: "payload ended without a last chunk"}
+	case err == io.ErrUnexpectedEOF:
+		chunk = chunk[:n]
+	case err != nil:
+		return err
+	}
+	last := len(chunk) < ageChunkSize+chacha20poly1305.Overhead
+	if !last {
+		if _, err := s.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	plain, err := s.aead.Open(nil, ageChunkNonce(s.counter, last), chunk, nil)
+	if err != nil {
+		return DecryptionError{Cause: ErrorCause{Err: fmt.Errorf("age payload chunk %d failed to authenticate", s.counter)}}
+	}
+	if len(plain) == 0 && (s.counter > 0 || !last) {
+		return ErrAgeMalformed{Msg: "empty chunk"}
+	}
+	s.counter++
+	s.buf = plain
+	s.done = last
+	return nil
+}
+
+// AgeDecrypt decrypts an age file addressed to one of keys from source into
+// sink. checkSender is called once the file key has been recovered and the
+// header authenticated, before any plaintext is written; age files are
+// never signed, so there's no sender to give it.
+func AgeDecrypt(source io.Reader, sink io.WriteCloser, keys []NaclDHKeyPair, checkSender func() error) error {
+	r := bufio.NewReader(source)
+	buf, _ := r.Peek(len(ageArmorHeader))
+	armored, ok := isAgeMessage(buf)
+	if !ok {
+		return WrongCryptoFormatError{Wanted: CryptoMessageFormatAge, Operation: "decrypt"}
+	}
+	if armored {
+		ar, err := newAgeArmorReader(r)
+		if err != nil {
+			return err
+		}
+		r = bufio.NewReader(ar)
+	}
+
+	h, err := readAgeHeader(r)
+	if err != nil {
+		return err
+	}
+	var fileKey []byte
+	for _, s := range h.stanzas {
+		if s.args[0] != "X25519" {
+			continue
+		}
+		for _, key := range keys {
+			if fileKey, err = ageUnwrapX25519(s, key); err != nil {
+				return err
+			}
+			if fileKey != nil {
+				break
+			}
+		}
+		if fileKey != nil {
+			break
+		}
+	}
+	if fileKey == nil {
+		return DecryptionError{Cause: ErrorCause{
+			Err:        NoDecryptionKeyError{Msg: "this age file isn't encrypted for any of your keys"},
+			StatusCode: SCDecryptionKeyNotFound,
+		}}
+	}
+	if !hmac.Equal(h.mac, ageHeaderMAC(fileKey, h.raw)) {
+		return DecryptionError{Cause: ErrorCause{Err: errors.New("age header MAC doesn't match")}}
+	}
+
+	nonce := make([]byte, ageNonceSize)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return ErrAgeMalformed{Msg: "truncated payload nonce"}
+	}
+	aead, err := chacha20poly1305.New(ageHKDF(fileKey, nonce, "payload"))
+	if err != nil {
+		return err
+	}
+	if checkSender != nil {
+		if err := checkSender(); err != nil {
+			return err
+		}
+	}
+	if _, err := io.Copy(sink, &ageStreamReader{r: r, aead: aead}); err != nil {
+		return err
+	}
+	return sink.Close()
+}
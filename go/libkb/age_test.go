@@ -0,0 +1,230 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package libkb
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+func ageTestHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func ageTestKey(t *testing.T, secret string) NaclDHKeyPair {
+	kp, err := MakeNaclDHKeyPairFromSecretBytes(ageTestHex(t, secret))
+	require.NoError(t, err)
+	return kp
+}
+
+// The X25519 test vector from RFC 7748, section 6.1: Keybase's NaCl DH keys
+// are the X25519 keys age uses, with no conversion in between.
+func TestAgeX25519RFC7748(t *testing.T) {
+	alice := ageTestKey(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bob := ageTestKey(t, "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	require.Equal(t, ageTestHex(t, "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a"), alice.Public[:])
+	require.Equal(t, ageTestHex(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f"), bob.Public[:])
+
+	shared, err := curve25519.X25519(alice.Private[:], bob.Public[:])
+	require.NoError(t, err)
+	require.Equal(t, ageTestHex(t, "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742"), shared)
+}
+
+// ageSpecFile builds a binary age file for one X25519 recipient straight
+// from the spec at https://age-encryption.org/v1, independently of age.go.
+func ageSpecFile(t *testing.T, fileKey, ephemeral, nonce []byte, recipient NaclDHKeyPublic, plaintext []byte) []byte {
+	key := func(secret, salt []byte, info string) []byte {
+		k := make([]byte, 32)
+		_, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), k)
+		require.NoError(t, err)
+		return k
+	}
+	b64 := base64.RawStdEncoding.EncodeToString
+
+	share, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	require.NoError(t, err)
+	shared, err := curve25519.X25519(ephemeral, recipient[:])
+	require.NoError(t, err)
+	wrap, err := chacha20poly1305.New(key(shared, append(append([]byte{}, share...), recipient[:]...), "age-encryption.org/v1/X25519"))
+	require.NoError(t, err)
+	body := wrap.Seal(nil, make([]byte, 12), fileKey, nil)
+
+	header := "age-encryption.org/v1\n-> X25519 " + b64(share) + "\n" + b64(body) + "\n---"
+	mac := hmac.New(sha256.New, key(fileKey, nil, "header"))
+	mac.Write([]byte(header))
+
+	var out bytes.Buffer
+	out.WriteString(header + " " + b64(mac.Sum(nil)) + "\n")
+	out.Write(nonce)
+	payload, err := chacha20poly1305.New(key(fileKey, nonce, "payload"))
+	require.NoError(t, err)
+	for counter := byte(0); ; counter++ {
+		chunk := plaintext
+		if len(chunk) > 64*1024 {
+			chunk = chunk[:64*1024]
+		}
+		plaintext = plaintext[len(chunk):]
+		chunkNonce := make([]byte, 12)
+		chunkNonce[10] = counter
+		if len(plaintext) == 0 {
+			chunkNonce[11] = 1
+		}
+		out.Write(payload.Seal(nil, chunkNonce, chunk, nil))
+		if len(plaintext) == 0 {
+			return out.Bytes()
+		}
+	}
+}
+
+func ageTestEncrypt(t *testing.T, receivers []NaclDHKeyPublic, plaintext []byte, binary bool) []byte {
+	var out outputBuffer
+	require.NoError(t, AgeEncrypt(bytes.NewReader(plaintext), &out, receivers, binary))
+	return out.Bytes()
+}
+
+func ageTestDecrypt(keys []NaclDHKeyPair, ciphertext []byte) ([]byte, error) {
+	var out outputBuffer
+	err := AgeDecrypt(bytes.NewReader(ciphertext), &out, keys, nil)
+	return out.Bytes(), err
+}
+
+// TestAgeDeterministicEncrypt checks the encrypting side, which the
+// testkit vectors in age_testkit_test.go can't, since its randomness is
+// fixed here: given the same file key, ephemeral key and nonce, the output
+// matches ageSpecFile byte for byte.
+func TestAgeDeterministicEncrypt(t *testing.T) {
+	recipient := ageTestKey(t, "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	fileKey := bytes.Repeat([]byte{0x01}, 16)
+	ephemeral := ageTestHex(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	nonce := bytes.Repeat([]byte{0x02}, 16)
+
+	for _, size := range []int{0, 5, ageChunkSize, ageChunkSize + 1, 2*ageChunkSize + 7} {
+		plaintext := bytes.Repeat([]byte("age!"), size/4+1)[:size]
+		expected := ageSpecFile(t, fileKey, ephemeral, nonce, recipient.Public, plaintext)
+		require.True(t, bytes.HasPrefix(expected, []byte(
+			"age-encryption.org/v1\n-> X25519 hSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo\n")))
+
+		// Given the same randomness, we write exactly what the spec says...
+		rng := bytes.NewReader(append(append(append([]byte{}, fileKey...), ephemeral...), nonce...))
+		var out outputBuffer
+		w, err := newAgeEncryptWriter(rng, &out, []NaclDHKeyPublic{recipient.Public}, false)
+		require.NoError(t, err)
+		_, err = w.Write(plaintext)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		require.Equal(t, expected, out.Bytes(), "size %d", size)
+
+		// ...and read it back.
+		decrypted, err := ageTestDecrypt([]NaclDHKeyPair{recipient}, expected)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	}
+}
+
+func TestAgeRoundTrip(t *testing.T) {
+	var keys []NaclDHKeyPair
+	var receivers []NaclDHKeyPublic
+	for i := 0; i < 3; i++ {
+		kp, err := GenerateNaclDHKeyPair()
+		require.NoError(t, err)
+		keys = append(keys, kp)
+		receivers = append(receivers, kp.Public)
+	}
+
+	for _, binary := range []bool{true, false} {
+		for _, size := range []int{0, 1, ageChunkSize - 1, ageChunkSize, ageChunkSize + 1, 3 * ageChunkSize} {
+			plaintext := make([]byte, size)
+			for i := range plaintext {
+				plaintext[i] = byte(i * 7)
+			}
+			ciphertext := ageTestEncrypt(t, receivers, plaintext, binary)
+			if !binary {
+				s := string(ciphertext)
+				require.True(t, strings.HasPrefix(s, ageArmorHeader+"\n"))
+				require.True(t, strings.HasSuffix(s, "\n"+ageArmorFooter+"\n"))
+				for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+					require.True(t, len(line) <= ageColumns)
+				}
+			}
+
+			// Every recipient can decrypt it on their own.
+			for _, key := range keys {
+				decrypted, err := ageTestDecrypt([]NaclDHKeyPair{key}, ciphertext)
+				require.NoError(t, err, "binary %v, size %d", binary, size)
+				require.Equal(t, plaintext, decrypted)
+			}
+		}
+	}
+}
+
+func TestAgeDecryptErrors(t *testing.T) {
+	kp, err := GenerateNaclDHKeyPair()
+	require.NoError(t, err)
+	other, err := GenerateNaclDHKeyPair()
+	require.NoError(t, err)
+	plaintext := bytes.Repeat([]byte("x"), ageChunkSize+10)
+	ciphertext := ageTestEncrypt(t, []NaclDHKeyPublic{kp.Public}, plaintext, true)
+
+	_, err = ageTestDecrypt([]NaclDHKeyPair{other}, ciphertext)
+	decErr, ok := err.(DecryptionError)
+	require.True(t, ok, "%v", err)
+	require.IsType(t, NoDecryptionKeyError{}, decErr.Cause.Err)
+
+	// A changed header MAC.
+	tampered := append([]byte{}, ciphertext...)
+	i := bytes.Index(tampered, []byte("\n--- ")) + 5
+	tampered[i] ^= 'A' ^ 'B'
+	_, err = ageTestDecrypt([]NaclDHKeyPair{kp}, tampered)
+	require.Error(t, err)
+
+	// A flipped bit in the last chunk.
+	tampered = append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = ageTestDecrypt([]NaclDHKeyPair{kp}, tampered)
+	require.Error(t, err)
+
+	// Dropping the last chunk leaves a full chunk that isn't marked last.
+	_, err = ageTestDecrypt([]NaclDHKeyPair{kp}, ciphertext[:len(ciphertext)-10-chacha20poly1305.Overhead])
+	require.Error(t, err)
+
+	// The sender check can refuse the file before any plaintext comes out.
+	var out outputBuffer
+	err = AgeDecrypt(bytes.NewReader(ciphertext), &out, []NaclDHKeyPair{kp}, func() error { return io.ErrUnexpectedEOF })
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+	require.Zero(t, out.Len())
+
+	_, err = ageTestDecrypt([]NaclDHKeyPair{kp}, []byte("BEGIN KEYBASE SALTPACK ENCRYPTED MESSAGE."))
+	require.IsType(t, WrongCryptoFormatError{}, err)
+}
+
+func TestAgeClassifyStream(t *testing.T) {
+	kp, err := GenerateNaclDHKeyPair()
+	require.NoError(t, err)
+	for _, binary := range []bool{true, false} {
+		ciphertext := ageTestEncrypt(t, []NaclDHKeyPublic{kp.Public}, []byte("hello"), binary)
+		sc, r, err := ClassifyStream(bytes.NewReader(ciphertext))
+		require.NoError(t, err)
+		require.Equal(t, StreamClassification{
+			Format:  CryptoMessageFormatAge,
+			Type:    CryptoMessageTypeEncryption,
+			Armored: !binary,
+		}, sc)
+		all, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, ciphertext, all)
+	}
+}
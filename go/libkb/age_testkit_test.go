@@ -0,0 +1,143 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package libkb
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/stretchr/testify/require"
+)
+
+const ageTestkitDir = "testdata/age"
+
+// ageTestkitVector is one file of the age testkit (https://c2sp.org/CCTV/age):
+// a few "key: value" lines, an empty line, and then an age file.
+type ageTestkitVector struct {
+	expect     string
+	payload    string
+	identities []string
+	passphrase bool
+	armored    bool
+	compressed bool
+	file       []byte
+}
+
+func parseAgeTestkitVector(t *testing.T, contents []byte) (v ageTestkitVector) {
+	r := bufio.NewReader(bytes.NewReader(contents))
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+		parts := strings.SplitN(line, ": ", 2)
+		require.Len(t, parts, 2, "bad vector header line %q", line)
+		switch key, value := parts[0], parts[1]; key {
+		case "expect":
+			v.expect = value
+		case "payload":
+			v.payload = value
+		case "identity":
+			v.identities = append(v.identities, value)
+		case "passphrase":
+			v.passphrase = true
+		case "armored":
+			v.armored = value == "yes"
+		case "compressed":
+			require.Equal(t, "zlib", value)
+			v.compressed = true
+		}
+	}
+	file, err := io.ReadAll(r)
+	require.NoError(t, err)
+	if v.compressed {
+		zr, err := zlib.NewReader(bytes.NewReader(file))
+		require.NoError(t, err)
+		file, err = io.ReadAll(zr)
+		require.NoError(t, err)
+	}
+	v.file = file
+	return v
+}
+
+// ageTestkitKey decodes an X25519 identity, or returns false for any other
+// kind (like plugin or post-quantum identities), which AgeDecrypt doesn't
+// support.
+func ageTestkitKey(t *testing.T, identity string) (NaclDHKeyPair, bool) {
+	if !strings.HasPrefix(identity, "AGE-SECRET-KEY-1") {
+		return NaclDHKeyPair{}, false
+	}
+	hrp, data, err := bech32.Decode(strings.ToLower(identity))
+	require.NoError(t, err)
+	require.Equal(t, "age-secret-key-", hrp)
+	secret, err := bech32.ConvertBits(data, 5, 8, false)
+	require.NoError(t, err)
+	kp, err := MakeNaclDHKeyPairFromSecretBytes(secret)
+	require.NoError(t, err)
+	return kp, true
+}
+
+// TestAgeTestkit decrypts every vector of the age testkit that only needs
+// X25519 identities. Files encrypted to a passphrase are skipped, since only
+// X25519 recipients are supported.
+func TestAgeTestkit(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(ageTestkitDir, "*"))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range files {
+		if filepath.Base(file) != "README.md" {
+			names = append(names, file)
+		}
+	}
+	if len(names) == 0 {
+		t.Skipf("no age testkit vectors in %s; see the README there", ageTestkitDir)
+	}
+
+	for _, name := range names {
+		name := name
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			contents, err := os.ReadFile(name)
+			require.NoError(t, err)
+			v := parseAgeTestkitVector(t, contents)
+			if v.passphrase {
+				t.Skip("passphrase recipients aren't supported")
+			}
+			var keys []NaclDHKeyPair
+			for _, identity := range v.identities {
+				key, ok := ageTestkitKey(t, identity)
+				if !ok {
+					t.Skipf("unsupported identity type %q", identity)
+				}
+				keys = append(keys, key)
+			}
+
+			plaintext, err := ageTestDecrypt(keys, v.file)
+			switch v.expect {
+			case "success":
+				require.NoError(t, err)
+				sum := sha256.Sum256(plaintext)
+				require.Equal(t, v.payload, hex.EncodeToString(sum[:]))
+			case "no match":
+				require.Error(t, err)
+				decErr, ok := err.(DecryptionError)
+				require.True(t, ok, "%v", err)
+				require.IsType(t, NoDecryptionKeyError{}, decErr.Cause.Err)
+			default:
+				// header, HMAC, payload and armor failures
+				require.Error(t, err, "expected %s", v.expect)
+			}
+		})
+	}
+}
@@ -50,6 +50,7 @@ const (
 	DBOfflineRPC                     = 0xbe
 	DBChatCollapses                  = 0xbf
 	DBSupportsHiddenFlagStorage      = 0xc0
+	DBKVStoreMirror                  = 0xc1
 	DBMerkleAudit                    = 0xca
 	DBUnfurler                       = 0xcb
 	DBStellarDisclaimer              = 0xcc
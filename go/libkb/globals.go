@@ -91,6 +91,7 @@ type GlobalContext struct {
 	loadUserLockTab        *LockTable
 	teamAuditor            TeamAuditor
 	teamBoxAuditor         TeamBoxAuditor
+	teamMemberExpirer      TeamMemberExpirer
 	stellar                Stellar            // Stellar related ops
 	deviceEKStorage        DeviceEKStorage    // Store device ephemeral keys
 	userEKBoxStorage       UserEKBoxStorage   // Store user ephemeral key boxes
@@ -299,6 +300,7 @@ func (g *GlobalContext) Init() *GlobalContext {
 	g.TeamRoleMapManager = newNullTeamRoleMapManager()
 	g.teamAuditor = newNullTeamAuditor()
 	g.teamBoxAuditor = newNullTeamBoxAuditor()
+	g.teamMemberExpirer = newNullTeamMemberExpirer()
 	g.stellar = newNullStellar(g)
 	g.fullSelfer = NewUncachedFullSelf(g)
 	g.ConnectivityMonitor = NullConnectivityMonitor{}
@@ -718,6 +720,12 @@ func (g *GlobalContext) GetTeamBoxAuditor() TeamBoxAuditor {
 	return g.teamBoxAuditor
 }
 
+func (g *GlobalContext) GetTeamMemberExpirer() TeamMemberExpirer {
+	g.cacheMu.RLock()
+	defer g.cacheMu.RUnlock()
+	return g.teamMemberExpirer
+}
+
 func (g *GlobalContext) GetStellar() Stellar {
 	g.cacheMu.RLock()
 	defer g.cacheMu.RUnlock()
@@ -1357,6 +1365,12 @@ func (g *GlobalContext) SetTeamBoxAuditor(a TeamBoxAuditor) {
 	g.teamBoxAuditor = a
 }
 
+func (g *GlobalContext) SetTeamMemberExpirer(e TeamMemberExpirer) {
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+	g.teamMemberExpirer = e
+}
+
 func (g *GlobalContext) SetStellar(s Stellar) {
 	g.cacheMu.Lock()
 	defer g.cacheMu.Unlock()
@@ -800,6 +800,11 @@ type TeamBoxAuditor interface {
 	Attempt(m MetaContext, id keybase1.TeamID, rotateBeforeAudit bool) keybase1.BoxAuditAttempt
 }
 
+// TeamMemberExpirer ends the team memberships that admins have set to expire.
+type TeamMemberExpirer interface {
+	ExpireMembers(m MetaContext) error
+}
+
 // MiniChatPayment is the argument for sending an in-chat payment.
 type MiniChatPayment struct {
 	Username NormalizedUsername
@@ -213,6 +213,7 @@ func (h *NodeHashAny) MarshalJSON() ([]byte, error) {
 
 type MerkleClientInterface interface {
 	CanExamineHistoricalRoot(m MetaContext, q keybase1.Seqno) bool
+	ExportLeafPath(m MetaContext, leafID keybase1.UserOrTeamID, hm keybase1.HashMeta, s keybase1.Seqno) (res []byte, err error)
 	FetchRootFromServerByMinSeqno(m MetaContext, lowerBound keybase1.Seqno) (mr *MerkleRoot, err error)
 	FetchRootFromServer(m MetaContext, freshness time.Duration) (mr *MerkleRoot, err error)
 	FirstExaminableHistoricalRoot(m MetaContext) *keybase1.Seqno
@@ -226,6 +227,7 @@ type MerkleClientInterface interface {
 	LookupTeam(m MetaContext, teamID keybase1.TeamID) (leaf *MerkleTeamLeaf, err error)
 	LookupTeamWithHidden(m MetaContext, teamID keybase1.TeamID, processHiddenRespFunc ProcessHiddenRespFunc) (leaf *MerkleTeamLeaf, hiddenResp *MerkleHiddenResponse, lastMerkleRoot *MerkleRoot, err error)
 	LookupUser(m MetaContext, q HTTPArgs, sigHints *SigHints, opts MerkleOpts) (u *MerkleUserLeaf, err error)
+	VerifyExportedLeafPath(m MetaContext, leafID keybase1.UserOrTeamID, res []byte) (leaf *MerkleGenericLeaf, root *MerkleRoot, err error)
 }
 
 type MerkleClient struct {
@@ -1861,6 +1863,77 @@ func (mc *MerkleClient) LookupLeafAtHashMeta(m MetaContext, leafID keybase1.User
 	return leaf, err
 }
 
+// ExportLeafPath fetches the path from a merkle root down to leafID and returns
+// the server's response verbatim, so that it can be checked later, without
+// the network, by VerifyExportedLeafPath. The root is the one with the given
+// hash meta if hm is set, the one at seqno s if s is set, and the latest one
+// otherwise.
+func (mc *MerkleClient) ExportLeafPath(m MetaContext, leafID keybase1.UserOrTeamID, hm keybase1.HashMeta, s keybase1.Seqno) (res []byte, err error) {
+	defer m.Trace(fmt.Sprintf("MerkleClient#ExportLeafPath(%v)", leafID), &err)()
+
+	q := NewHTTPArgs()
+	q.Add("leaf_id", S{Val: leafID.String()})
+	q.Add("c", B{Val: true})
+	switch {
+	case hm != nil:
+		q.Add("start_hash_meta", S{Val: hm.String()})
+	case s > 0:
+		if err = mc.checkHistoricalSeqno(s); err != nil {
+			return nil, err
+		}
+		q.Add("start_seqno", I{Val: int(s)})
+	}
+	apiRes, err := m.G().API.Get(m, APIArg{
+		Endpoint:    "merkle/path",
+		SessionType: APISessionTypeOPTIONAL,
+		Args:        q,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err = apiRes.Body.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	// Don't hand out anything that won't verify later.
+	if _, _, err = mc.VerifyExportedLeafPath(m, leafID, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// VerifyExportedLeafPath checks a response saved by ExportLeafPath: that its
+// root is signed by one of the known merkle keys, and that the path leads from
+// that root to the leaf for leafID. It only needs the network if the merkle
+// key isn't in the local DB yet.
+func (mc *MerkleClient) VerifyExportedLeafPath(m MetaContext, leafID keybase1.UserOrTeamID, res []byte) (leaf *MerkleGenericLeaf, root *MerkleRoot, err error) {
+	body, err := jsonw.Unmarshal(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := MerkleOpts{historical: true}
+	root, err = NewMerkleRootFromJSON(body.AtKey("root"), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = mc.verifyRootHelper(m, root, nil, opts); err != nil {
+		return nil, nil, err
+	}
+	path := &VerificationPath{
+		Contextified: NewContextified(mc.G()),
+		root:         root,
+	}
+	path.path, err = importPathFromJSON(body.AtKey("path"))
+	if err != nil {
+		return nil, nil, err
+	}
+	leaf, err = path.verifyUserOrTeam(m, leafID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return leaf, root, nil
+}
+
 func (mc *MerkleClient) checkHistoricalSeqno(s keybase1.Seqno) error {
 	if mc.G().Env.GetRunMode() == ProductionRunMode && s < FirstProdMerkleSeqnoWithSigs {
 		return MerkleClientError{fmt.Sprintf("cannot load seqno=%d; must load at %d or higher", s, FirstProdMerkleSeqnoWithSigs), merkleErrorAncientSeqno}
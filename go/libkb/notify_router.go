@@ -116,6 +116,7 @@ type NotifyListener interface {
 	TeamTreeMembershipsPartial(keybase1.TeamTreeMembership)
 	TeamTreeMembershipsDone(keybase1.TeamTreeMembershipsDoneResult)
 	WebOfTrustChanged(username string)
+	KVStoreEntryChanged(keybase1.KVEntryChange)
 }
 
 type NoopNotifyListener struct{}
@@ -269,6 +270,7 @@ func (n *NoopNotifyListener) TeamTreeMembershipsPartial(keybase1.TeamTreeMembers
 func (n *NoopNotifyListener) TeamTreeMembershipsDone(keybase1.TeamTreeMembershipsDoneResult) {}
 func (n *NoopNotifyListener) WebOfTrustChanged(username string) {
 }
+func (n *NoopNotifyListener) KVStoreEntryChanged(keybase1.KVEntryChange) {}
 
 type NotifyListenerID string
 
@@ -2965,3 +2967,23 @@ func (n *NotifyRouter) HandleTeamTreeMembershipsDone(ctx context.Context, result
 		listener.TeamTreeMembershipsDone(result)
 	})
 }
+
+func (n *NotifyRouter) HandleKVStoreEntryChanged(ctx context.Context, change keybase1.KVEntryChange) {
+	if n == nil {
+		return
+	}
+	n.cm.ApplyAll(func(id ConnectionID, xp rpc.Transporter) bool {
+		if n.getNotificationChannels(id).Kvstore {
+			// note there's no goroutine here on purpose
+			// (notification ordering)
+			_ = (keybase1.NotifyKVStoreClient{
+				Cli: rpc.NewClient(xp, NewContextifiedErrorUnwrapper(n.G()), nil),
+			}).KvEntryChanged(context.Background(), change)
+		}
+		return true
+	})
+
+	n.runListeners(func(listener NotifyListener) {
+		listener.KVStoreEntryChanged(change)
+	})
+}
@@ -26,6 +26,9 @@ const (
 	// CryptoMessageFormatSaltpack is the Saltpack messaging format for encrypted and signed
 	// messages
 	CryptoMessageFormatSaltpack CryptoMessageFormat = "saltpack"
+	// CryptoMessageFormatAge is the age file format (age-encryption.org/v1),
+	// which is only used for encryption
+	CryptoMessageFormatAge CryptoMessageFormat = "age"
 )
 
 // CryptoMessageType says what type of crypto message it is, regardless of Format
@@ -103,6 +106,17 @@ func isSaltpackMessage(stream *bufio.Reader, sc *StreamClassification) bool {
 	return true
 }
 
+func isAgeStream(b []byte, sc *StreamClassification) bool {
+	armored, ok := isAgeMessage(b)
+	if !ok {
+		return false
+	}
+	sc.Format = CryptoMessageFormatAge
+	sc.Armored = armored
+	sc.Type = CryptoMessageTypeEncryption
+	return true
+}
+
 func isPGPBinary(b []byte, sc *StreamClassification) bool {
 	if len(b) < 2 {
 		return false
@@ -178,6 +192,8 @@ func ClassifyStream(r io.Reader) (sc StreamClassification, out io.Reader, err er
 		sc.Format = CryptoMessageFormatPGP
 		sc.Armored = true
 		sc.Type = CryptoMessageTypeClearSignature
+	case isAgeStream(buf, &sc):
+		// Format etc. set by isAgeStream().
 	case isSaltpackMessage(stream, &sc):
 		// Format etc. set by isSaltpackBinary().
 	case isBase64KeybaseV0Sig(sb):
@@ -162,6 +162,14 @@ func (n nullTeamBoxAuditor) MaybeScheduleDelayedBoxAuditTeam(mctx MetaContext, t
 
 func newNullTeamBoxAuditor() nullTeamBoxAuditor { return nullTeamBoxAuditor{} }
 
+type nullTeamMemberExpirer struct{}
+
+var _ TeamMemberExpirer = nullTeamMemberExpirer{}
+
+func newNullTeamMemberExpirer() nullTeamMemberExpirer { return nullTeamMemberExpirer{} }
+
+func (n nullTeamMemberExpirer) ExpireMembers(m MetaContext) error { return nil }
+
 type nullHiddenTeamChainManager struct{}
 
 var _ HiddenTeamChainManager = nullHiddenTeamChainManager{}
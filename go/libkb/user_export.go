@@ -0,0 +1,132 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package libkb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	jsonw "github.com/keybase/go-jsonw"
+)
+
+// ExportedUser is everything needed to compute the key families of a user
+// without the network: the server's user/lookup and sig/get responses, and the
+// merkle path that anchors the tail of the sigchain. It's only as fresh as the
+// merkle root in the path.
+type ExportedUser struct {
+	UID        keybase1.UID    `json:"uid"`
+	Them       json.RawMessage `json:"them"`
+	Sigs       json.RawMessage `json:"sigs"`
+	MerklePath json.RawMessage `json:"merkle_path"`
+}
+
+// ExportUser fetches what VerifyExportedUser needs to check the sigchain of
+// uid later. The merkle path is fetched first, so that the chain is at least
+// as long as the leaf says.
+func ExportUser(m MetaContext, uid keybase1.UID) (ret *ExportedUser, err error) {
+	defer m.Trace(fmt.Sprintf("ExportUser(%s)", uid), &err)()
+
+	path, err := m.G().MerkleClient.ExportLeafPath(m, uid.AsUserOrTeam(), nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.G().API.Get(m, APIArg{
+		Endpoint:    "user/lookup",
+		SessionType: APISessionTypeNONE,
+		Args: HTTPArgs{
+			"uid":          UIDArg(uid),
+			"load_deleted": B{true},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	them, err := res.Body.AtKey("them").Marshal()
+	if err != nil {
+		return nil, err
+	}
+	res, err = m.G().API.Get(m, APIArg{
+		Endpoint:    "sig/get",
+		SessionType: APISessionTypeOPTIONAL,
+		Args: HTTPArgs{
+			"uid": UIDArg(uid),
+			"low": I{0},
+			"c3":  I{int(sigCompression3Unstubbed)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	sigs, err := res.Body.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	ret = &ExportedUser{
+		UID:        uid,
+		Them:       them,
+		Sigs:       sigs,
+		MerklePath: path,
+	}
+	// Don't hand out anything that won't verify later.
+	if _, err = VerifyExportedUser(m, *ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// VerifyExportedUser checks the merkle path in exp, that the sigchain in exp
+// ends at the leaf, and then verifies the sigchain and computes the key
+// families from it, as a regular load would. Links past the leaf are ignored.
+// It only needs the network if the merkle key isn't in the local DB yet.
+func VerifyExportedUser(m MetaContext, exp ExportedUser) (ret *keybase1.UserPlusKeysV2AllIncarnations, err error) {
+	defer m.Trace(fmt.Sprintf("VerifyExportedUser(%s)", exp.UID), &err)()
+
+	leaf, _, err := m.G().MerkleClient.VerifyExportedLeafPath(m, exp.UID.AsUserOrTeam(), exp.MerklePath)
+	if err != nil {
+		return nil, err
+	}
+	if leaf == nil || leaf.userExtras == nil || leaf.userExtras.public == nil {
+		return nil, UserNotFoundError{UID: exp.UID, Msg: "not in the merkle tree"}
+	}
+	tail := leaf.userExtras.public
+
+	them, err := jsonw.Unmarshal(exp.Them)
+	if err != nil {
+		return nil, err
+	}
+	u, err := NewUserFromServer(m.G(), them)
+	if err != nil {
+		return nil, err
+	}
+	if u.GetUID().NotEqual(exp.UID) {
+		return nil, UIDMismatchError{Msg: fmt.Sprintf("exported user is %s, not %s", u.GetUID(), exp.UID)}
+	}
+	u.leaf = *leaf.userExtras
+
+	sc := &SigChain{
+		Contextified: NewContextified(m.G()),
+		uid:          u.GetUID(),
+		username:     u.GetNormalizedName(),
+	}
+	if _, err = sc.LoadServerBody(m, exp.Sigs, 0, tail, ""); err != nil {
+		return nil, err
+	}
+	for len(sc.chainLinks) > 0 && sc.chainLinks[len(sc.chainLinks)-1].GetSeqno() > tail.Seqno {
+		sc.chainLinks = sc.chainLinks[:len(sc.chainLinks)-1]
+	}
+	if len(sc.chainLinks) == 0 || !sc.chainLinks[len(sc.chainLinks)-1].LinkID().Eq(tail.LinkID) {
+		return nil, NewServerChainError("exported sigchain of %s doesn't end at (%s, %d)",
+			exp.UID, tail.LinkID, int(tail.Seqno))
+	}
+	if err = sc.VerifyChain(m, u.GetUID()); err != nil {
+		return nil, err
+	}
+	ckf := ComputedKeyFamily{Contextified: NewContextified(m.G()), kf: u.GetKeyFamily()}
+	if _, err = sc.VerifySigsAndComputeKeys(m, u.leaf.eldest, &ckf, u.GetUID()); err != nil {
+		return nil, err
+	}
+	u.sigChainMem = sc
+	return u.ExportToUPKV2AllIncarnations()
+}
@@ -1,7 +1,9 @@
 package merkletree2
 
 import (
+	"fmt"
 	"math/big"
+	"sort"
 )
 
 // Position represents the position of a node in the tree. When converted to
@@ -264,3 +266,60 @@ func (p *Position) CmpInMerkleProofOrder(p2 *Position) int {
 	}
 	return (*big.Int)(p).CmpAbs((*big.Int)(p2))
 }
+
+// getMultiProofPositions takes the positions of the leaves in a
+// MerkleMultiInclusionProof, and returns the positions of all the internal
+// nodes on the paths from the root to those leaves, ordered by level from the
+// farthest to the closest to the root (and by position within each level). It
+// also returns, in the same order and then by child index, the positions of
+// the children of those nodes which are not on any of the paths themselves,
+// whose hashes are part of the proof. It returns an error if a leaf is listed
+// twice, or if one leaf is an ancestor of another.
+func (t *Config) getMultiProofPositions(leaves []Position) (parents []Position, siblings []Position, err error) {
+	leafSet := make(map[string]bool, len(leaves))
+	levels := make(map[int][]Position)
+	maxLevel := 0
+	for _, leaf := range leaves {
+		if leafSet[leaf.AsString()] {
+			return nil, nil, fmt.Errorf("duplicated leaf at position %x", leaf.GetBytes())
+		}
+		leafSet[leaf.AsString()] = true
+		level := t.getLevel(&leaf)
+		levels[level] = append(levels[level], leaf)
+		if level > maxLevel {
+			maxLevel = level
+		}
+	}
+
+	for level := maxLevel; level > 0; level-- {
+		nodes := levels[level]
+		sort.Slice(nodes, func(i, j int) bool {
+			return (*big.Int)(&nodes[i]).Cmp((*big.Int)(&nodes[j])) < 0
+		})
+		onPath := make(map[string]bool, len(nodes))
+		for _, node := range nodes {
+			onPath[node.AsString()] = true
+		}
+
+		var lastParent *Position
+		for _, node := range nodes {
+			parent := t.getParent(&node)
+			if lastParent != nil && parent.Equals(lastParent) {
+				continue
+			}
+			lastParent = parent
+			if leafSet[parent.AsString()] {
+				return nil, nil, fmt.Errorf("leaf at position %x is an ancestor of another leaf", parent.GetBytes())
+			}
+			parents = append(parents, *parent)
+			for c := ChildIndex(0); int(c) < t.ChildrenPerNode; c++ {
+				child := t.GetChild(parent, c)
+				if !onPath[child.AsString()] {
+					siblings = append(siblings, *child)
+				}
+			}
+			levels[level-1] = append(levels[level-1], *parent)
+		}
+	}
+	return parents, siblings, nil
+}
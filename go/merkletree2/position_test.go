@@ -396,3 +396,58 @@ func TestGetKeyIntervalUnderPosition(t *testing.T) {
 	}
 
 }
+
+func TestGetMultiProofPositions(t *testing.T) {
+
+	config1bit, _, config3bits := getTreeCfgsWith1_2_3BitsPerIndexUnblinded(t)
+
+	tests := []struct {
+		c      Config
+		leaves []string
+		pars   []string
+		sibs   []string
+	}{
+		{config1bit, []string{"1"}, nil, nil},
+		{config1bit, []string{"101"}, []string{"10", "1"}, []string{"100", "11"}},
+		{config1bit, []string{"1011", "100"}, []string{"101", "10", "1"}, []string{"1010", "11"}},
+		{config1bit, []string{"11", "100", "101"}, []string{"10", "1"}, nil},
+		{config3bits, []string{"1001", "1111"}, []string{"1"}, []string{"1000", "1010", "1011", "1100", "1101", "1110"}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v bits: leaves %v", test.c.BitsPerIndex, test.leaves), func(t *testing.T) {
+			var leaves []Position
+			for _, l := range test.leaves {
+				pos, err := makePositionFromStringForTesting(l)
+				require.NoError(t, err)
+				leaves = append(leaves, pos)
+			}
+			pars, sibs, err := test.c.getMultiProofPositions(leaves)
+			require.NoError(t, err)
+			require.Len(t, pars, len(test.pars))
+			for i, p := range test.pars {
+				expPos, err := makePositionFromStringForTesting(p)
+				require.NoError(t, err)
+				require.True(t, expPos.Equals(&pars[i]), "parent %v: expected %v", i, p)
+			}
+			require.Len(t, sibs, len(test.sibs))
+			for i, s := range test.sibs {
+				expPos, err := makePositionFromStringForTesting(s)
+				require.NoError(t, err)
+				require.True(t, expPos.Equals(&sibs[i]), "sibling %v: expected %v", i, s)
+			}
+		})
+	}
+
+	// duplicated leaves, and leaves which are ancestors of other leaves
+	for _, leaves := range [][]string{{"101", "101"}, {"10", "101"}, {"1", "11"}} {
+		var positions []Position
+		for _, l := range leaves {
+			pos, err := makePositionFromStringForTesting(l)
+			require.NoError(t, err)
+			positions = append(positions, pos)
+		}
+		_, _, err := config1bit.getMultiProofPositions(positions)
+		require.Error(t, err)
+	}
+}
@@ -2,6 +2,7 @@ package merkletree2
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/adamwalz/keybase-client/go/libkb"
 	"github.com/adamwalz/keybase-client/go/logger"
@@ -137,6 +138,181 @@ func (m *MerkleProofVerifier) verifyInclusionOrExclusionProof(ctx logger.Context
 	return nil
 }
 
+// VerifyMultiInclusionProof checks a MerkleMultiInclusionProof for all of kvps
+// at once. Pairs with a nil Value are checked not to be part of the tree, and
+// the others to be included in it with that value.
+func (m *MerkleProofVerifier) VerifyMultiInclusionProof(ctx logger.ContextInterface, kvps []KeyValuePair, proof *MerkleMultiInclusionProof, expRootHash Hash) (err error) {
+	if proof == nil {
+		return NewProofVerificationFailedError(fmt.Errorf("nil proof"))
+	}
+	if len(kvps) == 0 {
+		return NewProofVerificationFailedError(fmt.Errorf("no keys to verify"))
+	}
+	if proof.RootMetadataNoHash.RootVersion != RootVersionV1 {
+		return NewProofVerificationFailedError(libkb.NewAppOutdatedError(fmt.Errorf("RootVersion %v is not supported (this client can only handle V1)", proof.RootMetadataNoHash.RootVersion)))
+	}
+
+	sortedKVPs := make([]KeyValuePair, len(kvps))
+	copy(sortedKVPs, kvps)
+	sort.Slice(sortedKVPs, func(i, j int) bool { return sortedKVPs[i].Key.Cmp(sortedKVPs[j].Key) < 0 })
+	for i, kvp := range sortedKVPs {
+		if len(kvp.Key) != m.cfg.KeysByteLength {
+			return NewProofVerificationFailedError(fmt.Errorf("Key has wrong length for this tree: %v (expected %v)", len(kvp.Key), m.cfg.KeysByteLength))
+		}
+		if i > 0 && sortedKVPs[i-1].Key.Equal(kvp.Key) {
+			return NewProofVerificationFailedError(fmt.Errorf("Duplicated key %X", kvp.Key))
+		}
+	}
+
+	// Recompute the hash of each leaf from the keys (in order) which the proof
+	// assigns to it.
+	leafPositions := make([]Position, len(proof.Leaves))
+	nodeHashes := make(map[string]Hash)
+	next := 0
+	for i, leaf := range proof.Leaves {
+		if leaf.NumKeys < 1 || next+leaf.NumKeys > len(sortedKVPs) {
+			return NewProofVerificationFailedError(fmt.Errorf("Invalid number of keys in leaf %v: %v", i, leaf.NumKeys))
+		}
+		if leaf.Level < 0 || leaf.Level > m.cfg.MaxDepth {
+			return NewProofVerificationFailedError(fmt.Errorf("Invalid level for leaf %v: %v", i, leaf.Level))
+		}
+		leafKVPs := sortedKVPs[next : next+leaf.NumKeys]
+		next += leaf.NumKeys
+
+		for j, kvp := range leafKVPs {
+			keyAsPos, err := m.cfg.getDeepestPositionForKey(kvp.Key)
+			if err != nil {
+				return NewProofVerificationFailedError(err)
+			}
+			leafPos := m.cfg.getParentAtLevel(keyAsPos, uint(leaf.Level))
+			if j == 0 {
+				leafPositions[i] = *leafPos
+			} else if !leafPos.Equals(&leafPositions[i]) {
+				return NewProofVerificationFailedError(fmt.Errorf("Key %X is not under leaf %v", kvp.Key, i))
+			}
+		}
+
+		nodeHash, err := m.computeMultiProofLeafHash(leafKVPs, &leaf)
+		if err != nil {
+			return NewProofVerificationFailedError(err)
+		}
+		nodeHashes[leafPositions[i].AsString()] = nodeHash
+	}
+	if next != len(sortedKVPs) {
+		return NewProofVerificationFailedError(fmt.Errorf("The proof covers %v keys, but %v were expected", next, len(sortedKVPs)))
+	}
+
+	// Then recompute the hashes of all the internal nodes on the paths from
+	// the leaves to the root, from the deepest ones up.
+	parents, siblings, err := m.cfg.getMultiProofPositions(leafPositions)
+	if err != nil {
+		return NewProofVerificationFailedError(err)
+	}
+	if len(siblings) != len(proof.SiblingHashes) {
+		return NewProofVerificationFailedError(fmt.Errorf("Invalid number of SiblingHashes %v (expected %v)", len(proof.SiblingHashes), len(siblings)))
+	}
+	for i, p := range siblings {
+		nodeHashes[p.AsString()] = proof.SiblingHashes[i]
+	}
+	for _, p := range parents {
+		node := Node{INodes: make([]Hash, m.cfg.ChildrenPerNode)}
+		for c := ChildIndex(0); int(c) < m.cfg.ChildrenPerNode; c++ {
+			node.INodes[c] = nodeHashes[m.cfg.GetChild(&p, c).AsString()]
+		}
+		_, nodeHashes[p.AsString()], err = m.cfg.Encoder.EncodeAndHashGeneric(node)
+		if err != nil {
+			return NewProofVerificationFailedError(err)
+		}
+	}
+
+	// Compute the hash of the RootMetadata by filling in the BareRootHash
+	// with the value computed above.
+	rootMetadata := proof.RootMetadataNoHash
+	rootMetadata.BareRootHash = nodeHashes[m.cfg.GetRootPosition().AsString()]
+	_, rootHash, err := m.cfg.Encoder.EncodeAndHashGeneric(rootMetadata)
+	if err != nil {
+		return NewProofVerificationFailedError(err)
+	}
+
+	// Check the rootHash computed matches the expected value.
+	if !rootHash.Equal(expRootHash) {
+		return NewProofVerificationFailedError(fmt.Errorf("expected rootHash does not match the computed one: expected %X but got %X", expRootHash, rootHash))
+	}
+
+	// Success!
+	return nil
+}
+
+// computeMultiProofLeafHash returns the hash of a leaf in a
+// MerkleMultiInclusionProof, given the sorted key value pairs that are
+// supposed to be (or, if their Value is nil, not to be) stored in it.
+func (m *MerkleProofVerifier) computeMultiProofLeafHash(kvps []KeyValuePair, leaf *MerkleMultiProofLeaf) (Hash, error) {
+	var included []KeyHashPair
+	for _, kvp := range kvps {
+		if kvp.Value == nil {
+			continue
+		}
+		var kss KeySpecificSecret
+		if m.cfg.UseBlindedValueHashes {
+			if len(included) >= len(leaf.KeySpecificSecrets) {
+				return nil, fmt.Errorf("Missing KeySpecificSecret for key %X", kvp.Key)
+			}
+			kss = leaf.KeySpecificSecrets[len(included)]
+		}
+		h, err := m.cfg.Encoder.HashKeyValuePairWithKeySpecificSecret(kvp, kss)
+		if err != nil {
+			return nil, err
+		}
+		included = append(included, KeyHashPair{Key: kvp.Key, Hash: h})
+	}
+	if m.cfg.UseBlindedValueHashes && len(leaf.KeySpecificSecrets) != len(included) {
+		return nil, fmt.Errorf("Invalid number of KeySpecificSecrets %v (expected %v)", len(leaf.KeySpecificSecrets), len(included))
+	}
+	if !m.cfg.UseBlindedValueHashes && len(leaf.KeySpecificSecrets) > 0 {
+		return nil, fmt.Errorf("Unexpected KeySpecificSecrets in an unblinded tree")
+	}
+
+	// Keys which should not be in the tree can't be among the other pairs
+	// either.
+	for _, kvp := range kvps {
+		if kvp.Value != nil {
+			continue
+		}
+		for _, khp := range leaf.OtherPairsInLeaf {
+			if khp.Key.Equal(kvp.Key) {
+				return nil, fmt.Errorf("Key %X is in the leaf", kvp.Key)
+			}
+		}
+	}
+
+	// An absent node (nil hash) is fine only if none of the keys are in it.
+	if len(included) == 0 && leaf.OtherPairsInLeaf == nil {
+		return nil, nil
+	}
+	if len(included)+len(leaf.OtherPairsInLeaf) > m.cfg.MaxValuesPerLeaf {
+		return nil, fmt.Errorf("Too many keys in leaf: %v > %v", len(included)+len(leaf.OtherPairsInLeaf), m.cfg.MaxValuesPerLeaf)
+	}
+
+	// LeafHashes is obtained by merging the included pairs into
+	// OtherPairsInLeaf while maintaining sorted order
+	node := Node{LeafHashes: make([]KeyHashPair, 0, len(included)+len(leaf.OtherPairsInLeaf))}
+	for i, j := 0, 0; i < len(included) || j < len(leaf.OtherPairsInLeaf); {
+		if j >= len(leaf.OtherPairsInLeaf) || (i < len(included) && included[i].Key.Cmp(leaf.OtherPairsInLeaf[j].Key) < 0) {
+			node.LeafHashes = append(node.LeafHashes, included[i])
+			i++
+		} else {
+			node.LeafHashes = append(node.LeafHashes, leaf.OtherPairsInLeaf[j])
+			j++
+		}
+		// Ensure all the KeyHashPairs in the leaf node are different
+		if n := len(node.LeafHashes); n > 1 && node.LeafHashes[n-2].Key.Cmp(node.LeafHashes[n-1].Key) >= 0 {
+			return nil, fmt.Errorf("Error in Leaf Key ordering or duplicated key: %v >= %v", node.LeafHashes[n-2].Key, node.LeafHashes[n-1].Key)
+		}
+	}
+	_, h, err := m.cfg.Encoder.EncodeAndHashGeneric(node)
+	return h, err
+}
+
 func (m *MerkleProofVerifier) computeSkipsHashForSeqno(s Seqno, skipsMap map[Seqno]Hash) (Hash, error) {
 	skipSeqnos := SkipPointersForSeqno(s)
 	skips := make([]Hash, len(skipSeqnos))
@@ -0,0 +1,629 @@
+package merkletree2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/adamwalz/keybase-client/go/logger"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Every record type lives under its own single byte prefix. Nodes and
+// KeyEncodedValuePairs are stored once per Seqno at which they changed, with
+// the bitwise complement of the Seqno at the end of the key, so that the
+// versions of a position (or key) are sorted from the newest to the oldest and
+// the version visible at Seqno s is the first one at or after the one for s.
+const (
+	// prefixRoot + Seqno -> encoded RootMetadata
+	levelDBPrefixRoot byte = 'r'
+	// prefixRootHash + Hash -> Seqno
+	levelDBPrefixRootHash byte = 'h'
+	// prefixNode + uint16 length + Position + ^Seqno -> Hash
+	levelDBPrefixNode byte = 'n'
+	// prefixKEVPair + Key + ^Seqno -> EncodedValue
+	levelDBPrefixKEVPair byte = 'k'
+	// prefixMasterSecret + Seqno -> MasterSecret
+	levelDBPrefixMasterSecret byte = 'm'
+	// levelDBKeyPrunedBefore -> Seqno passed to the latest PruneBefore
+	levelDBKeyPrunedBefore byte = 'p'
+)
+
+// levelDBPruneBatchSize bounds how many deletes PruneBefore buffers at once
+// when it isn't running as part of a Transaction. Within a Transaction, all
+// the deletes are written at the end, since its iterators don't have a
+// snapshot to protect them from its own writes.
+const levelDBPruneBatchSize = 10000
+
+// levelDBOps is implemented by both *leveldb.DB and *leveldb.Transaction.
+type levelDBOps interface {
+	Get(key []byte, ro *opt.ReadOptions) (value []byte, err error)
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+	Put(key, value []byte, wo *opt.WriteOptions) error
+	Delete(key []byte, wo *opt.WriteOptions) error
+	Write(b *leveldb.Batch, wo *opt.WriteOptions) error
+}
+
+// LevelDBStorageEngine is a persistent StorageEngineWithBlinding backed by a
+// LevelDB database. It keeps every version of the tree, so that lookups at any
+// Seqno which has not been pruned are answered exactly as they were when that
+// Seqno was the latest one.
+//
+// Methods can be called with a nil Transaction, in which case reads see the
+// latest committed state and writes are applied immediately, or with a
+// Transaction passed to the function given to ExecTransaction.
+type LevelDBStorageEngine struct {
+	cfg    Config
+	db     *leveldb.DB
+	ownsDB bool
+}
+
+var _ StorageEngine = &LevelDBStorageEngine{}
+var _ StorageEngineWithBlinding = &LevelDBStorageEngine{}
+
+// LevelDBTransaction is the Transaction used by LevelDBStorageEngine. All the
+// writes made through it are committed atomically when the function passed
+// to ExecTransaction returns without an error, and reads made through it see
+// those writes before they are committed.
+type LevelDBTransaction struct {
+	tr *leveldb.Transaction
+}
+
+// NewLevelDBStorageEngine opens (or creates) the LevelDB database at path.
+// The database is closed by Close.
+func NewLevelDBStorageEngine(cfg Config, path string) (*LevelDBStorageEngine, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDBStorageEngine{cfg: cfg, db: db, ownsDB: true}, nil
+}
+
+// NewLevelDBStorageEngineFromDB returns an engine which stores the tree in an
+// already open database. The database should not be used for anything else,
+// and closing it is up to the caller.
+func NewLevelDBStorageEngineFromDB(cfg Config, db *leveldb.DB) *LevelDBStorageEngine {
+	return &LevelDBStorageEngine{cfg: cfg, db: db}
+}
+
+// Close closes the underlying database if it was opened by this engine.
+func (e *LevelDBStorageEngine) Close() error {
+	if !e.ownsDB {
+		return nil
+	}
+	return e.db.Close()
+}
+
+// ExecTransaction runs txFn within a LevelDB transaction, which is committed
+// if txFn returns nil and discarded otherwise. Other writes to the database
+// block until the transaction is done, so txFn must not call ExecTransaction
+// again or make any writes with a nil Transaction.
+func (e *LevelDBStorageEngine) ExecTransaction(ctx logger.ContextInterface, txFn func(logger.ContextInterface, Transaction) error) (err error) {
+	tr, err := e.db.OpenTransaction()
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tr.Discard()
+		}
+	}()
+	if err = txFn(ctx, &LevelDBTransaction{tr: tr}); err != nil {
+		return err
+	}
+	if err = tr.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+func (e *LevelDBStorageEngine) ops(t Transaction) (levelDBOps, error) {
+	switch t := t.(type) {
+	case nil:
+		return e.db, nil
+	case *LevelDBTransaction:
+		if t == nil || t.tr == nil {
+			return nil, fmt.Errorf("LevelDBStorageEngine: empty transaction")
+		}
+		return t.tr, nil
+	default:
+		return nil, fmt.Errorf("LevelDBStorageEngine: unsupported transaction type %T", t)
+	}
+}
+
+func levelDBSeqnoBytes(s Seqno) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(s))
+	return b[:]
+}
+
+// levelDBVersionBytes sorts newer Seqnos first.
+func levelDBVersionBytes(s Seqno) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], ^uint64(s))
+	return b[:]
+}
+
+func levelDBVersionFromBytes(b []byte) Seqno {
+	return Seqno(^binary.BigEndian.Uint64(b))
+}
+
+func levelDBKey(parts ...[]byte) []byte {
+	var buf bytes.Buffer
+	for _, part := range parts {
+		buf.Write(part)
+	}
+	return buf.Bytes()
+}
+
+func levelDBRootKey(s Seqno) []byte {
+	return levelDBKey([]byte{levelDBPrefixRoot}, levelDBSeqnoBytes(s))
+}
+
+func levelDBRootHashKey(h Hash) []byte {
+	return levelDBKey([]byte{levelDBPrefixRootHash}, h)
+}
+
+func levelDBMasterSecretKey(s Seqno) []byte {
+	return levelDBKey([]byte{levelDBPrefixMasterSecret}, levelDBSeqnoBytes(s))
+}
+
+// levelDBNodePrefix is the prefix shared by all the versions of the node at
+// position p. The length makes sure it isn't a prefix of any other
+// position's versions.
+func levelDBNodePrefix(p *Position) []byte {
+	pBytes := p.GetBytes()
+	var l [2]byte
+	binary.BigEndian.PutUint16(l[:], uint16(len(pBytes)))
+	return levelDBKey([]byte{levelDBPrefixNode}, l[:], pBytes)
+}
+
+func levelDBKEVPairPrefix(k Key) []byte {
+	return levelDBKey([]byte{levelDBPrefixKEVPair}, k)
+}
+
+func (e *LevelDBStorageEngine) get(ops levelDBOps, key []byte) (val []byte, found bool, err error) {
+	val, err = ops.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return val, true, nil
+}
+
+func (e *LevelDBStorageEngine) lookupPrunedBefore(ops levelDBOps) (Seqno, error) {
+	val, found, err := e.get(ops, []byte{levelDBKeyPrunedBefore})
+	if err != nil || !found {
+		return 0, err
+	}
+	if len(val) != 8 {
+		return 0, fmt.Errorf("LevelDBStorageEngine: malformed pruning record")
+	}
+	return Seqno(binary.BigEndian.Uint64(val)), nil
+}
+
+// checkNotPruned makes sure the tree at Seqno s is still fully stored, so
+// that lookups don't silently return nodes and values from a later version.
+func (e *LevelDBStorageEngine) checkNotPruned(ops levelDBOps, s Seqno) error {
+	prunedBefore, err := e.lookupPrunedBefore(ops)
+	if err != nil {
+		return err
+	}
+	if s < prunedBefore {
+		return NewInvalidSeqnoError(s, fmt.Errorf("Seqnos before %v have been pruned", prunedBefore))
+	}
+	return nil
+}
+
+// latestVersion returns the value and Seqno of the newest record at or before
+// Seqno s among the versions sharing prefix.
+func (e *LevelDBStorageEngine) latestVersion(ops levelDBOps, prefix []byte, s Seqno) (val []byte, s1 Seqno, found bool, err error) {
+	iter := ops.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+	if !iter.Seek(levelDBKey(prefix, levelDBVersionBytes(s))) {
+		return nil, 0, false, iter.Error()
+	}
+	key := iter.Key()
+	if len(key) != len(prefix)+8 {
+		return nil, 0, false, fmt.Errorf("LevelDBStorageEngine: malformed key %x", key)
+	}
+	return append([]byte{}, iter.Value()...), levelDBVersionFromBytes(key[len(prefix):]), true, nil
+}
+
+// kevPairsInRange returns, for every key in [minKey, maxKey], the newest
+// version at or before Seqno s, ordered by key.
+func (e *LevelDBStorageEngine) kevPairsInRange(ops levelDBOps, s Seqno, minKey, maxKey Key) (kevps []KeyEncodedValuePair, seqnos []Seqno, err error) {
+	rng := &util.Range{Start: levelDBKEVPairPrefix(minKey)}
+	if maxKey == nil {
+		rng.Limit = []byte{levelDBPrefixKEVPair + 1}
+	} else {
+		rng.Limit = levelDBKey(levelDBKEVPairPrefix(maxKey), bytes.Repeat([]byte{0xff}, 9))
+	}
+	iter := ops.NewIterator(rng, nil)
+	defer iter.Release()
+
+	var lastKey Key
+	for iter.Next() {
+		dbKey := iter.Key()
+		if len(dbKey) != 1+e.cfg.KeysByteLength+8 {
+			return nil, nil, fmt.Errorf("LevelDBStorageEngine: malformed key %x", dbKey)
+		}
+		k := Key(dbKey[1 : 1+e.cfg.KeysByteLength])
+		if lastKey != nil && lastKey.Equal(k) {
+			// an older version of a key we already returned
+			continue
+		}
+		s1 := levelDBVersionFromBytes(dbKey[1+e.cfg.KeysByteLength:])
+		if s1 > s {
+			continue
+		}
+		lastKey = append(Key{}, k...)
+		kevps = append(kevps, KeyEncodedValuePair{Key: lastKey, Value: append(EncodedValue{}, iter.Value()...)})
+		seqnos = append(seqnos, s1)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, err
+	}
+	return kevps, seqnos, nil
+}
+
+func (e *LevelDBStorageEngine) StoreKEVPairs(c logger.ContextInterface, t Transaction, s Seqno, kevps []KeyEncodedValuePair) error {
+	ops, err := e.ops(t)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	for _, kevp := range kevps {
+		if len(kevp.Key) != e.cfg.KeysByteLength {
+			return NewInvalidKeyError()
+		}
+		batch.Put(levelDBKey(levelDBKEVPairPrefix(kevp.Key), levelDBVersionBytes(s)), kevp.Value)
+	}
+	return ops.Write(batch, nil)
+}
+
+func (e *LevelDBStorageEngine) StoreNode(c logger.ContextInterface, t Transaction, s Seqno, p *Position, h Hash) error {
+	return e.StoreNodes(c, t, s, []PositionHashPair{{Position: *p, Hash: h}})
+}
+
+func (e *LevelDBStorageEngine) StoreNodes(c logger.ContextInterface, t Transaction, s Seqno, phps []PositionHashPair) error {
+	ops, err := e.ops(t)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	for _, php := range phps {
+		batch.Put(levelDBKey(levelDBNodePrefix(&php.Position), levelDBVersionBytes(s)), php.Hash)
+	}
+	return ops.Write(batch, nil)
+}
+
+func (e *LevelDBStorageEngine) StoreRootMetadata(c logger.ContextInterface, t Transaction, r RootMetadata, h Hash) error {
+	ops, err := e.ops(t)
+	if err != nil {
+		return err
+	}
+	enc, err := e.cfg.Encoder.Encode(r)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	batch.Put(levelDBRootKey(r.Seqno), enc)
+	batch.Put(levelDBRootHashKey(h), levelDBSeqnoBytes(r.Seqno))
+	return ops.Write(batch, nil)
+}
+
+func (e *LevelDBStorageEngine) decodeRoot(enc []byte) (r RootMetadata, err error) {
+	if err := e.cfg.Encoder.Decode(&r, enc); err != nil {
+		return RootMetadata{}, err
+	}
+	return r, nil
+}
+
+func (e *LevelDBStorageEngine) lookupRoot(ops levelDBOps, s Seqno) (RootMetadata, error) {
+	enc, found, err := e.get(ops, levelDBRootKey(s))
+	if err != nil {
+		return RootMetadata{}, err
+	}
+	if !found {
+		return RootMetadata{}, NewInvalidSeqnoError(s, fmt.Errorf("No root at seqno %v", s))
+	}
+	return e.decodeRoot(enc)
+}
+
+func (e *LevelDBStorageEngine) LookupLatestRoot(c logger.ContextInterface, t Transaction) (Seqno, RootMetadata, error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return 0, RootMetadata{}, err
+	}
+	iter := ops.NewIterator(util.BytesPrefix([]byte{levelDBPrefixRoot}), nil)
+	defer iter.Release()
+	if !iter.Last() {
+		if err := iter.Error(); err != nil {
+			return 0, RootMetadata{}, err
+		}
+		return 0, RootMetadata{}, NewNoLatestRootFoundError()
+	}
+	r, err := e.decodeRoot(iter.Value())
+	if err != nil {
+		return 0, RootMetadata{}, err
+	}
+	return r.Seqno, r, nil
+}
+
+func (e *LevelDBStorageEngine) LookupRoot(c logger.ContextInterface, t Transaction, s Seqno) (RootMetadata, error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return RootMetadata{}, err
+	}
+	return e.lookupRoot(ops, s)
+}
+
+func (e *LevelDBStorageEngine) LookupRootFromHash(c logger.ContextInterface, t Transaction, h Hash) (RootMetadata, error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return RootMetadata{}, err
+	}
+	val, found, err := e.get(ops, levelDBRootHashKey(h))
+	if err != nil {
+		return RootMetadata{}, err
+	}
+	if !found || len(val) != 8 {
+		return RootMetadata{}, NewInvalidSeqnoError(0, fmt.Errorf("No root with hash %x", h))
+	}
+	return e.lookupRoot(ops, Seqno(binary.BigEndian.Uint64(val)))
+}
+
+func (e *LevelDBStorageEngine) LookupRoots(c logger.ContextInterface, t Transaction, seqnos []Seqno) (roots []RootMetadata, err error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return nil, err
+	}
+	seqnosSorted := make([]Seqno, len(seqnos))
+	copy(seqnosSorted, seqnos)
+	sort.Sort(SeqnoSortedAsInt(seqnosSorted))
+
+	roots = make([]RootMetadata, len(seqnosSorted))
+	for j, s := range seqnosSorted {
+		roots[j], err = e.lookupRoot(ops, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return roots, nil
+}
+
+func (e *LevelDBStorageEngine) LookupRootHashes(c logger.ContextInterface, t Transaction, seqnos []Seqno) (hashes []Hash, err error) {
+	if len(seqnos) == 0 {
+		return nil, fmt.Errorf("No seqnos requested")
+	}
+	roots, err := e.LookupRoots(c, t, seqnos)
+	if err != nil {
+		return nil, err
+	}
+	hashes = make([]Hash, len(roots))
+	for j, r := range roots {
+		_, hashes[j], err = e.cfg.Encoder.EncodeAndHashGeneric(r)
+		if err != nil {
+			return nil, fmt.Errorf("Error encoding %+v: %v", r, err)
+		}
+	}
+	return hashes, nil
+}
+
+func (e *LevelDBStorageEngine) LookupNode(c logger.ContextInterface, t Transaction, s Seqno, p *Position) (Hash, error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkNotPruned(ops, s); err != nil {
+		return nil, err
+	}
+	h, _, found, err := e.latestVersion(ops, levelDBNodePrefix(p), s)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, NewNodeNotFoundError()
+	}
+	return h, nil
+}
+
+func (e *LevelDBStorageEngine) LookupNodes(c logger.ContextInterface, t Transaction, s Seqno, positions []Position) (res []PositionHashPair, err error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkNotPruned(ops, s); err != nil {
+		return nil, err
+	}
+	for _, p := range positions {
+		h, _, found, err := e.latestVersion(ops, levelDBNodePrefix(&p), s)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			res = append(res, PositionHashPair{Position: p, Hash: h})
+		}
+	}
+	return res, nil
+}
+
+func (e *LevelDBStorageEngine) LookupKEVPair(c logger.ContextInterface, t Transaction, s Seqno, k Key) (EncodedValue, Seqno, error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := e.checkNotPruned(ops, s); err != nil {
+		return nil, 0, err
+	}
+	val, s1, found, err := e.latestVersion(ops, levelDBKEVPairPrefix(k), s)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !found {
+		return nil, 0, NewKeyNotFoundError()
+	}
+	return val, s1, nil
+}
+
+func (e *LevelDBStorageEngine) LookupKEVPairsUnderPosition(ctx logger.ContextInterface, t Transaction, s Seqno, p *Position) (kevps []KeyEncodedValuePair, seqnos []Seqno, err error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.checkNotPruned(ops, s); err != nil {
+		return nil, nil, err
+	}
+	minKey, maxKey := e.cfg.GetKeyIntervalUnderPosition(p)
+	kevps, seqnos, err = e.kevPairsInRange(ops, s, minKey, maxKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(kevps) == 0 {
+		return nil, nil, NewKeyNotFoundError()
+	}
+	return kevps, seqnos, nil
+}
+
+// LookupAllKEVPairs returns all the keys and encoded values at the specified Seqno.
+func (e *LevelDBStorageEngine) LookupAllKEVPairs(ctx logger.ContextInterface, t Transaction, s Seqno) (kevps []KeyEncodedValuePair, err error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkNotPruned(ops, s); err != nil {
+		return nil, err
+	}
+	kevps, _, err = e.kevPairsInRange(ops, s, Key{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if kevps == nil {
+		kevps = []KeyEncodedValuePair{}
+	}
+	return kevps, nil
+}
+
+func (e *LevelDBStorageEngine) StoreMasterSecret(ctx logger.ContextInterface, t Transaction, s Seqno, ms MasterSecret) (err error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return err
+	}
+	return ops.Put(levelDBMasterSecretKey(s), ms, nil)
+}
+
+func (e *LevelDBStorageEngine) LookupMasterSecrets(ctx logger.ContextInterface, t Transaction, seqnos []Seqno) (msMap map[Seqno]MasterSecret, err error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return nil, err
+	}
+	msMap = make(map[Seqno]MasterSecret)
+	for _, s := range seqnos {
+		ms, found, err := e.get(ops, levelDBMasterSecretKey(s))
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("MasterSecret for Seqno %v not found", s)
+		}
+		msMap[s] = MasterSecret(ms)
+	}
+	return msMap, nil
+}
+
+// PruneBefore deletes the data which is only needed to look up the tree at
+// Seqnos smaller than s: the master secrets for those Seqnos, and the versions
+// of nodes and KeyEncodedValuePairs which were superseded at or before s.
+// Roots are all kept, since skip pointers and extension proofs refer back to
+// them. After pruning, lookups of nodes, values and master secrets at Seqnos
+// smaller than s return an InvalidSeqnoError.
+//
+// With a nil Transaction, the deletes are applied in batches. Lookups at s or
+// later return the same results throughout, so a PruneBefore that fails
+// midway can just be run again.
+func (e *LevelDBStorageEngine) PruneBefore(ctx logger.ContextInterface, t Transaction, s Seqno) (err error) {
+	ops, err := e.ops(t)
+	if err != nil {
+		return err
+	}
+	prunedBefore, err := e.lookupPrunedBefore(ops)
+	if err != nil {
+		return err
+	}
+	if s > prunedBefore {
+		// Mark the old seqnos as pruned first, so they are never looked up
+		// while partially deleted.
+		if err := ops.Put([]byte{levelDBKeyPrunedBefore}, levelDBSeqnoBytes(s), nil); err != nil {
+			return err
+		}
+	}
+
+	batch := new(leveldb.Batch)
+	flush := func(force bool) error {
+		if batch.Len() == 0 || (!force && (t != nil || batch.Len() < levelDBPruneBatchSize)) {
+			return nil
+		}
+		if err := ops.Write(batch, nil); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+
+	// Within each position or key, versions are sorted from the newest to the
+	// oldest: keep all those after s and the first one at or before s.
+	for _, prefix := range []byte{levelDBPrefixNode, levelDBPrefixKEVPair} {
+		var deleted int
+		err := func() error {
+			iter := ops.NewIterator(util.BytesPrefix([]byte{prefix}), nil)
+			defer iter.Release()
+			var lastPrefix []byte
+			for iter.Next() {
+				key := iter.Key()
+				if len(key) < 9 {
+					return fmt.Errorf("LevelDBStorageEngine: malformed key %x", key)
+				}
+				versionPrefix := key[:len(key)-8]
+				if levelDBVersionFromBytes(key[len(key)-8:]) > s {
+					continue
+				}
+				if !bytes.Equal(versionPrefix, lastPrefix) {
+					lastPrefix = append([]byte{}, versionPrefix...)
+					continue
+				}
+				batch.Delete(append([]byte{}, key...))
+				deleted++
+				if err := flush(false); err != nil {
+					return err
+				}
+			}
+			return iter.Error()
+		}()
+		if err != nil {
+			return err
+		}
+		ctx.Debug("LevelDBStorageEngine#PruneBefore(%v): deleted %d old versions with prefix %q", s, deleted, prefix)
+	}
+
+	iter := ops.NewIterator(&util.Range{
+		Start: []byte{levelDBPrefixMasterSecret},
+		Limit: levelDBMasterSecretKey(s),
+	}, nil)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return flush(true)
+}
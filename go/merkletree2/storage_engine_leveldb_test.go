@@ -0,0 +1,164 @@
+package merkletree2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/adamwalz/keybase-client/go/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelDBStorageEngineTransactions(t *testing.T) {
+	_, cfg, _ := getTreeCfgsWith1_2_3BitsPerIndexBlinded(t)
+	kvps1, kvps2, _ := getSampleKVPS1bit()
+	ctx := NewLoggerContextTodoForTesting(t)
+
+	tree, err := NewTree(cfg, 2, newLevelDBStorageEngineForTesting(t, cfg), RootVersionV1)
+	require.NoError(t, err)
+
+	errDiscard := errors.New("discard")
+	err = tree.ExecTransaction(ctx, func(ctx logger.ContextInterface, tr Transaction) error {
+		s, _, err := tree.Build(ctx, tr, kvps1, nil)
+		require.NoError(t, err)
+		require.Equal(t, Seqno(1), s)
+		// writes are visible within the transaction
+		kvp, err := tree.GetKeyValuePair(ctx, tr, 1, kvps1[0].Key)
+		require.NoError(t, err)
+		require.Equal(t, kvps1[0].Value, kvp.Value)
+		return errDiscard
+	})
+	require.Equal(t, errDiscard, err)
+	_, _, _, err = tree.GetLatestRoot(ctx, nil)
+	require.IsType(t, NoLatestRootFoundError{}, err)
+	t.Logf("a failed transaction leaves nothing behind")
+
+	var rootHash Hash
+	err = tree.ExecTransaction(ctx, func(ctx logger.ContextInterface, tr Transaction) (err error) {
+		_, rootHash, err = tree.Build(ctx, tr, kvps1, nil)
+		return err
+	})
+	require.NoError(t, err)
+	s, _, latestHash, err := tree.GetLatestRoot(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, Seqno(1), s)
+	require.Equal(t, rootHash, latestHash)
+
+	s, _, err = tree.Build(ctx, nil, kvps2, nil)
+	require.NoError(t, err)
+	require.Equal(t, Seqno(2), s)
+
+	_, err = tree.GetKeyValuePair(ctx, struct{}{}, 1, kvps1[0].Key)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported transaction type")
+}
+
+func TestLevelDBStorageEngineReopen(t *testing.T) {
+	_, cfg, _ := getTreeCfgsWith1_2_3BitsPerIndexBlinded(t)
+	kvps1, kvps2, kvps3 := getSampleKVPS1bit()
+	ctx := NewLoggerContextTodoForTesting(t)
+	dir := t.TempDir()
+
+	eng, err := NewLevelDBStorageEngine(cfg, dir)
+	require.NoError(t, err)
+	tree, err := NewTree(cfg, 2, eng, RootVersionV1)
+	require.NoError(t, err)
+	rootHashes := make(map[Seqno]Hash)
+	for _, kvps := range [][]KeyValuePair{kvps1, kvps2, kvps3} {
+		s, rootHash, err := tree.Build(ctx, nil, kvps, nil)
+		require.NoError(t, err)
+		rootHashes[s] = rootHash
+	}
+	require.NoError(t, eng.Close())
+
+	eng, err = NewLevelDBStorageEngine(cfg, dir)
+	require.NoError(t, err)
+	defer eng.Close()
+	tree, err = NewTree(cfg, 2, eng, RootVersionV1)
+	require.NoError(t, err)
+	verifier := NewMerkleProofVerifier(cfg)
+
+	s, _, latestHash, err := tree.GetLatestRoot(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, Seqno(3), s)
+	require.Equal(t, rootHashes[3], latestHash)
+	for s, kvps := range map[Seqno][]KeyValuePair{1: kvps1, 2: kvps2, 3: kvps3} {
+		for _, kvp := range kvps {
+			kvpRet, proof, err := tree.GetKeyValuePairWithProof(ctx, nil, s, kvp.Key)
+			require.NoError(t, err)
+			require.Equal(t, kvp.Value, kvpRet.Value)
+			require.NoError(t, verifier.VerifyInclusionProof(ctx, kvpRet, &proof, rootHashes[s]))
+		}
+	}
+	t.Logf("every version of the tree survives reopening the database")
+}
+
+func TestLevelDBStorageEnginePruneBefore(t *testing.T) {
+	_, cfg, _ := getTreeCfgsWith1_2_3BitsPerIndexBlinded(t)
+	kvps1, kvps2, kvps3 := getSampleKVPS1bit()
+	ctx := NewLoggerContextTodoForTesting(t)
+
+	eng := newLevelDBStorageEngineForTesting(t, cfg)
+	tree, err := NewTree(cfg, 2, eng, RootVersionV1)
+	require.NoError(t, err)
+	rootHashes := make(map[Seqno]Hash)
+	for _, kvps := range [][]KeyValuePair{kvps1, kvps2, kvps3} {
+		s, rootHash, err := tree.Build(ctx, nil, kvps, nil)
+		require.NoError(t, err)
+		rootHashes[s] = rootHash
+	}
+	countKEVPairVersions := func() (n int) {
+		iter := eng.db.NewIterator(nil, nil)
+		defer iter.Release()
+		for iter.Next() {
+			if iter.Key()[0] == levelDBPrefixKEVPair {
+				n++
+			}
+		}
+		return n
+	}
+	before := countKEVPairVersions()
+
+	require.NoError(t, eng.PruneBefore(ctx, nil, 2))
+	require.Less(t, countKEVPairVersions(), before)
+	// pruning is idempotent
+	after := countKEVPairVersions()
+	require.NoError(t, eng.PruneBefore(ctx, nil, 2))
+	require.Equal(t, after, countKEVPairVersions())
+
+	verifier := NewMerkleProofVerifier(cfg)
+	for s, kvps := range map[Seqno][]KeyValuePair{2: kvps2, 3: kvps3} {
+		for _, kvp := range kvps {
+			kvpRet, proof, err := tree.GetKeyValuePairWithProof(ctx, nil, s, kvp.Key)
+			require.NoError(t, err)
+			require.Equal(t, kvp.Value, kvpRet.Value)
+			require.NoError(t, verifier.VerifyInclusionProof(ctx, kvpRet, &proof, rootHashes[s]))
+		}
+	}
+	t.Logf("the tree at the pruning seqno and later is intact")
+
+	_, err = tree.GetKeyValuePairUnsafe(ctx, nil, 1, kvps1[0].Key)
+	require.Error(t, err)
+	require.IsType(t, InvalidSeqnoError{}, err)
+	_, err = eng.LookupMasterSecrets(ctx, nil, []Seqno{1})
+	require.Error(t, err)
+	t.Logf("older seqnos can't be looked up anymore")
+
+	hashes, err := eng.LookupRootHashes(ctx, nil, []Seqno{1, 2})
+	require.NoError(t, err)
+	require.Equal(t, []Hash{rootHashes[1], rootHashes[2]}, hashes)
+	s, _, err := tree.Build(ctx, nil, kvps1, nil)
+	require.NoError(t, err)
+	require.Equal(t, Seqno(4), s)
+	t.Logf("old roots are kept for extension proofs and skip pointers")
+
+	err = tree.ExecTransaction(ctx, func(ctx logger.ContextInterface, tr Transaction) error {
+		return eng.PruneBefore(ctx, tr, 4)
+	})
+	require.NoError(t, err)
+	_, err = tree.GetKeyValuePairUnsafe(ctx, nil, 3, kvps3[0].Key)
+	require.IsType(t, InvalidSeqnoError{}, err)
+	kvp, err := tree.GetKeyValuePair(ctx, nil, 4, kvps1[0].Key)
+	require.NoError(t, err)
+	require.Equal(t, kvps1[0].Value, kvp.Value)
+}
@@ -474,6 +474,39 @@ type MerkleInclusionProof struct {
 	RootMetadataNoHash  RootMetadata `codec:"e"`
 }
 
+// A MerkleMultiInclusionProof proves, at once, whether each of a set of keys
+// is stored in a merkle tree (and with which value), given the RootMetadata
+// hash of such tree. Nodes on the paths to more than one of the keys are
+// only included once.
+type MerkleMultiInclusionProof struct {
+	_struct struct{} `codec:",toarray"` //nolint
+	// Leaves has one element for each of the leaves the keys are (or would be)
+	// stored at, ordered by the (sorted) keys they contain.
+	Leaves []MerkleMultiProofLeaf `codec:"l"`
+	// SiblingHashes are the hashes of the children of the nodes on the paths
+	// from the root to Leaves which are not on any such path themselves,
+	// ordered by level from the farthest to the closest to the root, then by
+	// their parent's position and by child index.
+	SiblingHashes      []Hash       `codec:"s"`
+	RootMetadataNoHash RootMetadata `codec:"e"`
+}
+
+// MerkleMultiProofLeaf is a leaf in a MerkleMultiInclusionProof.
+type MerkleMultiProofLeaf struct {
+	_struct struct{} `codec:",toarray"` //nolint
+	Level   int      `codec:"v"`
+	// NumKeys is how many of the proven keys are (or would be) stored at this
+	// leaf.
+	NumKeys int `codec:"n"`
+	// KeySpecificSecrets has one element for each of those keys which is in
+	// the tree, ordered by key (only in blinded trees).
+	KeySpecificSecrets []KeySpecificSecret `codec:"k"`
+	// OtherPairsInLeaf are the pairs in the leaf with keys which are not being
+	// proven. Like in a MerkleInclusionProof, it is nil if there is no node at
+	// this leaf's position.
+	OtherPairsInLeaf []KeyHashPair `codec:"o"`
+}
+
 // A MerkleExtensionProof proves, given the RootMetadata hashes of two merkle
 // trees and their respective Seqno values, that: - the two merkle trees have
 // the expected Seqno values, - the most recent merkle tree "points back" to the
@@ -716,6 +749,153 @@ func (t *Tree) GetKeyValuePairWithProof(ctx logger.ContextInterface, tr Transact
 	return kvp, proof, nil
 }
 
+// GetEncodedValuesWithMultiInclusionProof returns the values of all the keys
+// at Seqno s (in the same order as keys, and nil for the ones which are not in
+// the tree), along with a single proof for all of them.
+func (t *Tree) GetEncodedValuesWithMultiInclusionProof(ctx logger.ContextInterface, tr Transaction, s Seqno, keys []Key) (vals []EncodedValue, proof MerkleMultiInclusionProof, err error) {
+	if len(keys) == 0 {
+		return nil, MerkleMultiInclusionProof{}, fmt.Errorf("No keys requested")
+	}
+	sortedKeys := make([]Key, len(keys))
+	copy(sortedKeys, keys)
+	sort.Slice(sortedKeys, func(i, j int) bool { return sortedKeys[i].Cmp(sortedKeys[j]) < 0 })
+	for i := 1; i < len(sortedKeys); i++ {
+		if sortedKeys[i-1].Equal(sortedKeys[i]) {
+			return nil, MerkleMultiInclusionProof{}, fmt.Errorf("Key %X was requested more than once", sortedKeys[i])
+		}
+	}
+
+	rootMetadata, err := t.eng.LookupRoot(ctx, tr, s)
+	if err != nil {
+		return nil, MerkleMultiInclusionProof{}, err
+	}
+	proof.RootMetadataNoHash = rootMetadata
+	// clear up hash to make the proof smaller.
+	proof.RootMetadataNoHash.BareRootHash = nil
+
+	// Build the proof for each key, then merge the leaves the keys share and
+	// keep each sibling hash only once. Since keys are sorted, the ones
+	// stored at the same leaf are next to each other.
+	sortedVals := make([]EncodedValue, len(sortedKeys))
+	keyProofs := make([]MerkleInclusionProof, len(sortedKeys))
+	var leafPositions []Position
+	var leafFirstKeys []int
+	siblingHashes := make(map[string]Hash)
+	for i, k := range sortedKeys {
+		sortedVals[i], keyProofs[i], err = t.getEncodedValueWithInclusionProofOrExclusionProof(ctx, tr, rootMetadata, k)
+		if err != nil {
+			return nil, MerkleMultiInclusionProof{}, err
+		}
+
+		leafLevel := len(keyProofs[i].SiblingHashesOnPath) / (t.cfg.ChildrenPerNode - 1)
+		deepestPosition, err := t.cfg.getDeepestPositionForKey(k)
+		if err != nil {
+			return nil, MerkleMultiInclusionProof{}, err
+		}
+		leafPos := t.cfg.getParentAtLevel(deepestPosition, uint(leafLevel))
+		if len(leafPositions) > 0 && leafPositions[len(leafPositions)-1].Equals(leafPos) {
+			proof.Leaves[len(proof.Leaves)-1].NumKeys++
+			continue
+		}
+		leafPositions = append(leafPositions, *leafPos)
+		leafFirstKeys = append(leafFirstKeys, i)
+		proof.Leaves = append(proof.Leaves, MerkleMultiProofLeaf{Level: leafLevel, NumKeys: 1})
+
+		// Index the sibling hashes in this key's proof by their position.
+		pathPos := leafPos
+		for level := leafLevel; level > 0; level-- {
+			parent := t.cfg.getParent(pathPos)
+			pathIndex := t.cfg.getDeepestChildIndex(pathPos)
+			for c := ChildIndex(0); int(c) < t.cfg.ChildrenPerNode; c++ {
+				if c == pathIndex {
+					continue
+				}
+				j := (leafLevel-level)*(t.cfg.ChildrenPerNode-1) + int(c)
+				if c > pathIndex {
+					j--
+				}
+				siblingHashes[t.cfg.GetChild(parent, c).AsString()] = keyProofs[i].SiblingHashesOnPath[j]
+			}
+			pathPos = parent
+		}
+	}
+
+	for i := range proof.Leaves {
+		leaf := &proof.Leaves[i]
+		first := leafFirstKeys[i]
+		leafKeys := sortedKeys[first : first+leaf.NumKeys]
+		isLeafKey := func(k Key) bool {
+			j := sort.Search(len(leafKeys), func(j int) bool { return leafKeys[j].Cmp(k) >= 0 })
+			return j < len(leafKeys) && leafKeys[j].Equal(k)
+		}
+		// The proofs for all the keys in this leaf list the same pairs, save
+		// for the one with the key they are about.
+		if others := keyProofs[first].OtherPairsInLeaf; others != nil {
+			leaf.OtherPairsInLeaf = make([]KeyHashPair, 0, len(others))
+			for _, khp := range others {
+				if !isLeafKey(khp.Key) {
+					leaf.OtherPairsInLeaf = append(leaf.OtherPairsInLeaf, khp)
+				}
+			}
+		}
+		if t.cfg.UseBlindedValueHashes {
+			for j := first; j < first+leaf.NumKeys; j++ {
+				if sortedVals[j] != nil {
+					leaf.KeySpecificSecrets = append(leaf.KeySpecificSecrets, keyProofs[j].KeySpecificSecret)
+				}
+			}
+		}
+	}
+
+	_, siblingPositions, err := t.cfg.getMultiProofPositions(leafPositions)
+	if err != nil {
+		return nil, MerkleMultiInclusionProof{}, err
+	}
+	proof.SiblingHashes = make([]Hash, len(siblingPositions))
+	for i, p := range siblingPositions {
+		h, found := siblingHashes[p.AsString()]
+		if !found {
+			return nil, MerkleMultiInclusionProof{}, fmt.Errorf("Missing the hash of the sibling node at position %x", p.GetBytes())
+		}
+		proof.SiblingHashes[i] = h
+	}
+
+	valsByKey := make(map[string]EncodedValue, len(sortedKeys))
+	for i, k := range sortedKeys {
+		valsByKey[string(k)] = sortedVals[i]
+	}
+	vals = make([]EncodedValue, len(keys))
+	for i, k := range keys {
+		vals[i] = valsByKey[string(k)]
+	}
+	return vals, proof, nil
+}
+
+// GetKeyValuePairsWithMultiInclusionProof is like
+// GetEncodedValuesWithMultiInclusionProof, but decodes the values. Keys which
+// are not in the tree are returned with a nil Value.
+func (t *Tree) GetKeyValuePairsWithMultiInclusionProof(ctx logger.ContextInterface, tr Transaction, s Seqno, keys []Key) (kvps []KeyValuePair, proof MerkleMultiInclusionProof, err error) {
+	vals, proof, err := t.GetEncodedValuesWithMultiInclusionProof(ctx, tr, s, keys)
+	if err != nil {
+		return nil, MerkleMultiInclusionProof{}, err
+	}
+
+	kvps = make([]KeyValuePair, len(keys))
+	for i, k := range keys {
+		kvps[i].Key = k
+		if vals[i] == nil {
+			continue
+		}
+		valContainer := t.cfg.ConstructValueContainer()
+		err = t.cfg.Encoder.Decode(&valContainer, vals[i])
+		if err != nil {
+			return nil, MerkleMultiInclusionProof{}, err
+		}
+		kvps[i].Value = valContainer
+	}
+	return kvps, proof, nil
+}
+
 func (t *Tree) getExtensionProof(ctx logger.ContextInterface, tr Transaction, fromSeqno, toSeqno Seqno, isPartOfIncExtProof bool) (proof MerkleExtensionProof, err error) {
 	// Optimization: no proof is required to show something extends itself.
 	if fromSeqno == toSeqno {
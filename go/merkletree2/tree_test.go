@@ -36,7 +36,7 @@ func TestEmptyTree(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
-			tree, err := NewTree(test.cfg, defaultStep, NewInMemoryStorageEngine(test.cfg), RootVersionV1)
+			tree, err := NewTree(test.cfg, defaultStep, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
 			require.NoError(t, err)
 
 			seq, root, hash, err := tree.GetLatestRoot(NewLoggerContextTodoForTesting(t), nil)
@@ -93,7 +93,7 @@ func TestBuildTreeAndGetKeyValuePair(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
-			tree, err := NewTree(test.cfg, defaultStep, NewInMemoryStorageEngine(test.cfg), RootVersionV1)
+			tree, err := NewTree(test.cfg, defaultStep, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
 			require.NoError(t, err)
 
 			// This kvp has a key which is not part of test.kvps1
@@ -219,7 +219,7 @@ func TestBuildTreeAndGetKeyValuePairWithProof(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
-			tree, err := NewTree(test.cfg, defaultStep, NewInMemoryStorageEngine(test.cfg), RootVersionV1)
+			tree, err := NewTree(test.cfg, defaultStep, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
 			require.NoError(t, err)
 
 			// This kvp has a key which is not part of test.kvps1
@@ -311,7 +311,7 @@ func TestHonestMerkleProofsVerifySuccesfully(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
-			tree, err := NewTree(test.cfg, defaultStep, NewInMemoryStorageEngine(test.cfg), RootVersionV1)
+			tree, err := NewTree(test.cfg, defaultStep, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
 			require.NoError(t, err)
 			verifier := MerkleProofVerifier{cfg: test.cfg}
 
@@ -408,7 +408,7 @@ func TestHonestMerkleProofsVerifySuccesfullyLargeTree(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
-			tree, err := NewTree(test.cfg, test.step, NewInMemoryStorageEngine(test.cfg), test.rootVersion)
+			tree, err := NewTree(test.cfg, test.step, newStorageEngineForTesting(t, test.cfg), test.rootVersion)
 			require.NoError(t, err)
 			verifier := MerkleProofVerifier{cfg: test.cfg}
 
@@ -500,7 +500,7 @@ func TestSomeMaliciousInclusionProofsFail(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
-			tree, err := NewTree(test.cfg, defaultStep, NewInMemoryStorageEngine(test.cfg), RootVersionV1)
+			tree, err := NewTree(test.cfg, defaultStep, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
 			require.NoError(t, err)
 			verifier := MerkleProofVerifier{cfg: test.cfg}
 
@@ -593,7 +593,7 @@ func TestSomeMaliciousExclusionProofsFail(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
-			tree, err := NewTree(test.cfg, defaultStep, NewInMemoryStorageEngine(test.cfg), RootVersionV1)
+			tree, err := NewTree(test.cfg, defaultStep, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
 			require.NoError(t, err)
 			verifier := MerkleProofVerifier{cfg: test.cfg}
 
@@ -671,7 +671,7 @@ func TestExclProofOnEmptyTree(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
-			tree, err := NewTree(test.cfg, defaultStep, NewInMemoryStorageEngine(test.cfg), RootVersionV1)
+			tree, err := NewTree(test.cfg, defaultStep, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
 			require.NoError(t, err)
 			verifier := MerkleProofVerifier{cfg: test.cfg}
 
@@ -702,7 +702,7 @@ func TestVerifyInclusionProofFailureBranches(t *testing.T) {
 		{Key: []byte{0x01, 0x12}, Value: "key0x0112Seqno1"},
 	}
 
-	tree, err := NewTree(cfg, defaultStep, NewInMemoryStorageEngine(cfg), RootVersionV1)
+	tree, err := NewTree(cfg, defaultStep, newStorageEngineForTesting(t, cfg), RootVersionV1)
 	require.NoError(t, err)
 	verifier := NewMerkleProofVerifier(cfg)
 
@@ -781,7 +781,7 @@ func TestTreeWithoutInternalNodes(t *testing.T) {
 
 			cfg, err := NewConfig(IdentityHasherBlinded{}, true, 2, 4, 2, ConstructStringValueContainer)
 			require.NoError(t, err)
-			tree, err := NewTree(cfg, test.step, NewInMemoryStorageEngine(cfg), RootVersionV1)
+			tree, err := NewTree(cfg, test.step, newStorageEngineForTesting(t, cfg), RootVersionV1)
 			require.NoError(t, err)
 			verifier := NewMerkleProofVerifier(cfg)
 
@@ -849,7 +849,7 @@ func TestGetLatestRoot(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
-			tree, err := NewTree(test.cfg, defaultStep, NewInMemoryStorageEngine(test.cfg), RootVersionV1)
+			tree, err := NewTree(test.cfg, defaultStep, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
 			require.NoError(t, err)
 
 			s1, rootHash1Exp, err := tree.Build(NewLoggerContextTodoForTesting(t), nil, test.kvps1, nil)
@@ -923,7 +923,7 @@ func TestInclusionExtensionProofsPass(t *testing.T) {
 	// make test deterministic
 	rand.Seed(1)
 
-	tree, err := NewTree(cfg, 2, NewInMemoryStorageEngine(cfg), RootVersionV1)
+	tree, err := NewTree(cfg, 2, newStorageEngineForTesting(t, cfg), RootVersionV1)
 	require.NoError(t, err)
 
 	keys, _, err := makeRandomKeysForTesting(uint(cfg.KeysByteLength), 5, 0)
@@ -1016,7 +1016,7 @@ func TestExtensionProofsFailureBranches(t *testing.T) {
 	// make test deterministic
 	rand.Seed(1)
 
-	tree, err := NewTree(cfg, 2, NewInMemoryStorageEngine(cfg), RootVersionV1)
+	tree, err := NewTree(cfg, 2, newStorageEngineForTesting(t, cfg), RootVersionV1)
 	require.NoError(t, err)
 
 	keys, _, err := makeRandomKeysForTesting(uint(cfg.KeysByteLength), 5, 0)
@@ -1132,7 +1132,7 @@ func TestInclusionExtensionProofsFailureBranches(t *testing.T) {
 	// make test deterministic
 	rand.Seed(1)
 
-	tree, err := NewTree(cfg, 2, NewInMemoryStorageEngine(cfg), RootVersionV1)
+	tree, err := NewTree(cfg, 2, newStorageEngineForTesting(t, cfg), RootVersionV1)
 	require.NoError(t, err)
 
 	keys, _, err := makeRandomKeysForTesting(uint(cfg.KeysByteLength), 5, 0)
@@ -1245,6 +1245,183 @@ func TestInclusionExtensionProofsFailureBranches(t *testing.T) {
 
 }
 
+func TestMultiInclusionProofs(t *testing.T) {
+	blindedBinaryTreeConfig, err := NewConfig(NewBlindedSHA512_256v1Encoder(), true, 1, 1, 32, ConstructStringValueContainer)
+	require.NoError(t, err)
+	unblindedBinaryTreeConfig, err := NewConfig(SHA512_256Encoder{}, false, 1, 1, 32, ConstructStringValueContainer)
+	require.NoError(t, err)
+	blinded16aryTreeConfig, err := NewConfig(NewBlindedSHA512_256v1Encoder(), true, 4, 4, 32, ConstructStringValueContainer)
+	require.NoError(t, err)
+	blinded16aryShallowTreeConfig, err := NewConfig(NewBlindedSHA512_256v1Encoder(), true, 4, 4, 2, ConstructStringValueContainer)
+	require.NoError(t, err)
+
+	// Make test deterministic.
+	rand.Seed(1)
+
+	tests := []struct {
+		cfg         Config
+		numIncPairs int
+		numExcPairs int
+	}{
+		{blindedBinaryTreeConfig, 300, 100},
+		{unblindedBinaryTreeConfig, 300, 100},
+		{blinded16aryTreeConfig, 300, 100},
+		{blinded16aryShallowTreeConfig, 300, 100},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
+			ctx := NewLoggerContextTodoForTesting(t)
+			tree, err := NewTree(test.cfg, 2, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
+			require.NoError(t, err)
+			verifier := NewMerkleProofVerifier(test.cfg)
+
+			keys, keysNotInTree, err := makeRandomKeysForTesting(uint(test.cfg.KeysByteLength), test.numIncPairs, test.numExcPairs)
+			require.NoError(t, err)
+			kvps, err := makeRandomKVPFromKeysForTesting(keys)
+			require.NoError(t, err)
+			_, rootHash, err := tree.Build(ctx, nil, kvps, nil)
+			require.NoError(t, err)
+
+			for _, numKeys := range []int{1, 2, 10, 100, 400} {
+				// a random mix of keys in the tree and not, in random order
+				var expected []KeyValuePair
+				for _, i := range rand.Perm(len(keys) + len(keysNotInTree))[:numKeys] {
+					if i < len(keys) {
+						expected = append(expected, kvps[i])
+					} else {
+						expected = append(expected, KeyValuePair{Key: keysNotInTree[i-len(keys)]})
+					}
+				}
+				requested := make([]Key, len(expected))
+				for i, kvp := range expected {
+					requested[i] = kvp.Key
+				}
+
+				kvpsRet, proof, err := tree.GetKeyValuePairsWithMultiInclusionProof(ctx, nil, 1, requested)
+				require.NoError(t, err)
+				require.Equal(t, expected, kvpsRet)
+				require.NoError(t, verifier.VerifyMultiInclusionProof(ctx, kvpsRet, &proof, rootHash))
+
+				// The proof survives encoding.
+				enc, err := msgpack.EncodeCanonical(proof)
+				require.NoError(t, err)
+				var decoded MerkleMultiInclusionProof
+				require.NoError(t, msgpack.Decode(&decoded, enc))
+				require.NoError(t, verifier.VerifyMultiInclusionProof(ctx, kvpsRet, &decoded, rootHash))
+
+				// It's smaller than the separate proofs for each key.
+				separateSize := 0
+				for _, k := range requested {
+					_, singleProof, err := tree.GetEncodedValueWithInclusionOrExclusionProofFromRootHash(ctx, nil, rootHash, k)
+					require.NoError(t, err)
+					singleEnc, err := msgpack.EncodeCanonical(singleProof)
+					require.NoError(t, err)
+					separateSize += len(singleEnc)
+				}
+				if numKeys > 1 {
+					require.Less(t, len(enc), separateSize)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiInclusionProofsOnSmallTrees(t *testing.T) {
+	config1bitU, config2bitsU, config3bitsU := getTreeCfgsWith1_2_3BitsPerIndexUnblinded(t)
+	config1bitB, config2bitsB, config3bitsB := getTreeCfgsWith1_2_3BitsPerIndexBlinded(t)
+	config3bits2valsPerLeafB, err := NewConfig(IdentityHasherBlinded{}, true, 3, 2, 3, ConstructStringValueContainer)
+	require.NoError(t, err)
+	kvps1_1bit, _, _ := getSampleKVPS1bit()
+	kvps1_3bits, _, _ := getSampleKVPS3bits()
+
+	tests := []struct {
+		cfg      Config
+		kvps     []KeyValuePair
+		extraKey Key
+	}{
+		{config1bitU, kvps1_1bit, []byte{0xaa}},
+		{config2bitsU, kvps1_1bit, []byte{0xaa}},
+		{config3bitsU, kvps1_3bits, []byte{0xaa, 0xaa, 0xaa}},
+		{config1bitB, kvps1_1bit, []byte{0xaa}},
+		{config2bitsB, kvps1_1bit, []byte{0xaa}},
+		{config3bitsB, kvps1_3bits, []byte{0xaa, 0xaa, 0xaa}},
+		{config3bits2valsPerLeafB, kvps1_3bits, []byte{0xaa, 0xaa, 0xaa}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v bits %v values per leaf tree (blinded %v)", test.cfg.BitsPerIndex, test.cfg.MaxValuesPerLeaf, test.cfg.UseBlindedValueHashes), func(t *testing.T) {
+			ctx := NewLoggerContextTodoForTesting(t)
+			tree, err := NewTree(test.cfg, 2, newStorageEngineForTesting(t, test.cfg), RootVersionV1)
+			require.NoError(t, err)
+			verifier := NewMerkleProofVerifier(test.cfg)
+
+			// On the empty tree, every key is absent.
+			_, emptyRootHash, err := tree.Build(ctx, nil, nil, nil)
+			require.NoError(t, err)
+			absent := []KeyValuePair{{Key: test.kvps[0].Key}, {Key: test.extraKey}}
+			kvpsRet, proof, err := tree.GetKeyValuePairsWithMultiInclusionProof(ctx, nil, 1, []Key{test.kvps[0].Key, test.extraKey})
+			require.NoError(t, err)
+			require.Equal(t, absent, kvpsRet)
+			require.NoError(t, verifier.VerifyMultiInclusionProof(ctx, absent, &proof, emptyRootHash))
+
+			_, rootHash, err := tree.Build(ctx, nil, test.kvps, nil)
+			require.NoError(t, err)
+			expected := append([]KeyValuePair{{Key: test.extraKey}}, test.kvps...)
+			requested := make([]Key, len(expected))
+			for i, kvp := range expected {
+				requested[i] = kvp.Key
+			}
+			kvpsRet, proof, err = tree.GetKeyValuePairsWithMultiInclusionProof(ctx, nil, 2, requested)
+			require.NoError(t, err)
+			require.Equal(t, expected, kvpsRet)
+			require.NoError(t, verifier.VerifyMultiInclusionProof(ctx, expected, &proof, rootHash))
+
+			// Wrong values, or keys claimed to be (or not to be) in the tree
+			// when they aren't (or are).
+			fake := append([]KeyValuePair{}, expected...)
+			fake[1].Value = "wrong value"
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, fake, &proof, rootHash))
+			fake = append([]KeyValuePair{}, expected...)
+			fake[1].Value = nil
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, fake, &proof, rootHash))
+			fake = append([]KeyValuePair{}, expected...)
+			fake[0].Value = "not in the tree"
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, fake, &proof, rootHash))
+
+			// Keys missing from (or added to) what the proof covers.
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, expected[1:], &proof, rootHash))
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, append(expected, expected[1]), &proof, rootHash))
+
+			// A proof for the absent key alone can't be passed off as one for
+			// a key in the tree.
+			_, excProof, err := tree.GetKeyValuePairsWithMultiInclusionProof(ctx, nil, 2, []Key{test.extraKey})
+			require.NoError(t, err)
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, []KeyValuePair{{Key: test.kvps[0].Key}}, &excProof, rootHash))
+
+			// Tampered proofs, and the wrong root hash.
+			fakeProof := proof
+			fakeProof.Leaves = append([]MerkleMultiProofLeaf{}, proof.Leaves...)
+			fakeProof.Leaves[0].Level++
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, expected, &fakeProof, rootHash))
+			if len(proof.SiblingHashes) > 0 {
+				fakeProof = proof
+				fakeProof.SiblingHashes = append([]Hash{}, proof.SiblingHashes...)
+				fakeProof.SiblingHashes[0] = Hash("fake hash")
+				require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, expected, &fakeProof, rootHash))
+			}
+			rootHashFake := Hash(append([]byte(nil), rootHash...))
+			rootHashFake[0]++
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, expected, &proof, rootHashFake))
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, expected, &MerkleMultiInclusionProof{}, rootHash))
+			require.IsType(t, ProofVerificationFailedError{}, verifier.VerifyMultiInclusionProof(ctx, expected, nil, rootHash))
+
+			_, _, err = tree.GetKeyValuePairsWithMultiInclusionProof(ctx, nil, 2, []Key{test.extraKey, test.extraKey})
+			require.Error(t, err)
+		})
+	}
+}
+
 func NewLoggerContextTodoForTesting(t *testing.T) logger.ContextInterface {
 	return logger.NewContext(context.TODO(), logger.NewTestLogger(t))
 }
@@ -3,6 +3,7 @@ package merkletree2
 import (
 	"crypto/sha512"
 	"errors"
+	"flag"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -15,6 +16,29 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+var testEngine = flag.String("engine", "memory", "storage engine to run the tree tests against (memory or leveldb)")
+
+// newStorageEngineForTesting returns the StorageEngine selected with the
+// -engine flag, so that the tree tests can be run against any of them.
+func newStorageEngineForTesting(t *testing.T, cfg Config) StorageEngineWithBlinding {
+	switch *testEngine {
+	case "memory":
+		return NewInMemoryStorageEngine(cfg)
+	case "leveldb":
+		return newLevelDBStorageEngineForTesting(t, cfg)
+	default:
+		t.Fatalf("unknown storage engine %q", *testEngine)
+		return nil
+	}
+}
+
+func newLevelDBStorageEngineForTesting(t *testing.T, cfg Config) *LevelDBStorageEngine {
+	eng, err := NewLevelDBStorageEngine(cfg, t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, eng.Close()) })
+	return eng
+}
+
 func makePositionFromStringForTesting(s string) (Position, error) {
 	posInt, err := strconv.ParseInt(s, 2, 64)
 	if err != nil {
@@ -857,6 +857,44 @@ func (o ListCommandsRes) DeepCopy() ListCommandsRes {
 	}
 }
 
+type ScheduledMsgSummary struct {
+	OutboxID OutboxID  `codec:"outboxID" json:"outbox_id"`
+	ConvID   ConvIDStr `codec:"convID" json:"conversation_id"`
+	SendAt   int64     `codec:"sendAt" json:"send_at"`
+	SendAtMs int64     `codec:"sendAtMs" json:"send_at_ms"`
+	Body     string    `codec:"body" json:"body"`
+}
+
+func (o ScheduledMsgSummary) DeepCopy() ScheduledMsgSummary {
+	return ScheduledMsgSummary{
+		OutboxID: o.OutboxID.DeepCopy(),
+		ConvID:   o.ConvID.DeepCopy(),
+		SendAt:   o.SendAt,
+		SendAtMs: o.SendAtMs,
+		Body:     o.Body,
+	}
+}
+
+type ListScheduledRes struct {
+	Messages []ScheduledMsgSummary `codec:"messages" json:"messages"`
+}
+
+func (o ListScheduledRes) DeepCopy() ListScheduledRes {
+	return ListScheduledRes{
+		Messages: (func(x []ScheduledMsgSummary) []ScheduledMsgSummary {
+			if x == nil {
+				return nil
+			}
+			ret := make([]ScheduledMsgSummary, len(x))
+			for i, v := range x {
+				vCopy := v.DeepCopy()
+				ret[i] = vCopy
+			}
+			return ret
+		})(o.Messages),
+	}
+}
+
 type EmptyRes struct {
 	RateLimits []RateLimitRes `codec:"rateLimits,omitempty" json:"ratelimits,omitempty"`
 }
@@ -2088,6 +2088,11 @@ type SearchOpts struct {
 	MatchMentions     bool            `codec:"matchMentions" json:"matchMentions"`
 	SentBefore        gregor1.Time    `codec:"sentBefore" json:"sentBefore"`
 	SentAfter         gregor1.Time    `codec:"sentAfter" json:"sentAfter"`
+	SentIn            string          `codec:"sentIn" json:"sentIn"`
+	HasAttachment     bool            `codec:"hasAttachment" json:"hasAttachment"`
+	HasLink           bool            `codec:"hasLink" json:"hasLink"`
+	IsPinned          bool            `codec:"isPinned" json:"isPinned"`
+	ExcludeTerms      []string        `codec:"excludeTerms" json:"excludeTerms"`
 	MaxHits           int             `codec:"maxHits" json:"maxHits"`
 	MaxMessages       int             `codec:"maxMessages" json:"maxMessages"`
 	BeforeContext     int             `codec:"beforeContext" json:"beforeContext"`
@@ -2111,6 +2116,21 @@ func (o SearchOpts) DeepCopy() SearchOpts {
 		MatchMentions: o.MatchMentions,
 		SentBefore:    o.SentBefore.DeepCopy(),
 		SentAfter:     o.SentAfter.DeepCopy(),
+		SentIn:        o.SentIn,
+		HasAttachment: o.HasAttachment,
+		HasLink:       o.HasLink,
+		IsPinned:      o.IsPinned,
+		ExcludeTerms: (func(x []string) []string {
+			if x == nil {
+				return nil
+			}
+			ret := make([]string, len(x))
+			for i, v := range x {
+				vCopy := v
+				ret[i] = vCopy
+			}
+			return ret
+		})(o.ExcludeTerms),
 		MaxHits:       o.MaxHits,
 		MaxMessages:   o.MaxMessages,
 		BeforeContext: o.BeforeContext,
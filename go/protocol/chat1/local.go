@@ -5130,6 +5130,26 @@ func (o MakePreviewRes) DeepCopy() MakePreviewRes {
 	}
 }
 
+type ScheduledMessage struct {
+	OutboxID         OutboxID                     `codec:"outboxID" json:"outboxID"`
+	ConvID           ConversationID               `codec:"convID" json:"convID"`
+	SendAt           gregor1.Time                 `codec:"sendAt" json:"sendAt"`
+	Ctime            gregor1.Time                 `codec:"ctime" json:"ctime"`
+	Msg              MessagePlaintext             `codec:"msg" json:"msg"`
+	IdentifyBehavior keybase1.TLFIdentifyBehavior `codec:"identifyBehavior" json:"identifyBehavior"`
+}
+
+func (o ScheduledMessage) DeepCopy() ScheduledMessage {
+	return ScheduledMessage{
+		OutboxID:         o.OutboxID.DeepCopy(),
+		ConvID:           o.ConvID.DeepCopy(),
+		SendAt:           o.SendAt.DeepCopy(),
+		Ctime:            o.Ctime.DeepCopy(),
+		Msg:              o.Msg.DeepCopy(),
+		IdentifyBehavior: o.IdentifyBehavior.DeepCopy(),
+	}
+}
+
 type MarkAsReadLocalRes struct {
 	Offline    bool        `codec:"offline" json:"offline"`
 	RateLimits []RateLimit `codec:"rateLimits" json:"rateLimits"`
@@ -6861,6 +6881,20 @@ type RetryPostArg struct {
 	IdentifyBehavior *keybase1.TLFIdentifyBehavior `codec:"identifyBehavior,omitempty" json:"identifyBehavior,omitempty"`
 }
 
+type ScheduleLocalNonblockArg struct {
+	ConversationID   ConversationID               `codec:"conversationID" json:"conversationID"`
+	Msg              MessagePlaintext             `codec:"msg" json:"msg"`
+	SendAt           gregor1.Time                 `codec:"sendAt" json:"sendAt"`
+	IdentifyBehavior keybase1.TLFIdentifyBehavior `codec:"identifyBehavior" json:"identifyBehavior"`
+}
+
+type GetScheduledMessagesLocalArg struct {
+}
+
+type CancelScheduledMessageLocalArg struct {
+	OutboxID OutboxID `codec:"outboxID" json:"outboxID"`
+}
+
 type MarkAsReadLocalArg struct {
 	SessionID      int            `codec:"sessionID" json:"sessionID"`
 	ConversationID ConversationID `codec:"conversationID" json:"conversationID"`
@@ -7303,6 +7337,9 @@ type LocalInterface interface {
 	CancelUploadTempFile(context.Context, OutboxID) error
 	CancelPost(context.Context, OutboxID) error
 	RetryPost(context.Context, RetryPostArg) error
+	ScheduleLocalNonblock(context.Context, ScheduleLocalNonblockArg) (ScheduledMessage, error)
+	GetScheduledMessagesLocal(context.Context) ([]ScheduledMessage, error)
+	CancelScheduledMessageLocal(context.Context, OutboxID) error
 	MarkAsReadLocal(context.Context, MarkAsReadLocalArg) (MarkAsReadLocalRes, error)
 	MarkTLFAsReadLocal(context.Context, MarkTLFAsReadLocalArg) (MarkTLFAsReadLocalRes, error)
 	FindConversationsLocal(context.Context, FindConversationsLocalArg) (FindConversationsLocalRes, error)
@@ -8048,6 +8085,46 @@ func LocalProtocol(i LocalInterface) rpc.Protocol {
 					return
 				},
 			},
+			"scheduleLocalNonblock": {
+				MakeArg: func() interface{} {
+					var ret [1]ScheduleLocalNonblockArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]ScheduleLocalNonblockArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]ScheduleLocalNonblockArg)(nil), args)
+						return
+					}
+					ret, err = i.ScheduleLocalNonblock(ctx, typedArgs[0])
+					return
+				},
+			},
+			"getScheduledMessagesLocal": {
+				MakeArg: func() interface{} {
+					var ret [1]GetScheduledMessagesLocalArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					ret, err = i.GetScheduledMessagesLocal(ctx)
+					return
+				},
+			},
+			"cancelScheduledMessageLocal": {
+				MakeArg: func() interface{} {
+					var ret [1]CancelScheduledMessageLocalArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]CancelScheduledMessageLocalArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]CancelScheduledMessageLocalArg)(nil), args)
+						return
+					}
+					err = i.CancelScheduledMessageLocal(ctx, typedArgs[0].OutboxID)
+					return
+				},
+			},
 			"markAsReadLocal": {
 				MakeArg: func() interface{} {
 					var ret [1]MarkAsReadLocalArg
@@ -9437,6 +9514,22 @@ func (c LocalClient) RetryPost(ctx context.Context, __arg RetryPostArg) (err err
 	return
 }
 
+func (c LocalClient) ScheduleLocalNonblock(ctx context.Context, __arg ScheduleLocalNonblockArg) (res ScheduledMessage, err error) {
+	err = c.Cli.Call(ctx, "chat.1.local.scheduleLocalNonblock", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
+
+func (c LocalClient) GetScheduledMessagesLocal(ctx context.Context) (res []ScheduledMessage, err error) {
+	err = c.Cli.Call(ctx, "chat.1.local.getScheduledMessagesLocal", []interface{}{GetScheduledMessagesLocalArg{}}, &res, 0*time.Millisecond)
+	return
+}
+
+func (c LocalClient) CancelScheduledMessageLocal(ctx context.Context, outboxID OutboxID) (err error) {
+	__arg := CancelScheduledMessageLocalArg{OutboxID: outboxID}
+	err = c.Cli.Call(ctx, "chat.1.local.cancelScheduledMessageLocal", []interface{}{__arg}, nil, 0*time.Millisecond)
+	return
+}
+
 func (c LocalClient) MarkAsReadLocal(ctx context.Context, __arg MarkAsReadLocalArg) (res MarkAsReadLocalRes, err error) {
 	err = c.Cli.Call(ctx, "chat.1.local.markAsReadLocal", []interface{}{__arg}, &res, 0*time.Millisecond)
 	return
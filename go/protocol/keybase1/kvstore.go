@@ -10,11 +10,13 @@ import (
 )
 
 type KVGetResult struct {
-	TeamName   string  `codec:"teamName" json:"teamName"`
-	Namespace  string  `codec:"namespace" json:"namespace"`
-	EntryKey   string  `codec:"entryKey" json:"entryKey"`
-	EntryValue *string `codec:"entryValue" json:"entryValue"`
-	Revision   int     `codec:"revision" json:"revision"`
+	TeamName   string       `codec:"teamName" json:"teamName"`
+	Namespace  string       `codec:"namespace" json:"namespace"`
+	EntryKey   string       `codec:"entryKey" json:"entryKey"`
+	EntryValue *string      `codec:"entryValue" json:"entryValue"`
+	Revision   int          `codec:"revision" json:"revision"`
+	Stale      bool         `codec:"stale" json:"stale"`
+	Writer     *UserVersion `codec:"writer,omitempty" json:"writer,omitempty"`
 }
 
 func (o KVGetResult) DeepCopy() KVGetResult {
@@ -30,6 +32,14 @@ func (o KVGetResult) DeepCopy() KVGetResult {
 			return &tmp
 		})(o.EntryValue),
 		Revision: o.Revision,
+		Stale:    o.Stale,
+		Writer: (func(x *UserVersion) *UserVersion {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x).DeepCopy()
+			return &tmp
+		})(o.Writer),
 	}
 }
 
@@ -161,11 +171,72 @@ func (o KVDeleteEntryResult) DeepCopy() KVDeleteEntryResult {
 	}
 }
 
-type GetKVEntryArg struct {
-	SessionID int    `codec:"sessionID" json:"sessionID"`
-	TeamName  string `codec:"teamName" json:"teamName"`
+type KVBatchEntry struct {
+	Namespace  string  `codec:"namespace" json:"namespace"`
+	EntryKey   string  `codec:"entryKey" json:"entryKey"`
+	Revision   int     `codec:"revision" json:"revision"`
+	EntryValue *string `codec:"entryValue" json:"entryValue"`
+}
+
+func (o KVBatchEntry) DeepCopy() KVBatchEntry {
+	return KVBatchEntry{
+		Namespace: o.Namespace,
+		EntryKey:  o.EntryKey,
+		Revision:  o.Revision,
+		EntryValue: (func(x *string) *string {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x)
+			return &tmp
+		})(o.EntryValue),
+	}
+}
+
+type KVBatchEntryResult struct {
 	Namespace string `codec:"namespace" json:"namespace"`
 	EntryKey  string `codec:"entryKey" json:"entryKey"`
+	Revision  int    `codec:"revision" json:"revision"`
+	Deleted   bool   `codec:"deleted" json:"deleted"`
+}
+
+func (o KVBatchEntryResult) DeepCopy() KVBatchEntryResult {
+	return KVBatchEntryResult{
+		Namespace: o.Namespace,
+		EntryKey:  o.EntryKey,
+		Revision:  o.Revision,
+		Deleted:   o.Deleted,
+	}
+}
+
+type KVBatchResult struct {
+	TeamName string               `codec:"teamName" json:"teamName"`
+	Results  []KVBatchEntryResult `codec:"results" json:"results"`
+}
+
+func (o KVBatchResult) DeepCopy() KVBatchResult {
+	return KVBatchResult{
+		TeamName: o.TeamName,
+		Results: (func(x []KVBatchEntryResult) []KVBatchEntryResult {
+			if x == nil {
+				return nil
+			}
+			ret := make([]KVBatchEntryResult, len(x))
+			for i, v := range x {
+				vCopy := v.DeepCopy()
+				ret[i] = vCopy
+			}
+			return ret
+		})(o.Results),
+	}
+}
+
+type GetKVEntryArg struct {
+	SessionID int                 `codec:"sessionID" json:"sessionID"`
+	TeamName  string              `codec:"teamName" json:"teamName"`
+	Namespace string              `codec:"namespace" json:"namespace"`
+	EntryKey  string              `codec:"entryKey" json:"entryKey"`
+	Oa        OfflineAvailability `codec:"oa" json:"oa"`
 }
 
 type PutKVEntryArg struct {
@@ -196,12 +267,21 @@ type DelKVEntryArg struct {
 	Revision  int    `codec:"revision" json:"revision"`
 }
 
+type BatchKVEntriesArg struct {
+	SessionID int            `codec:"sessionID" json:"sessionID"`
+	TeamName  string         `codec:"teamName" json:"teamName"`
+	Entries   []KVBatchEntry `codec:"entries" json:"entries"`
+}
+
 type KvstoreInterface interface {
 	GetKVEntry(context.Context, GetKVEntryArg) (KVGetResult, error)
 	PutKVEntry(context.Context, PutKVEntryArg) (KVPutResult, error)
 	ListKVNamespaces(context.Context, ListKVNamespacesArg) (KVListNamespaceResult, error)
 	ListKVEntries(context.Context, ListKVEntriesArg) (KVListEntryResult, error)
 	DelKVEntry(context.Context, DelKVEntryArg) (KVDeleteEntryResult, error)
+	// batchKVEntries puts and deletes several entries in a single team atomically. Either
+	// every entry in the batch is written at its expected revision, or none of them are.
+	BatchKVEntries(context.Context, BatchKVEntriesArg) (KVBatchResult, error)
 }
 
 func KvstoreProtocol(i KvstoreInterface) rpc.Protocol {
@@ -283,6 +363,21 @@ func KvstoreProtocol(i KvstoreInterface) rpc.Protocol {
 					return
 				},
 			},
+			"batchKVEntries": {
+				MakeArg: func() interface{} {
+					var ret [1]BatchKVEntriesArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]BatchKVEntriesArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]BatchKVEntriesArg)(nil), args)
+						return
+					}
+					ret, err = i.BatchKVEntries(ctx, typedArgs[0])
+					return
+				},
+			},
 		},
 	}
 }
@@ -315,3 +410,10 @@ func (c KvstoreClient) DelKVEntry(ctx context.Context, __arg DelKVEntryArg) (res
 	err = c.Cli.Call(ctx, "keybase.1.kvstore.delKVEntry", []interface{}{__arg}, &res, 0*time.Millisecond)
 	return
 }
+
+// batchKVEntries puts and deletes several entries in a single team atomically. Either
+// every entry in the batch is written at its expected revision, or none of them are.
+func (c KvstoreClient) BatchKVEntries(ctx context.Context, __arg BatchKVEntriesArg) (res KVBatchResult, err error) {
+	err = c.Cli.Call(ctx, "keybase.1.kvstore.batchKVEntries", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
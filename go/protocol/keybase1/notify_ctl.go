@@ -41,6 +41,7 @@ type NotificationChannels struct {
 	Runtimestats         bool `codec:"runtimestats" json:"runtimestats"`
 	FeaturedBots         bool `codec:"featuredBots" json:"featuredBots"`
 	Saltpack             bool `codec:"saltpack" json:"saltpack"`
+	Kvstore              bool `codec:"kvstore" json:"kvstore"`
 	AllowChatNotifySkips bool `codec:"allowChatNotifySkips" json:"allowChatNotifySkips"`
 }
 
@@ -77,6 +78,7 @@ func (o NotificationChannels) DeepCopy() NotificationChannels {
 		Runtimestats:         o.Runtimestats,
 		FeaturedBots:         o.FeaturedBots,
 		Saltpack:             o.Saltpack,
+		Kvstore:              o.Kvstore,
 		AllowChatNotifySkips: o.AllowChatNotifySkips,
 	}
 }
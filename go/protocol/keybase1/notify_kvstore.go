@@ -0,0 +1,104 @@
+// Auto-generated to Go types and interfaces using avdl-compiler v1.4.10 (https://github.com/keybase/node-avdl-compiler)
+//   Input file: avdl/keybase1/notify_kvstore.avdl
+
+package keybase1
+
+import (
+	"fmt"
+	"github.com/keybase/go-framed-msgpack-rpc/rpc"
+	context "golang.org/x/net/context"
+	"time"
+)
+
+type KVEntryChangeType int
+
+const (
+	KVEntryChangeType_PUT    KVEntryChangeType = 0
+	KVEntryChangeType_DELETE KVEntryChangeType = 1
+)
+
+func (o KVEntryChangeType) DeepCopy() KVEntryChangeType { return o }
+
+var KVEntryChangeTypeMap = map[string]KVEntryChangeType{
+	"PUT":    0,
+	"DELETE": 1,
+}
+
+var KVEntryChangeTypeRevMap = map[KVEntryChangeType]string{
+	0: "PUT",
+	1: "DELETE",
+}
+
+func (e KVEntryChangeType) String() string {
+	if v, ok := KVEntryChangeTypeRevMap[e]; ok {
+		return v
+	}
+	return fmt.Sprintf("%v", int(e))
+}
+
+type KVEntryChange struct {
+	ChangeType     KVEntryChangeType `codec:"changeType" json:"changeType"`
+	TeamID         TeamID            `codec:"teamID" json:"teamID"`
+	TeamName       string            `codec:"teamName" json:"teamName"`
+	Namespace      string            `codec:"namespace" json:"namespace"`
+	EntryKey       string            `codec:"entryKey" json:"entryKey"`
+	Revision       int               `codec:"revision" json:"revision"`
+	WriterUID      UID               `codec:"writerUID" json:"writerUID"`
+	WriterUsername string            `codec:"writerUsername" json:"writerUsername"`
+	WriterDeviceID DeviceID          `codec:"writerDeviceID" json:"writerDeviceID"`
+}
+
+func (o KVEntryChange) DeepCopy() KVEntryChange {
+	return KVEntryChange{
+		ChangeType:     o.ChangeType.DeepCopy(),
+		TeamID:         o.TeamID.DeepCopy(),
+		TeamName:       o.TeamName,
+		Namespace:      o.Namespace,
+		EntryKey:       o.EntryKey,
+		Revision:       o.Revision,
+		WriterUID:      o.WriterUID.DeepCopy(),
+		WriterUsername: o.WriterUsername,
+		WriterDeviceID: o.WriterDeviceID.DeepCopy(),
+	}
+}
+
+type KvEntryChangedArg struct {
+	Change KVEntryChange `codec:"change" json:"change"`
+}
+
+type NotifyKVStoreInterface interface {
+	KvEntryChanged(context.Context, KVEntryChange) error
+}
+
+func NotifyKVStoreProtocol(i NotifyKVStoreInterface) rpc.Protocol {
+	return rpc.Protocol{
+		Name: "keybase.1.NotifyKVStore",
+		Methods: map[string]rpc.ServeHandlerDescription{
+			"kvEntryChanged": {
+				MakeArg: func() interface{} {
+					var ret [1]KvEntryChangedArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]KvEntryChangedArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]KvEntryChangedArg)(nil), args)
+						return
+					}
+					err = i.KvEntryChanged(ctx, typedArgs[0].Change)
+					return
+				},
+			},
+		},
+	}
+}
+
+type NotifyKVStoreClient struct {
+	Cli rpc.GenericClient
+}
+
+func (c NotifyKVStoreClient) KvEntryChanged(ctx context.Context, change KVEntryChange) (err error) {
+	__arg := KvEntryChangedArg{Change: change}
+	err = c.Cli.Notify(ctx, "keybase.1.NotifyKVStore.kvEntryChanged", []interface{}{__arg}, 0*time.Millisecond)
+	return
+}
@@ -39,6 +39,32 @@ func (e AuthenticityType) String() string {
 	return fmt.Sprintf("%v", int(e))
 }
 
+type EncryptionFormat int
+
+const (
+	EncryptionFormat_SALTPACK EncryptionFormat = 0
+	EncryptionFormat_AGE      EncryptionFormat = 1
+)
+
+func (o EncryptionFormat) DeepCopy() EncryptionFormat { return o }
+
+var EncryptionFormatMap = map[string]EncryptionFormat{
+	"SALTPACK": 0,
+	"AGE":      1,
+}
+
+var EncryptionFormatRevMap = map[EncryptionFormat]string{
+	0: "SALTPACK",
+	1: "AGE",
+}
+
+func (e EncryptionFormat) String() string {
+	if v, ok := EncryptionFormatRevMap[e]; ok {
+		return v
+	}
+	return fmt.Sprintf("%v", int(e))
+}
+
 type SaltpackEncryptOptions struct {
 	Recipients                []string         `codec:"recipients" json:"recipients"`
 	TeamRecipients            []string         `codec:"teamRecipients" json:"teamRecipients"`
@@ -51,6 +77,7 @@ type SaltpackEncryptOptions struct {
 	SaltpackVersion           int              `codec:"saltpackVersion" json:"saltpackVersion"`
 	NoForcePoll               bool             `codec:"noForcePoll" json:"noForcePoll"`
 	UseKBFSKeysOnlyForTesting bool             `codec:"useKBFSKeysOnlyForTesting" json:"useKBFSKeysOnlyForTesting"`
+	Format                    EncryptionFormat `codec:"format" json:"format"`
 }
 
 func (o SaltpackEncryptOptions) DeepCopy() SaltpackEncryptOptions {
@@ -86,6 +113,7 @@ func (o SaltpackEncryptOptions) DeepCopy() SaltpackEncryptOptions {
 		SaltpackVersion:           o.SaltpackVersion,
 		NoForcePoll:               o.NoForcePoll,
 		UseKBFSKeysOnlyForTesting: o.UseKBFSKeysOnlyForTesting,
+		Format:                    o.Format.DeepCopy(),
 	}
 }
 
@@ -1126,6 +1126,23 @@ func (o OpProgress) DeepCopy() OpProgress {
 	}
 }
 
+type SimpleFSXattr struct {
+	Name  string `codec:"name" json:"name"`
+	Value []byte `codec:"value" json:"value"`
+}
+
+func (o SimpleFSXattr) DeepCopy() SimpleFSXattr {
+	return SimpleFSXattr{
+		Name: o.Name,
+		Value: (func(x []byte) []byte {
+			if x == nil {
+				return nil
+			}
+			return append([]byte{}, x...)
+		})(o.Value),
+	}
+}
+
 type SimpleFSQuotaUsage struct {
 	UsageBytes      int64 `codec:"usageBytes" json:"usageBytes"`
 	ArchiveBytes    int64 `codec:"archiveBytes" json:"archiveBytes"`
@@ -1598,13 +1615,129 @@ func (o GUIFileContext) DeepCopy() GUIFileContext {
 	}
 }
 
+type SimpleFSWebDAVServer struct {
+	Url   string `codec:"url" json:"url"`
+	Token string `codec:"token" json:"token"`
+}
+
+func (o SimpleFSWebDAVServer) DeepCopy() SimpleFSWebDAVServer {
+	return SimpleFSWebDAVServer{
+		Url:   o.Url,
+		Token: o.Token,
+	}
+}
+
+type SimpleFSS3Bucket struct {
+	Name string   `codec:"name" json:"name"`
+	Path KBFSPath `codec:"path" json:"path"`
+}
+
+func (o SimpleFSS3Bucket) DeepCopy() SimpleFSS3Bucket {
+	return SimpleFSS3Bucket{
+		Name: o.Name,
+		Path: o.Path.DeepCopy(),
+	}
+}
+
+type SimpleFSS3Gateway struct {
+	Url       string   `codec:"url" json:"url"`
+	AccessKey string   `codec:"accessKey" json:"accessKey"`
+	SecretKey string   `codec:"secretKey" json:"secretKey"`
+	Buckets   []string `codec:"buckets" json:"buckets"`
+}
+
+func (o SimpleFSS3Gateway) DeepCopy() SimpleFSS3Gateway {
+	return SimpleFSS3Gateway{
+		Url:       o.Url,
+		AccessKey: o.AccessKey,
+		SecretKey: o.SecretKey,
+		Buckets: (func(x []string) []string {
+			if x == nil {
+				return nil
+			}
+			ret := make([]string, len(x))
+			for i, v := range x {
+				vCopy := v
+				ret[i] = vCopy
+			}
+			return ret
+		})(o.Buckets),
+	}
+}
+
+type SimpleFSSearchMatch struct {
+	Start int `codec:"start" json:"start"`
+	End   int `codec:"end" json:"end"`
+}
+
+func (o SimpleFSSearchMatch) DeepCopy() SimpleFSSearchMatch {
+	return SimpleFSSearchMatch{
+		Start: o.Start,
+		End:   o.End,
+	}
+}
+
+type SimpleFSSearchFragment struct {
+	Text    string                `codec:"text" json:"text"`
+	Matches []SimpleFSSearchMatch `codec:"matches" json:"matches"`
+}
+
+func (o SimpleFSSearchFragment) DeepCopy() SimpleFSSearchFragment {
+	return SimpleFSSearchFragment{
+		Text: o.Text,
+		Matches: (func(x []SimpleFSSearchMatch) []SimpleFSSearchMatch {
+			if x == nil {
+				return nil
+			}
+			ret := make([]SimpleFSSearchMatch, len(x))
+			for i, v := range x {
+				vCopy := v.DeepCopy()
+				ret[i] = vCopy
+			}
+			return ret
+		})(o.Matches),
+	}
+}
+
 type SimpleFSSearchHit struct {
-	Path string `codec:"path" json:"path"`
+	Path      string                   `codec:"path" json:"path"`
+	Score     float64                  `codec:"score" json:"score"`
+	Fragments []SimpleFSSearchFragment `codec:"fragments" json:"fragments"`
 }
 
 func (o SimpleFSSearchHit) DeepCopy() SimpleFSSearchHit {
 	return SimpleFSSearchHit{
-		Path: o.Path,
+		Path:  o.Path,
+		Score: o.Score,
+		Fragments: (func(x []SimpleFSSearchFragment) []SimpleFSSearchFragment {
+			if x == nil {
+				return nil
+			}
+			ret := make([]SimpleFSSearchFragment, len(x))
+			for i, v := range x {
+				vCopy := v.DeepCopy()
+				ret[i] = vCopy
+			}
+			return ret
+		})(o.Fragments),
+	}
+}
+
+type SimpleFSSearchFilters struct {
+	Tlf            string `codec:"tlf" json:"tlf"`
+	PathPrefix     string `codec:"pathPrefix" json:"pathPrefix"`
+	FileType       string `codec:"fileType" json:"fileType"`
+	ModifiedAfter  Time   `codec:"modifiedAfter" json:"modifiedAfter"`
+	ModifiedBefore Time   `codec:"modifiedBefore" json:"modifiedBefore"`
+}
+
+func (o SimpleFSSearchFilters) DeepCopy() SimpleFSSearchFilters {
+	return SimpleFSSearchFilters{
+		Tlf:            o.Tlf,
+		PathPrefix:     o.PathPrefix,
+		FileType:       o.FileType,
+		ModifiedAfter:  o.ModifiedAfter.DeepCopy(),
+		ModifiedBefore: o.ModifiedBefore.DeepCopy(),
 	}
 }
 
@@ -1738,6 +1871,21 @@ type SimpleFSSetStatArg struct {
 	Flag DirentType `codec:"flag" json:"flag"`
 }
 
+type SimpleFSListXattrsArg struct {
+	Path Path `codec:"path" json:"path"`
+}
+
+type SimpleFSSetXattrArg struct {
+	Path  Path   `codec:"path" json:"path"`
+	Name  string `codec:"name" json:"name"`
+	Value []byte `codec:"value" json:"value"`
+}
+
+type SimpleFSRemoveXattrArg struct {
+	Path Path   `codec:"path" json:"path"`
+	Name string `codec:"name" json:"name"`
+}
+
 type SimpleFSReadArg struct {
 	OpID   OpID  `codec:"opID" json:"opID"`
 	Offset int64 `codec:"offset" json:"offset"`
@@ -1962,6 +2110,21 @@ type SimpleFSGetGUIFileContextArg struct {
 	Path KBFSPath `codec:"path" json:"path"`
 }
 
+type SimpleFSStartWebDAVArg struct {
+	Port int `codec:"port" json:"port"`
+}
+
+type SimpleFSStopWebDAVArg struct {
+}
+
+type SimpleFSStartS3GatewayArg struct {
+	Port    int                `codec:"port" json:"port"`
+	Buckets []SimpleFSS3Bucket `codec:"buckets" json:"buckets"`
+}
+
+type SimpleFSStopS3GatewayArg struct {
+}
+
 type SimpleFSUserInArg struct {
 	ClientID string `codec:"clientID" json:"clientID"`
 }
@@ -1971,9 +2134,10 @@ type SimpleFSUserOutArg struct {
 }
 
 type SimpleFSSearchArg struct {
-	Query        string `codec:"query" json:"query"`
-	NumResults   int    `codec:"numResults" json:"numResults"`
-	StartingFrom int    `codec:"startingFrom" json:"startingFrom"`
+	Query        string                `codec:"query" json:"query"`
+	NumResults   int                   `codec:"numResults" json:"numResults"`
+	StartingFrom int                   `codec:"startingFrom" json:"startingFrom"`
+	Filters      SimpleFSSearchFilters `codec:"filters" json:"filters"`
 }
 
 type SimpleFSResetIndexArg struct {
@@ -2027,6 +2191,13 @@ type SimpleFSInterface interface {
 	SimpleFSOpen(context.Context, SimpleFSOpenArg) error
 	// Set/clear file bits - only executable for now
 	SimpleFSSetStat(context.Context, SimpleFSSetStatArg) error
+	// List the user extended attributes of a file or directory.
+	SimpleFSListXattrs(context.Context, Path) ([]SimpleFSXattr, error)
+	// Set a user extended attribute (whose name must start with "user.")
+	// on a file or directory.
+	SimpleFSSetXattr(context.Context, SimpleFSSetXattrArg) error
+	// Remove a user extended attribute from a file or directory.
+	SimpleFSRemoveXattr(context.Context, SimpleFSRemoveXattrArg) error
 	// Read (possibly partial) contents of open file,
 	// up to the amount specified by size.
 	// Repeat until zero bytes are returned or error.
@@ -2118,6 +2289,20 @@ type SimpleFSInterface interface {
 	SimpleFSDismissUpload(context.Context, string) error
 	SimpleFSGetFilesTabBadge(context.Context) (FilesTabBadge, error)
 	SimpleFSGetGUIFileContext(context.Context, KBFSPath) (GUIFileContext, error)
+	// Start serving KBFS read-write over WebDAV on the loopback interface,
+	// on an automatically-picked port if `port` is 0.  If a server is
+	// already running, return it instead (with a new token, if its token
+	// has expired), unless it's on a different non-zero `port`.
+	SimpleFSStartWebDAV(context.Context, int) (SimpleFSWebDAVServer, error)
+	// Stop the WebDAV server, if one is running.
+	SimpleFSStopWebDAV(context.Context) error
+	// Start an S3-compatible gateway on the loopback interface, on an
+	// automatically-picked port if `port` is 0, serving each of the given
+	// KBFS directories as a bucket.  If a gateway is already running and
+	// no buckets are given, return it instead.
+	SimpleFSStartS3Gateway(context.Context, SimpleFSStartS3GatewayArg) (SimpleFSS3Gateway, error)
+	// Stop the S3 gateway, if one is running.
+	SimpleFSStopS3Gateway(context.Context) error
 	SimpleFSUserIn(context.Context, string) error
 	SimpleFSUserOut(context.Context, string) error
 	SimpleFSSearch(context.Context, SimpleFSSearchArg) (SimpleFSSearchResults, error)
@@ -2295,6 +2480,51 @@ func SimpleFSProtocol(i SimpleFSInterface) rpc.Protocol {
 					return
 				},
 			},
+			"simpleFSListXattrs": {
+				MakeArg: func() interface{} {
+					var ret [1]SimpleFSListXattrsArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]SimpleFSListXattrsArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]SimpleFSListXattrsArg)(nil), args)
+						return
+					}
+					ret, err = i.SimpleFSListXattrs(ctx, typedArgs[0].Path)
+					return
+				},
+			},
+			"simpleFSSetXattr": {
+				MakeArg: func() interface{} {
+					var ret [1]SimpleFSSetXattrArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]SimpleFSSetXattrArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]SimpleFSSetXattrArg)(nil), args)
+						return
+					}
+					err = i.SimpleFSSetXattr(ctx, typedArgs[0])
+					return
+				},
+			},
+			"simpleFSRemoveXattr": {
+				MakeArg: func() interface{} {
+					var ret [1]SimpleFSRemoveXattrArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]SimpleFSRemoveXattrArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]SimpleFSRemoveXattrArg)(nil), args)
+						return
+					}
+					err = i.SimpleFSRemoveXattr(ctx, typedArgs[0])
+					return
+				},
+			},
 			"simpleFSRead": {
 				MakeArg: func() interface{} {
 					var ret [1]SimpleFSReadArg
@@ -3025,6 +3255,56 @@ func SimpleFSProtocol(i SimpleFSInterface) rpc.Protocol {
 					return
 				},
 			},
+			"simpleFSStartWebDAV": {
+				MakeArg: func() interface{} {
+					var ret [1]SimpleFSStartWebDAVArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]SimpleFSStartWebDAVArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]SimpleFSStartWebDAVArg)(nil), args)
+						return
+					}
+					ret, err = i.SimpleFSStartWebDAV(ctx, typedArgs[0].Port)
+					return
+				},
+			},
+			"simpleFSStopWebDAV": {
+				MakeArg: func() interface{} {
+					var ret [1]SimpleFSStopWebDAVArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					err = i.SimpleFSStopWebDAV(ctx)
+					return
+				},
+			},
+			"simpleFSStartS3Gateway": {
+				MakeArg: func() interface{} {
+					var ret [1]SimpleFSStartS3GatewayArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]SimpleFSStartS3GatewayArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]SimpleFSStartS3GatewayArg)(nil), args)
+						return
+					}
+					ret, err = i.SimpleFSStartS3Gateway(ctx, typedArgs[0])
+					return
+				},
+			},
+			"simpleFSStopS3Gateway": {
+				MakeArg: func() interface{} {
+					var ret [1]SimpleFSStopS3GatewayArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					err = i.SimpleFSStopS3Gateway(ctx)
+					return
+				},
+			},
 			"simpleFSUserIn": {
 				MakeArg: func() interface{} {
 					var ret [1]SimpleFSUserInArg
@@ -3198,6 +3478,26 @@ func (c SimpleFSClient) SimpleFSSetStat(ctx context.Context, __arg SimpleFSSetSt
 	return
 }
 
+// List the user extended attributes of a file or directory.
+func (c SimpleFSClient) SimpleFSListXattrs(ctx context.Context, path Path) (res []SimpleFSXattr, err error) {
+	__arg := SimpleFSListXattrsArg{Path: path}
+	err = c.Cli.Call(ctx, "keybase.1.SimpleFS.simpleFSListXattrs", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
+
+// Set a user extended attribute (whose name must start with "user.")
+// on a file or directory.
+func (c SimpleFSClient) SimpleFSSetXattr(ctx context.Context, __arg SimpleFSSetXattrArg) (err error) {
+	err = c.Cli.Call(ctx, "keybase.1.SimpleFS.simpleFSSetXattr", []interface{}{__arg}, nil, 0*time.Millisecond)
+	return
+}
+
+// Remove a user extended attribute from a file or directory.
+func (c SimpleFSClient) SimpleFSRemoveXattr(ctx context.Context, __arg SimpleFSRemoveXattrArg) (err error) {
+	err = c.Cli.Call(ctx, "keybase.1.SimpleFS.simpleFSRemoveXattr", []interface{}{__arg}, nil, 0*time.Millisecond)
+	return
+}
+
 // Read (possibly partial) contents of open file,
 // up to the amount specified by size.
 // Repeat until zero bytes are returned or error.
@@ -3528,6 +3828,37 @@ func (c SimpleFSClient) SimpleFSGetGUIFileContext(ctx context.Context, path KBFS
 	return
 }
 
+// Start serving KBFS read-write over WebDAV on the loopback interface,
+// on an automatically-picked port if `port` is 0.  If a server is
+// already running, return it instead (with a new token, if its token
+// has expired), unless it's on a different non-zero `port`.
+func (c SimpleFSClient) SimpleFSStartWebDAV(ctx context.Context, port int) (res SimpleFSWebDAVServer, err error) {
+	__arg := SimpleFSStartWebDAVArg{Port: port}
+	err = c.Cli.Call(ctx, "keybase.1.SimpleFS.simpleFSStartWebDAV", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
+
+// Stop the WebDAV server, if one is running.
+func (c SimpleFSClient) SimpleFSStopWebDAV(ctx context.Context) (err error) {
+	err = c.Cli.Call(ctx, "keybase.1.SimpleFS.simpleFSStopWebDAV", []interface{}{SimpleFSStopWebDAVArg{}}, nil, 0*time.Millisecond)
+	return
+}
+
+// Start an S3-compatible gateway on the loopback interface, on an
+// automatically-picked port if `port` is 0, serving each of the given
+// KBFS directories as a bucket.  If a gateway is already running and
+// no buckets are given, return it instead.
+func (c SimpleFSClient) SimpleFSStartS3Gateway(ctx context.Context, __arg SimpleFSStartS3GatewayArg) (res SimpleFSS3Gateway, err error) {
+	err = c.Cli.Call(ctx, "keybase.1.SimpleFS.simpleFSStartS3Gateway", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
+
+// Stop the S3 gateway, if one is running.
+func (c SimpleFSClient) SimpleFSStopS3Gateway(ctx context.Context) (err error) {
+	err = c.Cli.Call(ctx, "keybase.1.SimpleFS.simpleFSStopS3Gateway", []interface{}{SimpleFSStopS3GatewayArg{}}, nil, 0*time.Millisecond)
+	return
+}
+
 func (c SimpleFSClient) SimpleFSUserIn(ctx context.Context, clientID string) (err error) {
 	__arg := SimpleFSUserInArg{ClientID: clientID}
 	err = c.Cli.Call(ctx, "keybase.1.SimpleFS.simpleFSUserIn", []interface{}{__arg}, nil, 0*time.Millisecond)
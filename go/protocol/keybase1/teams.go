@@ -3918,6 +3918,347 @@ func (o TeamEditMembersResult) DeepCopy() TeamEditMembersResult {
 	}
 }
 
+type TeamApplyTeam struct {
+	Name     string            `codec:"name" json:"name"`
+	Members  []TeamApplyMember `codec:"members" json:"members"`
+	Settings *TeamSettings     `codec:"settings,omitempty" json:"settings,omitempty"`
+}
+
+func (o TeamApplyTeam) DeepCopy() TeamApplyTeam {
+	return TeamApplyTeam{
+		Name: o.Name,
+		Members: (func(x []TeamApplyMember) []TeamApplyMember {
+			if x == nil {
+				return nil
+			}
+			ret := make([]TeamApplyMember, len(x))
+			for i, v := range x {
+				vCopy := v.DeepCopy()
+				ret[i] = vCopy
+			}
+			return ret
+		})(o.Members),
+		Settings: (func(x *TeamSettings) *TeamSettings {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x).DeepCopy()
+			return &tmp
+		})(o.Settings),
+	}
+}
+
+type TeamApplyMember struct {
+	Username    string           `codec:"username" json:"username"`
+	Role        TeamRole         `codec:"role" json:"role"`
+	BotSettings *TeamBotSettings `codec:"botSettings,omitempty" json:"botSettings,omitempty"`
+}
+
+func (o TeamApplyMember) DeepCopy() TeamApplyMember {
+	return TeamApplyMember{
+		Username: o.Username,
+		Role:     o.Role.DeepCopy(),
+		BotSettings: (func(x *TeamBotSettings) *TeamBotSettings {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x).DeepCopy()
+			return &tmp
+		})(o.BotSettings),
+	}
+}
+
+type TeamApplyChangeType int
+
+const (
+	TeamApplyChangeType_CREATE_SUBTEAM   TeamApplyChangeType = 0
+	TeamApplyChangeType_ADD_MEMBER       TeamApplyChangeType = 1
+	TeamApplyChangeType_CHANGE_ROLE      TeamApplyChangeType = 2
+	TeamApplyChangeType_REMOVE_MEMBER    TeamApplyChangeType = 3
+	TeamApplyChangeType_SET_BOT_SETTINGS TeamApplyChangeType = 4
+	TeamApplyChangeType_CHANGE_SETTINGS  TeamApplyChangeType = 5
+)
+
+func (o TeamApplyChangeType) DeepCopy() TeamApplyChangeType { return o }
+
+var TeamApplyChangeTypeMap = map[string]TeamApplyChangeType{
+	"CREATE_SUBTEAM":   0,
+	"ADD_MEMBER":       1,
+	"CHANGE_ROLE":      2,
+	"REMOVE_MEMBER":    3,
+	"SET_BOT_SETTINGS": 4,
+	"CHANGE_SETTINGS":  5,
+}
+
+var TeamApplyChangeTypeRevMap = map[TeamApplyChangeType]string{
+	0: "CREATE_SUBTEAM",
+	1: "ADD_MEMBER",
+	2: "CHANGE_ROLE",
+	3: "REMOVE_MEMBER",
+	4: "SET_BOT_SETTINGS",
+	5: "CHANGE_SETTINGS",
+}
+
+func (e TeamApplyChangeType) String() string {
+	if v, ok := TeamApplyChangeTypeRevMap[e]; ok {
+		return v
+	}
+	return fmt.Sprintf("%v", int(e))
+}
+
+type TeamApplyChange struct {
+	TeamName    string              `codec:"teamName" json:"teamName"`
+	Type        TeamApplyChangeType `codec:"type" json:"type"`
+	Username    string              `codec:"username" json:"username"`
+	OldRole     TeamRole            `codec:"oldRole" json:"oldRole"`
+	NewRole     TeamRole            `codec:"newRole" json:"newRole"`
+	BotSettings *TeamBotSettings    `codec:"botSettings,omitempty" json:"botSettings,omitempty"`
+	Settings    *TeamSettings       `codec:"settings,omitempty" json:"settings,omitempty"`
+}
+
+func (o TeamApplyChange) DeepCopy() TeamApplyChange {
+	return TeamApplyChange{
+		TeamName: o.TeamName,
+		Type:     o.Type.DeepCopy(),
+		Username: o.Username,
+		OldRole:  o.OldRole.DeepCopy(),
+		NewRole:  o.NewRole.DeepCopy(),
+		BotSettings: (func(x *TeamBotSettings) *TeamBotSettings {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x).DeepCopy()
+			return &tmp
+		})(o.BotSettings),
+		Settings: (func(x *TeamSettings) *TeamSettings {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x).DeepCopy()
+			return &tmp
+		})(o.Settings),
+	}
+}
+
+type TeamApplyTeamResult struct {
+	TeamName string  `codec:"teamName" json:"teamName"`
+	Applied  bool    `codec:"applied" json:"applied"`
+	Error    *string `codec:"error,omitempty" json:"error,omitempty"`
+}
+
+func (o TeamApplyTeamResult) DeepCopy() TeamApplyTeamResult {
+	return TeamApplyTeamResult{
+		TeamName: o.TeamName,
+		Applied:  o.Applied,
+		Error: (func(x *string) *string {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x)
+			return &tmp
+		})(o.Error),
+	}
+}
+
+type TeamApplyResult struct {
+	Changes []TeamApplyChange     `codec:"changes" json:"changes"`
+	Teams   []TeamApplyTeamResult `codec:"teams" json:"teams"`
+	Applied bool                  `codec:"applied" json:"applied"`
+}
+
+func (o TeamApplyResult) DeepCopy() TeamApplyResult {
+	return TeamApplyResult{
+		Changes: (func(x []TeamApplyChange) []TeamApplyChange {
+			if x == nil {
+				return nil
+			}
+			ret := make([]TeamApplyChange, len(x))
+			for i, v := range x {
+				vCopy := v.DeepCopy()
+				ret[i] = vCopy
+			}
+			return ret
+		})(o.Changes),
+		Teams: (func(x []TeamApplyTeamResult) []TeamApplyTeamResult {
+			if x == nil {
+				return nil
+			}
+			ret := make([]TeamApplyTeamResult, len(x))
+			for i, v := range x {
+				vCopy := v.DeepCopy()
+				ret[i] = vCopy
+			}
+			return ret
+		})(o.Teams),
+		Applied: o.Applied,
+	}
+}
+
+type TeamHistoryEventType int
+
+const (
+	TeamHistoryEventType_CREATED          TeamHistoryEventType = 0
+	TeamHistoryEventType_MEMBER_ADDED     TeamHistoryEventType = 1
+	TeamHistoryEventType_ROLE_CHANGED     TeamHistoryEventType = 2
+	TeamHistoryEventType_MEMBER_REMOVED   TeamHistoryEventType = 3
+	TeamHistoryEventType_INVITE_ADDED     TeamHistoryEventType = 4
+	TeamHistoryEventType_INVITE_CANCELED  TeamHistoryEventType = 5
+	TeamHistoryEventType_INVITE_COMPLETED TeamHistoryEventType = 6
+	TeamHistoryEventType_SETTINGS_CHANGED TeamHistoryEventType = 7
+	TeamHistoryEventType_KEY_ROTATED      TeamHistoryEventType = 8
+)
+
+func (o TeamHistoryEventType) DeepCopy() TeamHistoryEventType { return o }
+
+var TeamHistoryEventTypeMap = map[string]TeamHistoryEventType{
+	"CREATED":          0,
+	"MEMBER_ADDED":     1,
+	"ROLE_CHANGED":     2,
+	"MEMBER_REMOVED":   3,
+	"INVITE_ADDED":     4,
+	"INVITE_CANCELED":  5,
+	"INVITE_COMPLETED": 6,
+	"SETTINGS_CHANGED": 7,
+	"KEY_ROTATED":      8,
+}
+
+var TeamHistoryEventTypeRevMap = map[TeamHistoryEventType]string{
+	0: "CREATED",
+	1: "MEMBER_ADDED",
+	2: "ROLE_CHANGED",
+	3: "MEMBER_REMOVED",
+	4: "INVITE_ADDED",
+	5: "INVITE_CANCELED",
+	6: "INVITE_COMPLETED",
+	7: "SETTINGS_CHANGED",
+	8: "KEY_ROTATED",
+}
+
+func (e TeamHistoryEventType) String() string {
+	if v, ok := TeamHistoryEventTypeRevMap[e]; ok {
+		return v
+	}
+	return fmt.Sprintf("%v", int(e))
+}
+
+type TeamHistoryEvent struct {
+	Type                  TeamHistoryEventType `codec:"type" json:"type"`
+	Location              SigChainLocation     `codec:"location" json:"location"`
+	Time                  Time                 `codec:"time" json:"time"`
+	MerkleSeqno           Seqno                `codec:"merkleSeqno" json:"merkleSeqno"`
+	Signer                UserVersion          `codec:"signer" json:"signer"`
+	SignerUsername        string               `codec:"signerUsername" json:"signerUsername"`
+	SigningKID            KID                  `codec:"signingKID" json:"signingKID"`
+	SigningDevice         string               `codec:"signingDevice" json:"signingDevice"`
+	SignerIsImplicitAdmin bool                 `codec:"signerIsImplicitAdmin" json:"signerIsImplicitAdmin"`
+	Member                *UserVersion         `codec:"member,omitempty" json:"member,omitempty"`
+	MemberUsername        string               `codec:"memberUsername" json:"memberUsername"`
+	OldRole               TeamRole             `codec:"oldRole" json:"oldRole"`
+	NewRole               TeamRole             `codec:"newRole" json:"newRole"`
+	Invite                *TeamInvite          `codec:"invite,omitempty" json:"invite,omitempty"`
+	Settings              *TeamSettings        `codec:"settings,omitempty" json:"settings,omitempty"`
+	Generation            PerTeamKeyGeneration `codec:"generation" json:"generation"`
+}
+
+func (o TeamHistoryEvent) DeepCopy() TeamHistoryEvent {
+	return TeamHistoryEvent{
+		Type:                  o.Type.DeepCopy(),
+		Location:              o.Location.DeepCopy(),
+		Time:                  o.Time.DeepCopy(),
+		MerkleSeqno:           o.MerkleSeqno.DeepCopy(),
+		Signer:                o.Signer.DeepCopy(),
+		SignerUsername:        o.SignerUsername,
+		SigningKID:            o.SigningKID.DeepCopy(),
+		SigningDevice:         o.SigningDevice,
+		SignerIsImplicitAdmin: o.SignerIsImplicitAdmin,
+		Member: (func(x *UserVersion) *UserVersion {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x).DeepCopy()
+			return &tmp
+		})(o.Member),
+		MemberUsername: o.MemberUsername,
+		OldRole:        o.OldRole.DeepCopy(),
+		NewRole:        o.NewRole.DeepCopy(),
+		Invite: (func(x *TeamInvite) *TeamInvite {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x).DeepCopy()
+			return &tmp
+		})(o.Invite),
+		Settings: (func(x *TeamSettings) *TeamSettings {
+			if x == nil {
+				return nil
+			}
+			tmp := (*x).DeepCopy()
+			return &tmp
+		})(o.Settings),
+		Generation: o.Generation.DeepCopy(),
+	}
+}
+
+type TeamChainVerification struct {
+	TeamID              TeamID `codec:"teamID" json:"teamID"`
+	TeamName            string `codec:"teamName" json:"teamName"`
+	LastSeqno           Seqno  `codec:"lastSeqno" json:"lastSeqno"`
+	LastLinkID          LinkID `codec:"lastLinkID" json:"lastLinkID"`
+	LastHiddenSeqno     Seqno  `codec:"lastHiddenSeqno" json:"lastHiddenSeqno"`
+	StubbedLinks        int    `codec:"stubbedLinks" json:"stubbedLinks"`
+	Ancestors           int    `codec:"ancestors" json:"ancestors"`
+	Signers             int    `codec:"signers" json:"signers"`
+	HappensBeforeProofs int    `codec:"happensBeforeProofs" json:"happensBeforeProofs"`
+	MerkleSeqno         Seqno  `codec:"merkleSeqno" json:"merkleSeqno"`
+	MerkleTime          Time   `codec:"merkleTime" json:"merkleTime"`
+	ExportedAt          Time   `codec:"exportedAt" json:"exportedAt"`
+}
+
+func (o TeamChainVerification) DeepCopy() TeamChainVerification {
+	return TeamChainVerification{
+		TeamID:              o.TeamID.DeepCopy(),
+		TeamName:            o.TeamName,
+		LastSeqno:           o.LastSeqno.DeepCopy(),
+		LastLinkID:          o.LastLinkID.DeepCopy(),
+		LastHiddenSeqno:     o.LastHiddenSeqno.DeepCopy(),
+		StubbedLinks:        o.StubbedLinks,
+		Ancestors:           o.Ancestors,
+		Signers:             o.Signers,
+		HappensBeforeProofs: o.HappensBeforeProofs,
+		MerkleSeqno:         o.MerkleSeqno.DeepCopy(),
+		MerkleTime:          o.MerkleTime.DeepCopy(),
+		ExportedAt:          o.ExportedAt.DeepCopy(),
+	}
+}
+
+type TeamMemberExpiry struct {
+	TeamID      TeamID   `codec:"teamID" json:"teamID"`
+	TeamName    string   `codec:"teamName" json:"teamName"`
+	Uid         UID      `codec:"uid" json:"uid"`
+	Username    string   `codec:"username" json:"username"`
+	Role        TeamRole `codec:"role" json:"role"`
+	ExpiresAt   Time     `codec:"expiresAt" json:"expiresAt"`
+	DowngradeTo TeamRole `codec:"downgradeTo" json:"downgradeTo"`
+	SetBy       string   `codec:"setBy" json:"setBy"`
+	Ctime       Time     `codec:"ctime" json:"ctime"`
+	WarnedAt    Time     `codec:"warnedAt" json:"warnedAt"`
+}
+
+func (o TeamMemberExpiry) DeepCopy() TeamMemberExpiry {
+	return TeamMemberExpiry{
+		TeamID:      o.TeamID.DeepCopy(),
+		TeamName:    o.TeamName,
+		Uid:         o.Uid.DeepCopy(),
+		Username:    o.Username,
+		Role:        o.Role.DeepCopy(),
+		ExpiresAt:   o.ExpiresAt.DeepCopy(),
+		DowngradeTo: o.DowngradeTo.DeepCopy(),
+		SetBy:       o.SetBy,
+		Ctime:       o.Ctime.DeepCopy(),
+		WarnedAt:    o.WarnedAt.DeepCopy(),
+	}
+}
+
 type UntrustedTeamExistsResult struct {
 	Exists bool       `codec:"exists" json:"exists"`
 	Status StatusCode `codec:"status" json:"status"`
@@ -4688,6 +5029,43 @@ type TeamSetBotSettingsArg struct {
 	BotSettings TeamBotSettings `codec:"botSettings" json:"botSettings"`
 }
 
+type TeamApplyArg struct {
+	SessionID int             `codec:"sessionID" json:"sessionID"`
+	Teams     []TeamApplyTeam `codec:"teams" json:"teams"`
+	DryRun    bool            `codec:"dryRun" json:"dryRun"`
+}
+
+type TeamHistoryArg struct {
+	SessionID int    `codec:"sessionID" json:"sessionID"`
+	TeamName  string `codec:"teamName" json:"teamName"`
+	Member    string `codec:"member" json:"member"`
+	After     Time   `codec:"after" json:"after"`
+	Before    Time   `codec:"before" json:"before"`
+}
+
+type TeamExportChainArg struct {
+	SessionID int    `codec:"sessionID" json:"sessionID"`
+	TeamName  string `codec:"teamName" json:"teamName"`
+}
+
+type TeamVerifyChainArg struct {
+	SessionID int    `codec:"sessionID" json:"sessionID"`
+	Bundle    string `codec:"bundle" json:"bundle"`
+}
+
+type TeamSetMemberExpiryArg struct {
+	SessionID   int      `codec:"sessionID" json:"sessionID"`
+	TeamName    string   `codec:"teamName" json:"teamName"`
+	Username    string   `codec:"username" json:"username"`
+	ExpiresAt   Time     `codec:"expiresAt" json:"expiresAt"`
+	DowngradeTo TeamRole `codec:"downgradeTo" json:"downgradeTo"`
+}
+
+type TeamListExpiringMembersArg struct {
+	SessionID int    `codec:"sessionID" json:"sessionID"`
+	TeamName  string `codec:"teamName" json:"teamName"`
+}
+
 type UntrustedTeamExistsArg struct {
 	TeamName TeamName `codec:"teamName" json:"teamName"`
 }
@@ -4959,6 +5337,12 @@ type TeamsInterface interface {
 	TeamEditMembers(context.Context, TeamEditMembersArg) (TeamEditMembersResult, error)
 	TeamGetBotSettings(context.Context, TeamGetBotSettingsArg) (TeamBotSettings, error)
 	TeamSetBotSettings(context.Context, TeamSetBotSettingsArg) error
+	TeamApply(context.Context, TeamApplyArg) (TeamApplyResult, error)
+	TeamHistory(context.Context, TeamHistoryArg) ([]TeamHistoryEvent, error)
+	TeamExportChain(context.Context, TeamExportChainArg) (string, error)
+	TeamVerifyChain(context.Context, TeamVerifyChainArg) (TeamChainVerification, error)
+	TeamSetMemberExpiry(context.Context, TeamSetMemberExpiryArg) error
+	TeamListExpiringMembers(context.Context, TeamListExpiringMembersArg) ([]TeamMemberExpiry, error)
 	UntrustedTeamExists(context.Context, TeamName) (UntrustedTeamExistsResult, error)
 	TeamRename(context.Context, TeamRenameArg) error
 	TeamAcceptInvite(context.Context, TeamAcceptInviteArg) error
@@ -5344,6 +5728,96 @@ func TeamsProtocol(i TeamsInterface) rpc.Protocol {
 					return
 				},
 			},
+			"teamApply": {
+				MakeArg: func() interface{} {
+					var ret [1]TeamApplyArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]TeamApplyArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]TeamApplyArg)(nil), args)
+						return
+					}
+					ret, err = i.TeamApply(ctx, typedArgs[0])
+					return
+				},
+			},
+			"teamHistory": {
+				MakeArg: func() interface{} {
+					var ret [1]TeamHistoryArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]TeamHistoryArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]TeamHistoryArg)(nil), args)
+						return
+					}
+					ret, err = i.TeamHistory(ctx, typedArgs[0])
+					return
+				},
+			},
+			"teamExportChain": {
+				MakeArg: func() interface{} {
+					var ret [1]TeamExportChainArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]TeamExportChainArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]TeamExportChainArg)(nil), args)
+						return
+					}
+					ret, err = i.TeamExportChain(ctx, typedArgs[0])
+					return
+				},
+			},
+			"teamVerifyChain": {
+				MakeArg: func() interface{} {
+					var ret [1]TeamVerifyChainArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]TeamVerifyChainArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]TeamVerifyChainArg)(nil), args)
+						return
+					}
+					ret, err = i.TeamVerifyChain(ctx, typedArgs[0])
+					return
+				},
+			},
+			"teamSetMemberExpiry": {
+				MakeArg: func() interface{} {
+					var ret [1]TeamSetMemberExpiryArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]TeamSetMemberExpiryArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]TeamSetMemberExpiryArg)(nil), args)
+						return
+					}
+					err = i.TeamSetMemberExpiry(ctx, typedArgs[0])
+					return
+				},
+			},
+			"teamListExpiringMembers": {
+				MakeArg: func() interface{} {
+					var ret [1]TeamListExpiringMembersArg
+					return &ret
+				},
+				Handler: func(ctx context.Context, args interface{}) (ret interface{}, err error) {
+					typedArgs, ok := args.(*[1]TeamListExpiringMembersArg)
+					if !ok {
+						err = rpc.NewTypeError((*[1]TeamListExpiringMembersArg)(nil), args)
+						return
+					}
+					ret, err = i.TeamListExpiringMembers(ctx, typedArgs[0])
+					return
+				},
+			},
 			"untrustedTeamExists": {
 				MakeArg: func() interface{} {
 					var ret [1]UntrustedTeamExistsArg
@@ -6143,6 +6617,36 @@ func (c TeamsClient) TeamSetBotSettings(ctx context.Context, __arg TeamSetBotSet
 	return
 }
 
+func (c TeamsClient) TeamApply(ctx context.Context, __arg TeamApplyArg) (res TeamApplyResult, err error) {
+	err = c.Cli.Call(ctx, "keybase.1.teams.teamApply", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
+
+func (c TeamsClient) TeamHistory(ctx context.Context, __arg TeamHistoryArg) (res []TeamHistoryEvent, err error) {
+	err = c.Cli.Call(ctx, "keybase.1.teams.teamHistory", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
+
+func (c TeamsClient) TeamExportChain(ctx context.Context, __arg TeamExportChainArg) (res string, err error) {
+	err = c.Cli.Call(ctx, "keybase.1.teams.teamExportChain", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
+
+func (c TeamsClient) TeamVerifyChain(ctx context.Context, __arg TeamVerifyChainArg) (res TeamChainVerification, err error) {
+	err = c.Cli.Call(ctx, "keybase.1.teams.teamVerifyChain", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
+
+func (c TeamsClient) TeamSetMemberExpiry(ctx context.Context, __arg TeamSetMemberExpiryArg) (err error) {
+	err = c.Cli.Call(ctx, "keybase.1.teams.teamSetMemberExpiry", []interface{}{__arg}, nil, 0*time.Millisecond)
+	return
+}
+
+func (c TeamsClient) TeamListExpiringMembers(ctx context.Context, __arg TeamListExpiringMembersArg) (res []TeamMemberExpiry, err error) {
+	err = c.Cli.Call(ctx, "keybase.1.teams.teamListExpiringMembers", []interface{}{__arg}, &res, 0*time.Millisecond)
+	return
+}
+
 func (c TeamsClient) UntrustedTeamExists(ctx context.Context, teamName TeamName) (res UntrustedTeamExistsResult, err error) {
 	__arg := UntrustedTeamExistsArg{TeamName: teamName}
 	err = c.Cli.Call(ctx, "keybase.1.teams.untrustedTeamExists", []interface{}{__arg}, &res, 0*time.Millisecond)
@@ -44,3 +44,12 @@ func (e UserWasLoggedOutError) ToStatus() (s keybase1.Status) {
 	s.Desc = e.Error()
 	return
 }
+
+// KVBatchUnsupportedError means the server has no endpoint for writing
+// several kvstore entries atomically. None of the batch's entries were
+// written.
+type KVBatchUnsupportedError struct{}
+
+func (e KVBatchUnsupportedError) Error() string {
+	return "the server doesn't support atomic kvstore batches; no entries were written"
+}
@@ -1313,6 +1313,8 @@ func (g *gregorHandler) handleOutOfBandMessage(ctx context.Context, obm gregor.O
 	case "internal.reconnect":
 		g.G().Log.Debug("reconnected to push server")
 		return nil
+	case kvStoreUpdateSystem:
+		return handleKVStoreUpdate(libkb.NewMetaContext(ctx, g.G().ExternalG()), obm.Body().Bytes())
 	default:
 		return fmt.Errorf("unhandled system: %s", obm.System())
 	}
@@ -6,9 +6,12 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/adamwalz/keybase-client/go/kvstore"
 	"github.com/adamwalz/keybase-client/go/libkb"
@@ -22,7 +25,8 @@ type KVStoreHandler struct {
 	*BaseHandler
 	sync.Mutex
 	libkb.Contextified
-	Boxer kvstore.KVStoreBoxer
+	Boxer  kvstore.KVStoreBoxer
+	Mirror *kvstore.KVMirror
 }
 
 var _ keybase1.KvstoreInterface = (*KVStoreHandler)(nil)
@@ -35,10 +39,22 @@ func NewKVStoreHandler(xp rpc.Transporter, g *libkb.GlobalContext) *KVStoreHandl
 		BaseHandler:  NewBaseHandler(g, xp),
 		Contextified: libkb.NewContextified(g),
 		Boxer:        kvstore.NewKVStoreBoxer(g),
+		Mirror:       kvstore.NewKVMirror(g),
 	}
 }
 
 func (h *KVStoreHandler) resolveTeam(mctx libkb.MetaContext, userInputTeamName string) (teamID keybase1.TeamID, err error) {
+	teamID, err = h.resolveTeamFromServer(mctx, userInputTeamName)
+	if err != nil {
+		return teamID, err
+	}
+	if err := h.Mirror.PutTeamID(mctx, userInputTeamName, teamID); err != nil {
+		mctx.Debug("error mirroring team %s (%s): %v", userInputTeamName, teamID, err)
+	}
+	return teamID, nil
+}
+
+func (h *KVStoreHandler) resolveTeamFromServer(mctx libkb.MetaContext, userInputTeamName string) (teamID keybase1.TeamID, err error) {
 	if strings.Contains(userInputTeamName, ",") {
 		// it's an implicit team that might not exist yet
 		team, _, _, err := teams.LookupOrCreateImplicitTeam(mctx.Ctx(), mctx.G(), userInputTeamName, false /*public*/)
@@ -101,12 +117,139 @@ func (h *KVStoreHandler) serverFetch(mctx libkb.MetaContext, entryID keybase1.KV
 	return apiRes, nil
 }
 
+// mirrorPut and mirrorDelete keep the local mirror up to date with what the
+// server just confirmed. The mirror is only a fallback for when the server
+// can't be reached, so failing to update it doesn't fail the request.
+func (h *KVStoreHandler) mirrorPut(mctx libkb.MetaContext, entryID keybase1.KVEntryID, entryValue *string, ciphertext *string, teamKeyGen keybase1.PerTeamKeyGeneration, revision int) {
+	err := h.Mirror.Put(mctx, entryID, entryValue, ciphertext, teamKeyGen, revision)
+	if err != nil {
+		mctx.Warning("Error putting %+v to the local kvstore mirror: %s", entryID, err)
+	}
+}
+
+func (h *KVStoreHandler) mirrorDelete(mctx libkb.MetaContext, entryID keybase1.KVEntryID, revision int) {
+	err := h.Mirror.MarkDeleted(mctx, entryID, revision)
+	if err != nil {
+		mctx.Warning("Error deleting %+v from the local kvstore mirror: %s", entryID, err)
+	}
+}
+
+// kvBestEffortTimeout is how long a BEST_EFFORT read waits on the server
+// before falling back to the local mirror.
+const kvBestEffortTimeout = 500 * time.Millisecond
+
 func (h *KVStoreHandler) GetKVEntry(ctx context.Context, arg keybase1.GetKVEntryArg) (res keybase1.KVGetResult, err error) {
+	if arg.Oa == keybase1.OfflineAvailability_BEST_EFFORT {
+		return h.getKVEntryBestEffort(ctx, arg)
+	}
 	h.Lock()
 	defer h.Unlock()
 	return h.getKVEntryLocked(ctx, arg)
 }
 
+// getKVEntryMirrored returns the last version of an entry this device saw,
+// without talking to the server.
+func (h *KVStoreHandler) getKVEntryMirrored(mctx libkb.MetaContext, arg keybase1.GetKVEntryArg) (res keybase1.KVGetResult, found bool, err error) {
+	teamID, found, err := h.Mirror.LookupTeamID(mctx, arg.TeamName)
+	if err != nil || !found {
+		return res, false, err
+	}
+	entryID := keybase1.KVEntryID{
+		TeamID:    teamID,
+		Namespace: arg.Namespace,
+		EntryKey:  arg.EntryKey,
+	}
+	entry, found, err := h.Mirror.Get(mctx, entryID)
+	if err != nil || !found {
+		return res, false, err
+	}
+	mctx.Debug("found revision %d of %+v in the local mirror, last seen on the server at %v", entry.Revision, entryID, entry.Ctime.Time())
+	entryValue, err := h.assembleChunks(mctx, entryID, entry.EntryValue, func(mctx libkb.MetaContext, chunkID keybase1.KVEntryID) (*string, int, error) {
+		chunk, found, err := h.Mirror.Get(mctx, chunkID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !found {
+			return nil, 0, fmt.Errorf("chunk %s isn't in the local mirror", chunkID.EntryKey)
+		}
+		return chunk.EntryValue, chunk.Revision, nil
+	})
+	if err != nil {
+		return res, false, err
+	}
+	return keybase1.KVGetResult{
+		TeamName:   arg.TeamName,
+		Namespace:  arg.Namespace,
+		EntryKey:   arg.EntryKey,
+		EntryValue: entryValue,
+		Revision:   entry.Revision,
+		Stale:      true,
+	}, true, nil
+}
+
+// getKVEntryBestEffort follows the same rules as offline.RPCCache: if we
+// know we're offline, serve the mirrored entry right away; otherwise give the
+// server a short while to answer before falling back to the mirror, and keep
+// fetching in the background so that the mirror is fresh next time.
+func (h *KVStoreHandler) getKVEntryBestEffort(ctx context.Context, arg keybase1.GetKVEntryArg) (res keybase1.KVGetResult, err error) {
+	ctx = libkb.WithLogTag(ctx, "KV")
+	mctx := libkb.NewMetaContext(ctx, h.G()).WithLogTag("OFLN")
+	defer mctx.Trace(fmt.Sprintf("KVStoreHandler#getKVEntryBestEffort: t:%s, n:%s, k:%s", arg.TeamName, arg.Namespace, arg.EntryKey), &err)()
+
+	if err := assertLoggedIn(ctx, h.G()); err != nil {
+		mctx.Debug("not logged in err: %v", err)
+		return res, err
+	}
+	mirrored, found, err := h.getKVEntryMirrored(mctx, arg)
+	if err != nil {
+		mctx.Debug("error reading the local mirror, continuing without it: %v", err)
+		found = false
+	}
+
+	if mctx.G().ConnectivityMonitor.IsConnected(ctx) == libkb.ConnectivityMonitorNo {
+		if !found {
+			return res, libkb.OfflineError{}
+		}
+		mctx.Debug("offline, serving revision %d from the local mirror", mirrored.Revision)
+		return mirrored, nil
+	}
+
+	type fetchRes struct {
+		res keybase1.KVGetResult
+		err error
+	}
+	resCh := make(chan fetchRes, 1)
+	// The fetch might outlive this request, so it gets its own context.
+	bgMctx := mctx.BackgroundWithLogTags()
+	go func() {
+		h.Lock()
+		defer h.Unlock()
+		res, err := h.getKVEntryLocked(bgMctx.Ctx(), arg)
+		resCh <- fetchRes{res, err}
+	}()
+
+	var timerCh <-chan time.Time
+	if found {
+		timerCh = mctx.G().Clock().After(kvBestEffortTimeout)
+	} else {
+		// nothing to fall back to, so wait on the server
+		timerCh = make(chan time.Time)
+	}
+	select {
+	case fr := <-resCh:
+		if _, ok := fr.err.(libkb.APINetError); ok && found {
+			mctx.Debug("network error fetching from the server, serving revision %d from the local mirror: %v", mirrored.Revision, fr.err)
+			return mirrored, nil
+		}
+		return fr.res, fr.err
+	case <-timerCh:
+		mctx.Debug("timeout waiting for the server, serving revision %d from the local mirror", mirrored.Revision)
+		return mirrored, nil
+	case <-ctx.Done():
+		return res, ctx.Err()
+	}
+}
+
 func (h *KVStoreHandler) getKVEntryLocked(ctx context.Context, arg keybase1.GetKVEntryArg) (res keybase1.KVGetResult, err error) {
 	ctx = libkb.WithLogTag(ctx, "KV")
 	mctx := libkb.NewMetaContext(ctx, h.G())
@@ -125,41 +268,72 @@ func (h *KVStoreHandler) getKVEntryLocked(ctx context.Context, arg keybase1.GetK
 		Namespace: arg.Namespace,
 		EntryKey:  arg.EntryKey,
 	}
+	entryValue, revision, writer, err := h.fetchEntryWithWriterLocked(mctx, entryID)
+	if err != nil {
+		return res, err
+	}
+	entryValue, err = h.assembleChunks(mctx, entryID, entryValue, h.fetchEntryLocked)
+	if err != nil {
+		return res, err
+	}
+	return keybase1.KVGetResult{
+		TeamName:   arg.TeamName,
+		Namespace:  arg.Namespace,
+		EntryKey:   arg.EntryKey,
+		EntryValue: entryValue,
+		Revision:   revision,
+		Writer:     writer,
+	}, nil
+}
+
+// fetchEntryLocked fetches, checks and decrypts a single entry exactly as it's
+// stored on the server, so a chunked value comes back as its manifest.
+func (h *KVStoreHandler) fetchEntryLocked(mctx libkb.MetaContext, entryID keybase1.KVEntryID) (entryValue *string, revision int, err error) {
+	entryValue, revision, _, err = h.fetchEntryWithWriterLocked(mctx, entryID)
+	return entryValue, revision, err
+}
+
+// fetchEntryWithWriterLocked is fetchEntryLocked, but also returns who wrote
+// the entry, once their signature on it has been checked. The writer is nil
+// if the entry has no value.
+func (h *KVStoreHandler) fetchEntryWithWriterLocked(mctx libkb.MetaContext, entryID keybase1.KVEntryID) (entryValue *string, revision int, writer *keybase1.UserVersion, err error) {
 	apiRes, err := h.serverFetch(mctx, entryID)
 	if err != nil {
 		mctx.Debug("error fetching %+v from server: %v", entryID, err)
-		return res, err
+		return nil, 0, nil, err
 	}
 	// check the server response against the local cache
 	err = mctx.G().GetKVRevisionCache().Check(mctx, entryID, apiRes.Ciphertext, apiRes.TeamKeyGen, apiRes.Revision)
 	if err != nil {
 		err = fmt.Errorf("error comparing the entry from the server to what's in the local cache: %s", err)
 		mctx.Debug("%+v: %s", entryID, err)
-		return res, err
+		return nil, 0, nil, err
+	}
+	// and against the local mirror, which survives restarts
+	err = h.Mirror.Check(mctx, entryID, apiRes.Ciphertext, apiRes.TeamKeyGen, apiRes.Revision)
+	if err != nil {
+		err = fmt.Errorf("error comparing the entry from the server to what's in the local mirror: %s", err)
+		mctx.Debug("%+v: %s", entryID, err)
+		return nil, 0, nil, err
 	}
-	var entryValue *string
 	if apiRes.Ciphertext != nil && len(*apiRes.Ciphertext) > 0 {
 		// ciphertext coming back from the server is available to be unboxed (has previously been set, and was not previously deleted)
 		cleartext, err := h.Boxer.Unbox(mctx, entryID, apiRes.Revision, *apiRes.Ciphertext, apiRes.TeamKeyGen, apiRes.FormatVersion, apiRes.WriterUID, apiRes.WriterEldestSeqno, apiRes.WriterDeviceID)
 		if err != nil {
 			mctx.Debug("error unboxing %+v: %v", entryID, err)
-			return res, err
+			return nil, 0, nil, err
 		}
 		entryValue = &cleartext
+		writer = &keybase1.UserVersion{Uid: apiRes.WriterUID, EldestSeqno: apiRes.WriterEldestSeqno}
 	}
 	err = mctx.G().GetKVRevisionCache().Put(mctx, entryID, apiRes.Ciphertext, apiRes.TeamKeyGen, apiRes.Revision)
 	if err != nil {
 		err = fmt.Errorf("error putting newly fetched values into the local cache: %s", err)
 		mctx.Debug("%+v: %s", entryID, err)
-		return res, err
+		return nil, 0, nil, err
 	}
-	return keybase1.KVGetResult{
-		TeamName:   arg.TeamName,
-		Namespace:  arg.Namespace,
-		EntryKey:   arg.EntryKey,
-		EntryValue: entryValue,
-		Revision:   apiRes.Revision,
-	}, nil
+	h.mirrorPut(mctx, entryID, entryValue, apiRes.Ciphertext, apiRes.TeamKeyGen, apiRes.Revision)
+	return entryValue, apiRes.Revision, writer, nil
 }
 
 type putEntryAPIRes struct {
@@ -181,6 +355,9 @@ func (h *KVStoreHandler) putKVEntryLocked(ctx context.Context, arg keybase1.PutK
 		mctx.Debug("not logged in err: %v", err)
 		return res, err
 	}
+	if err := checkWritableEntry(arg.EntryKey, &arg.EntryValue); err != nil {
+		return res, err
+	}
 	teamID, err := h.resolveTeam(mctx, arg.TeamName)
 	if err != nil {
 		return res, err
@@ -191,21 +368,24 @@ func (h *KVStoreHandler) putKVEntryLocked(ctx context.Context, arg keybase1.PutK
 		EntryKey:  arg.EntryKey,
 	}
 
-	revision := arg.Revision
-	if revision == 0 {
-		// fetch to get the correct revision when it's not specified
-		getRes, err := h.getKVEntryLocked(ctx, keybase1.GetKVEntryArg{
-			SessionID: arg.SessionID,
-			TeamName:  arg.TeamName,
-			Namespace: arg.Namespace,
-			EntryKey:  arg.EntryKey,
-		})
-		if err != nil {
+	// fetch to get the correct revision when it's not specified, and to find
+	// any chunks that need to be replaced along with the entry
+	current, currentRevision, err := h.fetchEntryLocked(mctx, entryID)
+	if err != nil {
+		if arg.Revision == 0 {
 			err = fmt.Errorf("error fetching the revision before writing this entry: %s", err)
 			mctx.Debug("%+v: %s", entryID, err)
 			return res, err
 		}
-		revision = getRes.Revision + 1
+		mctx.Debug("unable to fetch %+v before writing it, so any chunks it has won't be cleaned up: %v", entryID, err)
+	}
+	revision := arg.Revision
+	if revision == 0 {
+		revision = currentRevision + 1
+	}
+	oldManifest := parseChunkManifest(current)
+	if len(arg.EntryValue) > kvChunkSize || oldManifest != nil {
+		return h.putChunkedLocked(mctx, arg, entryID, revision, oldManifest)
 	}
 
 	mctx.Debug("updating %+v to revision %d", entryID, revision)
@@ -249,6 +429,8 @@ func (h *KVStoreHandler) putKVEntryLocked(ctx context.Context, arg keybase1.PutK
 		mctx.Debug("%+v: %s", entryID, err)
 		return res, err
 	}
+	h.mirrorPut(mctx, entryID, &arg.EntryValue, &ciphertext, teamKeyGen, revision)
+	notifyLocalKVChange(mctx, entryID, arg.TeamName, revision, false)
 	return keybase1.KVPutResult{
 		TeamName:  arg.TeamName,
 		Namespace: arg.Namespace,
@@ -271,6 +453,9 @@ func (h *KVStoreHandler) delKVEntryLocked(ctx context.Context, arg keybase1.DelK
 		mctx.Debug("not logged in err: %v", err)
 		return res, err
 	}
+	if err := checkWritableEntry(arg.EntryKey, nil); err != nil {
+		return res, err
+	}
 	teamID, err := h.resolveTeam(mctx, arg.TeamName)
 	if err != nil {
 		return res, err
@@ -281,21 +466,23 @@ func (h *KVStoreHandler) delKVEntryLocked(ctx context.Context, arg keybase1.DelK
 		EntryKey:  arg.EntryKey,
 	}
 
-	revision := arg.Revision
-	if revision == 0 {
-		getArg := keybase1.GetKVEntryArg{
-			SessionID: arg.SessionID,
-			TeamName:  arg.TeamName,
-			Namespace: arg.Namespace,
-			EntryKey:  arg.EntryKey,
-		}
-		getRes, err := h.getKVEntryLocked(ctx, getArg)
-		if err != nil {
+	// fetch to get the correct revision when it's not specified, and to find
+	// any chunks that need to be deleted along with the entry
+	current, currentRevision, err := h.fetchEntryLocked(mctx, entryID)
+	if err != nil {
+		if arg.Revision == 0 {
 			err = fmt.Errorf("error fetching the revision before deleting this entry: %s", err)
 			mctx.Debug("%+v: %s", entryID, err)
 			return res, err
 		}
-		revision = getRes.Revision + 1
+		mctx.Debug("unable to fetch %+v before deleting it, so any chunks it has won't be cleaned up: %v", entryID, err)
+	}
+	revision := arg.Revision
+	if revision == 0 {
+		revision = currentRevision + 1
+	}
+	if manifest := parseChunkManifest(current); manifest != nil {
+		return h.delChunkedLocked(mctx, arg, entryID, revision, manifest)
 	}
 
 	mctx.Debug("deleting %+v at revision %d", entryID, revision)
@@ -335,6 +522,8 @@ func (h *KVStoreHandler) delKVEntryLocked(ctx context.Context, arg keybase1.DelK
 		mctx.Debug("%+v: %s", entryID, err)
 		return res, err
 	}
+	h.mirrorDelete(mctx, entryID, revision)
+	notifyLocalKVChange(mctx, entryID, arg.TeamName, revision, true)
 	return keybase1.KVDeleteEntryResult{
 		TeamName:  arg.TeamName,
 		Namespace: arg.Namespace,
@@ -443,6 +632,9 @@ func (h *KVStoreHandler) listKVEntriesLocked(ctx context.Context, arg keybase1.L
 	}
 	resKeys := []keybase1.KVListEntryKey{}
 	for _, ek := range apiRes.EntryKeys {
+		if isChunkKey(ek.EntryKey) {
+			continue
+		}
 		k := keybase1.KVListEntryKey{EntryKey: ek.EntryKey, Revision: ek.Revision}
 		resKeys = append(resKeys, k)
 	}
@@ -452,3 +644,291 @@ func (h *KVStoreHandler) listKVEntriesLocked(ctx context.Context, arg keybase1.L
 		EntryKeys: resKeys,
 	}, nil
 }
+
+// kvBatchMaxEntries is the largest number of entries the client will write in
+// a single batch. It keeps one batch request to a bounded size, and with
+// kvChunkSize it bounds how large a chunked value can get.
+const kvBatchMaxEntries = 100
+
+type batchEntryAPIArg struct {
+	Namespace         string                        `json:"namespace"`
+	EntryKey          string                        `json:"entry_key"`
+	Revision          int                           `json:"revision"`
+	Ciphertext        *string                       `json:"ciphertext"` // nil deletes the entry
+	TeamKeyGen        keybase1.PerTeamKeyGeneration `json:"team_key_gen,omitempty"`
+	CiphertextVersion int                           `json:"ciphertext_version,omitempty"`
+}
+
+type batchEntryAPIRes struct {
+	Namespace string `json:"namespace"`
+	EntryKey  string `json:"entry_key"`
+	Revision  int    `json:"revision"`
+}
+
+type batchEntriesAPIRes struct {
+	libkb.AppStatusEmbed
+	TeamID  keybase1.TeamID    `json:"team_id"`
+	Entries []batchEntryAPIRes `json:"entries"`
+}
+
+func (h *KVStoreHandler) BatchKVEntries(ctx context.Context, arg keybase1.BatchKVEntriesArg) (res keybase1.KVBatchResult, err error) {
+	for _, entry := range arg.Entries {
+		if err := checkWritableEntry(entry.EntryKey, entry.EntryValue); err != nil {
+			return res, err
+		}
+		if entry.EntryValue != nil && len(*entry.EntryValue) > kvChunkSize {
+			return res, fmt.Errorf("entry %s/%s is too large for a batch (at most %d bytes); put it on its own", entry.Namespace, entry.EntryKey, kvChunkSize)
+		}
+	}
+	h.Lock()
+	defer h.Unlock()
+	return h.batchKVEntriesLocked(ctx, arg)
+}
+
+func (h *KVStoreHandler) batchKVEntriesLocked(ctx context.Context, arg keybase1.BatchKVEntriesArg) (res keybase1.KVBatchResult, err error) {
+	ctx = libkb.WithLogTag(ctx, "KV")
+	mctx := libkb.NewMetaContext(ctx, h.G())
+	defer mctx.Trace(fmt.Sprintf("KVStoreHandler#BatchKVEntries: t:%s, entries:%d", arg.TeamName, len(arg.Entries)), &err)()
+	if err := assertLoggedIn(ctx, h.G()); err != nil {
+		mctx.Debug("not logged in err: %v", err)
+		return res, err
+	}
+	if len(arg.Entries) == 0 {
+		return res, fmt.Errorf("a batch needs at least one entry")
+	}
+	if len(arg.Entries) > kvBatchMaxEntries {
+		return res, fmt.Errorf("a batch can have at most %d entries, got %d", kvBatchMaxEntries, len(arg.Entries))
+	}
+	seen := make(map[keybase1.KVEntryID]bool)
+	teamID, err := h.resolveTeam(mctx, arg.TeamName)
+	if err != nil {
+		return res, err
+	}
+
+	// Box every entry and check its revision against the local cache before
+	// sending anything to the server, so that a bad entry anywhere in the
+	// batch means nothing gets written.
+	entryIDs := make([]keybase1.KVEntryID, len(arg.Entries))
+	apiEntries := make([]batchEntryAPIArg, len(arg.Entries))
+	for i, entry := range arg.Entries {
+		entryID := keybase1.KVEntryID{
+			TeamID:    teamID,
+			Namespace: entry.Namespace,
+			EntryKey:  entry.EntryKey,
+		}
+		if seen[entryID] {
+			return res, fmt.Errorf("entry %s/%s appears more than once in the batch", entry.Namespace, entry.EntryKey)
+		}
+		seen[entryID] = true
+
+		revision := entry.Revision
+		if revision == 0 {
+			current, currentRevision, err := h.fetchEntryLocked(mctx, entryID)
+			if err != nil {
+				err = fmt.Errorf("error fetching the revision before writing this batch: %s", err)
+				mctx.Debug("%+v: %s", entryID, err)
+				return res, err
+			}
+			if parseChunkManifest(current) != nil {
+				return res, fmt.Errorf("entry %s/%s has a chunked value; put or delete it on its own", entry.Namespace, entry.EntryKey)
+			}
+			revision = currentRevision + 1
+		}
+		err = mctx.G().GetKVRevisionCache().CheckForUpdate(mctx, entryID, revision)
+		if err != nil {
+			mctx.Debug("error from cache for updating %+v: %s", entryID, err)
+			return res, err
+		}
+		apiEntry := batchEntryAPIArg{
+			Namespace: entry.Namespace,
+			EntryKey:  entry.EntryKey,
+			Revision:  revision,
+		}
+		if entry.EntryValue != nil {
+			mctx.Debug("batch updating %+v to revision %d", entryID, revision)
+			ciphertext, teamKeyGen, ciphertextVersion, err := h.Boxer.Box(mctx, entryID, revision, *entry.EntryValue)
+			if err != nil {
+				mctx.Debug("error boxing %+v: %v", entryID, err)
+				return res, err
+			}
+			apiEntry.Ciphertext = &ciphertext
+			apiEntry.TeamKeyGen = teamKeyGen
+			apiEntry.CiphertextVersion = ciphertextVersion
+		} else {
+			mctx.Debug("batch deleting %+v at revision %d", entryID, revision)
+		}
+		entryIDs[i] = entryID
+		apiEntries[i] = apiEntry
+	}
+
+	payload := make(libkb.JSONPayload)
+	payload["team_id"] = teamID.String()
+	payload["entries"] = apiEntries
+	apiArg := libkb.APIArg{
+		Endpoint:    "team/storage/batch",
+		SessionType: libkb.APISessionTypeREQUIRED,
+		JSONPayload: payload,
+	}
+	var apiRes batchEntriesAPIRes
+	err = mctx.G().API.PostDecode(mctx, apiArg, &apiRes)
+	if apiErr, ok := err.(*libkb.APIError); ok && apiErr.Code == http.StatusNotFound {
+		mctx.Debug("the server doesn't know team/storage/batch: %v", err)
+		return res, KVBatchUnsupportedError{}
+	}
+	if err != nil {
+		mctx.Debug("error posting batch for team %s to the server: %v", teamID, err)
+		return res, err
+	}
+	if apiRes.TeamID != teamID {
+		return res, fmt.Errorf("expected teamID %s from the server, got %s", teamID, apiRes.TeamID)
+	}
+	if len(apiRes.Entries) != len(apiEntries) {
+		return res, fmt.Errorf("kvstore BATCH error. expected %d entries from the server, got %d", len(apiEntries), len(apiRes.Entries))
+	}
+	for i, apiEntry := range apiEntries {
+		resEntry := apiRes.Entries[i]
+		if resEntry.Namespace != apiEntry.Namespace || resEntry.EntryKey != apiEntry.EntryKey {
+			return res, fmt.Errorf("kvstore BATCH error. expected entry %s/%s at position %d, got %s/%s",
+				apiEntry.Namespace, apiEntry.EntryKey, i, resEntry.Namespace, resEntry.EntryKey)
+		}
+		if resEntry.Revision != apiEntry.Revision {
+			mctx.Debug("expected the server to return revision %d but got %d for %+v", apiEntry.Revision, resEntry.Revision, entryIDs[i])
+			return res, fmt.Errorf("kvstore BATCH revision error. expected %d, got %d", apiEntry.Revision, resEntry.Revision)
+		}
+	}
+
+	// the server committed every entry, so it's safe to update the cache
+	results := make([]keybase1.KVBatchEntryResult, len(apiEntries))
+	for i, apiEntry := range apiEntries {
+		if apiEntry.Ciphertext == nil {
+			err = mctx.G().GetKVRevisionCache().MarkDeleted(mctx, entryIDs[i], apiEntry.Revision)
+		} else {
+			err = mctx.G().GetKVRevisionCache().Put(mctx, entryIDs[i], apiEntry.Ciphertext, apiEntry.TeamKeyGen, apiEntry.Revision)
+		}
+		if err != nil {
+			err = fmt.Errorf("error caching this batch (try fetching its entries again): %s", err)
+			mctx.Debug("%+v: %s", entryIDs[i], err)
+			return res, err
+		}
+		if apiEntry.Ciphertext == nil {
+			h.mirrorDelete(mctx, entryIDs[i], apiEntry.Revision)
+		} else {
+			h.mirrorPut(mctx, entryIDs[i], arg.Entries[i].EntryValue, apiEntry.Ciphertext, apiEntry.TeamKeyGen, apiEntry.Revision)
+		}
+		results[i] = keybase1.KVBatchEntryResult{
+			Namespace: apiEntry.Namespace,
+			EntryKey:  apiEntry.EntryKey,
+			Revision:  apiEntry.Revision,
+			Deleted:   apiEntry.Ciphertext == nil,
+		}
+	}
+	for i, apiEntry := range apiEntries {
+		// chunks are an implementation detail of the entry that owns them
+		if isChunkKey(apiEntry.EntryKey) {
+			continue
+		}
+		notifyLocalKVChange(mctx, entryIDs[i], arg.TeamName, apiEntry.Revision, apiEntry.Ciphertext == nil)
+	}
+	return keybase1.KVBatchResult{
+		TeamName: arg.TeamName,
+		Results:  results,
+	}, nil
+}
+
+// kvStoreUpdateSystem is the gregor out-of-band system the server uses to tell
+// every member of a team that one of the team's entries was written.
+const kvStoreUpdateSystem = "kvstore.update"
+
+type kvStoreUpdateMsg struct {
+	TeamID    keybase1.TeamID   `json:"team_id"`
+	Namespace string            `json:"namespace"`
+	EntryKey  string            `json:"entry_key"`
+	Revision  int               `json:"revision"`
+	Deleted   bool              `json:"deleted"`
+	WriterUID keybase1.UID      `json:"uid"`
+	DeviceID  keybase1.DeviceID `json:"device_id"`
+}
+
+func kvStoreTeamDisplayName(mctx libkb.MetaContext, teamID keybase1.TeamID) (string, error) {
+	team, err := teams.Load(mctx.Ctx(), mctx.G(), keybase1.LoadTeamArg{
+		ID:     teamID,
+		Public: teamID.IsPublic(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if team.IsImplicit() {
+		// matches the "alice,bob" form that the kvstore API takes
+		return team.ImplicitTeamDisplayNameString(mctx.Ctx())
+	}
+	return team.Name().String(), nil
+}
+
+// notifyLocalKVChange tells subscribed clients about a write this device just
+// made. The server's kvstore.update messages for this device's own writes are
+// dropped in handleKVStoreUpdate, so this is the only notification for them.
+func notifyLocalKVChange(mctx libkb.MetaContext, entryID keybase1.KVEntryID, teamName string, revision int, deleted bool) {
+	if displayName, err := kvStoreTeamDisplayName(mctx, entryID.TeamID); err == nil {
+		teamName = displayName
+	} else {
+		mctx.Debug("error loading team %s for a kvstore notification, using %q: %v", entryID.TeamID, teamName, err)
+	}
+	changeType := keybase1.KVEntryChangeType_PUT
+	if deleted {
+		changeType = keybase1.KVEntryChangeType_DELETE
+	}
+	mctx.G().NotifyRouter.HandleKVStoreEntryChanged(mctx.Ctx(), keybase1.KVEntryChange{
+		ChangeType:     changeType,
+		TeamID:         entryID.TeamID,
+		TeamName:       teamName,
+		Namespace:      entryID.Namespace,
+		EntryKey:       entryID.EntryKey,
+		Revision:       revision,
+		WriterUID:      mctx.ActiveDevice().UID(),
+		WriterUsername: mctx.ActiveDevice().Username(mctx).String(),
+		WriterDeviceID: mctx.ActiveDevice().DeviceID(),
+	})
+}
+
+// handleKVStoreUpdate relays a kvstore.update message from the server to any
+// clients subscribed to kvstore notifications. The change is only a hint; the
+// entry itself isn't fetched or verified here.
+func handleKVStoreUpdate(mctx libkb.MetaContext, body []byte) (err error) {
+	mctx = mctx.WithLogTag("KV")
+	defer mctx.Trace("handleKVStoreUpdate", &err)()
+	var msg kvStoreUpdateMsg
+	if err := json.Unmarshal(body, &msg); err != nil {
+		mctx.Debug("error unmarshaling kvstore.update message: %v", err)
+		return err
+	}
+	if msg.DeviceID.Eq(mctx.ActiveDevice().DeviceID()) {
+		mctx.Debug("skipping kvstore.update for a write from this device, which was already notified")
+		return nil
+	}
+	teamName, err := kvStoreTeamDisplayName(mctx, msg.TeamID)
+	if err != nil {
+		mctx.Debug("error loading team %s for a kvstore update: %v", msg.TeamID, err)
+		return err
+	}
+	writer, err := mctx.G().GetUPAKLoader().LookupUsername(mctx.Ctx(), msg.WriterUID)
+	if err != nil {
+		mctx.Debug("error looking up writer %s for a kvstore update: %v", msg.WriterUID, err)
+		return err
+	}
+	changeType := keybase1.KVEntryChangeType_PUT
+	if msg.Deleted {
+		changeType = keybase1.KVEntryChangeType_DELETE
+	}
+	mctx.G().NotifyRouter.HandleKVStoreEntryChanged(mctx.Ctx(), keybase1.KVEntryChange{
+		ChangeType:     changeType,
+		TeamID:         msg.TeamID,
+		TeamName:       teamName,
+		Namespace:      msg.Namespace,
+		EntryKey:       msg.EntryKey,
+		Revision:       msg.Revision,
+		WriterUID:      msg.WriterUID,
+		WriterUsername: writer.String(),
+		WriterDeviceID: msg.DeviceID,
+	})
+	return nil
+}
@@ -0,0 +1,470 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/adamwalz/keybase-client/go/kbtest"
+	"github.com/adamwalz/keybase-client/go/kvstore"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/adamwalz/keybase-client/go/teams"
+	jsonw "github.com/keybase/go-jsonw"
+	"github.com/stretchr/testify/require"
+)
+
+type kvFakeEntry struct {
+	revision          int
+	ciphertext        *string
+	teamKeyGen        keybase1.PerTeamKeyGeneration
+	ciphertextVersion int
+	uid               keybase1.UID
+	eldestSeqno       keybase1.Seqno
+	deviceID          keybase1.DeviceID
+}
+
+// kvFakeServer is a local, in-memory stand-in for the team/storage endpoints,
+// so that tests can control exactly what the server commits and returns.
+// Every other endpoint is passed through to the real API.
+type kvFakeServer struct {
+	libkb.API
+	sync.Mutex
+	entries    map[keybase1.KVEntryID]kvFakeEntry
+	batchCalls int
+	// mutateBatchRes, if set, can tamper with a committed batch's response
+	mutateBatchRes func(res *batchEntriesAPIRes)
+	// noBatch makes the batch endpoint 404, like a server without it
+	noBatch bool
+}
+
+var _ libkb.API = (*kvFakeServer)(nil)
+
+func newKVFakeServer(real libkb.API) *kvFakeServer {
+	return &kvFakeServer{
+		API:     real,
+		entries: make(map[keybase1.KVEntryID]kvFakeEntry),
+	}
+}
+
+func (f *kvFakeServer) entryID(arg libkb.APIArg) keybase1.KVEntryID {
+	return keybase1.KVEntryID{
+		TeamID:    keybase1.TeamID(arg.Args["team_id"].String()),
+		Namespace: arg.Args["namespace"].String(),
+		EntryKey:  arg.Args["entry_key"].String(),
+	}
+}
+
+func (f *kvFakeServer) revisionError(expected, got int) error {
+	return kvstore.NewKVRevisionError(fmt.Sprintf("expected revision %d but got %d", expected, got))
+}
+
+func (f *kvFakeServer) writeLocked(mctx libkb.MetaContext, entryID keybase1.KVEntryID, revision int, ciphertext *string,
+	teamKeyGen keybase1.PerTeamKeyGeneration, ciphertextVersion int) {
+	uv, deviceID, _, _, _ := mctx.G().ActiveDevice.AllFields()
+	f.entries[entryID] = kvFakeEntry{
+		revision:          revision,
+		ciphertext:        ciphertext,
+		teamKeyGen:        teamKeyGen,
+		ciphertextVersion: ciphertextVersion,
+		uid:               uv.Uid,
+		eldestSeqno:       uv.EldestSeqno,
+		deviceID:          deviceID,
+	}
+}
+
+func (f *kvFakeServer) GetDecode(mctx libkb.MetaContext, arg libkb.APIArg, w libkb.APIResponseWrapper) error {
+	if arg.Endpoint != "team/storage" {
+		return f.API.GetDecode(mctx, arg, w)
+	}
+	f.Lock()
+	defer f.Unlock()
+	entryID := f.entryID(arg)
+	entry := f.entries[entryID]
+	res := w.(*getEntryAPIRes)
+	*res = getEntryAPIRes{
+		TeamID:            entryID.TeamID,
+		Namespace:         entryID.Namespace,
+		EntryKey:          entryID.EntryKey,
+		TeamKeyGen:        entry.teamKeyGen,
+		Revision:          entry.revision,
+		Ciphertext:        entry.ciphertext,
+		FormatVersion:     entry.ciphertextVersion,
+		WriterUID:         entry.uid,
+		WriterEldestSeqno: entry.eldestSeqno,
+		WriterDeviceID:    entry.deviceID,
+	}
+	return nil
+}
+
+func (f *kvFakeServer) PostDecode(mctx libkb.MetaContext, arg libkb.APIArg, w libkb.APIResponseWrapper) error {
+	switch arg.Endpoint {
+	case "team/storage":
+		return f.put(mctx, arg, w)
+	case "team/storage/batch":
+		return f.batch(mctx, arg, w)
+	default:
+		return f.API.PostDecode(mctx, arg, w)
+	}
+}
+
+func (f *kvFakeServer) put(mctx libkb.MetaContext, arg libkb.APIArg, w libkb.APIResponseWrapper) error {
+	f.Lock()
+	defer f.Unlock()
+	entryID := f.entryID(arg)
+	revision := arg.Args["revision"].(libkb.I).Val
+	expected := f.entries[entryID].revision + 1
+	if revision != expected {
+		return f.revisionError(expected, revision)
+	}
+	ciphertext := arg.Args["ciphertext"].String()
+	teamKeyGen := keybase1.PerTeamKeyGeneration(arg.Args["team_key_gen"].(libkb.I).Val)
+	f.writeLocked(mctx, entryID, revision, &ciphertext, teamKeyGen, arg.Args["ciphertext_version"].(libkb.I).Val)
+	res := w.(*putEntryAPIRes)
+	res.Revision = revision
+	return nil
+}
+
+func (f *kvFakeServer) Delete(mctx libkb.MetaContext, arg libkb.APIArg) (*libkb.APIRes, error) {
+	if arg.Endpoint != "team/storage" {
+		return f.API.Delete(mctx, arg)
+	}
+	f.Lock()
+	defer f.Unlock()
+	entryID := f.entryID(arg)
+	revision := arg.Args["revision"].(libkb.I).Val
+	existing := f.entries[entryID]
+	if existing.ciphertext == nil {
+		return nil, libkb.AppStatusError{Code: libkb.SCTeamStorageNotFound, Desc: "entry not found"}
+	}
+	if revision != existing.revision+1 {
+		return nil, f.revisionError(existing.revision+1, revision)
+	}
+	f.writeLocked(mctx, entryID, revision, nil, existing.teamKeyGen, 0)
+	body := jsonw.NewDictionary()
+	if err := body.SetKey("revision", jsonw.NewInt(revision)); err != nil {
+		return nil, err
+	}
+	return &libkb.APIRes{Body: body}, nil
+}
+
+func (f *kvFakeServer) batch(mctx libkb.MetaContext, arg libkb.APIArg, w libkb.APIResponseWrapper) error {
+	f.Lock()
+	defer f.Unlock()
+	f.batchCalls++
+	if f.noBatch {
+		return &libkb.APIError{Msg: "404 Not Found", Code: http.StatusNotFound}
+	}
+	teamID := keybase1.TeamID(arg.JSONPayload["team_id"].(string))
+	apiEntries := arg.JSONPayload["entries"].([]batchEntryAPIArg)
+	// check everything before committing anything
+	for _, e := range apiEntries {
+		entryID := keybase1.KVEntryID{TeamID: teamID, Namespace: e.Namespace, EntryKey: e.EntryKey}
+		existing := f.entries[entryID]
+		if e.Ciphertext == nil && existing.ciphertext == nil {
+			return libkb.AppStatusError{Code: libkb.SCTeamStorageNotFound, Desc: "entry not found"}
+		}
+		if e.Revision != existing.revision+1 {
+			return f.revisionError(existing.revision+1, e.Revision)
+		}
+	}
+	res := w.(*batchEntriesAPIRes)
+	res.TeamID = teamID
+	res.Entries = nil
+	for _, e := range apiEntries {
+		entryID := keybase1.KVEntryID{TeamID: teamID, Namespace: e.Namespace, EntryKey: e.EntryKey}
+		teamKeyGen := e.TeamKeyGen
+		if e.Ciphertext == nil {
+			teamKeyGen = f.entries[entryID].teamKeyGen
+		}
+		f.writeLocked(mctx, entryID, e.Revision, e.Ciphertext, teamKeyGen, e.CiphertextVersion)
+		res.Entries = append(res.Entries, batchEntryAPIRes{
+			Namespace: e.Namespace,
+			EntryKey:  e.EntryKey,
+			Revision:  e.Revision,
+		})
+	}
+	if f.mutateBatchRes != nil {
+		f.mutateBatchRes(res)
+	}
+	return nil
+}
+
+func (f *kvFakeServer) numBatchCalls() int {
+	f.Lock()
+	defer f.Unlock()
+	return f.batchCalls
+}
+
+func (f *kvFakeServer) revision(entryID keybase1.KVEntryID) int {
+	f.Lock()
+	defer f.Unlock()
+	return f.entries[entryID].revision
+}
+
+// kvFailingBoxer fails to box any entry with the given key
+type kvFailingBoxer struct {
+	kvstore.KVStoreBoxer
+	failKey string
+}
+
+func (b *kvFailingBoxer) Box(mctx libkb.MetaContext, entryID keybase1.KVEntryID, revision int, cleartextValue string) (ciphertext string,
+	teamKeyGen keybase1.PerTeamKeyGeneration, ciphertextVersion int, err error) {
+	if entryID.EntryKey == b.failKey {
+		return "", 0, 0, errors.New("fake boxing failure")
+	}
+	return b.KVStoreBoxer.Box(mctx, entryID, revision, cleartextValue)
+}
+
+func kvBatchTestSetup(t *testing.T) (tc libkb.TestContext, handler *KVStoreHandler, fake *kvFakeServer, teamName string, teamID keybase1.TeamID) {
+	tc = kvTestSetup(t)
+	user, err := kbtest.CreateAndSignupFakeUser("kvb", tc.G)
+	require.NoError(t, err)
+	teamName = user.Username + "t"
+	id, err := teams.CreateRootTeam(context.Background(), tc.G, teamName, keybase1.TeamSettings{})
+	require.NoError(t, err)
+	require.NotNil(t, id)
+	fake = newKVFakeServer(tc.G.API)
+	tc.G.API = fake
+	return tc, NewKVStoreHandler(nil, tc.G), fake, teamName, *id
+}
+
+func kvBatchPut(namespace, entryKey string, revision int, value string) keybase1.KVBatchEntry {
+	return keybase1.KVBatchEntry{
+		Namespace:  namespace,
+		EntryKey:   entryKey,
+		Revision:   revision,
+		EntryValue: &value,
+	}
+}
+
+func kvBatchDel(namespace, entryKey string, revision int) keybase1.KVBatchEntry {
+	return keybase1.KVBatchEntry{
+		Namespace: namespace,
+		EntryKey:  entryKey,
+		Revision:  revision,
+	}
+}
+
+func TestKVBatchPutGetDelete(t *testing.T) {
+	tc, handler, fake, teamName, _ := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	namespace := "bot-state"
+
+	res, err := handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries: []keybase1.KVBatchEntry{
+			kvBatchPut(namespace, "index", 0, "item-1,item-2"),
+			kvBatchPut(namespace, "item-1", 0, "first"),
+			kvBatchPut(namespace, "item-2", 1, "second"),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, teamName, res.TeamName)
+	require.Len(t, res.Results, 3)
+	for _, r := range res.Results {
+		require.Equal(t, 1, r.Revision)
+		require.False(t, r.Deleted)
+	}
+	require.Equal(t, 1, fake.numBatchCalls())
+
+	expected := map[string]string{"index": "item-1,item-2", "item-1": "first", "item-2": "second"}
+	for entryKey, value := range expected {
+		getRes, err := handler.GetKVEntry(ctx, keybase1.GetKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: entryKey})
+		require.NoError(t, err)
+		require.Equal(t, value, *getRes.EntryValue)
+		require.Equal(t, 1, getRes.Revision)
+	}
+	t.Logf("a batch of puts is readable entry by entry")
+
+	res, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries: []keybase1.KVBatchEntry{
+			kvBatchPut(namespace, "index", 0, "item-1,item-3"),
+			kvBatchDel(namespace, "item-2", 0),
+			kvBatchPut(namespace, "item-3", 0, "third"),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []keybase1.KVBatchEntryResult{
+		{Namespace: namespace, EntryKey: "index", Revision: 2},
+		{Namespace: namespace, EntryKey: "item-2", Revision: 2, Deleted: true},
+		{Namespace: namespace, EntryKey: "item-3", Revision: 1},
+	}, res.Results)
+	getRes, err := handler.GetKVEntry(ctx, keybase1.GetKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "item-2"})
+	require.NoError(t, err)
+	require.Nil(t, getRes.EntryValue)
+	require.Equal(t, 2, getRes.Revision)
+	getRes, err = handler.GetKVEntry(ctx, keybase1.GetKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "index"})
+	require.NoError(t, err)
+	require.Equal(t, "item-1,item-3", *getRes.EntryValue)
+	t.Logf("a batch can mix puts and deletes")
+}
+
+func TestKVBatchConflict(t *testing.T) {
+	tc, handler, fake, teamName, teamID := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	namespace := "bot-state"
+	indexID := keybase1.KVEntryID{TeamID: teamID, Namespace: namespace, EntryKey: "index"}
+	itemID := keybase1.KVEntryID{TeamID: teamID, Namespace: namespace, EntryKey: "item-1"}
+
+	_, err := handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "index", EntryValue: "v1"})
+	require.NoError(t, err)
+	// someone else writes the index, so our view of it is now stale
+	_, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "index", EntryValue: "v2"})
+	require.NoError(t, err)
+	tc.G.SetKVRevisionCache(kvstore.NewKVRevisionCache(tc.G))
+
+	_, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries: []keybase1.KVBatchEntry{
+			kvBatchPut(namespace, "item-1", 0, "first"),
+			kvBatchPut(namespace, "index", 2, "item-1"),
+		},
+	})
+	assertRevisionError(t, err, "server")
+	require.Equal(t, 0, fake.revision(itemID), "nothing in a rejected batch is committed")
+	require.Equal(t, 2, fake.revision(indexID))
+	_, _, revision := tc.G.GetKVRevisionCache().(*kvstore.KVRevisionCache).Inspect(itemID)
+	require.Equal(t, 0, revision, "nothing in a rejected batch is cached")
+	t.Logf("a server-side revision conflict on one entry rejects the whole batch")
+
+	// deleting an entry that doesn't exist also rejects the batch
+	_, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries: []keybase1.KVBatchEntry{
+			kvBatchPut(namespace, "item-1", 1, "first"),
+			kvBatchDel(namespace, "item-2", 1),
+		},
+	})
+	require.Error(t, err)
+	require.IsType(t, libkb.AppStatusError{}, err)
+	require.Equal(t, libkb.SCTeamStorageNotFound, err.(libkb.AppStatusError).Code)
+	require.Equal(t, 0, fake.revision(itemID))
+
+	// the cache catches revisions it knows to be stale without asking the server
+	_, err = handler.GetKVEntry(ctx, keybase1.GetKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "index"})
+	require.NoError(t, err)
+	callsBefore := fake.numBatchCalls()
+	_, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries: []keybase1.KVBatchEntry{
+			kvBatchPut(namespace, "item-1", 1, "first"),
+			kvBatchPut(namespace, "index", 2, "item-1"),
+		},
+	})
+	assertRevisionError(t, err, "cache")
+	require.Equal(t, callsBefore, fake.numBatchCalls())
+
+	// retrying at the right revision commits everything
+	res, err := handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries: []keybase1.KVBatchEntry{
+			kvBatchPut(namespace, "item-1", 1, "first"),
+			kvBatchPut(namespace, "index", 3, "item-1"),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Results[0].Revision)
+	require.Equal(t, 3, res.Results[1].Revision)
+	require.Equal(t, 1, fake.revision(itemID))
+	require.Equal(t, 3, fake.revision(indexID))
+}
+
+func TestKVBatchPartialFailure(t *testing.T) {
+	tc, handler, fake, teamName, teamID := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	namespace := "bot-state"
+	itemID := keybase1.KVEntryID{TeamID: teamID, Namespace: namespace, EntryKey: "item-1"}
+	entries := []keybase1.KVBatchEntry{
+		kvBatchPut(namespace, "item-1", 1, "first"),
+		kvBatchPut(namespace, "item-2", 1, "second"),
+	}
+
+	// failing to box any entry means nothing gets sent
+	handler.Boxer = &kvFailingBoxer{KVStoreBoxer: kvstore.NewKVStoreBoxer(tc.G), failKey: "item-2"}
+	_, err := handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{TeamName: teamName, Entries: entries})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "fake boxing failure")
+	require.Equal(t, 0, fake.numBatchCalls())
+	require.Equal(t, 0, fake.revision(itemID))
+	handler.Boxer = kvstore.NewKVStoreBoxer(tc.G)
+	t.Logf("a client-side failure on one entry sends nothing to the server")
+
+	// invalid batches are rejected before anything is sent
+	_, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries:  append(entries, kvBatchPut(namespace, "item-1", 1, "again")),
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than once")
+	_, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{TeamName: teamName})
+	require.Error(t, err)
+	require.Equal(t, 0, fake.numBatchCalls())
+
+	// a server response that doesn't confirm every entry is an error, and
+	// none of the batch is cached
+	fake.mutateBatchRes = func(res *batchEntriesAPIRes) {
+		res.Entries[1].Revision++
+	}
+	_, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{TeamName: teamName, Entries: entries})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "kvstore BATCH revision error")
+	_, _, revision := tc.G.GetKVRevisionCache().(*kvstore.KVRevisionCache).Inspect(itemID)
+	require.Equal(t, 0, revision)
+
+	fake.mutateBatchRes = func(res *batchEntriesAPIRes) {
+		res.Entries = res.Entries[:1]
+	}
+	_, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries: []keybase1.KVBatchEntry{
+			kvBatchPut(namespace, "item-1", 2, "first"),
+			kvBatchPut(namespace, "item-2", 2, "second"),
+		},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expected 2 entries from the server, got 1")
+	_, _, revision = tc.G.GetKVRevisionCache().(*kvstore.KVRevisionCache).Inspect(itemID)
+	require.Equal(t, 0, revision)
+	t.Logf("an incomplete server response doesn't update the cache")
+
+	// once the server behaves, fetching catches the cache up
+	fake.mutateBatchRes = nil
+	getRes, err := handler.GetKVEntry(ctx, keybase1.GetKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "item-1"})
+	require.NoError(t, err)
+	require.Equal(t, 2, getRes.Revision)
+	require.Equal(t, "first", *getRes.EntryValue)
+}
+
+func TestKVBatchWithoutServerSupport(t *testing.T) {
+	tc, handler, fake, teamName, teamID := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	namespace := "bot-state"
+	fake.noBatch = true
+
+	_, err := handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries: []keybase1.KVBatchEntry{
+			kvBatchPut(namespace, "index", 0, "item-1,item-2"),
+			kvBatchPut(namespace, "item-1", 0, "first"),
+		},
+	})
+	require.Error(t, err)
+	require.IsType(t, KVBatchUnsupportedError{}, err)
+	require.Equal(t, 1, fake.numBatchCalls())
+	t.Logf("without a batch endpoint, nothing gets written one entry at a time")
+	for _, entryKey := range []string{"index", "item-1"} {
+		entryID := keybase1.KVEntryID{TeamID: teamID, Namespace: namespace, EntryKey: entryKey}
+		require.Equal(t, 0, fake.revision(entryID))
+		_, _, revision := tc.G.GetKVRevisionCache().(*kvstore.KVRevisionCache).Inspect(entryID)
+		require.Equal(t, 0, revision)
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2019 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+// Large kvstore values are split into chunk entries, which are written
+// atomically along with a manifest entry under the key the caller asked for.
+
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+)
+
+const (
+	// kvChunkSize is the largest value stored in a single entry; anything
+	// bigger gets chunked.
+	kvChunkSize = 64 * 1024
+	// kvMaxChunks is as many chunks as fit in a single batch along with the
+	// manifest and the deletes of the chunks of a value of the same size
+	// being replaced.
+	kvMaxChunks      = (kvBatchMaxEntries - 1) / 2
+	kvMaxChunkedSize = kvChunkSize * kvMaxChunks
+
+	// kvChunkKeyPrefix starts the entry key of every chunk, which keeps them
+	// out of entry listings.
+	kvChunkKeyPrefix = "_kvchunk/"
+	// kvChunkManifestPrefix marks a manifest entry's value.
+	kvChunkManifestPrefix = "\x00kvstore.chunks.v1\x00"
+)
+
+type kvChunkRef struct {
+	EntryKey string `json:"entry_key"`
+	Revision int    `json:"revision"`
+	Hash     string `json:"hash"`
+}
+
+type kvChunkManifest struct {
+	Size   int          `json:"size"`
+	Hash   string       `json:"hash"`
+	Chunks []kvChunkRef `json:"chunks"`
+}
+
+func kvChunkHash(value string) string {
+	b := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(b[:])
+}
+
+func isChunkKey(entryKey string) bool {
+	return strings.HasPrefix(entryKey, kvChunkKeyPrefix)
+}
+
+// Chunk keys include the manifest's revision, so a new value's chunks never
+// collide with the ones it replaces and can always be written at revision 1.
+func chunkKey(entryKey string, revision int, i int) string {
+	return fmt.Sprintf("%s%s/%d/%d", kvChunkKeyPrefix, entryKey, revision, i)
+}
+
+// checkWritableEntry rejects entries that callers could use to forge or
+// clobber chunks. entryValue is nil for deletes.
+func checkWritableEntry(entryKey string, entryValue *string) error {
+	if isChunkKey(entryKey) {
+		return fmt.Errorf("entry keys starting with %q are reserved", kvChunkKeyPrefix)
+	}
+	if entryValue == nil {
+		return nil
+	}
+	if strings.HasPrefix(*entryValue, kvChunkManifestPrefix) {
+		return fmt.Errorf("entry values starting with %q are reserved", kvChunkManifestPrefix)
+	}
+	if len(*entryValue) > kvMaxChunkedSize {
+		return fmt.Errorf("entry value is too large: %d bytes, the limit is %d", len(*entryValue), kvMaxChunkedSize)
+	}
+	return nil
+}
+
+// parseChunkManifest returns nil if the value isn't a manifest.
+func parseChunkManifest(entryValue *string) *kvChunkManifest {
+	if entryValue == nil || !strings.HasPrefix(*entryValue, kvChunkManifestPrefix) {
+		return nil
+	}
+	var manifest kvChunkManifest
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(*entryValue, kvChunkManifestPrefix)), &manifest); err != nil {
+		// only this code writes the prefix, so treat it as a manifest that
+		// can't be reassembled rather than as a plain value
+		return &kvChunkManifest{Size: -1}
+	}
+	return &manifest
+}
+
+// splitChunks splits a value into chunks of at most kvChunkSize bytes,
+// without breaking up any UTF-8 characters.
+func splitChunks(value string) (chunks []string) {
+	for len(value) > 0 {
+		end := kvChunkSize
+		if end >= len(value) {
+			end = len(value)
+		} else {
+			for end > 0 && !utf8.RuneStart(value[end]) {
+				end--
+			}
+			if end == 0 {
+				end = kvChunkSize
+			}
+		}
+		chunks = append(chunks, value[:end])
+		value = value[end:]
+	}
+	return chunks
+}
+
+type kvFetchFn func(mctx libkb.MetaContext, entryID keybase1.KVEntryID) (entryValue *string, revision int, err error)
+
+// assembleChunks returns entryValue unchanged unless it's a manifest, in
+// which case it fetches the chunks with fetch and checks each of them, and
+// the whole value, against the manifest.
+func (h *KVStoreHandler) assembleChunks(mctx libkb.MetaContext, entryID keybase1.KVEntryID, entryValue *string, fetch kvFetchFn) (res *string, err error) {
+	manifest := parseChunkManifest(entryValue)
+	if manifest == nil {
+		return entryValue, nil
+	}
+	defer mctx.Trace(fmt.Sprintf("KVStoreHandler#assembleChunks: %+v, chunks:%d", entryID, len(manifest.Chunks)), &err)()
+	if manifest.Size < 0 {
+		return nil, fmt.Errorf("entry %s/%s has a malformed chunk manifest", entryID.Namespace, entryID.EntryKey)
+	}
+	var sb strings.Builder
+	sb.Grow(manifest.Size)
+	for i, ref := range manifest.Chunks {
+		chunkID := keybase1.KVEntryID{
+			TeamID:    entryID.TeamID,
+			Namespace: entryID.Namespace,
+			EntryKey:  ref.EntryKey,
+		}
+		chunk, revision, err := fetch(mctx, chunkID)
+		if err != nil {
+			mctx.Debug("error fetching chunk %d of %+v: %v", i, entryID, err)
+			return nil, err
+		}
+		if chunk == nil {
+			return nil, fmt.Errorf("chunk %d of %s/%s is missing", i, entryID.Namespace, entryID.EntryKey)
+		}
+		if revision != ref.Revision {
+			return nil, fmt.Errorf("chunk %d of %s/%s is at revision %d, the manifest expects %d", i, entryID.Namespace, entryID.EntryKey, revision, ref.Revision)
+		}
+		if kvChunkHash(*chunk) != ref.Hash {
+			return nil, fmt.Errorf("chunk %d of %s/%s doesn't match the manifest", i, entryID.Namespace, entryID.EntryKey)
+		}
+		sb.WriteString(*chunk)
+	}
+	value := sb.String()
+	if len(value) != manifest.Size || kvChunkHash(value) != manifest.Hash {
+		return nil, fmt.Errorf("reassembled value of %s/%s doesn't match the manifest", entryID.Namespace, entryID.EntryKey)
+	}
+	return &value, nil
+}
+
+// deleteChunkEntries are the batch entries that delete every chunk in a
+// manifest.
+func deleteChunkEntries(namespace string, manifest *kvChunkManifest) (entries []keybase1.KVBatchEntry) {
+	if manifest == nil {
+		return nil
+	}
+	for _, ref := range manifest.Chunks {
+		entries = append(entries, keybase1.KVBatchEntry{
+			Namespace: namespace,
+			EntryKey:  ref.EntryKey,
+			Revision:  ref.Revision + 1,
+		})
+	}
+	return entries
+}
+
+// putChunkedLocked writes a value that's too large for a single entry, or
+// replaces one that was, in one batch: the new chunks, the entry itself
+// (holding either the manifest or a small value), and deletes for the old
+// chunks.
+func (h *KVStoreHandler) putChunkedLocked(mctx libkb.MetaContext, arg keybase1.PutKVEntryArg, entryID keybase1.KVEntryID,
+	revision int, oldManifest *kvChunkManifest) (res keybase1.KVPutResult, err error) {
+	defer mctx.Trace(fmt.Sprintf("KVStoreHandler#putChunkedLocked: %+v, r:%d, size:%d", entryID, revision, len(arg.EntryValue)), &err)()
+
+	var entries []keybase1.KVBatchEntry
+	entryValue := arg.EntryValue
+	if len(arg.EntryValue) > kvChunkSize {
+		chunks := splitChunks(arg.EntryValue)
+		if len(chunks) > kvMaxChunks {
+			return res, fmt.Errorf("entry value is too large: %d bytes, the limit is %d", len(arg.EntryValue), kvMaxChunkedSize)
+		}
+		manifest := kvChunkManifest{
+			Size: len(arg.EntryValue),
+			Hash: kvChunkHash(arg.EntryValue),
+		}
+		for i := range chunks {
+			ref := kvChunkRef{
+				EntryKey: chunkKey(arg.EntryKey, revision, i),
+				Revision: 1,
+				Hash:     kvChunkHash(chunks[i]),
+			}
+			manifest.Chunks = append(manifest.Chunks, ref)
+			entries = append(entries, keybase1.KVBatchEntry{
+				Namespace:  arg.Namespace,
+				EntryKey:   ref.EntryKey,
+				Revision:   ref.Revision,
+				EntryValue: &chunks[i],
+			})
+		}
+		encoded, err := json.Marshal(manifest)
+		if err != nil {
+			return res, err
+		}
+		entryValue = kvChunkManifestPrefix + string(encoded)
+		mctx.Debug("writing %+v in %d chunks", entryID, len(chunks))
+	}
+	entryIndex := len(entries)
+	entries = append(entries, keybase1.KVBatchEntry{
+		Namespace:  arg.Namespace,
+		EntryKey:   arg.EntryKey,
+		Revision:   revision,
+		EntryValue: &entryValue,
+	})
+	entries = append(entries, deleteChunkEntries(arg.Namespace, oldManifest)...)
+
+	batchRes, err := h.batchKVEntriesLocked(mctx.Ctx(), keybase1.BatchKVEntriesArg{
+		SessionID: arg.SessionID,
+		TeamName:  arg.TeamName,
+		Entries:   entries,
+	})
+	if err != nil {
+		return res, err
+	}
+	return keybase1.KVPutResult{
+		TeamName:  arg.TeamName,
+		Namespace: arg.Namespace,
+		EntryKey:  arg.EntryKey,
+		Revision:  batchRes.Results[entryIndex].Revision,
+	}, nil
+}
+
+// delChunkedLocked deletes a chunked entry and all of its chunks in one
+// batch.
+func (h *KVStoreHandler) delChunkedLocked(mctx libkb.MetaContext, arg keybase1.DelKVEntryArg, entryID keybase1.KVEntryID,
+	revision int, manifest *kvChunkManifest) (res keybase1.KVDeleteEntryResult, err error) {
+	defer mctx.Trace(fmt.Sprintf("KVStoreHandler#delChunkedLocked: %+v, r:%d, chunks:%d", entryID, revision, len(manifest.Chunks)), &err)()
+
+	entries := []keybase1.KVBatchEntry{{
+		Namespace: arg.Namespace,
+		EntryKey:  arg.EntryKey,
+		Revision:  revision,
+	}}
+	entries = append(entries, deleteChunkEntries(arg.Namespace, manifest)...)
+	batchRes, err := h.batchKVEntriesLocked(mctx.Ctx(), keybase1.BatchKVEntriesArg{
+		SessionID: arg.SessionID,
+		TeamName:  arg.TeamName,
+		Entries:   entries,
+	})
+	if err != nil {
+		return res, err
+	}
+	return keybase1.KVDeleteEntryResult{
+		TeamName:  arg.TeamName,
+		Namespace: arg.Namespace,
+		EntryKey:  arg.EntryKey,
+		Revision:  batchRes.Results[0].Revision,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVSplitChunks(t *testing.T) {
+	require.Empty(t, splitChunks(""))
+	require.Equal(t, []string{"abc"}, splitChunks("abc"))
+
+	ascii := strings.Repeat("a", 2*kvChunkSize+1)
+	chunks := splitChunks(ascii)
+	require.Len(t, chunks, 3)
+	require.Len(t, chunks[0], kvChunkSize)
+	require.Len(t, chunks[2], 1)
+
+	// a 3-byte character straddling the chunk boundary moves to the next chunk
+	value := strings.Repeat("a", kvChunkSize-1) + "€" + "b"
+	chunks = splitChunks(value)
+	require.Equal(t, []string{strings.Repeat("a", kvChunkSize-1), "€b"}, chunks)
+	require.Equal(t, value, strings.Join(chunks, ""))
+}
+
+func TestKVChunkedPutGetDelete(t *testing.T) {
+	tc, handler, fake, teamName, teamID := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	namespace := "configs"
+	entryKey := "big"
+	chunkID := func(revision, i int) keybase1.KVEntryID {
+		return keybase1.KVEntryID{TeamID: teamID, Namespace: namespace, EntryKey: chunkKey(entryKey, revision, i)}
+	}
+	getArg := keybase1.GetKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: entryKey}
+
+	bigValue := strings.Repeat("0123456789abcdef€", 3*kvChunkSize/16)
+	putRes, err := handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: entryKey, EntryValue: bigValue})
+	require.NoError(t, err)
+	require.Equal(t, 1, putRes.Revision)
+	require.Equal(t, 1, fake.numBatchCalls(), "the chunks and manifest are written in one batch")
+	for i := 0; i < 4; i++ {
+		require.Equal(t, 1, fake.revision(chunkID(1, i)))
+	}
+	getRes, err := handler.GetKVEntry(ctx, getArg)
+	require.NoError(t, err)
+	require.Equal(t, bigValue, *getRes.EntryValue)
+	require.Equal(t, 1, getRes.Revision)
+	t.Logf("large values are chunked and reassembled")
+
+	biggerValue := bigValue + bigValue
+	putRes, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: entryKey, EntryValue: biggerValue})
+	require.NoError(t, err)
+	require.Equal(t, 2, putRes.Revision)
+	for i := 0; i < 4; i++ {
+		require.Equal(t, 2, fake.revision(chunkID(1, i)), "the old chunks are deleted")
+	}
+	getRes, err = handler.GetKVEntry(ctx, getArg)
+	require.NoError(t, err)
+	require.Equal(t, biggerValue, *getRes.EntryValue)
+	require.Equal(t, 2, getRes.Revision)
+
+	putRes, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: entryKey, EntryValue: "small"})
+	require.NoError(t, err)
+	require.Equal(t, 3, putRes.Revision)
+	require.Equal(t, 2, fake.revision(chunkID(2, 0)), "replacing a chunked value with a small one deletes the chunks")
+	getRes, err = handler.GetKVEntry(ctx, getArg)
+	require.NoError(t, err)
+	require.Equal(t, "small", *getRes.EntryValue)
+	t.Logf("overwriting a chunked value cleans up its chunks")
+
+	putRes, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: entryKey, EntryValue: bigValue})
+	require.NoError(t, err)
+	require.Equal(t, 4, putRes.Revision)
+	delRes, err := handler.DelKVEntry(ctx, keybase1.DelKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: entryKey})
+	require.NoError(t, err)
+	require.Equal(t, 5, delRes.Revision)
+	for i := 0; i < 4; i++ {
+		require.Equal(t, 2, fake.revision(chunkID(4, i)))
+	}
+	getRes, err = handler.GetKVEntry(ctx, getArg)
+	require.NoError(t, err)
+	require.Nil(t, getRes.EntryValue)
+	require.Equal(t, 5, getRes.Revision)
+	t.Logf("deleting a chunked value deletes its chunks")
+}
+
+func TestKVChunkedReplaceMaxSize(t *testing.T) {
+	tc, handler, fake, teamName, _ := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	getArg := keybase1.GetKVEntryArg{TeamName: teamName, Namespace: "configs", EntryKey: "biggest"}
+	put := func(value string) keybase1.KVPutResult {
+		res, err := handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: "configs", EntryKey: "biggest", EntryValue: value})
+		require.NoError(t, err)
+		getRes, err := handler.GetKVEntry(ctx, getArg)
+		require.NoError(t, err)
+		require.Equal(t, value, *getRes.EntryValue)
+		return res
+	}
+
+	require.Equal(t, 1, put(strings.Repeat("a", kvMaxChunkedSize)).Revision)
+	// the new chunks, the manifest and the deletes of every old chunk all
+	// fit in one batch
+	require.Equal(t, 2, put(strings.Repeat("b", kvMaxChunkedSize)).Revision)
+	require.Equal(t, 2, fake.numBatchCalls())
+	require.LessOrEqual(t, 2*kvMaxChunks+1, kvBatchMaxEntries)
+
+	_, err := handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: "configs", EntryKey: "biggest", EntryValue: strings.Repeat("c", kvMaxChunkedSize+1)})
+	require.Error(t, err)
+}
+
+func TestKVChunkedIntegrity(t *testing.T) {
+	tc, handler, fake, teamName, teamID := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	namespace := "configs"
+
+	_, err := handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: chunkKey("x", 1, 0), EntryValue: "forged"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "reserved")
+	_, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "x", EntryValue: kvChunkManifestPrefix + "{}"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "reserved")
+	_, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "x", EntryValue: strings.Repeat("a", kvMaxChunkedSize+1)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "too large")
+	big := strings.Repeat("a", kvChunkSize+1)
+	_, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{TeamName: teamName, Entries: []keybase1.KVBatchEntry{kvBatchPut(namespace, "x", 0, big)}})
+	require.Error(t, err)
+	require.Equal(t, 0, fake.numBatchCalls())
+	t.Logf("chunks can't be forged or batched")
+
+	entryKey := "big"
+	_, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: entryKey, EntryValue: big})
+	require.NoError(t, err)
+	// the server swaps in the second chunk's ciphertext for the first one
+	first := keybase1.KVEntryID{TeamID: teamID, Namespace: namespace, EntryKey: chunkKey(entryKey, 1, 0)}
+	second := keybase1.KVEntryID{TeamID: teamID, Namespace: namespace, EntryKey: chunkKey(entryKey, 1, 1)}
+	fake.Lock()
+	fake.entries[first] = fake.entries[second]
+	fake.Unlock()
+	_, err = handler.GetKVEntry(ctx, keybase1.GetKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: entryKey})
+	require.Error(t, err)
+	t.Logf("chunks are bound to their own entry keys")
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/adamwalz/keybase-client/go/kvstore"
+	"github.com/adamwalz/keybase-client/go/libkb"
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/stretchr/testify/require"
+)
+
+type kvOfflineConnectivityMonitor struct{}
+
+func (kvOfflineConnectivityMonitor) IsConnected(ctx context.Context) libkb.ConnectivityMonitorResult {
+	return libkb.ConnectivityMonitorNo
+}
+
+func (kvOfflineConnectivityMonitor) CheckReachability(ctx context.Context) error {
+	return nil
+}
+
+var _ libkb.ConnectivityMonitor = kvOfflineConnectivityMonitor{}
+
+// kvUnreachableServer fails every entry fetch with a network error
+type kvUnreachableServer struct {
+	*kvFakeServer
+}
+
+func (f *kvUnreachableServer) GetDecode(mctx libkb.MetaContext, arg libkb.APIArg, w libkb.APIResponseWrapper) error {
+	if arg.Endpoint == "team/storage" {
+		return libkb.APINetError{Err: errors.New("fake network error")}
+	}
+	return f.kvFakeServer.GetDecode(mctx, arg, w)
+}
+
+func TestKVMirrorServesStaleEntries(t *testing.T) {
+	tc, handler, fake, teamName, _ := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	namespace := "bot-config"
+
+	_, err := handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "channel", EntryValue: "#general"})
+	require.NoError(t, err)
+	_, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "gone", EntryValue: "soon"})
+	require.NoError(t, err)
+	_, err = handler.DelKVEntry(ctx, keybase1.DelKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "gone"})
+	require.NoError(t, err)
+
+	getArg := keybase1.GetKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "channel", Oa: keybase1.OfflineAvailability_BEST_EFFORT}
+	getRes, err := handler.GetKVEntry(ctx, getArg)
+	require.NoError(t, err)
+	require.False(t, getRes.Stale)
+	require.Equal(t, "#general", *getRes.EntryValue)
+	t.Logf("best-effort reads go to the server while it's reachable")
+
+	tc.G.API = &kvUnreachableServer{fake}
+	getRes, err = handler.GetKVEntry(ctx, getArg)
+	require.NoError(t, err)
+	require.True(t, getRes.Stale)
+	require.Equal(t, "#general", *getRes.EntryValue)
+	require.Equal(t, 1, getRes.Revision)
+	getArg.Oa = keybase1.OfflineAvailability_NONE
+	_, err = handler.GetKVEntry(ctx, getArg)
+	require.Error(t, err)
+	require.IsType(t, libkb.APINetError{}, err)
+	t.Logf("network errors fall back to the mirror, but only for best-effort reads")
+
+	tc.G.ConnectivityMonitor = kvOfflineConnectivityMonitor{}
+	// a fresh handler, as if the service had restarted
+	tc.G.SetKVRevisionCache(kvstore.NewKVRevisionCache(tc.G))
+	handler = NewKVStoreHandler(nil, tc.G)
+	getArg.Oa = keybase1.OfflineAvailability_BEST_EFFORT
+	getRes, err = handler.GetKVEntry(ctx, getArg)
+	require.NoError(t, err)
+	require.True(t, getRes.Stale)
+	require.Equal(t, "#general", *getRes.EntryValue)
+
+	getArg.EntryKey = "gone"
+	getRes, err = handler.GetKVEntry(ctx, getArg)
+	require.NoError(t, err)
+	require.True(t, getRes.Stale)
+	require.Nil(t, getRes.EntryValue)
+	require.Equal(t, 2, getRes.Revision)
+
+	getArg.EntryKey = "never-read"
+	_, err = handler.GetKVEntry(ctx, getArg)
+	require.Error(t, err)
+	require.IsType(t, libkb.OfflineError{}, err)
+	t.Logf("offline, only entries this device has seen are available")
+}
+
+func TestKVMirrorRollbackAcrossRestarts(t *testing.T) {
+	tc, handler, fake, teamName, teamID := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	namespace := "bot-config"
+	entryID := keybase1.KVEntryID{TeamID: teamID, Namespace: namespace, EntryKey: "channel"}
+
+	_, err := handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "channel", EntryValue: "#general"})
+	require.NoError(t, err)
+	fake.Lock()
+	oldEntry := fake.entries[entryID]
+	fake.Unlock()
+	_, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "channel", EntryValue: "#bots"})
+	require.NoError(t, err)
+
+	// restart, so that the in-memory revision cache is empty, then have the
+	// server roll the entry back
+	tc.G.SetKVRevisionCache(kvstore.NewKVRevisionCache(tc.G))
+	handler = NewKVStoreHandler(nil, tc.G)
+	fake.Lock()
+	fake.entries[entryID] = oldEntry
+	fake.Unlock()
+	getArg := keybase1.GetKVEntryArg{TeamName: teamName, Namespace: namespace, EntryKey: "channel"}
+	_, err = handler.GetKVEntry(ctx, getArg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "revision decreased from 2 to 1")
+	t.Logf("the mirror catches a rollback that the empty revision cache can't")
+
+	mirrored, found, err := handler.Mirror.Get(libkb.NewMetaContextForTest(tc), entryID)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, 2, mirrored.Revision)
+	require.Equal(t, "#bots", *mirrored.EntryValue)
+}
@@ -823,3 +823,132 @@ func TestKVStoreRace(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, secretData, *getRes.EntryValue)
 }
+
+type kvNotifyListener struct {
+	libkb.NoopNotifyListener
+	changes chan keybase1.KVEntryChange
+}
+
+var _ libkb.NotifyListener = (*kvNotifyListener)(nil)
+
+func (n *kvNotifyListener) KVStoreEntryChanged(change keybase1.KVEntryChange) {
+	n.changes <- change
+}
+
+func TestKVStoreUpdateNotification(t *testing.T) {
+	tc := kvTestSetup(t)
+	defer tc.Cleanup()
+	mctx := libkb.NewMetaContextForTest(tc)
+	user, err := kbtest.CreateAndSignupFakeUser("kvn", tc.G)
+	require.NoError(t, err)
+	teamName := user.Username + "t"
+	teamID, err := teams.CreateRootTeam(context.Background(), tc.G, teamName, keybase1.TeamSettings{})
+	require.NoError(t, err)
+	listener := &kvNotifyListener{changes: make(chan keybase1.KVEntryChange, 10)}
+	tc.G.NotifyRouter.AddListener(listener)
+	// the same user, writing from another of their devices
+	otherDeviceID, err := libkb.NewDeviceID()
+	require.NoError(t, err)
+
+	msg := kvStoreUpdateMsg{
+		TeamID:    *teamID,
+		Namespace: "myapp",
+		EntryKey:  "config",
+		Revision:  4,
+		WriterUID: user.GetUID(),
+		DeviceID:  otherDeviceID,
+	}
+	body, err := json.Marshal(msg)
+	require.NoError(t, err)
+	err = handleKVStoreUpdate(mctx, body)
+	require.NoError(t, err)
+	change := <-listener.changes
+	require.Equal(t, keybase1.KVEntryChange{
+		ChangeType:     keybase1.KVEntryChangeType_PUT,
+		TeamID:         *teamID,
+		TeamName:       teamName,
+		Namespace:      "myapp",
+		EntryKey:       "config",
+		Revision:       4,
+		WriterUID:      user.GetUID(),
+		WriterUsername: user.Username,
+		WriterDeviceID: otherDeviceID,
+	}, change)
+
+	// deletes in an implicit team are named the same way the kvstore API names them
+	selfTeamName := fmt.Sprintf("%s,%s", user.Username, user.Username)
+	selfTeam, _, _, err := teams.LookupOrCreateImplicitTeam(context.Background(), tc.G, selfTeamName, false /*public*/)
+	require.NoError(t, err)
+	msg.TeamID = selfTeam.ID
+	msg.Deleted = true
+	body, err = json.Marshal(msg)
+	require.NoError(t, err)
+	err = handleKVStoreUpdate(mctx, body)
+	require.NoError(t, err)
+	change = <-listener.changes
+	require.Equal(t, keybase1.KVEntryChangeType_DELETE, change.ChangeType)
+	require.Equal(t, selfTeamName, change.TeamName)
+
+	// this device's own writes were already notified when they were made
+	msg.DeviceID = tc.G.ActiveDevice.DeviceID()
+	body, err = json.Marshal(msg)
+	require.NoError(t, err)
+	err = handleKVStoreUpdate(mctx, body)
+	require.NoError(t, err)
+	require.Len(t, listener.changes, 0)
+
+	err = handleKVStoreUpdate(mctx, []byte("not json"))
+	require.Error(t, err)
+	require.Len(t, listener.changes, 0)
+}
+
+func TestKVStoreLocalWriteNotification(t *testing.T) {
+	tc, handler, _, teamName, teamID := kvBatchTestSetup(t)
+	defer tc.Cleanup()
+	ctx := context.Background()
+	listener := &kvNotifyListener{changes: make(chan keybase1.KVEntryChange, 10)}
+	tc.G.NotifyRouter.AddListener(listener)
+	namespace := "myapp"
+	expectChange := func(changeType keybase1.KVEntryChangeType, entryKey string, revision int) {
+		change := <-listener.changes
+		require.Equal(t, keybase1.KVEntryChange{
+			ChangeType:     changeType,
+			TeamID:         teamID,
+			TeamName:       teamName,
+			Namespace:      namespace,
+			EntryKey:       entryKey,
+			Revision:       revision,
+			WriterUID:      tc.G.ActiveDevice.UID(),
+			WriterUsername: tc.G.ActiveDevice.Username(libkb.NewMetaContextForTest(tc)).String(),
+			WriterDeviceID: tc.G.ActiveDevice.DeviceID(),
+		}, change)
+	}
+
+	_, err := handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{
+		TeamName: teamName, Namespace: namespace, EntryKey: "a", EntryValue: "1"})
+	require.NoError(t, err)
+	expectChange(keybase1.KVEntryChangeType_PUT, "a", 1)
+
+	_, err = handler.DelKVEntry(ctx, keybase1.DelKVEntryArg{
+		TeamName: teamName, Namespace: namespace, EntryKey: "a"})
+	require.NoError(t, err)
+	expectChange(keybase1.KVEntryChangeType_DELETE, "a", 2)
+
+	_, err = handler.BatchKVEntries(ctx, keybase1.BatchKVEntriesArg{
+		TeamName: teamName,
+		Entries: []keybase1.KVBatchEntry{
+			kvBatchPut(namespace, "a", 0, "2"),
+			kvBatchPut(namespace, "b", 0, "1"),
+		},
+	})
+	require.NoError(t, err)
+	expectChange(keybase1.KVEntryChangeType_PUT, "a", 3)
+	expectChange(keybase1.KVEntryChangeType_PUT, "b", 1)
+
+	// a chunked value is one change, not one per chunk
+	_, err = handler.PutKVEntry(ctx, keybase1.PutKVEntryArg{
+		TeamName: teamName, Namespace: namespace, EntryKey: "c", EntryValue: strings.Repeat("x", kvChunkSize+1)})
+	require.NoError(t, err)
+	expectChange(keybase1.KVEntryChangeType_PUT, "c", 1)
+	require.Len(t, listener.changes, 0)
+}
@@ -379,6 +379,7 @@ func (d *Service) RunBackgroundOperations(uir *UIRouter) {
 	d.runBackgroundWalletUpkeep()
 	d.runBackgroundBoxAuditRetry()
 	d.runBackgroundBoxAuditScheduler()
+	d.runBackgroundTeamMemberExpiry()
 	d.runBackgroundContactSync()
 	d.runBackgroundInviteFriendsPoll()
 	d.runTLFUpgrade()
@@ -904,6 +905,26 @@ func (d *Service) runBackgroundBoxAuditScheduler() {
 	})
 }
 
+func (d *Service) runBackgroundTeamMemberExpiry() {
+	// The expiries live in the teams' KV stores, which only the service can
+	// reach, so the expirer is installed here rather than in teams.ServiceInit.
+	teams.NewMemberExpirerAndInstall(d.G(), NewKVStoreHandler(nil, d.G()))
+	eng := engine.NewTeamMemberExpiryBackground(d.G())
+	go func() {
+		m := libkb.NewMetaContextBackground(d.G())
+		err := engine.RunEngine2(m, eng)
+		if err != nil {
+			m.Warning("background TeamMemberExpiry error: %v", err)
+		}
+	}()
+
+	d.G().PushShutdownHook(func(mctx libkb.MetaContext) error {
+		d.G().Log.Debug("stopping background TeamMemberExpiry")
+		eng.Shutdown()
+		return nil
+	})
+}
+
 func (d *Service) runBackgroundContactSync() {
 	eng := engine.NewContactSyncBackground(d.G())
 	go func() {
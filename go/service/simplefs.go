@@ -229,6 +229,45 @@ func (s *SimpleFSHandler) SimpleFSSetStat(ctx context.Context, arg keybase1.Simp
 	return cli.SimpleFSSetStat(ctx, arg)
 }
 
+// SimpleFSListXattrs - List the user extended attributes of a file or
+// directory.
+func (s *SimpleFSHandler) SimpleFSListXattrs(
+	ctx context.Context, path keybase1.Path) ([]keybase1.SimpleFSXattr, error) {
+	cli, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := s.wrapContextWithTimeout(ctx)
+	defer cancel()
+	return cli.SimpleFSListXattrs(ctx, path)
+}
+
+// SimpleFSSetXattr - Set a user extended attribute on a file or
+// directory.
+func (s *SimpleFSHandler) SimpleFSSetXattr(
+	ctx context.Context, arg keybase1.SimpleFSSetXattrArg) error {
+	cli, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.wrapContextWithTimeout(ctx)
+	defer cancel()
+	return cli.SimpleFSSetXattr(ctx, arg)
+}
+
+// SimpleFSRemoveXattr - Remove a user extended attribute from a file or
+// directory.
+func (s *SimpleFSHandler) SimpleFSRemoveXattr(
+	ctx context.Context, arg keybase1.SimpleFSRemoveXattrArg) error {
+	cli, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.wrapContextWithTimeout(ctx)
+	defer cancel()
+	return cli.SimpleFSRemoveXattr(ctx, arg)
+}
+
 // SimpleFSRead - Read (possibly partial) contents of open file,
 // up to the amount specified by size.
 // Repeat until zero bytes are returned or error.
@@ -742,6 +781,53 @@ func (s *SimpleFSHandler) SimpleFSGetGUIFileContext(ctx context.Context,
 	return cli.SimpleFSGetGUIFileContext(ctx, path)
 }
 
+// SimpleFSStartWebDAV implements the SimpleFSInterface.
+func (s *SimpleFSHandler) SimpleFSStartWebDAV(ctx context.Context,
+	port int) (keybase1.SimpleFSWebDAVServer, error) {
+	cli, err := s.client(ctx)
+	if err != nil {
+		return keybase1.SimpleFSWebDAVServer{}, err
+	}
+	ctx, cancel := s.wrapContextWithTimeout(ctx)
+	defer cancel()
+	return cli.SimpleFSStartWebDAV(ctx, port)
+}
+
+// SimpleFSStopWebDAV implements the SimpleFSInterface.
+func (s *SimpleFSHandler) SimpleFSStopWebDAV(ctx context.Context) error {
+	cli, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.wrapContextWithTimeout(ctx)
+	defer cancel()
+	return cli.SimpleFSStopWebDAV(ctx)
+}
+
+// SimpleFSStartS3Gateway implements the SimpleFSInterface.
+func (s *SimpleFSHandler) SimpleFSStartS3Gateway(ctx context.Context,
+	arg keybase1.SimpleFSStartS3GatewayArg) (
+	keybase1.SimpleFSS3Gateway, error) {
+	cli, err := s.client(ctx)
+	if err != nil {
+		return keybase1.SimpleFSS3Gateway{}, err
+	}
+	ctx, cancel := s.wrapContextWithTimeout(ctx)
+	defer cancel()
+	return cli.SimpleFSStartS3Gateway(ctx, arg)
+}
+
+// SimpleFSStopS3Gateway implements the SimpleFSInterface.
+func (s *SimpleFSHandler) SimpleFSStopS3Gateway(ctx context.Context) error {
+	cli, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.wrapContextWithTimeout(ctx)
+	defer cancel()
+	return cli.SimpleFSStopS3Gateway(ctx)
+}
+
 // SimpleFSGetFilesTabBadge implements the SimpleFSInterface.
 func (s *SimpleFSHandler) SimpleFSGetFilesTabBadge(ctx context.Context) (
 	keybase1.FilesTabBadge, error) {
@@ -525,6 +525,61 @@ func (h *TeamsHandler) TeamSetBotSettings(ctx context.Context, arg keybase1.Team
 	return teams.SetBotSettings(ctx, h.G().ExternalG(), arg.Name, arg.Username, arg.BotSettings)
 }
 
+func (h *TeamsHandler) TeamApply(ctx context.Context, arg keybase1.TeamApplyArg) (res keybase1.TeamApplyResult, err error) {
+	ctx = libkb.WithLogTag(ctx, "TM")
+	defer h.G().CTrace(ctx, fmt.Sprintf("TeamApply(%d teams, dryRun=%v)", len(arg.Teams), arg.DryRun),
+		&err)()
+	if err := assertLoggedIn(ctx, h.G().ExternalG()); err != nil {
+		return res, err
+	}
+	return teams.Apply(ctx, h.G().ExternalG(), arg.Teams, arg.DryRun)
+}
+
+func (h *TeamsHandler) TeamHistory(ctx context.Context, arg keybase1.TeamHistoryArg) (res []keybase1.TeamHistoryEvent, err error) {
+	ctx = libkb.WithLogTag(ctx, "TM")
+	defer h.G().CTrace(ctx, fmt.Sprintf("TeamHistory(%s,%s)", arg.TeamName, arg.Member), &err)()
+	if err := assertLoggedIn(ctx, h.G().ExternalG()); err != nil {
+		return nil, err
+	}
+	return teams.History(ctx, h.G().ExternalG(), arg)
+}
+
+func (h *TeamsHandler) TeamExportChain(ctx context.Context, arg keybase1.TeamExportChainArg) (res string, err error) {
+	ctx = libkb.WithLogTag(ctx, "TM")
+	defer h.G().CTrace(ctx, fmt.Sprintf("TeamExportChain(%s)", arg.TeamName), &err)()
+	if err := assertLoggedIn(ctx, h.G().ExternalG()); err != nil {
+		return "", err
+	}
+	return teams.ExportChain(ctx, h.G().ExternalG(), arg.TeamName)
+}
+
+// TeamVerifyChain doesn't need a login, since it only looks at the bundle.
+func (h *TeamsHandler) TeamVerifyChain(ctx context.Context, arg keybase1.TeamVerifyChainArg) (res keybase1.TeamChainVerification, err error) {
+	ctx = libkb.WithLogTag(ctx, "TM")
+	defer h.G().CTrace(ctx, "TeamVerifyChain", &err)()
+	return teams.VerifyChain(ctx, h.G().ExternalG(), arg.Bundle)
+}
+
+func (h *TeamsHandler) TeamSetMemberExpiry(ctx context.Context, arg keybase1.TeamSetMemberExpiryArg) (err error) {
+	ctx = libkb.WithLogTag(ctx, "TM")
+	defer h.G().CTrace(ctx, fmt.Sprintf("TeamSetMemberExpiry(%s,%s,%v)", arg.TeamName, arg.Username, arg.ExpiresAt), &err)()
+	if err := assertLoggedIn(ctx, h.G().ExternalG()); err != nil {
+		return err
+	}
+	kv := NewKVStoreHandler(h.xp, h.G().ExternalG())
+	return teams.SetMemberExpiry(ctx, h.G().ExternalG(), kv, arg.TeamName, arg.Username, arg.ExpiresAt, arg.DowngradeTo)
+}
+
+func (h *TeamsHandler) TeamListExpiringMembers(ctx context.Context, arg keybase1.TeamListExpiringMembersArg) (res []keybase1.TeamMemberExpiry, err error) {
+	ctx = libkb.WithLogTag(ctx, "TM")
+	defer h.G().CTrace(ctx, fmt.Sprintf("TeamListExpiringMembers(%s)", arg.TeamName), &err)()
+	if err := assertLoggedIn(ctx, h.G().ExternalG()); err != nil {
+		return nil, err
+	}
+	kv := NewKVStoreHandler(h.xp, h.G().ExternalG())
+	return teams.ListExpiringMembers(ctx, h.G().ExternalG(), kv, arg.TeamName)
+}
+
 func (h *TeamsHandler) TeamGetBotSettings(ctx context.Context, arg keybase1.TeamGetBotSettingsArg) (res keybase1.TeamBotSettings, err error) {
 	ctx = libkb.WithLogTag(ctx, "TM")
 	defer h.G().CTrace(ctx, fmt.Sprintf("TeamGetBotSettings(%s,%s)", arg.Name, arg.Username),
@@ -0,0 +1,72 @@
+package systests
+
+import (
+	"context"
+	"testing"
+
+	keybase1 "github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTeamApply applies a spec over RPC, the way `keybase team apply` does,
+// and checks the result from the members' side.
+func TestTeamApply(t *testing.T) {
+	tt := newTeamTester(t)
+	defer tt.cleanup()
+
+	ann := tt.addUser("ann")
+	bob := tt.addUser("bob")
+	cam := tt.addUser("cam")
+
+	team := ann.createTeam()
+	subteam := team + ".ops"
+	spec := []keybase1.TeamApplyTeam{
+		{
+			Name: team,
+			Members: []keybase1.TeamApplyMember{
+				{Username: ann.username, Role: keybase1.TeamRole_OWNER},
+				{Username: bob.username, Role: keybase1.TeamRole_WRITER},
+				{Username: cam.username, Role: keybase1.TeamRole_READER},
+			},
+		},
+		{
+			Name: subteam,
+			Members: []keybase1.TeamApplyMember{
+				{Username: bob.username, Role: keybase1.TeamRole_ADMIN},
+			},
+		},
+	}
+	res, err := ann.teamsClient.TeamApply(context.Background(), keybase1.TeamApplyArg{Teams: spec})
+	require.NoError(t, err)
+	require.True(t, res.Applied)
+	require.Len(t, res.Changes, 4)
+	require.Equal(t, []keybase1.TeamApplyTeamResult{
+		{TeamName: team, Applied: true},
+		{TeamName: subteam, Applied: true},
+	}, res.Teams)
+
+	teamObj := bob.loadTeam(team, false /* admin */)
+	role, err := teamObj.MemberRole(context.Background(), bob.userVersion())
+	require.NoError(t, err)
+	require.Equal(t, keybase1.TeamRole_WRITER, role)
+	subteamObj := bob.loadTeam(subteam, true /* admin */)
+	role, err = subteamObj.MemberRole(context.Background(), bob.userVersion())
+	require.NoError(t, err)
+	require.Equal(t, keybase1.TeamRole_ADMIN, role)
+	t.Logf("bob sees his new roles")
+
+	// take cam out of the team, and bob down to a reader
+	spec[0].Members = spec[0].Members[:2]
+	spec[0].Members[1].Role = keybase1.TeamRole_READER
+	res, err = ann.teamsClient.TeamApply(context.Background(), keybase1.TeamApplyArg{Teams: spec})
+	require.NoError(t, err)
+	require.True(t, res.Applied)
+	require.Len(t, res.Changes, 2)
+
+	teamObj = ann.loadTeam(team, true /* admin */)
+	require.False(t, teamObj.IsMember(context.Background(), cam.userVersion()))
+	teamObj = bob.loadTeam(team, false /* admin */)
+	role, err = teamObj.MemberRole(context.Background(), bob.userVersion())
+	require.NoError(t, err)
+	require.Equal(t, keybase1.TeamRole_READER, role)
+}
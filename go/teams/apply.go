@@ -0,0 +1,412 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package teams
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+)
+
+// applyMemberState is what Apply needs to know about a current member of a
+// team.
+type applyMemberState struct {
+	uv          keybase1.UserVersion
+	role        keybase1.TeamRole
+	botSettings *keybase1.TeamBotSettings
+}
+
+// applyTeamState is the current state of a team, as far as Apply is
+// concerned. A team that doesn't exist yet has no members and is closed.
+type applyTeamState struct {
+	exists   bool
+	id       keybase1.TeamID
+	members  map[libkb.NormalizedUsername]applyMemberState
+	settings keybase1.TeamSettings
+}
+
+// applyTeamPlan holds the changes for a single team in the spec.
+type applyTeamPlan struct {
+	name    keybase1.TeamName
+	state   applyTeamState
+	changes []keybase1.TeamApplyChange
+}
+
+// checkApplySpec validates the desired state of the teams, and returns their
+// names sorted so that parent teams come before their subteams.
+func checkApplySpec(teams []keybase1.TeamApplyTeam) (
+	names []keybase1.TeamName, sorted []keybase1.TeamApplyTeam, err error) {
+	seenTeams := make(map[string]bool)
+	for _, team := range teams {
+		name, err := keybase1.TeamNameFromString(team.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if seenTeams[name.String()] {
+			return nil, nil, fmt.Errorf("team %s is listed more than once", name)
+		}
+		seenTeams[name.String()] = true
+
+		seenMembers := make(map[libkb.NormalizedUsername]bool)
+		for _, member := range team.Members {
+			if len(member.Username) == 0 {
+				return nil, nil, fmt.Errorf("empty username in team %s", name)
+			}
+			username := libkb.NewNormalizedUsername(member.Username)
+			if seenMembers[username] {
+				return nil, nil, fmt.Errorf("%s is listed more than once in team %s", username, name)
+			}
+			seenMembers[username] = true
+			if err := assertValidNewTeamMemberRole(member.Role); err != nil {
+				return nil, nil, err
+			}
+			if member.Role == keybase1.TeamRole_OWNER && !name.IsRootTeam() {
+				return nil, nil, NewSubteamOwnersError()
+			}
+			if member.Role.IsRestrictedBot() != (member.BotSettings != nil) {
+				return nil, nil, fmt.Errorf("%s in team %s: bot settings are required for, and only allowed for, restricted bots",
+					username, name)
+			}
+		}
+
+		if team.Settings != nil && team.Settings.Open {
+			switch team.Settings.JoinAs {
+			case keybase1.TeamRole_READER, keybase1.TeamRole_WRITER:
+			default:
+				return nil, nil, fmt.Errorf("open team %s can only be joined as a reader or a writer", name)
+			}
+		}
+
+		names = append(names, name)
+		sorted = append(sorted, team)
+	}
+
+	sort.Stable(applySpecByDepth{names, sorted})
+	return names, sorted, nil
+}
+
+type applySpecByDepth struct {
+	names []keybase1.TeamName
+	teams []keybase1.TeamApplyTeam
+}
+
+func (a applySpecByDepth) Len() int { return len(a.names) }
+func (a applySpecByDepth) Less(i, j int) bool {
+	return a.names[i].Depth() < a.names[j].Depth()
+}
+func (a applySpecByDepth) Swap(i, j int) {
+	a.names[i], a.names[j] = a.names[j], a.names[i]
+	a.teams[i], a.teams[j] = a.teams[j], a.teams[i]
+}
+
+func stringSetsEqual(a, b []string) bool {
+	set := make(map[string]int)
+	for _, s := range a {
+		set[s]++
+	}
+	for _, s := range b {
+		set[s]--
+	}
+	for _, n := range set {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func botSettingsEqual(a, b *keybase1.TeamBotSettings) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmds == b.Cmds && a.Mentions == b.Mentions &&
+		stringSetsEqual(a.Triggers, b.Triggers) && stringSetsEqual(a.Convs, b.Convs)
+}
+
+func teamSettingsEqual(a, b keybase1.TeamSettings) bool {
+	return a.Open == b.Open && (!a.Open || a.JoinAs == b.JoinAs)
+}
+
+// planTeamChanges diffs the current state of a team against the desired one.
+// It refuses to remove or demote `me`, since that could leave the rest of
+// the plan (or the team) without an admin.
+func planTeamChanges(name keybase1.TeamName, state applyTeamState,
+	desired keybase1.TeamApplyTeam, me libkb.NormalizedUsername) (changes []keybase1.TeamApplyChange, err error) {
+	change := func(typ keybase1.TeamApplyChangeType) keybase1.TeamApplyChange {
+		return keybase1.TeamApplyChange{TeamName: name.String(), Type: typ}
+	}
+
+	if !state.exists {
+		changes = append(changes, change(keybase1.TeamApplyChangeType_CREATE_SUBTEAM))
+	}
+
+	members := append([]keybase1.TeamApplyMember{}, desired.Members...)
+	sort.Slice(members, func(i, j int) bool {
+		return libkb.NewNormalizedUsername(members[i].Username) <
+			libkb.NewNormalizedUsername(members[j].Username)
+	})
+	wanted := make(map[libkb.NormalizedUsername]bool)
+	for _, member := range members {
+		username := libkb.NewNormalizedUsername(member.Username)
+		wanted[username] = true
+		current, isMember := state.members[username]
+		switch {
+		case !isMember:
+			c := change(keybase1.TeamApplyChangeType_ADD_MEMBER)
+			c.Username = username.String()
+			c.NewRole = member.Role
+			c.BotSettings = member.BotSettings
+			changes = append(changes, c)
+		case current.role != member.Role:
+			if username.Eq(me) && !member.Role.IsOrAbove(current.role) {
+				return nil, fmt.Errorf("refusing to demote yourself in team %s from %s to %s",
+					name, current.role.HumanString(), member.Role.HumanString())
+			}
+			c := change(keybase1.TeamApplyChangeType_CHANGE_ROLE)
+			c.Username = username.String()
+			c.OldRole = current.role
+			c.NewRole = member.Role
+			c.BotSettings = member.BotSettings
+			changes = append(changes, c)
+		case member.Role.IsRestrictedBot() && !botSettingsEqual(current.botSettings, member.BotSettings):
+			c := change(keybase1.TeamApplyChangeType_SET_BOT_SETTINGS)
+			c.Username = username.String()
+			c.OldRole = current.role
+			c.NewRole = member.Role
+			c.BotSettings = member.BotSettings
+			changes = append(changes, c)
+		}
+	}
+
+	var toRemove []libkb.NormalizedUsername
+	for username := range state.members {
+		if !wanted[username] {
+			toRemove = append(toRemove, username)
+		}
+	}
+	sort.Slice(toRemove, func(i, j int) bool { return toRemove[i] < toRemove[j] })
+	for _, username := range toRemove {
+		if username.Eq(me) {
+			return nil, fmt.Errorf("refusing to remove yourself from team %s; use `keybase team leave` instead", name)
+		}
+		c := change(keybase1.TeamApplyChangeType_REMOVE_MEMBER)
+		c.Username = username.String()
+		c.OldRole = state.members[username].role
+		changes = append(changes, c)
+	}
+
+	if desired.Settings != nil && !teamSettingsEqual(state.settings, *desired.Settings) {
+		c := change(keybase1.TeamApplyChangeType_CHANGE_SETTINGS)
+		settings := *desired.Settings
+		c.Settings = &settings
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+func loadApplyTeamState(ctx context.Context, g *libkb.GlobalContext, name keybase1.TeamName) (
+	state applyTeamState, err error) {
+	team, err := GetForTeamManagementByStringName(ctx, g, name.String(), true /* needAdmin */)
+	switch err.(type) {
+	case nil:
+	case TeamDoesNotExistError:
+		return applyTeamState{}, nil
+	default:
+		return applyTeamState{}, err
+	}
+
+	details, err := MembersDetails(ctx, g, team)
+	if err != nil {
+		return applyTeamState{}, err
+	}
+	bots := team.chain().TeamBotSettings()
+	state = applyTeamState{
+		exists:   true,
+		id:       team.ID,
+		members:  make(map[libkb.NormalizedUsername]applyMemberState),
+		settings: team.Settings(),
+	}
+	for _, member := range details {
+		memberState := applyMemberState{uv: member.Uv, role: member.Role}
+		if botSettings, ok := bots[member.Uv]; ok {
+			memberState.botSettings = &botSettings
+		}
+		state.members[libkb.NewNormalizedUsername(member.Username)] = memberState
+	}
+	return state, nil
+}
+
+// planApply loads every team in the spec and works out what needs to change.
+func planApply(ctx context.Context, g *libkb.GlobalContext, teams []keybase1.TeamApplyTeam) (
+	plans []applyTeamPlan, err error) {
+	names, teams, err := checkApplySpec(teams)
+	if err != nil {
+		return nil, err
+	}
+	me := libkb.NewNormalizedUsername(g.Env.GetUsername().String())
+
+	willExist := make(map[string]bool)
+	for i, name := range names {
+		state, err := loadApplyTeamState(ctx, g, name)
+		if err != nil {
+			return nil, err
+		}
+		if !state.exists {
+			if name.IsRootTeam() {
+				return nil, fmt.Errorf("team %s does not exist; create it first with `keybase team create`", name)
+			}
+			parent, err := name.Parent()
+			if err != nil {
+				return nil, err
+			}
+			if !willExist[parent.String()] {
+				return nil, fmt.Errorf("cannot create subteam %s, since team %s does not exist", name, parent)
+			}
+		}
+		willExist[name.String()] = true
+
+		changes, err := planTeamChanges(name, state, teams[i], me)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, applyTeamPlan{name: name, state: state, changes: changes})
+	}
+	return plans, nil
+}
+
+// applyTeamChanges makes the changes planned for a single team: all the
+// membership changes go into a single transaction, followed by one link for
+// any bot settings, and one for the team settings.
+func applyTeamChanges(ctx context.Context, g *libkb.GlobalContext, plan applyTeamPlan) (err error) {
+	defer g.CTrace(ctx, fmt.Sprintf("applyTeamChanges(%s, %d changes)", plan.name, len(plan.changes)), &err)()
+
+	var membershipChanges []keybase1.TeamApplyChange
+	botSettings := make(map[keybase1.UserVersion]keybase1.TeamBotSettings)
+	var settings *keybase1.TeamSettings
+	for _, change := range plan.changes {
+		switch change.Type {
+		case keybase1.TeamApplyChangeType_CREATE_SUBTEAM:
+			parent, err := plan.name.Parent()
+			if err != nil {
+				return err
+			}
+			_, err = CreateSubteam(ctx, g, string(plan.name.LastPart()), parent, keybase1.TeamRole_NONE)
+			if err != nil {
+				return err
+			}
+		case keybase1.TeamApplyChangeType_ADD_MEMBER,
+			keybase1.TeamApplyChangeType_CHANGE_ROLE,
+			keybase1.TeamApplyChangeType_REMOVE_MEMBER:
+			membershipChanges = append(membershipChanges, change)
+		case keybase1.TeamApplyChangeType_SET_BOT_SETTINGS:
+			member := plan.state.members[libkb.NewNormalizedUsername(change.Username)]
+			botSettings[member.uv] = *change.BotSettings
+		case keybase1.TeamApplyChangeType_CHANGE_SETTINGS:
+			settings = change.Settings
+		default:
+			return fmt.Errorf("unexpected change type: %v", change.Type)
+		}
+	}
+
+	if len(membershipChanges) > 0 {
+		err = RetryIfPossible(ctx, g, func(ctx context.Context, _ int) error {
+			team, err := GetForTeamManagementByStringName(ctx, g, plan.name.String(), true /* needAdmin */)
+			if err != nil {
+				return err
+			}
+			tx := CreateAddMemberTx(team)
+			tx.AllowRoleChanges = true
+			for _, change := range membershipChanges {
+				if change.Type == keybase1.TeamApplyChangeType_REMOVE_MEMBER {
+					member := plan.state.members[libkb.NewNormalizedUsername(change.Username)]
+					if err := tx.RemoveMemberByUV(ctx, member.uv); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := tx.AddMemberByUsername(ctx, change.Username, change.NewRole, change.BotSettings); err != nil {
+					return err
+				}
+			}
+			return tx.Post(libkb.NewMetaContext(ctx, g))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(botSettings) > 0 {
+		err = RetryIfPossible(ctx, g, func(ctx context.Context, _ int) error {
+			team, err := GetForTeamManagementByStringName(ctx, g, plan.name.String(), true /* needAdmin */)
+			if err != nil {
+				return err
+			}
+			return team.PostTeamBotSettings(ctx, botSettings)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if settings != nil {
+		id := plan.state.id
+		if !plan.state.exists {
+			team, err := GetForTeamManagementByStringName(ctx, g, plan.name.String(), true /* needAdmin */)
+			if err != nil {
+				return err
+			}
+			id = team.ID
+		}
+		if err := ChangeTeamSettingsByID(ctx, g, id, *settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply brings every team in the spec to its desired state: subteams are
+// created, members are added, removed or have their role or bot settings
+// changed, and team settings are updated. With dryRun, it only reports the
+// changes it would make.
+//
+// Teams are changed one at a time, parents first, and Apply stops at the
+// first team that fails. That isn't an error, since some teams have been
+// changed by then: res.Teams says which ones, and what went wrong.
+func Apply(ctx context.Context, g *libkb.GlobalContext, teams []keybase1.TeamApplyTeam, dryRun bool) (
+	res keybase1.TeamApplyResult, err error) {
+	defer g.CTrace(ctx, fmt.Sprintf("teams.Apply(%d teams, dryRun=%v)", len(teams), dryRun), &err)()
+
+	plans, err := planApply(ctx, g, teams)
+	if err != nil {
+		return res, err
+	}
+	for _, plan := range plans {
+		res.Changes = append(res.Changes, plan.changes...)
+	}
+	if dryRun || len(res.Changes) == 0 {
+		return res, nil
+	}
+
+	for _, plan := range plans {
+		if len(plan.changes) == 0 {
+			continue
+		}
+		teamRes := keybase1.TeamApplyTeamResult{TeamName: plan.name.String()}
+		if err := applyTeamChanges(ctx, g, plan); err != nil {
+			g.Log.CDebugf(ctx, "teams.Apply: failed to apply changes to team %s: %v", plan.name, err)
+			msg := err.Error()
+			teamRes.Error = &msg
+			res.Teams = append(res.Teams, teamRes)
+			return res, nil
+		}
+		teamRes.Applied = true
+		res.Teams = append(res.Teams, teamRes)
+	}
+	res.Applied = true
+	return res, nil
+}
@@ -0,0 +1,241 @@
+package teams
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckApplySpec(t *testing.T) {
+	names, teams, err := checkApplySpec([]keybase1.TeamApplyTeam{
+		{Name: "acme.eng.infra"},
+		{Name: "acme", Members: []keybase1.TeamApplyMember{{Username: "alice", Role: keybase1.TeamRole_OWNER}}},
+		{Name: "acme.eng"},
+	})
+	require.NoError(t, err)
+	require.Len(t, teams, 3)
+	var got []string
+	for i, name := range names {
+		require.Equal(t, name.String(), teams[i].Name)
+		got = append(got, name.String())
+	}
+	require.Equal(t, []string{"acme", "acme.eng", "acme.eng.infra"}, got)
+
+	bot := &keybase1.TeamBotSettings{Cmds: true}
+	for _, bad := range [][]keybase1.TeamApplyTeam{
+		{{Name: "acme"}, {Name: "ACME"}},
+		{{Name: "not a team"}},
+		{{Name: "acme", Members: []keybase1.TeamApplyMember{
+			{Username: "alice", Role: keybase1.TeamRole_ADMIN},
+			{Username: "Alice", Role: keybase1.TeamRole_WRITER},
+		}}},
+		{{Name: "acme", Members: []keybase1.TeamApplyMember{{Username: "", Role: keybase1.TeamRole_READER}}}},
+		{{Name: "acme", Members: []keybase1.TeamApplyMember{{Username: "alice", Role: keybase1.TeamRole_NONE}}}},
+		{{Name: "acme.eng", Members: []keybase1.TeamApplyMember{{Username: "alice", Role: keybase1.TeamRole_OWNER}}}},
+		{{Name: "acme", Members: []keybase1.TeamApplyMember{{Username: "bot", Role: keybase1.TeamRole_RESTRICTEDBOT}}}},
+		{{Name: "acme", Members: []keybase1.TeamApplyMember{{Username: "bob", Role: keybase1.TeamRole_READER, BotSettings: bot}}}},
+		{{Name: "acme", Settings: &keybase1.TeamSettings{Open: true, JoinAs: keybase1.TeamRole_ADMIN}}},
+	} {
+		_, _, err := checkApplySpec(bad)
+		require.Error(t, err, "%+v", bad)
+	}
+}
+
+func TestPlanTeamChanges(t *testing.T) {
+	name, err := keybase1.TeamNameFromString("acme")
+	require.NoError(t, err)
+	uv := func(b byte) keybase1.UserVersion {
+		return keybase1.UserVersion{Uid: keybase1.MakeTestUID(uint32(b)), EldestSeqno: 1}
+	}
+	state := applyTeamState{
+		exists: true,
+		members: map[libkb.NormalizedUsername]applyMemberState{
+			"alice": {uv: uv(1), role: keybase1.TeamRole_OWNER},
+			"bob":   {uv: uv(2), role: keybase1.TeamRole_WRITER},
+			"carol": {uv: uv(3), role: keybase1.TeamRole_READER},
+			"robot": {uv: uv(4), role: keybase1.TeamRole_RESTRICTEDBOT,
+				botSettings: &keybase1.TeamBotSettings{Triggers: []string{"a", "b"}}},
+		},
+	}
+	me := libkb.NewNormalizedUsername("alice")
+
+	// Matching the current state exactly needs no changes.
+	desired := keybase1.TeamApplyTeam{
+		Name: "acme",
+		Members: []keybase1.TeamApplyMember{
+			{Username: "Alice", Role: keybase1.TeamRole_OWNER},
+			{Username: "bob", Role: keybase1.TeamRole_WRITER},
+			{Username: "carol", Role: keybase1.TeamRole_READER},
+			{Username: "robot", Role: keybase1.TeamRole_RESTRICTEDBOT,
+				BotSettings: &keybase1.TeamBotSettings{Triggers: []string{"b", "a"}}},
+		},
+		Settings: &keybase1.TeamSettings{},
+	}
+	changes, err := planTeamChanges(name, state, desired, me)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	desired = keybase1.TeamApplyTeam{
+		Name: "acme",
+		Members: []keybase1.TeamApplyMember{
+			{Username: "robot", Role: keybase1.TeamRole_RESTRICTEDBOT,
+				BotSettings: &keybase1.TeamBotSettings{Cmds: true}},
+			{Username: "dave", Role: keybase1.TeamRole_ADMIN},
+			{Username: "bob", Role: keybase1.TeamRole_READER},
+			{Username: "alice", Role: keybase1.TeamRole_OWNER},
+		},
+		Settings: &keybase1.TeamSettings{Open: true, JoinAs: keybase1.TeamRole_READER},
+	}
+	changes, err = planTeamChanges(name, state, desired, me)
+	require.NoError(t, err)
+	var got []string
+	for _, c := range changes {
+		require.Equal(t, "acme", c.TeamName)
+		got = append(got, c.Type.String()+" "+c.Username)
+	}
+	require.Equal(t, []string{
+		"CHANGE_ROLE bob",
+		"ADD_MEMBER dave",
+		"SET_BOT_SETTINGS robot",
+		"REMOVE_MEMBER carol",
+		"CHANGE_SETTINGS ",
+	}, got)
+	require.Equal(t, keybase1.TeamRole_WRITER, changes[0].OldRole)
+	require.Equal(t, keybase1.TeamRole_READER, changes[0].NewRole)
+	require.True(t, changes[2].BotSettings.Cmds)
+	require.True(t, changes[4].Settings.Open)
+
+	// A subteam that doesn't exist yet has to be created first.
+	subName, err := keybase1.TeamNameFromString("acme.eng")
+	require.NoError(t, err)
+	changes, err = planTeamChanges(subName, applyTeamState{}, keybase1.TeamApplyTeam{
+		Name:    "acme.eng",
+		Members: []keybase1.TeamApplyMember{{Username: "bob", Role: keybase1.TeamRole_WRITER}},
+	}, me)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, keybase1.TeamApplyChangeType_CREATE_SUBTEAM, changes[0].Type)
+	require.Equal(t, keybase1.TeamApplyChangeType_ADD_MEMBER, changes[1].Type)
+
+	// Removing or demoting yourself is refused.
+	_, err = planTeamChanges(name, state, keybase1.TeamApplyTeam{Name: "acme"}, me)
+	require.Error(t, err)
+	_, err = planTeamChanges(name, state, keybase1.TeamApplyTeam{
+		Name:    "acme",
+		Members: []keybase1.TeamApplyMember{{Username: "alice", Role: keybase1.TeamRole_ADMIN}},
+	}, me)
+	require.Error(t, err)
+}
+
+func applyChangeStrings(changes []keybase1.TeamApplyChange) (res []string) {
+	for _, c := range changes {
+		res = append(res, c.TeamName+" "+c.Type.String()+" "+c.Username)
+	}
+	return res
+}
+
+func TestApply(t *testing.T) {
+	tc, owner, otherA, otherB, name := memberSetupMultiple(t)
+	defer tc.Cleanup()
+	ctx := context.TODO()
+	subName := name + ".eng"
+
+	spec := []keybase1.TeamApplyTeam{
+		{
+			Name: subName,
+			Members: []keybase1.TeamApplyMember{
+				{Username: otherB.Username, Role: keybase1.TeamRole_READER},
+			},
+		},
+		{
+			Name: name,
+			Members: []keybase1.TeamApplyMember{
+				{Username: owner.Username, Role: keybase1.TeamRole_OWNER},
+				{Username: otherA.Username, Role: keybase1.TeamRole_WRITER},
+			},
+			Settings: &keybase1.TeamSettings{Open: true, JoinAs: keybase1.TeamRole_READER},
+		},
+	}
+	expected := []string{
+		name + " ADD_MEMBER " + otherA.Username,
+		name + " CHANGE_SETTINGS ",
+		subName + " CREATE_SUBTEAM ",
+		subName + " ADD_MEMBER " + otherB.Username,
+	}
+
+	res, err := Apply(ctx, tc.G, spec, true /* dryRun */)
+	require.NoError(t, err)
+	require.Equal(t, expected, applyChangeStrings(res.Changes))
+	require.False(t, res.Applied)
+	require.Empty(t, res.Teams)
+	role, err := MemberRole(ctx, tc.G, name, otherA.Username)
+	require.NoError(t, err)
+	require.Equal(t, keybase1.TeamRole_NONE, role)
+	t.Logf("a dry run changes nothing")
+
+	res, err = Apply(ctx, tc.G, spec, false /* dryRun */)
+	require.NoError(t, err)
+	require.Equal(t, expected, applyChangeStrings(res.Changes))
+	require.True(t, res.Applied)
+	require.Equal(t, []keybase1.TeamApplyTeamResult{
+		{TeamName: name, Applied: true},
+		{TeamName: subName, Applied: true},
+	}, res.Teams)
+
+	role, err = MemberRole(ctx, tc.G, name, otherA.Username)
+	require.NoError(t, err)
+	require.Equal(t, keybase1.TeamRole_WRITER, role)
+	role, err = MemberRole(ctx, tc.G, subName, otherB.Username)
+	require.NoError(t, err)
+	require.Equal(t, keybase1.TeamRole_READER, role)
+	team, err := Load(ctx, tc.G, keybase1.LoadTeamArg{Name: name, ForceRepoll: true})
+	require.NoError(t, err)
+	require.True(t, team.IsOpen())
+	require.Equal(t, keybase1.TeamRole_READER, team.OpenTeamJoinAs())
+
+	res, err = Apply(ctx, tc.G, spec, false /* dryRun */)
+	require.NoError(t, err)
+	require.Empty(t, res.Changes)
+	require.Empty(t, res.Teams)
+	t.Logf("applying the same spec again has nothing to do")
+}
+
+func TestApplyStopsAtFailedTeam(t *testing.T) {
+	tc, owner, otherA, _, name := memberSetupMultiple(t)
+	defer tc.Cleanup()
+	ctx := context.TODO()
+	badName := name + ".bad"
+	laterName := name + ".later"
+
+	res, err := Apply(ctx, tc.G, []keybase1.TeamApplyTeam{
+		{
+			Name: name,
+			Members: []keybase1.TeamApplyMember{
+				{Username: owner.Username, Role: keybase1.TeamRole_OWNER},
+				{Username: otherA.Username, Role: keybase1.TeamRole_WRITER},
+			},
+		},
+		{
+			Name: badName,
+			// not a user, which isn't found out until the member is added
+			Members: []keybase1.TeamApplyMember{{Username: "nosuchapplyuser", Role: keybase1.TeamRole_READER}},
+		},
+		{Name: laterName},
+	}, false /* dryRun */)
+	require.NoError(t, err)
+	require.False(t, res.Applied)
+	require.Len(t, res.Teams, 2)
+	require.Equal(t, keybase1.TeamApplyTeamResult{TeamName: name, Applied: true}, res.Teams[0])
+	require.Equal(t, badName, res.Teams[1].TeamName)
+	require.False(t, res.Teams[1].Applied)
+	require.NotNil(t, res.Teams[1].Error)
+
+	role, err := MemberRole(ctx, tc.G, name, otherA.Username)
+	require.NoError(t, err)
+	require.Equal(t, keybase1.TeamRole_WRITER, role)
+	_, err = Load(ctx, tc.G, keybase1.LoadTeamArg{Name: laterName, ForceRepoll: true})
+	require.Error(t, err, "teams after the failed one aren't attempted")
+}
@@ -0,0 +1,521 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package teams
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/merkletree2"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/adamwalz/keybase-client/go/sig3"
+	"github.com/adamwalz/keybase-client/go/teams/hidden"
+	jsonw "github.com/keybase/go-jsonw"
+)
+
+const chainBundleVersion = 2
+
+// chainBundle is what `keybase team export-chain` writes out: everything
+// needed to replay the sigchain of a team without asking the server for
+// anything.
+type chainBundle struct {
+	Version    int             `json:"version"`
+	TeamID     keybase1.TeamID `json:"team_id"`
+	Name       string          `json:"name"`
+	ExportedAt keybase1.Time   `json:"exported_at"`
+	// The team last, preceded by its ancestors, root first. Their chains are
+	// needed to check the links signed by implicit admins.
+	Teams []chainBundleTeam `json:"teams"`
+	// The sigchains of everyone who signed a link, which their key families
+	// are computed from.
+	Users map[keybase1.UID]libkb.ExportedUser `json:"users"`
+	// Historical merkle paths for the proofs that links were signed before
+	// keys were revoked, and by admins before they were demoted.
+	Proofs []chainBundlePath `json:"proofs"`
+}
+
+type chainBundleTeam struct {
+	ID    keybase1.TeamID   `json:"id"`
+	Chain []json.RawMessage `json:"chain"`
+	// Only exported for the team itself, not for its ancestors.
+	Hidden                []sig3.ExportJSON             `json:"hidden,omitempty"`
+	RatchetBlindingKeySet *hidden.RatchetBlindingKeySet `json:"ratchet_blinding_keys,omitempty"`
+	// The merkle/path response the chain tails are checked against.
+	MerklePath json.RawMessage `json:"merkle_path"`
+}
+
+type chainBundlePath struct {
+	LeafID   keybase1.UserOrTeamID `json:"leaf_id"`
+	HashMeta keybase1.HashMeta     `json:"hash_meta"`
+	Path     json.RawMessage       `json:"path"`
+}
+
+func (b *chainBundle) findPath(leafID keybase1.UserOrTeamID, hm keybase1.HashMeta) *chainBundlePath {
+	for i, p := range b.Proofs {
+		if p.LeafID.Equal(leafID) && p.HashMeta.Eq(hm) {
+			return &b.Proofs[i]
+		}
+	}
+	return nil
+}
+
+// chainBundleWorld is a LoaderContext that answers from a chainBundle. While
+// exporting (online) it fills the bundle in with whatever the replay asks
+// for; while verifying it never goes to the network.
+type chainBundleWorld struct {
+	libkb.Contextified
+	bundle *chainBundle
+	online bool
+	// Key families verified from bundle.Users so far.
+	upaks map[keybase1.UID]*keybase1.UserPlusKeysV2AllIncarnations
+}
+
+var _ LoaderContext = (*chainBundleWorld)(nil)
+
+func newChainBundleWorld(g *libkb.GlobalContext, bundle *chainBundle, online bool) *chainBundleWorld {
+	return &chainBundleWorld{
+		Contextified: libkb.NewContextified(g),
+		bundle:       bundle,
+		online:       online,
+		upaks:        make(map[keybase1.UID]*keybase1.UserPlusKeysV2AllIncarnations),
+	}
+}
+
+// loadUser returns the key family of uid, computed from the sigchain in the
+// bundle, which is exported first if the world is online.
+func (w *chainBundleWorld) loadUser(ctx context.Context, uid keybase1.UID) (*keybase1.UserPlusKeysV2AllIncarnations, error) {
+	if upak, ok := w.upaks[uid]; ok {
+		return upak, nil
+	}
+	mctx := w.MetaContext(ctx)
+	exp, ok := w.bundle.Users[uid]
+	if !ok {
+		if !w.online {
+			return nil, errNotInChainBundle(fmt.Sprintf("the sigchain of %v", uid))
+		}
+		tmp, err := libkb.ExportUser(mctx, uid)
+		if err != nil {
+			return nil, err
+		}
+		exp = *tmp
+		w.bundle.Users[uid] = exp
+	}
+	if exp.UID.NotEqual(uid) {
+		return nil, fmt.Errorf("chain export has the sigchain of %v in place of %v", exp.UID, uid)
+	}
+	upak, err := libkb.VerifyExportedUser(mctx, exp)
+	if err != nil {
+		return nil, err
+	}
+	w.upaks[uid] = upak
+	return upak, nil
+}
+
+func errNotInChainBundle(what string) error {
+	return fmt.Errorf("%s isn't available when replaying an exported chain", what)
+}
+
+func (w *chainBundleWorld) getNewLinksFromServer(ctx context.Context,
+	teamID keybase1.TeamID, lows getLinksLows, readSubteamID *keybase1.TeamID) (*rawTeam, error) {
+	return nil, errNotInChainBundle(fmt.Sprintf("the chain of %v", teamID))
+}
+
+func (w *chainBundleWorld) getLinksFromServer(ctx context.Context,
+	teamID keybase1.TeamID, requestSeqnos []keybase1.Seqno, readSubteamID *keybase1.TeamID) (*rawTeam, error) {
+	return nil, errNotInChainBundle(fmt.Sprintf("the chain of %v", teamID))
+}
+
+func (w *chainBundleWorld) getMe(context.Context) (keybase1.UserVersion, error) {
+	return keybase1.UserVersion{}, nil
+}
+
+func (w *chainBundleWorld) lookupEldestSeqno(ctx context.Context, uid keybase1.UID) (keybase1.Seqno, error) {
+	upak, err := w.loadUser(ctx, uid)
+	if err != nil {
+		return 0, err
+	}
+	return upak.Current.EldestSeqno, nil
+}
+
+func (w *chainBundleWorld) perUserEncryptionKey(ctx context.Context, userSeqno keybase1.Seqno) (*libkb.NaclDHKeyPair, error) {
+	return nil, errNotInChainBundle("the per-user key")
+}
+
+func (w *chainBundleWorld) merkleLookup(ctx context.Context, teamID keybase1.TeamID, public bool) (r1 keybase1.Seqno, r2 keybase1.LinkID, err error) {
+	return r1, r2, errNotInChainBundle("the current merkle root")
+}
+
+func (w *chainBundleWorld) merkleLookupWithHidden(ctx context.Context, teamID keybase1.TeamID, public bool) (r1 keybase1.Seqno, r2 keybase1.LinkID, hiddenResp *libkb.MerkleHiddenResponse, lastMerkleRoot *libkb.MerkleRoot, err error) {
+	return r1, r2, nil, nil, errNotInChainBundle("the current merkle root")
+}
+
+// merkleLookupTripleInPast does what LoaderContextG's does, but against the
+// merkle paths in the bundle. The paths are verified again every time, so a
+// bundle can't make a proof pass that wouldn't have passed online.
+func (w *chainBundleWorld) merkleLookupTripleInPast(ctx context.Context, isPublic bool, leafID keybase1.UserOrTeamID, root keybase1.MerkleRootV2) (triple *libkb.MerkleTriple, err error) {
+	mctx := w.MetaContext(ctx)
+	mc := w.G().MerkleClient
+
+	// Pre-checkpoint lookups are bumped forward to the checkpoint, as in
+	// LoaderContextG.
+	var checkpoint keybase1.Seqno
+	if chk := mc.FirstExaminableHistoricalRoot(mctx); chk != nil && root.Seqno < *chk {
+		checkpoint = *chk
+	}
+
+	p := w.bundle.findPath(leafID, root.HashMeta)
+	if p == nil {
+		if !w.online {
+			return nil, errNotInChainBundle(fmt.Sprintf("the merkle path to %v at root %d", leafID, root.Seqno))
+		}
+		hm := root.HashMeta
+		if checkpoint > 0 {
+			hm = nil
+		}
+		res, err := mc.ExportLeafPath(mctx, leafID, hm, checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		w.bundle.Proofs = append(w.bundle.Proofs, chainBundlePath{LeafID: leafID, HashMeta: root.HashMeta, Path: res})
+		p = &w.bundle.Proofs[len(w.bundle.Proofs)-1]
+	}
+
+	leaf, mroot, err := mc.VerifyExportedLeafPath(mctx, leafID, p.Path)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint > 0 {
+		if *mroot.Seqno() != checkpoint {
+			return nil, fmt.Errorf("merkle path for %v is at root %d, not at the checkpoint %d", leafID, *mroot.Seqno(), checkpoint)
+		}
+	} else if !mroot.HashMeta().Eq(root.HashMeta) {
+		return nil, fmt.Errorf("merkle path for %v is at the wrong root: %v != %v", leafID, mroot.HashMeta(), root.HashMeta)
+	}
+
+	if leaf == nil {
+		return nil, fmt.Errorf("unexpected nil leaf for %v", leafID)
+	}
+	if isPublic {
+		triple = leaf.Public
+	} else {
+		triple = leaf.Private
+	}
+	if triple == nil {
+		return nil, fmt.Errorf("unexpected nil leaf for %v", leafID)
+	}
+	return triple, nil
+}
+
+// forceLinkMapRefreshForUser doesn't refresh anything: the sigchains in the
+// bundle were all exported after the links that refer to them.
+func (w *chainBundleWorld) forceLinkMapRefreshForUser(ctx context.Context, uid keybase1.UID) (linkMap linkMapT, err error) {
+	upak, err := w.loadUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return upak.SeqnoLinkIDs, nil
+}
+
+func (w *chainBundleWorld) loadKeyV2(ctx context.Context, uid keybase1.UID, kid keybase1.KID, lkc *loadKeyCache) (
+	uv keybase1.UserVersion, pubKey *keybase1.PublicKeyV2NaCl, linkMap linkMapT, err error) {
+	upak, err := w.loadUser(ctx, uid)
+	if err != nil {
+		return uv, nil, nil, err
+	}
+	for _, user := range append(upak.PastIncarnations, upak.Current) {
+		if key, ok := user.DeviceKeys[kid]; ok {
+			return user.ToUserVersion(), &key, upak.SeqnoLinkIDs, nil
+		}
+	}
+	return uv, nil, nil, libkb.NotFoundError{Msg: "Not found: Key for user"}
+}
+
+// bundleHiddenResponse checks the blind merkle tree proof for the hidden
+// chain of a team in a merkle/path response, like
+// hidden.ProcessHiddenResponseFunc but without asking the server whether the
+// team supports hidden chains.
+func bundleHiddenResponse(mctx libkb.MetaContext, teamID keybase1.TeamID, res []byte, root *libkb.MerkleRoot) (*libkb.MerkleHiddenResponse, error) {
+	body, err := jsonw.Unmarshal(res)
+	if err != nil {
+		return nil, err
+	}
+	blindRootHashStr := root.BlindMerkleRootHash()
+	if blindRootHashStr == "" {
+		// As in ProcessHiddenResponseFunc, trust the server for the blind
+		// root if it isn't in the main tree yet.
+		blindRootHashStr, err = body.AtKey("last_blind_root_hash").GetString()
+		if err != nil {
+			return &libkb.MerkleHiddenResponse{RespType: libkb.MerkleHiddenResponseTypeNONE}, nil
+		}
+	}
+	blindRootHash, err := hex.DecodeString(blindRootHashStr)
+	if err != nil {
+		return nil, err
+	}
+	return hidden.ParseAndVerifyCommittedHiddenLinkID(mctx, teamID, &libkb.APIRes{Body: body}, merkletree2.Hash(blindRootHash))
+}
+
+// verifyChainBundle replays the chains in the bundle with the regular link
+// verification and TeamSigChainPlayer, checks that their tails are the ones
+// in the merkle tree, that the hidden chain is consistent with the blind
+// tree and the main chain, and that the happens-before proofs hold. l.world
+// must be a chainBundleWorld for the same bundle.
+func (l *TeamLoader) verifyChainBundle(mctx libkb.MetaContext, b *chainBundle) (res keybase1.TeamChainVerification, err error) {
+	ctx := mctx.Ctx()
+	defer mctx.Trace(fmt.Sprintf("TeamLoader#verifyChainBundle(%s)", b.TeamID), &err)()
+
+	if b.Version != chainBundleVersion {
+		return res, fmt.Errorf("unsupported chain export version %d", b.Version)
+	}
+	if len(b.Teams) == 0 || !b.Teams[len(b.Teams)-1].ID.Eq(b.TeamID) {
+		return res, fmt.Errorf("chain export doesn't contain the chain of %v", b.TeamID)
+	}
+
+	var me keybase1.UserVersion
+	proofSet := newProofSet(l.G())
+	lkc := newLoadKeyCache()
+	parentsCache := make(parentChainCache)
+	signers := make(map[keybase1.UID]bool)
+	var nameParts []keybase1.TeamNamePart
+
+	for i, bt := range b.Teams {
+		isTarget := i == len(b.Teams)-1
+
+		leaf, root, err := l.G().MerkleClient.VerifyExportedLeafPath(mctx, bt.ID.AsUserOrTeam(), bt.MerklePath)
+		if err != nil {
+			return res, err
+		}
+		var triple *libkb.MerkleTriple
+		if leaf != nil {
+			triple = leaf.Private
+			if bt.ID.IsPublic() {
+				triple = leaf.Public
+			}
+		}
+		if triple == nil {
+			return res, fmt.Errorf("%v isn't in the merkle tree", bt.ID)
+		}
+
+		hiddenPackage := hidden.NewLoaderPackageForPrecheck(mctx, bt.ID, nil)
+		hiddenPackage.SetRatchetBlindingKeySet(bt.RatchetBlindingKeySet)
+		var hiddenResp *libkb.MerkleHiddenResponse
+		if isTarget {
+			hiddenResp, err = bundleHiddenResponse(mctx, bt.ID, bt.MerklePath, root)
+			if err != nil {
+				return res, err
+			}
+			if hiddenResp.RespType != libkb.MerkleHiddenResponseTypeNONE {
+				if _, err = hiddenPackage.CheckHiddenMerklePathResponseAndAddRatchets(mctx, hiddenResp); err != nil {
+					return res, err
+				}
+			}
+		}
+
+		links, err := (&rawTeam{ID: bt.ID, Chain: bt.Chain}).unpackLinks(mctx)
+		if err != nil {
+			return res, err
+		}
+		var state *keybase1.TeamData
+		var prev libkb.LinkID
+		for j, link := range links {
+			if !link.Prev().Eq(prev) {
+				return res, NewPrevError("team replay failed: prev chain broken at link %d (%v != %v)",
+					j, link.Prev(), prev)
+			}
+			if err := consumeRatchets(mctx, hiddenPackage, link); err != nil {
+				return res, err
+			}
+			if err := checkPTKGenerationNotOnHiddenChain(mctx, hiddenPackage, link); err != nil {
+				return res, err
+			}
+			signer, err := l.verifyLink(ctx, bt.ID, state, me, link, 0 /* fullVerifyCutoff */, b.TeamID,
+				proofSet, lkc, parentsCache)
+			if err != nil {
+				return res, err
+			}
+			if signer != nil {
+				signers[signer.signer.Uid] = true
+			} else if isTarget {
+				res.StubbedLinks++
+			}
+			state, err = l.applyNewLink(ctx, state, hiddenPackage.ChainData(), link, signer, me)
+			if err != nil {
+				return res, err
+			}
+			// Admin permissions are looked up in the chains of ancestors,
+			// which have to come from the bundle too.
+			if parentID := state.Chain.ParentID; j == 0 && parentID != nil {
+				if _, ok := parentsCache[*parentID]; !ok {
+					return res, fmt.Errorf("chain export is missing %v, the parent of %v", *parentID, bt.ID)
+				}
+			}
+			prev = link.LinkID()
+		}
+		if state == nil {
+			return res, fmt.Errorf("chain export has no links for %v", bt.ID)
+		}
+		if state.Chain.LastSeqno != triple.Seqno || !state.Chain.LastLinkID.Eq(triple.LinkID.Export()) {
+			return res, fmt.Errorf("chain of %v ends at %d (%v), but the merkle tree has %d (%v)",
+				bt.ID, state.Chain.LastSeqno, state.Chain.LastLinkID, triple.Seqno, triple.LinkID.Export())
+		}
+		proofSet.SetTeamLinkMap(ctx, bt.ID, state.Chain.LinkIDs)
+		nameParts = append(nameParts, TeamSigChainState{inner: state.Chain}.LatestLastNamePart())
+
+		if !isTarget {
+			parentsCache[bt.ID] = state
+			continue
+		}
+
+		// As in load2, the hidden chain goes after the main chain, since the
+		// latter can ratchet the former.
+		if err = hiddenPackage.Update(mctx, bt.Hidden, hiddenResp.GetUncommittedSeqno()); err != nil {
+			return res, err
+		}
+		err = hiddenPackage.CheckPTKsForDuplicates(mctx, func(g keybase1.PerTeamKeyGeneration) bool {
+			_, ok := state.Chain.PerTeamKeys[g]
+			return ok
+		})
+		if err != nil {
+			return res, err
+		}
+		if err = hiddenPackage.CheckParentPointersOnFullLoad(mctx, state); err != nil {
+			return res, err
+		}
+
+		res.TeamID = bt.ID
+		res.TeamName = keybase1.TeamName{Parts: nameParts}.String()
+		res.LastSeqno = state.Chain.LastSeqno
+		res.LastLinkID = state.Chain.LastLinkID
+		res.LastHiddenSeqno = hiddenPackage.LastSeqno()
+		res.MerkleSeqno = *root.Seqno()
+		res.MerkleTime = keybase1.TimeFromSeconds(root.Ctime())
+	}
+
+	if proofSet.checkRequired() {
+		if err = proofSet.check(ctx, l.world, false /* parallel */); err != nil {
+			return res, err
+		}
+	}
+
+	res.Ancestors = len(b.Teams) - 1
+	res.Signers = len(signers)
+	res.HappensBeforeProofs = len(proofSet.AllProofs())
+	res.ExportedAt = b.ExportedAt
+	return res, nil
+}
+
+// ExportChain writes out the sigchain of a team, its hidden chain, the chains
+// of its ancestors, the merkle paths they are anchored to and the sigchains of
+// everyone who signed them, as JSON that VerifyChain can check offline.
+// The bundle is verified while it is being made.
+func ExportChain(ctx context.Context, g *libkb.GlobalContext, teamName string) (res string, err error) {
+	mctx := libkb.NewMetaContext(ctx, g)
+	defer mctx.Trace(fmt.Sprintf("teams.ExportChain(%s)", teamName), &err)()
+
+	team, err := Load(ctx, g, keybase1.LoadTeamArg{
+		Name:        teamName,
+		ForceRepoll: true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The team and its ancestors, root first.
+	ids := []keybase1.TeamID{team.ID}
+	for name := team.Name(); !name.IsRootTeam(); {
+		if name, err = name.Parent(); err != nil {
+			return "", err
+		}
+		id, err := ResolveNameToID(ctx, g, name)
+		if err != nil {
+			return "", err
+		}
+		ids = append([]keybase1.TeamID{id}, ids...)
+	}
+
+	bundle := &chainBundle{
+		Version:    chainBundleVersion,
+		TeamID:     team.ID,
+		Name:       team.Name().String(),
+		ExportedAt: keybase1.ToTime(g.Clock().Now()),
+		Users:      make(map[keybase1.UID]libkb.ExportedUser),
+	}
+	world := NewLoaderContextFromG(g)
+	for _, id := range ids {
+		isTarget := id.Eq(team.ID)
+		// Get the merkle path first, so the chain is at least as long as
+		// the leaf says.
+		path, err := g.MerkleClient.ExportLeafPath(mctx, id.AsUserOrTeam(), nil, 0)
+		if err != nil {
+			return "", err
+		}
+		leaf, _, err := g.MerkleClient.VerifyExportedLeafPath(mctx, id.AsUserOrTeam(), path)
+		if err != nil {
+			return "", err
+		}
+		triple := leaf.Private
+		if id.IsPublic() {
+			triple = leaf.Public
+		}
+		if triple == nil {
+			return "", fmt.Errorf("%v isn't in the merkle tree", id)
+		}
+
+		var readSubteamID *keybase1.TeamID
+		if !isTarget {
+			readSubteamID = &team.ID
+		}
+		raw, err := world.getNewLinksFromServer(ctx, id, getLinksLows{}, readSubteamID)
+		if err != nil {
+			return "", err
+		}
+		chain := raw.Chain
+		if len(chain) > int(triple.Seqno) {
+			// Added after the merkle lookup.
+			chain = chain[:triple.Seqno]
+		}
+		bt := chainBundleTeam{
+			ID:         id,
+			Chain:      chain,
+			MerklePath: path,
+		}
+		if isTarget {
+			bt.Hidden = raw.HiddenChain
+			bt.RatchetBlindingKeySet = raw.RatchetBlindingKeySet
+		}
+		bundle.Teams = append(bundle.Teams, bt)
+	}
+
+	// Replaying the chains fills in the sigchains of the signers and the
+	// historical merkle paths.
+	l := NewTeamLoader(g, newChainBundleWorld(g, bundle, true /* online */), nil, nil)
+	if _, err = l.verifyChainBundle(mctx, bundle); err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// VerifyChain checks a bundle made by ExportChain, without the network.
+func VerifyChain(ctx context.Context, g *libkb.GlobalContext, bundle string) (res keybase1.TeamChainVerification, err error) {
+	mctx := libkb.NewMetaContext(ctx, g)
+	defer mctx.Trace("teams.VerifyChain", &err)()
+
+	var b chainBundle
+	if err = json.Unmarshal([]byte(bundle), &b); err != nil {
+		return res, fmt.Errorf("reading chain export: %v", err)
+	}
+	if b.Users == nil {
+		b.Users = make(map[keybase1.UID]libkb.ExportedUser)
+	}
+	l := NewTeamLoader(g, newChainBundleWorld(g, &b, false /* online */), nil, nil)
+	return l.verifyChainBundle(mctx, &b)
+}
@@ -0,0 +1,175 @@
+package teams
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainBundleWorldOffline(t *testing.T) {
+	tc := SetupTest(t, "team", 1)
+	defer tc.Cleanup()
+
+	uid := keybase1.MakeTestUID(1)
+	oldKID := keybase1.KID("0120aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa0a")
+	newKID := keybase1.KID("0120bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb0a")
+	incarnation := func(eldest keybase1.Seqno, kid keybase1.KID) keybase1.UserPlusKeysV2 {
+		return keybase1.UserPlusKeysV2{
+			Uid:         uid,
+			EldestSeqno: eldest,
+			DeviceKeys: map[keybase1.KID]keybase1.PublicKeyV2NaCl{
+				kid: {Base: keybase1.PublicKeyV2Base{Kid: kid}},
+			},
+		}
+	}
+	upak := keybase1.UserPlusKeysV2AllIncarnations{
+		Current:          incarnation(5, newKID),
+		PastIncarnations: []keybase1.UserPlusKeysV2{incarnation(1, oldKID)},
+		SeqnoLinkIDs:     map[keybase1.Seqno]keybase1.LinkID{1: "aa", 5: "bb"},
+	}
+	bundle := chainBundle{
+		Version: chainBundleVersion,
+		Users:   make(map[keybase1.UID]libkb.ExportedUser),
+		Proofs: []chainBundlePath{{
+			LeafID:   uid.AsUserOrTeam(),
+			HashMeta: keybase1.HashMeta("hm"),
+		}},
+	}
+
+	// The bundle has to survive being written out and read back.
+	b, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	var read chainBundle
+	require.NoError(t, json.Unmarshal(b, &read))
+	world := newChainBundleWorld(tc.G, &read, false /* online */)
+	// As if uid's sigchain had been verified already.
+	world.upaks[uid] = &upak
+	ctx := context.TODO()
+
+	// Keys are found in past incarnations too, with the matching user version.
+	uv, key, linkMap, err := world.loadKeyV2(ctx, uid, oldKID, nil)
+	require.NoError(t, err)
+	require.Equal(t, NewUserVersion(uid, 1), uv)
+	require.Equal(t, oldKID, key.Base.Kid)
+	require.Equal(t, keybase1.LinkID("bb"), linkMap[5])
+	uv, _, _, err = world.loadKeyV2(ctx, uid, newKID, nil)
+	require.NoError(t, err)
+	require.Equal(t, NewUserVersion(uid, 5), uv)
+	_, _, _, err = world.loadKeyV2(ctx, uid, "0120cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc0a", nil)
+	require.Error(t, err)
+	_, _, _, err = world.loadKeyV2(ctx, keybase1.MakeTestUID(2), newKID, nil)
+	require.Error(t, err)
+
+	eldest, err := world.lookupEldestSeqno(ctx, uid)
+	require.NoError(t, err)
+	require.Equal(t, keybase1.Seqno(5), eldest)
+
+	require.NotNil(t, read.findPath(uid.AsUserOrTeam(), keybase1.HashMeta("hm")))
+	require.Nil(t, read.findPath(uid.AsUserOrTeam(), keybase1.HashMeta("other")))
+
+	// Nothing goes to the server.
+	_, err = world.getNewLinksFromServer(ctx, "", getLinksLows{}, nil)
+	require.Error(t, err)
+	_, err = world.merkleLookupTripleInPast(ctx, false, keybase1.MakeTestUID(2).AsUserOrTeam(),
+		keybase1.MerkleRootV2{Seqno: 1, HashMeta: keybase1.HashMeta("other")})
+	require.Error(t, err)
+
+	// Bundles that can't be verified are rejected up front.
+	_, err = VerifyChain(ctx, tc.G, `{"version": 1}`)
+	require.Error(t, err)
+	_, err = VerifyChain(ctx, tc.G, `{"version": 2, "team_id": "1d5a3cc8ab5a03dfd0cab8a8a2764924", "teams": []}`)
+	require.Error(t, err)
+	_, err = VerifyChain(ctx, tc.G, `not json`)
+	require.Error(t, err)
+}
+
+func TestExportChainRoundTrip(t *testing.T) {
+	tc, owner, otherA, _, name := memberSetupMultiple(t)
+	defer tc.Cleanup()
+	ctx := context.TODO()
+
+	_, err := AddMember(ctx, tc.G, name, otherA.Username, keybase1.TeamRole_WRITER, nil)
+	require.NoError(t, err)
+	parentName, err := keybase1.TeamNameFromString(name)
+	require.NoError(t, err)
+	subteamName, subteamID := createSubteam(&tc, parentName, "sub")
+	_, err = AddMember(ctx, tc.G, subteamName.String(), otherA.Username, keybase1.TeamRole_READER, nil)
+	require.NoError(t, err)
+
+	export, err := ExportChain(ctx, tc.G, subteamName.String())
+	require.NoError(t, err)
+
+	subteam, err := Load(ctx, tc.G, keybase1.LoadTeamArg{ID: subteamID, ForceRepoll: true})
+	require.NoError(t, err)
+	otherExport, err := libkb.ExportUser(libkb.NewMetaContextForTest(tc), otherA.GetUID())
+	require.NoError(t, err)
+
+	// Verifying doesn't touch the network.
+	origAPI := tc.G.API
+	tc.G.API = &libkb.ErrorMockAPI{}
+	defer func() { tc.G.API = origAPI }()
+
+	res, err := VerifyChain(ctx, tc.G, export)
+	require.NoError(t, err)
+	require.Equal(t, subteamID, res.TeamID)
+	require.Equal(t, subteamName.String(), res.TeamName)
+	require.Equal(t, 1, res.Ancestors)
+	require.Equal(t, 1, res.Signers)
+	require.Equal(t, subteam.CurrentSeqno(), res.LastSeqno)
+
+	var bundle chainBundle
+	require.NoError(t, json.Unmarshal([]byte(export), &bundle))
+	require.Len(t, bundle.Teams, 2)
+	require.Contains(t, bundle.Users, owner.GetUID())
+
+	tampered := func(desc string, f func(b *chainBundle)) {
+		var b chainBundle
+		require.NoError(t, json.Unmarshal([]byte(export), &b))
+		f(&b)
+		out, err := json.Marshal(b)
+		require.NoError(t, err)
+		_, err = VerifyChain(ctx, tc.G, string(out))
+		require.Error(t, err, desc)
+		t.Logf("%s: %v", desc, err)
+	}
+	tampered("old version", func(b *chainBundle) {
+		b.Version = 1
+	})
+	tampered("truncated chain", func(b *chainBundle) {
+		target := &b.Teams[1]
+		target.Chain = target.Chain[:len(target.Chain)-1]
+	})
+	tampered("link from another chain", func(b *chainBundle) {
+		b.Teams[1].Chain[0] = b.Teams[0].Chain[0]
+	})
+	tampered("missing parent", func(b *chainBundle) {
+		b.Teams = b.Teams[1:]
+	})
+	tampered("missing signer", func(b *chainBundle) {
+		delete(b.Users, owner.GetUID())
+	})
+	tampered("truncated signer sigchain", func(b *chainBundle) {
+		u := b.Users[owner.GetUID()]
+		u.Sigs = json.RawMessage(`{"status": {"code": 0}, "sigs": []}`)
+		b.Users[owner.GetUID()] = u
+	})
+	tampered("signer key family from someone else", func(b *chainBundle) {
+		u := b.Users[owner.GetUID()]
+		u.Them = otherExport.Them
+		b.Users[owner.GetUID()] = u
+	})
+	tampered("signer sigchain from someone else", func(b *chainBundle) {
+		u := b.Users[owner.GetUID()]
+		u.Sigs = otherExport.Sigs
+		b.Users[owner.GetUID()] = u
+	})
+	tampered("signer merkle path from someone else", func(b *chainBundle) {
+		u := b.Users[owner.GetUID()]
+		u.MerklePath = b.Teams[0].MerklePath
+		b.Users[owner.GetUID()] = u
+	})
+}
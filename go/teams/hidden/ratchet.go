@@ -160,6 +160,23 @@ func (r *RatchetBlindingKeySet) UnmarshalJSON(b []byte) error {
 	return err
 }
 
+// MarshalJSON is the inverse of UnmarshalJSON, used when a team/get response
+// is exported along with a team's sigchain.
+func (r RatchetBlindingKeySet) MarshalJSON() ([]byte, error) {
+	if len(r.m) == 0 {
+		return []byte("null"), nil
+	}
+	arr := make([]RatchetObj, 0, len(r.m))
+	for _, e := range r.m {
+		arr = append(arr, e)
+	}
+	b, err := msgpack.Encode(arr)
+	if err != nil {
+		return nil, err
+	}
+	return keybase1.Quote(base64.StdEncoding.EncodeToString(b)), nil
+}
+
 func (r *SCTeamRatchet) MarshalJSON() ([]byte, error) {
 	s := hex.EncodeToString((*r)[:])
 	b := keybase1.Quote(s)
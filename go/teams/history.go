@@ -0,0 +1,393 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package teams
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+)
+
+// replayChain fetches the whole sigchain of a team that has already been
+// loaded, and plays it again from the first link with the
+// TeamSigChainPlayer, calling fn with the state of the chain before (nil for
+// the first link) and after every link that want picks. The state before a
+// link is a copy, so it's only made for the links fn gets called with. Links
+// are only accepted if they match the link IDs of the loaded (and fully
+// verified) team, so the replay doesn't have to check proofs against other
+// sigchains again.
+func (l *TeamLoader) replayChain(mctx libkb.MetaContext, team *Team, want func(link *ChainLinkUnpacked) bool,
+	fn func(prev *TeamSigChainState, next TeamSigChainState, link *ChainLinkUnpacked, signer *SignerX) error) (err error) {
+	ctx := mctx.Ctx()
+	defer mctx.Trace(fmt.Sprintf("TeamLoader#replayChain(%s)", team.ID), &err)()
+
+	me, err := l.world.getMe(ctx)
+	if err != nil {
+		return err
+	}
+	verified := team.chain()
+	lastSeqno := verified.GetLatestSeqno()
+
+	teamUpdate, err := l.world.getNewLinksFromServer(ctx, team.ID, getLinksLows{}, nil)
+	if err != nil {
+		return err
+	}
+	links, err := teamUpdate.unpackLinks(mctx)
+	if err != nil {
+		return err
+	}
+
+	var state *keybase1.TeamData
+	var prev libkb.LinkID
+	proofSet := newProofSet(l.G())
+	lkc := newLoadKeyCache()
+	parentsCache := make(parentChainCache)
+	for i, link := range links {
+		if link.Seqno() > lastSeqno {
+			// Added since the team was loaded.
+			break
+		}
+		expected, err := verified.GetLibkbLinkIDBySeqno(link.Seqno())
+		if err != nil {
+			return err
+		}
+		if !expected.Eq(link.LinkID()) {
+			return fmt.Errorf("team replay failed: link %d doesn't match the loaded chain (%v != %v)",
+				link.Seqno(), link.LinkID(), expected)
+		}
+		if !link.Prev().Eq(prev) {
+			return NewPrevError("team replay failed: prev chain broken at link %d (%v != %v)",
+				i, link.Prev(), prev)
+		}
+
+		signer, err := l.verifyLink(ctx, team.ID, state, me, link, 0 /* fullVerifyCutoff */, team.ID,
+			proofSet, lkc, parentsCache)
+		if err != nil {
+			return err
+		}
+
+		// applyNewLink updates state in place
+		emit := want(link)
+		var prevState *TeamSigChainState
+		if emit && state != nil {
+			tmp := TeamSigChainState{inner: state.Chain.DeepCopy(), hidden: team.HiddenChain()}
+			prevState = &tmp
+		}
+		state, err = l.applyNewLink(ctx, state, team.HiddenChain(), link, signer, me)
+		if err != nil {
+			return err
+		}
+		if emit {
+			if err := fn(prevState, TeamSigChainState{inner: state.Chain, hidden: team.HiddenChain()}, link, signer); err != nil {
+				return err
+			}
+		}
+		prev = link.LinkID()
+	}
+
+	if state == nil || state.Chain.LastSeqno != lastSeqno {
+		return fmt.Errorf("team replay failed: server returned an incomplete chain for %s", team.ID)
+	}
+	return nil
+}
+
+// chainEvents lists the events a link made, by comparing the state of the
+// chain before (nil for the first link) and after it. The other fields of
+// base are copied into every event.
+func chainEvents(prev *TeamSigChainState, next TeamSigChainState,
+	base keybase1.TeamHistoryEvent) (res []keybase1.TeamHistoryEvent) {
+	event := func(typ keybase1.TeamHistoryEventType) keybase1.TeamHistoryEvent {
+		ret := base
+		ret.Type = typ
+		return ret
+	}
+
+	var prevInner keybase1.TeamSigChainState
+	if prev == nil {
+		e := event(keybase1.TeamHistoryEventType_CREATED)
+		e.Generation = next.inner.MaxPerTeamKeyGeneration
+		res = append(res, e)
+	} else {
+		prevInner = prev.inner
+	}
+
+	uvs := make([]keybase1.UserVersion, 0, len(next.inner.UserLog))
+	for uv := range next.inner.UserLog {
+		uvs = append(uvs, uv)
+	}
+	sort.Slice(uvs, func(i, j int) bool { return uvs[i].String() < uvs[j].String() })
+	for _, uv := range uvs {
+		points := next.inner.UserLog[uv]
+		for i := len(prevInner.UserLog[uv]); i < len(points); i++ {
+			oldRole := keybase1.TeamRole_NONE
+			if i > 0 {
+				oldRole = points[i-1].Role
+			}
+			newRole := points[i].Role
+			var e keybase1.TeamHistoryEvent
+			switch {
+			case oldRole == newRole:
+				continue
+			case oldRole == keybase1.TeamRole_NONE:
+				e = event(keybase1.TeamHistoryEventType_MEMBER_ADDED)
+			case newRole == keybase1.TeamRole_NONE:
+				e = event(keybase1.TeamHistoryEventType_MEMBER_REMOVED)
+			default:
+				e = event(keybase1.TeamHistoryEventType_ROLE_CHANGED)
+			}
+			member := uv
+			e.Member = &member
+			e.OldRole = oldRole
+			e.NewRole = newRole
+			res = append(res, e)
+		}
+	}
+
+	inviteIDs := make([]keybase1.TeamInviteID, 0, len(next.inner.InviteMetadatas))
+	for id := range next.inner.InviteMetadatas {
+		inviteIDs = append(inviteIDs, id)
+	}
+	sort.Slice(inviteIDs, func(i, j int) bool { return inviteIDs[i] < inviteIDs[j] })
+	for _, id := range inviteIDs {
+		md := next.inner.InviteMetadatas[id]
+		invite := md.Invite
+		inviteEvent := func(typ keybase1.TeamHistoryEventType) keybase1.TeamHistoryEvent {
+			e := event(typ)
+			e.Invite = &invite
+			e.NewRole = invite.Role
+			return e
+		}
+
+		prevCode := keybase1.TeamInviteMetadataStatusCode_ACTIVE
+		var prevUsed int
+		prevMD, existed := prevInner.InviteMetadatas[id]
+		if existed {
+			prevUsed = len(prevMD.UsedInvites)
+			if code, err := prevMD.Status.Code(); err == nil {
+				prevCode = code
+			}
+		} else {
+			res = append(res, inviteEvent(keybase1.TeamHistoryEventType_INVITE_ADDED))
+		}
+
+		for _, used := range md.UsedInvites[prevUsed:] {
+			e := inviteEvent(keybase1.TeamHistoryEventType_INVITE_COMPLETED)
+			member := used.Uv
+			e.Member = &member
+			res = append(res, e)
+		}
+		code, err := md.Status.Code()
+		if err != nil || code == prevCode {
+			continue
+		}
+		switch code {
+		case keybase1.TeamInviteMetadataStatusCode_CANCELLED:
+			res = append(res, inviteEvent(keybase1.TeamHistoryEventType_INVITE_CANCELED))
+		case keybase1.TeamInviteMetadataStatusCode_COMPLETED:
+			res = append(res, inviteEvent(keybase1.TeamHistoryEventType_INVITE_COMPLETED))
+		}
+	}
+
+	settings := keybase1.TeamSettings{Open: next.inner.Open, JoinAs: next.inner.OpenTeamJoinAs}
+	prevSettings := keybase1.TeamSettings{Open: prevInner.Open, JoinAs: prevInner.OpenTeamJoinAs}
+	if !teamSettingsEqual(settings, prevSettings) {
+		e := event(keybase1.TeamHistoryEventType_SETTINGS_CHANGED)
+		e.Settings = &settings
+		res = append(res, e)
+	}
+
+	if prev != nil && next.inner.MaxPerTeamKeyGeneration > prevInner.MaxPerTeamKeyGeneration {
+		e := event(keybase1.TeamHistoryEventType_KEY_ROTATED)
+		e.Generation = next.inner.MaxPerTeamKeyGeneration
+		res = append(res, e)
+	}
+
+	return res
+}
+
+// historyEntry is an event with where it goes in the history: hidden chain
+// links are ordered after the main chain link they point to.
+type historyEntry struct {
+	mainSeqno   keybase1.Seqno
+	hiddenSeqno keybase1.Seqno
+	event       keybase1.TeamHistoryEvent
+}
+
+func hiddenChainEvents(hiddenChain *keybase1.HiddenTeamChain) (res []historyEntry) {
+	if hiddenChain == nil {
+		return nil
+	}
+	for seqno, link := range hiddenChain.Inner {
+		ptk, ok := link.Ptk[keybase1.PTKType_READER]
+		if !ok {
+			continue
+		}
+		res = append(res, historyEntry{
+			mainSeqno:   link.ParentChain.Seqno,
+			hiddenSeqno: seqno,
+			event: keybase1.TeamHistoryEvent{
+				Type: keybase1.TeamHistoryEventType_KEY_ROTATED,
+				Location: keybase1.SigChainLocation{
+					Seqno:   seqno,
+					SeqType: keybase1.SeqType_TEAM_PRIVATE_HIDDEN,
+				},
+				Time:        hiddenChain.LinkReceiptTimes[seqno],
+				MerkleSeqno: link.MerkleRoot.Seqno,
+				Signer:      NewUserVersion(link.Signer.U, link.Signer.E),
+				SigningKID:  link.Signer.K,
+				Generation:  ptk.Ptk.Gen,
+			},
+		})
+	}
+	return res
+}
+
+// historyUsers looks up the usernames and devices in events, remembering
+// them since the same few admins tend to sign most of the links.
+type historyUsers struct {
+	g         *libkb.GlobalContext
+	usernames map[keybase1.UID]string
+	devices   map[keybase1.KID]string
+}
+
+func (h *historyUsers) username(ctx context.Context, uid keybase1.UID) string {
+	if name, ok := h.usernames[uid]; ok {
+		return name
+	}
+	name, err := h.g.GetUPAKLoader().LookupUsername(ctx, uid)
+	if err != nil {
+		h.g.Log.CDebugf(ctx, "team history: failed to look up username of %s: %v", uid, err)
+	}
+	h.usernames[uid] = name.String()
+	return name.String()
+}
+
+func (h *historyUsers) device(ctx context.Context, uid keybase1.UID, kid keybase1.KID) string {
+	if desc, ok := h.devices[kid]; ok {
+		return desc
+	}
+	var desc string
+	_, _, key, err := h.g.GetUPAKLoader().LoadKeyV2(ctx, uid, kid)
+	switch {
+	case err != nil:
+		h.g.Log.CDebugf(ctx, "team history: failed to load key %s of %s: %v", kid, uid, err)
+	case key != nil:
+		desc = key.DeviceDescription
+	}
+	h.devices[kid] = desc
+	return desc
+}
+
+func (h *historyUsers) annotate(ctx context.Context, e *keybase1.TeamHistoryEvent) {
+	if e.Signer.Uid.Exists() {
+		e.SignerUsername = h.username(ctx, e.Signer.Uid)
+		if e.SigningKID.Exists() {
+			e.SigningDevice = h.device(ctx, e.Signer.Uid, e.SigningKID)
+		}
+	}
+	if e.Member != nil {
+		e.MemberUsername = h.username(ctx, e.Member.Uid)
+	}
+}
+
+func historyEventMatches(e keybase1.TeamHistoryEvent, member keybase1.UID, after, before keybase1.Time) bool {
+	if member.Exists() && (e.Member == nil || !e.Member.Uid.Equal(member)) {
+		return false
+	}
+	return historyTimeMatches(e.Time, after, before)
+}
+
+func historyTimeMatches(t, after, before keybase1.Time) bool {
+	if after == 0 && before == 0 {
+		return true
+	}
+	if t == 0 {
+		// Can't tell if it's in the range.
+		return false
+	}
+	return (after == 0 || t >= after) && (before == 0 || t < before)
+}
+
+// History replays the sigchain of a team to list its membership, invite,
+// settings and key rotation events, oldest first. Links that are stubbed
+// for the current user (like invites, for non-admins) don't show up.
+func History(ctx context.Context, g *libkb.GlobalContext, arg keybase1.TeamHistoryArg) (
+	res []keybase1.TeamHistoryEvent, err error) {
+	mctx := libkb.NewMetaContext(ctx, g)
+	defer mctx.Trace(fmt.Sprintf("teams.History(%s)", arg.TeamName), &err)()
+
+	var member keybase1.UID
+	if len(arg.Member) > 0 {
+		member, err = g.GetUPAKLoader().LookupUID(ctx, libkb.NewNormalizedUsername(arg.Member))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	team, err := Load(ctx, g, keybase1.LoadTeamArg{
+		Name:        arg.TeamName,
+		ForceRepoll: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	loader, ok := g.GetTeamLoader().(*TeamLoader)
+	if !ok {
+		return nil, fmt.Errorf("unexpected team loader: %T", g.GetTeamLoader())
+	}
+
+	var entries []historyEntry
+	// Every event of a link has the link's time, so links outside the range
+	// can be skipped without working out their events.
+	want := func(link *ChainLinkUnpacked) bool {
+		return !link.isStubbed() && historyTimeMatches(link.SignatureMetadata().Time, arg.After, arg.Before)
+	}
+	err = loader.replayChain(mctx, team, want, func(prev *TeamSigChainState, next TeamSigChainState,
+		link *ChainLinkUnpacked, signer *SignerX) error {
+		sigMeta := link.SignatureMetadata()
+		base := keybase1.TeamHistoryEvent{
+			Location:    sigMeta.SigChainLocation,
+			Time:        sigMeta.Time,
+			MerkleSeqno: sigMeta.PrevMerkleRootSigned.Seqno,
+		}
+		if signer != nil {
+			base.Signer = signer.signer
+			base.SignerIsImplicitAdmin = signer.implicitAdmin
+		}
+		if key := link.inner.Body.Key; key != nil {
+			base.SigningKID = key.KID
+		}
+		for _, e := range chainEvents(prev, next, base) {
+			entries = append(entries, historyEntry{mainSeqno: link.Seqno(), event: e})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, hiddenChainEvents(team.HiddenChain())...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].mainSeqno != entries[j].mainSeqno {
+			return entries[i].mainSeqno < entries[j].mainSeqno
+		}
+		return entries[i].hiddenSeqno < entries[j].hiddenSeqno
+	})
+
+	users := historyUsers{
+		g:         g,
+		usernames: make(map[keybase1.UID]string),
+		devices:   make(map[keybase1.KID]string),
+	}
+	for _, entry := range entries {
+		if !historyEventMatches(entry.event, member, arg.After, arg.Before) {
+			continue
+		}
+		users.annotate(ctx, &entry.event)
+		res = append(res, entry.event)
+	}
+	return res, nil
+}
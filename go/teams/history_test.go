@@ -0,0 +1,98 @@
+package teams
+
+import (
+	"testing"
+
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryChainEvents(t *testing.T) {
+	alice := NewUserVersion(keybase1.MakeTestUID(1), 1)
+	bob := NewUserVersion(keybase1.MakeTestUID(2), 1)
+	point := func(role keybase1.TeamRole, seqno keybase1.Seqno) keybase1.UserLogPoint {
+		return keybase1.UserLogPoint{
+			Role:    role,
+			SigMeta: keybase1.SignatureMetadata{SigChainLocation: keybase1.SigChainLocation{Seqno: seqno}},
+		}
+	}
+	base := keybase1.TeamHistoryEvent{Signer: alice, Time: 1000}
+
+	// The first link creates the team with its owner.
+	created := TeamSigChainState{inner: keybase1.TeamSigChainState{
+		UserLog: map[keybase1.UserVersion][]keybase1.UserLogPoint{
+			alice: {point(keybase1.TeamRole_OWNER, 1)},
+		},
+		MaxPerTeamKeyGeneration: 1,
+	}}
+	events := chainEvents(nil, created, base)
+	require.Len(t, events, 2)
+	require.Equal(t, keybase1.TeamHistoryEventType_CREATED, events[0].Type)
+	require.Equal(t, keybase1.PerTeamKeyGeneration(1), events[0].Generation)
+	require.Equal(t, keybase1.TeamHistoryEventType_MEMBER_ADDED, events[1].Type)
+	require.Equal(t, alice, *events[1].Member)
+	require.Equal(t, keybase1.TeamRole_OWNER, events[1].NewRole)
+	require.Equal(t, keybase1.Time(1000), events[1].Time)
+
+	// Adding bob and inviting someone by email.
+	inviteID := keybase1.TeamInviteID("54eafff3400b5bcd8b40bff3d225ab27")
+	invite := keybase1.TeamInvite{Id: inviteID, Role: keybase1.TeamRole_WRITER, Name: "max@keybase.io"}
+	added := created.DeepCopy()
+	added.inner.UserLog[bob] = []keybase1.UserLogPoint{point(keybase1.TeamRole_WRITER, 2)}
+	added.inner.InviteMetadatas = map[keybase1.TeamInviteID]keybase1.TeamInviteMetadata{
+		inviteID: {Invite: invite, Status: keybase1.NewTeamInviteMetadataStatusWithActive()},
+	}
+	events = chainEvents(&created, added, base)
+	require.Len(t, events, 2)
+	require.Equal(t, keybase1.TeamHistoryEventType_MEMBER_ADDED, events[0].Type)
+	require.Equal(t, bob, *events[0].Member)
+	require.Equal(t, keybase1.TeamHistoryEventType_INVITE_ADDED, events[1].Type)
+	require.Equal(t, inviteID, events[1].Invite.Id)
+
+	// Promoting bob, cancelling the invite, opening the team and rotating
+	// the key all at once.
+	changed := added.DeepCopy()
+	changed.inner.UserLog[bob] = append(changed.inner.UserLog[bob], point(keybase1.TeamRole_ADMIN, 3))
+	changed.inner.InviteMetadatas[inviteID] = keybase1.TeamInviteMetadata{
+		Invite: invite,
+		Status: keybase1.NewTeamInviteMetadataStatusWithCancelled(keybase1.TeamInviteMetadataCancel{}),
+	}
+	changed.inner.Open = true
+	changed.inner.OpenTeamJoinAs = keybase1.TeamRole_READER
+	changed.inner.MaxPerTeamKeyGeneration = 2
+	events = chainEvents(&added, changed, base)
+	var types []keybase1.TeamHistoryEventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	require.Equal(t, []keybase1.TeamHistoryEventType{
+		keybase1.TeamHistoryEventType_ROLE_CHANGED,
+		keybase1.TeamHistoryEventType_INVITE_CANCELED,
+		keybase1.TeamHistoryEventType_SETTINGS_CHANGED,
+		keybase1.TeamHistoryEventType_KEY_ROTATED,
+	}, types)
+	require.Equal(t, keybase1.TeamRole_WRITER, events[0].OldRole)
+	require.Equal(t, keybase1.TeamRole_ADMIN, events[0].NewRole)
+	require.True(t, events[2].Settings.Open)
+	require.Equal(t, keybase1.PerTeamKeyGeneration(2), events[3].Generation)
+
+	// Bob leaves.
+	left := changed.DeepCopy()
+	left.inner.UserLog[bob] = append(left.inner.UserLog[bob], point(keybase1.TeamRole_NONE, 4))
+	events = chainEvents(&changed, left, keybase1.TeamHistoryEvent{Signer: bob})
+	require.Len(t, events, 1)
+	require.Equal(t, keybase1.TeamHistoryEventType_MEMBER_REMOVED, events[0].Type)
+	require.Equal(t, keybase1.TeamRole_ADMIN, events[0].OldRole)
+	require.Equal(t, bob, events[0].Signer)
+
+	// Filters.
+	require.True(t, historyEventMatches(events[0], bob.Uid, 0, 0))
+	require.False(t, historyEventMatches(events[0], alice.Uid, 0, 0))
+	e := events[0]
+	e.Time = 1000
+	require.True(t, historyEventMatches(e, "", 500, 1500))
+	require.True(t, historyEventMatches(e, "", 1000, 0))
+	require.False(t, historyEventMatches(e, "", 0, 1000))
+	e.Time = 0
+	require.False(t, historyEventMatches(e, "", 500, 0))
+}
@@ -0,0 +1,455 @@
+// Copyright 2020 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package teams
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/chat1"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+)
+
+// Memberships set to expire are recorded in the team's KV store, one entry
+// per member keyed by UID, so that the service of any admin of the team can
+// end them. Team writers can edit the KV store too, so an entry is only acted
+// on if the KV store vouches that it was last written by someone who is
+// currently an admin of the team; SetBy is just for display. Entries still
+// only say what admins asked for; they aren't a guarantee that a membership
+// ends.
+const memberExpiryNamespace = "keybase.team.member-expiry"
+
+// Admins are warned this long before a membership expires.
+const memberExpiryWarnWindow = 72 * time.Hour
+
+type memberExpiryEntry struct {
+	UID         keybase1.UID      `json:"uid"`
+	EldestSeqno keybase1.Seqno    `json:"eldest_seqno"`
+	Username    string            `json:"username"`
+	ExpiresAt   keybase1.Time     `json:"expires_at"`
+	DowngradeTo keybase1.TeamRole `json:"downgrade_to"`
+	SetBy       string            `json:"set_by"`
+	Ctime       keybase1.Time     `json:"ctime"`
+	WarnedAt    keybase1.Time     `json:"warned_at,omitempty"`
+}
+
+type memberExpiryStep int
+
+const (
+	memberExpiryWait memberExpiryStep = iota
+	memberExpiryWarn
+	memberExpiryEnd
+)
+
+// nextStep says what has to happen to the membership at now.
+func (e memberExpiryEntry) nextStep(now time.Time) memberExpiryStep {
+	expiresAt := e.ExpiresAt.Time()
+	switch {
+	case !now.Before(expiresAt):
+		return memberExpiryEnd
+	case e.WarnedAt == 0 && !now.Before(expiresAt.Add(-memberExpiryWarnWindow)):
+		return memberExpiryWarn
+	default:
+		return memberExpiryWait
+	}
+}
+
+func (e memberExpiryEntry) export(teamID keybase1.TeamID, teamName string, role keybase1.TeamRole) keybase1.TeamMemberExpiry {
+	return keybase1.TeamMemberExpiry{
+		TeamID:      teamID,
+		TeamName:    teamName,
+		Uid:         e.UID,
+		Username:    e.Username,
+		Role:        role,
+		ExpiresAt:   e.ExpiresAt,
+		DowngradeTo: e.DowngradeTo,
+		SetBy:       e.SetBy,
+		Ctime:       e.Ctime,
+		WarnedAt:    e.WarnedAt,
+	}
+}
+
+func (e memberExpiryEntry) describe() string {
+	if e.DowngradeTo == keybase1.TeamRole_NONE {
+		return fmt.Sprintf("%s will be removed", e.Username)
+	}
+	return fmt.Sprintf("%s will become a %s", e.Username, e.DowngradeTo.HumanString())
+}
+
+func checkMemberExpiryDowngrade(role, downgradeTo keybase1.TeamRole) error {
+	if downgradeTo == keybase1.TeamRole_NONE {
+		return nil
+	}
+	if downgradeTo.IsBotLike() {
+		return errors.New("members can't be downgraded to bots when their membership expires")
+	}
+	if downgradeTo.IsOrAbove(role) {
+		return fmt.Errorf("can't downgrade a %s to %s", role.HumanString(), downgradeTo.HumanString())
+	}
+	return nil
+}
+
+type memberExpiryKV struct {
+	kv       keybase1.KvstoreInterface
+	teamName string
+}
+
+func (s memberExpiryKV) list(ctx context.Context) ([]keybase1.KVListEntryKey, error) {
+	res, err := s.kv.ListKVEntries(ctx, keybase1.ListKVEntriesArg{
+		TeamName:  s.teamName,
+		Namespace: memberExpiryNamespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.EntryKeys, nil
+}
+
+// get returns a nil entry if there isn't one for the key, or it was deleted.
+// writer is who last wrote the entry, as checked by the KV store.
+func (s memberExpiryKV) get(ctx context.Context, key string) (entry *memberExpiryEntry, revision int, writer *keybase1.UserVersion, err error) {
+	res, err := s.kv.GetKVEntry(ctx, keybase1.GetKVEntryArg{
+		TeamName:  s.teamName,
+		Namespace: memberExpiryNamespace,
+		EntryKey:  key,
+	})
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if res.EntryValue == nil || *res.EntryValue == "" {
+		return nil, res.Revision, nil, nil
+	}
+	entry = new(memberExpiryEntry)
+	if err := json.Unmarshal([]byte(*res.EntryValue), entry); err != nil {
+		return nil, res.Revision, nil, fmt.Errorf("bad member expiry entry %q: %v", key, err)
+	}
+	return entry, res.Revision, res.Writer, nil
+}
+
+// put writes the entry at the given revision, so that two admins' services
+// racing to act on the same entry can't both win.
+func (s memberExpiryKV) put(ctx context.Context, entry memberExpiryEntry, revision int) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.PutKVEntry(ctx, keybase1.PutKVEntryArg{
+		TeamName:   s.teamName,
+		Namespace:  memberExpiryNamespace,
+		EntryKey:   entry.UID.String(),
+		Revision:   revision,
+		EntryValue: string(b),
+	})
+	return err
+}
+
+func (s memberExpiryKV) del(ctx context.Context, key string, revision int) error {
+	_, err := s.kv.DelKVEntry(ctx, keybase1.DelKVEntryArg{
+		TeamName:  s.teamName,
+		Namespace: memberExpiryNamespace,
+		EntryKey:  key,
+		Revision:  revision,
+	})
+	return err
+}
+
+// writtenByAdmin says whether writer can currently manage t's members, as an
+// admin or owner of t or of one of its ancestors. Entries written by anyone
+// else are ignored.
+func writtenByAdmin(ctx context.Context, g *libkb.GlobalContext, t *Team, writer *keybase1.UserVersion) (bool, error) {
+	if writer == nil {
+		return false, nil
+	}
+	role, err := t.MemberRole(ctx, *writer)
+	if err != nil {
+		return false, err
+	}
+	if role.IsAdminOrAbove() {
+		return true, nil
+	}
+	if !t.IsSubteam() {
+		return false, nil
+	}
+	implicitAdmins, err := g.GetTeamLoader().ImplicitAdmins(ctx, t.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, uv := range implicitAdmins {
+		if uv.Eq(*writer) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SetMemberExpiry makes username's membership of teamName end at expiresAt,
+// by removing them or, if downgradeTo isn't NONE, changing their role to
+// downgradeTo. A zero expiresAt clears the expiry.
+func SetMemberExpiry(ctx context.Context, g *libkb.GlobalContext, kv keybase1.KvstoreInterface,
+	teamName, username string, expiresAt keybase1.Time, downgradeTo keybase1.TeamRole) error {
+	mctx := libkb.NewMetaContext(ctx, g)
+	t, err := GetForTeamManagementByStringName(ctx, g, teamName, true)
+	if err != nil {
+		return err
+	}
+	if _, err := t.getAdminPermission(ctx); err != nil {
+		return err
+	}
+	uv, err := loadUserVersionByUsername(ctx, g, username, false /* useTracking */)
+	if err != nil {
+		return err
+	}
+	store := memberExpiryKV{kv: kv, teamName: t.Name().String()}
+	current, revision, _, err := store.get(ctx, uv.Uid.String())
+	if err != nil {
+		return err
+	}
+	if expiresAt == 0 {
+		if current == nil {
+			return nil
+		}
+		return store.del(ctx, uv.Uid.String(), revision+1)
+	}
+
+	if !expiresAt.Time().After(g.Clock().Now()) {
+		return errors.New("the expiry time has to be in the future")
+	}
+	role, err := t.MemberRole(ctx, uv)
+	if err != nil {
+		return err
+	}
+	if role == keybase1.TeamRole_NONE {
+		return libkb.NotFoundError{Msg: fmt.Sprintf("%s is not a member of %s", username, t.Name())}
+	}
+	if err := checkMemberExpiryDowngrade(role, downgradeTo); err != nil {
+		return err
+	}
+	return store.put(ctx, memberExpiryEntry{
+		UID:         uv.Uid,
+		EldestSeqno: uv.EldestSeqno,
+		Username:    username,
+		ExpiresAt:   expiresAt,
+		DowngradeTo: downgradeTo,
+		SetBy:       mctx.ActiveDevice().Username(mctx).String(),
+		Ctime:       keybase1.ToTime(g.Clock().Now()),
+	}, revision+1)
+}
+
+// adminTeamNames returns the teams the current user is an explicit admin or
+// owner of.
+func adminTeamNames(ctx context.Context, g *libkb.GlobalContext) (names []string, err error) {
+	list, err := ListTeamsUnverified(ctx, g, keybase1.TeamListUnverifiedArg{})
+	if err != nil {
+		return nil, err
+	}
+	for _, team := range list.Teams {
+		if team.Role.IsAdminOrAbove() {
+			names = append(names, team.FqName)
+		}
+	}
+	return names, nil
+}
+
+// ListExpiringMembers returns the memberships of teamName that are set to
+// expire, or of every team the current user is an admin of if teamName is
+// empty, soonest first.
+func ListExpiringMembers(ctx context.Context, g *libkb.GlobalContext, kv keybase1.KvstoreInterface,
+	teamName string) (res []keybase1.TeamMemberExpiry, err error) {
+	teamNames := []string{teamName}
+	if teamName == "" {
+		if teamNames, err = adminTeamNames(ctx, g); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range teamNames {
+		t, err := GetForTeamManagementByStringName(ctx, g, name, false)
+		if err != nil {
+			return nil, err
+		}
+		store := memberExpiryKV{kv: kv, teamName: t.Name().String()}
+		keys, err := store.list(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			entry, _, writer, err := store.get(ctx, key.EntryKey)
+			if err != nil {
+				return nil, err
+			}
+			if entry == nil {
+				continue
+			}
+			ok, err := writtenByAdmin(ctx, g, t, writer)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			role, err := t.MemberRole(ctx, NewUserVersion(entry.UID, entry.EldestSeqno))
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, entry.export(t.ID, t.Name().String(), role))
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].ExpiresAt < res[j].ExpiresAt })
+	return res, nil
+}
+
+// MemberExpirer warns admins about and then ends the memberships that are
+// set to expire in the teams the current user is an admin of. The
+// TeamMemberExpiryBackground engine runs it periodically.
+type MemberExpirer struct {
+	kv keybase1.KvstoreInterface
+}
+
+var _ libkb.TeamMemberExpirer = (*MemberExpirer)(nil)
+
+func NewMemberExpirer(kv keybase1.KvstoreInterface) *MemberExpirer {
+	return &MemberExpirer{kv: kv}
+}
+
+func NewMemberExpirerAndInstall(g *libkb.GlobalContext, kv keybase1.KvstoreInterface) {
+	g.SetTeamMemberExpirer(NewMemberExpirer(kv))
+}
+
+func (e *MemberExpirer) ExpireMembers(mctx libkb.MetaContext) (err error) {
+	mctx = mctx.WithLogTag("TMEXP")
+	defer mctx.Trace("MemberExpirer#ExpireMembers", &err)()
+	teamNames, err := adminTeamNames(mctx.Ctx(), mctx.G())
+	if err != nil {
+		return err
+	}
+	// Keep going through the other teams when one fails; whatever failed is
+	// retried on the next round.
+	var errs []error
+	for _, teamName := range teamNames {
+		if err := e.expireTeamMembers(mctx, teamName); err != nil {
+			mctx.Debug("| failed to expire members of %s: %v", teamName, err)
+			errs = append(errs, err)
+		}
+	}
+	return libkb.CombineErrors(errs...)
+}
+
+func (e *MemberExpirer) expireTeamMembers(mctx libkb.MetaContext, teamName string) error {
+	store := memberExpiryKV{kv: e.kv, teamName: teamName}
+	keys, err := store.list(mctx.Ctx())
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	t, err := GetForTeamManagementByStringName(mctx.Ctx(), mctx.G(), teamName, false)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, key := range keys {
+		entry, revision, writer, err := store.get(mctx.Ctx(), key.EntryKey)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		step := entry.nextStep(mctx.G().Clock().Now())
+		if step == memberExpiryWait {
+			continue
+		}
+		ok, err := writtenByAdmin(mctx.Ctx(), mctx.G(), t, writer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			mctx.Debug("| ignoring the expiry of %s in %s, which wasn't set by an admin (writer: %v)", entry.Username, teamName, writer)
+			continue
+		}
+		switch step {
+		case memberExpiryWarn:
+			err = e.warn(mctx, store, *entry, revision)
+		case memberExpiryEnd:
+			err = e.end(mctx, store, *entry, revision)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return libkb.CombineErrors(errs...)
+}
+
+func (e *MemberExpirer) warn(mctx libkb.MetaContext, store memberExpiryKV, entry memberExpiryEntry, revision int) error {
+	t, err := GetForTeamManagementByStringName(mctx.Ctx(), mctx.G(), store.teamName, true)
+	if err != nil {
+		return err
+	}
+	// Mark the entry first: if another admin's service got there before us,
+	// the write fails and they send the warning instead.
+	entry.WarnedAt = keybase1.ToTime(mctx.G().Clock().Now())
+	if err := store.put(mctx.Ctx(), entry, revision+1); err != nil {
+		return err
+	}
+
+	g := mctx.G()
+	if !g.Env.SendSystemChatMessages() {
+		mctx.Debug("| skipping expiry warning for %s via environment flag", entry.Username)
+		return nil
+	}
+	admins, err := t.UsersWithRoleOrAbove(keybase1.TeamRole_ADMIN)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, uv := range admins {
+		name, err := g.GetUPAKLoader().LookupUsername(mctx.Ctx(), uv.Uid)
+		if err != nil {
+			return err
+		}
+		names = append(names, name.String())
+	}
+	sort.Strings(names)
+	text := fmt.Sprintf("%s's membership of %s expires %s, after which %s. Run `keybase team add-member --expires` to extend it.",
+		entry.Username, store.teamName, entry.ExpiresAt.Time().Format(time.RFC1123), entry.describe())
+	g.StartStandaloneChat()
+	return g.ChatHelper.SendTextByName(mctx.Ctx(), strings.Join(names, ","), nil,
+		chat1.ConversationMembersType_IMPTEAMNATIVE, keybase1.TLFIdentifyBehavior_CHAT_CLI, text)
+}
+
+func (e *MemberExpirer) end(mctx libkb.MetaContext, store memberExpiryKV, entry memberExpiryEntry, revision int) error {
+	t, err := GetForTeamManagementByStringName(mctx.Ctx(), mctx.G(), store.teamName, true)
+	if err != nil {
+		return err
+	}
+	// Someone who has since reset or left isn't the member whose expiry was
+	// set, so there's nothing left to do.
+	role, err := t.MemberRole(mctx.Ctx(), NewUserVersion(entry.UID, entry.EldestSeqno))
+	if err != nil {
+		return err
+	}
+	switch {
+	case role == keybase1.TeamRole_NONE:
+		mctx.Debug("| %s is no longer in %s", entry.Username, store.teamName)
+	case entry.DowngradeTo == keybase1.TeamRole_NONE:
+		mctx.Debug("| removing %s from %s", entry.Username, store.teamName)
+		err = RemoveMemberByID(mctx.Ctx(), mctx.G(), t.ID, entry.Username)
+	case role.IsOrAbove(entry.DowngradeTo) && role != entry.DowngradeTo:
+		mctx.Debug("| downgrading %s in %s to %v", entry.Username, store.teamName, entry.DowngradeTo)
+		err = EditMemberByID(mctx.Ctx(), mctx.G(), t.ID, entry.Username, entry.DowngradeTo, nil)
+	}
+	if err != nil {
+		return err
+	}
+	return store.del(mctx.Ctx(), entry.UID.String(), revision+1)
+}
@@ -0,0 +1,158 @@
+package teams
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/adamwalz/keybase-client/go/libkb"
+	"github.com/adamwalz/keybase-client/go/protocol/keybase1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemberExpiryNextStep(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	entry := memberExpiryEntry{
+		UID:       keybase1.MakeTestUID(1),
+		Username:  "alice",
+		ExpiresAt: keybase1.ToTime(now.Add(10 * 24 * time.Hour)),
+	}
+	require.Equal(t, memberExpiryWait, entry.nextStep(now))
+
+	// Inside the warning window admins are warned, but only once.
+	entry.ExpiresAt = keybase1.ToTime(now.Add(memberExpiryWarnWindow - time.Minute))
+	require.Equal(t, memberExpiryWarn, entry.nextStep(now))
+	entry.WarnedAt = keybase1.ToTime(now)
+	require.Equal(t, memberExpiryWait, entry.nextStep(now))
+
+	// Memberships end once the time passes, warned or not.
+	entry.ExpiresAt = keybase1.ToTime(now)
+	require.Equal(t, memberExpiryEnd, entry.nextStep(now))
+	entry.WarnedAt = 0
+	entry.ExpiresAt = keybase1.ToTime(now.Add(-time.Hour))
+	require.Equal(t, memberExpiryEnd, entry.nextStep(now))
+
+	// Entries are read back by other admins' services.
+	b, err := json.Marshal(entry)
+	require.NoError(t, err)
+	var read memberExpiryEntry
+	require.NoError(t, json.Unmarshal(b, &read))
+	require.Equal(t, entry, read)
+}
+
+func TestCheckMemberExpiryDowngrade(t *testing.T) {
+	require.NoError(t, checkMemberExpiryDowngrade(keybase1.TeamRole_WRITER, keybase1.TeamRole_NONE))
+	require.NoError(t, checkMemberExpiryDowngrade(keybase1.TeamRole_WRITER, keybase1.TeamRole_READER))
+	require.NoError(t, checkMemberExpiryDowngrade(keybase1.TeamRole_OWNER, keybase1.TeamRole_ADMIN))
+	require.Error(t, checkMemberExpiryDowngrade(keybase1.TeamRole_WRITER, keybase1.TeamRole_WRITER))
+	require.Error(t, checkMemberExpiryDowngrade(keybase1.TeamRole_READER, keybase1.TeamRole_ADMIN))
+	require.Error(t, checkMemberExpiryDowngrade(keybase1.TeamRole_WRITER, keybase1.TeamRole_BOT))
+}
+
+// memberExpiryTestKV is an in-memory KV store for a single team, which says
+// that every entry was written by writer.
+type memberExpiryTestKV struct {
+	writer  keybase1.UserVersion
+	entries map[string]keybase1.KVGetResult
+}
+
+var _ keybase1.KvstoreInterface = (*memberExpiryTestKV)(nil)
+
+func (k *memberExpiryTestKV) GetKVEntry(_ context.Context, arg keybase1.GetKVEntryArg) (keybase1.KVGetResult, error) {
+	if res, ok := k.entries[arg.EntryKey]; ok {
+		return res, nil
+	}
+	return keybase1.KVGetResult{TeamName: arg.TeamName, Namespace: arg.Namespace, EntryKey: arg.EntryKey}, nil
+}
+
+func (k *memberExpiryTestKV) PutKVEntry(_ context.Context, arg keybase1.PutKVEntryArg) (keybase1.KVPutResult, error) {
+	current := k.entries[arg.EntryKey]
+	if arg.Revision != current.Revision+1 {
+		return keybase1.KVPutResult{}, fmt.Errorf("revision %d isn't %d", arg.Revision, current.Revision+1)
+	}
+	value := arg.EntryValue
+	writer := k.writer
+	k.entries[arg.EntryKey] = keybase1.KVGetResult{
+		TeamName:   arg.TeamName,
+		Namespace:  arg.Namespace,
+		EntryKey:   arg.EntryKey,
+		EntryValue: &value,
+		Revision:   arg.Revision,
+		Writer:     &writer,
+	}
+	return keybase1.KVPutResult{TeamName: arg.TeamName, Namespace: arg.Namespace, EntryKey: arg.EntryKey, Revision: arg.Revision}, nil
+}
+
+func (k *memberExpiryTestKV) ListKVNamespaces(_ context.Context, arg keybase1.ListKVNamespacesArg) (keybase1.KVListNamespaceResult, error) {
+	return keybase1.KVListNamespaceResult{TeamName: arg.TeamName, Namespaces: []string{memberExpiryNamespace}}, nil
+}
+
+func (k *memberExpiryTestKV) ListKVEntries(_ context.Context, arg keybase1.ListKVEntriesArg) (res keybase1.KVListEntryResult, err error) {
+	res = keybase1.KVListEntryResult{TeamName: arg.TeamName, Namespace: arg.Namespace}
+	for key, entry := range k.entries {
+		if entry.EntryValue != nil {
+			res.EntryKeys = append(res.EntryKeys, keybase1.KVListEntryKey{EntryKey: key, Revision: entry.Revision})
+		}
+	}
+	return res, nil
+}
+
+func (k *memberExpiryTestKV) DelKVEntry(_ context.Context, arg keybase1.DelKVEntryArg) (keybase1.KVDeleteEntryResult, error) {
+	current := k.entries[arg.EntryKey]
+	if arg.Revision != current.Revision+1 {
+		return keybase1.KVDeleteEntryResult{}, fmt.Errorf("revision %d isn't %d", arg.Revision, current.Revision+1)
+	}
+	current.EntryValue = nil
+	current.Revision = arg.Revision
+	k.entries[arg.EntryKey] = current
+	return keybase1.KVDeleteEntryResult{TeamName: arg.TeamName, Namespace: arg.Namespace, EntryKey: arg.EntryKey, Revision: arg.Revision}, nil
+}
+
+func (k *memberExpiryTestKV) BatchKVEntries(context.Context, keybase1.BatchKVEntriesArg) (keybase1.KVBatchResult, error) {
+	return keybase1.KVBatchResult{}, fmt.Errorf("not implemented")
+}
+
+func TestMemberExpiryIgnoresEntriesFromNonAdmins(t *testing.T) {
+	tc, owner, writer, admin, name := memberSetupMultiple(t)
+	defer tc.Cleanup()
+	ctx := context.TODO()
+
+	_, err := AddMember(ctx, tc.G, name, writer.Username, keybase1.TeamRole_WRITER, nil)
+	require.NoError(t, err)
+	_, err = AddMember(ctx, tc.G, name, admin.Username, keybase1.TeamRole_ADMIN, nil)
+	require.NoError(t, err)
+
+	// A writer forges an entry, claiming the owner set it, that removes the
+	// admin an hour ago.
+	kv := &memberExpiryTestKV{writer: writer.GetUserVersion(), entries: make(map[string]keybase1.KVGetResult)}
+	store := memberExpiryKV{kv: kv, teamName: name}
+	forged := memberExpiryEntry{
+		UID:         admin.GetUID(),
+		EldestSeqno: admin.GetUserVersion().EldestSeqno,
+		Username:    admin.Username,
+		ExpiresAt:   keybase1.ToTime(tc.G.Clock().Now().Add(-time.Hour)),
+		DowngradeTo: keybase1.TeamRole_NONE,
+		SetBy:       owner.Username,
+	}
+	require.NoError(t, store.put(ctx, forged, 1))
+
+	mctx := libkb.NewMetaContextForTest(tc)
+	expirer := NewMemberExpirer(kv)
+	require.NoError(t, expirer.expireTeamMembers(mctx, name))
+	assertRole(tc, name, admin.Username, keybase1.TeamRole_ADMIN)
+	list, err := ListExpiringMembers(ctx, tc.G, kv, name)
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+
+	// The same entry, written by the owner, is acted on.
+	kv.writer = owner.GetUserVersion()
+	require.NoError(t, store.put(ctx, forged, 2))
+	require.NoError(t, expirer.expireTeamMembers(mctx, name))
+	assertRole(tc, name, admin.Username, keybase1.TeamRole_NONE)
+	entry, _, _, err := store.get(ctx, admin.GetUID().String())
+	require.NoError(t, err)
+	require.Nil(t, entry)
+}
@@ -957,6 +957,23 @@ func (tx *AddMemberTx) ReAddMemberToImplicitTeam(ctx context.Context, uv keybase
 	return nil
 }
 
+// RemoveMemberByUV removes a cryptomember from the team. It fails if the UV
+// is not a member of the team.
+func (tx *AddMemberTx) RemoveMemberByUV(ctx context.Context, uv keybase1.UserVersion) (err error) {
+	team := tx.team
+	defer team.G().CTrace(ctx, fmt.Sprintf("AddMemberTx.RemoveMemberByUV(%v) from team %q", uv, team.Name()), &err)()
+
+	role, err := team.chain().GetUserRole(uv)
+	if err != nil {
+		return err
+	}
+	if role == keybase1.TeamRole_NONE {
+		return libkb.NotFoundError{Msg: fmt.Sprintf("%v is not a member of %s", uv, team.Name())}
+	}
+	tx.removeMember(uv)
+	return nil
+}
+
 func (tx *AddMemberTx) CancelInvite(id keybase1.TeamInviteID, forUID keybase1.UID) {
 	payload := tx.inviteKeybasePayload(forUID)
 	if payload.Cancel == nil {
@@ -2741,6 +2741,9 @@ const createSlice: Z.ImmerStateCreator<ConvoState> = (set, get) => {
                 afterContext: 0,
                 beforeContext: 0,
                 convID: T.Chat.keyToConversationID(conversationIDKey),
+                hasAttachment: false,
+                hasLink: false,
+                isPinned: false,
                 isRegex: false,
                 matchMentions: false,
                 maxBots: 0,
@@ -2754,6 +2757,7 @@ const createSlice: Z.ImmerStateCreator<ConvoState> = (set, get) => {
                 sentAfter: 0,
                 sentBefore: 0,
                 sentBy: '',
+                sentIn: '',
                 sentTo: '',
                 skipBotCache: false,
               },
@@ -738,6 +738,9 @@ export const _useState = Z.createZustand<State>((set, get) => {
               opts: {
                 afterContext: 0,
                 beforeContext: 0,
+                hasAttachment: false,
+                hasLink: false,
+                isPinned: false,
                 isRegex: false,
                 matchMentions: false,
                 maxBots: 10,
@@ -751,6 +754,7 @@ export const _useState = Z.createZustand<State>((set, get) => {
                 sentAfter: 0,
                 sentBefore: 0,
                 sentBy: '',
+                sentIn: '',
                 sentTo: '',
                 skipBotCache: false,
               },
@@ -126,6 +126,7 @@ export const _useState = Z.createZustand<State>((set, get) => {
               kbfsrequest: false,
               kbfssubscription: true,
               keyfamily: false,
+              kvstore: false,
               paperkeys: false,
               pgp: true,
               reachability: true,
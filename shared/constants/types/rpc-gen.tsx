@@ -1763,6 +1763,11 @@ export enum DismissReasonType {
   handledElsewhere = 1,
 }
 
+export enum EncryptionFormat {
+  saltpack = 0,
+  age = 1,
+}
+
 export enum ExitCode {
   ok = 0,
   notok = 2,
@@ -2010,6 +2015,11 @@ export enum KeyType {
   pgp = 2,
 }
 
+export enum KVEntryChangeType {
+  put = 0,
+  delete = 1,
+}
+
 export enum ListFilter {
   noFilter = 0,
   filterAllHidden = 1,
@@ -2668,6 +2678,15 @@ export enum TeamApplication {
   kvstore = 7,
 }
 
+export enum TeamApplyChangeType {
+  createSubteam = 0,
+  addMember = 1,
+  changeRole = 2,
+  removeMember = 3,
+  setBotSettings = 4,
+  changeSettings = 5,
+}
+
 export enum TeamChangedSource {
   server = 0,
   local = 1,
@@ -2679,6 +2698,18 @@ export enum TeamEphemeralKeyType {
   teambot = 1,
 }
 
+export enum TeamHistoryEventType {
+  created = 0,
+  memberAdded = 1,
+  roleChanged = 2,
+  memberRemoved = 3,
+  inviteAdded = 4,
+  inviteCanceled = 5,
+  inviteCompleted = 6,
+  settingsChanged = 7,
+  keyRotated = 8,
+}
+
 export enum TeamInviteCategory {
   none = 0,
   unknown = 1,
@@ -3049,9 +3080,13 @@ export type KBFSRootHash = Bytes
 export type KBFSStatus = {readonly version: String; readonly installedVersion: String; readonly running: Boolean; readonly pid: String; readonly log: String; readonly perfLog: String; readonly mount: String}
 export type KBFSTeamSettings = {readonly tlfID: TLFID}
 export type KID = String
+export type KVBatchEntry = {readonly namespace: String; readonly entryKey: String; readonly revision: Int; readonly entryValue?: String | null}
+export type KVBatchEntryResult = {readonly namespace: String; readonly entryKey: String; readonly revision: Int; readonly deleted: Boolean}
+export type KVBatchResult = {readonly teamName: String; readonly results?: Array<KVBatchEntryResult> | null}
 export type KVDeleteEntryResult = {readonly teamName: String; readonly namespace: String; readonly entryKey: String; readonly revision: Int}
+export type KVEntryChange = {readonly changeType: KVEntryChangeType; readonly teamID: TeamID; readonly teamName: String; readonly namespace: String; readonly entryKey: String; readonly revision: Int; readonly writerUID: UID; readonly writerUsername: String; readonly writerDeviceID: DeviceID}
 export type KVEntryID = {readonly teamID: TeamID; readonly namespace: String; readonly entryKey: String}
-export type KVGetResult = {readonly teamName: String; readonly namespace: String; readonly entryKey: String; readonly entryValue?: String | null; readonly revision: Int}
+export type KVGetResult = {readonly teamName: String; readonly namespace: String; readonly entryKey: String; readonly entryValue?: String | null; readonly revision: Int; readonly stale: Boolean; readonly writer?: UserVersion | null}
 export type KVListEntryKey = {readonly entryKey: String; readonly revision: Int}
 export type KVListEntryResult = {readonly teamName: String; readonly namespace: String; readonly entryKeys?: Array<KVListEntryKey> | null}
 export type KVListNamespaceResult = {readonly teamName: String; readonly namespaces?: Array<String> | null}
@@ -3103,7 +3138,7 @@ export type NaclSigningKeyPrivate = string | null
 export type NaclSigningKeyPublic = string | null
 export type NextMerkleRootRes = {readonly res?: MerkleRootV2 | null}
 export type NonUserDetails = {readonly isNonUser: Boolean; readonly assertionValue: String; readonly assertionKey: String; readonly description: String; readonly contact?: ProcessedContact | null; readonly service?: APIUserServiceResult | null; readonly siteIcon?: Array<SizedImage> | null; readonly siteIconDarkmode?: Array<SizedImage> | null; readonly siteIconFull?: Array<SizedImage> | null; readonly siteIconFullDarkmode?: Array<SizedImage> | null}
-export type NotificationChannels = {readonly session: Boolean; readonly users: Boolean; readonly kbfs: Boolean; readonly kbfsdesktop: Boolean; readonly kbfslegacy: Boolean; readonly kbfssubscription: Boolean; readonly tracking: Boolean; readonly favorites: Boolean; readonly paperkeys: Boolean; readonly keyfamily: Boolean; readonly service: Boolean; readonly app: Boolean; readonly chat: Boolean; readonly pgp: Boolean; readonly kbfsrequest: Boolean; readonly badges: Boolean; readonly reachability: Boolean; readonly team: Boolean; readonly ephemeral: Boolean; readonly teambot: Boolean; readonly chatkbfsedits: Boolean; readonly chatdev: Boolean; readonly chatemoji: Boolean; readonly chatemojicross: Boolean; readonly deviceclone: Boolean; readonly chatattachments: Boolean; readonly wallet: Boolean; readonly audit: Boolean; readonly runtimestats: Boolean; readonly featuredBots: Boolean; readonly saltpack: Boolean; readonly allowChatNotifySkips: Boolean}
+export type NotificationChannels = {readonly session: Boolean; readonly users: Boolean; readonly kbfs: Boolean; readonly kbfsdesktop: Boolean; readonly kbfslegacy: Boolean; readonly kbfssubscription: Boolean; readonly tracking: Boolean; readonly favorites: Boolean; readonly paperkeys: Boolean; readonly keyfamily: Boolean; readonly service: Boolean; readonly app: Boolean; readonly chat: Boolean; readonly pgp: Boolean; readonly kbfsrequest: Boolean; readonly badges: Boolean; readonly reachability: Boolean; readonly team: Boolean; readonly ephemeral: Boolean; readonly teambot: Boolean; readonly chatkbfsedits: Boolean; readonly chatdev: Boolean; readonly chatemoji: Boolean; readonly chatemojicross: Boolean; readonly deviceclone: Boolean; readonly chatattachments: Boolean; readonly wallet: Boolean; readonly audit: Boolean; readonly runtimestats: Boolean; readonly featuredBots: Boolean; readonly saltpack: Boolean; readonly kvstore: Boolean; readonly allowChatNotifySkips: Boolean}
 export type OpDescription = {asyncOp: AsyncOps.list; list: ListArgs} | {asyncOp: AsyncOps.listRecursive; listRecursive: ListArgs} | {asyncOp: AsyncOps.listRecursiveToDepth; listRecursiveToDepth: ListToDepthArgs} | {asyncOp: AsyncOps.read; read: ReadArgs} | {asyncOp: AsyncOps.write; write: WriteArgs} | {asyncOp: AsyncOps.copy; copy: CopyArgs} | {asyncOp: AsyncOps.move; move: MoveArgs} | {asyncOp: AsyncOps.remove; remove: RemoveArgs} | {asyncOp: AsyncOps.getRevisions; getRevisions: GetRevisionsArgs}
 export type OpID = string | null
 export type OpProgress = {readonly start: Time; readonly endEstimate: Time; readonly opType: AsyncOps; readonly bytesTotal: Int64; readonly bytesRead: Int64; readonly bytesWritten: Int64; readonly filesTotal: Int64; readonly filesRead: Int64; readonly filesWritten: Int64}
@@ -3192,7 +3227,7 @@ export type RuntimeStats = {readonly processStats?: Array<ProcessRuntimeStats> |
 export type SHA512 = Bytes
 export type SaltpackDecryptOptions = {readonly interactive: Boolean; readonly forceRemoteCheck: Boolean; readonly usePaperKey: Boolean}
 export type SaltpackEncryptFileResult = {readonly usedUnresolvedSBS: Boolean; readonly unresolvedSBSAssertion: String; readonly filename: String}
-export type SaltpackEncryptOptions = {readonly recipients?: Array<String> | null; readonly teamRecipients?: Array<String> | null; readonly authenticityType: AuthenticityType; readonly useEntityKeys: Boolean; readonly useDeviceKeys: Boolean; readonly usePaperKeys: Boolean; readonly noSelfEncrypt: Boolean; readonly binary: Boolean; readonly saltpackVersion: Int; readonly noForcePoll: Boolean; readonly useKBFSKeysOnlyForTesting: Boolean}
+export type SaltpackEncryptOptions = {readonly recipients?: Array<String> | null; readonly teamRecipients?: Array<String> | null; readonly authenticityType: AuthenticityType; readonly useEntityKeys: Boolean; readonly useDeviceKeys: Boolean; readonly usePaperKeys: Boolean; readonly noSelfEncrypt: Boolean; readonly binary: Boolean; readonly saltpackVersion: Int; readonly noForcePoll: Boolean; readonly useKBFSKeysOnlyForTesting: Boolean; readonly format: EncryptionFormat}
 export type SaltpackEncryptResult = {readonly usedUnresolvedSBS: Boolean; readonly unresolvedSBSAssertion: String}
 export type SaltpackEncryptStringResult = {readonly usedUnresolvedSBS: Boolean; readonly unresolvedSBSAssertion: String; readonly ciphertext: String}
 export type SaltpackEncryptedMessageInfo = {readonly devices?: Array<Device> | null; readonly numAnonReceivers: Int; readonly receiverIsAnon: Boolean; readonly sender: SaltpackSender}
@@ -3243,9 +3278,16 @@ export type SignupRes = {readonly passphraseOk: Boolean; readonly postOk: Boolea
 export type SimpleFSIndexProgress = {readonly overallProgress: IndexProgressRecord; readonly currFolder: Folder; readonly currProgress: IndexProgressRecord; readonly foldersLeft?: Array<Folder> | null}
 export type SimpleFSListResult = {readonly entries?: Array<Dirent> | null; readonly progress: Progress}
 export type SimpleFSQuotaUsage = {readonly usageBytes: Int64; readonly archiveBytes: Int64; readonly limitBytes: Int64; readonly gitUsageBytes: Int64; readonly gitArchiveBytes: Int64; readonly gitLimitBytes: Int64}
-export type SimpleFSSearchHit = {readonly path: String}
+export type SimpleFSS3Bucket = {readonly name: String; readonly path: KBFSPath}
+export type SimpleFSS3Gateway = {readonly url: String; readonly accessKey: String; readonly secretKey: String; readonly buckets?: Array<String> | null}
+export type SimpleFSSearchFilters = {readonly tlf: String; readonly pathPrefix: String; readonly fileType: String; readonly modifiedAfter: Time; readonly modifiedBefore: Time}
+export type SimpleFSSearchFragment = {readonly text: String; readonly matches?: Array<SimpleFSSearchMatch> | null}
+export type SimpleFSSearchHit = {readonly path: String; readonly score: Double; readonly fragments?: Array<SimpleFSSearchFragment> | null}
+export type SimpleFSSearchMatch = {readonly start: Int; readonly end: Int}
 export type SimpleFSSearchResults = {readonly hits?: Array<SimpleFSSearchHit> | null; readonly nextResult: Int}
 export type SimpleFSStats = {readonly processStats: ProcessRuntimeStats; readonly blockCacheDbStats?: Array<String> | null; readonly syncCacheDbStats?: Array<String> | null; readonly runtimeDbStats?: Array<DbStats> | null}
+export type SimpleFSWebDAVServer = {readonly url: String; readonly token: String}
+export type SimpleFSXattr = {readonly name: String; readonly value: Bytes}
 export type SizedImage = {readonly path: String; readonly width: Int}
 export type SocialAssertion = {readonly user: String; readonly service: SocialAssertionService}
 export type SocialAssertionService = String
@@ -3270,11 +3312,17 @@ export type TeamAddMemberResult = {readonly invited: Boolean; readonly user?: Us
 export type TeamAddMembersResult = {readonly notAdded?: Array<User> | null}
 export type TeamAndMemberShowcase = {readonly teamShowcase: TeamShowcase; readonly isMemberShowcased: Boolean}
 export type TeamApplicationKey = {readonly application: TeamApplication; readonly keyGeneration: PerTeamKeyGeneration; readonly key: Bytes32}
+export type TeamApplyChange = {readonly teamName: String; readonly type: TeamApplyChangeType; readonly username: String; readonly oldRole: TeamRole; readonly newRole: TeamRole; readonly botSettings?: TeamBotSettings | null; readonly settings?: TeamSettings | null}
+export type TeamApplyMember = {readonly username: String; readonly role: TeamRole; readonly botSettings?: TeamBotSettings | null}
+export type TeamApplyResult = {readonly changes?: Array<TeamApplyChange> | null; readonly teams?: Array<TeamApplyTeamResult> | null; readonly applied: Boolean}
+export type TeamApplyTeam = {readonly name: String; readonly members?: Array<TeamApplyMember> | null; readonly settings?: TeamSettings | null}
+export type TeamApplyTeamResult = {readonly teamName: String; readonly applied: Boolean; readonly error?: String | null}
 export type TeamAvatar = {readonly avatarFilename: String; readonly crop?: ImageCropRect | null}
 export type TeamBlock = {readonly teamName: String; readonly createTime: Time}
 export type TeamBotSettings = {readonly cmds: Boolean; readonly mentions: Boolean; readonly triggers?: Array<String> | null; readonly convs?: Array<String> | null}
 export type TeamCLKRMsg = {readonly teamID: TeamID; readonly generation: PerTeamKeyGeneration; readonly score: Int; readonly resetUsersUntrusted?: Array<TeamCLKRResetUser> | null}
 export type TeamCLKRResetUser = {readonly uid: UID; readonly userEldestSeqno: Seqno; readonly memberEldestSeqno: Seqno}
+export type TeamChainVerification = {readonly teamID: TeamID; readonly teamName: String; readonly lastSeqno: Seqno; readonly lastLinkID: LinkID; readonly lastHiddenSeqno: Seqno; readonly stubbedLinks: Int; readonly ancestors: Int; readonly signers: Int; readonly happensBeforeProofs: Int; readonly merkleSeqno: Seqno; readonly merkleTime: Time; readonly exportedAt: Time}
 export type TeamChangeReq = {readonly owners?: Array<UserVersion> | null; readonly admins?: Array<UserVersion> | null; readonly writers?: Array<UserVersion> | null; readonly readers?: Array<UserVersion> | null; readonly bots?: Array<UserVersion> | null; readonly restrictedBots?: {[key: string]: TeamBotSettings} | null; readonly none?: Array<UserVersion> | null; readonly completedInvites?: {[key: string]: UserVersionPercentForm} | null; readonly usedInvites?: Array<TeamUsedInvite> | null}
 export type TeamChangeRow = {readonly id: TeamID; readonly name: String; readonly keyRotated: Boolean; readonly membershipChanged: Boolean; readonly latestSeqno: Seqno; readonly latestHiddenSeqno: Seqno; readonly latestOffchainSeqno: Seqno; readonly implicitTeam: Boolean; readonly misc: Boolean; readonly removedResetUsers: Boolean}
 export type TeamChangeSet = {readonly membershipChanged: Boolean; readonly keyRotated: Boolean; readonly renamed: Boolean; readonly misc: Boolean}
@@ -3296,6 +3344,7 @@ export type TeamEphemeralKey = {keyType: TeamEphemeralKeyType.team; team: TeamEk
 export type TeamEphemeralKeyBoxed = {keyType: TeamEphemeralKeyType.team; team: TeamEkBoxed} | {keyType: TeamEphemeralKeyType.teambot; teambot: TeambotEkBoxed}
 export type TeamExitRow = {readonly id: TeamID}
 export type TeamGetLegacyTLFUpgrade = {readonly encryptedKeyset: String; readonly teamGeneration: PerTeamKeyGeneration; readonly legacyGeneration: Int; readonly appType: TeamApplication}
+export type TeamHistoryEvent = {readonly type: TeamHistoryEventType; readonly location: SigChainLocation; readonly time: Time; readonly merkleSeqno: Seqno; readonly signer: UserVersion; readonly signerUsername: String; readonly signingKID: KID; readonly signingDevice: String; readonly signerIsImplicitAdmin: Boolean; readonly member?: UserVersion | null; readonly memberUsername: String; readonly oldRole: TeamRole; readonly newRole: TeamRole; readonly invite?: TeamInvite | null; readonly settings?: TeamSettings | null; readonly generation: PerTeamKeyGeneration}
 export type TeamID = String
 export type TeamIDAndName = {readonly id: TeamID; readonly name: TeamName}
 export type TeamIDWithVisibility = {readonly teamID: TeamID; readonly visibility: TLFVisibility}
@@ -3317,6 +3366,7 @@ export type TeamLegacyTLFUpgradeChainInfo = {readonly keysetHash: TeamEncryptedK
 export type TeamList = {readonly teams?: Array<MemberInfo> | null}
 export type TeamMember = {readonly uid: UID; readonly role: TeamRole; readonly eldestSeqno: Seqno; readonly status: TeamMemberStatus; readonly botSettings?: TeamBotSettings | null}
 export type TeamMemberDetails = {readonly uv: UserVersion; readonly username: String; readonly fullName: FullName; readonly needsPUK: Boolean; readonly status: TeamMemberStatus; readonly joinTime?: Time | null; readonly role: TeamRole}
+export type TeamMemberExpiry = {readonly teamID: TeamID; readonly teamName: String; readonly uid: UID; readonly username: String; readonly role: TeamRole; readonly expiresAt: Time; readonly downgradeTo: TeamRole; readonly setBy: String; readonly ctime: Time; readonly warnedAt: Time}
 export type TeamMemberOutFromReset = {readonly teamID: TeamID; readonly teamName: String; readonly resetUser: TeamResetUser}
 export type TeamMemberOutReset = {readonly teamID: TeamID; readonly teamname: String; readonly username: String; readonly uid: UID; readonly id: Gregor1.MsgID}
 export type TeamMemberRole = {readonly uid: UID; readonly username: String; readonly fullName: FullName; readonly role: TeamRole}
@@ -4128,6 +4178,7 @@ export const wotWotVouchRpcPromise = (params: MessageTypes['keybase.1.wot.wotVou
 // 'keybase.1.kvstore.listKVNamespaces'
 // 'keybase.1.kvstore.listKVEntries'
 // 'keybase.1.kvstore.delKVEntry'
+// 'keybase.1.kvstore.batchKVEntries'
 // 'keybase.1.log.registerLogger'
 // 'keybase.1.logUi.log'
 // 'keybase.1.login.loginProvisionedDevice'
@@ -4207,6 +4258,7 @@ export const wotWotVouchRpcPromise = (params: MessageTypes['keybase.1.wot.wotVou
 // 'keybase.1.NotifyFSRequest.FSSyncStatusRequest'
 // 'keybase.1.NotifyInviteFriends.updateInviteCounts'
 // 'keybase.1.NotifyKeyfamily.keyfamilyChanged'
+// 'keybase.1.NotifyKVStore.kvEntryChanged'
 // 'keybase.1.NotifyPaperKey.paperKeyCached'
 // 'keybase.1.NotifyPGP.pgpKeyInSecretStoreFile'
 // 'keybase.1.NotifyPhoneNumber.phoneNumbersChanged'
@@ -4320,6 +4372,9 @@ export const wotWotVouchRpcPromise = (params: MessageTypes['keybase.1.wot.wotVou
 // 'keybase.1.SimpleFS.simpleFSSymlink'
 // 'keybase.1.SimpleFS.simpleFSRename'
 // 'keybase.1.SimpleFS.simpleFSSetStat'
+// 'keybase.1.SimpleFS.simpleFSListXattrs'
+// 'keybase.1.SimpleFS.simpleFSSetXattr'
+// 'keybase.1.SimpleFS.simpleFSRemoveXattr'
 // 'keybase.1.SimpleFS.simpleFSRead'
 // 'keybase.1.SimpleFS.simpleFSWrite'
 // 'keybase.1.SimpleFS.simpleFSGetRevisions'
@@ -4337,6 +4392,10 @@ export const wotWotVouchRpcPromise = (params: MessageTypes['keybase.1.wot.wotVou
 // 'keybase.1.SimpleFS.simpleFSDeobfuscatePath'
 // 'keybase.1.SimpleFS.simpleFSGetStats'
 // 'keybase.1.SimpleFS.simpleFSCancelUpload'
+// 'keybase.1.SimpleFS.simpleFSStartWebDAV'
+// 'keybase.1.SimpleFS.simpleFSStopWebDAV'
+// 'keybase.1.SimpleFS.simpleFSStartS3Gateway'
+// 'keybase.1.SimpleFS.simpleFSStopS3Gateway'
 // 'keybase.1.SimpleFS.simpleFSSearch'
 // 'keybase.1.SimpleFS.simpleFSResetIndex'
 // 'keybase.1.SimpleFS.simpleFSGetIndexProgress'
@@ -4354,6 +4413,12 @@ export const wotWotVouchRpcPromise = (params: MessageTypes['keybase.1.wot.wotVou
 // 'keybase.1.teams.teamEditMember'
 // 'keybase.1.teams.teamGetBotSettings'
 // 'keybase.1.teams.teamSetBotSettings'
+// 'keybase.1.teams.teamApply'
+// 'keybase.1.teams.teamHistory'
+// 'keybase.1.teams.teamExportChain'
+// 'keybase.1.teams.teamVerifyChain'
+// 'keybase.1.teams.teamSetMemberExpiry'
+// 'keybase.1.teams.teamListExpiringMembers'
 // 'keybase.1.teams.teamAcceptInvite'
 // 'keybase.1.teams.teamRequestAccess'
 // 'keybase.1.teams.teamTreeUnverified'